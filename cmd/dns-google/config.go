@@ -0,0 +1,266 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/provider/google"
+	webhookapi "sigs.k8s.io/external-dns/provider/webhook/api"
+)
+
+// configEnvar is the environment variable of the --config flag.
+const configEnvar = "DNS_GOOGLE_CONFIG"
+
+// instanceName is the syntax of the names of the instances, used in the URLs.
+var instanceName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// config is the configuration of dns-google, read from the optional config
+// file, then the DNS_GOOGLE_* environment variables, then the flags.
+type config struct {
+	// The provider of the flags, served at the root unless Instances is set.
+	instance `json:",inline"`
+	// Instances are the providers served under a prefix, /NAME/records for
+	// the instance NAME - for other projects or zones. They are only set in
+	// the config file, and share the batch, dry run and server settings.
+	Instances map[string]*instance `json:"instances,omitempty"`
+
+	BatchChangeSize     int             `json:"batchChangeSize,omitempty"`
+	BatchChangeInterval metav1.Duration `json:"batchChangeInterval,omitempty"`
+	DryRun              bool            `json:"dryRun,omitempty"`
+	ListenAddress       string          `json:"listenAddress,omitempty"`
+	ReadTimeout         metav1.Duration `json:"readTimeout,omitempty"`
+	WriteTimeout        metav1.Duration `json:"writeTimeout,omitempty"`
+	ShutdownTimeout     metav1.Duration `json:"shutdownTimeout,omitempty"`
+	MetricsAddress      string          `json:"metricsAddress,omitempty"`
+	DebugEndpoints      bool            `json:"debugEndpoints,omitempty"`
+	DoH                 bool            `json:"doh,omitempty"`
+	CertManager         bool            `json:"certManager,omitempty"`
+	Cutover             bool            `json:"cutover,omitempty"`
+	LogFormat           string          `json:"logFormat,omitempty"`
+	LogLevel            string          `json:"logLevel,omitempty"`
+	LogLevels           string          `json:"logLevels,omitempty"`
+
+	// Listeners replace ListenAddress with several addresses, each with its
+	// TLS, client CA and prefixes - only set in the config file.
+	Listeners []webhookapi.Listener `json:"listeners,omitempty"`
+	// Authorization restricts the clients of the prefixes to the identities
+	// of their client certificates, read-only or read-write - only set in the
+	// config file, and applied on reload.
+	Authorization []webhookapi.AccessPolicy `json:"authorization,omitempty"`
+}
+
+// instance is the project and the zones of a Google provider.
+type instance struct {
+	Project      string            `json:"project,omitempty"`
+	Zones        map[string]string `json:"zones,omitempty"`
+	ZoneIDFilter []string          `json:"zoneIDFilter,omitempty"`
+	DomainFilter []string          `json:"domainFilter,omitempty"`
+	Visibility   string            `json:"visibility,omitempty"`
+	ReadOnly     bool              `json:"readOnly,omitempty"`
+	// ForwardingZonePolicy is the policy of the forwarding and peering zones
+	// matching the filters: skip, error or manage, by default.
+	ForwardingZonePolicy string `json:"forwardingZonePolicy,omitempty"`
+	// ZoneBatchChanges override the batch size and interval by zone name,
+	// as SIZE/INTERVAL, SIZE or /INTERVAL.
+	ZoneBatchChanges map[string]string `json:"zoneBatchChanges,omitempty"`
+}
+
+var defaultConfig = config{
+	BatchChangeSize:     1000,
+	BatchChangeInterval: metav1.Duration{Duration: time.Second},
+	ListenAddress:       ":8080",
+	ReadTimeout:         metav1.Duration{Duration: 5 * time.Second},
+	WriteTimeout:        metav1.Duration{Duration: 10 * time.Second},
+	ShutdownTimeout:     metav1.Duration{Duration: 30 * time.Second},
+	MetricsAddress:      ":7979",
+	LogFormat:           "text",
+	LogLevel:            "info",
+}
+
+// parseConfig returns the configuration of the args, layered over the
+// environment and the config file of --config or DNS_GOOGLE_CONFIG.
+func parseConfig(args []string) (*config, error) {
+	defaults := defaultConfig
+	path := configPath(args)
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.UnmarshalStrict(data, &defaults); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	cfg := &config{instance: instance{Zones: map[string]string{}, ZoneBatchChanges: map[string]string{}}}
+	app := newApp(cfg, &defaults)
+	if _, err := app.Parse(args); err != nil {
+		return nil, err
+	}
+	cfg.Instances = defaults.Instances
+	cfg.Listeners = defaults.Listeners
+	cfg.Authorization = defaults.Authorization
+	for i := range cfg.Listeners {
+		if err := cfg.Listeners[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := webhookapi.NewAuthorizer(cfg.Authorization); err != nil {
+		return nil, err
+	}
+	for name, in := range cfg.Instances {
+		if !instanceName.MatchString(name) {
+			return nil, fmt.Errorf("invalid instance name %q: use lowercase letters, digits and '-'", name)
+		}
+		if in == nil {
+			return nil, fmt.Errorf("instance %s has no configuration", name)
+		}
+		if in.Visibility != "" && in.Visibility != "public" && in.Visibility != "private" {
+			return nil, fmt.Errorf("instance %s: invalid visibility %q, use public or private", name, in.Visibility)
+		}
+		switch in.ForwardingZonePolicy {
+		case "", google.ForwardingZoneSkip, google.ForwardingZoneError, google.ForwardingZoneManage:
+		default:
+			return nil, fmt.Errorf("instance %s: invalid forwardingZonePolicy %q, use skip, error or manage", name, in.ForwardingZonePolicy)
+		}
+	}
+	return cfg, nil
+}
+
+// newApp returns the application with the flags bound to cfg, with the
+// values of defaults.
+func newApp(cfg, defaults *config) *kingpin.Application {
+	app := kingpin.New("dns-google", "dns-google serves the Google Cloud DNS provider with the ExternalDNS webhook API.\n\nThe flags are read from the config file of --config, replaced by the env vars - `--flag value` -> `DNS_GOOGLE_FLAG=value`, then by the flags.")
+	app.Version(externaldns.Version)
+	app.DefaultEnvars()
+
+	// Not bound: the config file is read before parsing, by configPath.
+	app.Flag("config", "A YAML file with the configuration, like 'project: my-project' (optional)").String()
+
+	app.Flag("google-project", "The project of the zones, auto-detected on GCP or from PROJECT_ID; must be specified when running outside GCP").Default(defaults.Project).StringVar(&cfg.Project)
+	app.Flag("zone", "A zone name and its domain, like my-zone=example.com; specify multiple times for multiple zones. The zones are listed if not set, requiring the permission to list them (optional)").PlaceHolder("NAME=DOMAIN").Default(mapValues(defaults.Zones)...).StringMapVar(&cfg.Zones)
+	app.Flag("zone-id-filter", "Filter the listed zones by name; specify multiple times for multiple zones (optional)").Default(defaults.ZoneIDFilter...).StringsVar(&cfg.ZoneIDFilter)
+	app.Flag("domain-filter", "Limit the records to the domains matching this filter; specify multiple times for multiple domains (optional)").Default(defaults.DomainFilter...).StringsVar(&cfg.DomainFilter)
+	app.Flag("google-zone-visibility", "Filter the listed zones by visibility (optional, options: public, private)").Default(defaults.Visibility).EnumVar(&cfg.Visibility, "", "public", "private")
+	app.Flag("google-batch-change-size", "The maximum number of changes applied in each batch").Default(strconv.Itoa(defaults.BatchChangeSize)).IntVar(&cfg.BatchChangeSize)
+	app.Flag("google-batch-change-interval", "The interval between the batches of changes").Default(defaults.BatchChangeInterval.Duration.String()).DurationVar(&cfg.BatchChangeInterval.Duration)
+	app.Flag("zone-batch-change", "Override the batch size and interval of a zone, like a large private zone of pods, as ZONE=SIZE/INTERVAL, ZONE=SIZE or ZONE=/INTERVAL; specify multiple times for multiple zones (optional)").PlaceHolder("ZONE=SIZE/INTERVAL").Default(mapValues(defaults.ZoneBatchChanges)...).StringMapVar(&cfg.ZoneBatchChanges)
+	app.Flag("dry-run", "Log the changes instead of applying them (default: disabled)").Default(strconv.FormatBool(defaults.DryRun)).BoolVar(&cfg.DryRun)
+	app.Flag("read-only", "Serve the zones and the records but fail the changes, for the deployments without write credentials (default: disabled)").Default(strconv.FormatBool(defaults.ReadOnly)).BoolVar(&cfg.ReadOnly)
+	app.Flag("forwarding-zone-policy", "The policy of the forwarding and peering zones matching the filters: skip them, fail, or manage the forwarding zones and skip the peering zones (default: manage, options: skip, error, manage)").Default(defaults.ForwardingZonePolicy).EnumVar(&cfg.ForwardingZonePolicy, "", google.ForwardingZoneSkip, google.ForwardingZoneError, google.ForwardingZoneManage)
+	app.Flag("listen-address", "The address of the webhook API").Default(defaults.ListenAddress).StringVar(&cfg.ListenAddress)
+	app.Flag("read-timeout", "The read timeout of the webhook API").Default(defaults.ReadTimeout.Duration.String()).DurationVar(&cfg.ReadTimeout.Duration)
+	app.Flag("write-timeout", "The write timeout of the webhook API").Default(defaults.WriteTimeout.Duration.String()).DurationVar(&cfg.WriteTimeout.Duration)
+	app.Flag("shutdown-timeout", "On SIGTERM, the time to wait for the webhook API requests in progress, like a batch of changes").Default(defaults.ShutdownTimeout.Duration.String()).DurationVar(&cfg.ShutdownTimeout.Duration)
+	app.Flag("doh", "When enabled, serves DNS-over-HTTPS queries for the records of the providers on /dns-query with the webhook API (default: disabled)").Default(strconv.FormatBool(defaults.DoH)).BoolVar(&cfg.DoH)
+	app.Flag("cert-manager", "When enabled, solves the cert-manager DNS01 challenges with the providers on /apis/ with the webhook API (default: disabled)").Default(strconv.FormatBool(defaults.CertManager)).BoolVar(&cfg.CertManager)
+	app.Flag("cutover", "When enabled, serves the weighted cutovers of the names on /cutover with the webhook API (default: disabled)").Default(strconv.FormatBool(defaults.Cutover)).BoolVar(&cfg.Cutover)
+	app.Flag("metrics-address", "Specify where to serve the metrics and the health checks (/healthz, /readyz)").Default(defaults.MetricsAddress).StringVar(&cfg.MetricsAddress)
+	app.Flag("debug-endpoints", "When enabled, serves the runtime profiles on /debug/pprof and the providers on /debug/state with the metrics (default: disabled)").Default(strconv.FormatBool(defaults.DebugEndpoints)).BoolVar(&cfg.DebugEndpoints)
+	app.Flag("log-format", "The format in which log messages are printed: gcp is JSON with the Cloud Logging severity, trace and labels (default: text, options: text, json, gcp)").Default(defaults.LogFormat).EnumVar(&cfg.LogFormat, "text", "json", "gcp")
+	app.Flag("log-level", "Set the level of logging. (default: info, options: panic, debug, info, warning, error, fatal)").Default(defaults.LogLevel).EnumVar(&cfg.LogLevel, "panic", "debug", "info", "warning", "error", "fatal")
+	app.Flag("log-levels", "Set the level of components or zones, like 'provider/google=debug,zone/example.com=debug' (optional)").Default(defaults.LogLevels).StringVar(&cfg.LogLevels)
+	return app
+}
+
+// configPath returns the value of the --config flag, or DNS_GOOGLE_CONFIG.
+func configPath(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if v, ok := strings.CutPrefix(arg, "--config="); ok {
+			return v
+		}
+		if arg == "--config" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return os.Getenv(configEnvar)
+}
+
+// mapValues returns the map as sorted KEY=VALUE flag values.
+func mapValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for k, v := range m {
+		values = append(values, k+"="+v)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// instances returns the providers to serve by URL prefix: the provider of the
+// flags at the root, or the instances of the config file under /NAME.
+func (cfg *config) instances() map[string]*instance {
+	if len(cfg.Instances) == 0 {
+		return map[string]*instance{"": &cfg.instance}
+	}
+	instances := make(map[string]*instance, len(cfg.Instances))
+	for name, in := range cfg.Instances {
+		instances["/"+name] = in
+	}
+	return instances
+}
+
+// providerConfig returns the configuration of the Google provider of the
+// instance.
+func (cfg *config) providerConfig(in *instance) *externaldns.ProviderConfig {
+	pc := &externaldns.ProviderConfig{
+		GoogleProject:              in.Project,
+		GoogleBatchChangeSize:      cfg.BatchChangeSize,
+		GoogleBatchChangeInterval:  cfg.BatchChangeInterval.Duration,
+		GoogleZoneVisibility:       in.Visibility,
+		GoogleReadOnly:             in.ReadOnly,
+		GoogleForwardingZonePolicy: in.ForwardingZonePolicy,
+	}
+	if len(in.Zones) > 0 {
+		pc.Zones = in.Zones
+	}
+	for zone, batch := range in.ZoneBatchChanges {
+		pc.GoogleZoneBatchChanges = append(pc.GoogleZoneBatchChanges, zone+"="+batch)
+	}
+	sort.Strings(pc.GoogleZoneBatchChanges)
+	return pc
+}
+
+// restartRequired returns true if next changes the settings not applied by a
+// reload - the server and logging settings.
+func (cfg *config) restartRequired(next *config) bool {
+	return cfg.ListenAddress != next.ListenAddress ||
+		!reflect.DeepEqual(cfg.Listeners, next.Listeners) ||
+		cfg.ReadTimeout != next.ReadTimeout ||
+		cfg.WriteTimeout != next.WriteTimeout ||
+		cfg.ShutdownTimeout != next.ShutdownTimeout ||
+		cfg.MetricsAddress != next.MetricsAddress ||
+		cfg.DebugEndpoints != next.DebugEndpoints ||
+		cfg.LogFormat != next.LogFormat ||
+		cfg.LogLevel != next.LogLevel ||
+		cfg.LogLevels != next.LogLevels
+}
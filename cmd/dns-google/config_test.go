@@ -0,0 +1,216 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfigDefaults(t *testing.T) {
+	cfg, err := parseConfig(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, cfg.BatchChangeSize)
+	assert.Equal(t, time.Second, cfg.BatchChangeInterval.Duration)
+	assert.Equal(t, ":8080", cfg.ListenAddress)
+	assert.Equal(t, ":7979", cfg.MetricsAddress)
+	assert.Equal(t, 30*time.Second, cfg.ShutdownTimeout.Duration)
+	assert.False(t, cfg.DryRun)
+	assert.Empty(t, cfg.Zones)
+	assert.Nil(t, cfg.providerConfig(&cfg.instance).Zones, "the zones are listed")
+	assert.Equal(t, map[string]*instance{"": &cfg.instance}, cfg.instances())
+}
+
+func TestParseConfigLayers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+project: file-project
+zones:
+  my-zone: example.com.
+domainFilter: [example.com]
+visibility: private
+batchChangeSize: 10
+batchChangeInterval: 2s
+dryRun: true
+listenAddress: :9090
+logFormat: gcp
+`), 0o600))
+	t.Setenv("DNS_GOOGLE_GOOGLE_PROJECT", "env-project")
+	t.Setenv("DNS_GOOGLE_LISTEN_ADDRESS", ":9091")
+
+	cfg, err := parseConfig([]string{"--config", path, "--listen-address=:9092", "--google-batch-change-size=20"})
+	require.NoError(t, err)
+	assert.Equal(t, "env-project", cfg.Project)
+	assert.Equal(t, map[string]string{"my-zone": "example.com."}, cfg.Zones)
+	assert.Equal(t, []string{"example.com"}, cfg.DomainFilter)
+	assert.Equal(t, "private", cfg.Visibility)
+	assert.Equal(t, 20, cfg.BatchChangeSize)
+	assert.Equal(t, 2*time.Second, cfg.BatchChangeInterval.Duration)
+	assert.True(t, cfg.DryRun)
+	assert.Equal(t, ":9092", cfg.ListenAddress)
+	assert.Equal(t, "gcp", cfg.LogFormat)
+
+	pc := cfg.providerConfig(&cfg.instance)
+	assert.Equal(t, "env-project", pc.GoogleProject)
+	assert.Equal(t, 20, pc.GoogleBatchChangeSize)
+	assert.Equal(t, cfg.Zones, pc.Zones)
+
+	// The zone flags replace the zones of the file.
+	t.Setenv("DNS_GOOGLE_CONFIG", path)
+	cfg, err = parseConfig([]string{"--zone=other-zone=example.org.", "--no-dry-run"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"other-zone": "example.org."}, cfg.Zones)
+	assert.False(t, cfg.DryRun)
+}
+
+func TestParseConfigInstances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+batchChangeSize: 10
+instances:
+  prod:
+    project: prod-project
+    zones:
+      prod-zone: example.com.
+      prod-pods: pods.example.com.
+    zoneBatchChanges:
+      prod-pods: 100/5s
+  dev:
+    project: dev-project
+    visibility: private
+    readOnly: true
+`), 0o600))
+
+	cfg, err := parseConfig([]string{"--config", path, "--dry-run"})
+	require.NoError(t, err)
+	instances := cfg.instances()
+	require.Len(t, instances, 2, "the provider of the flags is not served")
+
+	pc := cfg.providerConfig(instances["/prod"])
+	assert.Equal(t, "prod-project", pc.GoogleProject)
+	assert.Equal(t, map[string]string{"prod-zone": "example.com.", "prod-pods": "pods.example.com."}, pc.Zones)
+	assert.Equal(t, 10, pc.GoogleBatchChangeSize)
+	assert.Equal(t, []string{"prod-pods=100/5s"}, pc.GoogleZoneBatchChanges)
+
+	pc = cfg.providerConfig(instances["/dev"])
+	assert.Equal(t, "dev-project", pc.GoogleProject)
+	assert.Equal(t, "private", pc.GoogleZoneVisibility)
+	assert.True(t, pc.GoogleReadOnly)
+	assert.Nil(t, pc.Zones)
+	assert.Nil(t, pc.GoogleZoneBatchChanges)
+}
+
+func TestParseConfigListeners(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+listeners:
+- address: 127.0.0.1:8080
+- address: :8443
+  certFile: /etc/tls/tls.crt
+  keyFile: /etc/tls/tls.key
+  clientCAFile: /etc/tls/ca.crt
+  prefixes: [/prod]
+`), 0o600))
+
+	cfg, err := parseConfig([]string{"--config", path})
+	require.NoError(t, err)
+	require.Len(t, cfg.Listeners, 2)
+	assert.Equal(t, "127.0.0.1:8080", cfg.Listeners[0].Address)
+	assert.Equal(t, "/etc/tls/ca.crt", cfg.Listeners[1].ClientCAFile)
+	assert.Equal(t, []string{"/prod"}, cfg.Listeners[1].Prefixes)
+
+	next := *cfg
+	next.Listeners = cfg.Listeners[:1]
+	assert.True(t, cfg.restartRequired(&next))
+}
+
+func TestParseConfigAuthorization(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+authorization:
+- prefix: /team-a
+  readWrite: [spiffe://example.com/ns/team-a/sa/external-dns]
+  readOnly: ["*"]
+`), 0o600))
+
+	cfg, err := parseConfig([]string{"--config", path})
+	require.NoError(t, err)
+	require.Len(t, cfg.Authorization, 1)
+	assert.Equal(t, "/team-a", cfg.Authorization[0].Prefix)
+	assert.Equal(t, []string{"*"}, cfg.Authorization[0].ReadOnly)
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+authorization:
+- prefix: team-a/
+  readOnly: ["*"]
+`), 0o600))
+	_, err = parseConfig([]string{"--config", path})
+	assert.Error(t, err)
+}
+
+func TestRestartRequired(t *testing.T) {
+	cfg, err := parseConfig([]string{"--google-project=a", "--zone=z=example.com"})
+	require.NoError(t, err)
+
+	next, err := parseConfig([]string{"--google-project=b", "--zone=y=example.org", "--dry-run"})
+	require.NoError(t, err)
+	assert.False(t, cfg.restartRequired(next), "the providers are reloaded")
+
+	next, err = parseConfig([]string{"--google-project=a", "--listen-address=:8081"})
+	require.NoError(t, err)
+	assert.True(t, cfg.restartRequired(next))
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("projet: typo\n"), 0o600))
+	badName := filepath.Join(dir, "name.yaml")
+	require.NoError(t, os.WriteFile(badName, []byte("instances:\n  Prod/1:\n    project: p\n"), 0o600))
+	badVisibility := filepath.Join(dir, "visibility.yaml")
+	require.NoError(t, os.WriteFile(badVisibility, []byte("instances:\n  prod:\n    visibility: internal\n"), 0o600))
+	badPolicy := filepath.Join(dir, "policy.yaml")
+	require.NoError(t, os.WriteFile(badPolicy, []byte("instances:\n  prod:\n    forwardingZonePolicy: ignore\n"), 0o600))
+	badListener := filepath.Join(dir, "listener.yaml")
+	require.NoError(t, os.WriteFile(badListener, []byte("listeners:\n- address: :8443\n  clientCAFile: ca.crt\n"), 0o600))
+	for _, args := range [][]string{
+		{"--config=" + path},
+		{"--config=" + badName},
+		{"--config=" + badVisibility},
+		{"--config=" + badPolicy},
+		{"--config=" + badListener},
+		{"--config=" + path + ".missing"},
+		{"--google-zone-visibility=internal"},
+		{"--forwarding-zone-policy=ignore"},
+		{"--zone=my-zone"},
+	} {
+		_, err := parseConfig(args)
+		assert.Error(t, err, args)
+	}
+}
+
+func TestConfigPath(t *testing.T) {
+	t.Setenv("DNS_GOOGLE_CONFIG", "env.yaml")
+	assert.Equal(t, "a.yaml", configPath([]string{"--dry-run", "--config=a.yaml"}))
+	assert.Equal(t, "b.yaml", configPath([]string{"--config", "b.yaml"}))
+	assert.Equal(t, "env.yaml", configPath([]string{"--", "--config=c.yaml"}))
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// edns-lite is an ExternalDNS binary with the providers and sources selected
+// by build tags:
+//
+//	go build -tags google,istio ./cmd/edns-lite
+//
+// The tags are google and cloudflare for the providers, which compile in the
+// provider SDKs, and kubernetes (service, ingress, node), pods and istio
+// (istio-gateway, istio-virtualservice, istio-se) for the sources, which only
+// enable them: the source package is always linked in. The inmemory and
+// webhook providers, and the empty, plugin and file sources, are always
+// enabled.
+//
+// edns-lite takes the flags of external-dns. With --webhook-server it serves
+// the provider with the webhook API, for an external-dns or ednsctl client:
+//
+//	edns-lite --webhook-server --provider=google --google-project=my-project --source=empty
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"sigs.k8s.io/external-dns/controller"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/pkg/apis/externaldns/validation"
+	"sigs.k8s.io/external-dns/pkg/debug"
+	"sigs.k8s.io/external-dns/pkg/logging"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	webhookapi "sigs.k8s.io/external-dns/provider/webhook/api"
+	"sigs.k8s.io/external-dns/registry"
+	"sigs.k8s.io/external-dns/source"
+)
+
+// providerFunc returns the provider of the configuration.
+type providerFunc func(ctx context.Context, cfg *externaldns.Config, domainFilter endpoint.DomainFilter) (provider.Provider, error)
+
+var (
+	// providers are the providers compiled in, registered by the files of
+	// their build tags.
+	providers = map[string]providerFunc{}
+	// sources are the names of the sources enabled by the build tags. The
+	// source package is linked in whole, the tags only allow the names.
+	sources = map[string]bool{"empty": true, "plugin": true, "file": true, "docker": true, "consul": true, "nomad": true}
+)
+
+func main() {
+	cfg := externaldns.NewConfig()
+	if err := cfg.ParseFlags(os.Args[1:]); err != nil {
+		log.Fatalf("flag parsing error: %v", err)
+	}
+	if _, err := logging.Setup(os.Stderr, cfg.LogFormat, cfg.LogLevel, cfg.LogLevels); err != nil {
+		log.Fatalf("failed to configure logging: %v", err)
+	}
+	if err := validation.ValidateConfig(cfg); err != nil {
+		log.Fatalf("config validation failed: %v", err)
+	}
+	if err := checkCompiled(cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+		<-signals
+		log.Info("Received SIGTERM. Terminating...")
+		cancel()
+	}()
+
+	// RegexDomainFilter overrides DomainFilter
+	domainFilter := endpoint.NewDomainFilterWithExclusions(cfg.DomainFilter, cfg.ExcludeDomains)
+	if cfg.RegexDomainFilter.String() != "" {
+		domainFilter = endpoint.NewRegexDomainFilter(cfg.RegexDomainFilter, cfg.RegexDomainExclusion)
+	}
+	p, err := providers[cfg.Provider](ctx, cfg, domainFilter)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.Validate {
+		// The sources and the registry are created, the errors are fatal.
+		if _, err := newController(ctx, cfg, p, domainFilter); err != nil {
+			log.Fatal(err)
+		}
+		data, err := cfg.Effective()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(string(data))
+		return
+	}
+
+	if cfg.WebhookServer {
+		webhookAddr := os.Getenv("EXTERNAL_DNS_WEBHOOK_ADDR")
+		if webhookAddr == "" {
+			webhookAddr = ":8080"
+		}
+		if err := webhookapi.ServeHTTPApi(ctx, p, nil, cfg.WebhookProviderReadTimeout, cfg.WebhookProviderWriteTimeout, cfg.ShutdownTimeout, webhookAddr, webhookapi.Handlers{DoH: cfg.WebhookServerDoH, CertManager: cfg.WebhookServerCertManager, Cutover: cfg.WebhookServerCutover}); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	ctrl, err := newController(ctx, cfg, p, domainFilter)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.Once {
+		// 0 if in sync or the changes are applied, 2 if there are changes in
+		// dry run, 1 on error.
+		os.Exit(ctrl.RunOnceExitCode(ctx))
+	}
+
+	if cfg.MetricsAddress != "" {
+		http.Handle("/metrics", promhttp.Handler())
+		http.Handle("/dashboard", ctrl)
+		if cfg.DebugEndpoints {
+			d := &debug.Handler{}
+			d.AddState("controller", func() any { return ctrl.DebugState() })
+			d.Register(http.DefaultServeMux)
+		}
+		go func() { log.Fatal(http.ListenAndServe(cfg.MetricsAddress, nil)) }()
+	}
+	if cfg.UpdateEvents {
+		ctrl.Source.AddEventHandler(ctx, func() { ctrl.ScheduleEvent(time.Now()) })
+	}
+	ctrl.ScheduleRunOnce(time.Now())
+	if err := ctrl.RunUntilShutdown(ctx, cfg.FinalSync, cfg.ShutdownTimeout); err != nil {
+		log.Fatalf("Failed to do the final sync: %v", err)
+	}
+}
+
+// checkCompiled returns an error if the provider of the configuration is not
+// compiled in, or a source is not enabled.
+func checkCompiled(cfg *externaldns.Config) error {
+	if providers[cfg.Provider] == nil {
+		return fmt.Errorf("provider %s is not compiled in, the providers are: %v", cfg.Provider, names(providers))
+	}
+	for _, s := range cfg.Sources {
+		if !sources[s] {
+			return fmt.Errorf("source %s is not enabled, the sources are: %v", s, names(sources))
+		}
+	}
+	return nil
+}
+
+func names[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for name := range m {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// newController returns the controller syncing the sources to the provider,
+// with the TXT or noop registry.
+func newController(ctx context.Context, cfg *externaldns.Config, p provider.Provider, domainFilter endpoint.DomainFilter) (*controller.Controller, error) {
+	// The label filter is already validated.
+	cfg.Config.LabelFilter, _ = labels.Parse(cfg.LabelFilter)
+	srcs, err := source.ByNames(ctx, &source.SingletonClientGenerator{
+		KubeConfig:   cfg.KubeConfig,
+		APIServerURL: cfg.APIServerURL,
+		// If update events are enabled, disable timeout.
+		RequestTimeout: func() time.Duration {
+			if cfg.UpdateEvents {
+				return 0
+			}
+			return cfg.RequestTimeout
+		}(),
+	}, cfg.Sources, &cfg.Config)
+	if err != nil {
+		return nil, err
+	}
+	targetFilter := endpoint.NewTargetNetFilterWithExclusions(cfg.TargetNetFilter, cfg.ExcludeTargetNets)
+	src := source.NewTargetFilterSource(source.NewDedupSource(source.NewMultiSource(srcs, cfg.DefaultTargets, source.WithSourceNames(cfg.Sources), source.WithMergePolicy(cfg.SourceMergePolicy))), targetFilter)
+
+	var r registry.Registry
+	switch cfg.Registry {
+	case "noop":
+		r, err = registry.NewNoopRegistry(p)
+	case "txt":
+		r, err = registry.NewTXTRegistry(p, cfg.TXTPrefix, cfg.TXTSuffix, cfg.TXTOwnerID, cfg.TXTCacheInterval, cfg.TXTWildcardReplacement, cfg.ManagedDNSRecordTypes, cfg.ExcludeDNSRecordTypes, cfg.TXTEncryptEnabled, []byte(cfg.TXTEncryptAESKey))
+	default:
+		return nil, fmt.Errorf("registry %s is not supported by edns-lite, use txt or noop", cfg.Registry)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	policy, ok := plan.Policies[cfg.Policy]
+	if !ok {
+		return nil, fmt.Errorf("unknown policy: %s", cfg.Policy)
+	}
+	var schedule controller.Schedule
+	if cfg.Schedule != "" {
+		if schedule, err = controller.ParseSchedule(cfg.Schedule); err != nil {
+			return nil, err
+		}
+	}
+	return &controller.Controller{
+		Source:               src,
+		Registry:             r,
+		Policy:               policy,
+		Interval:             cfg.Interval,
+		Schedule:             schedule,
+		DomainFilter:         domainFilter,
+		ManagedRecordTypes:   cfg.ManagedDNSRecordTypes,
+		ExcludeRecordTypes:   cfg.ExcludeDNSRecordTypes,
+		MinEventSyncInterval: cfg.MinEventSyncInterval,
+		DryRun:               cfg.DryRun,
+	}, nil
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
+)
+
+func TestCheckCompiled(t *testing.T) {
+	for _, tc := range []struct {
+		provider string
+		sources  []string
+		err      string
+	}{
+		{provider: "inmemory", sources: []string{"empty"}},
+		{provider: "webhook", sources: []string{"plugin", "empty"}},
+		{provider: "aws", sources: []string{"empty"}, err: "provider aws is not compiled in"},
+		{provider: "inmemory", sources: []string{"empty", "crd"}, err: "source crd is not enabled"},
+	} {
+		err := checkCompiled(&externaldns.Config{Provider: tc.provider, Sources: tc.sources})
+		if tc.err == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.ErrorContains(t, err, tc.err)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c"}, names(map[string]bool{"c": true, "a": true, "b": false}))
+	assert.Empty(t, names(map[string]int{}))
+}
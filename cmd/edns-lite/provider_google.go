@@ -0,0 +1,37 @@
+//go:build google
+
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/provider/google"
+)
+
+func init() {
+	providers["google"] = func(ctx context.Context, cfg *externaldns.Config, domainFilter endpoint.DomainFilter) (provider.Provider, error) {
+		zoneIDFilter := provider.NewZoneIDFilter(cfg.ZoneIDFilter)
+		return google.NewGoogleProvider(ctx, &cfg.ProviderConfig, &domainFilter, &zoneIDFilter, cfg.DryRun)
+	}
+	sources["gce"] = true
+	sources["gce-forwarding-rule"] = true
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmin(t *testing.T) {
+	var requests, authorizations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.String())
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		if r.URL.Path == "/admin/fail" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"paused":true}`))
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	ctx := context.Background()
+	require.NoError(t, admin(ctx, server.URL, "secret", "pause", nil, &out))
+	require.NoError(t, admin(ctx, server.URL+"/", "", "state", nil, &out))
+	require.NoError(t, admin(ctx, server.URL, "", "loglevel", nil, &out))
+	require.NoError(t, admin(ctx, server.URL, "", "loglevel", url.Values{"key": {"provider"}, "level": {"debug"}}, &out))
+	require.NoError(t, admin(ctx, server.URL, "", "changesets", nil, &out))
+	require.NoError(t, admin(ctx, server.URL, "", "rollback", url.Values{"id": {"2024/06/01/a.json"}}, &out))
+	assert.Equal(t, []string{
+		"POST /admin/pause",
+		"GET /admin/state",
+		"GET /admin/loglevel",
+		"POST /admin/loglevel?key=provider&level=debug",
+		"GET /admin/changesets",
+		"POST /admin/rollback?id=2024%2F06%2F01%2Fa.json",
+	}, requests)
+	assert.Equal(t, "Bearer secret", authorizations[0])
+	assert.Empty(t, authorizations[1])
+	assert.Contains(t, out.String(), `{"paused":true}`)
+
+	err := admin(ctx, server.URL, "", "fail", nil, &out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// ednsctl is a command line front end for a running webhook provider - for
+// example 'external-dns --webhook-server' - to list the records, diff them
+// against a records file or live sources, and apply the changes.
+//
+//	ednsctl records -o yaml > records.yaml
+//	ednsctl diff --file records.yaml
+//	ednsctl apply --file records.yaml
+//	ednsctl diff --source service --source ingress
+//	ednsctl audit --location gs://bucket/audit --since 48h
+//	ednsctl admin pause
+//	ednsctl admin rollback --id "$(ednsctl admin changesets | jq -r '.[0].id')"
+//
+// Like 'external-dns --once --dry-run', diff exits with 2 if there are
+// changes, for CI pipelines.
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"sigs.k8s.io/external-dns/controller"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/audit"
+	"sigs.k8s.io/external-dns/pkg/gitops"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/webhook"
+	"sigs.k8s.io/external-dns/registry"
+	"sigs.k8s.io/external-dns/source"
+)
+
+type config struct {
+	Server             string
+	OwnerID            string
+	TXTPrefix          string
+	Policy             string
+	DomainFilter       []string
+	ManagedRecordTypes []string
+	KubeConfig         string
+
+	Output  string
+	File    string
+	Sources []string
+	Yes     bool
+
+	AuditLocation string
+	AuditSince    time.Duration
+	AuditName     string
+
+	AdminServer    string
+	AdminTokenFile string
+	LogKey         string
+	LogLevel       string
+	ChangeSet      string
+}
+
+// fileSource returns the records read from a file.
+type fileSource struct {
+	records []*endpoint.Endpoint
+}
+
+func (s *fileSource) Endpoints(context.Context) ([]*endpoint.Endpoint, error) {
+	return s.records, nil
+}
+
+func (s *fileSource) AddEventHandler(context.Context, func()) {}
+
+func main() {
+	cfg := &config{}
+	app := kingpin.New("ednsctl", "Query, diff and apply DNS records using a running webhook provider.")
+	app.DefaultEnvars()
+	app.Flag("server", "The URL of the webhook provider").Default("http://localhost:8888").StringVar(&cfg.Server)
+	app.Flag("txt-owner-id", "Use the TXT registry with this owner ID - only records owned by it are updated or deleted (default: no registry)").StringVar(&cfg.OwnerID)
+	app.Flag("txt-prefix", "The prefix of the TXT registry records").StringVar(&cfg.TXTPrefix)
+	app.Flag("policy", "Modify how DNS records are synchronized (options: sync, upsert-only, create-only)").Default("upsert-only").EnumVar(&cfg.Policy, "sync", "upsert-only", "create-only")
+	app.Flag("domain-filter", "Limit the changes to this domain; specify multiple times for multiple domains").StringsVar(&cfg.DomainFilter)
+	app.Flag("managed-record-types", "Record types to manage; specify multiple times to include many").Default(endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME).StringsVar(&cfg.ManagedRecordTypes)
+	app.Flag("kubeconfig", "Kubeconfig for the live sources (default: in-cluster or $KUBECONFIG)").StringVar(&cfg.KubeConfig)
+
+	recordsCmd := app.Command("records", "List the records of the provider.")
+	recordsCmd.Flag("output", "Output format (options: table, yaml, json)").Short('o').Default("table").EnumVar(&cfg.Output, "table", "yaml", "json")
+
+	diffCmd := app.Command("diff", "Show the changes to move the provider records to the desired records.")
+	applyCmd := app.Command("apply", "Apply the changes to move the provider records to the desired records.")
+	applyCmd.Flag("yes", "Apply without confirmation").Short('y').BoolVar(&cfg.Yes)
+	for _, cmd := range []*kingpin.CmdClause{diffCmd, applyCmd} {
+		cmd.Flag("file", "The desired records, as YAML or JSON ('-' for stdin)").StringVar(&cfg.File)
+		cmd.Flag("source", "Use the endpoints of a live source as the desired records; specify multiple times for multiple sources").StringsVar(&cfg.Sources)
+	}
+
+	auditCmd := app.Command("audit", "Show the changes recorded in an audit trail.")
+	auditCmd.Flag("location", "The audit trail directory, or gs://BUCKET/PREFIX or s3://BUCKET/PREFIX").Required().StringVar(&cfg.AuditLocation)
+	auditCmd.Flag("since", "Show the changes recorded in this duration").Default("24h").DurationVar(&cfg.AuditSince)
+	auditCmd.Flag("name", "Show only the changes of this DNS name").StringVar(&cfg.AuditName)
+
+	adminCmd := app.Command("admin", "Operate a running controller with its admin API - see 'external-dns --admin-api'.")
+	adminCmd.Flag("admin-server", "The URL of the admin API of the controller - see 'external-dns --admin-api-address'").Default("http://localhost:7980").StringVar(&cfg.AdminServer)
+	adminCmd.Flag("admin-token-file", "A file with the bearer token of the admin API - see 'external-dns --admin-api-token-file' (optional)").StringVar(&cfg.AdminTokenFile)
+	adminCmd.Command("sync", "Sync now.")
+	adminCmd.Command("pause", "Pause the applies: the syncs keep their changes pending.")
+	adminCmd.Command("resume", "Resume the applies.")
+	adminCmd.Command("flush", "Flush the caches of the records.")
+	adminCmd.Command("state", "Show the desired endpoints, the records and the pending changes.")
+	logLevelCmd := adminCmd.Command("loglevel", "Show the log levels, or set the level of a logger with --key and --level.")
+	logLevelCmd.Flag("key", "The logger, for example provider").StringVar(&cfg.LogKey)
+	logLevelCmd.Flag("level", "The level of the logger").StringVar(&cfg.LogLevel)
+	adminCmd.Command("changesets", "List the change sets of the audit trail which can be rolled back, newest first - see 'external-dns --audit-location'.")
+	rollbackCmd := adminCmd.Command("rollback", "Roll back a change set, applying its inverse, and pause the applies.")
+	rollbackCmd.Flag("id", "The ID of the change set, from changesets").Required().StringVar(&cfg.ChangeSet)
+
+	command := kingpin.MustParse(app.Parse(os.Args[1:]))
+	ctx := context.Background()
+
+	if op, ok := strings.CutPrefix(command, adminCmd.FullCommand()+" "); ok {
+		query := url.Values{}
+		if cfg.LogKey != "" || cfg.LogLevel != "" {
+			query.Set("key", cfg.LogKey)
+			query.Set("level", cfg.LogLevel)
+		}
+		if cfg.ChangeSet != "" {
+			query.Set("id", cfg.ChangeSet)
+		}
+		var token string
+		if cfg.AdminTokenFile != "" {
+			data, err := os.ReadFile(cfg.AdminTokenFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			token = strings.TrimSpace(string(data))
+		}
+		if err := admin(ctx, cfg.AdminServer, token, op, query, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if command == auditCmd.FullCommand() {
+		if err := showAudit(ctx, cfg); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	p, err := webhook.NewWebhookProvider(cfg.Server)
+	if err != nil {
+		log.Fatalf("Failed to connect to %s: %v", cfg.Server, err)
+	}
+	var r registry.Registry
+	if cfg.OwnerID != "" {
+		r, err = registry.NewTXTRegistry(p, cfg.TXTPrefix, "", cfg.OwnerID, 0, "", cfg.ManagedRecordTypes, nil, false, nil)
+	} else {
+		r, err = registry.NewNoopRegistry(p)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if command == recordsCmd.FullCommand() {
+		records, err := r.Records(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := gitops.WriteRecords(os.Stdout, records, cfg.Output); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	src, err := desiredSource(ctx, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctrl := &controller.Controller{
+		Source:             src,
+		Registry:           r,
+		Policy:             plan.Policies[cfg.Policy],
+		DomainFilter:       endpoint.NewDomainFilter(cfg.DomainFilter),
+		ManagedRecordTypes: cfg.ManagedRecordTypes,
+	}
+	changes, err := ctrl.DetectDrift(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	n := gitops.CountChanges(changes)
+	if n == 0 {
+		fmt.Println("No changes")
+		return
+	}
+	gitops.WriteChanges(os.Stdout, changes)
+	if command == diffCmd.FullCommand() {
+		os.Exit(controller.ExitDrift)
+	}
+
+	if !cfg.Yes && !confirm(os.Stdin, os.Stdout, fmt.Sprintf("Apply %d changes to %s?", n, cfg.Server)) {
+		fmt.Println("Canceled")
+		return
+	}
+	if err := r.ApplyChanges(ctx, changes); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Applied %d changes\n", n)
+}
+
+// showAudit prints the audit entries, oldest first.
+func showAudit(ctx context.Context, cfg *config) error {
+	store, err := audit.Open(ctx, cfg.AuditLocation)
+	if err != nil {
+		return err
+	}
+	entries, err := audit.Query(ctx, store, time.Now().Add(-cfg.AuditSince), time.Time{}, cfg.AuditName)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		fmt.Printf("%s %s %s\n", e.Time.Format(time.RFC3339), e.Actor, strings.Join(e.Resources, ","))
+		if e.Error != "" {
+			fmt.Printf("  failed: %s\n", e.Error)
+		}
+		if e.Changes != nil {
+			gitops.WriteChanges(os.Stdout, e.Changes)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// desiredSource returns the source of the desired records - the file or the
+// live sources.
+func desiredSource(ctx context.Context, cfg *config) (source.Source, error) {
+	switch {
+	case cfg.File != "" && len(cfg.Sources) > 0:
+		return nil, fmt.Errorf("--file and --source are exclusive")
+	case cfg.File != "":
+		records, err := gitops.ReadRecords(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		return &fileSource{records: records}, nil
+	case len(cfg.Sources) > 0:
+		sources, err := source.ByNames(ctx, &source.SingletonClientGenerator{
+			KubeConfig:     cfg.KubeConfig,
+			RequestTimeout: 30 * time.Second,
+		}, cfg.Sources, &source.Config{LabelFilter: labels.Everything()})
+		if err != nil {
+			return nil, err
+		}
+		return source.NewDedupSource(source.NewMultiSource(sources, nil)), nil
+	}
+	return nil, fmt.Errorf("one of --file or --source is required")
+}
+
+// confirm asks for confirmation on the terminal.
+func confirm(in io.Reader, out io.Writer, prompt string) bool {
+	fmt.Fprintf(out, "%s [y/N]: ", prompt)
+	line, _ := bufio.NewReader(in).ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
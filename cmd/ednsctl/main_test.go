@@ -0,0 +1,34 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirm(t *testing.T) {
+	var out bytes.Buffer
+	assert.True(t, confirm(strings.NewReader("y\n"), &out, "Apply?"))
+	assert.True(t, confirm(strings.NewReader("YES\n"), &out, "Apply?"))
+	assert.False(t, confirm(strings.NewReader("\n"), &out, "Apply?"))
+	assert.False(t, confirm(strings.NewReader(""), &out, "Apply?"))
+	assert.Contains(t, out.String(), "Apply? [y/N]: ")
+}
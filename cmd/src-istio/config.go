@@ -0,0 +1,318 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/external-dns/controller"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/pkg/ipam"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+	"sigs.k8s.io/external-dns/source"
+)
+
+// configEnvar is the environment variable of the --config flag.
+const configEnvar = "SRC_ISTIO_CONFIG"
+
+// config is the configuration of src-istio, read from the optional config
+// file, then the SRC_ISTIO_* environment variables, then the flags.
+type config struct {
+	KubeConfig     string          `json:"kubeConfig,omitempty"`
+	APIServerURL   string          `json:"apiServerURL,omitempty"`
+	RequestTimeout metav1.Duration `json:"requestTimeout,omitempty"`
+
+	// The ServiceEntrySourceConfig fields.
+	MeshExternalNamespace string   `json:"meshExternalNamespace,omitempty"`
+	MeshInternalDomain    string   `json:"meshInternalDomain,omitempty"`
+	EgressGatewayVIP      []string `json:"egressGatewayVIP,omitempty"`
+	HttpVIP               string   `json:"httpVIP,omitempty"`
+	VIPPool               []string `json:"vipPool,omitempty"`
+	VIPLeaseFile          string   `json:"vipLeaseFile,omitempty"`
+	UpdateServiceEntry    bool     `json:"updateServiceEntry,omitempty"`
+	ReverseNamespace      string   `json:"reverseNamespace,omitempty"`
+	ReverseDomainFilter   []string `json:"reverseDomainFilter,omitempty"`
+	ReverseLabelFilter    string   `json:"reverseLabelFilter,omitempty"`
+
+	Registry               string   `json:"registry,omitempty"`
+	TXTOwnerID             string   `json:"txtOwnerID,omitempty"`
+	TXTPrefix              string   `json:"txtPrefix,omitempty"`
+	TXTSuffix              string   `json:"txtSuffix,omitempty"`
+	TXTWildcardReplacement string   `json:"txtWildcardReplacement,omitempty"`
+	TXTFormats             []string `json:"txtFormats,omitempty"`
+	ManagedRecordTypes     []string `json:"managedRecordTypes,omitempty"`
+	DomainFilter           []string `json:"domainFilter,omitempty"`
+
+	Policy               string          `json:"policy,omitempty"`
+	Interval             metav1.Duration `json:"interval,omitempty"`
+	MinEventSyncInterval metav1.Duration `json:"minEventSyncInterval,omitempty"`
+	// EventDebounce postpones the syncs of the ServiceEntry events until no
+	// event was received for this duration, up to EventMaxDelay after the
+	// first one; disabled if zero.
+	EventDebounce metav1.Duration `json:"eventDebounce,omitempty"`
+	EventMaxDelay metav1.Duration `json:"eventMaxDelay,omitempty"`
+	// MinInterval is the interval after a sync applying changes, doubled
+	// after each sync without changes up to Interval; disabled if zero.
+	MinInterval metav1.Duration `json:"minInterval,omitempty"`
+	// Jitter is the fraction of the interval randomly added to each periodic
+	// sync, so that the clusters of a fleet don't sync at the same time.
+	Jitter float64 `json:"jitter,omitempty"`
+	Once   bool    `json:"once,omitempty"`
+	// DryRun prints the changes instead of applying them, and doesn't update
+	// the ServiceEntries.
+	DryRun bool `json:"dryRun,omitempty"`
+	// FinalSync runs a last sync on SIGTERM, after the sync in progress; both
+	// are canceled after ShutdownTimeout.
+	FinalSync       bool            `json:"finalSync,omitempty"`
+	ShutdownTimeout metav1.Duration `json:"shutdownTimeout,omitempty"`
+
+	// MetricsAddress serves the metrics, the dashboard and the health checks.
+	MetricsAddress string `json:"metricsAddress,omitempty"`
+	// DebugEndpoints serves /debug/pprof and /debug/state with the metrics.
+	DebugEndpoints bool `json:"debugEndpoints,omitempty"`
+	// MaxSyncAge fails the liveness check without a successful sync for
+	// this duration, 3 intervals if zero.
+	MaxSyncAge metav1.Duration `json:"maxSyncAge,omitempty"`
+
+	// ProviderURL is the webhook provider, like a dns-google server; the
+	// records are only logged by an in-memory provider if empty.
+	ProviderURL string `json:"providerURL,omitempty"`
+	// InMemoryZones are the zones of the in-memory provider, the DomainFilter
+	// if empty.
+	InMemoryZones []string `json:"inMemoryZones,omitempty"`
+	// InMemoryFile persists the records of the in-memory provider, for a
+	// local development loop surviving restarts.
+	InMemoryFile string `json:"inMemoryFile,omitempty"`
+
+	LogFormat string `json:"logFormat,omitempty"`
+	LogLevel  string `json:"logLevel,omitempty"`
+	LogLevels string `json:"logLevels,omitempty"`
+}
+
+var defaultConfig = config{
+	RequestTimeout:         metav1.Duration{Duration: 30 * time.Second},
+	Registry:               "txt",
+	TXTOwnerID:             "k8s",
+	TXTPrefix:              "k8s-%{record_type}-",
+	TXTWildcardReplacement: "all",
+	ManagedRecordTypes:     []string{"A", "CNAME", "TXT", "SRV", "PTR", "CAA", "DS", "DNSKEY", "NAPTR", "TLSA", "URI"},
+	Policy:                 "sync",
+	// Using the informers - the interval is only a resync.
+	Interval:             metav1.Duration{Duration: time.Hour},
+	MinEventSyncInterval: metav1.Duration{Duration: 5 * time.Second},
+	Jitter:               0.1,
+	EventMaxDelay:        metav1.Duration{Duration: time.Minute},
+	ShutdownTimeout:      metav1.Duration{Duration: 30 * time.Second},
+	MetricsAddress:       ":7979",
+	LogFormat:            "text",
+	LogLevel:             "info",
+}
+
+// parseConfig returns the configuration of the args, layered over the
+// environment and the config file of --config or SRC_ISTIO_CONFIG.
+func parseConfig(args []string) (*config, error) {
+	defaults := defaultConfig
+	if path := configPath(args); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.UnmarshalStrict(data, &defaults); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	cfg := &config{}
+	if _, err := newApp(cfg, &defaults).Parse(args); err != nil {
+		return nil, err
+	}
+	if _, err := labels.Parse(cfg.ReverseLabelFilter); err != nil {
+		return nil, fmt.Errorf("invalid reverse label filter: %w", err)
+	}
+	if cfg.Jitter < 0 || cfg.Jitter > 1 {
+		return nil, fmt.Errorf("--jitter must be between 0 and 1, got %v", cfg.Jitter)
+	}
+	if cfg.Registry == "txt" && cfg.TXTOwnerID == "" {
+		return nil, errors.New("--txt-owner-id is required with the txt registry")
+	}
+	return cfg, nil
+}
+
+// newApp returns the application with the flags bound to cfg, with the
+// values of defaults.
+func newApp(cfg, defaults *config) *kingpin.Application {
+	app := kingpin.New("src-istio", "src-istio syncs the Istio ServiceEntries to a webhook DNS provider.\n\nThe flags are read from the config file of --config, replaced by the env vars - `--flag value` -> `SRC_ISTIO_FLAG=value`, then by the flags.")
+	app.Version(externaldns.Version)
+	app.DefaultEnvars()
+
+	// Not bound: the config file is read before parsing, by configPath.
+	app.Flag("config", "A YAML file with the configuration, like 'providerURL: http://localhost:8080' (optional)").String()
+
+	app.Flag("kubeconfig", "Retrieve target cluster configuration from a Kubernetes configuration file (default: auto-detect)").Default(defaults.KubeConfig).StringVar(&cfg.KubeConfig)
+	app.Flag("server", "The Kubernetes API server to connect to (default: auto-detect)").Default(defaults.APIServerURL).StringVar(&cfg.APIServerURL)
+	app.Flag("request-timeout", "Request timeout when calling Kubernetes APIs. 0s means no timeout").Default(defaults.RequestTimeout.Duration.String()).DurationVar(&cfg.RequestTimeout.Duration)
+
+	app.Flag("mesh-external-namespace", "The namespace of the MESH_EXTERNAL ServiceEntries; other namespaces are ignored (optional)").Default(defaults.MeshExternalNamespace).StringVar(&cfg.MeshExternalNamespace)
+	app.Flag("mesh-internal-domain", "The domain of the MESH_INTERNAL ServiceEntries, named NAME.NAMESPACE.DOMAIN (optional)").Default(defaults.MeshInternalDomain).StringVar(&cfg.MeshInternalDomain)
+	app.Flag("egress-gateway-vip", "The address of the egress gateway for the MESH_EXTERNAL ServiceEntries without addresses; specify multiple times for multiple addresses (optional)").Default(defaults.EgressGatewayVIP...).StringsVar(&cfg.EgressGatewayVIP)
+	app.Flag("http-vip", "The address of the MESH_INTERNAL ServiceEntries with HTTP ports and without addresses (optional)").Default(defaults.HttpVIP).StringVar(&cfg.HttpVIP)
+	app.Flag("vip-pool", "A CIDR of the addresses allocated to the MESH_INTERNAL ServiceEntries without addresses; specify multiple times for multiple CIDRs (optional)").Default(defaults.VIPPool...).StringsVar(&cfg.VIPPool)
+	app.Flag("vip-lease-file", "Persist the allocated addresses to this file (default: in memory)").Default(defaults.VIPLeaseFile).StringVar(&cfg.VIPLeaseFile)
+	app.Flag("update-service-entry", "Patch the allocated addresses into the ServiceEntries (default: disabled)").Default(strconv.FormatBool(defaults.UpdateServiceEntry)).BoolVar(&cfg.UpdateServiceEntry)
+	app.Flag("reverse-namespace", "Generate MESH_EXTERNAL ServiceEntries in this namespace from the provider records (optional)").Default(defaults.ReverseNamespace).StringVar(&cfg.ReverseNamespace)
+	app.Flag("reverse-domain-filter", "Limit the records used to generate ServiceEntries to this domain; specify multiple times for multiple domains (optional)").Default(defaults.ReverseDomainFilter...).StringsVar(&cfg.ReverseDomainFilter)
+	app.Flag("reverse-label-filter", "Limit the records used to generate ServiceEntries to the records with labels matching this selector (optional)").Default(defaults.ReverseLabelFilter).StringVar(&cfg.ReverseLabelFilter)
+
+	app.Flag("registry", "The registry implementation to use to keep track of DNS record ownership (default: txt, options: txt, noop)").Default(defaults.Registry).EnumVar(&cfg.Registry, "txt", "noop")
+	app.Flag("txt-owner-id", "When using the TXT registry, a name that identifies this instance of ExternalDNS").Default(defaults.TXTOwnerID).StringVar(&cfg.TXTOwnerID)
+	app.Flag("txt-prefix", "When using the TXT registry, a custom string that's prefixed to each ownership DNS record; may contain %{record_type}").Default(defaults.TXTPrefix).StringVar(&cfg.TXTPrefix)
+	app.Flag("txt-suffix", "When using the TXT registry, a custom string that's suffixed to the host portion of each ownership DNS record (optional)").Default(defaults.TXTSuffix).StringVar(&cfg.TXTSuffix)
+	app.Flag("txt-format", "When using the TXT registry, the format of the ownership records of a zone, as ZONE=FORMAT: affix - a record per record named with --txt-prefix or --txt-suffix, suffix or suffix:TEMPLATE - a record per record named with a suffix, -%{record_type} by default, or encoded - a record per name with the labels of all its records; specify multiple times for multiple zones (default: affix)").PlaceHolder("ZONE=FORMAT").Default(defaults.TXTFormats...).StringsVar(&cfg.TXTFormats)
+	app.Flag("txt-wildcard-replacement", "When using the TXT registry, a custom string that's used instead of an asterisk for TXT records corresponding to wildcard DNS records").Default(defaults.TXTWildcardReplacement).StringVar(&cfg.TXTWildcardReplacement)
+	app.Flag("managed-record-types", "Record types to manage; specify multiple times to include many options").Default(defaults.ManagedRecordTypes...).StringsVar(&cfg.ManagedRecordTypes)
+	app.Flag("domain-filter", "Limit the records to the domains matching this filter; specify multiple times for multiple domains (optional)").Default(defaults.DomainFilter...).StringsVar(&cfg.DomainFilter)
+
+	app.Flag("policy", "Modify how DNS records are synchronized between sources and providers (default: sync, options: sync, upsert-only, create-only)").Default(defaults.Policy).EnumVar(&cfg.Policy, "sync", "upsert-only", "create-only")
+	app.Flag("interval", "The interval between two consecutive synchronizations in duration format").Default(defaults.Interval.Duration.String()).DurationVar(&cfg.Interval.Duration)
+	app.Flag("min-event-sync-interval", "The minimum interval between two consecutive synchronizations triggered from the ServiceEntry events").Default(defaults.MinEventSyncInterval.Duration.String()).DurationVar(&cfg.MinEventSyncInterval.Duration)
+	app.Flag("event-debounce", "Postpone the synchronization triggered by the ServiceEntry events until no event was received for this duration, so that a burst of events triggers a single synchronization (default: disabled)").Default(defaults.EventDebounce.Duration.String()).DurationVar(&cfg.EventDebounce.Duration)
+	app.Flag("event-max-delay", "With --event-debounce, the maximum delay between the first event and the synchronization; 0s means no limit").Default(defaults.EventMaxDelay.Duration.String()).DurationVar(&cfg.EventMaxDelay.Duration)
+	app.Flag("min-interval", "The interval after a synchronization applying changes, doubled after each synchronization without changes up to --interval (default: disabled)").Default(defaults.MinInterval.Duration.String()).DurationVar(&cfg.MinInterval.Duration)
+	app.Flag("jitter", "The fraction of the interval, 0 to 1, randomly added to each periodic synchronization, so that the clusters of a fleet don't synchronize at the same time").Default(strconv.FormatFloat(defaults.Jitter, 'f', -1, 64)).Float64Var(&cfg.Jitter)
+	app.Flag("once", "When enabled, exits the synchronization loop after the first iteration (default: disabled)").Default(strconv.FormatBool(defaults.Once)).BoolVar(&cfg.Once)
+	app.Flag("dry-run", "When enabled, prints the changes instead of applying them; with --once, exits with 2 if there are changes (default: disabled)").Default(strconv.FormatBool(defaults.DryRun)).BoolVar(&cfg.DryRun)
+	app.Flag("final-sync", "When enabled, runs a last synchronization on SIGTERM, to apply the changes of the pending events (default: disabled)").Default(strconv.FormatBool(defaults.FinalSync)).BoolVar(&cfg.FinalSync)
+	app.Flag("shutdown-timeout", "On SIGTERM, the time to wait for the synchronization in progress and the final synchronization").Default(defaults.ShutdownTimeout.Duration.String()).DurationVar(&cfg.ShutdownTimeout.Duration)
+	app.Flag("metrics-address", "Specify where to serve the metrics, the dashboard and the health checks (/healthz, /readyz)").Default(defaults.MetricsAddress).StringVar(&cfg.MetricsAddress)
+	app.Flag("debug-endpoints", "When enabled, serves the runtime profiles on /debug/pprof and the state of the controller and the ServiceEntries on /debug/state with the metrics (default: disabled)").Default(strconv.FormatBool(defaults.DebugEndpoints)).BoolVar(&cfg.DebugEndpoints)
+	app.Flag("max-sync-age", "Fail the liveness check without a successful sync for this duration (default: 3 intervals)").Default(defaults.MaxSyncAge.Duration.String()).DurationVar(&cfg.MaxSyncAge.Duration)
+	app.Flag("provider-url", "The URL of the webhook provider, like a dns-google server (default: log the records with an in-memory provider)").Default(defaults.ProviderURL).StringVar(&cfg.ProviderURL)
+	app.Flag("inmemory-zone", "Without --provider-url, a zone of the in-memory provider; specify multiple times for multiple zones (default: the --domain-filter domains)").Default(defaults.InMemoryZones...).StringsVar(&cfg.InMemoryZones)
+	app.Flag("inmemory-file", "Without --provider-url, load the records of the in-memory provider from this file and save them after each change (default: in memory)").Default(defaults.InMemoryFile).StringVar(&cfg.InMemoryFile)
+
+	app.Flag("log-format", "The format in which log messages are printed: gcp is JSON with the Cloud Logging severity, trace and labels (default: text, options: text, json, gcp)").Default(defaults.LogFormat).EnumVar(&cfg.LogFormat, "text", "json", "gcp")
+	app.Flag("log-level", "Set the level of logging. (default: info, options: panic, debug, info, warning, error, fatal)").Default(defaults.LogLevel).EnumVar(&cfg.LogLevel, "panic", "debug", "info", "warning", "error", "fatal")
+	app.Flag("log-levels", "Set the level of components or zones, like 'source/istio-se=debug' (optional)").Default(defaults.LogLevels).StringVar(&cfg.LogLevels)
+	return app
+}
+
+// configPath returns the value of the --config flag, or SRC_ISTIO_CONFIG.
+func configPath(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if v, ok := strings.CutPrefix(arg, "--config="); ok {
+			return v
+		}
+		if arg == "--config" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return os.Getenv(configEnvar)
+}
+
+// settings returns the settings of the controller, applied by a reload.
+func (cfg *config) settings() controller.Settings {
+	return controller.Settings{
+		Policy:             plan.Policies[cfg.Policy],
+		DomainFilter:       endpoint.NewDomainFilter(cfg.DomainFilter),
+		ManagedRecordTypes: cfg.ManagedRecordTypes,
+	}
+}
+
+// selectors returns the selectors of the ServiceEntry source, applied by a
+// reload.
+func (cfg *config) selectors() source.ServiceEntrySelectors {
+	// Validated by parseConfig.
+	reverseLabelFilter, _ := labels.Parse(cfg.ReverseLabelFilter)
+	return source.ServiceEntrySelectors{
+		MeshExternalNamespace: cfg.MeshExternalNamespace,
+		ReverseDomainFilter:   endpoint.NewDomainFilter(cfg.ReverseDomainFilter),
+		ReverseLabelFilter:    reverseLabelFilter,
+	}
+}
+
+// restartRequired returns true if next changes the settings not applied by a
+// reload - the settings other than the settings and the selectors.
+func (cfg *config) restartRequired(next *config) bool {
+	return !reflect.DeepEqual(cfg.withoutReloadable(), next.withoutReloadable())
+}
+
+func (cfg *config) withoutReloadable() config {
+	c := *cfg
+	c.Policy, c.DomainFilter, c.ManagedRecordTypes = "", nil, nil
+	c.MeshExternalNamespace, c.ReverseDomainFilter, c.ReverseLabelFilter = "", nil, ""
+	return c
+}
+
+// inMemoryOptions returns the options of the in-memory provider.
+func (cfg *config) inMemoryOptions() []inmemory.InMemoryOption {
+	zones := cfg.InMemoryZones
+	if len(zones) == 0 {
+		zones = cfg.DomainFilter
+	}
+	opts := []inmemory.InMemoryOption{
+		inmemory.InMemoryInitZones(zones),
+		inmemory.InMemoryWithDomain(endpoint.NewDomainFilter(cfg.DomainFilter)),
+		inmemory.InMemoryWithLogging(),
+	}
+	if cfg.InMemoryFile != "" {
+		opts = append(opts, inmemory.InMemoryWithFile(cfg.InMemoryFile))
+	}
+	return opts
+}
+
+// sourceConfig returns the configuration of the ServiceEntry source.
+func (cfg *config) sourceConfig() (source.ServiceEntrySourceConfig, error) {
+	selectors := cfg.selectors()
+	sc := source.ServiceEntrySourceConfig{
+		MeshExternalNamespace: selectors.MeshExternalNamespace,
+		MeshInternalDomain:    cfg.MeshInternalDomain,
+		EgressGatewayVIP:      cfg.EgressGatewayVIP,
+		HttpVIP:               cfg.HttpVIP,
+		UpdateServiceEntry:    cfg.UpdateServiceEntry && !cfg.DryRun,
+		ReverseNamespace:      cfg.ReverseNamespace,
+		ReverseDomainFilter:   selectors.ReverseDomainFilter,
+		ReverseLabelFilter:    selectors.ReverseLabelFilter,
+	}
+	if len(cfg.VIPPool) > 0 {
+		var store ipam.Store
+		if cfg.VIPLeaseFile != "" {
+			store = ipam.NewFileStore(cfg.VIPLeaseFile)
+		}
+		allocator, err := ipam.NewAllocator(cfg.VIPPool, store)
+		if err != nil {
+			return sc, fmt.Errorf("invalid VIP pool: %w", err)
+		}
+		sc.Allocator = allocator
+	}
+	return sc, nil
+}
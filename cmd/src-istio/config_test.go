@@ -0,0 +1,146 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+func TestParseConfigDefaults(t *testing.T) {
+	cfg, err := parseConfig(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "txt", cfg.Registry)
+	assert.Equal(t, "k8s-%{record_type}-", cfg.TXTPrefix)
+	assert.Equal(t, "sync", cfg.Policy)
+	assert.Equal(t, time.Hour, cfg.Interval.Duration)
+	assert.Zero(t, cfg.MinInterval.Duration)
+	assert.Equal(t, 0.1, cfg.Jitter)
+	assert.Empty(t, cfg.ProviderURL)
+	assert.Equal(t, ":7979", cfg.MetricsAddress)
+	assert.Equal(t, 30*time.Second, cfg.ShutdownTimeout.Duration)
+	assert.Zero(t, cfg.MaxSyncAge.Duration)
+	assert.False(t, cfg.FinalSync)
+
+	sc, err := cfg.sourceConfig()
+	require.NoError(t, err)
+	assert.Nil(t, sc.Allocator)
+	assert.True(t, sc.ReverseLabelFilter.Empty())
+}
+
+func TestParseConfigLayers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+meshInternalDomain: mesh.internal
+vipPool: [10.10.0.0/24]
+reverseNamespace: egress
+reverseLabelFilter: owner=k8s
+txtOwnerID: cluster-1
+policy: upsert-only
+interval: 10m
+providerURL: http://localhost:8080
+`), 0o600))
+	t.Setenv("SRC_ISTIO_POLICY", "create-only")
+
+	cfg, err := parseConfig([]string{"--config=" + path, "--provider-url=http://dns-google:8080", "--managed-record-types=A"})
+	require.NoError(t, err)
+	assert.Equal(t, "mesh.internal", cfg.MeshInternalDomain)
+	assert.Equal(t, "cluster-1", cfg.TXTOwnerID)
+	assert.Equal(t, "create-only", cfg.Policy)
+	assert.Equal(t, 10*time.Minute, cfg.Interval.Duration)
+	assert.Equal(t, "http://dns-google:8080", cfg.ProviderURL)
+	assert.Equal(t, []string{"A"}, cfg.ManagedRecordTypes)
+
+	sc, err := cfg.sourceConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "mesh.internal", sc.MeshInternalDomain)
+	assert.Equal(t, "egress", sc.ReverseNamespace)
+	assert.NotNil(t, sc.Allocator)
+	assert.Equal(t, "owner=k8s", sc.ReverseLabelFilter.String())
+}
+
+func TestParseConfigDryRun(t *testing.T) {
+	cfg, err := parseConfig([]string{"--update-service-entry", "--dry-run", "--once"})
+	require.NoError(t, err)
+	assert.True(t, cfg.Once)
+	sc, err := cfg.sourceConfig()
+	require.NoError(t, err)
+	assert.False(t, sc.UpdateServiceEntry, "the ServiceEntries are not updated in dry run")
+}
+
+func TestParseConfigInMemory(t *testing.T) {
+	cfg, err := parseConfig([]string{"--domain-filter=mesh.internal", "--domain-filter=example.com"})
+	require.NoError(t, err)
+	p := inmemory.NewInMemoryProvider(cfg.inMemoryOptions()...)
+	assert.Equal(t, map[string]string{"mesh.internal": "mesh.internal", "example.com": "example.com"}, p.Zones())
+
+	path := filepath.Join(t.TempDir(), "records.json")
+	cfg, err = parseConfig([]string{"--domain-filter=example.com", "--inmemory-zone=mesh.internal", "--inmemory-file=" + path})
+	require.NoError(t, err)
+	p = inmemory.NewInMemoryProvider(cfg.inMemoryOptions()...)
+	assert.Equal(t, map[string]string{"mesh.internal": "mesh.internal"}, p.Zones())
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpoint("web.mesh.internal", endpoint.RecordTypeA, "10.10.0.1"),
+	}}))
+	assert.FileExists(t, path)
+}
+
+func TestReloadableConfig(t *testing.T) {
+	cfg, err := parseConfig([]string{"--domain-filter=example.com", "--policy=upsert-only", "--reverse-label-filter=owner=k8s"})
+	require.NoError(t, err)
+	settings := cfg.settings()
+	assert.Equal(t, &plan.UpsertOnlyPolicy{}, settings.Policy)
+	assert.True(t, settings.DomainFilter.Match("www.example.com"))
+	assert.Equal(t, "owner=k8s", cfg.selectors().ReverseLabelFilter.String())
+
+	next, err := parseConfig([]string{"--domain-filter=example.org", "--policy=sync", "--mesh-external-namespace=egress", "--reverse-domain-filter=example.org"})
+	require.NoError(t, err)
+	assert.False(t, cfg.restartRequired(next))
+	next, err = parseConfig([]string{"--domain-filter=example.com", "--provider-url=http://dns-google:8080"})
+	require.NoError(t, err)
+	assert.True(t, cfg.restartRequired(next))
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("meshDomain: typo\n"), 0o600))
+	for _, args := range [][]string{
+		{"--config=" + path},
+		{"--policy=delete-all"},
+		{"--reverse-label-filter=a=(b"},
+		{"--txt-owner-id="},
+		{"--jitter=1.5"},
+	} {
+		_, err := parseConfig(args)
+		assert.Error(t, err, args)
+	}
+
+	cfg, err := parseConfig([]string{"--vip-pool=10.0.0.0/33"})
+	require.NoError(t, err)
+	_, err = cfg.sourceConfig()
+	assert.Error(t, err)
+}
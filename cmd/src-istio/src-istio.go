@@ -1,11 +1,45 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// src-istio syncs the Istio ServiceEntries to a webhook provider, like a
+// dns-google server:
+//
+//	src-istio --provider-url=http://localhost:8080 --mesh-internal-domain=mesh.internal
+//	src-istio --config=/etc/src-istio/config.yaml
+//
+// Run 'src-istio --help' for the flags.
 package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
 	"sigs.k8s.io/external-dns/controller"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/debug"
+	"sigs.k8s.io/external-dns/pkg/health"
+	"sigs.k8s.io/external-dns/pkg/logging"
+	"sigs.k8s.io/external-dns/pkg/reload"
 	"sigs.k8s.io/external-dns/plan"
 	"sigs.k8s.io/external-dns/provider"
 	"sigs.k8s.io/external-dns/provider/inmemory"
@@ -14,102 +48,195 @@ import (
 	"sigs.k8s.io/external-dns/source"
 )
 
-type DnsSource struct {
-	Address string
+// fatal logs the error and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
 
 func main() {
-	ctx := context.Background()
-
-	cfg := &DnsSource{
+	// On SIGTERM, the informers and the event handlers stop, and the sync in
+	// progress completes.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
 
+	cfg, err := parseConfig(os.Args[1:])
+	if err != nil {
+		fatal("Invalid configuration", err)
+	}
+	if _, err := logging.Setup(os.Stderr, cfg.LogFormat, cfg.LogLevel, cfg.LogLevels); err != nil {
+		fatal("Invalid logging configuration", err)
 	}
-
-	source.InstrumentationWrapper = nil
 
 	sg := &source.SingletonClientGenerator{
-		KubeConfig: "", //   cfg.KubeConfig,
-		APIServerURL: "", // cfg.APIServerURL,
-		// If update events are enabled, disable timeout.
-		RequestTimeout: func() time.Duration {
-			//if cfg.UpdateEvents {
-			//	return 0
-			//}
-			//return cfg.RequestTimeout
-			return 1 * time.Second
-		}(),
+		KubeConfig:   cfg.KubeConfig,
+		APIServerURL: cfg.APIServerURL,
+		// The informers watch the ServiceEntries, no timeout.
+		RequestTimeout: 0,
+	}
+	if cfg.Once {
+		sg.RequestTimeout = cfg.RequestTimeout.Duration
 	}
 	kc, err := sg.KubeClient()
+	if err != nil {
+		fatal("Failed to create the Kubernetes client", err)
+	}
 	ic, err := sg.IstioClient()
-
-
-
-	src, err := source.NewIstioServiceEntrySourceConfig(ctx, kc, ic, source.ServiceEntrySourceConfig{})
 	if err != nil {
-		log.Fatalf("Failed to create webhook provider: %v", err)
+		fatal("Failed to create the Istio client", err)
 	}
 
-	//ep, err  := src.Endpoints(ctx)
-	//if err != nil {
-	//	log.Fatal(err)
-	//}
-	//for _, e := range ep {
-	//	log.Println(e)
-	//}
+	sc, err := cfg.sourceConfig()
+	if err != nil {
+		fatal("Invalid ServiceEntry source configuration", err)
+	}
+	src, err := source.NewIstioServiceEntrySourceConfig(ctx, kc, ic, sc)
+	if err != nil {
+		fatal("Failed to create ServiceEntry source", err)
+	}
 
+	domainFilter := endpoint.NewDomainFilter(cfg.DomainFilter)
 	var p provider.Provider
-	if cfg.Address == "" {
-		p = inmemory.NewInMemoryProvider(inmemory.InMemoryWithLogging())
+	var zones http.Handler
+	if cfg.ProviderURL == "" {
+		im := inmemory.NewInMemoryProvider(cfg.inMemoryOptions()...)
+		p, zones = im, im
 	} else {
 		// Now push the changed endpoints to provider
-		wp, err := webhook.NewWebhookProvider("http://localhost:8081")
+		wp, err := webhook.NewWebhookProvider(cfg.ProviderURL)
 		if err != nil {
-			log.Fatalf("Failed to create webhook provider: %v", err)
+			fatal("Failed to create webhook provider", err)
 		}
 		p = wp
 	}
 
-	recordTypes := []string{"A", "CNAME", "TXT", "SRV", "PTR", "CAA", "DS", "DNSKEY", "NAPTR", "TLSA", "URI"}
-
-	//%{record_type}-prefix- and suffix are added to the TXT records
-	// ownerID should include the cluster name (config cluster)
-	r, err := registry.NewTXTRegistry(p, "k8s-%{record_type}-", "", "k8s", 0, "all", recordTypes, nil, false, nil)
-	// registry.NewNoopRegistry(p)
-
-	r.Records(ctx)
+	var r registry.Registry
+	if cfg.Registry == "noop" {
+		r, err = registry.NewNoopRegistry(p)
+	} else {
+		// %{record_type} in the prefix and suffix is replaced by the type of the record.
+		var tr *registry.TXTRegistry
+		tr, err = registry.NewTXTRegistry(p, cfg.TXTPrefix, cfg.TXTSuffix, cfg.TXTOwnerID, 0, cfg.TXTWildcardReplacement, cfg.ManagedRecordTypes, nil, false, nil)
+		if err == nil {
+			err = tr.SetZoneFormats(cfg.TXTFormats)
+		}
+		r = tr
+	}
+	if err != nil {
+		fatal("Failed to create the registry", err)
+	}
 
 	ctrl := controller.Controller{
-		Source:               src,
-		Registry:             r,
+		Source:   src,
+		Registry: r,
 
 		// upsert-only - create and update, doesn't delete
 		// create-only - doesn't update
 		// sync - delete too
-		Policy:               plan.Policies["sync"],
-		// Using watcher - no need to have interval
-		Interval:             3600 * time.Second,
-		//DomainFilter:         domainFilter,
-		ManagedRecordTypes:   recordTypes,
-		//ExcludeRecordTypes:   cfg.ExcludeDNSRecordTypes,
-		MinEventSyncInterval: 5 * time.Second,
+		Policy:               plan.Policies[cfg.Policy],
+		Interval:             cfg.Interval.Duration,
+		DomainFilter:         domainFilter,
+		ManagedRecordTypes:   cfg.ManagedRecordTypes,
+		MinEventSyncInterval: cfg.MinEventSyncInterval.Duration,
+		EventDebounce:        cfg.EventDebounce.Duration,
+		EventMaxDelay:        cfg.EventMaxDelay.Duration,
+		MinInterval:          cfg.MinInterval.Duration,
+		Jitter:               cfg.Jitter,
+		DryRun:               cfg.DryRun,
 	}
 
-	if false {
-		err := ctrl.RunOnce(ctx)
-		if err != nil {
-			log.Fatal(err)
+	if cfg.Once {
+		// 0 if in sync or the changes are applied, 2 if there are changes in
+		// dry run, 1 on error.
+		code := ctrl.RunOnceExitCode(ctx)
+		stop()
+		os.Exit(code)
+	}
+
+	// On SIGHUP or POST /reload, the filters, the policy and the selectors
+	// are read again from the config file, without restarting the informers.
+	current := cfg
+	reloader := &reload.Reloader{Reload: func(context.Context) error {
+		next, err := reloadConfig(current, &ctrl, src)
+		if err == nil {
+			current = next
 		}
-	} else {
-		// Add RunOnce as the handler function that will be called when ingress/service sources have changed.
-		// Note that k8s Informers will perform an initial list operation, which results in the handler
-		// function initially being called for every Service/Ingress that exists
-		src.AddEventHandler(ctx, func() {
-			// This will be called for all existing SE - causing a lot of churn and a sync.
-			//log.Println("SE event handler called.")
-			ctrl.ScheduleRunOnce(time.Now())
-		})
-
-		ctrl.ScheduleRunOnce(time.Now())
-		ctrl.Run(ctx)
+		return err
+	}}
+	go reloader.Run(ctx)
+	go serveMetrics(cfg, &ctrl, src, zones, reloader)
+
+	// Add RunOnce as the handler function that will be called when ServiceEntries have changed.
+	// Note that k8s Informers will perform an initial list operation, which results in the handler
+	// function initially being called for every ServiceEntry that exists
+	src.AddEventHandler(ctx, func() {
+		ctrl.ScheduleEvent(time.Now())
+	})
+
+	ctrl.ScheduleRunOnce(time.Now())
+	if err := ctrl.RunUntilShutdown(ctx, cfg.FinalSync, cfg.ShutdownTimeout.Duration); err != nil {
+		fatal("Failed to do the final sync", err)
+	}
+	slog.Info("Stopped")
+}
+
+// reloadConfig parses the configuration again and applies the controller
+// settings and the ServiceEntry selectors of next, returning it.
+func reloadConfig(cfg *config, ctrl *controller.Controller, src source.Source) (*config, error) {
+	next, err := parseConfig(os.Args[1:])
+	if err != nil {
+		return nil, err
+	}
+	if cfg.restartRequired(next) {
+		slog.Warn("Only the domain filter, the policy, the managed record types and the ServiceEntry selectors are reloaded, the other changes require a restart")
+	}
+	if se, ok := src.(*source.ServiceEntrySource); ok {
+		se.SetSelectors(next.selectors())
+	}
+	ctrl.Reload(next.settings())
+	return next, nil
+}
+
+// serveMetrics serves the metrics, the dashboard and the health checks:
+// /healthz fails without a successful sync for --max-sync-age, /readyz while
+// the source is not healthy, like before the ServiceEntries are listed, until
+// the first sync succeeds and while the provider is unreachable. zones serves the zone file of the in-memory
+// provider at /zones, nil with a webhook provider, and POST /reload reloads
+// the configuration. With --debug-endpoints, /debug/pprof and /debug/state
+// are served too.
+func serveMetrics(cfg *config, ctrl *controller.Controller, src source.Source, zones http.Handler, reloader http.Handler) {
+	if cfg.MetricsAddress == "" {
+		return
+	}
+	maxSyncAge := cfg.MaxSyncAge.Duration
+	if maxSyncAge == 0 {
+		maxSyncAge = 3 * cfg.Interval.Duration
+	}
+	h := &health.Handler{}
+	h.AddLivenessCheck("sync-age", health.SyncAge(ctrl.LastSyncTime, maxSyncAge))
+	h.AddReadinessCheck("source", func(context.Context) error { return source.Healthy(src) })
+	h.AddReadinessCheck("first-sync", health.FirstSync(ctrl.LastSyncTime))
+	if cfg.ProviderURL != "" {
+		h.AddReadinessCheck("provider", health.Cached(health.HTTPGet(cfg.ProviderURL), 10*time.Second))
+	}
+
+	mux := http.NewServeMux()
+	h.Register(mux)
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/dashboard", ctrl)
+	if zones != nil {
+		mux.Handle("/zones", zones)
+	}
+	mux.Handle("/reload", reloader)
+	if cfg.DebugEndpoints {
+		d := &debug.Handler{}
+		d.AddState("controller", func() any { return ctrl.DebugState() })
+		if se, ok := src.(*source.ServiceEntrySource); ok {
+			d.AddState("source/istio-se", func() any { return se.DebugState() })
+		}
+		d.Register(mux)
+	}
+	if err := http.ListenAndServe(cfg.MetricsAddress, mux); err != nil {
+		fatal("Failed to serve the metrics", err)
 	}
 }
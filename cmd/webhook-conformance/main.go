@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// webhook-conformance checks that a running webhook provider implements the
+// protocol of ExternalDNS, with the checks of provider/webhook/conformance:
+//
+//	webhook-conformance --server http://localhost:8888 --domain test.example.org
+//
+// The checks create and delete records under the domain, which should be a
+// test domain. It exits with 1 if a check failed.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"sigs.k8s.io/external-dns/provider/webhook/conformance"
+)
+
+func main() {
+	cfg := conformance.Config{}
+	var timeout time.Duration
+	app := kingpin.New("webhook-conformance", "Check that a running webhook provider implements the ExternalDNS webhook protocol.")
+	app.DefaultEnvars()
+	app.Flag("server", "The URL of the webhook provider").Default("http://localhost:8888").StringVar(&cfg.URL)
+	app.Flag("domain", "The domain of the records created by the checks (default: the first domain of the domain filter of the provider)").StringVar(&cfg.Domain)
+	app.Flag("records", "The number of records of the large batch check, or -1 to skip it").Default(fmt.Sprint(conformance.DefaultRecords)).IntVar(&cfg.Records)
+	app.Flag("timeout", "The timeout of all the checks").Default("5m").DurationVar(&timeout)
+	kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	results := conformance.Run(ctx, cfg)
+	cancel()
+	writeResults(os.Stdout, results)
+	if !conformance.Passed(results) {
+		os.Exit(1)
+	}
+}
+
+// writeResults prints a line per check.
+func writeResults(w io.Writer, results []conformance.Result) {
+	failed := 0
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			fmt.Fprintf(w, "SKIP %s\n", r.Name)
+		case r.Err != nil:
+			failed++
+			fmt.Fprintf(w, "FAIL %s: %v\n", r.Name, r.Err)
+		default:
+			fmt.Fprintf(w, "PASS %s\n", r.Name)
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(w, "%d of %d checks failed\n", failed, len(results))
+		return
+	}
+	fmt.Fprintf(w, "All %d checks passed\n", len(results))
+}
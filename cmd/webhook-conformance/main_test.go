@@ -0,0 +1,41 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/external-dns/provider/webhook/conformance"
+)
+
+func TestWriteResults(t *testing.T) {
+	var out bytes.Buffer
+	writeResults(&out, []conformance.Result{
+		{Name: "negotiate"},
+		{Name: "records", Err: errors.New("GET records returned 500, expected 200")},
+		{Name: "large-payload", Skipped: true},
+	})
+	assert.Equal(t, "PASS negotiate\nFAIL records: GET records returned 500, expected 200\nSKIP large-payload\n1 of 3 checks failed\n", out.String())
+
+	out.Reset()
+	writeResults(&out, []conformance.Result{{Name: "negotiate"}})
+	assert.Equal(t, "PASS negotiate\nAll 1 checks passed\n", out.String())
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/audit"
+	"sigs.k8s.io/external-dns/pkg/gitops"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+var pausedGauge = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "controller",
+		Name:      "paused",
+		Help:      "1 while the applies are paused by the admin API.",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(pausedGauge)
+}
+
+// Pause pauses the applies: the syncs plan the changes and keep them pending,
+// like in dry run, until Resume.
+func (c *Controller) Pause() {
+	c.paused.Store(true)
+	pausedGauge.Set(1)
+}
+
+// Resume resumes the applies paused by Pause.
+func (c *Controller) Resume() {
+	c.paused.Store(false)
+	pausedGauge.Set(0)
+}
+
+// Paused returns true while the applies are paused.
+func (c *Controller) Paused() bool {
+	return c.paused.Load()
+}
+
+// pause keeps the changes of a paused sync pending.
+func (c *Controller) pause(ctx context.Context, changes *plan.Changes) {
+	c.state.setPending(changes)
+	if changes.HasChanges() {
+		log.WithContext(ctx).Infof("Applies paused: %d changes are not applied", gitops.CountChanges(changes))
+	}
+}
+
+// SyncNow runs the next sync at the next tick of Run, without waiting for the
+// interval or the events.
+func (c *Controller) SyncNow() {
+	c.nextRunAtMux.Lock()
+	defer c.nextRunAtMux.Unlock()
+	c.nextRunAt = time.Now()
+}
+
+// Flush flushes the caches of the records: the next sync lists all the
+// records of the provider, including the ones cached by the registry.
+func (c *Controller) Flush() {
+	c.runMux.Lock()
+	defer c.runMux.Unlock()
+	c.forceFullSync()
+	if f, ok := c.Registry.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+}
+
+// RollBack applies the inverse of a change set of the audit trail between
+// the syncs, and pauses the applies: the next syncs would apply the change set
+// again until the sources are fixed. The next sync lists all the records.
+func (c *Controller) RollBack(ctx context.Context, r *audit.Rollback, id string) (*plan.Changes, error) {
+	c.runMux.Lock()
+	defer c.runMux.Unlock()
+	changes, err := r.Apply(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.Pause()
+	c.forceFullSync()
+	if f, ok := c.Registry.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+	return changes, nil
+}
+
+// AdminState is the desired and the current state of the last sync, served
+// by the admin API.
+type AdminState struct {
+	OwnerID string `json:"ownerID"`
+	Paused  bool   `json:"paused"`
+	// Desired are the endpoints of the sources, Records the ones of the
+	// registry, and Pending the changes not applied yet.
+	Desired      []*endpoint.Endpoint `json:"desired"`
+	Records      []*endpoint.Endpoint `json:"records"`
+	Pending      *plan.Changes        `json:"pending,omitempty"`
+	LastSyncTime time.Time            `json:"lastSyncTime,omitempty"`
+	LastError    string               `json:"lastError,omitempty"`
+}
+
+// AdminState returns the desired and the current state of the last sync.
+func (c *Controller) AdminState() AdminState {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	return AdminState{
+		OwnerID:      c.Registry.OwnerID(),
+		Paused:       c.Paused(),
+		Desired:      c.state.desired,
+		Records:      c.state.records,
+		Pending:      c.state.status.Pending,
+		LastSyncTime: c.state.status.LastSyncTime,
+		LastError:    c.state.status.LastError,
+	}
+}
+
+// Admin serves the operations on a running controller, under /admin/:
+//
+//	POST /admin/sync      sync now
+//	POST /admin/pause     pause the applies
+//	POST /admin/resume    resume the applies
+//	POST /admin/flush     flush the caches of the records
+//	GET  /admin/state     the desired endpoints, the records and the pending changes
+//	     /admin/loglevel  the log levels, see logging.Levels
+//	GET  /admin/changesets  the change sets which can be rolled back, newest first
+//	POST /admin/rollback?id=ID  roll back a change set, and pause the applies
+//	     /admin/quarantine[/NAME]  the quarantine, of a federation target with NAME, cleared with POST
+//
+// The operations return the state, as JSON, except loglevel, changesets, and
+// rollback returning the applied changes, and quarantine its state. With a
+// Token, the requests without it as bearer token are rejected.
+type Admin struct {
+	// Controller is nil for a federation, which only serves the quarantines.
+	Controller *Controller
+	// Token is the bearer token required by the requests, if set.
+	Token string
+	// LogLevels serves /admin/loglevel, if set.
+	LogLevels http.Handler
+	// Rollback serves /admin/changesets and /admin/rollback, if set.
+	Rollback *audit.Rollback
+	// Quarantines serves /admin/quarantine, by federation target name, or ""
+	// without a federation.
+	Quarantines map[string]*Quarantine
+}
+
+func (a *Admin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if a.Token != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	op := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin"), "/")
+	if op == "loglevel" && a.LogLevels != nil {
+		a.LogLevels.ServeHTTP(w, r)
+		return
+	}
+	if name, ok := strings.CutPrefix(op, "quarantine"); ok && (name == "" || name[0] == '/') {
+		q := a.Quarantines[strings.TrimPrefix(name, "/")]
+		if q == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodPost {
+			log.Infof("Admin API: %s cleared", op)
+		}
+		q.ServeHTTP(w, r)
+		return
+	}
+	if a.Controller == nil {
+		http.NotFound(w, r)
+		return
+	}
+	method := http.MethodPost
+	if op == "state" || op == "changesets" {
+		method = http.MethodGet
+	}
+	if r.Method != method {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if (op == "changesets" || op == "rollback") && a.Rollback != nil {
+		a.serveRollback(w, r, op)
+		return
+	}
+	switch op {
+	case "state":
+	case "sync":
+		a.Controller.SyncNow()
+	case "pause":
+		a.Controller.Pause()
+	case "resume":
+		a.Controller.Resume()
+	case "flush":
+		a.Controller.Flush()
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if op != "state" {
+		log.Infof("Admin API: %s", op)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(a.Controller.AdminState()); err != nil {
+		log.Errorf("Failed to encode the admin state: %v", err)
+	}
+}
+
+// serveRollback lists the change sets, or rolls one back.
+func (a *Admin) serveRollback(w http.ResponseWriter, r *http.Request, op string) {
+	var resp any
+	if op == "changesets" {
+		entries, err := a.Rollback.ChangeSets(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp = entries
+	} else {
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "missing id", http.StatusBadRequest)
+			return
+		}
+		log.Infof("Admin API: rollback %s", id)
+		changes, err := a.Controller.RollBack(r.Context(), a.Rollback, id)
+		if errors.Is(err, audit.ErrChangeSetNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if errors.Is(err, audit.ErrRollbackConflict) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp = changes
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Errorf("Failed to encode the admin response: %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/pkg/audit"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/fake"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+type flushRegistry struct {
+	registry.Registry
+	flushed int
+}
+
+func (r *flushRegistry) Flush() {
+	r.flushed++
+}
+
+func TestAdmin(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+	}, nil)
+	p := fake.New()
+	noop, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	r := &flushRegistry{Registry: noop}
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+	}
+	admin := &Admin{Controller: ctrl}
+	do := func(method, path string) (*httptest.ResponseRecorder, AdminState) {
+		w := httptest.NewRecorder()
+		admin.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		var state AdminState
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&state))
+		}
+		return w, state
+	}
+
+	w, state := do(http.MethodPost, "/admin/pause")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, state.Paused)
+
+	// A paused sync keeps its changes pending.
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Empty(t, p.Changes())
+	_, state = do(http.MethodGet, "/admin/state")
+	assert.Len(t, state.Desired, 1)
+	require.NotNil(t, state.Pending)
+	assert.Len(t, state.Pending.Create, 1)
+
+	_, state = do(http.MethodPost, "/admin/resume")
+	assert.False(t, state.Paused)
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Len(t, p.Changes(), 1)
+
+	ctrl.nextRunAt = time.Now().Add(time.Hour)
+	do(http.MethodPost, "/admin/sync")
+	assert.True(t, ctrl.ShouldRunOnce(time.Now()))
+
+	do(http.MethodPost, "/admin/flush")
+	assert.Equal(t, 1, r.flushed)
+
+	w, _ = do(http.MethodGet, "/admin/pause")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	w, _ = do(http.MethodPost, "/admin/unknown")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, _ = do(http.MethodGet, "/admin/loglevel")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestAdminToken(t *testing.T) {
+	noop, err := registry.NewNoopRegistry(fake.New())
+	require.NoError(t, err)
+	ctrl := &Controller{Registry: noop}
+	admin := &Admin{Controller: ctrl, Token: "secret"}
+	do := func(authorization string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/pause", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		admin.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, do(""))
+	assert.Equal(t, http.StatusUnauthorized, do("Bearer other"))
+	assert.Equal(t, http.StatusUnauthorized, do("secret"))
+	assert.False(t, ctrl.Paused(), "the rejected requests are not applied")
+	assert.Equal(t, http.StatusOK, do("Bearer secret"))
+	assert.True(t, ctrl.Paused())
+}
+
+func TestAdminQuarantine(t *testing.T) {
+	quarantine := func() *Quarantine {
+		q := NewQuarantine(1, 1, 10, 0)
+		require.Error(t, q.Check(deletes(5), time.Now()))
+		return q
+	}
+	east, west := quarantine(), quarantine()
+	// A federation has no controller.
+	admin := &Admin{Token: "secret", Quarantines: map[string]*Quarantine{"east": east, "west": west}}
+	do := func(method, target, authorization string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		admin.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/admin/quarantine/east", ""))
+	assert.True(t, east.State().Quarantined, "the rejected requests are not applied")
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/admin/quarantine/east", "Bearer secret"))
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/admin/quarantine/east", "Bearer secret"))
+	assert.False(t, east.State().Quarantined)
+	assert.True(t, west.State().Quarantined, "only the quarantine of the target is cleared")
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/admin/quarantine", "Bearer secret"))
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/admin/quarantine/north", "Bearer secret"))
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/admin/quarantines", "Bearer secret"))
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/admin/pause", "Bearer secret"))
+}
+
+func TestAdminRollback(t *testing.T) {
+	ctx := context.Background()
+	store := &audit.DirStore{Dir: t.TempDir()}
+	p := audit.NewProvider(inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"})), audit.NewRecorder(store, "test", 0))
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+	}, nil)
+	noop, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           noop,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+	}
+	admin := &Admin{Controller: ctrl, Rollback: audit.NewRollback(store, p, audit.DefaultRollbackHistory)}
+	require.NoError(t, ctrl.RunOnce(ctx))
+
+	w := httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/changesets", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var changeSets []audit.Entry
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&changeSets))
+	require.Len(t, changeSets, 1)
+
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/rollback?id=unknown", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.False(t, ctrl.Paused())
+
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/rollback?id="+url.QueryEscape(changeSets[0].ID), nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, ctrl.Paused(), "the next syncs would apply the change set again")
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	// The records changed since.
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/rollback?id="+url.QueryEscape(changeSets[0].ID), nil))
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
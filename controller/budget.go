@@ -0,0 +1,175 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+var (
+	budgetDeferredChanges = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "budget_deferred_changes",
+			Help:      "Number of record changes deferred to a later sync by the change budget, by domain.",
+		},
+		[]string{"domain"},
+	)
+	budgetDeferredTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "budget_deferred_changes_total",
+			Help:      "Number of record changes deferred by the change budget.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(budgetDeferredChanges)
+	prometheus.MustRegister(budgetDeferredTotal)
+}
+
+// ChangeBudget limits the record changes applied per minute, globally and per
+// zone, across providers. Changes over the budget are deferred - the next syncs
+// compute them again, and apply them as the budget refills. Each created,
+// updated or deleted record counts as one change.
+type ChangeBudget struct {
+	global  *rate.Limiter
+	perZone int
+
+	mu    sync.Mutex
+	zones map[string]*rate.Limiter
+}
+
+// NewChangeBudget returns a budget of changes per minute, globally and per
+// zone. Zero is unlimited. A full minute of changes can be applied at once.
+func NewChangeBudget(global, perZone int) *ChangeBudget {
+	b := &ChangeBudget{perZone: perZone, zones: map[string]*rate.Limiter{}}
+	if global > 0 {
+		b.global = rate.NewLimiter(perMinute(global), global)
+	}
+	return b
+}
+
+func perMinute(n int) rate.Limit {
+	return rate.Limit(float64(n) / 60)
+}
+
+// Allow splits the changes into the changes within the budget, which are
+// consumed, and the deferred changes. Deletes are allowed first, then updates
+// and creates. zone returns the zone of a DNS name.
+func (b *ChangeBudget) Allow(changes *plan.Changes, zone func(string) string, now time.Time) (allowed, deferred *plan.Changes) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	deferredZones := map[string]int{}
+	allowed, deferred = splitChanges(changes, func(ep *endpoint.Endpoint) bool {
+		z := zone(ep.DNSName)
+		if b.allow(z, now) {
+			return true
+		}
+		deferredZones[z]++
+		return false
+	})
+
+	budgetDeferredChanges.Reset()
+	for z, n := range deferredZones {
+		budgetDeferredChanges.WithLabelValues(z).Set(float64(n))
+		budgetDeferredTotal.Add(float64(n))
+	}
+	return allowed, deferred
+}
+
+// allow consumes one change of the zone and global budgets, if both have one.
+func (b *ChangeBudget) allow(zone string, now time.Time) bool {
+	var zr *rate.Reservation
+	if b.perZone > 0 {
+		l := b.zones[zone]
+		if l == nil {
+			l = rate.NewLimiter(perMinute(b.perZone), b.perZone)
+			b.zones[zone] = l
+		}
+		zr = l.ReserveN(now, 1)
+		if zr.DelayFrom(now) > 0 {
+			zr.CancelAt(now)
+			return false
+		}
+	}
+	if b.global != nil {
+		if gr := b.global.ReserveN(now, 1); gr.DelayFrom(now) > 0 {
+			gr.CancelAt(now)
+			if zr != nil {
+				zr.CancelAt(now)
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// splitChanges splits the changes into the ones kept, and the others. keep is
+// called for the deletes first, then the updates and creates. An update is
+// kept with its old record.
+func splitChanges(changes *plan.Changes, keep func(*endpoint.Endpoint) bool) (kept, rest *plan.Changes) {
+	kept, rest = &plan.Changes{}, &plan.Changes{}
+	for _, ep := range changes.Delete {
+		if keep(ep) {
+			kept.Delete = append(kept.Delete, ep)
+		} else {
+			rest.Delete = append(rest.Delete, ep)
+		}
+	}
+	old := map[string]*endpoint.Endpoint{}
+	for _, ep := range changes.UpdateOld {
+		old[updateKey(ep)] = ep
+	}
+	for _, ep := range changes.UpdateNew {
+		o := old[updateKey(ep)]
+		if keep(ep) {
+			kept.UpdateNew = append(kept.UpdateNew, ep)
+			if o != nil {
+				kept.UpdateOld = append(kept.UpdateOld, o)
+			}
+		} else {
+			rest.UpdateNew = append(rest.UpdateNew, ep)
+			if o != nil {
+				rest.UpdateOld = append(rest.UpdateOld, o)
+			}
+		}
+	}
+	for _, ep := range changes.Create {
+		if keep(ep) {
+			kept.Create = append(kept.Create, ep)
+		} else {
+			rest.Create = append(rest.Create, ep)
+		}
+	}
+	return kept, rest
+}
+
+func updateKey(ep *endpoint.Endpoint) string {
+	return ep.DNSName + "/" + ep.RecordType + "/" + ep.SetIdentifier
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func creates(domain string, n int) []*endpoint.Endpoint {
+	var eps []*endpoint.Endpoint
+	for i := 0; i < n; i++ {
+		eps = append(eps, endpoint.NewEndpoint(fmt.Sprintf("r%d.%s", i, domain), endpoint.RecordTypeA, "1.2.3.4"))
+	}
+	return eps
+}
+
+func TestChangeBudgetZone(t *testing.T) {
+	b := NewChangeBudget(0, 3)
+	ctrl := &Controller{}
+	now := time.Now()
+
+	changes := &plan.Changes{
+		Create:    append(creates("example.com", 3), creates("example.org", 2)...),
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("u.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("u.example.com", endpoint.RecordTypeA, "1.2.3.5")},
+	}
+	allowed, deferred := b.Allow(changes, ctrl.driftDomain, now)
+	// The update goes first, leaving 2 creates for example.com.
+	assert.Len(t, allowed.UpdateNew, 1)
+	assert.Len(t, allowed.UpdateOld, 1)
+	assert.Len(t, allowed.Create, 4)
+	assert.Len(t, deferred.Create, 1)
+	assert.Equal(t, "r2.example.com", deferred.Create[0].DNSName)
+	assert.Equal(t, 1.0, testutil.ToFloat64(budgetDeferredChanges.WithLabelValues("example.com")))
+
+	// One change per 20s.
+	allowed, deferred = b.Allow(deferred, ctrl.driftDomain, now.Add(10*time.Second))
+	assert.Empty(t, allowed.Create)
+	assert.Len(t, deferred.Create, 1)
+	allowed, deferred = b.Allow(deferred, ctrl.driftDomain, now.Add(20*time.Second))
+	assert.Len(t, allowed.Create, 1)
+	assert.False(t, deferred.HasChanges())
+}
+
+func TestChangeBudgetGlobal(t *testing.T) {
+	b := NewChangeBudget(4, 3)
+	ctrl := &Controller{}
+	now := time.Now()
+
+	changes := &plan.Changes{
+		Delete: creates("example.com", 4),
+		Create: creates("example.org", 2),
+	}
+	allowed, deferred := b.Allow(changes, ctrl.driftDomain, now)
+	assert.Len(t, allowed.Delete, 3)
+	assert.Len(t, allowed.Create, 1)
+	assert.Len(t, deferred.Delete, 1)
+	assert.Len(t, deferred.Create, 1)
+
+	// The zone budget isn't consumed by changes denied by the global budget.
+	allowed, _ = b.Allow(&plan.Changes{Create: creates("example.org", 2)}, ctrl.driftDomain, now.Add(30*time.Second))
+	assert.Len(t, allowed.Create, 2)
+}
+
+func TestRunOnceBudget(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return(creates("example.com", 5), nil)
+	p := &filteredMockProvider{}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		Budget:             NewChangeBudget(2, 0),
+		Interval:           time.Hour,
+	}
+	require.True(t, ctrl.ShouldRunOnce(time.Now()))
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, p.ApplyChangesCalls, 1)
+	assert.Len(t, p.ApplyChangesCalls[0].Create, 2)
+	// The deferred changes are scheduled before the next interval.
+	assert.True(t, ctrl.ShouldRunOnce(time.Now()))
+}
@@ -20,13 +20,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 
 	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/gitops"
+	"sigs.k8s.io/external-dns/pkg/logging"
 	"sigs.k8s.io/external-dns/plan"
 	"sigs.k8s.io/external-dns/provider"
 	"sigs.k8s.io/external-dns/registry"
@@ -185,6 +190,17 @@ type Controller struct {
 	Policy plan.Policy
 	// The interval between individual synchronizations
 	Interval time.Duration
+	// Schedule, if set, replaces Interval: the syncs run at its times, like a cron job
+	Schedule Schedule
+	// MinInterval, if set and shorter than Interval, is the interval after a
+	// sync applying changes; it doubles after each sync without changes, up
+	// to Interval
+	MinInterval time.Duration
+	// Jitter is the fraction of the interval, 0 to 1, randomly added to each
+	// periodic sync, so that many instances don't sync at the same time
+	Jitter float64
+	// The adapted interval, between MinInterval and Interval
+	interval time.Duration
 	// The DomainFilter defines which DNS records to keep or exclude
 	DomainFilter endpoint.DomainFilter
 	// The nextRunAt used for throttling and batching reconciliation
@@ -197,28 +213,125 @@ type Controller struct {
 	ExcludeRecordTypes []string
 	// MinEventSyncInterval is used as window for batching events
 	MinEventSyncInterval time.Duration
+	// EventDebounce, if set, postpones the sync of ScheduleEvent until no event
+	// was received for this duration, up to EventMaxDelay after the first one
+	EventDebounce time.Duration
+	EventMaxDelay time.Duration
+	// The events since the last sync, the time of the first one, and the sync
+	// time set by ScheduleEvent
+	events       int
+	firstEventAt time.Time
+	debouncedAt  time.Time
+	// Budget limits the changes applied per minute, if set
+	Budget *ChangeBudget
+	// Quarantine pauses the applies on anomalous plans, if set
+	Quarantine *Quarantine
+	// DomainLock, if set, locks the domains of the changes while they are
+	// applied: the changes of a domain locked by another controller are
+	// deferred to a later sync
+	DomainLock DomainLock
+	// The runMux serializes RunOnce and DetectDrift, which share the registry cache
+	runMux sync.Mutex
+	// The state of the last runs, for the dashboard
+	state syncState
+	// DryRun prints the changes to DryRunOutput instead of applying them
+	DryRun bool
+	// DryRunOutput is where the changes are printed in dry run, os.Stdout if nil
+	DryRunOutput io.Writer
+	// Journal, if set, lists the changes made to the provider records: the
+	// syncs reuse the records of the previous one, and only plan the names
+	// changed in the sources
+	Journal provider.Journal
+	// FullSyncInterval is the interval between the syncs listing every record
+	// with a Journal, zero to only list them when the journal requires it
+	FullSyncInterval time.Duration
+	// The state kept between the syncs with a Journal
+	incremental incrementalState
+	// Shards, if more than 1, is the number of shards of the names planned
+	// and applied one after the other, holding a shard of the records at a time
+	Shards int
+	// RecordCacheFile, if set, keeps the records of the last sync, used to
+	// plan the first sync after a start without waiting for the listing
+	RecordCacheFile string
+	// RecordCacheMaxAge is the age of the RecordCacheFile after which it is
+	// not used, zero for no limit
+	RecordCacheMaxAge time.Duration
+	// cacheLoaded is set once the RecordCacheFile was read, fromCache while
+	// the records of the sync are the cached ones
+	cacheLoaded bool
+	fromCache   bool
+	// Replica, with Replicas, is the name of this replica among Replicas: it
+	// only plans the names it owns, assigned by plan.ReplicaOwner
+	Replica  string
+	Replicas []string
+	// The records relabeled by the previous sync, to detect the conflicts
+	relabeled map[endpoint.EndpointKey]bool
+	// Lease, if set, is the lease of the records of this instance, renewed by
+	// the syncs: the records of other owners whose lease expired are deleted
+	Lease time.Duration
+	// StrictShadowing fails the syncs while desired endpoints shadow records
+	// of another owner, or of no owner, or delegated subzones, see
+	// plan.Plan.Shadowed
+	StrictShadowing bool
+	// ConflictPolicy is the policy of the changes to the records of another
+	// owner, or of no owner: ConflictPolicySkip by default
+	ConflictPolicy string
+	// ConflictReporter, if set, reports the new ownership conflicts
+	ConflictReporter ConflictReporter
+	// ChangeReporter, if set, reports the changes applied to the Registry
+	ChangeReporter ChangeReporter
+	// The ownership conflicts of the previous syncs
+	conflicts map[conflictKey]plan.OwnershipConflict
+	// paused is set while the applies are paused by the admin API
+	paused atomic.Bool
+}
+
+// sourceEndpoints returns the endpoints of the Source, or a soft error while
+// it is not healthy: a Source with a broken informer returns no endpoints, and
+// the plan would delete their records. The sync is skipped and the next one
+// retries once the informer recovers.
+func (c *Controller) sourceEndpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	endpoints, err := c.Source.Endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := source.Healthy(c.Source); err != nil {
+		return nil, provider.NewSoftError(fmt.Errorf("source not healthy: %w", err))
+	}
+	return endpoints, nil
 }
 
 // RunOnce runs a single iteration of a reconciliation loop.
 func (c *Controller) RunOnce(ctx context.Context) error {
+	c.runMux.Lock()
+	defer c.runMux.Unlock()
+
 	lastReconcileTimestamp.SetToCurrentTime()
 	t0 := time.Now()
+	// The messages of the sync, and of the webhook provider requests, share a trace.
+	ctx = logging.NewTrace(ctx)
+	if c.Shards > 1 {
+		return c.runShards(ctx)
+	}
 
-	records, err := c.Registry.Records(ctx)
+	records, full, err := c.currentRecords(ctx)
 	if err != nil {
 		registryErrorsTotal.Inc()
 		deprecatedRegistryErrors.Inc()
 		return err
 	}
+	fromCache := c.fromCache
+	c.fromCache = false
 	t1 := time.Now()
 
+	c.state.setRecords(records)
 	registryEndpointsTotal.Set(float64(len(records)))
 	regARecords, regAAAARecords := countAddressRecords(records)
 	registryARecords.Set(float64(regARecords))
 	registryAAAARecords.Set(float64(regAAAARecords))
 	ctx = context.WithValue(ctx, provider.RecordsContextKey, records)
 
-	endpoints, err := c.Source.Endpoints(ctx)
+	endpoints, err := c.sourceEndpoints(ctx)
 	if err != nil {
 		sourceErrorsTotal.Inc()
 		deprecatedSourceErrors.Inc()
@@ -236,39 +349,133 @@ func (c *Controller) RunOnce(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("adjusting endpoints: %w", err)
 	}
-	registryFilter := c.Registry.GetDomainFilter()
-
-	plan := &plan.Plan{
-		Policies:       []plan.Policy{c.Policy},
-		Current:        records,
-		Desired:        endpoints,
-		DomainFilter:   endpoint.MatchAllDomainFilters{&c.DomainFilter, &registryFilter},
-		ManagedRecords: c.ManagedRecordTypes,
-		ExcludeRecords: c.ExcludeRecordTypes,
-		OwnerID:        c.Registry.OwnerID(),
+	c.state.setDesired(endpoints)
+	// With Replicas, only the names owned by this replica are planned.
+	current, desired := c.ownedByReplica(records), c.desiredByReplica(endpoints)
+	owned := desired
+	if !full {
+		current, desired = c.affected(current, desired)
+	}
+	if err := c.checkShadowing(records, desired); err != nil {
+		c.state.setError(err)
+		// The next syncs fail until the shadowing endpoints are fixed.
+		return provider.NewSoftError(err)
 	}
+	plan := c.newPlan(current, desired).Calculate()
+	c.reportConflicts(ctx, plan.OwnershipConflicts, full)
 
-	plan = plan.Calculate()
+	changes := plan.Changes
+	c.relabeled = c.relabel(ctx, current, changes)
+	c.lease(ctx, current, changes, time.Now())
+	if c.DryRun {
+		c.dryRun(changes)
+		lastSyncTimestamp.SetToCurrentTime()
+		c.state.setSynced()
+		return nil
+	}
+	if c.Paused() {
+		c.pause(ctx, changes)
+		lastSyncTimestamp.SetToCurrentTime()
+		c.state.setSynced()
+		return nil
+	}
+	if c.Quarantine != nil {
+		if err := c.Quarantine.Check(changes, time.Now()); err != nil {
+			log.WithContext(ctx).Error(err)
+			c.state.setPending(changes)
+			c.state.setError(err)
+			// The plans of the next syncs are compared again in full.
+			c.forceFullSync()
+			return provider.NewSoftError(err)
+		}
+	}
+	if c.Budget != nil && changes.HasChanges() {
+		allowed, deferred := c.Budget.Allow(changes, c.driftDomain, time.Now())
+		changes = allowed
+		if n := len(deferred.Create) + len(deferred.UpdateNew) + len(deferred.Delete); n > 0 {
+			log.WithContext(ctx).Infof("Change budget exceeded, deferring %d changes to the next sync", n)
+			c.forceFullSync()
+			defer c.ScheduleRunOnce(time.Now())
+		}
+	}
+	if c.DomainLock != nil && changes.HasChanges() {
+		since := t0
+		if fromCache {
+			// The cached records may miss any change of another controller.
+			since = time.Time{}
+		}
+		var unlock func()
+		changes, unlock = c.lockDomains(ctx, changes, since)
+		defer unlock()
+	}
 
-	if plan.Changes.HasChanges() {
-		err = c.Registry.ApplyChanges(ctx, plan.Changes)
+	if changes.HasChanges() {
+		c.state.setPending(plan.Changes)
+		err = c.applyChanges(ctx, changes)
 		if err != nil {
 			registryErrorsTotal.Inc()
 			deprecatedRegistryErrors.Inc()
+			c.state.setError(err)
+			c.forceFullSync()
+			if fromCache {
+				// The cached records are outdated, the next sync lists them.
+				return provider.NewSoftError(fmt.Errorf("applying the changes planned with the cached records: %w", err))
+			}
 			return err
 		}
+		c.state.setApplied(changes)
+		c.adapt(t0, true)
 		t3 := time.Now()
-		log.Info("All records are already up to date ", t1.Sub(t0), t2.Sub(t1), t3.Sub(t2), len(plan.Changes.Create), len(plan.Changes.UpdateNew), len(plan.Changes.UpdateOld), len(plan.Changes.Delete))
+		log.WithContext(ctx).Info("All records are already up to date ", t1.Sub(t0), t2.Sub(t1), t3.Sub(t2), len(changes.Create), len(changes.UpdateNew), len(changes.UpdateOld), len(changes.Delete))
 	} else {
 		controllerNoChangesTotal.Inc()
-		log.Info("All records are already up to date ", t1.Sub(t0), t2.Sub(t1))
+		c.adapt(t0, false)
+		log.WithContext(ctx).Info("All records are already up to date ", t1.Sub(t0), t2.Sub(t1))
 	}
 
+	c.applied(changes, owned)
+	// The cached records are only saved again once listed.
+	if (full || changes.HasChanges()) && !fromCache {
+		c.saveRecords(ctx, records, changes)
+	}
 	lastSyncTimestamp.SetToCurrentTime()
+	c.state.setSynced()
 
 	return nil
 }
 
+// dryRun prints the changes, and keeps them pending, instead of applying them.
+func (c *Controller) dryRun(changes *plan.Changes) {
+	c.state.setPending(changes)
+	if !changes.HasChanges() {
+		controllerNoChangesTotal.Inc()
+		log.Info("All records are already up to date")
+		return
+	}
+	w := c.DryRunOutput
+	if w == nil {
+		w = os.Stdout
+	}
+	log.Infof("Dry run: %d changes are not applied", gitops.CountChanges(changes))
+	gitops.WriteChanges(w, changes)
+}
+
+// newPlan returns the plan to move the current records towards the desired endpoints.
+func (c *Controller) newPlan(current, desired []*endpoint.Endpoint) *plan.Plan {
+	registryFilter := c.Registry.GetDomainFilter()
+	return &plan.Plan{
+		Policies:       []plan.Policy{c.Policy},
+		Current:        current,
+		Desired:        desired,
+		DomainFilter:   endpoint.MatchAllDomainFilters{&c.DomainFilter, &registryFilter},
+		ManagedRecords: c.ManagedRecordTypes,
+		ExcludeRecords: c.ExcludeRecordTypes,
+		OwnerID:        c.Registry.OwnerID(),
+		AdoptForeign:   c.ConflictPolicy == ConflictPolicyAdopt,
+		AdoptUnowned:   c.ConflictPolicy == ConflictPolicyAdoptUnowned,
+	}
+}
+
 // Counts the intersections of A and AAAA records in endpoint and registry.
 func countMatchingAddressRecords(endpoints []*endpoint.Endpoint, registryRecords []*endpoint.Endpoint) (int, int) {
 	recordsMap := make(map[string]map[string]struct{})
@@ -326,18 +533,29 @@ func (c *Controller) ShouldRunOnce(now time.Time) bool {
 	if now.Before(c.nextRunAt) {
 		return false
 	}
-	c.nextRunAt = now.Add(c.Interval)
+	c.coalesce()
+	if c.Schedule != nil {
+		c.nextRunAt = c.Schedule.Next(now)
+	} else {
+		c.nextRunAt = now.Add(c.nextInterval())
+	}
 	return true
 }
 
 // Run runs RunOnce in a loop with a delay until context is canceled
 func (c *Controller) Run(ctx context.Context) {
+	c.run(ctx, ctx)
+}
+
+// run runs RunOnce with syncCtx in a loop with a delay until ctx is canceled.
+func (c *Controller) run(ctx, syncCtx context.Context) {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 	for {
 		if c.ShouldRunOnce(time.Now()) {
-			if err := c.RunOnce(ctx); err != nil {
-				if errors.Is(err, provider.SoftError) {
+			if err := c.RunOnce(syncCtx); err != nil {
+				// A sync interrupted by the shutdown is not fatal.
+				if errors.Is(err, provider.SoftError) || ctx.Err() != nil {
 					log.Errorf("Failed to do run once: %v", err)
 				} else {
 					log.Fatalf("Failed to do run once: %v", err)
@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
 
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/internal/testutils"
@@ -359,6 +360,123 @@ func testControllerFiltersDomains(t *testing.T, configuredEndpoints []*endpoint.
 	}
 }
 
+// unhealthySource is a source with no endpoints and a broken informer.
+type unhealthySource struct {
+	*testutils.MockSource
+}
+
+func (unhealthySource) Healthy() error {
+	return errors.New("the informer of services is not synced")
+}
+
+func (unhealthySource) LastEvent() time.Time {
+	return time.Time{}
+}
+
+func TestControllerSkipsUnhealthySource(t *testing.T) {
+	src := unhealthySource{new(testutils.MockSource)}
+	src.On("Endpoints").Return([]*endpoint.Endpoint{}, nil)
+	provider := &filteredMockProvider{
+		RecordsStore: []*endpoint.Endpoint{
+			{DNSName: "some-record.used.tld", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"8.8.8.8"}},
+		},
+	}
+	r, err := registry.NewNoopRegistry(provider)
+	require.NoError(t, err)
+
+	ctrl := &Controller{
+		Source:             src,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+	}
+	err = ctrl.RunOnce(context.Background())
+	assert.ErrorContains(t, err, "source not healthy")
+	assert.Empty(t, provider.ApplyChangesCalls, "the records are not deleted")
+}
+
+func TestRunSurvivesUnhealthySource(t *testing.T) {
+	src := unhealthySource{new(testutils.MockSource)}
+	src.On("Endpoints").Return([]*endpoint.Endpoint{}, nil)
+	r, err := registry.NewNoopRegistry(&filteredMockProvider{})
+	require.NoError(t, err)
+
+	// log.Fatalf exits through the ExitFunc of the logger.
+	fatal := false
+	logger := log.StandardLogger()
+	exit := logger.ExitFunc
+	logger.ExitFunc = func(int) { fatal = true }
+	defer func() { logger.ExitFunc = exit }()
+
+	ctrl := &Controller{
+		Source:             src,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+	}
+	ctrl.nextRunAt = time.Now().Add(-time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		ctrl.Run(ctx)
+		close(stopped)
+	}()
+	time.Sleep(1500 * time.Millisecond)
+	cancel()
+	<-stopped
+
+	src.AssertCalled(t, "Endpoints")
+	assert.False(t, fatal, "an unhealthy source doesn't stop the controller")
+}
+
+// readOnlyProvider fails the changes with a soft error, as the read-only
+// providers do.
+type readOnlyProvider struct {
+	filteredMockProvider
+}
+
+func (p *readOnlyProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	p.ApplyChangesCalls = append(p.ApplyChangesCalls, changes)
+	return provider.NewSoftError(errors.New("the provider is read-only"))
+}
+
+func TestRunSurvivesReadOnlyProvider(t *testing.T) {
+	src := new(testutils.MockSource)
+	src.On("Endpoints").Return([]*endpoint.Endpoint{
+		{DNSName: "create-record.used.tld", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"1.2.3.4"}},
+	}, nil)
+	p := &readOnlyProvider{}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+
+	// log.Fatalf exits through the ExitFunc of the logger.
+	fatal := false
+	logger := log.StandardLogger()
+	exit := logger.ExitFunc
+	logger.ExitFunc = func(int) { fatal = true }
+	defer func() { logger.ExitFunc = exit }()
+
+	ctrl := &Controller{
+		Source:             src,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+	}
+	ctrl.nextRunAt = time.Now().Add(-time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		ctrl.Run(ctx)
+		close(stopped)
+	}()
+	time.Sleep(1500 * time.Millisecond)
+	cancel()
+	<-stopped
+
+	assert.NotEmpty(t, p.ApplyChangesCalls, "the pending changes are applied")
+	assert.False(t, fatal, "a read-only provider doesn't stop the controller")
+}
+
 func TestControllerSkipsEmptyChanges(t *testing.T) {
 	testControllerFiltersDomains(
 		t,
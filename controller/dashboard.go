@@ -0,0 +1,241 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+//go:embed dashboard.html
+var dashboardHTML string
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"join": strings.Join,
+}).Parse(dashboardHTML))
+
+// Status is the controller state shown in the dashboard.
+type Status struct {
+	OwnerID string          `json:"ownerID"`
+	Domains []*DomainStatus `json:"domains"`
+
+	// RecordsTime is the time the records were read from the registry.
+	RecordsTime time.Time `json:"recordsTime,omitempty"`
+
+	// LastApplied are the last changes applied to the provider.
+	LastApplied     *plan.Changes `json:"lastApplied,omitempty"`
+	LastAppliedTime time.Time     `json:"lastAppliedTime,omitempty"`
+
+	// Pending are the changes calculated but not applied - because the apply
+	// failed, or found by drift detection.
+	Pending     *plan.Changes `json:"pending,omitempty"`
+	PendingTime time.Time     `json:"pendingTime,omitempty"`
+
+	// Conflicts are the changes to the records of another owner, or of no
+	// owner, skipped or adopted as set by the ConflictPolicy.
+	Conflicts []plan.OwnershipConflict `json:"conflicts,omitempty"`
+
+	LastError     string    `json:"lastError,omitempty"`
+	LastErrorTime time.Time `json:"lastErrorTime,omitempty"`
+
+	// LastSyncTime is the time of the last successful sync.
+	LastSyncTime time.Time `json:"lastSyncTime,omitempty"`
+}
+
+// DomainStatus holds the records of a domain, as grouped by the drift metrics.
+type DomainStatus struct {
+	Domain  string               `json:"domain"`
+	Records []*endpoint.Endpoint `json:"records"`
+}
+
+// syncState is the state of the last runs, updated by RunOnce and DetectDrift.
+type syncState struct {
+	mu     sync.Mutex
+	status Status
+	// records are grouped in domains when the status is requested.
+	records []*endpoint.Endpoint
+	// desired are the endpoints of the sources, for the service discovery.
+	desired []*endpoint.Endpoint
+}
+
+func (s *syncState) setRecords(records []*endpoint.Endpoint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = records
+	s.status.RecordsTime = time.Now()
+}
+
+func (s *syncState) setDesired(endpoints []*endpoint.Endpoint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.desired = endpoints
+}
+
+func (s *syncState) setPending(changes *plan.Changes) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !changes.HasChanges() {
+		changes = nil
+	}
+	s.status.Pending = changes
+	s.status.PendingTime = time.Now()
+}
+
+func (s *syncState) setApplied(changes *plan.Changes) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LastApplied = changes
+	s.status.LastAppliedTime = time.Now()
+	s.status.Pending = nil
+}
+
+func (s *syncState) setSynced() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LastSyncTime = time.Now()
+}
+
+func (s *syncState) setConflicts(conflicts []plan.OwnershipConflict) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Conflicts = conflicts
+}
+
+func (s *syncState) setError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LastError = err.Error()
+	s.status.LastErrorTime = time.Now()
+}
+
+// LastSyncTime returns the time of the last successful sync, zero before the
+// first one - for the health checks.
+func (c *Controller) LastSyncTime() time.Time {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	return c.state.status.LastSyncTime
+}
+
+// Records returns the registry records of the last sync, with the owner and
+// resource labels - for the admission webhook.
+func (c *Controller) Records() []*endpoint.Endpoint {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	return c.state.records
+}
+
+// Status returns the records, grouped by domain, and the last changes.
+func (c *Controller) Status() Status {
+	c.state.mu.Lock()
+	status := c.state.status
+	records := c.state.records
+	c.state.mu.Unlock()
+
+	status.OwnerID = c.Registry.OwnerID()
+	byDomain := map[string]*DomainStatus{}
+	for _, ep := range records {
+		domain := c.driftDomain(ep.DNSName)
+		if byDomain[domain] == nil {
+			byDomain[domain] = &DomainStatus{Domain: domain}
+			status.Domains = append(status.Domains, byDomain[domain])
+		}
+		byDomain[domain].Records = append(byDomain[domain].Records, ep)
+	}
+	sort.Slice(status.Domains, func(i, j int) bool { return status.Domains[i].Domain < status.Domains[j].Domain })
+	for _, d := range status.Domains {
+		sort.Slice(d.Records, func(i, j int) bool {
+			if d.Records[i].DNSName != d.Records[j].DNSName {
+				return d.Records[i].DNSName < d.Records[j].DNSName
+			}
+			return d.Records[i].RecordType < d.Records[j].RecordType
+		})
+	}
+	return status
+}
+
+// DebugState is the size of the controller state and the last plan, for
+// /debug/state - the records are counted, not listed as in Status.
+type DebugState struct {
+	// Records are the registry records by domain.
+	Records     map[string]int `json:"records"`
+	RecordsTime time.Time      `json:"recordsTime,omitempty"`
+	// Desired is the number of endpoints of the sources.
+	Desired int `json:"desired"`
+
+	LastApplied     *plan.Changes `json:"lastApplied,omitempty"`
+	LastAppliedTime time.Time     `json:"lastAppliedTime,omitempty"`
+	Pending         *plan.Changes `json:"pending,omitempty"`
+	LastSyncTime    time.Time     `json:"lastSyncTime,omitempty"`
+	// Conflicts is the number of ownership conflicts.
+	Conflicts int `json:"conflicts"`
+}
+
+// DebugState returns the size of the state and the last plan.
+func (c *Controller) DebugState() DebugState {
+	c.state.mu.Lock()
+	status := c.state.status
+	records := c.state.records
+	desired := len(c.state.desired)
+	c.state.mu.Unlock()
+
+	byDomain := map[string]int{}
+	for _, ep := range records {
+		byDomain[c.driftDomain(ep.DNSName)]++
+	}
+	return DebugState{
+		Records:         byDomain,
+		RecordsTime:     status.RecordsTime,
+		Desired:         desired,
+		LastApplied:     status.LastApplied,
+		LastAppliedTime: status.LastAppliedTime,
+		Pending:         status.Pending,
+		LastSyncTime:    status.LastSyncTime,
+		Conflicts:       len(status.Conflicts),
+	}
+}
+
+// ServeHTTP serves a read-only dashboard of the controller status, as HTML or
+// as JSON with ?format=json or an Accept: application/json header.
+func (c *Controller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	status := c.Status()
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			log.Errorf("Failed to encode dashboard status: %v", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, status); err != nil {
+		log.Errorf("Failed to render dashboard: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func TestDashboard(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.5"),
+	}, nil)
+	existing := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	existing.Labels = endpoint.Labels{endpoint.OwnerLabelKey: "", endpoint.ResourceLabelKey: "service/default/a"}
+	p := &filteredMockProvider{RecordsStore: []*endpoint.Endpoint{existing}}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+	}
+	assert.True(t, ctrl.LastSyncTime().IsZero())
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.False(t, ctrl.LastSyncTime().IsZero())
+
+	w := httptest.NewRecorder()
+	ctrl.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dashboard?format=json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var status Status
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&status))
+	require.Len(t, status.Domains, 1)
+	assert.Equal(t, "example.com", status.Domains[0].Domain)
+	assert.Len(t, status.Domains[0].Records, 1)
+	require.NotNil(t, status.LastApplied)
+	assert.Len(t, status.LastApplied.Create, 1)
+	assert.Nil(t, status.Pending)
+	assert.Empty(t, status.LastError)
+	assert.Equal(t, ctrl.LastSyncTime().Unix(), status.LastSyncTime.Unix())
+
+	state := ctrl.DebugState()
+	assert.Equal(t, map[string]int{"example.com": 1}, state.Records)
+	assert.Equal(t, 2, state.Desired)
+	require.NotNil(t, state.LastApplied)
+	assert.Len(t, state.LastApplied.Create, 1)
+
+	w = httptest.NewRecorder()
+	ctrl.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dashboard", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "service/default/a")
+	assert.Contains(t, w.Body.String(), "<td>create</td><td>b.example.com</td>")
+
+	w = httptest.NewRecorder()
+	ctrl.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/dashboard", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	sourceEventsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "source_events_total",
+			Help:      "Number of source events scheduling a sync, counted by each federation target.",
+		},
+	)
+	coalescedEvents = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "coalesced_events",
+			Help:      "Number of source events handled by each sync following events.",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(sourceEventsTotal)
+	prometheus.MustRegister(coalescedEvents)
+}
+
+// ScheduleEvent schedules a sync for a source event, the handler of
+// Source.AddEventHandler. Without EventDebounce, it is ScheduleRunOnce. With
+// it, each event postpones the sync until no event was received for
+// EventDebounce, but not later than EventMaxDelay after the first event, so
+// that a burst of events is handled by a single sync.
+func (c *Controller) ScheduleEvent(now time.Time) {
+	sourceEventsTotal.Inc()
+	if c.EventDebounce <= 0 {
+		c.nextRunAtMux.Lock()
+		c.events++
+		c.nextRunAtMux.Unlock()
+		c.ScheduleRunOnce(now)
+		return
+	}
+
+	c.nextRunAtMux.Lock()
+	defer c.nextRunAtMux.Unlock()
+	c.events++
+	if c.events == 1 {
+		c.firstEventAt = now
+	}
+	next := now.Add(c.EventDebounce)
+	if c.EventMaxDelay > 0 {
+		if limit := c.firstEventAt.Add(c.EventMaxDelay); next.After(limit) {
+			next = limit
+		}
+	}
+	// The sync set by the previous event is postponed; an earlier sync, like
+	// the periodic one, is kept and handles the events.
+	if (c.events > 1 && c.nextRunAt.Equal(c.debouncedAt)) || next.Before(c.nextRunAt) {
+		c.nextRunAt = next
+		c.debouncedAt = next
+	}
+}
+
+// coalesce observes the events handled by a sync starting. Called with
+// nextRunAtMux held.
+func (c *Controller) coalesce() {
+	if c.events == 0 {
+		return
+	}
+	coalescedEvents.Observe(float64(c.events))
+	c.events = 0
+	c.debouncedAt = time.Time{}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleEvent(t *testing.T) {
+	ctrl := &Controller{Interval: time.Hour, MinEventSyncInterval: 5 * time.Second}
+
+	now := time.Now()
+	assert.True(t, ctrl.ShouldRunOnce(now))
+
+	// Without debounce, the first event schedules the sync.
+	ctrl.ScheduleEvent(now)
+	ctrl.ScheduleEvent(now.Add(4 * time.Second))
+	assert.True(t, ctrl.ShouldRunOnce(now.Add(5*time.Second)))
+	assert.Zero(t, ctrl.events)
+}
+
+func TestScheduleEventDebounce(t *testing.T) {
+	ctrl := &Controller{Interval: time.Hour, EventDebounce: 10 * time.Second, EventMaxDelay: time.Minute}
+
+	now := time.Now()
+	assert.True(t, ctrl.ShouldRunOnce(now))
+
+	// Each event postpones the sync.
+	ctrl.ScheduleEvent(now)
+	ctrl.ScheduleEvent(now.Add(8 * time.Second))
+	assert.False(t, ctrl.ShouldRunOnce(now.Add(12*time.Second)))
+	assert.True(t, ctrl.ShouldRunOnce(now.Add(18*time.Second)))
+	assert.Zero(t, ctrl.events, "the events are handled by the sync")
+
+	// Not later than the max delay after the first event.
+	now = now.Add(time.Minute)
+	for i := 0; i < 20; i++ {
+		ctrl.ScheduleEvent(now.Add(time.Duration(i) * 5 * time.Second))
+	}
+	assert.Equal(t, 20, ctrl.events)
+	assert.False(t, ctrl.ShouldRunOnce(now.Add(time.Minute-time.Second)))
+	assert.True(t, ctrl.ShouldRunOnce(now.Add(time.Minute)))
+
+	// An earlier sync is not postponed.
+	now = now.Add(2 * time.Minute)
+	ctrl.ScheduleRunOnce(now)
+	ctrl.ScheduleEvent(now)
+	assert.True(t, ctrl.ShouldRunOnce(now))
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+var (
+	domainLockHolder = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "domain_lock_holder",
+			Help:      "Holder of the lock of each domain with changes, 1 for the last seen holder.",
+		},
+		[]string{"domain", "holder"},
+	)
+	domainLockDeferredTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "domain_lock_deferred_changes_total",
+			Help:      "Number of record changes deferred to a later sync as the lock of their domain was held by another controller, by domain.",
+		},
+		[]string{"domain"},
+	)
+	domainLockErrorsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "domain_lock_errors_total",
+			Help:      "Number of failures to lock or unlock a domain.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(domainLockHolder)
+	prometheus.MustRegister(domainLockDeferredTotal)
+	prometheus.MustRegister(domainLockErrorsTotal)
+}
+
+// DomainLock serializes the changes of the controllers managing the same
+// domains, see domainlock.LeaseLock.
+type DomainLock interface {
+	// Lock acquires the lock of the domain, and returns its holder and whether
+	// it was acquired. It is not acquired while held by another controller,
+	// nor if another one released it after since, the time of the records the
+	// changes were planned with.
+	Lock(ctx context.Context, domain string, since time.Time) (string, bool, error)
+	// Unlock releases the lock of the domain.
+	Unlock(ctx context.Context, domain string) error
+}
+
+// lockDomains locks the domains of the changes, and returns the changes of
+// the locked domains and the unlock function, to call once they are applied.
+// The changes of a domain held by another controller are deferred to the
+// next sync, which lists the records again.
+func (c *Controller) lockDomains(ctx context.Context, changes *plan.Changes, since time.Time) (*plan.Changes, func()) {
+	domains := map[string]bool{}
+	for _, eps := range [][]*endpoint.Endpoint{changes.Create, changes.UpdateNew, changes.Delete} {
+		for _, ep := range eps {
+			domains[c.driftDomain(ep.DNSName)] = true
+		}
+	}
+
+	var locked []string
+	for domain := range domains {
+		holder, acquired, err := c.DomainLock.Lock(ctx, domain, since)
+		if err != nil {
+			domainLockErrorsTotal.Inc()
+			log.WithContext(ctx).Warnf("Failed to lock the domain %s: %v", domain, err)
+			domains[domain] = false
+			continue
+		}
+		domainLockHolder.DeletePartialMatch(prometheus.Labels{"domain": domain})
+		domainLockHolder.WithLabelValues(domain, holder).Set(1)
+		if acquired {
+			locked = append(locked, domain)
+			continue
+		}
+		log.WithContext(ctx).Infof("The domain %s is locked by %s, deferring its changes to the next sync", domain, holder)
+		domains[domain] = false
+	}
+
+	allowed, deferred := splitChanges(changes, func(ep *endpoint.Endpoint) bool {
+		return domains[c.driftDomain(ep.DNSName)]
+	})
+	for _, eps := range [][]*endpoint.Endpoint{deferred.Create, deferred.UpdateNew, deferred.Delete} {
+		for _, ep := range eps {
+			domainLockDeferredTotal.WithLabelValues(c.driftDomain(ep.DNSName)).Inc()
+		}
+	}
+	if deferred.HasChanges() {
+		c.forceFullSync()
+		c.ScheduleRunOnce(time.Now())
+	}
+
+	return allowed, func() {
+		for _, domain := range locked {
+			if err := c.DomainLock.Unlock(ctx, domain); err != nil {
+				domainLockErrorsTotal.Inc()
+				log.WithContext(ctx).Warnf("Failed to unlock the domain %s: %v", domain, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// fakeDomainLock is held by the holders of its domains, this controller
+// holding the others.
+type fakeDomainLock struct {
+	holders  map[string]string
+	unlocked []string
+}
+
+func (l *fakeDomainLock) Lock(_ context.Context, domain string, _ time.Time) (string, bool, error) {
+	if holder, ok := l.holders[domain]; ok {
+		return holder, false, nil
+	}
+	return "self", true, nil
+}
+
+func (l *fakeDomainLock) Unlock(_ context.Context, domain string) error {
+	l.unlocked = append(l.unlocked, domain)
+	return nil
+}
+
+func TestDomainLockRunOnce(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+	}, nil)
+	p := &filteredMockProvider{}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	lock := &fakeDomainLock{holders: map[string]string{"example.com": "other"}}
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		Interval:           time.Hour,
+		DomainLock:         lock,
+	}
+	deferred := testutil.ToFloat64(domainLockDeferredTotal.WithLabelValues("example.com"))
+
+	require.True(t, ctrl.ShouldRunOnce(time.Now()))
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, p.ApplyChangesCalls, 1)
+	require.Len(t, p.ApplyChangesCalls[0].Create, 1)
+	assert.Equal(t, "a.example.org", p.ApplyChangesCalls[0].Create[0].DNSName)
+	assert.Equal(t, []string{"example.org"}, lock.unlocked)
+	assert.Equal(t, deferred+1, testutil.ToFloat64(domainLockDeferredTotal.WithLabelValues("example.com")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(domainLockHolder.WithLabelValues("example.com", "other")))
+	assert.True(t, ctrl.ShouldRunOnce(time.Now()), "the deferred changes are retried")
+
+	// Once released, the deferred changes are applied.
+	delete(lock.holders, "example.com")
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, p.ApplyChangesCalls, 2)
+	assert.Len(t, p.ApplyChangesCalls[1].Create, 2)
+	assert.Equal(t, 1.0, testutil.ToFloat64(domainLockHolder.WithLabelValues("example.com", "self")))
+	assert.Zero(t, testutil.ToFloat64(domainLockHolder.WithLabelValues("example.com", "other")))
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+var (
+	driftRecords = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "drift",
+			Name:      "records",
+			Help:      "Number of records differing between the sources and the provider, by domain and change (create, update, delete).",
+		},
+		[]string{"domain", "change"},
+	)
+	driftLastInSyncTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "drift",
+			Name:      "last_in_sync_timestamp_seconds",
+			Help:      "Timestamp of the last drift check finding no difference between the sources and the provider, by domain.",
+		},
+		[]string{"domain"},
+	)
+	driftErrorsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "drift",
+			Name:      "errors_total",
+			Help:      "Number of failed drift checks.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(driftRecords)
+	prometheus.MustRegister(driftLastInSyncTimestamp)
+	prometheus.MustRegister(driftErrorsTotal)
+}
+
+// RunDriftDetection runs DetectDrift every interval until the context is
+// canceled. It is independent of the apply loop - a drift that the controller
+// fails to fix, or that comes back after each sync, stays visible.
+func (c *Controller) RunDriftDetection(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := c.DetectDrift(ctx); err != nil {
+				driftErrorsTotal.Inc()
+				log.Errorf("Failed to detect drift: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// DetectDrift compares the desired endpoints with the provider records and
+// updates the drift metrics, without applying the changes.
+func (c *Controller) DetectDrift(ctx context.Context) (*plan.Changes, error) {
+	c.runMux.Lock()
+	defer c.runMux.Unlock()
+
+	records, endpoints, changes, err := c.calculate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := float64(time.Now().Unix())
+	counts := map[string]map[string]int{}
+	for _, eps := range [][]*endpoint.Endpoint{records, endpoints} {
+		for _, ep := range eps {
+			counts[c.driftDomain(ep.DNSName)] = map[string]int{}
+		}
+	}
+	count := func(change string, eps []*endpoint.Endpoint) {
+		for _, ep := range eps {
+			counts[c.driftDomain(ep.DNSName)][change]++
+		}
+	}
+	count("create", changes.Create)
+	count("update", changes.UpdateNew)
+	count("delete", changes.Delete)
+
+	for domain, n := range counts {
+		for _, change := range []string{"create", "update", "delete"} {
+			driftRecords.WithLabelValues(domain, change).Set(float64(n[change]))
+		}
+		if len(n) == 0 {
+			driftLastInSyncTimestamp.WithLabelValues(domain).Set(now)
+		} else {
+			log.Debugf("Drift in %s: %d to create, %d to update, %d to delete", domain, n["create"], n["update"], n["delete"])
+		}
+	}
+	return changes, nil
+}
+
+// calculate returns the provider records, the desired endpoints and the changes
+// to move the records towards the endpoints. The caller holds the runMux.
+func (c *Controller) calculate(ctx context.Context) ([]*endpoint.Endpoint, []*endpoint.Endpoint, *plan.Changes, error) {
+	records, err := c.Registry.Records(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	c.state.setRecords(records)
+	ctx = context.WithValue(ctx, provider.RecordsContextKey, records)
+	endpoints, err := c.sourceEndpoints(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	endpoints, err = c.Registry.AdjustEndpoints(endpoints)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	changes := c.newPlan(records, endpoints).Calculate().Changes
+	c.state.setPending(changes)
+	return records, endpoints, changes, nil
+}
+
+// driftDomain returns the longest domain filter matching the name, or the last
+// two labels of the name.
+func (c *Controller) driftDomain(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	domain := ""
+	for _, f := range c.DomainFilter.Filters {
+		f = strings.ToLower(strings.Trim(f, "."))
+		if (name == f || strings.HasSuffix(name, "."+f)) && len(f) > len(domain) {
+			domain = f
+		}
+	}
+	if domain != "" {
+		return domain
+	}
+	labels := strings.Split(name, ".")
+	if len(labels) > 2 {
+		labels = labels[len(labels)-2:]
+	}
+	return strings.Join(labels, ".")
+}
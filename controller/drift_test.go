@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func TestDetectDrift(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.5"),
+		endpoint.NewEndpoint("a.sub.example.org", endpoint.RecordTypeA, "1.2.3.6"),
+	}, nil)
+	p := &filteredMockProvider{
+		RecordsStore: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "9.9.9.9"),
+			endpoint.NewEndpoint("a.sub.example.org", endpoint.RecordTypeA, "1.2.3.6"),
+			endpoint.NewEndpoint("manual.sub.example.org", endpoint.RecordTypeA, "1.2.3.7"),
+		},
+	}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		DomainFilter:       endpoint.NewDomainFilter([]string{"sub.example.org"}),
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+	}
+	changes, err := ctrl.DetectDrift(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, changes.UpdateNew, 0, "example.com is not in the domain filter")
+	require.Len(t, changes.Delete, 1)
+	assert.Empty(t, p.ApplyChangesCalls, "drift detection doesn't apply changes")
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(driftRecords.WithLabelValues("sub.example.org", "delete")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(driftRecords.WithLabelValues("sub.example.org", "create")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(driftRecords.WithLabelValues("example.com", "update")))
+	assert.NotZero(t, testutil.ToFloat64(driftLastInSyncTimestamp.WithLabelValues("example.com")))
+	assert.Zero(t, testutil.ToFloat64(driftLastInSyncTimestamp.WithLabelValues("sub.example.org")))
+}
+
+func TestDriftDomain(t *testing.T) {
+	ctrl := &Controller{DomainFilter: endpoint.NewDomainFilter([]string{"example.org", "sub.example.org"})}
+	assert.Equal(t, "sub.example.org", ctrl.driftDomain("a.sub.example.org."))
+	assert.Equal(t, "example.org", ctrl.driftDomain("a.example.org"))
+	assert.Equal(t, "example.com", ctrl.driftDomain("a.b.example.com"))
+}
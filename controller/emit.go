@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/gitops"
+)
+
+// Emit writes the desired records, and the changes against the provider
+// records, with the writer instead of applying them. Only the records managed
+// by the controller - matching the domain filter and the managed record types -
+// are written.
+func (c *Controller) Emit(ctx context.Context, w *gitops.Writer) error {
+	c.runMux.Lock()
+	defer c.runMux.Unlock()
+
+	_, endpoints, changes, err := c.calculate(ctx)
+	if err != nil {
+		return err
+	}
+	var desired []*endpoint.Endpoint
+	for _, ep := range endpoints {
+		if c.DomainFilter.Match(ep.DNSName) && slices.Contains(c.ManagedRecordTypes, ep.RecordType) && !slices.Contains(c.ExcludeRecordTypes, ep.RecordType) {
+			desired = append(desired, ep)
+		}
+	}
+	changed, err := w.Write(ctx, desired, changes)
+	if err != nil {
+		c.state.setError(err)
+		return err
+	}
+	if changed {
+		log.Infof("Wrote %d records and %d changes to %s", len(desired), gitops.CountChanges(changes), w.Dir)
+	}
+	lastSyncTimestamp.SetToCurrentTime()
+	return nil
+}
+
+// RunEmit runs Emit every interval until the context is canceled.
+func (c *Controller) RunEmit(ctx context.Context, w *gitops.Writer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Emit(ctx, w); err != nil {
+			log.Errorf("Failed to write the records to %s: %v", w.Dir, err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
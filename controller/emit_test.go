@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/pkg/gitops"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func TestEmit(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "1.2.3.5"),
+		endpoint.NewEndpoint("c.example.org", endpoint.RecordTypeTXT, "text"),
+		endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.6"),
+	}, nil)
+	p := &filteredMockProvider{
+		RecordsStore: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		},
+	}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		DomainFilter:       endpoint.NewDomainFilter([]string{"example.org"}),
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+	}
+	w := &gitops.Writer{Dir: t.TempDir()}
+	require.NoError(t, ctrl.Emit(context.Background(), w))
+	assert.Empty(t, p.ApplyChangesCalls, "emit doesn't apply changes")
+
+	records, err := gitops.ReadRecords(filepath.Join(w.Dir, gitops.RecordsFile))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "a.example.org", records[0].DNSName)
+	assert.Equal(t, "b.example.org", records[1].DNSName)
+	diff, err := os.ReadFile(filepath.Join(w.Dir, gitops.ChangesFile))
+	require.NoError(t, err)
+	assert.Equal(t, "+ b.example.org A - 1.2.3.5\n", string(diff))
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// ChangeReporter reports the changes applied to the registry, or rejected by
+// it, like as Kubernetes events of the resources of the endpoints.
+type ChangeReporter interface {
+	ReportChanges(ctx context.Context, changes *plan.Changes, err error)
+}
+
+// applyChanges applies the changes to the registry, and reports them.
+func (c *Controller) applyChanges(ctx context.Context, changes *plan.Changes) error {
+	err := c.Registry.ApplyChanges(ctx, changes)
+	if c.ChangeReporter != nil {
+		c.ChangeReporter.ReportChanges(ctx, changes, err)
+	}
+	return err
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+type changeRecorder struct {
+	reported []*plan.Changes
+	errs     []error
+}
+
+func (r *changeRecorder) ReportChanges(_ context.Context, changes *plan.Changes, err error) {
+	r.reported = append(r.reported, changes)
+	r.errs = append(r.errs, err)
+}
+
+func TestControllerReportsChanges(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4")}, nil)
+	p := &filteredMockProvider{}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	recorder := &changeRecorder{}
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		ChangeReporter:     recorder,
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, recorder.reported, 1)
+	assert.NoError(t, recorder.errs[0])
+	require.Len(t, recorder.reported[0].Create, 1)
+	assert.Equal(t, "foo.example.org", recorder.reported[0].Create[0].DNSName)
+}
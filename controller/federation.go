@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"sigs.k8s.io/external-dns/pkg/logging"
+)
+
+var (
+	federationSyncErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "federation_sync_errors_total",
+			Help:      "Number of failed syncs of the federation targets, by target.",
+		},
+		[]string{"target"},
+	)
+	federationLastSync = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "federation_last_sync_timestamp_seconds",
+			Help:      "Timestamp of the last successful sync of the federation targets, by target.",
+		},
+		[]string{"target"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(federationSyncErrors)
+	prometheus.MustRegister(federationLastSync)
+}
+
+// FederationTarget is a controller of a federation, with its own registry,
+// domain filter and policy.
+type FederationTarget struct {
+	Name string
+	*Controller
+}
+
+// Federation syncs the same sources to several targets, for example the private
+// zones of a provider and the public zones of another. The targets are synced
+// independently: the error of a target is logged and retried at its next sync,
+// without stopping the other targets.
+type Federation struct {
+	Targets []*FederationTarget
+}
+
+// RunOnce syncs all the targets concurrently, and returns their errors.
+func (f *Federation) RunOnce(ctx context.Context) error {
+	errs := make([]error, len(f.Targets))
+	var wg sync.WaitGroup
+	for i, t := range f.Targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = f.runTarget(ctx, t)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// ScheduleRunOnce schedules a sync of all the targets.
+func (f *Federation) ScheduleRunOnce(now time.Time) {
+	for _, t := range f.Targets {
+		t.ScheduleRunOnce(now)
+	}
+}
+
+// ScheduleEvent schedules a sync of all the targets for a source event,
+// debounced by each target.
+func (f *Federation) ScheduleEvent(now time.Time) {
+	for _, t := range f.Targets {
+		t.ScheduleEvent(now)
+	}
+}
+
+// Run syncs each target at its interval until the context is canceled.
+func (f *Federation) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, t := range f.Targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				if t.ShouldRunOnce(time.Now()) {
+					// Errors are logged and counted by runTarget.
+					_ = f.runTarget(ctx, t)
+				}
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	logging.For("controller").Info("Terminating federation loop")
+}
+
+func (f *Federation) runTarget(ctx context.Context, t *FederationTarget) error {
+	if err := t.RunOnce(ctx); err != nil {
+		federationSyncErrors.WithLabelValues(t.Name).Inc()
+		logging.For("controller").Error("Failed to sync federation target", "target", t.Name, "error", err)
+		return fmt.Errorf("target %s: %w", t.Name, err)
+	}
+	federationLastSync.WithLabelValues(t.Name).SetToCurrentTime()
+	return nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func newFederationTarget(t *testing.T, name string, source *testutils.MockSource, p provider.Provider, domains ...string) *FederationTarget {
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	return &FederationTarget{Name: name, Controller: &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		DomainFilter:       endpoint.NewDomainFilter(domains),
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		Interval:           time.Hour,
+	}}
+}
+
+func TestFederationRunOnce(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.internal.example.com", endpoint.RecordTypeA, "10.0.0.1"),
+		endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+	}, nil)
+	internal := &filteredMockProvider{}
+	public := &filteredMockProvider{}
+	f := &Federation{Targets: []*FederationTarget{
+		newFederationTarget(t, "internal", source, internal, "internal.example.com"),
+		newFederationTarget(t, "public", source, public, "example.com"),
+		newFederationTarget(t, "broken", source, &errorMockProvider{}, "example.org"),
+	}}
+	f.Targets[1].DomainFilter = endpoint.NewDomainFilterWithExclusions([]string{"example.com"}, []string{"internal.example.com"})
+
+	errorsBefore := testutil.ToFloat64(federationSyncErrors.WithLabelValues("broken"))
+	err := f.RunOnce(context.Background())
+	assert.ErrorContains(t, err, "target broken")
+	assert.NotContains(t, err.Error(), "target public")
+
+	require.Len(t, internal.ApplyChangesCalls, 1)
+	require.Len(t, internal.ApplyChangesCalls[0].Create, 1)
+	assert.Equal(t, "a.internal.example.com", internal.ApplyChangesCalls[0].Create[0].DNSName)
+	require.Len(t, public.ApplyChangesCalls, 1)
+	require.Len(t, public.ApplyChangesCalls[0].Create, 1)
+	assert.Equal(t, "www.example.com", public.ApplyChangesCalls[0].Create[0].DNSName)
+
+	assert.Equal(t, errorsBefore+1, testutil.ToFloat64(federationSyncErrors.WithLabelValues("broken")))
+	assert.NotZero(t, testutil.ToFloat64(federationLastSync.WithLabelValues("public")))
+	assert.Zero(t, testutil.ToFloat64(federationLastSync.WithLabelValues("broken")))
+}
+
+func TestFederationRun(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+	}, nil)
+	ok := &filteredMockProvider{}
+	f := &Federation{Targets: []*FederationTarget{
+		newFederationTarget(t, "broken-run", source, &errorMockProvider{}),
+		newFederationTarget(t, "ok", source, ok),
+	}}
+
+	errorsBefore := testutil.ToFloat64(federationSyncErrors.WithLabelValues("broken-run"))
+	federationLastSync.DeleteLabelValues("ok")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		f.Run(ctx)
+		close(done)
+	}()
+	// The error of a target doesn't stop the others.
+	assert.Eventually(t, func() bool { return testutil.ToFloat64(federationLastSync.WithLabelValues("ok")) > 0 }, 5*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(federationSyncErrors.WithLabelValues("broken-run")) == errorsBefore+1
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+}
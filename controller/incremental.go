@@ -0,0 +1,191 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+var (
+	syncsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "syncs_total",
+			Help:      "Number of syncs, by mode (full, listing every record, or incremental, planning the changed names only).",
+		},
+		[]string{"mode"},
+	)
+	plannedNames = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "planned_names",
+			Help:      "Number of DNS names planned by the last incremental sync.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(syncsTotal)
+	prometheus.MustRegister(plannedNames)
+}
+
+// incrementalState is the state kept between the syncs with a Journal.
+type incrementalState struct {
+	// cursor is the journal position of the records, empty before the
+	// first full sync and after a failed one.
+	cursor string
+	// fullSyncAt is the time of the last full sync.
+	fullSyncAt time.Time
+	// records are the registry records of the last full sync, updated with
+	// the changes applied since.
+	records []*endpoint.Endpoint
+	// desired are the endpoints of the last applied plan, by normalized name.
+	desired map[string]string
+}
+
+// currentRecords returns the registry records, compacted, and whether they
+// were listed in full. Without a Journal, they always are. With one, they are
+// listed at the first sync, every FullSyncInterval, and when the journal
+// reports a change made outside of the controller; the syncs in between reuse
+// the records of the previous one.
+func (c *Controller) currentRecords(ctx context.Context) ([]*endpoint.Endpoint, bool, error) {
+	if records, ok := c.cachedRecords(ctx); ok {
+		return records, true, nil
+	}
+	if c.Journal == nil {
+		records, err := c.Registry.Records(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		return records, true, nil
+	}
+
+	s := &c.incremental
+	if s.cursor != "" && (c.FullSyncInterval <= 0 || time.Since(s.fullSyncAt) < c.FullSyncInterval) {
+		changes, err := c.Journal.Changes(ctx, s.cursor)
+		switch {
+		case errors.Is(err, provider.ErrJournalExpired):
+			log.WithContext(ctx).Info("The provider journal expired, listing all records")
+		case err != nil:
+			return nil, false, fmt.Errorf("reading the provider journal: %w", err)
+		case len(changes.Names) > 0:
+			log.WithContext(ctx).Infof("The provider journal reports %d changed names, listing all records", len(changes.Names))
+		default:
+			s.cursor = changes.Cursor
+			syncsTotal.WithLabelValues("incremental").Inc()
+			return s.records, false, nil
+		}
+	}
+
+	// The position is read before the records, the changes made while they
+	// are listed are reported by the next sync.
+	s.cursor = ""
+	changes, err := c.Journal.Changes(ctx, "")
+	if err != nil {
+		return nil, false, fmt.Errorf("reading the provider journal: %w", err)
+	}
+	records, err := c.Registry.Records(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	syncsTotal.WithLabelValues("full").Inc()
+	// A compact copy of the records is kept until the next full sync, this
+	// sync uses the records of the registry.
+	s.cursor = changes.Cursor
+	s.fullSyncAt = time.Now()
+	s.records = endpoint.Compact(records)
+	s.desired = nil
+	return records, true, nil
+}
+
+// affected returns the current records and the desired endpoints of the
+// names whose desired endpoints changed since the last applied plan.
+func (c *Controller) affected(current, desired []*endpoint.Endpoint) ([]*endpoint.Endpoint, []*endpoint.Endpoint) {
+	names := map[string]bool{}
+	byName := desiredByName(desired)
+	for name, fingerprint := range byName {
+		if c.incremental.desired[name] != fingerprint {
+			names[name] = true
+		}
+	}
+	for name := range c.incremental.desired {
+		if _, ok := byName[name]; !ok {
+			names[name] = true
+		}
+	}
+	plannedNames.Set(float64(len(names)))
+	return filterNames(current, names), filterNames(desired, names)
+}
+
+// applied updates the state with the changes applied to the registry, and
+// the desired endpoints of the plan.
+func (c *Controller) applied(changes *plan.Changes, desired []*endpoint.Endpoint) {
+	s := &c.incremental
+	if c.Journal == nil || s.cursor == "" {
+		return
+	}
+	s.records = withChanges(s.records, changes)
+	s.desired = desiredByName(desired)
+}
+
+// forceFullSync makes the next sync list every record and plan every name.
+func (c *Controller) forceFullSync() {
+	c.incremental.cursor = ""
+}
+
+// desiredByName returns a fingerprint of the endpoints of each name.
+func desiredByName(endpoints []*endpoint.Endpoint) map[string]string {
+	byName := map[string][]string{}
+	for _, ep := range endpoints {
+		name := normalizeName(ep.DNSName)
+		byName[name] = append(byName[name], fmt.Sprint(ep.RecordTTL, ep.RecordType, ep.SetIdentifier, ep.Targets, ep.ProviderSpecific, ep.Labels))
+	}
+	fingerprints := make(map[string]string, len(byName))
+	for name, eps := range byName {
+		sort.Strings(eps)
+		fingerprints[name] = strings.Join(eps, "\n")
+	}
+	return fingerprints
+}
+
+func filterNames(endpoints []*endpoint.Endpoint, names map[string]bool) []*endpoint.Endpoint {
+	filtered := []*endpoint.Endpoint{}
+	for _, ep := range endpoints {
+		if names[normalizeName(ep.DNSName)] {
+			filtered = append(filtered, ep)
+		}
+	}
+	return filtered
+}
+
+func normalizeName(name string) string {
+	return strings.TrimSuffix(strings.TrimSpace(strings.ToLower(name)), ".")
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// mockJournal reports the names set in Names once, and counts the positions.
+type mockJournal struct {
+	Names    []string
+	Expired  bool
+	position int
+}
+
+func (j *mockJournal) Changes(ctx context.Context, cursor string) (*provider.JournalChanges, error) {
+	if j.Expired {
+		j.Expired = false
+		return nil, provider.ErrJournalExpired
+	}
+	j.position++
+	changes := &provider.JournalChanges{Cursor: strconv.Itoa(j.position)}
+	if cursor != "" {
+		changes.Names, j.Names = j.Names, nil
+	}
+	return changes, nil
+}
+
+func TestIncrementalRunOnce(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "1.2.3.5"),
+	}, nil).Times(2)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "1.2.3.6"),
+	}, nil)
+	p := &filteredMockProvider{
+		RecordsStore: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "9.9.9.9"),
+			endpoint.NewEndpoint("manual.example.org", endpoint.RecordTypeA, "1.2.3.7"),
+		},
+	}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	journal := &mockJournal{}
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		Journal:            journal,
+	}
+	ctx := context.Background()
+
+	// The first sync lists and plans every record.
+	require.NoError(t, ctrl.RunOnce(ctx))
+	assert.Equal(t, 1, p.RecordsCallCount)
+	require.Len(t, p.ApplyChangesCalls, 1)
+	assert.Len(t, p.ApplyChangesCalls[0].Create, 1)
+	assert.Len(t, p.ApplyChangesCalls[0].UpdateNew, 1)
+	assert.Len(t, p.ApplyChangesCalls[0].Delete, 1)
+
+	// Nothing changed in the sources or in the journal.
+	require.NoError(t, ctrl.RunOnce(ctx))
+	assert.Equal(t, 1, p.RecordsCallCount, "the records are not listed again")
+	assert.Len(t, p.ApplyChangesCalls, 1)
+
+	// Only the changed name is planned, against the records updated with the applied changes.
+	require.NoError(t, ctrl.RunOnce(ctx))
+	assert.Equal(t, 1, p.RecordsCallCount)
+	require.Len(t, p.ApplyChangesCalls, 2)
+	assert.Empty(t, p.ApplyChangesCalls[1].Create)
+	require.Len(t, p.ApplyChangesCalls[1].UpdateNew, 1)
+	assert.Equal(t, "b.example.org", p.ApplyChangesCalls[1].UpdateNew[0].DNSName)
+	assert.Equal(t, endpoint.Targets{"1.2.3.6"}, p.ApplyChangesCalls[1].UpdateNew[0].Targets)
+
+	// A change made outside of the controller lists the records again.
+	journal.Names = []string{"manual.example.org"}
+	require.NoError(t, ctrl.RunOnce(ctx))
+	assert.Equal(t, 2, p.RecordsCallCount)
+
+	journal.Expired = true
+	require.NoError(t, ctrl.RunOnce(ctx))
+	assert.Equal(t, 3, p.RecordsCallCount)
+}
+
+func TestAffected(t *testing.T) {
+	ctrl := &Controller{}
+	ctrl.incremental.desired = desiredByName([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "1.2.3.5"),
+		endpoint.NewEndpoint("c.example.org", endpoint.RecordTypeA, "1.2.3.6"),
+	})
+	current := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "1.2.3.5"),
+		endpoint.NewEndpoint("c.example.org", endpoint.RecordTypeA, "1.2.3.6"),
+	}
+	relabeled := endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "1.2.3.5")
+	relabeled.Labels[endpoint.ResourceLabelKey] = "service/default/b"
+	desired := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("A.example.org.", endpoint.RecordTypeA, "1.2.3.4"),
+		relabeled,
+		endpoint.NewEndpoint("d.example.org", endpoint.RecordTypeA, "1.2.3.7"),
+	}
+
+	current, desired = ctrl.affected(current, desired)
+	var names []string
+	for _, ep := range current {
+		names = append(names, ep.DNSName)
+	}
+	assert.Equal(t, []string{"b.example.org", "c.example.org"}, names)
+	names = nil
+	for _, ep := range desired {
+		names = append(names, ep.DNSName)
+	}
+	assert.Equal(t, []string{"b.example.org", "d.example.org"}, names)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+var (
+	leaseRenewalsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "lease_renewals_total",
+			Help:      "Number of records whose lease was renewed, with --record-lease.",
+		},
+	)
+	leaseExpiredRecordsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "lease_expired_records_total",
+			Help:      "Number of records of other owners deleted as their lease expired, with --record-lease.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(leaseRenewalsTotal)
+	prometheus.MustRegister(leaseExpiredRecordsTotal)
+}
+
+// lease stamps the records created and updated with the expiry of their
+// Lease, and adds to the changes the renewal of the records of this instance
+// past half their lease, and the deletion of the records of other owners whose
+// lease expired - the records left by an instance no longer running, like the
+// one of a crashed cluster. The deletions are filtered by the Policy.
+func (c *Controller) lease(ctx context.Context, current []*endpoint.Endpoint, changes *plan.Changes, now time.Time) {
+	if c.Lease <= 0 {
+		return
+	}
+	expires := strconv.FormatInt(now.Add(c.Lease).Unix(), 10)
+	for i, ep := range changes.Create {
+		changes.Create[i] = withLabel(ep, endpoint.ExpiresLabelKey, expires)
+	}
+	for i, ep := range changes.UpdateNew {
+		changes.UpdateNew[i] = withLabel(ep, endpoint.ExpiresLabelKey, expires)
+	}
+
+	changed := map[endpoint.EndpointKey]bool{}
+	for _, ep := range changes.UpdateOld {
+		changed[ep.Key()] = true
+	}
+	for _, ep := range changes.Delete {
+		changed[ep.Key()] = true
+	}
+	ownerID := c.Registry.OwnerID()
+	registryFilter := c.Registry.GetDomainFilter()
+	domainFilter := endpoint.MatchAllDomainFilters{&c.DomainFilter, &registryFilter}
+	var expired []*endpoint.Endpoint
+	for _, ep := range current {
+		if changed[ep.Key()] || !domainFilter.Match(ep.DNSName) || !plan.IsManagedRecord(ep.RecordType, c.ManagedRecordTypes, c.ExcludeRecordTypes) {
+			continue
+		}
+		if ownerID == "" || ep.IsOwnedBy(ownerID) {
+			if expiry, ok := ep.Expiry(); !ok || expiry.Sub(now) < c.Lease/2 {
+				changes.UpdateOld = append(changes.UpdateOld, ep)
+				changes.UpdateNew = append(changes.UpdateNew, withLabel(ep, endpoint.ExpiresLabelKey, expires))
+				leaseRenewalsTotal.Inc()
+			}
+		} else if ep.Expired(now) {
+			expired = append(expired, ep)
+		}
+	}
+	for _, ep := range c.Policy.Apply(&plan.Changes{Delete: expired}).Delete {
+		log.WithContext(ctx).Warnf("Deleting the record %s %s of owner %s, its lease expired", ep.DNSName, ep.RecordType, ep.Labels[endpoint.OwnerLabelKey])
+		changes.Delete = append(changes.Delete, ep)
+		leaseExpiredRecordsTotal.Inc()
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func leasedEndpoint(name, owner, expires string) *endpoint.Endpoint {
+	ep := endpoint.NewEndpoint(name, endpoint.RecordTypeA, "10.0.0.1")
+	ep.Labels[endpoint.OwnerLabelKey] = owner
+	if expires != "" {
+		ep.Labels[endpoint.ExpiresLabelKey] = expires
+	}
+	return ep
+}
+
+func TestLease(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	r, err := registry.NewTXTRegistry(&filteredMockProvider{}, "", "", "owner", 0, "", []string{endpoint.RecordTypeA}, nil, false, nil)
+	require.NoError(t, err)
+	ctrl := &Controller{
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		Lease:              time.Hour,
+	}
+	renewed := leasedEndpoint("renewed.example.org", "owner", "1700001000")
+	current := []*endpoint.Endpoint{
+		renewed,
+		leasedEndpoint("leased.example.org", "owner", "1700003000"),
+		leasedEndpoint("expired.example.org", "other", "1700000000"),
+		leasedEndpoint("alive.example.org", "other", "1700000001"),
+		leasedEndpoint("forever.example.org", "other", ""),
+	}
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("new.example.org", endpoint.RecordTypeA, "10.0.0.2")}}
+
+	ctrl.lease(context.Background(), current, changes, now)
+	expires := "1700003600"
+	assert.Equal(t, expires, changes.Create[0].Labels[endpoint.ExpiresLabelKey])
+	assert.Equal(t, []*endpoint.Endpoint{renewed}, changes.UpdateOld)
+	require.Len(t, changes.UpdateNew, 1)
+	assert.Equal(t, expires, changes.UpdateNew[0].Labels[endpoint.ExpiresLabelKey])
+	assert.Equal(t, "1700001000", renewed.Labels[endpoint.ExpiresLabelKey])
+	assert.Equal(t, []*endpoint.Endpoint{current[2]}, changes.Delete)
+
+	// The deletions are filtered by the policy.
+	ctrl.Policy = &plan.UpsertOnlyPolicy{}
+	changes = &plan.Changes{}
+	ctrl.lease(context.Background(), current, changes, now)
+	assert.Empty(t, changes.Delete)
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The exit codes of the --once mode, for CI pipelines and cron jobs. Like
+// 'git diff --exit-code', ExitDrift means the records are not in sync.
+const (
+	ExitOK    = 0
+	ExitError = 1
+	ExitDrift = 2
+)
+
+// RunOnceExitCode runs a single sync and returns the exit code of the command:
+// ExitError if the sync failed, ExitDrift if the controller is in dry run and
+// there are changes to apply, ExitOK otherwise.
+func (c *Controller) RunOnceExitCode(ctx context.Context) int {
+	if err := c.RunOnce(ctx); err != nil {
+		log.Errorf("Failed to sync: %v", err)
+		return ExitError
+	}
+	if c.DryRun && c.Status().Pending != nil {
+		return ExitDrift
+	}
+	return ExitOK
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/provider/fake"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func newOnceController(t *testing.T, p provider.Provider, err error) *Controller {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+	}, err)
+	r, rerr := registry.NewNoopRegistry(p)
+	require.NoError(t, rerr)
+	return &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+	}
+}
+
+func newOnceProvider() *fake.Provider {
+	return fake.New(fake.WithDomainFilter(endpoint.NewDomainFilter([]string{"example.com"})))
+}
+
+func TestRunOnceExitCode(t *testing.T) {
+	ctx := context.Background()
+	p := newOnceProvider()
+
+	// Dry run: the changes are printed, not applied.
+	var out bytes.Buffer
+	ctrl := newOnceController(t, p, nil)
+	ctrl.DryRun = true
+	ctrl.DryRunOutput = &out
+	assert.Equal(t, ExitDrift, ctrl.RunOnceExitCode(ctx))
+	assert.Equal(t, "+ a.example.com A - 1.2.3.4\n", out.String())
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+	assert.Empty(t, p.Changes())
+	assert.False(t, ctrl.LastSyncTime().IsZero())
+
+	ctrl.DryRun = false
+	assert.Equal(t, ExitOK, ctrl.RunOnceExitCode(ctx))
+	records, err = p.Records(ctx)
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Len(t, p.Changes(), 1)
+
+	// In sync.
+	out.Reset()
+	ctrl.DryRun = true
+	assert.Equal(t, ExitOK, ctrl.RunOnceExitCode(ctx))
+	assert.Empty(t, out.String())
+
+	ctrl = newOnceController(t, p, errors.New("source error"))
+	assert.Equal(t, ExitError, ctrl.RunOnceExitCode(ctx))
+}
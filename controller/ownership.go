@@ -0,0 +1,159 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// The policies of the ownership conflicts, see Controller.ConflictPolicy.
+const (
+	// ConflictPolicySkip skips the changes, only counted and reported.
+	ConflictPolicySkip = "skip"
+	// ConflictPolicyWarn skips the changes, and logs a warning for each one.
+	ConflictPolicyWarn = "warn"
+	// ConflictPolicyAdopt applies the changes, taking the ownership of the
+	// records (see plan.Plan.AdoptForeign).
+	ConflictPolicyAdopt = "adopt"
+	// ConflictPolicyAdoptUnowned takes the ownership of the records of no
+	// owner, even unchanged, and skips the changes to the records of another
+	// owner with a warning (see plan.Plan.AdoptUnowned).
+	ConflictPolicyAdoptUnowned = "adopt-unowned"
+)
+
+var (
+	ownershipConflicts = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "ownership_conflicts",
+			Help:      "Number of changes of the desired names to records of another owner, or of no owner, by change (create, update, delete).",
+		},
+		[]string{"change"},
+	)
+	ownershipConflictsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "ownership_conflicts_total",
+			Help:      "Number of new ownership conflicts, by change and owner of the record, empty for no owner.",
+		},
+		[]string{"change", "owner"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(ownershipConflicts)
+	prometheus.MustRegister(ownershipConflictsTotal)
+}
+
+// ConflictReporter reports the new ownership conflicts, like as Kubernetes
+// events, with ConflictPolicyWarn or ConflictPolicyAdopt.
+type ConflictReporter interface {
+	ReportConflicts(ctx context.Context, conflicts []plan.OwnershipConflict)
+}
+
+// reportConflicts records the ownership conflicts of a plan, and reports the
+// new ones. full is false for an incremental sync, which only plans the
+// affected names: the conflicts of the other names are kept.
+func (c *Controller) reportConflicts(ctx context.Context, conflicts []plan.OwnershipConflict, full bool) {
+	known := c.conflicts
+	if full || known == nil {
+		c.conflicts = map[conflictKey]plan.OwnershipConflict{}
+	}
+	var added []plan.OwnershipConflict
+	for _, conflict := range conflicts {
+		key := newConflictKey(conflict)
+		if _, ok := known[key]; !ok {
+			added = append(added, conflict)
+			ownershipConflictsTotal.WithLabelValues(conflict.Change, conflict.Owner).Inc()
+			c.logConflict(ctx, conflict)
+		}
+		c.conflicts[key] = conflict
+	}
+
+	counts := map[string]int{}
+	all := make([]plan.OwnershipConflict, 0, len(c.conflicts))
+	for _, conflict := range c.conflicts {
+		counts[conflict.Change]++
+		all = append(all, conflict)
+	}
+	for _, change := range []string{plan.ConflictCreate, plan.ConflictUpdate, plan.ConflictDelete} {
+		ownershipConflicts.WithLabelValues(change).Set(float64(counts[change]))
+	}
+	sort.Slice(all, func(i, j int) bool { return newConflictKey(all[i]).less(newConflictKey(all[j])) })
+	c.state.setConflicts(all)
+
+	if c.ConflictReporter != nil && c.ConflictPolicy != ConflictPolicySkip && c.ConflictPolicy != "" && len(added) > 0 {
+		c.ConflictReporter.ReportConflicts(ctx, added)
+	}
+}
+
+func (c *Controller) logConflict(ctx context.Context, conflict plan.OwnershipConflict) {
+	owner := conflict.Owner
+	if owner == "" {
+		owner = "no owner"
+	}
+	adopted := c.ConflictPolicy == ConflictPolicyAdopt || (c.ConflictPolicy == ConflictPolicyAdoptUnowned && conflict.Owner == "")
+	switch {
+	case adopted && conflict.Change != plan.ConflictDelete:
+		log.WithContext(ctx).Infof("Adopting the record %s %s of %s to %s it", conflict.Record.DNSName, conflict.Record.RecordType, owner, conflict.Change)
+	case c.ConflictPolicy == ConflictPolicyWarn || c.ConflictPolicy == ConflictPolicyAdopt || c.ConflictPolicy == ConflictPolicyAdoptUnowned:
+		log.WithContext(ctx).Warnf("Skipping the %s of %s %s conflicting with the record of %s", conflict.Change, conflict.Record.DNSName, conflict.Record.RecordType, owner)
+	default:
+		log.WithContext(ctx).Debugf("Skipping the %s of %s %s conflicting with the record of %s", conflict.Change, conflict.Record.DNSName, conflict.Record.RecordType, owner)
+	}
+}
+
+// conflictKey identifies a conflict across the syncs.
+type conflictKey struct {
+	change  string
+	record  endpoint.EndpointKey
+	desired endpoint.EndpointKey
+	owner   string
+}
+
+func newConflictKey(conflict plan.OwnershipConflict) conflictKey {
+	key := conflictKey{change: conflict.Change, record: conflict.Record.Key(), owner: conflict.Owner}
+	if conflict.Desired != nil {
+		key.desired = conflict.Desired.Key()
+	}
+	return key
+}
+
+func (k conflictKey) less(o conflictKey) bool {
+	if k.record.DNSName != o.record.DNSName {
+		return k.record.DNSName < o.record.DNSName
+	}
+	if k.record.RecordType != o.record.RecordType {
+		return k.record.RecordType < o.record.RecordType
+	}
+	if k.record.SetIdentifier != o.record.SetIdentifier {
+		return k.record.SetIdentifier < o.record.SetIdentifier
+	}
+	if k.change != o.change {
+		return k.change < o.change
+	}
+	return k.desired.RecordType < o.desired.RecordType
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// ownedRegistry is a noop registry with an owner.
+type ownedRegistry struct {
+	*registry.NoopRegistry
+}
+
+func (r ownedRegistry) OwnerID() string {
+	return "owner"
+}
+
+type conflictRecorder struct {
+	reported []plan.OwnershipConflict
+}
+
+func (r *conflictRecorder) ReportConflicts(_ context.Context, conflicts []plan.OwnershipConflict) {
+	r.reported = append(r.reported, conflicts...)
+}
+
+func newConflictController(t *testing.T, policy string) (*Controller, *filteredMockProvider, *conflictRecorder) {
+	record := endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	record.Labels[endpoint.OwnerLabelKey] = "other"
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "5.6.7.8")}, nil)
+	p := &filteredMockProvider{RecordsStore: []*endpoint.Endpoint{record}}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	recorder := &conflictRecorder{}
+	return &Controller{
+		Source:             source,
+		Registry:           ownedRegistry{r},
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		ConflictPolicy:     policy,
+		ConflictReporter:   recorder,
+	}, p, recorder
+}
+
+func TestConflictPolicyWarn(t *testing.T) {
+	ctrl, p, recorder := newConflictController(t, ConflictPolicyWarn)
+	total := testutil.ToFloat64(ownershipConflictsTotal.WithLabelValues(plan.ConflictUpdate, "other"))
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Empty(t, p.ApplyChangesCalls)
+	require.Len(t, recorder.reported, 1)
+	assert.Equal(t, plan.ConflictUpdate, recorder.reported[0].Change)
+	assert.Equal(t, "other", recorder.reported[0].Owner)
+	assert.Equal(t, 1.0, testutil.ToFloat64(ownershipConflicts.WithLabelValues(plan.ConflictUpdate)))
+	assert.Equal(t, total+1, testutil.ToFloat64(ownershipConflictsTotal.WithLabelValues(plan.ConflictUpdate, "other")))
+	assert.Len(t, ctrl.Status().Conflicts, 1)
+
+	// A known conflict is reported once.
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Len(t, recorder.reported, 1)
+	assert.Equal(t, total+1, testutil.ToFloat64(ownershipConflictsTotal.WithLabelValues(plan.ConflictUpdate, "other")))
+	assert.Len(t, ctrl.Status().Conflicts, 1)
+}
+
+func TestConflictPolicySkip(t *testing.T) {
+	ctrl, p, recorder := newConflictController(t, ConflictPolicySkip)
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Empty(t, p.ApplyChangesCalls)
+	assert.Empty(t, recorder.reported, "the conflicts are only reported with warn or adopt")
+	assert.Len(t, ctrl.Status().Conflicts, 1)
+}
+
+func TestConflictPolicyAdopt(t *testing.T) {
+	ctrl, p, recorder := newConflictController(t, ConflictPolicyAdopt)
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, p.ApplyChangesCalls, 1)
+	changes := p.ApplyChangesCalls[0]
+	require.Len(t, changes.UpdateNew, 1)
+	assert.Equal(t, "owner", changes.UpdateNew[0].Labels[endpoint.OwnerLabelKey])
+	assert.Equal(t, "other", changes.UpdateOld[0].Labels[endpoint.OwnerLabelKey])
+	assert.Len(t, recorder.reported, 1)
+}
+
+func TestConflictPolicyAdoptUnowned(t *testing.T) {
+	ctrl, p, recorder := newConflictController(t, ConflictPolicyAdoptUnowned)
+	handmade := endpoint.NewEndpoint("bar.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	p.RecordsStore = append(p.RecordsStore, handmade)
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "5.6.7.8"),
+		endpoint.NewEndpoint("bar.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+	}, nil)
+	ctrl.Source = source
+
+	// The record of no owner is claimed unchanged, the one of another owner
+	// is skipped.
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, p.ApplyChangesCalls, 1)
+	changes := p.ApplyChangesCalls[0]
+	require.Len(t, changes.UpdateNew, 1)
+	assert.Equal(t, "bar.example.org", changes.UpdateNew[0].DNSName)
+	assert.Equal(t, endpoint.Targets{"1.2.3.4"}, changes.UpdateNew[0].Targets)
+	assert.Equal(t, "owner", changes.UpdateNew[0].Labels[endpoint.OwnerLabelKey])
+	assert.Len(t, recorder.reported, 2)
+}
+
+func TestStrictShadowing(t *testing.T) {
+	ctrl, p, _ := newConflictController(t, ConflictPolicySkip)
+	ctrl.StrictShadowing = true
+
+	err := ctrl.RunOnce(context.Background())
+	var shadowing plan.ShadowingError
+	require.ErrorAs(t, err, &shadowing)
+	require.Len(t, shadowing, 1)
+	assert.Equal(t, "other", shadowing[0].Record.Labels[endpoint.OwnerLabelKey])
+	assert.Empty(t, p.ApplyChangesCalls)
+	assert.Equal(t, 1.0, testutil.ToFloat64(shadowedRecords))
+}
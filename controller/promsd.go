@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// invalidLabelChars are the characters not allowed in Prometheus label names.
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// TargetGroup is a target group of the Prometheus HTTP service discovery.
+type TargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// TargetGroups returns a target group for each DNS name with address records
+// in the endpoints of the last sync, matching the domain filters and the
+// selector labels. The target is the DNS name, with the port if not empty.
+func (c *Controller) TargetGroups(port string, selector map[string]string) []*TargetGroup {
+	c.state.mu.Lock()
+	desired := c.state.desired
+	ownFilter := c.DomainFilter
+	c.state.mu.Unlock()
+
+	registryFilter := c.Registry.GetDomainFilter()
+	domainFilter := endpoint.MatchAllDomainFilters{&ownFilter, &registryFilter}
+	byName := map[string][]*endpoint.Endpoint{}
+	for _, ep := range desired {
+		switch ep.RecordType {
+		case endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME:
+		default:
+			continue
+		}
+		if !domainFilter.Match(ep.DNSName) || !matchLabels(ep.Labels, selector) {
+			continue
+		}
+		byName[ep.DNSName] = append(byName[ep.DNSName], ep)
+	}
+
+	groups := make([]*TargetGroup, 0, len(byName))
+	for name, eps := range byName {
+		target := name
+		if port != "" {
+			target = net.JoinHostPort(name, port)
+		}
+		labels := map[string]string{"__meta_external_dns_name": name}
+		var recordTypes []string
+		for _, ep := range eps {
+			recordTypes = append(recordTypes, ep.RecordType)
+			for k, v := range ep.Labels {
+				labels["__meta_external_dns_label_"+invalidLabelChars.ReplaceAllString(k, "_")] = v
+			}
+		}
+		sort.Strings(recordTypes)
+		labels["__meta_external_dns_record_types"] = strings.Join(recordTypes, ",")
+		groups = append(groups, &TargetGroup{Targets: []string{target}, Labels: labels})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Targets[0] < groups[j].Targets[0] })
+	return groups
+}
+
+func matchLabels(labels endpoint.Labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ServePrometheusSD serves the target groups in the format of the Prometheus
+// HTTP service discovery. The port query parameter is added to the targets,
+// and the label parameters, like label=team=platform, select the endpoints.
+func (c *Controller) ServePrometheusSD(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	selector := map[string]string{}
+	for _, l := range query["label"] {
+		k, v, ok := strings.Cut(l, "=")
+		if !ok || k == "" {
+			http.Error(w, "invalid label selector "+l+", expected key=value", http.StatusBadRequest)
+			return
+		}
+		selector[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(c.TargetGroups(query.Get("port"), selector)); err != nil {
+		log.Errorf("Failed to encode the Prometheus service discovery: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func TestPrometheusSD(t *testing.T) {
+	web4 := endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	web4.Labels = endpoint.Labels{endpoint.ResourceLabelKey: "service/default/web", "team": "platform"}
+	web6 := endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeAAAA, "2001:db8::1")
+	web6.Labels = endpoint.Labels{endpoint.ResourceLabelKey: "service/default/web", "team": "platform"}
+	api := endpoint.NewEndpoint("api.example.com", endpoint.RecordTypeCNAME, "lb.example.net")
+	api.Labels = endpoint.Labels{"team": "api"}
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		web4, web6, api,
+		endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeTXT, "v=spf1"),
+		endpoint.NewEndpoint("other.example.org", endpoint.RecordTypeA, "1.2.3.5"),
+	}, nil)
+	r, err := registry.NewNoopRegistry(&filteredMockProvider{})
+	require.NoError(t, err)
+
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		DomainFilter:       endpoint.NewDomainFilter([]string{"example.com"}),
+		ManagedRecordTypes: []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME},
+	}
+	assert.Empty(t, ctrl.TargetGroups("", nil))
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+
+	w := httptest.NewRecorder()
+	ctrl.ServePrometheusSD(w, httptest.NewRequest(http.MethodGet, "/prometheus/sd?port=9090", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var groups []*TargetGroup
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&groups))
+	require.Len(t, groups, 2)
+	assert.Equal(t, []string{"api.example.com:9090"}, groups[0].Targets)
+	assert.Equal(t, &TargetGroup{
+		Targets: []string{"web.example.com:9090"},
+		Labels: map[string]string{
+			"__meta_external_dns_name":           "web.example.com",
+			"__meta_external_dns_record_types":   "A,AAAA",
+			"__meta_external_dns_label_resource": "service/default/web",
+			"__meta_external_dns_label_team":     "platform",
+		},
+	}, groups[1])
+
+	w = httptest.NewRecorder()
+	ctrl.ServePrometheusSD(w, httptest.NewRequest(http.MethodGet, "/prometheus/sd?label=team=api", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	groups = nil
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&groups))
+	require.Len(t, groups, 1)
+	assert.Equal(t, []string{"api.example.com"}, groups[0].Targets)
+
+	w = httptest.NewRecorder()
+	ctrl.ServePrometheusSD(w, httptest.NewRequest(http.MethodGet, "/prometheus/sd?label=team", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"sigs.k8s.io/external-dns/plan"
+)
+
+var (
+	quarantined = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "quarantined",
+			Help:      "1 while the applies are paused by an anomalous plan, until cleared or timed out.",
+		},
+	)
+	quarantinesTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "quarantines_total",
+			Help:      "Number of anomalous plans pausing the applies.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(quarantined)
+	prometheus.MustRegister(quarantinesTotal)
+}
+
+// Quarantine pauses the applies on a plan deleting or updating far more records
+// than the recent plans, like the deletes of all the records when a source
+// returns no endpoints after an informer failure. The applies resume once
+// cleared, or after the timeout: the next plan is then applied, anomalous or
+// not.
+type Quarantine struct {
+	// Factor is the ratio of the deletes and updates of an anomalous plan to
+	// the average of the recent plans, at least 1.
+	Factor float64
+	// MinChanges is the minimum number of deletes and updates of an anomalous
+	// plan.
+	MinChanges int
+	// History is the number of recent plans averaged.
+	History int
+	// Timeout clears the quarantine, 0 to only clear it manually.
+	Timeout time.Duration
+
+	mu      sync.Mutex
+	history []int
+	state   QuarantineState
+	// cleared applies the next plan.
+	cleared bool
+}
+
+// QuarantineState is the state of the quarantine, served as JSON.
+type QuarantineState struct {
+	Quarantined bool      `json:"quarantined"`
+	Since       time.Time `json:"since,omitempty"`
+	// Changes are the deletes and updates of the anomalous plan, Average the
+	// ones of the recent plans.
+	Changes int     `json:"changes,omitempty"`
+	Average float64 `json:"average,omitempty"`
+}
+
+// QuarantineError is the error of the syncs while the applies are paused.
+type QuarantineError struct {
+	QuarantineState
+}
+
+func (e *QuarantineError) Error() string {
+	return fmt.Sprintf("applies paused since %s: a plan deleted or updated %d records, %.1f on average, clear the quarantine to resume", e.Since.Format(time.RFC3339), e.Changes, e.Average)
+}
+
+// NewQuarantine returns a quarantine of the plans with factor times the deletes
+// and updates of the average of the history, and at least minChanges.
+func NewQuarantine(factor float64, minChanges, history int, timeout time.Duration) *Quarantine {
+	return &Quarantine{Factor: factor, MinChanges: minChanges, History: history, Timeout: timeout}
+}
+
+// Check returns a QuarantineError if the changes are anomalous, or while the
+// quarantine is not cleared. The other changes are added to the history.
+func (q *Quarantine) Check(changes *plan.Changes, now time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := len(changes.Delete) + len(changes.UpdateNew)
+	if q.state.Quarantined && q.Timeout > 0 && now.Sub(q.state.Since) >= q.Timeout {
+		q.clear()
+	}
+	if q.cleared {
+		q.cleared = false
+		q.record(n)
+		return nil
+	}
+	if q.state.Quarantined {
+		return &QuarantineError{q.state}
+	}
+
+	average := q.average()
+	if n >= q.MinChanges && float64(n) > q.Factor*max(average, 1) {
+		q.state = QuarantineState{Quarantined: true, Since: now, Changes: n, Average: average}
+		quarantined.Set(1)
+		quarantinesTotal.Inc()
+		return &QuarantineError{q.state}
+	}
+	q.record(n)
+	return nil
+}
+
+func (q *Quarantine) average() float64 {
+	if len(q.history) == 0 {
+		return 0
+	}
+	sum := 0
+	for _, n := range q.history {
+		sum += n
+	}
+	return float64(sum) / float64(len(q.history))
+}
+
+func (q *Quarantine) record(n int) {
+	q.history = append(q.history, n)
+	if len(q.history) > q.History {
+		q.history = q.history[len(q.history)-q.History:]
+	}
+}
+
+// Clear resumes the applies: the next plan is applied.
+func (q *Quarantine) Clear() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.state.Quarantined {
+		q.clear()
+	}
+}
+
+func (q *Quarantine) clear() {
+	q.state = QuarantineState{}
+	q.cleared = true
+	quarantined.Set(0)
+}
+
+// State returns the state of the quarantine.
+func (q *Quarantine) State() QuarantineState {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.state
+}
+
+// ServeHTTP shows the state as JSON, and clears the quarantine with POST.
+func (q *Quarantine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		q.Clear()
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(q.State())
+}
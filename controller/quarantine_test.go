@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func deletes(n int) *plan.Changes {
+	changes := &plan.Changes{}
+	for i := 0; i < n; i++ {
+		changes.Delete = append(changes.Delete, endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4"))
+	}
+	return changes
+}
+
+func TestQuarantineCheck(t *testing.T) {
+	q := NewQuarantine(5, 10, 3, time.Hour)
+	now := time.Now()
+
+	for _, n := range []int{2, 4, 3} {
+		require.NoError(t, q.Check(deletes(n), now))
+	}
+	assert.NoError(t, q.Check(deletes(15), now), "15 changes are within 5 times the average of 3")
+	assert.NoError(t, q.Check(deletes(0), now))
+
+	total := testutil.ToFloat64(quarantinesTotal)
+	var qerr *QuarantineError
+	require.ErrorAs(t, q.Check(deletes(100), now), &qerr)
+	assert.Equal(t, 100, qerr.Changes)
+	assert.Equal(t, 1.0, testutil.ToFloat64(quarantined))
+	assert.Equal(t, total+1, testutil.ToFloat64(quarantinesTotal))
+
+	// The applies are paused, even for the usual plans.
+	assert.ErrorAs(t, q.Check(deletes(1), now.Add(time.Minute)), &qerr)
+
+	// After the timeout, the next plan is applied.
+	assert.NoError(t, q.Check(deletes(100), now.Add(time.Hour)))
+	assert.False(t, q.State().Quarantined)
+	assert.Equal(t, 0.0, testutil.ToFloat64(quarantined))
+}
+
+func TestQuarantineMinChanges(t *testing.T) {
+	q := NewQuarantine(2, 10, 10, 0)
+	assert.NoError(t, q.Check(deletes(9), time.Now()), "plans of less than the minimum changes are not anomalous")
+	assert.Error(t, q.Check(deletes(30), time.Now()))
+}
+
+func TestQuarantineServeHTTP(t *testing.T) {
+	q := NewQuarantine(1, 1, 10, 0)
+	require.Error(t, q.Check(deletes(5), time.Now()))
+
+	w := httptest.NewRecorder()
+	q.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/quarantine", nil))
+	var state QuarantineState
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&state))
+	assert.True(t, state.Quarantined)
+	assert.Equal(t, 5, state.Changes)
+
+	w = httptest.NewRecorder()
+	q.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/quarantine", nil))
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&state))
+	assert.False(t, state.Quarantined)
+	assert.NoError(t, q.Check(deletes(5), time.Now()))
+
+	w = httptest.NewRecorder()
+	q.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/quarantine", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestQuarantineRunOnce(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{}, nil)
+	p := &filteredMockProvider{RecordsStore: []*endpoint.Endpoint{
+		endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("bar.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("baz.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+	}}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		Quarantine:         NewQuarantine(2, 2, 10, 0),
+	}
+
+	err = ctrl.RunOnce(context.Background())
+	assert.ErrorIs(t, err, provider.SoftError)
+	var qerr *QuarantineError
+	require.ErrorAs(t, err, &qerr)
+	assert.Equal(t, 3, qerr.Changes)
+	assert.Empty(t, p.ApplyChangesCalls)
+	assert.Len(t, ctrl.Status().Pending.Delete, 3)
+
+	require.Error(t, ctrl.RunOnce(context.Background()))
+	assert.Empty(t, p.ApplyChangesCalls)
+
+	ctrl.Quarantine.Clear()
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, p.ApplyChangesCalls, 1)
+	assert.Len(t, p.ApplyChangesCalls[0].Delete, 3)
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// recordCache is the content of the RecordCacheFile.
+type recordCache struct {
+	// SavedAt is the time of the sync which saved the records.
+	SavedAt time.Time `json:"savedAt"`
+	// Cursor is the journal position of the records, with a Journal.
+	Cursor string `json:"cursor,omitempty"`
+	// Records are the registry records after the changes of the sync.
+	Records []*endpoint.Endpoint `json:"records"`
+}
+
+// cachedRecords returns the records of the RecordCacheFile for the first
+// sync after a start, and whether they are used. With a Journal, they are
+// reused until the journal reports a change, like the records of a full
+// sync; without one, they are only used to plan the first sync, and the
+// next sync, scheduled right after, lists the records.
+func (c *Controller) cachedRecords(ctx context.Context) ([]*endpoint.Endpoint, bool) {
+	if c.RecordCacheFile == "" || c.cacheLoaded {
+		return nil, false
+	}
+	c.cacheLoaded = true
+	cache, err := loadRecordCache(c.RecordCacheFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, false
+	case err != nil:
+		log.WithContext(ctx).Warnf("Failed to load the record cache, listing all records: %v", err)
+		return nil, false
+	case c.RecordCacheMaxAge > 0 && time.Since(cache.SavedAt) > c.RecordCacheMaxAge:
+		log.WithContext(ctx).Infof("The record cache of %s is too old, listing all records", cache.SavedAt.Format(time.RFC3339))
+		return nil, false
+	}
+
+	records := endpoint.Compact(cache.Records)
+	if c.Journal != nil {
+		if cache.Cursor == "" {
+			return nil, false
+		}
+		s := &c.incremental
+		s.cursor = cache.Cursor
+		s.fullSyncAt = cache.SavedAt
+		s.records = records
+		return nil, false
+	}
+	log.WithContext(ctx).Infof("Planning the first sync with the %d cached records of %s", len(records), cache.SavedAt.Format(time.RFC3339))
+	c.fromCache = true
+	c.ScheduleRunOnce(time.Now())
+	return records, true
+}
+
+// saveRecords saves the records after the changes of a sync to the
+// RecordCacheFile.
+func (c *Controller) saveRecords(ctx context.Context, records []*endpoint.Endpoint, changes *plan.Changes) {
+	if c.RecordCacheFile == "" {
+		return
+	}
+	cache := &recordCache{SavedAt: time.Now(), Records: withChanges(records, changes)}
+	if c.Journal != nil {
+		cache.Cursor = c.incremental.cursor
+		cache.Records = c.incremental.records
+	}
+	if err := saveRecordCache(c.RecordCacheFile, cache); err != nil {
+		log.WithContext(ctx).Warnf("Failed to save the record cache: %v", err)
+	}
+}
+
+// withChanges returns the records with the changes applied.
+func withChanges(records []*endpoint.Endpoint, changes *plan.Changes) []*endpoint.Endpoint {
+	if changes == nil || !changes.HasChanges() {
+		return records
+	}
+	removed := map[endpoint.EndpointKey]bool{}
+	for _, ep := range changes.UpdateOld {
+		removed[ep.Key()] = true
+	}
+	for _, ep := range changes.Delete {
+		removed[ep.Key()] = true
+	}
+	result := make([]*endpoint.Endpoint, 0, len(records)+len(changes.Create))
+	for _, ep := range records {
+		if !removed[ep.Key()] {
+			result = append(result, ep)
+		}
+	}
+	result = append(result, changes.Create...)
+	return append(result, changes.UpdateNew...)
+}
+
+func loadRecordCache(path string) (*recordCache, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cache := &recordCache{}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return cache, nil
+}
+
+// saveRecordCache writes the cache to a temporary file renamed to path, so
+// a crash never leaves a partial cache.
+func saveRecordCache(path string, cache *recordCache) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := json.NewEncoder(f).Encode(cache); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
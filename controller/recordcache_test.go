@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// newRecordCacheController returns a controller syncing a.example.org to the
+// provider, with a record cache in path.
+func newRecordCacheController(t *testing.T, path string, journal provider.Journal) (*Controller, *filteredMockProvider) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+	}, nil)
+	p := &filteredMockProvider{
+		RecordsStore: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "1.2.3.5"),
+		},
+	}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	ctrl := &Controller{
+		Source:               source,
+		Registry:             r,
+		Policy:               &plan.UpsertOnlyPolicy{},
+		ManagedRecordTypes:   []string{endpoint.RecordTypeA},
+		MinEventSyncInterval: 5 * time.Second,
+		RecordCacheFile:      path,
+		RecordCacheMaxAge:    time.Hour,
+	}
+	if journal != nil {
+		ctrl.Journal = journal
+	}
+	return ctrl, p
+}
+
+func TestRecordCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.json")
+	ctx := context.Background()
+
+	// Without a cache, the records are listed.
+	ctrl, p := newRecordCacheController(t, path, nil)
+	require.NoError(t, ctrl.RunOnce(ctx))
+	assert.Equal(t, 1, p.RecordsCallCount)
+	require.Len(t, p.ApplyChangesCalls, 1)
+	assert.Len(t, p.ApplyChangesCalls[0].Create, 1)
+
+	cache, err := loadRecordCache(path)
+	require.NoError(t, err)
+	assert.Len(t, cache.Records, 2, "the records are saved with the applied changes")
+
+	// After a restart, the first sync is planned with the cached records,
+	// and the next one lists them.
+	ctrl, p = newRecordCacheController(t, path, nil)
+	now := time.Now()
+	require.NoError(t, ctrl.RunOnce(ctx))
+	assert.Zero(t, p.RecordsCallCount)
+	assert.Empty(t, p.ApplyChangesCalls)
+	assert.True(t, ctrl.ShouldRunOnce(now.Add(ctrl.MinEventSyncInterval+time.Second)))
+	require.NoError(t, ctrl.RunOnce(ctx))
+	assert.Equal(t, 1, p.RecordsCallCount)
+
+	// An old cache is not used.
+	cache.SavedAt = time.Now().Add(-2 * time.Hour)
+	require.NoError(t, saveRecordCache(path, cache))
+	ctrl, p = newRecordCacheController(t, path, nil)
+	require.NoError(t, ctrl.RunOnce(ctx))
+	assert.Equal(t, 1, p.RecordsCallCount)
+}
+
+func TestRecordCacheJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.json")
+	ctx := context.Background()
+
+	ctrl, p := newRecordCacheController(t, path, &mockJournal{})
+	require.NoError(t, ctrl.RunOnce(ctx))
+	assert.Equal(t, 1, p.RecordsCallCount)
+
+	cache, err := loadRecordCache(path)
+	require.NoError(t, err)
+	assert.Equal(t, "1", cache.Cursor)
+
+	// The cached records are reused while the journal reports no change.
+	journal := &mockJournal{position: 1}
+	ctrl, p = newRecordCacheController(t, path, journal)
+	require.NoError(t, ctrl.RunOnce(ctx))
+	require.NoError(t, ctrl.RunOnce(ctx))
+	assert.Zero(t, p.RecordsCallCount)
+	assert.Empty(t, p.ApplyChangesCalls)
+
+	journal.Names = []string{"b.example.org"}
+	require.NoError(t, ctrl.RunOnce(ctx))
+	assert.Equal(t, 1, p.RecordsCallCount)
+}
+
+func TestWithChanges(t *testing.T) {
+	a := endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	b := endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "1.2.3.5")
+	b2 := endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "1.2.3.6")
+	c := endpoint.NewEndpoint("c.example.org", endpoint.RecordTypeA, "1.2.3.7")
+
+	records := withChanges([]*endpoint.Endpoint{a, b}, &plan.Changes{
+		Create:    []*endpoint.Endpoint{c},
+		UpdateOld: []*endpoint.Endpoint{b},
+		UpdateNew: []*endpoint.Endpoint{b2},
+		Delete:    []*endpoint.Endpoint{a},
+	})
+	assert.Equal(t, []*endpoint.Endpoint{c, b2}, records)
+}
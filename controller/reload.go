@@ -0,0 +1,49 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// Settings are the settings of the controller changed by Reload.
+type Settings struct {
+	Policy             plan.Policy
+	DomainFilter       endpoint.DomainFilter
+	ManagedRecordTypes []string
+	ExcludeRecordTypes []string
+}
+
+// Reload replaces the settings after the sync in progress, and schedules a
+// sync applying them. The source and the registry are kept, so the informers
+// don't start again.
+func (c *Controller) Reload(s Settings) {
+	c.runMux.Lock()
+	// TargetGroups reads the domain filter with the state lock.
+	c.state.mu.Lock()
+	c.Policy = s.Policy
+	c.DomainFilter = s.DomainFilter
+	c.ManagedRecordTypes = s.ManagedRecordTypes
+	c.ExcludeRecordTypes = s.ExcludeRecordTypes
+	c.state.mu.Unlock()
+	c.runMux.Unlock()
+
+	c.ScheduleRunOnce(time.Now())
+}
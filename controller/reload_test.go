@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func TestReload(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com", "example.org"}))
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.5"),
+		endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeAAAA, "2001:db8::1"),
+	}, nil)
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		DomainFilter:       endpoint.NewDomainFilter([]string{"example.com"}),
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		Interval:           time.Hour,
+	}
+	names := func() []string {
+		records, err := p.Records(ctx)
+		require.NoError(t, err)
+		var out []string
+		for _, r := range records {
+			out = append(out, r.DNSName)
+		}
+		return out
+	}
+
+	require.NoError(t, ctrl.RunOnce(ctx))
+	assert.Equal(t, []string{"a.example.com"}, names())
+
+	// The reload schedules a sync with the new settings.
+	require.True(t, ctrl.ShouldRunOnce(time.Now()))
+	assert.False(t, ctrl.ShouldRunOnce(time.Now()))
+	ctrl.Reload(Settings{
+		Policy:             &plan.UpsertOnlyPolicy{},
+		DomainFilter:       endpoint.NewDomainFilter([]string{"example.org"}),
+		ManagedRecordTypes: []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA},
+	})
+	assert.True(t, ctrl.ShouldRunOnce(time.Now()))
+
+	require.NoError(t, ctrl.RunOnce(ctx))
+	assert.ElementsMatch(t, []string{"a.example.com", "a.example.org", "b.example.org"}, names())
+	assert.Len(t, ctrl.TargetGroups("", nil), 2)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+var (
+	replicaNames = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "replica_names",
+			Help:      "Number of desired DNS names owned by this replica, with --replicas.",
+		},
+	)
+	replicaConflictsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "controller",
+			Name:      "replica_conflicts_total",
+			Help:      "Number of records of the names owned by this replica written again by another replica since the previous sync, by replica - the replicas are not configured with the same --replicas.",
+		},
+		[]string{"replica"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(replicaNames)
+	prometheus.MustRegister(replicaConflictsTotal)
+}
+
+// ownedByReplica returns the endpoints of the names owned by the Replica, all
+// of them without Replicas.
+func (c *Controller) ownedByReplica(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	if len(c.Replicas) == 0 {
+		return endpoints
+	}
+	owned := []*endpoint.Endpoint{}
+	for _, ep := range endpoints {
+		if plan.ReplicaOwner(ep.DNSName, c.Replicas) == c.Replica {
+			owned = append(owned, ep)
+		}
+	}
+	return owned
+}
+
+// desiredByReplica returns the desired endpoints of the names owned by the
+// Replica, labeled with it.
+func (c *Controller) desiredByReplica(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	if len(c.Replicas) == 0 {
+		return endpoints
+	}
+	owned := c.ownedByReplica(endpoints)
+	names := map[string]bool{}
+	labeled := make([]*endpoint.Endpoint, 0, len(owned))
+	for _, ep := range owned {
+		labeled = append(labeled, withReplicaLabel(ep, c.Replica))
+		names[normalizeName(ep.DNSName)] = true
+	}
+	replicaNames.Set(float64(len(names)))
+	return labeled
+}
+
+// relabel adds to the changes the update of the records of the names owned by
+// the Replica, owned by this instance and written by another replica - the
+// previous owner of the names, before a change of the replicas. A record
+// written again by another replica since the previous sync is a conflict: the
+// replicas don't agree on the owner of the name. It returns the relabeled
+// records, kept in relabeled for the next sync.
+func (c *Controller) relabel(ctx context.Context, current []*endpoint.Endpoint, changes *plan.Changes) map[endpoint.EndpointKey]bool {
+	relabeled := map[endpoint.EndpointKey]bool{}
+	if len(c.Replicas) == 0 {
+		return relabeled
+	}
+	ownerID := c.Registry.OwnerID()
+	updated := map[endpoint.EndpointKey]bool{}
+	for _, ep := range changes.UpdateOld {
+		updated[ep.Key()] = true
+	}
+	for _, ep := range changes.Delete {
+		updated[ep.Key()] = true
+	}
+
+	for _, ep := range current {
+		replica := ep.Labels[endpoint.ReplicaLabelKey]
+		if replica == "" || replica == c.Replica || updated[ep.Key()] || (ownerID != "" && !ep.IsOwnedBy(ownerID)) {
+			continue
+		}
+		if c.relabeled[ep.Key()] {
+			replicaConflictsTotal.WithLabelValues(replica).Inc()
+			log.WithContext(ctx).Warnf("The record %s %s owned by replica %s was written again by replica %s, check that the replicas have the same --replicas", ep.DNSName, ep.RecordType, c.Replica, replica)
+		}
+		changes.UpdateOld = append(changes.UpdateOld, ep)
+		changes.UpdateNew = append(changes.UpdateNew, withReplicaLabel(ep, c.Replica))
+		relabeled[ep.Key()] = true
+	}
+	return relabeled
+}
+
+// withReplicaLabel returns a copy of the endpoint labeled with the replica.
+func withReplicaLabel(ep *endpoint.Endpoint, replica string) *endpoint.Endpoint {
+	return withLabel(ep, endpoint.ReplicaLabelKey, replica)
+}
+
+// withLabel returns a copy of the endpoint with the label, leaving the
+// endpoint, which may be shared with the next syncs, unchanged.
+func withLabel(ep *endpoint.Endpoint, key, value string) *endpoint.Endpoint {
+	copied := *ep
+	copied.Labels = endpoint.NewLabels()
+	for k, v := range ep.Labels {
+		copied.Labels[k] = v
+	}
+	copied.Labels[key] = value
+	return &copied
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func TestReplicasRunOnce(t *testing.T) {
+	desired := []*endpoint.Endpoint{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		desired = append(desired, endpoint.NewEndpoint(name+".example.org", endpoint.RecordTypeA, "1.2.3.4"))
+	}
+	replicas := []string{"r0", "r1"}
+
+	created := map[string]string{}
+	for _, replica := range replicas {
+		source := new(testutils.MockSource)
+		source.On("Endpoints").Return(desired, nil)
+		p := &filteredMockProvider{}
+		r, err := registry.NewNoopRegistry(p)
+		require.NoError(t, err)
+		ctrl := &Controller{
+			Source:             source,
+			Registry:           r,
+			Policy:             &plan.SyncPolicy{},
+			ManagedRecordTypes: []string{endpoint.RecordTypeA},
+			Replica:            replica,
+			Replicas:           replicas,
+		}
+		require.NoError(t, ctrl.RunOnce(context.Background()))
+
+		require.Len(t, p.ApplyChangesCalls, 1)
+		for _, ep := range p.ApplyChangesCalls[0].Create {
+			assert.Equal(t, replica, plan.ReplicaOwner(ep.DNSName, replicas))
+			assert.Equal(t, replica, ep.Labels[endpoint.ReplicaLabelKey])
+			assert.NotContains(t, created, ep.DNSName, "each name is created by one replica")
+			created[ep.DNSName] = replica
+		}
+	}
+	assert.Len(t, created, len(desired))
+	for _, ep := range desired {
+		assert.NotContains(t, ep.Labels, endpoint.ReplicaLabelKey, "the endpoints of the source are not changed")
+	}
+}
+
+func TestReplicasRelabel(t *testing.T) {
+	replicas := []string{"r0", "r1"}
+	var name string
+	for _, n := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		if plan.ReplicaOwner(n+".example.org", replicas) == "r0" {
+			name = n + ".example.org"
+			break
+		}
+	}
+	require.NotEmpty(t, name)
+
+	record := endpoint.NewEndpoint(name, endpoint.RecordTypeA, "1.2.3.4")
+	record.Labels[endpoint.ReplicaLabelKey] = "r1"
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{endpoint.NewEndpoint(name, endpoint.RecordTypeA, "1.2.3.4")}, nil)
+	p := &filteredMockProvider{RecordsStore: []*endpoint.Endpoint{record}}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		Replica:            "r0",
+		Replicas:           replicas,
+	}
+	conflicts := testutil.ToFloat64(replicaConflictsTotal.WithLabelValues("r1"))
+
+	// The record written by the previous owner of the name is relabeled.
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, p.ApplyChangesCalls, 1)
+	require.Len(t, p.ApplyChangesCalls[0].UpdateNew, 1)
+	assert.Equal(t, "r0", p.ApplyChangesCalls[0].UpdateNew[0].Labels[endpoint.ReplicaLabelKey])
+	assert.Equal(t, "r1", record.Labels[endpoint.ReplicaLabelKey])
+	assert.Equal(t, conflicts, testutil.ToFloat64(replicaConflictsTotal.WithLabelValues("r1")))
+
+	// Written again by r1, the record is a conflict.
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	require.Len(t, p.ApplyChangesCalls, 2)
+	assert.Equal(t, conflicts+1, testutil.ToFloat64(replicaConflictsTotal.WithLabelValues("r1")))
+}
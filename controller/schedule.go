@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robfig/cron/v3"
+)
+
+var syncIntervalSeconds = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "controller",
+		Name:      "sync_interval_seconds",
+		Help:      "Interval before the next periodic sync, without the jitter; adapted between the min interval and the interval.",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(syncIntervalSeconds)
+}
+
+// Schedule returns the time of the next sync after a sync started at t.
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule parses a cron schedule - five fields like '*/15 * * * *', or a
+// descriptor like '@hourly' or '@every 10m'. The times are in the local time
+// zone, unless the spec starts with 'CRON_TZ=Europe/Paris'.
+func ParseSchedule(spec string) (Schedule, error) {
+	s, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	return s, nil
+}
+
+// nextInterval returns the delay before the next periodic sync: the adapted
+// interval, plus up to Jitter of it. Called with nextRunAtMux held.
+func (c *Controller) nextInterval() time.Duration {
+	interval := c.Interval
+	if c.adaptive() && c.interval > 0 {
+		interval = c.interval
+	}
+	if c.Jitter > 0 && interval > 0 {
+		if n := int64(float64(interval) * c.Jitter); n > 0 {
+			interval += time.Duration(rand.Int63n(n))
+		}
+	}
+	return interval
+}
+
+// adaptive returns whether the interval adapts to the changes, between
+// MinInterval and Interval.
+func (c *Controller) adaptive() bool {
+	return c.MinInterval > 0 && c.MinInterval < c.Interval && c.Schedule == nil
+}
+
+// adapt adapts the interval to a sync started at now: MinInterval after a
+// sync applying changes, doubled after each sync without changes, up to
+// Interval. A shorter interval brings the next sync forward.
+func (c *Controller) adapt(now time.Time, changed bool) {
+	if !c.adaptive() {
+		return
+	}
+	c.nextRunAtMux.Lock()
+	defer c.nextRunAtMux.Unlock()
+	switch {
+	case changed:
+		c.interval = c.MinInterval
+	case c.interval == 0:
+		c.interval = c.Interval
+	default:
+		c.interval = min(2*c.interval, c.Interval)
+	}
+	syncIntervalSeconds.Set(c.interval.Seconds())
+	if next := now.Add(c.nextInterval()); next.Before(c.nextRunAt) {
+		c.nextRunAt = next
+	}
+}
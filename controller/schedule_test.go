@@ -0,0 +1,107 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 7, 30, 0, time.UTC)
+	for spec, next := range map[string]time.Time{
+		"*/15 * * * *":           time.Date(2024, 5, 1, 10, 15, 0, 0, time.UTC),
+		"@hourly":                time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC),
+		"@every 10m":             start.Add(10 * time.Minute),
+		"CRON_TZ=UTC 30 2 * * *": time.Date(2024, 5, 2, 2, 30, 0, 0, time.UTC),
+	} {
+		s, err := ParseSchedule(spec)
+		require.NoError(t, err, spec)
+		assert.True(t, next.Equal(s.Next(start)), "%s: %s", spec, s.Next(start))
+	}
+
+	_, err := ParseSchedule("every 10m")
+	assert.Error(t, err)
+}
+
+func TestShouldRunOnceSchedule(t *testing.T) {
+	s, err := ParseSchedule("@every 1h")
+	require.NoError(t, err)
+	ctrl := &Controller{Interval: time.Minute, Schedule: s, MinEventSyncInterval: 5 * time.Second}
+
+	now := time.Now()
+	assert.True(t, ctrl.ShouldRunOnce(now))
+	assert.False(t, ctrl.ShouldRunOnce(now.Add(time.Minute)), "the schedule replaces the interval")
+	assert.True(t, ctrl.ShouldRunOnce(now.Add(time.Hour)))
+
+	// The events still trigger a sync.
+	now = now.Add(time.Hour + time.Minute)
+	ctrl.ScheduleRunOnce(now)
+	assert.True(t, ctrl.ShouldRunOnce(now.Add(5*time.Second)))
+}
+
+func TestShouldRunOnceJitter(t *testing.T) {
+	ctrl := &Controller{Interval: 10 * time.Minute, Jitter: 0.5}
+
+	now := time.Now()
+	for i := 0; i < 20; i++ {
+		assert.True(t, ctrl.ShouldRunOnce(now))
+		delay := ctrl.nextRunAt.Sub(now)
+		assert.GreaterOrEqual(t, delay, 10*time.Minute)
+		assert.Less(t, delay, 15*time.Minute)
+		now = ctrl.nextRunAt
+	}
+}
+
+func TestAdaptInterval(t *testing.T) {
+	ctrl := &Controller{Interval: 10 * time.Minute, MinInterval: time.Minute}
+
+	now := time.Now()
+	assert.True(t, ctrl.ShouldRunOnce(now))
+	assert.Equal(t, now.Add(10*time.Minute), ctrl.nextRunAt)
+
+	// A sync applying changes brings the next one forward.
+	ctrl.adapt(now, true)
+	assert.Equal(t, time.Minute, ctrl.interval)
+	assert.Equal(t, now.Add(time.Minute), ctrl.nextRunAt)
+
+	// The syncs without changes back off to the interval.
+	for _, interval := range []time.Duration{2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 10 * time.Minute, 10 * time.Minute} {
+		now = ctrl.nextRunAt
+		assert.True(t, ctrl.ShouldRunOnce(now))
+		ctrl.adapt(now, false)
+		assert.Equal(t, interval, ctrl.interval)
+	}
+
+	// The events still trigger a sync before the interval.
+	ctrl.MinEventSyncInterval = 5 * time.Second
+	ctrl.ScheduleRunOnce(now)
+	assert.True(t, ctrl.ShouldRunOnce(now.Add(5*time.Second)))
+}
+
+func TestAdaptIntervalDisabled(t *testing.T) {
+	ctrl := &Controller{Interval: time.Minute, MinInterval: 10 * time.Minute}
+
+	now := time.Now()
+	assert.True(t, ctrl.ShouldRunOnce(now))
+	ctrl.adapt(now, true)
+	assert.Zero(t, ctrl.interval)
+	assert.Equal(t, now.Add(time.Minute), ctrl.nextRunAt)
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+var shadowedRecords = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "controller",
+		Name:      "shadowed_records",
+		Help:      "Number of desired records shadowing the records of other owners or delegated subzones, failing the sync with StrictShadowing.",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(shadowedRecords)
+}
+
+// checkShadowing returns a plan.ShadowingError listing the desired endpoints
+// shadowing the records, with StrictShadowing. The records are all the
+// registry records, for the delegations of the parent names.
+func (c *Controller) checkShadowing(records, desired []*endpoint.Endpoint) error {
+	if !c.StrictShadowing {
+		return nil
+	}
+	shadows := c.newPlan(records, desired).Shadowed()
+	shadowedRecords.Set(float64(len(shadows)))
+	if len(shadows) > 0 {
+		return plan.ShadowingError(shadows)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// runShards runs a sync planning the names shard by shard (see plan.Shard):
+// the records of a shard are read, planned and applied before the next one,
+// so only a shard of the records is held in memory. The records are not kept
+// for the dashboard.
+func (c *Controller) runShards(ctx context.Context) error {
+	start := time.Now()
+	endpoints, err := c.sourceEndpoints(ctx)
+	if err != nil {
+		sourceErrorsTotal.Inc()
+		deprecatedSourceErrors.Inc()
+		return err
+	}
+	sourceEndpointsTotal.Set(float64(len(endpoints)))
+	srcARecords, srcAAAARecords := countAddressRecords(endpoints)
+	sourceARecords.Set(float64(srcARecords))
+	sourceAAAARecords.Set(float64(srcAAAARecords))
+	endpoints, err = c.Registry.AdjustEndpoints(endpoints)
+	if err != nil {
+		return fmt.Errorf("adjusting endpoints: %w", err)
+	}
+	c.state.setDesired(endpoints)
+	endpoints = c.desiredByReplica(endpoints)
+
+	desired := make([][]*endpoint.Endpoint, c.Shards)
+	for _, ep := range endpoints {
+		shard := plan.Shard(ep.DNSName, c.Shards)
+		desired[shard] = append(desired[shard], ep)
+	}
+
+	var records, regARecords, regAAAARecords, vARecords, vAAAARecords int
+	all := &plan.Changes{}
+	relabeled := map[endpoint.EndpointKey]bool{}
+	var conflicts []plan.OwnershipConflict
+	paused := c.Paused()
+	for shard := range desired {
+		listed := time.Now()
+		current, err := registry.RecordsShard(ctx, c.Registry, shard, c.Shards)
+		if err != nil {
+			registryErrorsTotal.Inc()
+			deprecatedRegistryErrors.Inc()
+			return fmt.Errorf("shard %d: %w", shard, err)
+		}
+		current = c.ownedByReplica(current)
+		records += len(current)
+		a, aaaa := countAddressRecords(current)
+		regARecords, regAAAARecords = regARecords+a, regAAAARecords+aaaa
+		a, aaaa = countMatchingAddressRecords(desired[shard], current)
+		vARecords, vAAAARecords = vARecords+a, vAAAARecords+aaaa
+
+		calculated := c.newPlan(current, desired[shard]).Calculate()
+		conflicts = append(conflicts, calculated.OwnershipConflicts...)
+		changes := calculated.Changes
+		for key := range c.relabel(ctx, current, changes) {
+			relabeled[key] = true
+		}
+		c.lease(ctx, current, changes, time.Now())
+		desired[shard] = nil
+		if !c.DryRun && !paused && changes.HasChanges() {
+			if changes, err = c.applyShard(ctx, changes, listed); err != nil {
+				return fmt.Errorf("shard %d: %w", shard, err)
+			}
+		}
+		all.Create = append(all.Create, changes.Create...)
+		all.UpdateOld = append(all.UpdateOld, changes.UpdateOld...)
+		all.UpdateNew = append(all.UpdateNew, changes.UpdateNew...)
+		all.Delete = append(all.Delete, changes.Delete...)
+	}
+	c.relabeled = relabeled
+	c.reportConflicts(ctx, conflicts, true)
+	registryEndpointsTotal.Set(float64(records))
+	registryARecords.Set(float64(regARecords))
+	registryAAAARecords.Set(float64(regAAAARecords))
+	verifiedARecords.Set(float64(vARecords))
+	verifiedAAAARecords.Set(float64(vAAAARecords))
+
+	if c.DryRun {
+		c.dryRun(all)
+	} else if paused {
+		c.pause(ctx, all)
+	} else if all.HasChanges() {
+		c.state.setApplied(all)
+		c.adapt(start, true)
+		log.WithContext(ctx).Infof("Applied %d changes in %d shards", len(all.Create)+len(all.UpdateNew)+len(all.Delete), c.Shards)
+	} else {
+		controllerNoChangesTotal.Inc()
+		c.adapt(start, false)
+		log.WithContext(ctx).Info("All records are already up to date")
+	}
+
+	lastSyncTimestamp.SetToCurrentTime()
+	c.state.setSynced()
+	return nil
+}
+
+// applyShard applies the changes of a shard, listed at the given time, within
+// the budget and the domain locks, and returns the applied ones.
+func (c *Controller) applyShard(ctx context.Context, changes *plan.Changes, listed time.Time) (*plan.Changes, error) {
+	if c.Budget != nil {
+		allowed, deferred := c.Budget.Allow(changes, c.driftDomain, time.Now())
+		changes = allowed
+		if n := len(deferred.Create) + len(deferred.UpdateNew) + len(deferred.Delete); n > 0 {
+			log.WithContext(ctx).Infof("Change budget exceeded, deferring %d changes to the next sync", n)
+			defer c.ScheduleRunOnce(time.Now())
+		}
+		if !changes.HasChanges() {
+			return changes, nil
+		}
+	}
+	if c.DomainLock != nil {
+		var unlock func()
+		changes, unlock = c.lockDomains(ctx, changes, listed)
+		defer unlock()
+		if !changes.HasChanges() {
+			return changes, nil
+		}
+	}
+	c.state.setPending(changes)
+	if err := c.applyChanges(ctx, changes); err != nil {
+		registryErrorsTotal.Inc()
+		deprecatedRegistryErrors.Inc()
+		c.state.setError(err)
+		return nil, err
+	}
+	return changes, nil
+}
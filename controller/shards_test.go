@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func TestRunShards(t *testing.T) {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "1.2.3.5"),
+		endpoint.NewEndpoint("c.example.org", endpoint.RecordTypeA, "1.2.3.6"),
+		endpoint.NewEndpoint("d.example.org", endpoint.RecordTypeA, "1.2.3.7"),
+	}, nil)
+	p := &filteredMockProvider{
+		RecordsStore: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "9.9.9.9"),
+			endpoint.NewEndpoint("e.example.org", endpoint.RecordTypeA, "1.2.3.8"),
+		},
+	}
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	ctrl := &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+		Shards:             4,
+	}
+
+	require.NoError(t, ctrl.RunOnce(context.Background()))
+	assert.Equal(t, 4, p.RecordsCallCount, "the records are read once per shard")
+	applied := &plan.Changes{}
+	for _, changes := range p.ApplyChangesCalls {
+		shards := map[int]bool{}
+		for _, eps := range [][]*endpoint.Endpoint{changes.Create, changes.UpdateNew, changes.Delete} {
+			for _, ep := range eps {
+				shards[plan.Shard(ep.DNSName, 4)] = true
+			}
+		}
+		assert.Len(t, shards, 1, "the changes of a shard are applied together")
+		applied.Create = append(applied.Create, changes.Create...)
+		applied.UpdateNew = append(applied.UpdateNew, changes.UpdateNew...)
+		applied.Delete = append(applied.Delete, changes.Delete...)
+	}
+	assert.Len(t, applied.Create, 2)
+	assert.Len(t, applied.UpdateNew, 1)
+	assert.Len(t, applied.Delete, 1)
+	assert.Equal(t, 3.0, testutil.ToFloat64(registryEndpointsTotal))
+	assert.Equal(t, 2.0, testutil.ToFloat64(verifiedARecords))
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RunUntilShutdown runs RunOnce in a loop like Run until ctx is canceled, but
+// lets the sync in progress finish instead of canceling it mid-batch. With
+// finalSync, a last RunOnce then flushes the changes of the pending events.
+// Both are canceled after timeout, if not zero.
+func (c *Controller) RunUntilShutdown(ctx context.Context, finalSync bool, timeout time.Duration) error {
+	syncCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	stop := context.AfterFunc(ctx, func() {
+		if timeout > 0 {
+			time.AfterFunc(timeout, cancel)
+		}
+	})
+	defer stop()
+
+	c.run(ctx, syncCtx)
+	if !finalSync {
+		return nil
+	}
+	log.Info("Running the final sync")
+	return c.RunOnce(syncCtx)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// slowProvider blocks ApplyChanges until release is closed, or its context
+// is canceled.
+type slowProvider struct {
+	provider.BaseProvider
+	started chan struct{}
+	release chan struct{}
+
+	mu      sync.Mutex
+	applied []error
+}
+
+func (p *slowProvider) Records(context.Context) ([]*endpoint.Endpoint, error) {
+	return nil, nil
+}
+
+func (p *slowProvider) ApplyChanges(ctx context.Context, _ *plan.Changes) error {
+	select {
+	case p.started <- struct{}{}:
+	default:
+	}
+	var err error
+	select {
+	case <-p.release:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.applied = append(p.applied, err)
+	return err
+}
+
+func newShutdownController(t *testing.T, p provider.Provider) *Controller {
+	source := new(testutils.MockSource)
+	source.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+	}, nil)
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	return &Controller{
+		Source:             source,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		Interval:           time.Hour,
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+	}
+}
+
+func TestRunUntilShutdown(t *testing.T) {
+	p := &slowProvider{started: make(chan struct{}, 1), release: make(chan struct{})}
+	ctrl := newShutdownController(t, p)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- ctrl.RunUntilShutdown(ctx, true, time.Minute) }()
+
+	// Shut down in the middle of the first sync: it completes, then the final
+	// sync runs.
+	<-p.started
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	close(p.release)
+	require.NoError(t, <-done)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	assert.Equal(t, []error{nil, nil}, p.applied)
+}
+
+func TestRunUntilShutdownTimeout(t *testing.T) {
+	p := &slowProvider{started: make(chan struct{}, 1), release: make(chan struct{})}
+	ctrl := newShutdownController(t, p)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- ctrl.RunUntilShutdown(ctx, false, 10*time.Millisecond) }()
+
+	<-p.started
+	cancel()
+	require.NoError(t, <-done)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	assert.Equal(t, []error{context.Canceled}, p.applied)
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package e2e runs the sources against a Kubernetes cluster - for example
+// one created with 'kind create cluster' - and the controller with the
+// inmemory provider, served by the webhook server like a remote provider.
+//
+// A Framework is created by each test, with a namespace deleted at the end:
+//
+//	f := e2e.New(t)
+//	f.Start(t, f.Source(t, "service"))
+//	... create a Service in f.Namespace ...
+//	f.ExpectRecord(t, "web.example.org", endpoint.RecordTypeA, "10.0.0.1")
+//
+// The tests need the e2e build tag and a cluster in $KUBECONFIG, and are
+// skipped without: 'make e2e' creates a kind cluster and runs them.
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	istioclient "istio.io/client-go/pkg/clientset/versioned"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/external-dns/controller"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+	"sigs.k8s.io/external-dns/provider/webhook"
+	webhookapi "sigs.k8s.io/external-dns/provider/webhook/api"
+	"sigs.k8s.io/external-dns/registry"
+	"sigs.k8s.io/external-dns/source"
+)
+
+const (
+	// DefaultDomain is the zone of the inmemory provider.
+	DefaultDomain = "example.org"
+	// DefaultOwnerID is the owner of the TXT registry.
+	DefaultOwnerID = "e2e"
+	// DefaultTimeout is the time ExpectRecord waits for the records.
+	DefaultTimeout = 30 * time.Second
+)
+
+// Framework is the cluster and the provider of a test.
+type Framework struct {
+	// KubeConfig is the kubeconfig of the cluster, from $KUBECONFIG.
+	KubeConfig string
+	Kube       kubernetes.Interface
+	Istio      istioclient.Interface
+	// Namespace is the namespace of the test, deleted at the end.
+	Namespace string
+
+	// Provider is the inmemory provider, and Webhook its webhook client.
+	Provider *inmemory.InMemoryProvider
+	Webhook  *webhook.WebhookProvider
+
+	// Domain is the zone of the provider, and OwnerID the owner of the records.
+	Domain  string
+	OwnerID string
+	// Timeout is the time ExpectRecord waits for the records.
+	Timeout time.Duration
+
+	clients *source.SingletonClientGenerator
+}
+
+// New returns the framework of the test, skipping it without a cluster in
+// $KUBECONFIG.
+func New(t *testing.T) *Framework {
+	t.Helper()
+	kubeConfig := os.Getenv("KUBECONFIG")
+	if kubeConfig == "" {
+		t.Skip("KUBECONFIG is not set, the e2e tests need a cluster")
+	}
+	f := &Framework{
+		KubeConfig: kubeConfig,
+		Domain:     DefaultDomain,
+		OwnerID:    DefaultOwnerID,
+		Timeout:    DefaultTimeout,
+		clients:    &source.SingletonClientGenerator{KubeConfig: kubeConfig, RequestTimeout: 30 * time.Second},
+	}
+	var err error
+	if f.Kube, err = f.clients.KubeClient(); err != nil {
+		t.Fatalf("Failed to create the Kubernetes client: %v", err)
+	}
+	if f.Istio, err = f.clients.IstioClient(); err != nil {
+		t.Fatalf("Failed to create the Istio client: %v", err)
+	}
+
+	ctx := context.Background()
+	ns, err := f.Kube.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "e2e-"},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create the namespace: %v", err)
+	}
+	f.Namespace = ns.Name
+	t.Cleanup(func() {
+		if err := f.Kube.CoreV1().Namespaces().Delete(context.Background(), f.Namespace, metav1.DeleteOptions{}); err != nil {
+			t.Logf("Failed to delete the namespace %s: %v", f.Namespace, err)
+		}
+	})
+
+	f.Provider = inmemory.NewInMemoryProvider(
+		inmemory.InMemoryInitZones([]string{f.Domain}),
+		inmemory.InMemoryWithDomain(endpoint.NewDomainFilter([]string{f.Domain})),
+	)
+	m := http.NewServeMux()
+	webhookapi.InitHandlers(f.Provider, m, "", webhookapi.Handlers{})
+	server := httptest.NewServer(m)
+	t.Cleanup(server.Close)
+	if f.Webhook, err = webhook.NewWebhookProvider(server.URL); err != nil {
+		t.Fatalf("Failed to connect to the webhook server: %v", err)
+	}
+	return f
+}
+
+// Source returns the source of the name, with the namespace of the test, or
+// the namespace of cfg if set.
+func (f *Framework) Source(t *testing.T, name string, cfg ...*source.Config) source.Source {
+	t.Helper()
+	c := &source.Config{}
+	if len(cfg) > 0 {
+		c = cfg[0]
+	}
+	if c.Namespace == "" {
+		c.Namespace = f.Namespace
+	}
+	if c.LabelFilter == nil {
+		c.LabelFilter = labels.Everything()
+	}
+	src, err := source.BuildWithConfig(context.Background(), name, f.clients, c)
+	if err != nil {
+		t.Fatalf("Failed to create the %s source: %v", name, err)
+	}
+	return src
+}
+
+// Start runs the controller with the sources until the end of the test,
+// syncing every second and on the events of the sources, with the TXT
+// registry of OwnerID and the sync policy.
+func (f *Framework) Start(t *testing.T, sources ...source.Source) *controller.Controller {
+	t.Helper()
+	r, err := registry.NewTXTRegistry(f.Webhook, "", "", f.OwnerID, 0, "", []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME}, nil, false, nil)
+	if err != nil {
+		t.Fatalf("Failed to create the registry: %v", err)
+	}
+	ctrl := &controller.Controller{
+		Source:             source.NewDedupSource(source.NewMultiSource(sources, nil)),
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		Interval:           time.Second,
+		DomainFilter:       endpoint.NewDomainFilter([]string{f.Domain}),
+		ManagedRecordTypes: []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ctrl.Source.AddEventHandler(ctx, func() { ctrl.ScheduleEvent(time.Now()) })
+	go ctrl.Run(ctx)
+	t.Cleanup(cancel)
+	return ctrl
+}
+
+// Records returns the records of the provider, without the TXT records of
+// the registry.
+func (f *Framework) Records(t *testing.T) []*endpoint.Endpoint {
+	t.Helper()
+	records, err := f.Provider.Records(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to list the records: %v", err)
+	}
+	result := []*endpoint.Endpoint{}
+	for _, r := range records {
+		if r.RecordType != endpoint.RecordTypeTXT {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// Record returns the record of the name and the record type, or nil.
+func (f *Framework) Record(t *testing.T, name, recordType string) *endpoint.Endpoint {
+	t.Helper()
+	for _, r := range f.Records(t) {
+		if strings.EqualFold(r.DNSName, name) && r.RecordType == recordType {
+			return r
+		}
+	}
+	return nil
+}
+
+// ExpectRecord waits until the provider has the record with the targets -
+// any targets if none are given - and returns it.
+func (f *Framework) ExpectRecord(t *testing.T, name, recordType string, targets ...string) *endpoint.Endpoint {
+	t.Helper()
+	var record *endpoint.Endpoint
+	f.Eventually(t, func() error {
+		record = f.Record(t, name, recordType)
+		switch {
+		case record == nil:
+			return fmt.Errorf("no %s record %s", recordType, name)
+		case len(targets) > 0 && !record.Targets.Same(targets):
+			return fmt.Errorf("the %s record %s has the targets %s, expected %s", recordType, name, record.Targets, endpoint.Targets(targets))
+		}
+		return nil
+	})
+	return record
+}
+
+// ExpectNoRecord waits until the provider has no record of the name and the
+// record type.
+func (f *Framework) ExpectNoRecord(t *testing.T, name, recordType string) {
+	t.Helper()
+	f.Eventually(t, func() error {
+		if r := f.Record(t, name, recordType); r != nil {
+			return fmt.Errorf("the record %s still exists", r)
+		}
+		return nil
+	})
+}
+
+// Eventually calls check until it returns nil, failing the test with its
+// last error after Timeout.
+func (f *Framework) Eventually(t *testing.T, check func() error) {
+	t.Helper()
+	deadline := time.Now().Add(f.Timeout)
+	for {
+		err := check()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out after %s: %v", f.Timeout, err)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
@@ -0,0 +1,68 @@
+//go:build e2e
+
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestServiceRecords(t *testing.T) {
+	f := New(t)
+	f.Start(t, f.Source(t, "service"))
+	ctx := context.Background()
+	svcs := f.Kube.CoreV1().Services(f.Namespace)
+
+	svc, err := svcs.Create(ctx, &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "web",
+			Annotations: map[string]string{
+				"external-dns.alpha.kubernetes.io/hostname": "web." + f.Domain,
+				"external-dns.alpha.kubernetes.io/target":   "10.0.0.1",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeClusterIP,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80}},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := f.ExpectRecord(t, "web."+f.Domain, endpoint.RecordTypeA, "10.0.0.1")
+	if !record.IsOwnedBy(f.OwnerID) {
+		t.Errorf("the record %s is not owned by %s", record, f.OwnerID)
+	}
+
+	svc.Annotations["external-dns.alpha.kubernetes.io/target"] = "10.0.0.2"
+	if _, err := svcs.Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	f.ExpectRecord(t, "web."+f.Domain, endpoint.RecordTypeA, "10.0.0.2")
+
+	if err := svcs.Delete(ctx, svc.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	f.ExpectNoRecord(t, "web."+f.Domain, endpoint.RecordTypeA)
+}
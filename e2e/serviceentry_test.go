@@ -0,0 +1,122 @@
+//go:build e2e
+
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"testing"
+
+	"istio.io/api/networking/v1alpha3"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/ipam"
+	"sigs.k8s.io/external-dns/source"
+)
+
+// TestServiceEntryAllocation checks that a MESH_INTERNAL ServiceEntry without
+// addresses gets a VIP of the pool, patched into the ServiceEntry and
+// published, and reserved again after a restart.
+func TestServiceEntryAllocation(t *testing.T) {
+	f := New(t)
+	ctx := context.Background()
+	ses := f.Istio.NetworkingV1alpha3().ServiceEntries(f.Namespace)
+	if _, err := ses.List(ctx, metav1.ListOptions{}); err != nil {
+		t.Skipf("The Istio CRDs are not installed: %v", err)
+	}
+
+	pool := netip.MustParsePrefix("10.10.0.0/24")
+	allocator, err := ipam.NewAllocator([]string{pool.String()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := source.NewIstioServiceEntrySourceConfig(ctx, f.Kube, f.Istio, source.ServiceEntrySourceConfig{
+		Allocator:          allocator,
+		UpdateServiceEntry: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Start(t, src)
+
+	host := fmt.Sprintf("db.%s.%s", f.Namespace, f.Domain)
+	if _, err := ses.Create(ctx, &networkingv1alpha3.ServiceEntry{
+		ObjectMeta: metav1.ObjectMeta{Name: "db"},
+		Spec: v1alpha3.ServiceEntry{
+			Hosts:      []string{host},
+			Location:   v1alpha3.ServiceEntry_MESH_INTERNAL,
+			Resolution: v1alpha3.ServiceEntry_STATIC,
+			Ports:      []*v1alpha3.ServicePort{{Number: 5432, Name: "tcp", Protocol: "TCP"}},
+		},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	record := f.ExpectRecord(t, host, endpoint.RecordTypeA)
+	if len(record.Targets) != 1 || !pool.Contains(netip.MustParseAddr(record.Targets[0])) {
+		t.Fatalf("the record %s doesn't have a VIP of %s", record, pool)
+	}
+	vip := record.Targets[0]
+	f.Eventually(t, func() error {
+		se, err := ses.Get(ctx, "db", metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if len(se.Spec.Addresses) != 1 || se.Spec.Addresses[0] != vip {
+			return fmt.Errorf("the ServiceEntry has the addresses %v, expected %s", se.Spec.Addresses, vip)
+		}
+		return nil
+	})
+
+	// After a restart, the patched address is reserved by the new allocator.
+	restarted, err := ipam.NewAllocator([]string{pool.String()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err = source.NewIstioServiceEntrySourceConfig(ctx, f.Kube, f.Istio, source.ServiceEntrySourceConfig{
+		Allocator:          restarted,
+		UpdateServiceEntry: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Eventually(t, func() error {
+		endpoints, err := src.Endpoints(ctx)
+		if err != nil {
+			return err
+		}
+		for _, e := range endpoints {
+			if e.DNSName == host && e.Targets.Same(endpoint.Targets{vip}) {
+				return nil
+			}
+		}
+		return fmt.Errorf("no endpoint of %s with the VIP %s: %v", host, vip, endpoints)
+	})
+	if ip, ok := restarted.Lookup("serviceentry/" + f.Namespace + "/db"); !ok || ip.String() != vip {
+		t.Errorf("the VIP %s is not reserved after a restart", vip)
+	}
+
+	if err := ses.Delete(ctx, "db", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	f.ExpectNoRecord(t, host, endpoint.RecordTypeA)
+}
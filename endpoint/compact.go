@@ -0,0 +1,107 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpoint
+
+// Interner deduplicates the strings of endpoints. The record types, the
+// targets - like the node IPs of hundreds of thousands of pod records - the
+// labels and the names shared by the records of several types are held once.
+// It is not safe for concurrent use.
+type Interner struct {
+	strings map[string]string
+}
+
+// NewInterner returns an empty Interner.
+func NewInterner() *Interner {
+	return &Interner{strings: map[string]string{}}
+}
+
+// String returns the interned copy of s.
+func (in *Interner) String(s string) string {
+	if s == "" {
+		return ""
+	}
+	if interned, ok := in.strings[s]; ok {
+		return interned
+	}
+	in.strings[s] = s
+	return s
+}
+
+// Len returns the number of interned strings.
+func (in *Interner) Len() int {
+	return len(in.strings)
+}
+
+// Endpoint replaces the strings of the endpoint with their interned copies,
+// in a new Labels map.
+func (in *Interner) Endpoint(ep *Endpoint) {
+	ep.DNSName = in.String(ep.DNSName)
+	ep.RecordType = in.String(ep.RecordType)
+	ep.SetIdentifier = in.String(ep.SetIdentifier)
+	for i, t := range ep.Targets {
+		ep.Targets[i] = in.String(t)
+	}
+	for i, ps := range ep.ProviderSpecific {
+		ep.ProviderSpecific[i] = ProviderSpecificProperty{Name: in.String(ps.Name), Value: in.String(ps.Value)}
+	}
+	if ep.Labels != nil {
+		labels := make(Labels, len(ep.Labels))
+		for k, v := range ep.Labels {
+			labels[in.String(k)] = in.String(v)
+		}
+		ep.Labels = labels
+	}
+}
+
+// Compact returns copies of the endpoints with interned strings, allocated in
+// a few large arrays instead of one allocation per endpoint, target and
+// provider specific property. The targets and properties of each endpoint
+// are capacity-bounded slices of the shared arrays: appending to them copies
+// instead of overwriting the next endpoint. The arrays stay in memory while
+// any of the copies is used - Compact is meant for sets replaced as a whole,
+// like the records of a sync.
+func Compact(endpoints []*Endpoint) []*Endpoint {
+	targets, properties := 0, 0
+	for _, ep := range endpoints {
+		targets += len(ep.Targets)
+		properties += len(ep.ProviderSpecific)
+	}
+
+	in := NewInterner()
+	eps := make([]Endpoint, len(endpoints))
+	targetsSlab := make([]string, 0, targets)
+	propertiesSlab := make([]ProviderSpecificProperty, 0, properties)
+	compact := make([]*Endpoint, len(endpoints))
+	for i, ep := range endpoints {
+		c := &eps[i]
+		*c = *ep
+		if ep.Targets != nil {
+			start := len(targetsSlab)
+			targetsSlab = append(targetsSlab, ep.Targets...)
+			c.Targets = targetsSlab[start:len(targetsSlab):len(targetsSlab)]
+		}
+		if ep.ProviderSpecific != nil {
+			start := len(propertiesSlab)
+			propertiesSlab = append(propertiesSlab, ep.ProviderSpecific...)
+			c.ProviderSpecific = propertiesSlab[start:len(propertiesSlab):len(propertiesSlab)]
+		}
+		// The labels are copied by the Interner.
+		in.Endpoint(c)
+		compact[i] = c
+	}
+	return compact
+}
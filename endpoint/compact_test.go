@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpoint
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompact(t *testing.T) {
+	endpoints := []*Endpoint{
+		NewEndpoint("a.example.org", RecordTypeA, strings.Repeat("1", 1)+".2.3.4", "1.2.3.5").WithProviderSpecific("alias", "false"),
+		NewEndpoint("a.example.org", RecordTypeAAAA, "2001:db8::1"),
+		NewEndpoint("b.example.org", RecordTypeA, strings.Repeat("1", 1)+".2.3.4"),
+	}
+	endpoints[0].Labels[OwnerLabelKey] = "owner"
+	endpoints[2].Labels[OwnerLabelKey] = "owner"
+
+	compact := Compact(endpoints)
+	assert.Equal(t, endpoints, compact)
+	for i := range endpoints {
+		assert.NotSame(t, endpoints[i], compact[i])
+	}
+
+	// The repeated strings are shared.
+	assert.Equal(t, unsafe.StringData(compact[0].DNSName), unsafe.StringData(compact[1].DNSName))
+	assert.Equal(t, unsafe.StringData(compact[0].Targets[0]), unsafe.StringData(compact[2].Targets[0]))
+
+	// The copies are independent of the endpoints, and of each other.
+	compact[0].Targets = append(compact[0].Targets, "1.2.3.6")
+	compact[0].Labels[OwnerLabelKey] = "other"
+	compact[1].ProviderSpecific = append(compact[1].ProviderSpecific, ProviderSpecificProperty{Name: "alias", Value: "true"})
+	assert.Equal(t, Targets{"2001:db8::1"}, compact[1].Targets)
+	assert.Equal(t, "owner", endpoints[0].Labels[OwnerLabelKey])
+	assert.Equal(t, "owner", compact[2].Labels[OwnerLabelKey])
+	assert.Equal(t, ProviderSpecific{{Name: "alias", Value: "false"}}, compact[0].ProviderSpecific)
+	assert.Equal(t, Targets{"1.2.3.4"}, compact[2].Targets)
+}
+
+func TestInterner(t *testing.T) {
+	in := NewInterner()
+	a := in.String(strings.Repeat("a", 3))
+	b := in.String(strings.Repeat("a", 3))
+	assert.Equal(t, unsafe.StringData(a), unsafe.StringData(b))
+	assert.Equal(t, "", in.String(""))
+	assert.Equal(t, 1, in.Len())
+
+	ep := NewEndpoint("a.example.org", RecordTypeA, "1.2.3.4")
+	labels := ep.Labels
+	in.Endpoint(ep)
+	ep.Labels["k"] = "v"
+	assert.Empty(t, labels, "the labels are copied")
+}
@@ -119,11 +119,10 @@ func (t Targets) Same(o Targets) bool {
 				}).Debugf("Couldn't parse %s as an IP address: %v", e, err)
 			}
 
-			// IPv6 Address Shortener == IPv6 Address Expander
-			if ipA.IsValid() && ipB.IsValid() {
-				return ipA.String() == ipB.String()
+			// IPv6 Address Shortener == IPv6 Address Expander, the next targets are compared too
+			if !ipA.IsValid() || !ipB.IsValid() || ipA != ipB {
+				return false
 			}
-			return false
 		}
 	}
 	return true
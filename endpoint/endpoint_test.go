@@ -105,6 +105,10 @@ func TestSameFailures(t *testing.T) {
 			[]string{"::1", "2600.com", "3.3.3.3"},
 			[]string{"2600.com", "3.3.3.3", "1.1.1.1"},
 		},
+		{
+			[]string{"2001:db8:0:0::2", "2001:db8::5"},
+			[]string{"2001:db8::2", "2001:db8::3"},
+		},
 	}
 
 	for _, d := range tests {
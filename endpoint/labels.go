@@ -44,6 +44,15 @@ const (
 	// DualstackLabelKey is the name of the label that identifies dualstack endpoints
 	DualstackLabelKey = "dualstack"
 
+	// ReplicaLabelKey is the name of the label that identifies the replica which last wrote the record, with --replicas
+	ReplicaLabelKey = "replica"
+
+	// ExpiresLabelKey is the name of the label with the expiry of the lease of a record, in Unix seconds, with --record-lease
+	ExpiresLabelKey = "expires"
+
+	// ZoneLabelKey is the name of the label with the zone of an endpoint, for the providers with several zones of its name
+	ZoneLabelKey = "zone"
+
 	// txtEncryptionNonce label for keep same nonce for same txt records, for prevent different result of encryption for same txt record, it can cause issues for some providers
 	txtEncryptionNonce = "txt-encryption-nonce"
 )
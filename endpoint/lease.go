@@ -0,0 +1,38 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpoint
+
+import (
+	"strconv"
+	"time"
+)
+
+// Expiry returns the expiry of the lease of the endpoint, false if it has no
+// lease.
+func (e *Endpoint) Expiry() (time.Time, bool) {
+	seconds, err := strconv.ParseInt(e.Labels[ExpiresLabelKey], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+// Expired returns true if the endpoint has a lease, expired at now.
+func (e *Endpoint) Expired(now time.Time) bool {
+	expiry, ok := e.Expiry()
+	return ok && !now.Before(expiry)
+}
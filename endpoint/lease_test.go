@@ -0,0 +1,42 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpoint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ep := NewEndpoint("pod.example.org", RecordTypeA, "10.0.0.1")
+	_, ok := ep.Expiry()
+	assert.False(t, ok)
+	assert.False(t, ep.Expired(now))
+
+	ep.Labels[ExpiresLabelKey] = "1700000060"
+	expiry, ok := ep.Expiry()
+	assert.True(t, ok)
+	assert.Equal(t, now.Add(time.Minute), expiry)
+	assert.False(t, ep.Expired(now))
+	assert.True(t, ep.Expired(now.Add(time.Minute)))
+
+	ep.Labels[ExpiresLabelKey] = "soon"
+	assert.False(t, ep.Expired(now.Add(time.Hour)))
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpoint
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ProviderSpecificPriority is the provider-specific property with the
+	// priority of the targets of an SRV endpoint.
+	ProviderSpecificPriority = "priority"
+	// ProviderSpecificWeight is the provider-specific property with the weight
+	// of the targets of an SRV endpoint.
+	ProviderSpecificWeight = "weight"
+	// ProviderSpecificPreference is the provider-specific property with the
+	// preference of the targets of an MX endpoint.
+	ProviderSpecificPreference = "preference"
+)
+
+// RenderPriority renders the priority and the weight properties of an SRV
+// endpoint into its targets, "PRIORITY WEIGHT PORT TARGET", and the preference
+// property of an MX endpoint into its targets, "PREFERENCE TARGET". The
+// properties replace the ones of the targets, which may omit them - an SRV
+// target without them has a priority of 0 and a weight of 50 - and are
+// removed: the providers only see the targets. An invalid property is removed
+// and returned as an error, the targets kept.
+func (e *Endpoint) RenderPriority() error {
+	// The properties, their defaults, and the fields of a target.
+	var names, defaults []string
+	var size int
+	switch e.RecordType {
+	case RecordTypeSRV:
+		names, defaults, size = []string{ProviderSpecificPriority, ProviderSpecificWeight}, []string{"0", "50"}, 4
+	case RecordTypeMX:
+		names, defaults, size = []string{ProviderSpecificPreference}, []string{""}, 2
+	default:
+		return nil
+	}
+	values := make([]string, len(names))
+	found := false
+	properties := ProviderSpecific{}
+	for _, p := range e.ProviderSpecific {
+		i := indexOf(names, p.Name)
+		if i < 0 {
+			properties = append(properties, p)
+			continue
+		}
+		values[i], found = p.Value, found || p.Value != ""
+	}
+	if len(properties) == len(e.ProviderSpecific) {
+		return nil
+	}
+	e.ProviderSpecific = properties
+	if !found {
+		return nil
+	}
+	for i, v := range values {
+		if _, err := strconv.ParseUint(v, 10, 16); v != "" && err != nil {
+			return fmt.Errorf("invalid %s %q of the %s endpoint %s: must be between 0 and 65535", names[i], v, e.RecordType, e.DNSName)
+		}
+	}
+
+	// The targets are copied, as the sources may share them between syncs.
+	targets := make(Targets, 0, len(e.Targets))
+	for _, t := range e.Targets {
+		fields := strings.Fields(t)
+		if len(fields) == size-len(names) {
+			fields = append(append([]string{}, defaults...), fields...)
+		}
+		if len(fields) != size {
+			targets = append(targets, t)
+			continue
+		}
+		for i, v := range values {
+			if v != "" {
+				fields[i] = v
+			}
+		}
+		targets = append(targets, strings.Join(fields, " "))
+	}
+	e.Targets = targets
+	return nil
+}
+
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderPriority(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		ep         *Endpoint
+		targets    Targets
+		properties ProviderSpecific
+		err        bool
+	}{
+		{
+			name:    "srv without priority",
+			ep:      NewEndpoint("_http._tcp.example.org", RecordTypeSRV, "443 a.example.org"),
+			targets: Targets{"443 a.example.org"},
+		},
+		{
+			name:    "srv priority and weight",
+			ep:      NewEndpoint("_http._tcp.example.org", RecordTypeSRV, "443 a.example.org", "1 2 443 b.example.org").WithProviderSpecific(ProviderSpecificPriority, "10").WithProviderSpecific(ProviderSpecificWeight, "20"),
+			targets: Targets{"10 20 443 a.example.org", "10 20 443 b.example.org"},
+		},
+		{
+			name:    "srv weight",
+			ep:      NewEndpoint("_http._tcp.example.org", RecordTypeSRV, "443 a.example.org", "1 2 443 b.example.org").WithProviderSpecific(ProviderSpecificWeight, "20"),
+			targets: Targets{"0 20 443 a.example.org", "1 20 443 b.example.org"},
+		},
+		{
+			name:       "mx preference",
+			ep:         NewEndpoint("example.org", RecordTypeMX, "mail.example.org", "5 backup.example.org").WithProviderSpecific(ProviderSpecificPreference, "10").WithProviderSpecific("other", "true"),
+			targets:    Targets{"10 mail.example.org", "10 backup.example.org"},
+			properties: ProviderSpecific{{Name: "other", Value: "true"}},
+		},
+		{
+			name:    "invalid weight",
+			ep:      NewEndpoint("_http._tcp.example.org", RecordTypeSRV, "443 a.example.org").WithProviderSpecific(ProviderSpecificWeight, "heavy"),
+			targets: Targets{"443 a.example.org"},
+			err:     true,
+		},
+		{
+			name:       "other record type",
+			ep:         NewEndpoint("example.org", RecordTypeA, "10.0.0.1").WithProviderSpecific(ProviderSpecificPriority, "10"),
+			targets:    Targets{"10.0.0.1"},
+			properties: ProviderSpecific{{Name: ProviderSpecificPriority, Value: "10"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.ep.RenderPriority()
+			if tc.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.targets, tc.ep.Targets)
+			assert.ElementsMatch(t, tc.properties, tc.ep.ProviderSpecific)
+		})
+	}
+}
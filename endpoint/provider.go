@@ -28,6 +28,20 @@ type DNSSource struct {
 	Name string
 	Domain string
 
+	Status DNSSourceStatus `json:"status,omitempty"`
+}
+
+// DNSSourceStatus is the health of a source. An unhealthy source, like one
+// with an informer failing to list its resources, doesn't delete the records
+// of its missing endpoints.
+type DNSSourceStatus struct {
+	// Healthy is false while the source is not synced or fails to list its
+	// resources, with the error in Message.
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message,omitempty"`
+	// LastEvent is the time of the last change of the resources of the
+	// source.
+	LastEvent *metav1.Time `json:"lastEvent,omitempty"`
 }
 
 type DNSServiceStatus struct {
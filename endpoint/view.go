@@ -0,0 +1,49 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpoint
+
+const (
+	// ProviderSpecificView is the provider-specific property with the view of
+	// an endpoint: the providers with split views, like a public and a private
+	// zone of the same domain, answer it only in the zones of its view.
+	ProviderSpecificView = "view"
+
+	// ViewPublic is the view of the public zones.
+	ViewPublic = "public"
+	// ViewPrivate is the view of the private zones.
+	ViewPrivate = "private"
+)
+
+// View returns the view of the endpoint, empty if it is answered in all
+// views.
+func (e *Endpoint) View() string {
+	view, _ := e.GetProviderSpecificProperty(ProviderSpecificView)
+	return view
+}
+
+// WithView sets the view of the endpoint, and its set identifier if it has
+// none: the endpoints of the views of a name are separate records, with
+// separate owners. The provider-specific properties are copied, as the
+// sources share them between endpoints.
+func (e *Endpoint) WithView(view string) *Endpoint {
+	e.ProviderSpecific = append(ProviderSpecific{}, e.ProviderSpecific...)
+	e.SetProviderSpecificProperty(ProviderSpecificView, view)
+	if e.SetIdentifier == "" {
+		e.SetIdentifier = view
+	}
+	return e
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithView(t *testing.T) {
+	shared := ProviderSpecific{{Name: "alias", Value: "true"}}
+	public := NewEndpoint("app.example.org", RecordTypeA, "34.1.2.3")
+	public.ProviderSpecific = shared
+	private := NewEndpoint("app.example.org", RecordTypeA, "10.0.0.1").WithSetIdentifier("blue")
+	private.ProviderSpecific = shared
+
+	assert.Equal(t, "", public.View())
+	public.WithView(ViewPublic)
+	private.WithView(ViewPrivate)
+
+	assert.Equal(t, ViewPublic, public.View())
+	assert.Equal(t, ViewPublic, public.SetIdentifier)
+	assert.Equal(t, ViewPrivate, private.View())
+	assert.Equal(t, "blue", private.SetIdentifier)
+	assert.Equal(t, ProviderSpecific{{Name: "alias", Value: "true"}}, shared)
+}
@@ -43,6 +43,9 @@ func (b byAllFields) Less(i, j int) bool {
 		// This rather bad, we need a more complex comparison for Targets, which considers all elements
 		if b[i].Targets.Same(b[j].Targets) {
 			if b[i].RecordType == (b[j].RecordType) {
+				if b[i].SetIdentifier != b[j].SetIdentifier {
+					return b[i].SetIdentifier < b[j].SetIdentifier
+				}
 				sa := b[i].ProviderSpecific
 				sb := b[j].ProviderSpecific
 				sort.Sort(byNames(sa))
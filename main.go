@@ -19,9 +19,12 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path"
+	"strings"
 	"syscall"
 	"time"
 
@@ -30,6 +33,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/route53"
 	sd "github.com/aws/aws-sdk-go/service/servicediscovery"
 	"github.com/go-logr/logr"
+	"github.com/miekg/dns"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/labels"
@@ -38,8 +42,27 @@ import (
 
 	"sigs.k8s.io/external-dns/controller"
 	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/admission"
 	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
 	"sigs.k8s.io/external-dns/pkg/apis/externaldns/validation"
+	"sigs.k8s.io/external-dns/pkg/approval"
+	"sigs.k8s.io/external-dns/pkg/audit"
+	"sigs.k8s.io/external-dns/pkg/canary"
+	"sigs.k8s.io/external-dns/pkg/chaos"
+	"sigs.k8s.io/external-dns/pkg/debug"
+	"sigs.k8s.io/external-dns/pkg/dnsserver"
+	"sigs.k8s.io/external-dns/pkg/domainlock"
+	"sigs.k8s.io/external-dns/pkg/failover"
+	"sigs.k8s.io/external-dns/pkg/flatten"
+	"sigs.k8s.io/external-dns/pkg/gitops"
+	"sigs.k8s.io/external-dns/pkg/lbhealth"
+	"sigs.k8s.io/external-dns/pkg/logging"
+	"sigs.k8s.io/external-dns/pkg/ownership"
+	"sigs.k8s.io/external-dns/pkg/planpolicy"
+	"sigs.k8s.io/external-dns/pkg/transform"
+	"sigs.k8s.io/external-dns/pkg/ttlpolicy"
+	"sigs.k8s.io/external-dns/pkg/verify"
+	"sigs.k8s.io/external-dns/pkg/wasm"
 	"sigs.k8s.io/external-dns/plan"
 	"sigs.k8s.io/external-dns/provider"
 	"sigs.k8s.io/external-dns/provider/akamai"
@@ -50,6 +73,7 @@ import (
 	"sigs.k8s.io/external-dns/provider/bluecat"
 	"sigs.k8s.io/external-dns/provider/civo"
 	"sigs.k8s.io/external-dns/provider/cloudflare"
+	"sigs.k8s.io/external-dns/provider/consul"
 	"sigs.k8s.io/external-dns/provider/coredns"
 	"sigs.k8s.io/external-dns/provider/designate"
 	"sigs.k8s.io/external-dns/provider/digitalocean"
@@ -59,6 +83,7 @@ import (
 	"sigs.k8s.io/external-dns/provider/gandi"
 	"sigs.k8s.io/external-dns/provider/godaddy"
 	"sigs.k8s.io/external-dns/provider/google"
+	"sigs.k8s.io/external-dns/provider/hostsfile"
 	"sigs.k8s.io/external-dns/provider/ibmcloud"
 	"sigs.k8s.io/external-dns/provider/inmemory"
 	"sigs.k8s.io/external-dns/provider/linode"
@@ -84,13 +109,22 @@ import (
 	"sigs.k8s.io/external-dns/source"
 )
 
+// debugState serves /debug/state with the metrics, with --debug-endpoints.
+var debugState = &debug.Handler{}
+
 func main() {
 	cfg := externaldns.NewConfig()
 	if err := cfg.ParseFlags(os.Args[1:]); err != nil {
 		log.Fatalf("flag parsing error: %v", err)
 	}
-	if cfg.LogFormat == "json" {
-		log.SetFormatter(&log.JSONFormatter{})
+	// logrus messages are forwarded to the slog handler.
+	logLevels, err := logging.Setup(os.Stderr, cfg.LogFormat, cfg.LogLevel, cfg.LogLevels)
+	if err != nil {
+		log.Fatalf("failed to configure logging: %v", err)
+	}
+	// The levels are changed at runtime with the admin API, see --admin-api.
+	if cfg.DebugEndpoints {
+		debugState.Register(http.DefaultServeMux)
 	}
 	log.Infof("config: %s", cfg)
 
@@ -102,12 +136,6 @@ func main() {
 		log.Info("running in dry-run mode. No changes to DNS records will be made.")
 	}
 
-	ll, err := log.ParseLevel(cfg.LogLevel)
-	if err != nil {
-		log.Fatalf("failed to parse log level: %v", err)
-	}
-	log.SetLevel(ll)
-
 	// Klog V2 is used by k8s.io/apimachinery/pkg/labels and can throw (a lot) of irrelevant logs
 	// See https://github.com/kubernetes-sigs/external-dns/issues/2348
 	defer klog.ClearLogger()
@@ -117,7 +145,7 @@ func main() {
 
 	// No need to register metrics or signal handling if we're running in once mode.
 	// TODO: switch to OTel, generate traces too
-	if !cfg.Once {
+	if !cfg.Once && !cfg.Validate {
 		go serveMetrics(cfg.MetricsAddress)
 	}
 	go handleSigterm(cancel)
@@ -153,9 +181,215 @@ func main() {
 	targetFilter := endpoint.NewTargetNetFilterWithExclusions(cfg.TargetNetFilter, cfg.ExcludeTargetNets)
 
 	// Combine multiple sources into a single, deduplicated source.
-	endpointsSource := source.NewDedupSource(source.NewMultiSource(sources, sourceCfg.DefaultTargets))
+	multiSourceOpts := []source.MultiSourceOption{source.WithSourceNames(cfg.Sources), source.WithSourceTimeout(cfg.SourceTimeout), source.WithMergePolicy(cfg.SourceMergePolicy)}
+	if cfg.SourceFailurePolicy == "skip" {
+		multiSourceOpts = append(multiSourceOpts, source.WithSkipFailingSources())
+	}
+	sourceDomainFilters, err := source.ParseSourceDomainFilters(cfg.SourceDomainFilters)
+	if err != nil {
+		log.Fatal(err)
+	}
+	endpointsSource := source.NewDedupSource(source.NewMultiSource(source.WithDomainFilters(sources, cfg.Sources, sourceDomainFilters), sourceCfg.DefaultTargets, multiSourceOpts...))
 	endpointsSource = source.NewTargetFilterSource(endpointsSource, targetFilter)
 
+	// Rewrite the endpoints with the transformation rules, then the WebAssembly modules.
+	var transformers []source.Transformer
+	if cfg.TransformRules != "" {
+		rules, err := transform.Load(cfg.TransformRules)
+		if err != nil {
+			log.Fatal(err)
+		}
+		transformers = append(transformers, rules)
+	}
+	for _, path := range cfg.WasmTransforms {
+		t, err := wasm.Load(ctx, path, wasm.Options{})
+		if err != nil {
+			log.Fatal(err)
+		}
+		transformers = append(transformers, t)
+	}
+	// Withdraw the targets with unhealthy load balancer backends.
+	var withdrawer *lbhealth.Withdrawer
+	if cfg.LBHealthProject != "" {
+		api, err := lbhealth.NewGCP(ctx, cfg.LBHealthProject)
+		if err != nil {
+			log.Fatal(err)
+		}
+		withdrawer = lbhealth.New(api, cfg.LBHealthUnhealthyThreshold, cfg.LBHealthHealthyThreshold)
+		transformers = append(transformers, withdrawer)
+	}
+	// Flatten the CNAMEs before the TTL policy, which limits their TTLs.
+	var flattener *flatten.Flattener
+	if len(cfg.CNAMEFlattening) > 0 || cfg.ApexAlias {
+		domains, err := flatten.ParseDomains(cfg.CNAMEFlattening)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if flattener, err = flatten.New(domains, cfg.CNAMEFlatteningResolver); err != nil {
+			log.Fatal(err)
+		}
+		transformers = append(transformers, flattener)
+	}
+	// Enforce the TTL policy on the transformed endpoints.
+	if cfg.TTLPolicy != "" || cfg.DefaultTTL > 0 {
+		var ttlCfg ttlpolicy.Config
+		if cfg.TTLPolicy != "" {
+			var err error
+			if ttlCfg, err = ttlpolicy.ReadConfig(cfg.TTLPolicy); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if ttlCfg.Default == 0 {
+			ttlCfg.Default = cfg.DefaultTTL
+		}
+		policy, err := ttlpolicy.New(ttlCfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		transformers = append(transformers, policy)
+	}
+	if len(transformers) > 0 {
+		endpointsSource = source.NewTransformSource(endpointsSource, transformers...)
+	}
+
+	if cfg.Provider == "federation" {
+		runFederation(ctx, cfg, endpointsSource, logLevels)
+		return
+	}
+
+	p, domainFilter := buildProvider(ctx, cfg, endpointsSource)
+	if cfg.ApexAlias {
+		// The zones of the provider, not of the standby one.
+		zones := p
+		flattener.Zones = func() []string { return zones.GetDomainFilter().Filters }
+	}
+	if flattener != nil && len(cfg.VerifyResolvers) > 0 {
+		// Check that the flattened records follow their targets, served with the metrics.
+		flattener.Verifier = verify.New(verify.ParseResolvers(cfg.VerifyResolvers))
+		http.Handle("/verify/flatten", flattener.Verifier)
+	}
+	if cfg.FailoverConfig != "" {
+		p = buildFailover(ctx, cfg, endpointsSource, p)
+	}
+
+	if cfg.Validate {
+		// The sources and the provider are created, the errors are fatal.
+		buildRegistry(ctx, cfg, p, "")
+		printEffective(cfg, "")
+		os.Exit(0)
+	}
+
+	if cfg.WebhookServer {
+		webhookAddr := os.Getenv("EXTERNAL_DNS_WEBHOOK_ADDR")
+		if webhookAddr == "" {
+			webhookAddr = ":8080"
+		}
+		// TODO(costin): listen address (assume mesh or frontend authz)
+		if err := webhookapi.ServeHTTPApi(ctx, p, nil, cfg.WebhookProviderReadTimeout, cfg.WebhookProviderWriteTimeout, cfg.ShutdownTimeout, webhookAddr, webhookapi.Handlers{DoH: cfg.WebhookServerDoH, CertManager: cfg.WebhookServerCertManager, Cutover: cfg.WebhookServerCutover}); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	}
+
+	dnsServer := newDNSServer(cfg, p)
+	registryProvider := p
+	if dnsServer != nil && dnsServer.Journal != nil {
+		// The changes applied by the controller, for the IXFR of the secondaries.
+		registryProvider = dnsServer.Journal.Provider(p)
+	}
+	r, rp, store := buildRegistry(ctx, cfg, registryProvider, "")
+
+	ctrl := newController(cfg, endpointsSource, p, r, domainFilter, newChangeBudget(cfg), "")
+	debugState.AddState("controller", func() any { return ctrl.DebugState() })
+
+	// Reverse sync of ServiceEntries, using the registry records to filter by owner.
+	for _, s := range sources {
+		se, ok := s.(*source.ServiceEntrySource)
+		if !ok {
+			continue
+		}
+		debugState.AddState("source/istio-se", func() any { return se.DebugState() })
+		if cfg.IstioSEReverseNamespace != "" {
+			go se.RunReverseSync(ctx, syncedRecords(ctrl), cfg.Interval)
+		}
+	}
+
+	// Read-only dashboard and Prometheus service discovery, served with the metrics.
+	http.Handle("/dashboard", ctrl)
+	http.HandleFunc("/prometheus/sd", ctrl.ServePrometheusSD)
+	if cfg.AdminAPI {
+		admin := &controller.Admin{Controller: ctrl, LogLevels: logLevels}
+		if store != nil {
+			admin.Rollback = audit.NewRollback(store, rp, cfg.RollbackHistory)
+		}
+		if ctrl.Quarantine != nil {
+			admin.Quarantines = map[string]*controller.Quarantine{"": ctrl.Quarantine}
+		}
+		go serveAdmin(cfg, admin)
+	}
+
+	if cfg.AdmissionWebhookAddress != "" {
+		webhook := &admission.Webhook{
+			Checker: &admission.Checker{Records: ctrl.Records, OwnerID: r.OwnerID()},
+			Warn:    cfg.AdmissionWebhookMode == admission.ModeWarn,
+		}
+		go func() {
+			if err := webhook.ListenAndServeTLS(ctx, cfg.AdmissionWebhookAddress, cfg.AdmissionWebhookCertFile, cfg.AdmissionWebhookKeyFile); err != nil {
+				log.Fatalf("Failed to serve the admission webhook: %v", err)
+			}
+		}()
+	}
+
+	serveDNS(ctx, cfg, dnsServer)
+
+	if cfg.EmitDir != "" {
+		w := &gitops.Writer{Dir: cfg.EmitDir, Commit: cfg.EmitGitCommit}
+		if cfg.Once {
+			if err := ctrl.Emit(ctx, w); err != nil {
+				log.Fatal(err)
+			}
+			os.Exit(0)
+		}
+		ctrl.RunEmit(ctx, w, cfg.Interval)
+		return
+	}
+
+	if cfg.Once {
+		// 0 if in sync or the changes are applied, 2 if there are changes in
+		// dry run, 1 on error.
+		os.Exit(ctrl.RunOnceExitCode(ctx))
+	}
+
+	if cfg.UpdateEvents {
+		// Add RunOnce as the handler function that will be called when ingress/service sources have changed.
+		// Note that k8s Informers will perform an initial list operation, which results in the handler
+		// function initially being called for every Service/Ingress that exists
+		ctrl.Source.AddEventHandler(ctx, func() { ctrl.ScheduleEvent(time.Now()) })
+	}
+
+	if flattener != nil {
+		// Sync when the addresses of a flattened target change.
+		go flattener.Run(ctx, func() { ctrl.ScheduleEvent(time.Now()) })
+	}
+	if withdrawer != nil {
+		// Sync when a target is withdrawn or restored.
+		go withdrawer.Run(ctx, cfg.LBHealthInterval, func() { ctrl.ScheduleEvent(time.Now()) })
+	}
+
+	if cfg.DriftInterval > 0 {
+		go ctrl.RunDriftDetection(ctx, cfg.DriftInterval)
+	}
+
+	ctrl.ScheduleRunOnce(time.Now())
+	if err := ctrl.RunUntilShutdown(ctx, cfg.FinalSync, cfg.ShutdownTimeout); err != nil {
+		log.Fatalf("Failed to do the final sync: %v", err)
+	}
+}
+
+// buildProvider returns the provider of the configuration, and its domain filter.
+func buildProvider(ctx context.Context, cfg *externaldns.Config, endpointsSource source.Source) (provider.Provider, endpoint.DomainFilter) {
+	var err error
+
 	// RegexDomainFilter overrides DomainFilter
 	var domainFilter endpoint.DomainFilter
 	if cfg.RegexDomainFilter.String() != "" {
@@ -354,6 +588,26 @@ func main() {
 				DryRun:                cfg.DryRun,
 			},
 		)
+	case "consul":
+		p, err = consul.NewConsulProvider(
+			consul.ConsulConfig{
+				Address:      cfg.ConsulAddress,
+				Token:        cfg.ConsulToken,
+				Datacenter:   cfg.ConsulDatacenter,
+				Node:         cfg.ConsulNode,
+				Domain:       cfg.ConsulDomain,
+				DomainFilter: domainFilter,
+				DryRun:       cfg.DryRun,
+			},
+		)
+	case "hostsfile":
+		p, err = hostsfile.NewHostsFileProvider(
+			hostsfile.HostsFileConfig{
+				Path:         cfg.HostsFilePath,
+				DomainFilter: domainFilter,
+				DryRun:       cfg.DryRun,
+			},
+		)
 	case "ibmcloud":
 		p, err = ibmcloud.NewIBMCloudProvider(cfg.IBMCloudConfigFile, domainFilter, zoneIDFilter, endpointsSource, cfg.IBMCloudProxied, cfg.DryRun)
 	case "safedns":
@@ -371,14 +625,78 @@ func main() {
 		log.Fatal(err)
 	}
 
-	if cfg.WebhookServer {
-		webhookAddr := os.Getenv("EXTERNAL_DNS_WEBHOOK_ADDR")
-		if webhookAddr == "" {
-			webhookAddr = ":8080"
+	return p, domainFilter
+}
+
+// buildFailover returns the provider failing over from p to the secondary
+// provider of --failover-config.
+func buildFailover(ctx context.Context, cfg *externaldns.Config, endpointsSource source.Source, p provider.Provider) provider.Provider {
+	target, err := externaldns.LoadFailoverTarget(cfg.FailoverConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	secondaryCfg, err := externaldns.TargetConfig(os.Args[1:], target)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := validation.ValidateConfig(secondaryCfg); err != nil {
+		log.Fatalf("%s: config validation failed: %v", target.Name, err)
+	}
+	secondary, _ := buildProvider(ctx, secondaryCfg, endpointsSource)
+	log.Infof("Standby provider %s: %s, domain filter %v", target.Name, secondaryCfg.Provider, secondaryCfg.DomainFilter)
+	return failover.NewProvider(p, secondary, failover.Config{Threshold: cfg.FailoverThreshold, After: cfg.FailoverAfter})
+}
+
+// buildRegistry wraps the provider with the verification, the fault injection and
+// the audit trail, and returns the registry of the configuration, the wrapped
+// provider and the store of the audit trail, nil without one. name is the name
+// of a federation target, empty otherwise.
+func buildRegistry(ctx context.Context, cfg *externaldns.Config, p provider.Provider, name string) (registry.Registry, provider.Provider, audit.Store) {
+	var err error
+	var store audit.Store
+
+	// Apply the changes to the canary domain first, verified by its own verifier.
+	if cfg.CanaryDomain != "" && cfg.Registry != "aws-sd" {
+		v := verify.New(verify.ParseResolvers(cfg.VerifyResolvers))
+		v.Wait.Timeout = cfg.VerifyTimeout
+		p = canary.NewProvider(p, cfg.CanaryDomain, v)
+	}
+
+	// The aws-sd registry requires the unwrapped provider.
+	if len(cfg.VerifyResolvers) > 0 && cfg.Registry != "aws-sd" {
+		v := verify.New(verify.ParseResolvers(cfg.VerifyResolvers))
+		v.Wait.Timeout = cfg.VerifyTimeout
+		vp := verify.NewProvider(p, v)
+		vp.Block = cfg.VerifyWait
+		p = vp
+		// Served with the metrics.
+		http.Handle(path.Join("/verify", name), v)
+	}
+
+	// Inject the faults into the verified provider; the audit trail records the injected failures.
+	chaosConfig := chaos.Config{
+		RecordsErrorRate: cfg.ChaosRecordsErrorRate,
+		ErrorRate:        cfg.ChaosErrorRate,
+		PartialRate:      cfg.ChaosPartialRate,
+		MaxDelay:         cfg.ChaosMaxDelay,
+	}
+	if chaosConfig.Enabled() && cfg.Registry != "aws-sd" {
+		log.Warnf("Injecting faults into the provider calls: %+v", chaosConfig)
+		p = chaos.NewProvider(p, chaosConfig)
+	}
+
+	if cfg.AuditLocation != "" && cfg.Registry != "aws-sd" {
+		store, err = audit.Open(ctx, cfg.AuditLocation)
+		if err != nil {
+			log.Fatalf("Failed to open audit location: %v", err)
 		}
-		// TODO(costin): listen address (assume mesh or frontend authz)
-		webhookapi.StartHTTPApi(p, nil, cfg.WebhookProviderReadTimeout, cfg.WebhookProviderWriteTimeout, webhookAddr)
-		os.Exit(0)
+		actor := cfg.AuditActor
+		if actor == "" {
+			actor, _ = os.Hostname()
+		}
+		rec := audit.NewRecorder(store, actor, cfg.AuditRetention)
+		go rec.Run(ctx, time.Hour)
+		p = audit.NewProvider(p, rec)
 	}
 
 	var r registry.Registry
@@ -403,12 +721,49 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if cfg.ApprovalNamespace != "" {
+		client, err := source.NewDynamicKubernetesClient(cfg.KubeConfig, cfg.APIServerURL, cfg.RequestTimeout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ar := approval.NewRegistry(r, client, cfg.ApprovalNamespace)
+		go ar.Run(ctx, cfg.Interval)
+		r = ar
+	}
+
+	if len(cfg.RegoPolicies) > 0 {
+		policies, err := planpolicy.LoadRego(ctx, cfg.RegoPolicies)
+		if err != nil {
+			log.Fatal(err)
+		}
+		r = planpolicy.NewRegistry(r, policies, "Rego policies")
+	}
+	if cfg.PolicyWebhookURL != "" {
+		r = planpolicy.NewRegistry(r, planpolicy.NewWebhook(cfg.PolicyWebhookURL, cfg.PolicyWebhookTimeout), "policy webhook")
+	}
+
+	return r, p, store
+}
+
+// newChangeBudget returns the budget of the changes applied per minute, shared
+// by the controllers of all the providers, or nil if unlimited.
+func newChangeBudget(cfg *externaldns.Config) *controller.ChangeBudget {
+	if cfg.MaxChangesPerMinute <= 0 && cfg.MaxZoneChangesPerMinute <= 0 {
+		return nil
+	}
+	return controller.NewChangeBudget(cfg.MaxChangesPerMinute, cfg.MaxZoneChangesPerMinute)
+}
+
+// newController returns the controller syncing the source to the registry,
+// within the budget of changes if not nil. name is the name of a federation
+// target, empty otherwise.
+func newController(cfg *externaldns.Config, endpointsSource source.Source, p provider.Provider, r registry.Registry, domainFilter endpoint.DomainFilter, budget *controller.ChangeBudget, name string) *controller.Controller {
 	policy, exists := plan.Policies[cfg.Policy]
 	if !exists {
 		log.Fatalf("unknown policy: %s", cfg.Policy)
 	}
 
-	ctrl := controller.Controller{
+	ctrl := &controller.Controller{
 		Source:               endpointsSource,
 		Registry:             r,
 		Policy:               policy,
@@ -417,26 +772,144 @@ func main() {
 		ManagedRecordTypes:   cfg.ManagedDNSRecordTypes,
 		ExcludeRecordTypes:   cfg.ExcludeDNSRecordTypes,
 		MinEventSyncInterval: cfg.MinEventSyncInterval,
+		EventDebounce:        cfg.EventDebounce,
+		EventMaxDelay:        cfg.EventMaxDelay,
+		DryRun:               cfg.DryRun,
+		Shards:               cfg.PlanShards,
+		RecordCacheFile:      cfg.RecordCacheFile,
+		RecordCacheMaxAge:    cfg.RecordCacheMaxAge,
+		Replica:              cfg.Replica,
+		Replicas:             cfg.Replicas,
+		Lease:                cfg.RecordLease,
+		ConflictPolicy:       cfg.ConflictPolicy,
+		StrictShadowing:      cfg.StrictShadowing,
+		Budget:               budget,
+	}
+	if cfg.Schedule != "" {
+		schedule, err := controller.ParseSchedule(cfg.Schedule)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ctrl.Schedule = schedule
+	}
+	if cfg.QuarantineFactor > 0 {
+		// Served, and cleared, with the admin API.
+		ctrl.Quarantine = controller.NewQuarantine(cfg.QuarantineFactor, cfg.QuarantineMinChanges, cfg.QuarantineHistory, cfg.QuarantineTimeout)
+	}
+	if cfg.ConflictEvents || cfg.ChangeEvents {
+		client, err := source.NewKubeClient(cfg.KubeConfig, cfg.APIServerURL, cfg.RequestTimeout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		reporter := ownership.NewEventReporter(client)
+		if cfg.ConflictEvents {
+			ctrl.ConflictReporter = reporter
+		}
+		if cfg.ChangeEvents {
+			ctrl.ChangeReporter = reporter
+		}
+	}
+	if cfg.DomainLockNamespace != "" {
+		client, err := source.NewKubeClient(cfg.KubeConfig, cfg.APIServerURL, cfg.RequestTimeout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		identity := cfg.DomainLockIdentity
+		if identity == "" {
+			identity, _ = os.Hostname()
+		}
+		ctrl.DomainLock = domainlock.NewLeaseLock(client, cfg.DomainLockNamespace, identity, cfg.DomainLockDuration)
+	}
+	if cfg.FullSyncInterval > 0 {
+		if journal, ok := p.(provider.Journal); ok {
+			ctrl.Journal = journal
+			ctrl.FullSyncInterval = cfg.FullSyncInterval
+		} else {
+			log.Warnf("The %s provider has no change journal, --full-sync-interval is ignored", cfg.Provider)
+		}
 	}
+	return ctrl
+}
 
-	if cfg.Once {
-		err := ctrl.RunOnce(ctx)
+// runFederation syncs the source to the targets of --federation-config, each
+// configured with the command line flags replaced by its own. The admin API
+// only serves the log levels and the quarantines of the targets.
+func runFederation(ctx context.Context, cfg *externaldns.Config, endpointsSource source.Source, logLevels *logging.Levels) {
+	targets, err := externaldns.LoadFederationTargets(cfg.FederationConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	f := &controller.Federation{}
+	quarantines := map[string]*controller.Quarantine{}
+	// The budget of the changes is shared by all the targets.
+	budget := newChangeBudget(cfg)
+	targetCfgs := make([]*externaldns.Config, len(targets))
+	for i, target := range targets {
+		targetCfg, err := externaldns.TargetConfig(os.Args[1:], target)
 		if err != nil {
 			log.Fatal(err)
 		}
+		targetCfgs[i] = targetCfg
+		if err := validation.ValidateConfig(targetCfg); err != nil {
+			log.Fatalf("target %s: config validation failed: %v", target.Name, err)
+		}
+		if targetCfg.FailoverConfig != "" {
+			log.Fatalf("target %s: --failover-config is not supported in the federation targets", target.Name)
+		}
+		if targetCfg.RecordCacheFile != "" {
+			log.Fatalf("target %s: --record-cache-file is not supported in the federation targets", target.Name)
+		}
+		p, domainFilter := buildProvider(ctx, targetCfg, endpointsSource)
+		r, _, _ := buildRegistry(ctx, targetCfg, p, target.Name)
+		ctrl := newController(targetCfg, endpointsSource, p, r, domainFilter, budget, target.Name)
+		debugState.AddState(path.Join("controller", target.Name), func() any { return ctrl.DebugState() })
+		// Read-only dashboard and Prometheus service discovery of the target, served with the metrics.
+		http.Handle(path.Join("/dashboard", target.Name), ctrl)
+		http.HandleFunc(path.Join("/prometheus/sd", target.Name), ctrl.ServePrometheusSD)
+		if ctrl.Quarantine != nil {
+			quarantines[target.Name] = ctrl.Quarantine
+		}
+		f.Targets = append(f.Targets, &controller.FederationTarget{Name: target.Name, Controller: ctrl})
+		log.Infof("Federation target %s: provider %s, domain filter %v", target.Name, targetCfg.Provider, targetCfg.DomainFilter)
+	}
 
+	if cfg.Validate {
+		printEffective(cfg, "")
+		for i, target := range targets {
+			printEffective(targetCfgs[i], target.Name)
+		}
 		os.Exit(0)
 	}
 
+	if cfg.Once {
+		if err := f.RunOnce(ctx); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	}
+	if cfg.AdminAPI {
+		go serveAdmin(cfg, &controller.Admin{LogLevels: logLevels, Quarantines: quarantines})
+	}
 	if cfg.UpdateEvents {
-		// Add RunOnce as the handler function that will be called when ingress/service sources have changed.
-		// Note that k8s Informers will perform an initial list operation, which results in the handler
-		// function initially being called for every Service/Ingress that exists
-		ctrl.Source.AddEventHandler(ctx, func() { ctrl.ScheduleRunOnce(time.Now()) })
+		endpointsSource.AddEventHandler(ctx, func() { f.ScheduleEvent(time.Now()) })
 	}
+	f.ScheduleRunOnce(time.Now())
+	f.Run(ctx)
+}
 
-	ctrl.ScheduleRunOnce(time.Now())
-	ctrl.Run(ctx)
+// printEffective prints the effective configuration, with the secrets
+// redacted, as a YAML document. name is the name of a federation target, empty
+// otherwise.
+func printEffective(cfg *externaldns.Config, name string) {
+	data, err := cfg.Effective()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("---")
+	if name != "" {
+		fmt.Printf("# target %s\n", name)
+	}
+	fmt.Print(string(data))
 }
 
 func handleSigterm(cancel func()) {
@@ -447,6 +920,92 @@ func handleSigterm(cancel func()) {
 	cancel()
 }
 
+// syncedRecords returns the records of the last sync of the controller. They
+// are not listed again: the registry is only used by the syncs.
+func syncedRecords(ctrl *controller.Controller) func(context.Context) ([]*endpoint.Endpoint, error) {
+	return func(context.Context) ([]*endpoint.Endpoint, error) {
+		if ctrl.LastSyncTime().IsZero() {
+			return nil, source.ErrRecordsNotSynced
+		}
+		return ctrl.Records(), nil
+	}
+}
+
+// serveAdmin serves the admin API on its own address, not with the metrics:
+// it changes the state of the controller.
+func serveAdmin(cfg *externaldns.Config, admin *controller.Admin) {
+	log.Fatal(http.ListenAndServe(cfg.AdminAPIAddress, newAdminMux(cfg, admin)))
+}
+
+// newAdminMux returns the mux of the admin API, requiring the token of
+// --admin-api-token-file if set.
+func newAdminMux(cfg *externaldns.Config, admin *controller.Admin) *http.ServeMux {
+	if cfg.AdminAPITokenFile != "" {
+		token, err := os.ReadFile(cfg.AdminAPITokenFile)
+		if err != nil {
+			log.Fatalf("Failed to read the admin API token: %v", err)
+		}
+		admin.Token = strings.TrimSpace(string(token))
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/admin/", admin)
+	return mux
+}
+
+// newDNSServer returns the embedded DNS server serving the records of p, nil
+// without any listener. With the zone transfers, its journal must record the
+// changes applied to p, for IXFR.
+func newDNSServer(cfg *externaldns.Config, p provider.Provider) *dnsserver.Server {
+	if cfg.DNSServerAddress == "" && cfg.DNSServerTLSAddress == "" && len(cfg.DNSServerMDNSDomains) == 0 {
+		return nil
+	}
+	s := dnsserver.New(p)
+	if len(cfg.DNSServerTransferSecondaries) > 0 || cfg.DNSServerTSIGKeyName != "" {
+		s.Transfer = &dnsserver.TransferConfig{Secondaries: cfg.DNSServerTransferSecondaries}
+		if cfg.DNSServerTSIGKeyName != "" {
+			s.Transfer.TSIGSecrets = map[string]string{dns.Fqdn(cfg.DNSServerTSIGKeyName): cfg.DNSServerTSIGSecret}
+		}
+		s.Journal = dnsserver.NewJournal()
+	}
+	return s
+}
+
+// serveDNS starts the listeners of the embedded DNS server, if not nil.
+func serveDNS(ctx context.Context, cfg *externaldns.Config, s *dnsserver.Server) {
+	if s == nil {
+		return
+	}
+	if cfg.DNSServerAddress != "" {
+		go func() {
+			if err := s.ListenAndServe(ctx, cfg.DNSServerAddress); err != nil {
+				log.Fatalf("Failed to serve DNS: %v", err)
+			}
+		}()
+	}
+	if cfg.DNSServerTLSAddress != "" {
+		go func() {
+			if err := s.ListenAndServeTLS(ctx, cfg.DNSServerTLSAddress, cfg.DNSServerTLSCertFile, cfg.DNSServerTLSKeyFile); err != nil {
+				log.Fatalf("Failed to serve DNS-over-TLS: %v", err)
+			}
+		}()
+	}
+	if len(cfg.DNSServerMDNSDomains) > 0 {
+		var ifi *net.Interface
+		if cfg.DNSServerMDNSInterface != "" {
+			var err error
+			if ifi, err = net.InterfaceByName(cfg.DNSServerMDNSInterface); err != nil {
+				log.Fatalf("Failed to find the mDNS interface: %v", err)
+			}
+		}
+		responder := dnsserver.NewMDNSResponder(s, cfg.DNSServerMDNSDomains)
+		go func() {
+			if err := responder.ListenAndServe(ctx, ifi); err != nil {
+				log.Fatalf("Failed to serve mDNS: %v", err)
+			}
+		}()
+	}
+}
+
 func serveMetrics(address string) {
 	if address == "" {
 		return
@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/controller"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/provider/fake"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+func TestQuarantineServedByAdminAPI(t *testing.T) {
+	cfg := externaldns.NewConfig()
+	cfg.Policy = "sync"
+	cfg.QuarantineFactor = 2
+	cfg.AdminAPITokenFile = filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(cfg.AdminAPITokenFile, []byte("secret\n"), 0o600))
+	p := fake.New()
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	ctrl := newController(cfg, new(testutils.MockSource), p, r, endpoint.DomainFilter{}, nil, "")
+	require.NotNil(t, ctrl.Quarantine)
+	admin := newAdminMux(cfg, &controller.Admin{Controller: ctrl, Quarantines: map[string]*controller.Quarantine{"": ctrl.Quarantine}})
+
+	do := func(h http.Handler, target, authorization string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, target, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+	// The metrics address doesn't change the state of the controller.
+	assert.Equal(t, http.StatusNotFound, do(http.DefaultServeMux, "/quarantine", ""))
+	assert.Equal(t, http.StatusUnauthorized, do(admin, "/admin/quarantine", ""))
+	assert.Equal(t, http.StatusOK, do(admin, "/admin/quarantine", "Bearer secret"))
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package admission is a validating admission webhook checking the hostnames
+// claimed by the Services, ServiceEntries, Gateways, Ingresses and routes
+// against the ownership data of the registry: an object claiming a hostname
+// already published for another namespace is rejected, or admitted with a
+// warning, so a tenant can't take over the DNS names of another one.
+package admission
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// The modes of the webhook, for a conflicting object.
+const (
+	ModeDeny = "deny"
+	ModeWarn = "warn"
+)
+
+const hostnameAnnotationKey = "external-dns.alpha.kubernetes.io/hostname"
+
+var (
+	istioServiceEntry = schema.GroupKind{Group: "networking.istio.io", Kind: "ServiceEntry"}
+	istioGateway      = schema.GroupKind{Group: "networking.istio.io", Kind: "Gateway"}
+	gatewayAPIGateway = schema.GroupKind{Group: "gateway.networking.k8s.io", Kind: "Gateway"}
+	ingress           = schema.GroupKind{Group: "networking.k8s.io", Kind: "Ingress"}
+)
+
+// externalResources are the kinds of the resource labels of the sources
+// outside of Kubernetes, like nomad/NAMESPACE/NAME: their namespaces are not
+// Kubernetes namespaces.
+var externalResources = map[string]bool{
+	"consul":         true,
+	"docker":         true,
+	"file":           true,
+	"forwardingrule": true,
+	"gce":            true,
+	"istiod":         true,
+	"nomad":          true,
+}
+
+// Checker finds the hostnames of an object published for the objects of
+// other namespaces.
+type Checker struct {
+	// Records returns the registry records, with their owner and resource
+	// labels - the ones of the last sync of the controller.
+	Records func() []*endpoint.Endpoint
+	// OwnerID is the owner of the records of the cluster, the others are
+	// ignored.
+	OwnerID string
+}
+
+// Conflict is a hostname of an object published for an object of another
+// namespace.
+type Conflict struct {
+	Hostname string
+	Resource string
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s is owned by %s", c.Hostname, c.Resource)
+}
+
+// Conflicts returns the hostnames of the object owned by the objects of the
+// other namespaces, sorted by hostname.
+func (c *Checker) Conflicts(obj *unstructured.Unstructured) []Conflict {
+	hostnames := Hostnames(obj)
+	if len(hostnames) == 0 {
+		return nil
+	}
+	owners := map[string]string{}
+	for _, ep := range c.Records() {
+		if ep.Labels[endpoint.OwnerLabelKey] != c.OwnerID {
+			continue
+		}
+		resource := ep.Labels[endpoint.ResourceLabelKey]
+		if namespace, ok := resourceNamespace(resource); ok && namespace != obj.GetNamespace() {
+			owners[normalizeHostname(ep.DNSName)] = resource
+		}
+	}
+
+	var conflicts []Conflict
+	for _, hostname := range hostnames {
+		if resource, ok := owners[hostname]; ok {
+			conflicts = append(conflicts, Conflict{Hostname: hostname, Resource: resource})
+		}
+	}
+	return conflicts
+}
+
+// resourceNamespace returns the namespace of a resource label of a
+// Kubernetes source, like service/NAMESPACE/NAME.
+func resourceNamespace(resource string) (string, bool) {
+	parts := strings.Split(resource, "/")
+	if len(parts) != 3 || externalResources[parts[0]] || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// Hostnames returns the hostnames claimed by an object: the hostname
+// annotation, and the hosts of the ServiceEntries, the Istio and Gateway API
+// Gateways, the Ingresses and the Gateway API routes. The wildcards are
+// skipped.
+func Hostnames(obj *unstructured.Unstructured) []string {
+	var hostnames []string
+	if annotation, ok := obj.GetAnnotations()[hostnameAnnotationKey]; ok {
+		hostnames = append(hostnames, strings.Split(annotation, ",")...)
+	}
+
+	gk := obj.GroupVersionKind().GroupKind()
+	switch {
+	case gk == istioServiceEntry:
+		hosts, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "hosts")
+		hostnames = append(hostnames, hosts...)
+	case gk == istioGateway:
+		for _, server := range nestedMaps(obj.Object, "spec", "servers") {
+			hosts, _, _ := unstructured.NestedStringSlice(server, "hosts")
+			for _, host := range hosts {
+				// The hosts are NAMESPACE/HOST for the VirtualServices of a namespace.
+				if i := strings.Index(host, "/"); i >= 0 {
+					host = host[i+1:]
+				}
+				hostnames = append(hostnames, host)
+			}
+		}
+	case gk == gatewayAPIGateway:
+		for _, listener := range nestedMaps(obj.Object, "spec", "listeners") {
+			host, _, _ := unstructured.NestedString(listener, "hostname")
+			hostnames = append(hostnames, host)
+		}
+	case gk == ingress:
+		for _, rule := range nestedMaps(obj.Object, "spec", "rules") {
+			host, _, _ := unstructured.NestedString(rule, "host")
+			hostnames = append(hostnames, host)
+		}
+	case gk.Group == gatewayAPIGateway.Group && strings.HasSuffix(gk.Kind, "Route"):
+		hosts, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "hostnames")
+		hostnames = append(hostnames, hosts...)
+	}
+
+	seen := map[string]bool{}
+	var result []string
+	for _, hostname := range hostnames {
+		hostname = normalizeHostname(hostname)
+		if hostname == "" || strings.Contains(hostname, "*") || seen[hostname] {
+			continue
+		}
+		seen[hostname] = true
+		result = append(result, hostname)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// nestedMaps returns the objects of a list field.
+func nestedMaps(obj map[string]interface{}, fields ...string) []map[string]interface{} {
+	items, _, _ := unstructured.NestedSlice(obj, fields...)
+	var maps []map[string]interface{}
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			maps = append(maps, m)
+		}
+	}
+	return maps
+}
+
+func normalizeHostname(hostname string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(hostname), "."))
+}
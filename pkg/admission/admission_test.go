@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func object(apiVersion, kind, namespace, name string, annotations map[string]string, spec map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	u.SetAnnotations(annotations)
+	return u
+}
+
+func TestHostnames(t *testing.T) {
+	for _, tt := range []struct {
+		desc string
+		obj  *unstructured.Unstructured
+		want []string
+	}{
+		{
+			desc: "service-annotation",
+			obj:  object("v1", "Service", "shop", "web", map[string]string{hostnameAnnotationKey: "Shop.example.com., www.example.com"}, nil),
+			want: []string{"shop.example.com", "www.example.com"},
+		},
+		{
+			desc: "serviceentry",
+			obj: object("networking.istio.io/v1beta1", "ServiceEntry", "shop", "db", nil,
+				map[string]interface{}{"hosts": []interface{}{"db.example.com", "*.db.example.com"}}),
+			want: []string{"db.example.com"},
+		},
+		{
+			desc: "istio-gateway",
+			obj: object("networking.istio.io/v1", "Gateway", "shop", "gw", nil,
+				map[string]interface{}{"servers": []interface{}{
+					map[string]interface{}{"hosts": []interface{}{"shop/api.example.com", "*"}},
+					map[string]interface{}{"hosts": []interface{}{"admin.example.com"}},
+				}}),
+			want: []string{"admin.example.com", "api.example.com"},
+		},
+		{
+			desc: "gateway-api-gateway",
+			obj: object("gateway.networking.k8s.io/v1", "Gateway", "shop", "gw", nil,
+				map[string]interface{}{"listeners": []interface{}{
+					map[string]interface{}{"name": "https", "hostname": "gw.example.com"},
+					map[string]interface{}{"name": "any"},
+				}}),
+			want: []string{"gw.example.com"},
+		},
+		{
+			desc: "httproute",
+			obj: object("gateway.networking.k8s.io/v1", "HTTPRoute", "shop", "web", map[string]string{hostnameAnnotationKey: "web.example.com"},
+				map[string]interface{}{"hostnames": []interface{}{"web.example.com", "cart.example.com"}}),
+			want: []string{"cart.example.com", "web.example.com"},
+		},
+		{
+			desc: "ingress",
+			obj: object("networking.k8s.io/v1", "Ingress", "shop", "web", nil,
+				map[string]interface{}{"rules": []interface{}{map[string]interface{}{"host": "ing.example.com"}}}),
+			want: []string{"ing.example.com"},
+		},
+		{
+			desc: "other-kind",
+			obj:  object("v1", "ConfigMap", "shop", "cm", nil, map[string]interface{}{"hosts": []interface{}{"x.example.com"}}),
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			assert.Equal(t, tt.want, Hostnames(tt.obj))
+		})
+	}
+}
+
+func record(dnsName, owner, resource string) *endpoint.Endpoint {
+	return &endpoint.Endpoint{
+		DNSName:    dnsName,
+		RecordType: endpoint.RecordTypeA,
+		Targets:    endpoint.Targets{"1.2.3.4"},
+		Labels:     endpoint.Labels{endpoint.OwnerLabelKey: owner, endpoint.ResourceLabelKey: resource},
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	records := []*endpoint.Endpoint{
+		record("shop.example.com", "prod", "service/shop/web"),
+		record("Billing.example.com", "prod", "serviceentry/billing/api"),
+		// Owned by another cluster.
+		record("other.example.com", "staging", "service/billing/other"),
+		// Published by a source outside of Kubernetes.
+		record("jobs.example.com", "prod", "nomad/billing/jobs"),
+	}
+	checker := &Checker{Records: func() []*endpoint.Endpoint { return records }, OwnerID: "prod"}
+
+	obj := object("v1", "Service", "shop", "hijack", map[string]string{
+		hostnameAnnotationKey: "shop.example.com,billing.example.com,other.example.com,jobs.example.com",
+	}, nil)
+	assert.Equal(t, []Conflict{{Hostname: "billing.example.com", Resource: "serviceentry/billing/api"}}, checker.Conflicts(obj))
+	assert.Equal(t, "billing.example.com is owned by serviceentry/billing/api", checker.Conflicts(obj)[0].String())
+
+	// The same hostnames are allowed in the namespace of their owner.
+	obj.SetNamespace("billing")
+	assert.Equal(t, []Conflict{{Hostname: "shop.example.com", Resource: "service/shop/web"}}, checker.Conflicts(obj))
+
+	assert.Empty(t, checker.Conflicts(object("v1", "Service", "shop", "plain", nil, nil)))
+}
+
+func TestResourceNamespace(t *testing.T) {
+	for resource, want := range map[string]string{
+		"service/shop/web":               "shop",
+		"gateway/istio-system/ingress":   "istio-system",
+		"nomad/default/jobs":             "",
+		"gce/project/us-central1-a/vm-1": "",
+		"consul/web":                     "",
+		"":                               "",
+	} {
+		namespace, ok := resourceNamespace(resource)
+		assert.Equal(t, want, namespace, resource)
+		assert.Equal(t, want != "", ok, resource)
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"sigs.k8s.io/external-dns/pkg/tlsutils"
+)
+
+// Path is the path of the webhook, in the clientConfig of the
+// ValidatingWebhookConfiguration.
+const Path = "/validate"
+
+// maxReviewSize bounds the AdmissionReviews read, the objects are limited to
+// 1.5MB by etcd.
+const maxReviewSize = 3 << 20
+
+var conflicts = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "external_dns",
+		Subsystem: "admission",
+		Name:      "conflicts_total",
+		Help:      "Number of objects claiming hostnames owned by another namespace, by mode.",
+	},
+	[]string{"mode"},
+)
+
+func init() {
+	prometheus.MustRegister(conflicts)
+}
+
+// Webhook serves the AdmissionReviews of a ValidatingWebhookConfiguration.
+type Webhook struct {
+	Checker *Checker
+	// Warn admits the conflicting objects with a warning, instead of
+	// rejecting them.
+	Warn bool
+}
+
+// ServeHTTP answers an AdmissionReview.
+func (w *Webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxReviewSize)).Decode(&review); err != nil || review.Request == nil {
+		http.Error(rw, "invalid AdmissionReview", http.StatusBadRequest)
+		return
+	}
+	review.Response = w.review(review.Request)
+	review.Request = nil
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(&review); err != nil {
+		log.Errorf("Failed to encode the AdmissionReview: %v", err)
+	}
+}
+
+// review checks the object of a request. The objects that can't be decoded
+// are admitted: the webhook only guards the hostnames.
+func (w *Webhook) review(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	resp := &admissionv1.AdmissionResponse{UID: req.UID, Allowed: true}
+	if req.Operation == admissionv1.Delete || len(req.Object.Raw) == 0 {
+		return resp
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(req.Object.Raw); err != nil {
+		log.Warnf("Admitting %s %s/%s, failed to decode it: %v", req.Kind.Kind, req.Namespace, req.Name, err)
+		return resp
+	}
+	// The namespace is not set in the objects created without one.
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(req.Namespace)
+	}
+
+	found := w.Checker.Conflicts(obj)
+	if len(found) == 0 {
+		return resp
+	}
+	messages := make([]string, len(found))
+	for i, c := range found {
+		messages[i] = c.String()
+	}
+	if w.Warn {
+		conflicts.WithLabelValues(ModeWarn).Inc()
+		log.Warnf("Admitting %s %s/%s with hostnames owned by another namespace: %s", req.Kind.Kind, req.Namespace, req.Name, strings.Join(messages, ", "))
+		resp.Warnings = messages
+		return resp
+	}
+	conflicts.WithLabelValues(ModeDeny).Inc()
+	log.Infof("Rejecting %s %s/%s with hostnames owned by another namespace: %s", req.Kind.Kind, req.Namespace, req.Name, strings.Join(messages, ", "))
+	resp.Allowed = false
+	resp.Result = &metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusForbidden,
+		Reason:  metav1.StatusReasonForbidden,
+		Message: "hostnames owned by another namespace: " + strings.Join(messages, ", "),
+	}
+	return resp
+}
+
+// ListenAndServeTLS serves the webhook on Path. The certificate is reloaded
+// when the files change, so rotation doesn't require a restart.
+//
+// It blocks until the context is canceled or the listener fails.
+func (w *Webhook) ListenAndServeTLS(ctx context.Context, addr, certFile, keyFile string) error {
+	certs, err := tlsutils.NewCertReloader(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(Path, w)
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		TLSConfig:         certs.TLSConfig(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		if err := srv.Shutdown(context.Background()); err != nil {
+			log.Debugf("Admission webhook shutdown: %v", err)
+		}
+	}()
+
+	log.Infof("Starting the admission webhook on %s", addr)
+	if err := srv.ListenAndServeTLS("", ""); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func serveReview(t *testing.T, w *Webhook, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	t.Helper()
+	body, err := json.Marshal(&admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request:  req,
+	})
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, Path, bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var review admissionv1.AdmissionReview
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &review))
+	assert.Nil(t, review.Request)
+	require.NotNil(t, review.Response)
+	assert.Equal(t, req.UID, review.Response.UID)
+	return review.Response
+}
+
+func serviceRequest(t *testing.T, namespace string, operation admissionv1.Operation, hostnames string) *admissionv1.AdmissionRequest {
+	raw, err := json.Marshal(object("v1", "Service", "", "web", map[string]string{hostnameAnnotationKey: hostnames}, nil))
+	require.NoError(t, err)
+	return &admissionv1.AdmissionRequest{
+		UID:       "8d1a4f2c",
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Service"},
+		Namespace: namespace,
+		Name:      "web",
+		Operation: operation,
+		Object:    runtime.RawExtension{Raw: raw},
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	records := []*endpoint.Endpoint{record("shop.example.com", "prod", "service/shop/web")}
+	checker := &Checker{Records: func() []*endpoint.Endpoint { return records }, OwnerID: "prod"}
+	deny := &Webhook{Checker: checker}
+	warn := &Webhook{Checker: checker, Warn: true}
+
+	// The namespace of the request is used for the objects without one.
+	resp := serveReview(t, deny, serviceRequest(t, "tenant", admissionv1.Create, "shop.example.com"))
+	assert.False(t, resp.Allowed)
+	require.NotNil(t, resp.Result)
+	assert.Equal(t, int32(http.StatusForbidden), resp.Result.Code)
+	assert.Equal(t, "hostnames owned by another namespace: shop.example.com is owned by service/shop/web", resp.Result.Message)
+
+	resp = serveReview(t, warn, serviceRequest(t, "tenant", admissionv1.Update, "shop.example.com"))
+	assert.True(t, resp.Allowed)
+	assert.Equal(t, []string{"shop.example.com is owned by service/shop/web"}, resp.Warnings)
+
+	for _, req := range []*admissionv1.AdmissionRequest{
+		serviceRequest(t, "shop", admissionv1.Update, "shop.example.com"),
+		serviceRequest(t, "tenant", admissionv1.Create, "tenant.example.com"),
+		{UID: "deleted", Namespace: "tenant", Operation: admissionv1.Delete},
+		{UID: "invalid", Namespace: "tenant", Operation: admissionv1.Create, Object: runtime.RawExtension{Raw: []byte(`{"spec":{}}`)}},
+	} {
+		resp := serveReview(t, deny, req)
+		assert.True(t, resp.Allowed, req.UID)
+		assert.Empty(t, resp.Warnings, req.UID)
+	}
+}
+
+func TestWebhookInvalidRequests(t *testing.T) {
+	w := &Webhook{Checker: &Checker{Records: func() []*endpoint.Endpoint { return nil }}}
+
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	for _, body := range []string{"not json", `{"kind":"AdmissionReview"}`} {
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, Path, bytes.NewBufferString(body)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// secretFlag matches the flags of the passwords, tokens and keys, redacted
+// in the effective configuration.
+var secretFlag = regexp.MustCompile(`(password|secret|token|api-?key|aes-key)$`)
+
+// Effective returns the effective configuration as YAML: the value of each
+// flag, from the command line, the environment or the default, by flag name.
+// The secrets are redacted.
+func (cfg *Config) Effective() ([]byte, error) {
+	values := map[string]interface{}{}
+	for _, f := range cfg.newApp().Model().Flags {
+		if f.Hidden || f.Name == "help" || f.Name == "version" {
+			continue
+		}
+		values[f.Name] = flagValue(f)
+	}
+	return yaml.Marshal(values)
+}
+
+// flagValue returns the value of the flag: a list for the repeatable flags, a
+// bool, or the string of the value.
+func flagValue(f *kingpin.FlagModel) interface{} {
+	s := f.Value.String()
+	switch {
+	case secretFlag.MatchString(f.Name):
+		if s != "" {
+			return passwordMask
+		}
+	case f.IsBoolFlag():
+		return s == "true"
+	case isCumulative(f.Value):
+		// The values of the repeatable flags are joined with commas.
+		if s == "" {
+			return []string{}
+		}
+		return strings.Split(s, ",")
+	}
+	return s
+}
+
+func isCumulative(v kingpin.Value) bool {
+	c, ok := v.(interface{ IsCumulative() bool })
+	return ok && c.IsCumulative()
+}
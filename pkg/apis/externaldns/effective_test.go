@@ -0,0 +1,52 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+func TestEffective(t *testing.T) {
+	t.Setenv("EXTERNAL_DNS_TXT_OWNER_ID", "cluster-1")
+	cfg := NewConfig()
+	require.NoError(t, cfg.ParseFlags([]string{
+		"--source=service", "--source=ingress", "--provider=pdns", "--pdns-api-key=pdns-secret",
+		"--rfc2136-tsig-secret-alg=hmac-sha256", "--dry-run", "--interval=5m",
+	}))
+
+	data, err := cfg.Effective()
+	require.NoError(t, err)
+	assert.False(t, strings.Contains(string(data), "pdns-secret"))
+
+	var values map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(data, &values))
+	assert.Equal(t, []interface{}{"service", "ingress"}, values["source"])
+	assert.Equal(t, "pdns", values["provider"])
+	assert.Equal(t, passwordMask, values["pdns-api-key"])
+	assert.Equal(t, "", values["godaddy-api-key"], "empty secrets are not masked")
+	assert.Equal(t, "hmac-sha256", values["rfc2136-tsig-secret-alg"])
+	assert.Equal(t, "cluster-1", values["txt-owner-id"])
+	assert.Equal(t, true, values["dry-run"])
+	assert.Equal(t, "5m0s", values["interval"])
+	assert.Equal(t, []interface{}{}, values["domain-filter"])
+	assert.NotContains(t, values, "help")
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// FederationConfig is the YAML configuration of the federation provider.
+type FederationConfig struct {
+	Targets []FederationTarget `json:"targets"`
+}
+
+// FederationTarget is a provider of the federation. It is configured with the
+// flags of the command line, the flags in Args replacing theirs - so each
+// target has its own provider, domain filter, registry and policy flags.
+type FederationTarget struct {
+	Name string   `json:"name"`
+	Args []string `json:"args"`
+}
+
+// LoadFederationTargets reads the targets from the YAML file.
+func LoadFederationTargets(path string) ([]FederationTarget, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fc FederationConfig
+	if err := yaml.UnmarshalStrict(data, &fc); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(fc.Targets) == 0 {
+		return nil, fmt.Errorf("%s: no targets", path)
+	}
+	names := map[string]bool{}
+	for _, t := range fc.Targets {
+		if t.Name == "" || strings.Contains(t.Name, "/") {
+			return nil, fmt.Errorf("%s: invalid target name %q", path, t.Name)
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("%s: duplicate target %s", path, t.Name)
+		}
+		names[t.Name] = true
+	}
+	return fc.Targets, nil
+}
+
+// LoadFailoverTarget reads the secondary provider of --failover-config, a YAML
+// file with the name and args of a target. The name defaults to "secondary".
+func LoadFailoverTarget(path string) (FederationTarget, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FederationTarget{}, err
+	}
+	var t FederationTarget
+	if err := yaml.UnmarshalStrict(data, &t); err != nil {
+		return FederationTarget{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(t.Args) == 0 {
+		return FederationTarget{}, fmt.Errorf("%s: no args", path)
+	}
+	if t.Name == "" {
+		t.Name = "secondary"
+	}
+	return t, nil
+}
+
+// TargetConfig returns the configuration of the target, parsing args - the
+// command line - with the flags of the target replacing theirs.
+func TargetConfig(args []string, target FederationTarget) (*Config, error) {
+	boolFlags := map[string]bool{}
+	for _, f := range NewConfig().newApp().Model().Flags {
+		boolFlags[f.Name] = f.IsBoolFlag()
+	}
+	merged, err := mergeArgs(args, target.Args, boolFlags)
+	if err != nil {
+		return nil, fmt.Errorf("target %s: %w", target.Name, err)
+	}
+	cfg := NewConfig()
+	if err := cfg.ParseFlags(merged); err != nil {
+		return nil, fmt.Errorf("target %s: %w", target.Name, err)
+	}
+	if cfg.Provider == "federation" {
+		return nil, fmt.Errorf("target %s: --provider is required", target.Name)
+	}
+	return cfg, nil
+}
+
+// mergeArgs returns args without the flags set in overrides, followed by the
+// overrides. boolFlags tells the flags without a value.
+func mergeArgs(args, overrides []string, boolFlags map[string]bool) ([]string, error) {
+	overridden := map[string]bool{}
+	for i := 0; i < len(overrides); i++ {
+		name, hasValue, ok := flagName(overrides[i], boolFlags)
+		if !ok {
+			return nil, fmt.Errorf("expected a flag, got %q", overrides[i])
+		}
+		overridden[name] = true
+		if !hasValue && !boolFlags[name] {
+			i++
+		}
+	}
+
+	merged := make([]string, 0, len(args)+len(overrides))
+	for i := 0; i < len(args); i++ {
+		name, hasValue, ok := flagName(args[i], boolFlags)
+		skip := ok && overridden[name]
+		if !skip {
+			merged = append(merged, args[i])
+		}
+		if ok && !hasValue && !boolFlags[name] && i+1 < len(args) {
+			i++
+			if !skip {
+				merged = append(merged, args[i])
+			}
+		}
+	}
+	return append(merged, overrides...), nil
+}
+
+// flagName returns the name of the flag of a --name, --name=value or --no-name
+// argument, and whether the value is in the argument.
+func flagName(arg string, boolFlags map[string]bool) (string, bool, bool) {
+	if !strings.HasPrefix(arg, "--") || arg == "--" {
+		return "", false, false
+	}
+	name, _, hasValue := strings.Cut(arg[2:], "=")
+	if negated, ok := strings.CutPrefix(name, "no-"); ok && boolFlags[negated] {
+		name = negated
+	}
+	return name, hasValue, true
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldns
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeArgs(t *testing.T) {
+	boolFlags := map[string]bool{"dry-run": true, "once": true}
+	merged, err := mergeArgs(
+		[]string{"--source=service", "--provider", "federation", "--dry-run", "--domain-filter=example.com", "--txt-owner-id", "default", "--once"},
+		[]string{"--provider=google", "--no-dry-run", "--domain-filter", "internal.example.com"},
+		boolFlags)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--source=service", "--txt-owner-id", "default", "--once",
+		"--provider=google", "--no-dry-run", "--domain-filter", "internal.example.com"}, merged)
+
+	_, err = mergeArgs(nil, []string{"google"}, boolFlags)
+	assert.Error(t, err)
+}
+
+func TestTargetConfig(t *testing.T) {
+	args := []string{"--source=service", "--provider=federation", "--federation-config=federation.yaml", "--txt-owner-id=default"}
+	cfg, err := TargetConfig(args, FederationTarget{Name: "internal", Args: []string{
+		"--provider=google", "--google-zone-visibility=private", "--domain-filter=internal.example.com",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "google", cfg.Provider)
+	assert.Equal(t, []string{"internal.example.com"}, cfg.DomainFilter)
+	assert.Equal(t, "default", cfg.TXTOwnerID)
+	assert.Equal(t, []string{"service"}, cfg.Sources)
+
+	_, err = TargetConfig(args, FederationTarget{Name: "none", Args: []string{"--domain-filter=example.com"}})
+	assert.ErrorContains(t, err, "target none: --provider is required")
+}
+
+func TestLoadFederationTargets(t *testing.T) {
+	dir := t.TempDir()
+	write := func(data string) string {
+		path := filepath.Join(dir, "federation.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+		return path
+	}
+
+	targets, err := LoadFederationTargets(write(`
+targets:
+- name: internal
+  args: [--provider=google, --domain-filter=internal.example.com]
+- name: public
+  args: [--provider=cloudflare, --domain-filter=example.com, --exclude-domains=internal.example.com]
+`))
+	require.NoError(t, err)
+	require.Len(t, targets, 2)
+	assert.Equal(t, "public", targets[1].Name)
+
+	for _, data := range []string{
+		"targets: []",
+		"targets:\n- args: [--provider=google]",
+		"targets:\n- name: a\n- name: a",
+		"targets:\n- name: a\n  flags: []",
+	} {
+		_, err := LoadFederationTargets(write(data))
+		assert.Error(t, err, data)
+	}
+}
+
+func TestLoadFailoverTarget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "failover.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("args: [--provider=cloudflare, --domain-filter=example.com]\n"), 0o644))
+	target, err := LoadFailoverTarget(path)
+	require.NoError(t, err)
+	assert.Equal(t, "secondary", target.Name)
+	assert.Equal(t, []string{"--provider=cloudflare", "--domain-filter=example.com"}, target.Args)
+
+	require.NoError(t, os.WriteFile(path, []byte("name: dr\n"), 0o644))
+	_, err = LoadFailoverTarget(path)
+	assert.Error(t, err)
+}
@@ -96,6 +96,43 @@ type Config struct {
 	TargetNetFilter   []string
 	ExcludeTargetNets []string
 
+	// TransformRules is a YAML file with rules rewriting the endpoints of the
+	// sources, applied before the WasmTransforms.
+	TransformRules string
+	// WasmTransforms are the WebAssembly modules rewriting the endpoints of the
+	// sources, applied in order.
+	WasmTransforms []string
+	// TTLPolicy is a YAML file with the TTL rules of the domains, enforced
+	// after the transformations. DefaultTTL is its default TTL when it sets
+	// none.
+	TTLPolicy  string
+	DefaultTTL int64
+	// CNAMEFlattening are the domains whose CNAMEs are replaced by the
+	// addresses of their targets, resolved by CNAMEFlatteningResolver.
+	// ApexAlias flattens the CNAMEs at the apex of the provider zones.
+	CNAMEFlattening         []string
+	CNAMEFlatteningResolver string
+	ApexAlias               bool
+	// LBHealthProject is the project of the load balancers whose unhealthy
+	// backends are withdrawn from the records, checked every
+	// LBHealthInterval with the thresholds of the hysteresis.
+	LBHealthProject            string
+	LBHealthInterval           time.Duration
+	LBHealthUnhealthyThreshold int
+	LBHealthHealthyThreshold   int
+	// SourceTimeout bounds the time to collect the endpoints of each source,
+	// 0 for no timeout.
+	SourceTimeout time.Duration
+	// SourceFailurePolicy is what a sync does when a source fails: abort, or
+	// skip it, reusing its endpoints of the last sync.
+	SourceFailurePolicy string
+	// SourceMergePolicy merges the endpoints of the same name of several
+	// sources with different targets: none, union, priority or error.
+	SourceMergePolicy string
+	// SourceDomainFilters limit the names of the endpoints of a source, as
+	// SOURCE=DOMAIN: the other endpoints of the source are rejected.
+	SourceDomainFilters []string
+
 	// Configurations for egress TLS connections.
 	TLSCA            string
 	TLSClientCert    string
@@ -114,21 +151,180 @@ type Config struct {
 
 	Interval             time.Duration
 	MinEventSyncInterval time.Duration
+	// EventDebounce postpones the syncs of the events until no event was
+	// received for this duration, up to EventMaxDelay after the first one.
+	EventDebounce time.Duration
+	EventMaxDelay time.Duration
+	DriftInterval        time.Duration
+	// FullSyncInterval enables the incremental syncs with the providers
+	// exposing a change journal, listing every record at this interval.
+	FullSyncInterval time.Duration
+	// PlanShards splits the names in shards planned one after the other.
+	PlanShards int
+	// Replica is the name of this replica among Replicas, which plan the
+	// names they own by rendezvous hashing.
+	Replica  string
+	Replicas []string
+	// RecordLease is the lease of the records, renewed by the syncs: the
+	// records of other owners whose lease expired are deleted.
+	RecordLease time.Duration
+	// RecordCacheFile keeps the records of the last sync across restarts,
+	// used to plan the first sync if younger than RecordCacheMaxAge.
+	RecordCacheFile   string
+	RecordCacheMaxAge time.Duration
+	// DomainLockNamespace enables a Lease per domain in this namespace, held
+	// by DomainLockIdentity while applying the changes of the domain.
+	DomainLockNamespace string
+	DomainLockIdentity  string
+	DomainLockDuration  time.Duration
+	// ConflictPolicy is the policy of the changes to the records of another
+	// owner, or of no owner: skip, warn, adopt or adopt-unowned. ConflictEvents reports them
+	// as events of the resources of the endpoints.
+	ConflictPolicy string
+	ConflictEvents bool
+	// ChangeEvents reports the created, updated, deleted and rejected records
+	// as events of the resources of the endpoints.
+	ChangeEvents bool
+	// StrictShadowing fails the syncs of desired records shadowing the records
+	// of other owners or delegated subzones.
+	StrictShadowing bool
+	// Schedule is a cron schedule of the syncs, replacing Interval.
+	Schedule string
+	// MaxChangesPerMinute and MaxZoneChangesPerMinute limit the applied record
+	// changes, globally and per zone - zero is unlimited.
+	MaxChangesPerMinute     int
+	MaxZoneChangesPerMinute int
+	// QuarantineFactor pauses the applies on the plans deleting or updating
+	// more than this factor times the recent plans - zero is disabled - and at
+	// least QuarantineMinChanges records, until cleared or QuarantineTimeout.
+	QuarantineFactor     float64
+	QuarantineMinChanges int
+	QuarantineHistory    int
+	QuarantineTimeout    time.Duration
 
 	// Operating mode settings
 
 	// Run once and exit
 	Once bool
 
+	// Validate creates the sources, the provider and the registry, prints the
+	// effective configuration and exits, without syncing.
+	Validate bool
+
+	// FinalSync runs a last sync on SIGTERM, after the sync in progress;
+	// both are canceled after ShutdownTimeout.
+	FinalSync       bool
+	ShutdownTimeout time.Duration
+
 	// Provider will not write
 	DryRun bool
 
 	// Start a webhook server on default address.
 	WebhookServer bool
+	// WebhookServerDoH serves DNS-over-HTTPS queries with the webhook server.
+	WebhookServerDoH bool
+	// WebhookServerCertManager solves the cert-manager DNS01 challenges with
+	// the webhook server.
+	WebhookServerCertManager bool
+	// WebhookServerCutover serves the weighted cutovers with the webhook server.
+	WebhookServerCutover bool
+
+	// VerifyResolvers are the DNS servers used to check that the applied changes
+	// are served, in NAME=HOST[:PORT] or HOST[:PORT] format.
+	VerifyResolvers []string
+	// VerifyWait blocks ApplyChanges until the changes are served, or VerifyTimeout.
+	VerifyWait    bool
+	VerifyTimeout time.Duration
+	// CanaryDomain is the test domain where the changes are applied, and
+	// verified, before the production zones.
+	CanaryDomain string
+
+	// AuditLocation is the directory or bucket (gs://, s3://) of the audit trail
+	// of the applied changes, kept for AuditRetention.
+	AuditLocation  string
+	AuditRetention time.Duration
+	AuditActor     string
+	// RollbackHistory is the number of the last change sets of the audit trail
+	// which can be rolled back with the AdminAPI.
+	RollbackHistory int
+
+	// ApprovalNamespace enables the approval mode: the changes are written as
+	// DNSChangeRequest objects in the namespace, and applied once approved.
+	ApprovalNamespace string
+
+	// PolicyWebhookURL enables the review of the changes by an external policy
+	// webhook, allowing, denying or modifying them before they are applied.
+	PolicyWebhookURL     string
+	PolicyWebhookTimeout time.Duration
+
+	// RegoPolicies are the files or directories of the Rego policies reviewing
+	// the changes in the controller, see planpolicy.RegoPackage.
+	RegoPolicies []string
+
+	// AdmissionWebhookAddress enables the validating admission webhook
+	// rejecting the objects claiming hostnames owned by another namespace, or
+	// admitting them with a warning with AdmissionWebhookMode warn.
+	AdmissionWebhookAddress  string
+	AdmissionWebhookCertFile string
+	AdmissionWebhookKeyFile  string
+	AdmissionWebhookMode     string
+
+	// DNSServerAddress serves the records of the provider with DNS on UDP
+	// and TCP.
+	DNSServerAddress string
+	// DNSServerTLSAddress serves the records of the provider with DNS-over-TLS,
+	// with the certificate of DNSServerTLSCertFile and DNSServerTLSKeyFile.
+	DNSServerTLSAddress  string
+	DNSServerTLSCertFile string
+	DNSServerTLSKeyFile  string
+	// DNSServerTransferSecondaries are the IPs or CIDRs allowed to transfer
+	// the zones with AXFR and IXFR, also with the TSIG key if set.
+	DNSServerTransferSecondaries []string
+	DNSServerTSIGKeyName         string
+	DNSServerTSIGSecret          string `secure:"yes"`
+	// DNSServerMDNSDomains are the domains whose names are advertised under
+	// .local with mDNS, on DNSServerMDNSInterface or the default interface.
+	DNSServerMDNSDomains   []string
+	DNSServerMDNSInterface string
+
+	// EmitDir enables the GitOps mode: the desired records and the changes are
+	// written to the directory instead of being applied.
+	EmitDir       string
+	EmitGitCommit bool
+
+	// FederationConfig is the YAML file with the targets of the federation
+	// provider, each a provider with its own flags.
+	FederationConfig string
+
+	// FailoverConfig is the YAML file with the flags of the secondary provider,
+	// used after the primary failed FailoverThreshold consecutive calls over at
+	// least FailoverAfter.
+	FailoverConfig    string
+	FailoverThreshold int
+	FailoverAfter     time.Duration
+
+	// The chaos settings inject faults into the provider calls, to check that
+	// the controller converges. They require ChaosSandbox, a confirmation that
+	// the provider is a disposable sandbox.
+	ChaosSandbox          bool
+	ChaosRecordsErrorRate float64
+	ChaosErrorRate        float64
+	ChaosPartialRate      float64
+	ChaosMaxDelay         time.Duration
 
 	MetricsAddress string
-	LogFormat      string
-	LogLevel       string
+	// DebugEndpoints serves /debug/pprof and /debug/state on MetricsAddress.
+	DebugEndpoints bool
+	// AdminAPI serves the operations on the controller on /admin/ on AdminAPIAddress.
+	AdminAPI bool
+	// AdminAPIAddress is the address of the admin API, separate from the metrics.
+	AdminAPIAddress string
+	// AdminAPITokenFile is a file with the bearer token required by the admin API.
+	AdminAPITokenFile string
+	LogFormat         string
+	LogLevel          string
+	// LogLevels are the levels of components or zones, like "provider/google=debug,zone/example.com=debug".
+	LogLevels string
 
 	// Provider specific options
 	ProviderConfig
@@ -136,9 +332,9 @@ type Config struct {
 
 type ProviderConfig struct {
 	AkamaiServiceConsumerDomain       string
-	AkamaiClientToken                 string
-	AkamaiClientSecret                string
-	AkamaiAccessToken                 string
+	AkamaiClientToken                 string `secure:"yes"`
+	AkamaiClientSecret                string `secure:"yes"`
+	AkamaiAccessToken                 string `secure:"yes"`
 	AkamaiEdgercPath                  string
 	AkamaiEdgercSection               string
 
@@ -204,7 +400,17 @@ type ProviderConfig struct {
 	GoogleProject                     string
 	GoogleBatchChangeSize             int
 	GoogleBatchChangeInterval         time.Duration
+	// GoogleZoneBatchChanges override the batch size and interval by zone
+	// name, as ZONE=SIZE/INTERVAL, ZONE=SIZE or ZONE=/INTERVAL.
+	GoogleZoneBatchChanges            []string
 	GoogleZoneVisibility              string
+	GoogleSplitView                   bool
+	// GoogleReadOnly lists the zones and records but fails the changes, for
+	// the observer deployments without write credentials.
+	GoogleReadOnly                    bool
+	// GoogleForwardingZonePolicy is the policy of the forwarding and peering
+	// zones matching the filters: skip, error or manage.
+	GoogleForwardingZonePolicy        string
 
 	IBMCloudProxied                   bool
 	IBMCloudConfigFile                string
@@ -229,6 +435,11 @@ type ProviderConfig struct {
 	PiholePassword                    string `secure:"yes"`
 	PiholeTLSInsecureSkipVerify       bool
 
+	ConsulNode                        string
+	ConsulDomain                      string
+
+	HostsFilePath                     string
+
 	PluralCluster                     string
 	PluralProvider                    string
 
@@ -299,17 +510,36 @@ var defaultConfig = &Config{
 	TXTCacheInterval:       0,
 	TXTWildcardReplacement: "",
 	MinEventSyncInterval:   5 * time.Second,
+	EventMaxDelay:          time.Minute,
 	TXTEncryptEnabled:      false,
 	TXTEncryptAESKey:       "",
 	Interval:               time.Minute,
 	Once:                   false,
+	ShutdownTimeout:        30 * time.Second,
 	DryRun:                 false,
 	LogFormat:              "text",
 	MetricsAddress:         ":7979",
+	AdminAPIAddress:        "127.0.0.1:7980",
 	LogLevel:               logrus.InfoLevel.String(),
 	ManagedDNSRecordTypes:  []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME},
 	ExcludeDNSRecordTypes:  []string{},
 	WebhookServer:          false,
+	VerifyTimeout:          5 * time.Minute,
+	AuditRetention:         90 * 24 * time.Hour,
+	PolicyWebhookTimeout:   10 * time.Second,
+	RollbackHistory:        10,
+	FailoverThreshold:      3,
+	FailoverAfter:          5 * time.Minute,
+	SourceFailurePolicy:    "abort",
+	SourceMergePolicy:      "none",
+	RecordCacheMaxAge:      time.Hour,
+	DomainLockDuration:     time.Minute,
+	ConflictPolicy:         "skip",
+	QuarantineMinChanges:   10,
+	QuarantineHistory:      10,
+	LBHealthInterval:       30 * time.Second,
+	LBHealthUnhealthyThreshold: 3,
+	LBHealthHealthyThreshold:   2,
 
 	Config: source.Config{
 		APIServerURL:             "",
@@ -335,6 +565,7 @@ var defaultConfig = &Config{
 		UpdateEvents:             false,
 		CRDSourceAPIVersion:      "externaldns.k8s.io/v1alpha1",
 		CRDSourceKind:            "DNSEndpoint",
+		ConsulAddress:            "http://127.0.0.1:8500",
 		ServiceTypeFilter:        []string{},
 		CFAPIEndpoint:            "",
 		CFUsername:               "",
@@ -349,6 +580,7 @@ var defaultConfig = &Config{
 		GoogleBatchChangeSize:     1000,
 		GoogleBatchChangeInterval: time.Second,
 		GoogleZoneVisibility:      "",
+		GoogleForwardingZonePolicy: "manage",
 
 		AlibabaCloudConfigFile:      "/etc/kubernetes/alibaba-cloud.json",
 		AWSZoneType:                 "",
@@ -427,6 +659,7 @@ var defaultConfig = &Config{
 		PiholeServer:                "",
 		PiholePassword:              "",
 		PiholeTLSInsecureSkipVerify: false,
+		ConsulNode:                  "external-dns",
 		PluralCluster:               "",
 		PluralProvider:              "",
 		WebhookProviderURL:          "http://localhost:8888",
@@ -443,22 +676,30 @@ func NewConfig() *Config {
 func (cfg *Config) String() string {
 	// prevent logging of sensitive information
 	temp := *cfg
+	redact(reflect.ValueOf(&temp).Elem())
 
-	t := reflect.TypeOf(temp)
+	return fmt.Sprintf("%+v", temp)
+}
+
+// redact masks the non-empty string fields tagged secure:"yes" of the struct,
+// and of its embedded structs like ProviderConfig.
+func redact(v reflect.Value) {
+	t := v.Type()
 	for i := 0; i < t.NumField(); i++ {
 		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			redact(v.Field(i))
+			continue
+		}
 		if val, ok := f.Tag.Lookup("secure"); ok && val == "yes" {
 			if f.Type.Kind() != reflect.String {
 				continue
 			}
-			v := reflect.ValueOf(&temp).Elem().Field(i)
-			if v.String() != "" {
-				v.SetString(passwordMask)
+			if fv := v.Field(i); fv.String() != "" {
+				fv.SetString(passwordMask)
 			}
 		}
 	}
-
-	return fmt.Sprintf("%+v", temp)
 }
 
 // allLogLevelsAsStrings returns all logrus levels as a list of strings
@@ -474,6 +715,12 @@ func allLogLevelsAsStrings() []string {
 
 // ParseFlags adds and parses flags from command line
 func (cfg *Config) ParseFlags(args []string) error {
+	_, err := cfg.newApp().Parse(args)
+	return err
+}
+
+// newApp returns the application with the flags bound to cfg.
+func (cfg *Config) newApp() *kingpin.Application {
 	app := kingpin.New("external-dns", "ExternalDNS synchronizes exposed Kubernetes Services and Ingresses with DNS providers.\n\nNote that all flags may be replaced with env vars - `--flag` -> `EXTERNAL_DNS_FLAG=1` or `--flag value` -> `EXTERNAL_DNS_FLAG=value`")
 	app.Version(Version)
 	// Enable the use of env variables for all flags.
@@ -497,7 +744,7 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("skipper-routegroup-groupversion", "The resource version for skipper routegroup").Default(source.DefaultRoutegroupVersion).StringVar(&cfg.SkipperRouteGroupVersion)
 
 	// Flags related to processing source
-	app.Flag("source", "The resource types that are queried for endpoints; specify multiple times for multiple sources (required, options: service, ingress, node, pod, fake, connector, gateway-httproute, gateway-grpcroute, gateway-tlsroute, gateway-tcproute, gateway-udproute, istio-gateway, istio-virtualservice, cloudfoundry, contour-httpproxy, gloo-proxy, crd, empty, skipper-routegroup, openshift-route, ambassador-host, kong-tcpingress, f5-virtualserver, traefik-proxy)").PlaceHolder("source").EnumsVar(&cfg.Sources, "service", "ingress", "node", "pod", "gateway-httproute", "gateway-grpcroute", "gateway-tlsroute", "gateway-tcproute", "gateway-udproute", "istio-gateway", "istio-virtualservice", "cloudfoundry", "contour-httpproxy", "gloo-proxy", "fake", "connector", "crd", "empty", "skipper-routegroup", "openshift-route", "ambassador-host", "kong-tcpingress", "f5-virtualserver", "traefik-proxy")
+	app.Flag("source", "The resource types that are queried for endpoints; specify multiple times for multiple sources (required, options: service, ingress, node, pod, fake, connector, axfr, gateway-httproute, gateway-grpcroute, gateway-tlsroute, gateway-tcproute, gateway-udproute, istio-gateway, istio-virtualservice, istio-se, cloudfoundry, contour-httpproxy, gloo-proxy, crd, empty, skipper-routegroup, openshift-route, ambassador-host, kong-tcpingress, f5-virtualserver, traefik-proxy, plugin, file, docker, consul, nomad, istiod, knative, gce, gce-forwarding-rule, mcs)").PlaceHolder("source").EnumsVar(&cfg.Sources, "service", "ingress", "node", "pod", "gateway-httproute", "gateway-grpcroute", "gateway-tlsroute", "gateway-tcproute", "gateway-udproute", "istio-gateway", "istio-virtualservice", "istio-se", "cloudfoundry", "contour-httpproxy", "gloo-proxy", "fake", "connector", "axfr", "crd", "empty", "skipper-routegroup", "openshift-route", "ambassador-host", "kong-tcpingress", "f5-virtualserver", "traefik-proxy", "plugin", "file", "docker", "consul", "nomad", "istiod", "knative", "gce", "gce-forwarding-rule", "mcs")
 	app.Flag("openshift-router-name", "if source is openshift-route then you can pass the ingress controller name. Based on this name external-dns will select the respective router from the route status and map that routerCanonicalHostname to the route host while creating a CNAME record.").StringVar(&cfg.OCPRouterName)
 	app.Flag("namespace", "Limit resources queried for endpoints to a specific namespace (default: all namespaces)").Default(defaultConfig.Namespace).StringVar(&cfg.Namespace)
 	app.Flag("annotation-filter", "Filter resources queried for endpoints by annotation, using label selector semantics").Default(defaultConfig.AnnotationFilter).StringVar(&cfg.AnnotationFilter)
@@ -515,6 +762,43 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("publish-host-ip", "Allow external-dns to publish host-ip for headless services (optional)").BoolVar(&cfg.PublishHostIP)
 	app.Flag("always-publish-not-ready-addresses", "Always publish also not ready addresses for headless services (optional)").BoolVar(&cfg.AlwaysPublishNotReadyAddresses)
 	app.Flag("connector-source-server", "The server to connect for connector source, valid only when using connector source").Default(defaultConfig.ConnectorServer).StringVar(&cfg.ConnectorServer)
+	app.Flag("axfr-source-server", "The authoritative server (host:port) to transfer zones from, valid only when using axfr source").StringVar(&cfg.AXFRServer)
+	app.Flag("axfr-source-zone", "The zones to transfer for the axfr source; specify multiple times for multiple zones").StringsVar(&cfg.AXFRZones)
+	app.Flag("axfr-source-tsig-key", "The TSIG key name used to authenticate zone transfers (optional, hmac-sha256)").StringVar(&cfg.AXFRTSIGKey)
+	app.Flag("axfr-source-tsig-secret", "The base64 TSIG secret used to authenticate zone transfers (optional)").StringVar(&cfg.AXFRTSIGSecret)
+	app.Flag("axfr-source-incremental", "Use IXFR to update the zones after the first transfer (default: false)").BoolVar(&cfg.AXFRIncremental)
+	app.Flag("file-source", "A records file for the file source, as a path or an http:// or https:// URL - a YAML or JSON list of endpoints, like the output of 'ednsctl records -o yaml'; specify multiple times for multiple files").StringsVar(&cfg.FileSources)
+	app.Flag("file-source-token-file", "A file with a bearer token for the http:// and https:// URLs of the file source, read for each request (optional)").StringVar(&cfg.FileSourceTokenFile)
+	app.Flag("file-source-ca-file", "A file with the CA certificates of the https:// URLs of the file source (default: the system roots)").StringVar(&cfg.FileSourceCAFile)
+	app.Flag("plugin", "An external source for the plugin source, as NAME=COMMAND or NAME=URL of a localhost HTTP server - see docs/sources/plugin.md; specify multiple times for multiple plugins").StringsVar(&cfg.PluginSources)
+	app.Flag("docker-host", "The Docker or Podman API for the docker source, as unix:///path or tcp://host:port (default: DOCKER_HOST or unix:///var/run/docker.sock)").StringVar(&cfg.DockerHost)
+	app.Flag("docker-target", "A target of the containers without a dns.target label, like the address of the host, for the docker source (default: the container IPs); specify multiple times for multiple targets").StringsVar(&cfg.DockerTargets)
+	app.Flag("consul-source-tag", "A tag the services must have for the consul source; specify multiple times for multiple tags, all required").StringsVar(&cfg.ConsulSourceTags)
+	app.Flag("consul-source-domain", "Publish the services without an external-dns-hostname meta as SERVICE.DOMAIN for the consul source (optional)").StringVar(&cfg.ConsulSourceDomain)
+	app.Flag("nomad-address", "The Nomad API for the nomad source (default: NOMAD_ADDR or http://127.0.0.1:4646)").StringVar(&cfg.NomadAddress)
+	app.Flag("nomad-token", "The ACL token for the nomad source, with the read-job capability in the namespaces (default: NOMAD_TOKEN)").StringVar(&cfg.NomadToken)
+	app.Flag("nomad-region", "The region listed by the nomad source (default: the region of the agent)").StringVar(&cfg.NomadRegion)
+	app.Flag("nomad-namespace", "A namespace listed by the nomad source, '*' for all (default: NOMAD_NAMESPACE or default); specify multiple times for multiple namespaces").StringsVar(&cfg.NomadNamespaces)
+	app.Flag("nomad-tag", "A tag the services must have for the nomad source; specify multiple times for multiple tags, all required").StringsVar(&cfg.NomadTags)
+	app.Flag("nomad-domain", "Publish the services without a dns.name= tag as SERVICE.DOMAIN for the nomad source (optional)").StringVar(&cfg.NomadDomain)
+	app.Flag("istiod-address", "The debug API of istiod for the istiod source (default: http://istiod.istio-system:15014)").StringVar(&cfg.IstiodAddress)
+	app.Flag("istiod-token-file", "A file with a bearer token for the debug API of istiod, read for each request (optional)").StringVar(&cfg.IstiodTokenFile)
+	app.Flag("istiod-domain", "Publish the Kubernetes services of the mesh as NAME.NAMESPACE.DOMAIN for the istiod source - only the ServiceEntry hosts are published if not set (optional)").StringVar(&cfg.IstiodDomain)
+	app.Flag("gce-project", "The project of the Compute Engine instances for the gce source and of the forwarding rules for the gce-forwarding-rule source (default: the project of the metadata server)").StringVar(&cfg.GCEProject)
+	app.Flag("gce-suffix", "The suffix of the hostnames of the gce source: an instance is published as NAME.ZONE.SUFFIX (required with the gce source)").StringVar(&cfg.GCESuffix)
+	app.Flag("gce-address", "The IPs of the instances published by the gce source, internal or external (default: internal)").EnumVar(&cfg.GCEAddress, "internal", "external")
+	app.Flag("gce-label-filter", "Filter the instances of the gce source by labels, like env=prod,role!=batch (default: all)").StringVar(&cfg.GCELabelFilter)
+	app.Flag("forwarding-rule-suffix", "Publish the forwarding rules without a dns.name= description as NAME.SUFFIX for the gce-forwarding-rule source (optional)").StringVar(&cfg.ForwardingRuleSuffix)
+	app.Flag("forwarding-rule-label-filter", "Filter the forwarding rules of the gce-forwarding-rule source by labels, like team=shop,dns!=off (default: all)").StringVar(&cfg.ForwardingRuleLabelFilter)
+	app.Flag("forwarding-rule-description-filter", "Filter the forwarding rules of the gce-forwarding-rule source by a regular expression on their description (default: all)").StringVar(&cfg.ForwardingRuleDescription)
+	app.Flag("mcs-api-group", "The API group of the ServiceExports and ServiceImports of the mcs source, multicluster.x-k8s.io or net.gke.io for GKE fleets (default: multicluster.x-k8s.io)").EnumVar(&cfg.MCSAPIGroup, "multicluster.x-k8s.io", "net.gke.io")
+	app.Flag("mcs-domain", "Publish the ServiceImports as NAME.NAMESPACE.DOMAIN for the mcs source - only the hostname annotations are published if not set (optional)").StringVar(&cfg.MCSDomain)
+	app.Flag("istio-se-reverse-namespace", "Generate MESH_EXTERNAL ServiceEntries in this namespace from the provider records, valid only when using istio-se source (optional)").StringVar(&cfg.IstioSEReverseNamespace)
+	app.Flag("istio-se-reverse-domain-filter", "Limit the records used to generate ServiceEntries to this domain; specify multiple times for multiple domains (optional)").StringsVar(&cfg.IstioSEReverseDomainFilter)
+	app.Flag("istio-se-reverse-label-filter", "Limit the records used to generate ServiceEntries to the records with labels (such as owner) matching this selector (optional)").StringVar(&cfg.IstioSEReverseLabelFilter)
+	app.Flag("geo-routing", "Route the records of the k8s and istio-se sources by region with the geo routing policy of the google provider: the region of the ServiceEntry endpoint localities, --geo-region or the topology.kubernetes.io/region label of the nodes is the geo location and set identifier of the records (default: disabled)").BoolVar(&cfg.GeoRouting)
+	app.Flag("geo-region", "The region of the cluster for --geo-routing (default: the region of the nodes)").StringVar(&cfg.GeoRegion)
+	app.Flag("k8s-node-hints", "Add a TXT record per pod to the k8s source, _node.NAME.NAMESPACE.p.SUFFIX, with the node and the creation time of the pod, to find where a pod lives without access to the API server; requires TXT in --managed-record-types (default: disabled)").BoolVar(&cfg.K8SNodeHints)
 	app.Flag("crd-source-apiversion", "API version of the CRD for crd source, e.g. `externaldns.k8s.io/v1alpha1`, valid only when using crd source").Default(defaultConfig.CRDSourceAPIVersion).StringVar(&cfg.CRDSourceAPIVersion)
 	app.Flag("crd-source-kind", "Kind of the CRD for the crd source in API group and version specified by crd-source-apiversion").Default(defaultConfig.CRDSourceKind).StringVar(&cfg.CRDSourceKind)
 	app.Flag("service-type-filter", "The service types to take care about (default: all, expected: ClusterIP, NodePort, LoadBalancer or ExternalName)").StringsVar(&cfg.ServiceTypeFilter)
@@ -523,12 +807,31 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("default-targets", "Set globally default host/IP that will apply as a target instead of source addresses. Specify multiple times for multiple targets (optional)").StringsVar(&cfg.DefaultTargets)
 	app.Flag("target-net-filter", "Limit possible targets by a net filter; specify multiple times for multiple possible nets (optional)").StringsVar(&cfg.TargetNetFilter)
 	app.Flag("exclude-target-net", "Exclude target nets (optional)").StringsVar(&cfg.ExcludeTargetNets)
+	app.Flag("transform-rules", "A YAML file with rules adding suffixes, rewriting targets, setting TTLs or adding labels to the endpoints of the sources before planning - see docs/transform.md (optional)").StringVar(&cfg.TransformRules)
+	app.Flag("wasm-transform", "A WebAssembly module rewriting or filtering the endpoints of the sources before planning - see docs/wasm/wasm.md; specify multiple times to apply several modules in order (optional)").StringsVar(&cfg.WasmTransforms)
+	app.Flag("ttl-policy", "A YAML file with the default, minimum and maximum TTLs of the records of the domains, enforced on the endpoints after the transformation rules - see docs/ttl.md (optional)").StringVar(&cfg.TTLPolicy)
+	app.Flag("default-ttl", "The TTL in seconds of the records without a TTL, unless --ttl-policy sets a default (default: 0, the default of the provider)").Int64Var(&cfg.DefaultTTL)
+	app.Flag("cname-flattening", "Replace the CNAME of this domain by the A and AAAA records of its target, resolved at sync time; *.DOMAIN for the CNAMEs of all its names; specify multiple times for multiple domains (optional)").StringsVar(&cfg.CNAMEFlattening)
+	app.Flag("cname-flattening-resolver", "The DNS server resolving the targets of --cname-flattening, in HOST[:PORT] format (default: the first nameserver of /etc/resolv.conf)").StringVar(&cfg.CNAMEFlatteningResolver)
+	app.Flag("apex-alias", "Replace the CNAMEs at the apex of the zones of the provider by the A and AAAA records of their targets, kept up to date like --cname-flattening and checked by the --verify-resolver servers (default: disabled)").BoolVar(&cfg.ApexAlias)
+	app.Flag("lb-health-project", "Withdraw the targets of the multi-value A and AAAA records whose load balancer backends are unhealthy, from the health of the backend services of this GCP project (optional)").StringVar(&cfg.LBHealthProject)
+	app.Flag("lb-health-interval", "The interval between the reads of the backend health of --lb-health-project").Default(defaultConfig.LBHealthInterval.String()).DurationVar(&cfg.LBHealthInterval)
+	app.Flag("lb-health-unhealthy-threshold", "The number of unhealthy checks in a row withdrawing a target").Default(strconv.Itoa(defaultConfig.LBHealthUnhealthyThreshold)).IntVar(&cfg.LBHealthUnhealthyThreshold)
+	app.Flag("lb-health-healthy-threshold", "The number of healthy checks in a row restoring a withdrawn target").Default(strconv.Itoa(defaultConfig.LBHealthHealthyThreshold)).IntVar(&cfg.LBHealthHealthyThreshold)
+	app.Flag("source-timeout", "Timeout to collect the endpoints of each source, collected concurrently; 0s means no timeout (default: 0s)").DurationVar(&cfg.SourceTimeout)
+	app.Flag("source-domain-filter", "Limit the endpoints of a source to a domain, as SOURCE=DOMAIN, like istio-se=mesh.example.com: its endpoints of other names are rejected before planning; specify multiple times for multiple sources or domains (optional)").PlaceHolder("SOURCE=DOMAIN").StringsVar(&cfg.SourceDomainFilters)
+	app.Flag("source-failure-policy", "What a sync does when a source fails: abort, or skip it and reuse its endpoints of the last successful collection (default: abort, options: abort, skip)").Default(defaultConfig.SourceFailurePolicy).EnumVar(&cfg.SourceFailurePolicy, "abort", "skip")
+	app.Flag("source-merge-policy", "How the endpoints of the same name, record type and set identifier of several sources, with different targets, are merged: keep them all for the plan to pick one, union of their targets (except for CNAME records), priority to the first source in the order of --source, or error failing the sync (default: none, options: none, union, priority, error)").Default(defaultConfig.SourceMergePolicy).EnumVar(&cfg.SourceMergePolicy, "none", "union", "priority", "error")
 	app.Flag("traefik-disable-legacy", "Disable listeners on Resources under the traefik.containo.us API Group").Default(strconv.FormatBool(defaultConfig.TraefikDisableLegacy)).BoolVar(&cfg.TraefikDisableLegacy)
 	app.Flag("traefik-disable-new", "Disable listeners on Resources under the traefik.io API Group").Default(strconv.FormatBool(defaultConfig.TraefikDisableNew)).BoolVar(&cfg.TraefikDisableNew)
 
 	// Flags related to providers
-	providers := []string{"akamai", "alibabacloud", "aws", "aws-sd", "azure", "azure-dns", "azure-private-dns", "bluecat", "civo", "cloudflare", "coredns", "designate", "digitalocean", "dnsimple", "dyn", "exoscale", "gandi", "godaddy", "google", "ibmcloud", "inmemory", "linode", "ns1", "oci", "ovh", "pdns", "pihole", "plural", "rcodezero", "rdns", "rfc2136", "safedns", "scaleway", "skydns", "tencentcloud", "transip", "ultradns", "vinyldns", "vultr", "webhook"}
+	providers := []string{"akamai", "alibabacloud", "aws", "aws-sd", "azure", "azure-dns", "azure-private-dns", "bluecat", "civo", "cloudflare", "consul", "coredns", "designate", "digitalocean", "dnsimple", "dyn", "exoscale", "federation", "gandi", "godaddy", "google", "hostsfile", "ibmcloud", "inmemory", "linode", "ns1", "oci", "ovh", "pdns", "pihole", "plural", "rcodezero", "rdns", "rfc2136", "safedns", "scaleway", "skydns", "tencentcloud", "transip", "ultradns", "vinyldns", "vultr", "webhook"}
 	app.Flag("provider", "The DNS provider where the DNS records will be created (required, options: "+strings.Join(providers, ", ")+")").Required().PlaceHolder("provider").EnumVar(&cfg.Provider, providers...)
+	app.Flag("federation-config", "With --provider=federation, the YAML file with the targets, each a provider with its own domain filter and registry flags - see docs/federation.md").StringVar(&cfg.FederationConfig)
+	app.Flag("failover-config", "The YAML file with the flags of a standby secondary provider, replacing the flags of the command line; the changes go to the secondary once the primary provider is persistently unavailable - see docs/failover.md (optional)").StringVar(&cfg.FailoverConfig)
+	app.Flag("failover-threshold", "With --failover-config, the number of consecutive failed calls of the primary provider before failing over (default: 3)").Default(strconv.Itoa(defaultConfig.FailoverThreshold)).IntVar(&cfg.FailoverThreshold)
+	app.Flag("failover-after", "With --failover-config, the minimum duration of the failures of the primary provider before failing over (default: 5m)").Default(defaultConfig.FailoverAfter.String()).DurationVar(&cfg.FailoverAfter)
 	app.Flag("domain-filter", "Limit possible target zones by a domain suffix; specify multiple times for multiple domains (optional)").Default("").StringsVar(&cfg.DomainFilter)
 	app.Flag("exclude-domains", "Exclude subdomains (optional)").Default("").StringsVar(&cfg.ExcludeDomains)
 	app.Flag("regex-domain-filter", "Limit possible domains and target zones by a Regex filter; Overrides domain-filter (optional)").Default(defaultConfig.RegexDomainFilter.String()).RegexpVar(&cfg.RegexDomainFilter)
@@ -538,7 +841,11 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("google-project", "When using the Google provider, current project is auto-detected, when running on GCP. Specify other project with this. Must be specified when running outside GCP.").Default(defaultConfig.GoogleProject).StringVar(&cfg.GoogleProject)
 	app.Flag("google-batch-change-size", "When using the Google provider, set the maximum number of changes that will be applied in each batch.").Default(strconv.Itoa(defaultConfig.GoogleBatchChangeSize)).IntVar(&cfg.GoogleBatchChangeSize)
 	app.Flag("google-batch-change-interval", "When using the Google provider, set the interval between batch changes.").Default(defaultConfig.GoogleBatchChangeInterval.String()).DurationVar(&cfg.GoogleBatchChangeInterval)
+	app.Flag("google-zone-batch-change", "When using the Google provider, override the batch size and interval of a zone, like a large private zone of pods, as ZONE=SIZE/INTERVAL, ZONE=SIZE or ZONE=/INTERVAL; specify multiple times for multiple zones (optional)").PlaceHolder("ZONE=SIZE/INTERVAL").StringsVar(&cfg.GoogleZoneBatchChanges)
 	app.Flag("google-zone-visibility", "When using the Google provider, filter for zones with this visibility (optional, options: public, private)").Default(defaultConfig.GoogleZoneVisibility).EnumVar(&cfg.GoogleZoneVisibility, "", "public", "private")
+	app.Flag("google-split-view", "When using the Google provider, answer the endpoints with a view (public, private) only in the zones of that visibility, and the other endpoints in the zones of both (default: disabled)").BoolVar(&cfg.GoogleSplitView)
+	app.Flag("google-read-only", "When using the Google provider, list the zones and the records but fail the changes with an error, unlike --dry-run, for the deployments without write credentials (default: disabled)").BoolVar(&cfg.GoogleReadOnly)
+	app.Flag("google-forwarding-zone-policy", "When using the Google provider, the policy of the forwarding and peering zones matching the filters: skip them, fail, or manage the forwarding zones and skip the peering zones (default: manage, options: skip, error, manage)").Default(defaultConfig.GoogleForwardingZonePolicy).EnumVar(&cfg.GoogleForwardingZonePolicy, "skip", "error", "manage")
 	app.Flag("alibaba-cloud-config-file", "When using the Alibaba Cloud provider, specify the Alibaba Cloud configuration file (required when --provider=alibabacloud)").Default(defaultConfig.AlibabaCloudConfigFile).StringVar(&cfg.AlibabaCloudConfigFile)
 	app.Flag("alibaba-cloud-zone-type", "When using the Alibaba Cloud provider, filter for zones of this type (optional, options: public, private)").Default(defaultConfig.AlibabaCloudZoneType).EnumVar(&cfg.AlibabaCloudZoneType, "", "public", "private")
 	app.Flag("aws-zone-type", "When using the AWS provider, filter for zones of this type (optional, options: public, private)").Default(defaultConfig.AWSZoneType).EnumVar(&cfg.AWSZoneType, "", "public", "private")
@@ -644,6 +951,16 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("transip-account", "When using the TransIP provider, specify the account name (required when --provider=transip)").Default(defaultConfig.TransIPAccountName).StringVar(&cfg.TransIPAccountName)
 	app.Flag("transip-keyfile", "When using the TransIP provider, specify the path to the private key file (required when --provider=transip)").Default(defaultConfig.TransIPPrivateKeyFile).StringVar(&cfg.TransIPPrivateKeyFile)
 
+	// Flags related to Consul provider
+	app.Flag("consul-address", "When using the Consul provider or source, the address of the Consul HTTP API (default: http://127.0.0.1:8500)").Default(defaultConfig.ConsulAddress).StringVar(&cfg.ConsulAddress)
+	app.Flag("consul-token", "When using the Consul provider or source, the ACL token - with write access to the node and its services for the provider, read access to the services and nodes for the source (optional)").Default(defaultConfig.ConsulToken).StringVar(&cfg.ConsulToken)
+	app.Flag("consul-datacenter", "When using the Consul provider or source, the datacenter of the catalog (default: the datacenter of the server)").Default(defaultConfig.ConsulDatacenter).StringVar(&cfg.ConsulDatacenter)
+	app.Flag("consul-node", "When using the Consul provider, the external node of the services, owned by this instance of ExternalDNS (default: external-dns)").Default(defaultConfig.ConsulNode).StringVar(&cfg.ConsulNode)
+	app.Flag("consul-domain", "When using the Consul provider, the domain removed from the DNS names to get the service names: app.example.com is registered as the service app with example.com (optional)").Default(defaultConfig.ConsulDomain).StringVar(&cfg.ConsulDomain)
+
+	// Flags related to the hosts file provider
+	app.Flag("hosts-file-path", "When using the hostsfile provider, the file in the /etc/hosts format where the A and AAAA records are written, like /etc/hosts or a file of the CoreDNS hosts plugin (required when --provider=hostsfile)").Default(defaultConfig.HostsFilePath).StringVar(&cfg.HostsFilePath)
+
 	// Flags related to Pihole provider
 	app.Flag("pihole-server", "When using the Pihole provider, the base URL of the Pihole web server (required when --provider=pihole)").Default(defaultConfig.PiholeServer).StringVar(&cfg.PiholeServer)
 	app.Flag("pihole-password", "When using the Pihole provider, the password to the server if it is protected").Default(defaultConfig.PiholePassword).StringVar(&cfg.PiholePassword)
@@ -664,21 +981,87 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("txt-wildcard-replacement", "When using the TXT registry, a custom string that's used instead of an asterisk for TXT records corresponding to wildcard DNS records (optional)").Default(defaultConfig.TXTWildcardReplacement).StringVar(&cfg.TXTWildcardReplacement)
 	app.Flag("txt-encrypt-enabled", "When using the TXT registry, set if TXT records should be encrypted before stored (default: disabled)").BoolVar(&cfg.TXTEncryptEnabled)
 	app.Flag("txt-encrypt-aes-key", "When using the TXT registry, set TXT record decryption and encryption 32 byte aes key (required when --txt-encrypt=true)").Default(defaultConfig.TXTEncryptAESKey).StringVar(&cfg.TXTEncryptAESKey)
+	app.Flag("conflict-policy", "The policy of the changes to the records of another owner, or of no owner, counted and shown in the dashboard: skip them, skip them with a warning, adopt the records, taking their ownership, or adopt only the records of no owner, including the ones matching the desired records, to migrate the records created by hand (default: skip, options: skip, warn, adopt, adopt-unowned; adopt and adopt-unowned require the txt registry)").Default(defaultConfig.ConflictPolicy).EnumVar(&cfg.ConflictPolicy, "skip", "warn", "adopt", "adopt-unowned")
+	app.Flag("conflict-events", "With --conflict-policy=warn, adopt or adopt-unowned, report the new conflicts as Warning events of the resources of the endpoints (default: disabled)").BoolVar(&cfg.ConflictEvents)
+	app.Flag("change-events", "Report the created, updated and deleted records, and the changes rejected by a policy, as events of the resources of the endpoints (default: disabled)").BoolVar(&cfg.ChangeEvents)
+	app.Flag("strict-shadowing", "Fail the synchronization, listing each record, while desired records shadow the records of another owner, or of no owner, or are in subzones delegated by NS records, instead of applying the other changes (default: disabled)").BoolVar(&cfg.StrictShadowing)
 	app.Flag("dynamodb-region", "When using the DynamoDB registry, the AWS region of the DynamoDB table (optional)").Default(cfg.AWSDynamoDBRegion).StringVar(&cfg.AWSDynamoDBRegion)
 	app.Flag("dynamodb-table", "When using the DynamoDB registry, the name of the DynamoDB table (default: \"external-dns\")").Default(defaultConfig.AWSDynamoDBTable).StringVar(&cfg.AWSDynamoDBTable)
 
 	// Flags related to the main control loop
 	app.Flag("txt-cache-interval", "The interval between cache synchronizations in duration format (default: disabled)").Default(defaultConfig.TXTCacheInterval.String()).DurationVar(&cfg.TXTCacheInterval)
 	app.Flag("interval", "The interval between two consecutive synchronizations in duration format (default: 1m)").Default(defaultConfig.Interval.String()).DurationVar(&cfg.Interval)
+	app.Flag("schedule", "Run the synchronizations at the times of this cron schedule instead of every --interval, like '*/15 * * * *', '@hourly' or '@every 10m' (optional)").Default(defaultConfig.Schedule).StringVar(&cfg.Schedule)
 	app.Flag("min-event-sync-interval", "The minimum interval between two consecutive synchronizations triggered from kubernetes events in duration format (default: 5s)").Default(defaultConfig.MinEventSyncInterval.String()).DurationVar(&cfg.MinEventSyncInterval)
+	app.Flag("event-debounce", "Postpone the synchronization triggered by kubernetes events until no event was received for this duration, so that a burst of events triggers a single synchronization (default: disabled)").Default(defaultConfig.EventDebounce.String()).DurationVar(&cfg.EventDebounce)
+	app.Flag("event-max-delay", "With --event-debounce, the maximum delay between the first event and the synchronization; 0s means no limit").Default(defaultConfig.EventMaxDelay.String()).DurationVar(&cfg.EventMaxDelay)
+	app.Flag("drift-interval", "The interval between two consecutive comparisons of the sources with the provider records, exported as drift metrics without applying changes (default: disabled)").Default(defaultConfig.DriftInterval.String()).DurationVar(&cfg.DriftInterval)
+	app.Flag("full-sync-interval", "With a provider exposing a change journal (google, webhook), list every record at this interval only; the syncs in between plan the names changed in the sources, unless the journal reports other changes (default: disabled)").Default(defaultConfig.FullSyncInterval.String()).DurationVar(&cfg.FullSyncInterval)
+	app.Flag("plan-shards", "Split the names in this number of shards, read from the provider, planned and applied one after the other, to bound the memory used by very large zones (default: disabled)").IntVar(&cfg.PlanShards)
+	app.Flag("replica", "The name of this replica among --replicas, like the pod name of a StatefulSet (required with --replicas)").Default(defaultConfig.Replica).StringVar(&cfg.Replica)
+	app.Flag("replicas", "Split the names between these replicas, each planning and applying the names it owns by a hash of the name; specify multiple times for multiple replicas, the same on all of them (default: disabled)").StringsVar(&cfg.Replicas)
+	app.Flag("record-lease", "Stamp the records of this instance with a lease of this duration, renewed by the syncs, and delete the records of other owners whose lease expired, like the records of the pods of a crashed cluster; at least twice --interval, requires --registry=txt (default: disabled)").Default(defaultConfig.RecordLease.String()).DurationVar(&cfg.RecordLease)
+	app.Flag("record-cache-file", "Save the records after each sync to this file, and plan the first sync after a restart with them instead of waiting for the listing of the records; with --full-sync-interval, they are reused until the journal reports a change (optional)").Default(defaultConfig.RecordCacheFile).StringVar(&cfg.RecordCacheFile)
+	app.Flag("record-cache-max-age", "The age of the --record-cache-file after which it is not used; 0s means no limit").Default(defaultConfig.RecordCacheMaxAge.String()).DurationVar(&cfg.RecordCacheMaxAge)
+	app.Flag("domain-lock-namespace", "Lock each domain with a Lease in this namespace while applying its changes, deferring the changes of the domains locked by another instance; for instances managing overlapping domains, like during a migration (default: disabled)").Default(defaultConfig.DomainLockNamespace).StringVar(&cfg.DomainLockNamespace)
+	app.Flag("domain-lock-identity", "The holder of the --domain-lock-namespace Leases, distinct for each instance (default: the host name)").Default(defaultConfig.DomainLockIdentity).StringVar(&cfg.DomainLockIdentity)
+	app.Flag("domain-lock-duration", "The duration after which a Lease of --domain-lock-namespace not renewed, like the one of a crashed instance, expires; longer than the application of the changes").Default(defaultConfig.DomainLockDuration.String()).DurationVar(&cfg.DomainLockDuration)
+	app.Flag("max-changes-per-minute", "The maximum number of record changes applied per minute, across all zones; the other changes are deferred to the next syncs (default: unlimited)").IntVar(&cfg.MaxChangesPerMinute)
+	app.Flag("max-zone-changes-per-minute", "The maximum number of record changes applied per minute in each zone - the domain filter matching the record, or its last two labels (default: unlimited)").IntVar(&cfg.MaxZoneChangesPerMinute)
+	app.Flag("quarantine-factor", "Pause the applies on a plan deleting or updating more than this factor times the records of the recent plans, like when a source returns no endpoints, until cleared with a POST to /admin/quarantine of the admin API or --quarantine-timeout (default: disabled)").Float64Var(&cfg.QuarantineFactor)
+	app.Flag("quarantine-min-changes", "The minimum number of deleted or updated records of a plan pausing the applies with --quarantine-factor").Default(strconv.Itoa(defaultConfig.QuarantineMinChanges)).IntVar(&cfg.QuarantineMinChanges)
+	app.Flag("quarantine-history", "The number of recent plans averaged by --quarantine-factor").Default(strconv.Itoa(defaultConfig.QuarantineHistory)).IntVar(&cfg.QuarantineHistory)
+	app.Flag("quarantine-timeout", "The time after which the applies paused by --quarantine-factor resume, applying the next plan; 0s means until cleared").Default(defaultConfig.QuarantineTimeout.String()).DurationVar(&cfg.QuarantineTimeout)
 	app.Flag("once", "When enabled, exits the synchronization loop after the first iteration (default: disabled)").BoolVar(&cfg.Once)
-	app.Flag("dry-run", "When enabled, prints DNS record changes rather than actually performing them (default: disabled)").BoolVar(&cfg.DryRun)
+	app.Flag("final-sync", "When enabled, runs a last synchronization on SIGTERM, to apply the changes of the pending events (default: disabled)").BoolVar(&cfg.FinalSync)
+	app.Flag("shutdown-timeout", "On SIGTERM, the time to wait for the synchronization in progress, the final synchronization and the webhook server requests (default: 30s)").Default(defaultConfig.ShutdownTimeout.String()).DurationVar(&cfg.ShutdownTimeout)
+	app.Flag("validate", "When enabled, creates the sources, the provider and the registry, prints the effective configuration with the secrets redacted and exits: 1 if the configuration is invalid (default: disabled)").BoolVar(&cfg.Validate)
+	app.Flag("dry-run", "When enabled, prints DNS record changes rather than actually performing them; with --once, exits with 2 if there are changes (default: disabled)").BoolVar(&cfg.DryRun)
 	app.Flag("events", "When enabled, in addition to running every interval, the reconciliation loop will get triggered when supported sources change (default: disabled)").BoolVar(&cfg.UpdateEvents)
+	app.Flag("verify-resolver", "Resolve the applied changes against this DNS server and report drift as metrics, in NAME=HOST[:PORT] format; specify multiple times for multiple resolvers (optional)").StringsVar(&cfg.VerifyResolvers)
+	app.Flag("verify-wait", "When enabled with --verify-resolver, each sync waits until the applied changes are served (default: disabled)").BoolVar(&cfg.VerifyWait)
+	app.Flag("verify-timeout", "The max time to wait for each applied record to be served, in duration format (default: 5m)").Default(defaultConfig.VerifyTimeout.String()).DurationVar(&cfg.VerifyTimeout)
+	app.Flag("canary-domain", "Apply each change first to the same name under this domain, managed by the provider, and to the production zones only once served by the --verify-resolver servers within --verify-timeout (optional)").StringVar(&cfg.CanaryDomain)
+	app.Flag("audit-location", "Record every applied change set as a JSON object in this directory, or in a bucket with gs://BUCKET/PREFIX or s3://BUCKET/PREFIX (optional)").StringVar(&cfg.AuditLocation)
+	app.Flag("audit-retention", "Delete the audit records older than this, in duration format; 0 keeps them (default: 2160h)").Default(defaultConfig.AuditRetention.String()).DurationVar(&cfg.AuditRetention)
+	app.Flag("audit-actor", "The actor of the audit records (default: the hostname)").StringVar(&cfg.AuditActor)
+	app.Flag("rollback-history", "With --audit-location and --admin-api, the number of the last applied change sets which can be rolled back (default: 10)").Default(strconv.Itoa(defaultConfig.RollbackHistory)).IntVar(&cfg.RollbackHistory)
+	app.Flag("emit-dir", "Write the desired records (records.yaml) and the changes against the provider (changes.diff) to this directory instead of applying them, to be applied with 'ednsctl apply --file' (default: disabled)").StringVar(&cfg.EmitDir)
+	app.Flag("emit-git-commit", "Commit the files written with --emit-dir when they change; the directory must be a git worktree (default: disabled)").BoolVar(&cfg.EmitGitCommit)
+	app.Flag("chaos-sandbox", "Confirm that the provider is a disposable sandbox, required by the --chaos flags (default: disabled)").BoolVar(&cfg.ChaosSandbox)
+	app.Flag("chaos-records-error-rate", "The probability, from 0 to 1, of failing a read of the provider records, for resilience tests (default: 0)").Float64Var(&cfg.ChaosRecordsErrorRate)
+	app.Flag("chaos-error-rate", "The probability, from 0 to 1, of failing a change batch before applying it, for resilience tests (default: 0)").Float64Var(&cfg.ChaosErrorRate)
+	app.Flag("chaos-partial-rate", "The probability, from 0 to 1, of applying the first half of a change batch and failing it, for resilience tests (default: 0)").Float64Var(&cfg.ChaosPartialRate)
+	app.Flag("chaos-max-delay", "Delay each provider call by a random duration up to this, for resilience tests (default: 0)").DurationVar(&cfg.ChaosMaxDelay)
+	app.Flag("approval-namespace", "Write the changes as DNSChangeRequest objects in this namespace, and apply them only once approved (default: disabled)").StringVar(&cfg.ApprovalNamespace)
+	app.Flag("policy-webhook-url", "POST the changes to this policy webhook before applying them, and apply them only if it allows or modifies them (default: disabled)").StringVar(&cfg.PolicyWebhookURL)
+	app.Flag("policy-webhook-timeout", "The timeout of the reviews of the policy webhook (default: 10s)").Default(defaultConfig.PolicyWebhookTimeout.String()).DurationVar(&cfg.PolicyWebhookTimeout)
+	app.Flag("rego-policy", "A Rego policy file or directory reviewing the changes before applying them, evaluated in the controller - see docs/review.md; specify multiple times for several policies (optional)").StringsVar(&cfg.RegoPolicies)
+	app.Flag("admission-webhook-address", "Serve a validating admission webhook on this address, checking that the objects don't claim hostnames owned by another namespace in the registry (default: disabled)").StringVar(&cfg.AdmissionWebhookAddress)
+	app.Flag("admission-webhook-cert-file", "The TLS certificate of the admission webhook, reloaded when it changes (required with --admission-webhook-address)").StringVar(&cfg.AdmissionWebhookCertFile)
+	app.Flag("admission-webhook-key-file", "The TLS key of the admission webhook (required with --admission-webhook-address)").StringVar(&cfg.AdmissionWebhookKeyFile)
+	app.Flag("admission-webhook-mode", "Reject the objects claiming hostnames owned by another namespace, or admit them with a warning (default: deny, options: deny, warn)").EnumVar(&cfg.AdmissionWebhookMode, "deny", "warn")
+
+	// Embedded DNS server
+	app.Flag("dns-server-address", "Serve the records of the provider with DNS on UDP and TCP on this address, like :53, for the clients and the secondaries that can't query the provider (default: disabled)").StringVar(&cfg.DNSServerAddress)
+	app.Flag("dns-server-tls-address", "Serve the records of the provider with DNS-over-TLS on this address, like :853, for the resolvers that can't query the provider (default: disabled)").StringVar(&cfg.DNSServerTLSAddress)
+	app.Flag("dns-server-tls-cert-file", "The TLS certificate of the DNS-over-TLS server, reloaded when it changes (required with --dns-server-tls-address)").StringVar(&cfg.DNSServerTLSCertFile)
+	app.Flag("dns-server-tls-key-file", "The TLS key of the DNS-over-TLS server (required with --dns-server-tls-address)").StringVar(&cfg.DNSServerTLSKeyFile)
+	app.Flag("dns-server-transfer-secondary", "Allow the zone transfers, AXFR and IXFR, to this IP or CIDR, also requiring the TSIG key if set; specify multiple times for multiple secondaries (default: disabled)").StringsVar(&cfg.DNSServerTransferSecondaries)
+	app.Flag("dns-server-tsig-keyname", "Allow the zone transfers signed with this TSIG key, from the --dns-server-transfer-secondary if set, otherwise from any address (default: disabled)").StringVar(&cfg.DNSServerTSIGKeyName)
+	app.Flag("dns-server-tsig-secret", "The base64 secret of --dns-server-tsig-keyname (required with --dns-server-tsig-keyname)").StringVar(&cfg.DNSServerTSIGSecret)
+	app.Flag("dns-server-mdns-domain", "Answer the mDNS queries for NAME.local with the A and AAAA records of NAME.DOMAIN, trying the domains in order; specify multiple times for multiple domains (default: disabled)").StringsVar(&cfg.DNSServerMDNSDomains)
+	app.Flag("dns-server-mdns-interface", "The network interface of the mDNS responder (default: the system default)").StringVar(&cfg.DNSServerMDNSInterface)
 
 	// Miscellaneous flags
-	app.Flag("log-format", "The format in which log messages are printed (default: text, options: text, json)").Default(defaultConfig.LogFormat).EnumVar(&cfg.LogFormat, "text", "json")
+	app.Flag("log-format", "The format in which log messages are printed: gcp is JSON with the Cloud Logging severity, trace and labels (default: text, options: text, json, gcp)").Default(defaultConfig.LogFormat).EnumVar(&cfg.LogFormat, "text", "json", "gcp")
 	app.Flag("metrics-address", "Specify where to serve the metrics and health check endpoint (default: :7979)").Default(defaultConfig.MetricsAddress).StringVar(&cfg.MetricsAddress)
+	app.Flag("debug-endpoints", "When enabled, serves the runtime profiles on /debug/pprof and the state of the controller and the sources on /debug/state with the metrics (default: disabled)").BoolVar(&cfg.DebugEndpoints)
+	app.Flag("admin-api", "When enabled, serves the operations on the controller - sync now, pause and resume the applies, flush the caches, dump the desired and current state, change the log levels - on /admin/ on --admin-api-address (default: disabled)").BoolVar(&cfg.AdminAPI)
+	app.Flag("admin-api-address", "With --admin-api, where to serve the admin API, separately from the metrics (default: 127.0.0.1:7980)").Default(defaultConfig.AdminAPIAddress).StringVar(&cfg.AdminAPIAddress)
+	app.Flag("admin-api-token-file", "With --admin-api, a file with the bearer token required by the admin API (optional)").StringVar(&cfg.AdminAPITokenFile)
 	app.Flag("log-level", "Set the level of logging. (default: info, options: panic, debug, info, warning, error, fatal)").Default(defaultConfig.LogLevel).EnumVar(&cfg.LogLevel, allLogLevelsAsStrings()...)
+	app.Flag("log-levels", "Set the level of logging of components or zones, as a comma separated list of KEY=LEVEL, for example provider/google=warn,zone/example.com=debug; changed at runtime on /admin/loglevel with --admin-api (optional)").StringVar(&cfg.LogLevels)
 
 	// Webhook provider
 	app.Flag("webhook-provider-url", "The URL of the remote endpoint to call for the webhook provider (default: http://localhost:8888)").Default(defaultConfig.WebhookProviderURL).StringVar(&cfg.WebhookProviderURL)
@@ -686,11 +1069,9 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("webhook-provider-write-timeout", "The write timeout for the webhook provider in duration format (default: 10s)").Default(defaultConfig.WebhookProviderWriteTimeout.String()).DurationVar(&cfg.WebhookProviderWriteTimeout)
 
 	app.Flag("webhook-server", "When enabled, runs as a webhook server instead of a controller. (default: false).").BoolVar(&cfg.WebhookServer)
+	app.Flag("webhook-server-doh", "When enabled, the webhook server also serves DNS-over-HTTPS queries for the records of the provider on /dns-query (default: false)").BoolVar(&cfg.WebhookServerDoH)
+	app.Flag("webhook-server-cert-manager", "When enabled, the webhook server also solves the cert-manager DNS01 challenges with the provider, as an aggregated API on /apis/ (default: false)").BoolVar(&cfg.WebhookServerCertManager)
+	app.Flag("webhook-server-cutover", "When enabled, the webhook server also serves the weighted cutovers of the names on /cutover (default: false)").BoolVar(&cfg.WebhookServerCutover)
 
-	_, err := app.Parse(args)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return app
 }
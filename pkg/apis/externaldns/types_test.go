@@ -47,6 +47,7 @@ var (
 			ConnectorServer:         "localhost:8080",
 			CRDSourceAPIVersion:     "externaldns.k8s.io/v1alpha1",
 			CRDSourceKind:           "DNSEndpoint",
+			ConsulAddress:           "http://127.0.0.1:8500",
 		},
 		Sources:                     []string{"service"},
 		Provider:                    "google",
@@ -55,6 +56,7 @@ var (
 			GoogleBatchChangeSize:       1000,
 			GoogleBatchChangeInterval:   time.Second,
 			GoogleZoneVisibility:        "",
+			GoogleForwardingZonePolicy:  "manage",
 			AlibabaCloudConfigFile:      "/etc/kubernetes/alibaba-cloud.json",
 			AWSZoneType:                 "",
 			AWSZoneTagFilter:            []string{""},
@@ -113,6 +115,7 @@ var (
 			IBMCloudConfigFile:          "/etc/kubernetes/ibmcloud.json",
 			TencentCloudConfigFile:      "/etc/kubernetes/tencent-cloud.json",
 			TencentCloudZoneType:        "",
+			ConsulNode:                  "external-dns",
 			WebhookProviderURL:          "http://localhost:8888",
 			WebhookProviderReadTimeout:  5 * time.Second,
 			WebhookProviderWriteTimeout: 10 * time.Second,
@@ -131,11 +134,30 @@ var (
 		Interval:                time.Minute,
 		MinEventSyncInterval:    5 * time.Second,
 		Once:                    false,
+		ShutdownTimeout:         30 * time.Second,
 		DryRun:                  false,
 		LogFormat:               "text",
 		MetricsAddress:          ":7979",
+		AdminAPIAddress:         "127.0.0.1:7980",
 		LogLevel:                logrus.InfoLevel.String(),
 		ManagedDNSRecordTypes:       []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME},
+		VerifyTimeout:               5 * time.Minute,
+		AuditRetention:              90 * 24 * time.Hour,
+		PolicyWebhookTimeout:        10 * time.Second,
+		RollbackHistory:             10,
+		FailoverThreshold:           3,
+		FailoverAfter:               5 * time.Minute,
+		SourceFailurePolicy:         "abort",
+		SourceMergePolicy:           "none",
+		RecordCacheMaxAge:           time.Hour,
+		DomainLockDuration:          time.Minute,
+		ConflictPolicy:              "skip",
+		QuarantineMinChanges:        10,
+		QuarantineHistory:           10,
+		LBHealthInterval:            30 * time.Second,
+		LBHealthUnhealthyThreshold:  3,
+		LBHealthHealthyThreshold:    2,
+		EventMaxDelay:               time.Minute,
 	}
 
 	overriddenConfig = &Config{
@@ -155,6 +177,7 @@ var (
 			ConnectorServer:        "localhost:8081",
 			CRDSourceAPIVersion:    "test.k8s.io/v1alpha1",
 			CRDSourceKind:          "Endpoint",
+			ConsulAddress:          "http://127.0.0.1:8500",
 		},
 		Sources:                     []string{"service", "ingress", "connector"},
 		Provider:                    "google",
@@ -163,6 +186,7 @@ var (
 			GoogleBatchChangeSize:       100,
 			GoogleBatchChangeInterval:   time.Second * 2,
 			GoogleZoneVisibility:        "private",
+			GoogleForwardingZonePolicy:  "manage",
 			AlibabaCloudConfigFile:      "/etc/kubernetes/alibaba-cloud.json",
 			AWSZoneType:                 "private",
 			AWSZoneTagFilter:            []string{"tag=foo"},
@@ -224,6 +248,7 @@ var (
 			IBMCloudConfigFile:          "ibmcloud.json",
 			TencentCloudConfigFile:      "tencent-cloud.json",
 			TencentCloudZoneType:        "private",
+			ConsulNode:                  "external-dns",
 			WebhookProviderURL:          "http://localhost:8888",
 			WebhookProviderReadTimeout:  5 * time.Second,
 			WebhookProviderWriteTimeout: 10 * time.Second,
@@ -247,11 +272,30 @@ var (
 		Interval:               10 * time.Minute,
 		MinEventSyncInterval:   50 * time.Second,
 		Once:                   true,
+		ShutdownTimeout:        30 * time.Second,
 		DryRun:                 true,
 		LogFormat:              "json",
 		MetricsAddress:         "127.0.0.1:9099",
+		AdminAPIAddress:        "127.0.0.1:7980",
 		LogLevel:               logrus.DebugLevel.String(),
 		ManagedDNSRecordTypes:       []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME, endpoint.RecordTypeNS},
+		VerifyTimeout:               5 * time.Minute,
+		AuditRetention:              90 * 24 * time.Hour,
+		PolicyWebhookTimeout:        10 * time.Second,
+		RollbackHistory:             10,
+		FailoverThreshold:           3,
+		FailoverAfter:               5 * time.Minute,
+		SourceFailurePolicy:         "abort",
+		SourceMergePolicy:           "none",
+		RecordCacheMaxAge:           time.Hour,
+		DomainLockDuration:          time.Minute,
+		ConflictPolicy:              "skip",
+		QuarantineMinChanges:        10,
+		QuarantineHistory:           10,
+		LBHealthInterval:            30 * time.Second,
+		LBHealthUnhealthyThreshold:  3,
+		LBHealthHealthyThreshold:    2,
+		EventMaxDelay:               time.Minute,
 
 	}
 )
@@ -546,10 +590,14 @@ func restoreEnv(t *testing.T, originalEnv map[string]string) {
 
 func TestPasswordsNotLogged(t *testing.T) {
 	cfg := Config{
+		Config: source.Config{
+			ConsulToken: "consul-token",
+		},
 		ProviderConfig: ProviderConfig{
 			DynPassword:          "dyn-pass",
 			PDNSAPIKey:           "pdns-api-key",
 			RFC2136TSIGSecret:    "tsig-secret",
+			AkamaiClientSecret:   "akamai-secret",
 		},
 	}
 
@@ -558,4 +606,6 @@ func TestPasswordsNotLogged(t *testing.T) {
 	assert.False(t, strings.Contains(s, "dyn-pass"))
 	assert.False(t, strings.Contains(s, "pdns-api-key"))
 	assert.False(t, strings.Contains(s, "tsig-secret"))
+	assert.False(t, strings.Contains(s, "consul-token"))
+	assert.False(t, strings.Contains(s, "akamai-secret"))
 }
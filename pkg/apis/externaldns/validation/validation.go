@@ -19,7 +19,11 @@ package validation
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/robfig/cron/v3"
 	"k8s.io/apimachinery/pkg/labels"
 
 	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
@@ -28,7 +32,7 @@ import (
 // ValidateConfig performs validation on the Config object
 func ValidateConfig(cfg *externaldns.Config) error {
 	// TODO: Should probably return field.ErrorList
-	if cfg.LogFormat != "text" && cfg.LogFormat != "json" {
+	if cfg.LogFormat != "text" && cfg.LogFormat != "json" && cfg.LogFormat != "gcp" {
 		return fmt.Errorf("unsupported log format: %s", cfg.LogFormat)
 	}
 	if len(cfg.Sources) == 0 {
@@ -37,6 +41,77 @@ func ValidateConfig(cfg *externaldns.Config) error {
 	if cfg.Provider == "" {
 		return errors.New("no provider specified")
 	}
+	for _, v := range cfg.SourceDomainFilters {
+		name, domain, ok := strings.Cut(v, "=")
+		if !ok || domain == "" {
+			return fmt.Errorf("invalid --source-domain-filter %q, use SOURCE=DOMAIN", v)
+		}
+		if !slices.Contains(cfg.Sources, name) {
+			return fmt.Errorf("--source-domain-filter %q is not of a --source", v)
+		}
+	}
+
+	// Federation provider specific validations
+	if cfg.Provider == "federation" {
+		if cfg.FederationConfig == "" {
+			return errors.New("no federation config specified")
+		}
+		if cfg.EmitDir != "" || cfg.WebhookServer {
+			return errors.New("--emit-dir and --webhook-server are not supported with the federation provider")
+		}
+	}
+
+	if cfg.FailoverConfig != "" {
+		if cfg.Provider == "federation" {
+			return errors.New("--failover-config is not supported with the federation provider")
+		}
+		if cfg.Registry == "aws-sd" {
+			return errors.New("--failover-config is not supported with the aws-sd registry")
+		}
+		if cfg.FailoverThreshold < 1 || cfg.FailoverAfter < 0 {
+			return errors.New("--failover-threshold must be positive and --failover-after must not be negative")
+		}
+	}
+
+	if cfg.AdmissionWebhookAddress != "" {
+		if cfg.AdmissionWebhookCertFile == "" || cfg.AdmissionWebhookKeyFile == "" {
+			return errors.New("--admission-webhook-address requires --admission-webhook-cert-file and --admission-webhook-key-file")
+		}
+		if cfg.Registry == "noop" {
+			return errors.New("--admission-webhook-address requires a registry with the owners of the records, not --registry=noop")
+		}
+	}
+
+	if cfg.DNSServerTLSAddress != "" && (cfg.DNSServerTLSCertFile == "" || cfg.DNSServerTLSKeyFile == "") {
+		return errors.New("--dns-server-tls-address requires --dns-server-tls-cert-file and --dns-server-tls-key-file")
+	}
+
+	if len(cfg.DNSServerTransferSecondaries) > 0 || cfg.DNSServerTSIGKeyName != "" {
+		if cfg.DNSServerAddress == "" && cfg.DNSServerTLSAddress == "" {
+			return errors.New("the zone transfers require --dns-server-address or --dns-server-tls-address")
+		}
+		if cfg.DNSServerTSIGKeyName != "" && cfg.DNSServerTSIGSecret == "" {
+			return errors.New("--dns-server-tsig-keyname requires --dns-server-tsig-secret")
+		}
+		if cfg.Registry == "aws-sd" {
+			return errors.New("the zone transfers are not supported with the aws-sd registry")
+		}
+	}
+
+	// Consul provider specific validations
+	if cfg.Provider == "consul" && cfg.Registry != "noop" {
+		return errors.New("the consul provider requires --registry=noop: the services of --consul-node are owned by ExternalDNS")
+	}
+
+	// Hosts file provider specific validations
+	if cfg.Provider == "hostsfile" {
+		if cfg.HostsFilePath == "" {
+			return errors.New("no hosts file path specified")
+		}
+		if cfg.Registry != "noop" {
+			return errors.New("the hostsfile provider requires --registry=noop: the records between the markers of the file are owned by ExternalDNS")
+		}
+	}
 
 	// Azure provider specific validations
 	if cfg.Provider == "azure" {
@@ -102,9 +177,124 @@ func ValidateConfig(cfg *externaldns.Config) error {
 		return errors.New("txt-prefix and txt-suffix are mutual exclusive")
 	}
 
+	for _, rate := range []float64{cfg.ChaosRecordsErrorRate, cfg.ChaosErrorRate, cfg.ChaosPartialRate} {
+		if rate < 0 || rate > 1 {
+			return errors.New("--chaos rates must be between 0 and 1")
+		}
+	}
+	chaos := cfg.ChaosRecordsErrorRate > 0 || cfg.ChaosErrorRate > 0 || cfg.ChaosPartialRate > 0 || cfg.ChaosMaxDelay > 0
+	if chaos && !cfg.ChaosSandbox {
+		return errors.New("--chaos flags require --chaos-sandbox, confirming that the provider is a disposable sandbox")
+	}
+
+	if cfg.PlanShards > 1 {
+		if cfg.FullSyncInterval > 0 {
+			return errors.New("--plan-shards and --full-sync-interval are mutually exclusive")
+		}
+		if cfg.AdmissionWebhookAddress != "" {
+			return errors.New("--admission-webhook-address requires all the records in memory, not --plan-shards")
+		}
+		if cfg.RecordCacheFile != "" {
+			return errors.New("--record-cache-file requires all the records in memory, not --plan-shards")
+		}
+		if cfg.StrictShadowing {
+			return errors.New("--strict-shadowing requires all the records in memory, not --plan-shards")
+		}
+		if cfg.QuarantineFactor > 0 {
+			return errors.New("--quarantine-factor compares the whole plans, not the ones of --plan-shards")
+		}
+	}
+
+	if len(cfg.Replicas) > 0 || cfg.Replica != "" {
+		if err := validateReplicas(cfg.Replica, cfg.Replicas); err != nil {
+			return err
+		}
+	}
+
+	if cfg.CanaryDomain != "" {
+		if len(cfg.VerifyResolvers) == 0 {
+			return errors.New("--canary-domain requires --verify-resolver")
+		}
+		if cfg.Registry == "aws-sd" {
+			return errors.New("--canary-domain is not supported with --registry=aws-sd")
+		}
+	}
+
+	if cfg.QuarantineFactor < 0 {
+		return errors.New("--quarantine-factor must not be negative")
+	}
+	if cfg.QuarantineFactor > 0 && (cfg.QuarantineMinChanges < 1 || cfg.QuarantineHistory < 1) {
+		return errors.New("--quarantine-min-changes and --quarantine-history must be at least 1")
+	}
+
+	if cfg.DefaultTTL < 0 {
+		return errors.New("--default-ttl must not be negative")
+	}
+	if cfg.CNAMEFlatteningResolver != "" && len(cfg.CNAMEFlattening) == 0 && !cfg.ApexAlias {
+		return errors.New("--cname-flattening-resolver requires --cname-flattening or --apex-alias")
+	}
+	if cfg.GoogleSplitView && cfg.GoogleZoneVisibility != "" {
+		return errors.New("--google-split-view requires the zones of both visibilities, without --google-zone-visibility")
+	}
+	if cfg.LBHealthProject != "" && (cfg.LBHealthInterval <= 0 || cfg.LBHealthUnhealthyThreshold < 1 || cfg.LBHealthHealthyThreshold < 1) {
+		return errors.New("--lb-health-interval must be positive, and the --lb-health thresholds at least 1")
+	}
+	if cfg.GeoRegion != "" && !cfg.GeoRouting {
+		return errors.New("--geo-region requires --geo-routing")
+	}
+	if cfg.ApexAlias && cfg.Provider == "federation" {
+		return errors.New("--apex-alias is not supported with --provider=federation")
+	}
+
+	if cfg.RecordLease > 0 {
+		if cfg.Registry != "txt" {
+			return errors.New("--record-lease requires --registry=txt")
+		}
+		if cfg.RecordLease < 2*cfg.Interval {
+			return errors.New("--record-lease must be at least twice --interval, renewed by the syncs")
+		}
+	}
+
+	if cfg.ConflictPolicy == "adopt" || cfg.ConflictPolicy == "adopt-unowned" {
+		if cfg.Registry != "txt" {
+			return fmt.Errorf("--conflict-policy=%s requires --registry=txt", cfg.ConflictPolicy)
+		}
+		if cfg.StrictShadowing {
+			return fmt.Errorf("--conflict-policy=%s and --strict-shadowing are mutually exclusive", cfg.ConflictPolicy)
+		}
+	}
+
+	if cfg.DomainLockNamespace != "" && cfg.DomainLockDuration < time.Second {
+		return errors.New("--domain-lock-duration must be at least 1s")
+	}
+
+	if cfg.Schedule != "" {
+		if _, err := cron.ParseStandard(cfg.Schedule); err != nil {
+			return fmt.Errorf("--schedule is not a valid cron schedule: %w", err)
+		}
+	}
+
 	_, err := labels.Parse(cfg.LabelFilter)
 	if err != nil {
 		return errors.New("--label-filter does not specify a valid label selector")
 	}
 	return nil
 }
+
+// validateReplicas checks that the replica is one of the replicas, listed once.
+func validateReplicas(replica string, replicas []string) error {
+	if len(replicas) == 0 {
+		return errors.New("--replica requires --replicas")
+	}
+	seen := map[string]bool{}
+	for _, r := range replicas {
+		if r == "" || seen[r] {
+			return fmt.Errorf("--replicas must be distinct and not empty, got %q", replicas)
+		}
+		seen[r] = true
+	}
+	if !seen[replica] {
+		return fmt.Errorf("--replica %q is not one of the --replicas %q", replica, replicas)
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ package validation
 
 import (
 	"testing"
+	"time"
 
 	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
 
@@ -37,7 +38,7 @@ func TestValidateFlags(t *testing.T) {
 	cfg.LogFormat = ""
 	assert.Error(t, ValidateConfig(cfg))
 
-	for _, format := range []string{"text", "json"} {
+	for _, format := range []string{"text", "json", "gcp"} {
 		cfg = newValidConfig(t)
 		cfg.LogFormat = format
 		assert.NoError(t, ValidateConfig(cfg))
@@ -280,3 +281,279 @@ func TestValidateGoodRfc2136GssTsigConfig(t *testing.T) {
 		assert.Nil(t, err)
 	}
 }
+
+func TestValidateChaosConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.ChaosPartialRate = 0.1
+	assert.ErrorContains(t, ValidateConfig(cfg), "--chaos-sandbox")
+
+	cfg.ChaosSandbox = true
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.ChaosErrorRate = 1.5
+	assert.Error(t, ValidateConfig(cfg))
+}
+
+func TestValidateSchedule(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.Schedule = "*/15 * * * *"
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.Schedule = "@every 10m"
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.Schedule = "every 10m"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--schedule")
+}
+
+func TestValidateFederationConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.Provider = "federation"
+	assert.ErrorContains(t, ValidateConfig(cfg), "federation config")
+
+	cfg.FederationConfig = "federation.yaml"
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.EmitDir = "out"
+	assert.Error(t, ValidateConfig(cfg))
+}
+
+func TestValidateFailoverConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.FailoverConfig = "failover.yaml"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--failover-threshold")
+
+	cfg.FailoverThreshold = 3
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.Provider = "federation"
+	cfg.FederationConfig = "federation.yaml"
+	assert.ErrorContains(t, ValidateConfig(cfg), "federation")
+}
+
+func TestValidateAdmissionWebhookConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.AdmissionWebhookAddress = ":9443"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--admission-webhook-cert-file")
+
+	cfg.AdmissionWebhookCertFile = "tls.crt"
+	cfg.AdmissionWebhookKeyFile = "tls.key"
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.Registry = "noop"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--registry=noop")
+}
+
+func TestValidatePlanShardsConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.PlanShards = 16
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.FullSyncInterval = time.Hour
+	assert.ErrorContains(t, ValidateConfig(cfg), "--full-sync-interval")
+
+	cfg.FullSyncInterval = 0
+	cfg.AdmissionWebhookAddress = ":9443"
+	cfg.AdmissionWebhookCertFile = "tls.crt"
+	cfg.AdmissionWebhookKeyFile = "tls.key"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--plan-shards")
+
+	cfg.AdmissionWebhookAddress = ""
+	cfg.RecordCacheFile = "/var/cache/external-dns/records.json"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--record-cache-file")
+
+	cfg.RecordCacheFile = ""
+	cfg.StrictShadowing = true
+	assert.ErrorContains(t, ValidateConfig(cfg), "--strict-shadowing")
+
+	cfg.StrictShadowing = false
+	cfg.QuarantineFactor = 10
+	assert.ErrorContains(t, ValidateConfig(cfg), "--quarantine-factor")
+}
+
+func TestValidateReplicasConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.Replica = "external-dns-1"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--replicas")
+
+	cfg.Replicas = []string{"external-dns-0", "external-dns-1"}
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.Replica = "external-dns-2"
+	assert.ErrorContains(t, ValidateConfig(cfg), "is not one of")
+
+	cfg.Replica = ""
+	assert.ErrorContains(t, ValidateConfig(cfg), "is not one of")
+
+	cfg.Replica = "external-dns-0"
+	cfg.Replicas = []string{"external-dns-0", "external-dns-0"}
+	assert.ErrorContains(t, ValidateConfig(cfg), "distinct")
+}
+
+func TestValidateDomainLockConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.DomainLockNamespace = "external-dns"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--domain-lock-duration")
+
+	cfg.DomainLockDuration = time.Minute
+	assert.NoError(t, ValidateConfig(cfg))
+}
+
+func TestValidateSourceDomainFilterConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.SourceDomainFilters = []string{"service=svc.example.com"}
+	assert.ErrorContains(t, ValidateConfig(cfg), "is not of a --source")
+
+	cfg.Sources = []string{"service", "istio-se"}
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.SourceDomainFilters = []string{"service"}
+	assert.ErrorContains(t, ValidateConfig(cfg), "use SOURCE=DOMAIN")
+}
+
+func TestValidateConflictPolicyConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.ConflictPolicy = "adopt"
+	cfg.Registry = "noop"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--registry=txt")
+
+	cfg.Registry = "txt"
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.StrictShadowing = true
+	assert.ErrorContains(t, ValidateConfig(cfg), "--strict-shadowing")
+
+	cfg.StrictShadowing = false
+	cfg.ConflictPolicy = "adopt-unowned"
+	assert.NoError(t, ValidateConfig(cfg))
+	cfg.Registry = "noop"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--conflict-policy=adopt-unowned requires --registry=txt")
+}
+
+func TestValidateCanaryConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+
+	cfg.CanaryDomain = "canary.example.net"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--verify-resolver")
+
+	cfg.VerifyResolvers = []string{"8.8.8.8"}
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.Registry = "aws-sd"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--registry=aws-sd")
+}
+
+func TestValidateQuarantineConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+
+	cfg.QuarantineFactor = -1
+	assert.ErrorContains(t, ValidateConfig(cfg), "--quarantine-factor")
+
+	cfg.QuarantineFactor = 10
+	cfg.QuarantineHistory = 0
+	assert.ErrorContains(t, ValidateConfig(cfg), "--quarantine-history")
+
+	cfg.QuarantineHistory = 10
+	cfg.QuarantineMinChanges = 10
+	assert.NoError(t, ValidateConfig(cfg))
+}
+
+func TestValidateDefaultTTLConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+
+	cfg.DefaultTTL = -1
+	assert.ErrorContains(t, ValidateConfig(cfg), "--default-ttl")
+
+	cfg.DefaultTTL = 300
+	assert.NoError(t, ValidateConfig(cfg))
+}
+
+func TestValidateRecordLeaseConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.Registry = "txt"
+	cfg.Interval = time.Minute
+	cfg.RecordLease = time.Hour
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.RecordLease = time.Minute
+	assert.ErrorContains(t, ValidateConfig(cfg), "--interval")
+
+	cfg.RecordLease = time.Hour
+	cfg.Registry = "noop"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--registry=txt")
+}
+
+func TestValidateGoogleSplitViewConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.GoogleSplitView = true
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.GoogleZoneVisibility = "private"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--google-split-view")
+}
+
+func TestValidateLBHealthConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.LBHealthProject = "my-project"
+	cfg.LBHealthInterval = 30 * time.Second
+	cfg.LBHealthUnhealthyThreshold = 3
+	cfg.LBHealthHealthyThreshold = 2
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.LBHealthHealthyThreshold = 0
+	assert.ErrorContains(t, ValidateConfig(cfg), "--lb-health")
+
+	cfg.LBHealthHealthyThreshold = 2
+	cfg.LBHealthInterval = 0
+	assert.ErrorContains(t, ValidateConfig(cfg), "--lb-health-interval")
+}
+
+func TestValidateGeoRoutingConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+
+	cfg.GeoRegion = "us-central1"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--geo-routing")
+
+	cfg.GeoRouting = true
+	assert.NoError(t, ValidateConfig(cfg))
+}
+
+func TestValidateCNAMEFlatteningConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+
+	cfg.CNAMEFlatteningResolver = "10.0.0.2"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--cname-flattening")
+
+	cfg.CNAMEFlattening = []string{"example.org"}
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.CNAMEFlattening = nil
+	cfg.ApexAlias = true
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.Provider = "federation"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--apex-alias")
+}
+
+func TestValidateConsulConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.Provider = "consul"
+	cfg.Registry = "txt"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--registry=noop")
+
+	cfg.Registry = "noop"
+	assert.NoError(t, ValidateConfig(cfg))
+}
+
+func TestValidateHostsFileConfig(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.Provider = "hostsfile"
+	cfg.Registry = "noop"
+	assert.ErrorContains(t, ValidateConfig(cfg), "hosts file path")
+
+	cfg.HostsFilePath = "/etc/hosts"
+	assert.NoError(t, ValidateConfig(cfg))
+
+	cfg.Registry = "txt"
+	assert.ErrorContains(t, ValidateConfig(cfg), "--registry=noop")
+}
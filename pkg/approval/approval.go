@@ -0,0 +1,399 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package approval holds the computed changes as DNSChangeRequest objects until
+// they are approved, for environments where DNS edits need sign-off.
+package approval
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// DNSChangeRequestGVR is the resource of the DNSChangeRequest CRD
+// (docs/approval/dnschangerequest-crd.yaml).
+var DNSChangeRequestGVR = schema.GroupVersionResource{
+	Group:    "externaldns.k8s.io",
+	Version:  "v1alpha1",
+	Resource: "dnschangerequests",
+}
+
+// The phases of a DNSChangeRequest, in status.phase. Requests are created
+// Pending; the approver sets Approved or Rejected, and the controller sets the
+// other phases.
+const (
+	PhasePending    = "Pending"
+	PhaseApproved   = "Approved"
+	PhaseRejected   = "Rejected"
+	PhaseApplied    = "Applied"
+	PhaseFailed     = "Failed"
+	PhaseSuperseded = "Superseded"
+	PhaseStale      = "Stale"
+)
+
+const (
+	// OwnerLabel selects the requests of a controller, by owner ID.
+	OwnerLabel = "externaldns.k8s.io/owner"
+	// HashLabel is the hash of the changes, to avoid duplicate requests.
+	HashLabel = "externaldns.k8s.io/change-hash"
+
+	fieldManager = "external-dns"
+)
+
+var (
+	pendingRequests = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "approval",
+			Name:      "pending_requests",
+			Help:      "Number of DNSChangeRequests waiting for approval.",
+		},
+	)
+	appliedRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "approval",
+			Name:      "applied_requests_total",
+			Help:      "Number of approved DNSChangeRequests applied, by result.",
+		},
+		[]string{"result"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(pendingRequests)
+	prometheus.MustRegister(appliedRequests)
+}
+
+// Changes is the spec.changes of a DNSChangeRequest.
+type Changes struct {
+	Create    []*endpoint.Endpoint `json:"create,omitempty"`
+	UpdateOld []*endpoint.Endpoint `json:"updateOld,omitempty"`
+	UpdateNew []*endpoint.Endpoint `json:"updateNew,omitempty"`
+	Delete    []*endpoint.Endpoint `json:"delete,omitempty"`
+}
+
+// Registry submits the changes as DNSChangeRequest objects instead of applying
+// them. The approved requests are applied to the wrapped registry by Run.
+type Registry struct {
+	registry.Registry
+	Client    dynamic.Interface
+	Namespace string
+
+	// mu serializes the wrapped registry, used by both the controller and Run.
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// NewRegistry returns a registry creating DNSChangeRequests in the namespace.
+func NewRegistry(r registry.Registry, client dynamic.Interface, namespace string) *Registry {
+	return &Registry{Registry: r, Client: client, Namespace: namespace, now: time.Now}
+}
+
+// Records implements registry.Registry.
+func (r *Registry) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Registry.Records(ctx)
+}
+
+// ApplyChanges creates a pending DNSChangeRequest with the changes. Since the
+// controller computes the same plan until it is applied, no request is created
+// if a pending, approved or rejected one has the same changes. Pending requests with
+// different changes are superseded, so stale plans can't be approved.
+func (r *Registry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	spec, hash, err := specChanges(changes)
+	if err != nil {
+		return err
+	}
+	requests, err := r.list(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range requests {
+		req := &requests[i]
+		if phase := Phase(req); (phase == PhasePending || phase == PhaseApproved || phase == PhaseRejected) && req.GetLabels()[HashLabel] == hash {
+			log.Debugf("Changes already submitted as DNSChangeRequest %s (%s)", req.GetName(), phase)
+			pendingRequests.Set(float64(countPhase(requests, PhasePending)))
+			return nil
+		}
+	}
+	for i := range requests {
+		req := &requests[i]
+		if Phase(req) != PhasePending {
+			continue
+		}
+		if err := r.setStatus(ctx, req, PhaseSuperseded, "Superseded by newer changes"); err != nil {
+			return err
+		}
+		log.Infof("DNSChangeRequest %s superseded by newer changes", req.GetName())
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(DNSChangeRequestGVR.GroupVersion().String())
+	obj.SetKind("DNSChangeRequest")
+	obj.SetGenerateName("dns-")
+	obj.SetNamespace(r.Namespace)
+	obj.SetLabels(map[string]string{OwnerLabel: r.OwnerID(), HashLabel: hash})
+	obj.Object["spec"] = map[string]interface{}{
+		"ownerID": r.OwnerID(),
+		"changes": spec,
+	}
+	created, err := r.Client.Resource(DNSChangeRequestGVR).Namespace(r.Namespace).Create(ctx, obj, metav1.CreateOptions{FieldManager: fieldManager})
+	if err != nil {
+		return fmt.Errorf("creating DNSChangeRequest: %w", err)
+	}
+	if err := r.setStatus(ctx, created, PhasePending, summary(changes)); err != nil {
+		return err
+	}
+	pendingRequests.Set(float64(countPhase(append(requests, *created), PhasePending)))
+	log.Infof("Created DNSChangeRequest %s/%s: %s", r.Namespace, created.GetName(), summary(changes))
+	return nil
+}
+
+// ApplyApproved applies the approved requests, oldest first, and records the
+// result in their status. The records may have changed since a request was
+// created: the requests whose changes don't match the current records are
+// marked Stale instead of applied.
+func (r *Registry) ApplyApproved(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	requests, err := r.list(ctx)
+	if err != nil {
+		return err
+	}
+	pendingRequests.Set(float64(countPhase(requests, PhasePending)))
+	sort.SliceStable(requests, func(i, j int) bool {
+		ti, tj := requests[i].GetCreationTimestamp(), requests[j].GetCreationTimestamp()
+		return ti.Before(&tj)
+	})
+	for i := range requests {
+		req := &requests[i]
+		if Phase(req) != PhaseApproved {
+			continue
+		}
+		changes, err := RequestChanges(req)
+		if err != nil {
+			appliedRequests.WithLabelValues("failed").Inc()
+			if err := r.setStatus(ctx, req, PhaseFailed, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		records, err := r.Registry.Records(ctx)
+		if err != nil {
+			return err
+		}
+		if reason := staleReason(changes, records); reason != "" {
+			log.Warnf("DNSChangeRequest %s is stale: %s", req.GetName(), reason)
+			if err := r.setStatus(ctx, req, PhaseStale, reason); err != nil {
+				return err
+			}
+			continue
+		}
+		approvedBy, _, _ := unstructured.NestedString(req.Object, "status", "approvedBy")
+		if err := r.Registry.ApplyChanges(ctx, changes); err != nil {
+			log.Errorf("Failed to apply DNSChangeRequest %s approved by %q: %v", req.GetName(), approvedBy, err)
+			appliedRequests.WithLabelValues("failed").Inc()
+			if err := r.setStatus(ctx, req, PhaseFailed, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		log.Infof("Applied DNSChangeRequest %s approved by %q: %s", req.GetName(), approvedBy, summary(changes))
+		appliedRequests.WithLabelValues("applied").Inc()
+		if err := r.setStatus(ctx, req, PhaseApplied, summary(changes)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run applies the approved requests every interval, until the context is done.
+func (r *Registry) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.ApplyApproved(ctx); err != nil {
+			log.Errorf("Failed to apply the approved DNSChangeRequests: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Phase returns the phase of a request - Pending if not set yet.
+func Phase(req *unstructured.Unstructured) string {
+	phase, _, _ := unstructured.NestedString(req.Object, "status", "phase")
+	if phase == "" {
+		return PhasePending
+	}
+	return phase
+}
+
+// RequestChanges returns the changes of a request.
+func RequestChanges(req *unstructured.Unstructured) (*plan.Changes, error) {
+	spec, found, err := unstructured.NestedMap(req.Object, "spec", "changes")
+	if err != nil || !found {
+		return nil, fmt.Errorf("DNSChangeRequest %s has no changes", req.GetName())
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	var c Changes
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("DNSChangeRequest %s: %w", req.GetName(), err)
+	}
+	// The registries set labels on the records.
+	for _, eps := range [][]*endpoint.Endpoint{c.Create, c.UpdateOld, c.UpdateNew, c.Delete} {
+		for _, ep := range eps {
+			if ep.Labels == nil {
+				ep.Labels = endpoint.NewLabels()
+			}
+		}
+	}
+	return &plan.Changes{Create: c.Create, UpdateOld: c.UpdateOld, UpdateNew: c.UpdateNew, Delete: c.Delete}, nil
+}
+
+// staleReason returns why the changes don't match the current records, or ""
+// if they still apply: the created records must not exist, and the updated and
+// deleted ones must have the same targets and TTL.
+func staleReason(changes *plan.Changes, records []*endpoint.Endpoint) string {
+	current := make(map[endpoint.EndpointKey]*endpoint.Endpoint, len(records))
+	for _, ep := range records {
+		current[ep.Key()] = ep
+	}
+	for _, ep := range changes.Create {
+		if _, ok := current[ep.Key()]; ok {
+			return fmt.Sprintf("%s %s was created meanwhile", ep.RecordType, ep.DNSName)
+		}
+	}
+	for _, ep := range append(append([]*endpoint.Endpoint{}, changes.UpdateOld...), changes.Delete...) {
+		cur, ok := current[ep.Key()]
+		if !ok {
+			return fmt.Sprintf("%s %s was deleted meanwhile", ep.RecordType, ep.DNSName)
+		}
+		if !cur.Targets.Same(ep.Targets) || cur.RecordTTL != ep.RecordTTL {
+			return fmt.Sprintf("%s %s was changed meanwhile", ep.RecordType, ep.DNSName)
+		}
+	}
+	return ""
+}
+
+func (r *Registry) list(ctx context.Context) ([]unstructured.Unstructured, error) {
+	list, err := r.Client.Resource(DNSChangeRequestGVR).Namespace(r.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: OwnerLabel + "=" + r.OwnerID(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing DNSChangeRequests: %w", err)
+	}
+	return list.Items, nil
+}
+
+// setStatus updates the status of the request, and req once updated.
+func (r *Registry) setStatus(ctx context.Context, orig *unstructured.Unstructured, phase, message string) error {
+	req := orig.DeepCopy()
+	status, _, _ := unstructured.NestedMap(req.Object, "status")
+	if status == nil {
+		status = map[string]interface{}{}
+	}
+	status["phase"] = phase
+	status["message"] = message
+	if phase == PhaseApplied || phase == PhaseFailed {
+		status["appliedTime"] = r.now().UTC().Format(time.RFC3339)
+	}
+	req.Object["status"] = status
+	updated, err := r.Client.Resource(DNSChangeRequestGVR).Namespace(r.Namespace).UpdateStatus(ctx, req, metav1.UpdateOptions{FieldManager: fieldManager})
+	if err != nil {
+		return fmt.Errorf("updating DNSChangeRequest %s: %w", req.GetName(), err)
+	}
+	*orig = *updated
+	return nil
+}
+
+// specChanges returns the changes as an unstructured spec, and their hash. The
+// records are sorted, as the plan doesn't keep the order.
+func specChanges(changes *plan.Changes) (map[string]interface{}, string, error) {
+	c := Changes{
+		Create:    sorted(changes.Create),
+		UpdateOld: sorted(changes.UpdateOld),
+		UpdateNew: sorted(changes.UpdateNew),
+		Delete:    sorted(changes.Delete),
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, "", err
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(data)
+	return spec, hex.EncodeToString(sum[:])[:16], nil
+}
+
+func sorted(eps []*endpoint.Endpoint) []*endpoint.Endpoint {
+	eps = append([]*endpoint.Endpoint(nil), eps...)
+	sort.SliceStable(eps, func(i, j int) bool {
+		if eps[i].DNSName != eps[j].DNSName {
+			return eps[i].DNSName < eps[j].DNSName
+		}
+		if eps[i].RecordType != eps[j].RecordType {
+			return eps[i].RecordType < eps[j].RecordType
+		}
+		return eps[i].SetIdentifier < eps[j].SetIdentifier
+	})
+	return eps
+}
+
+func summary(changes *plan.Changes) string {
+	return fmt.Sprintf("%d create, %d update, %d delete", len(changes.Create), len(changes.UpdateNew), len(changes.Delete))
+}
+
+func countPhase(requests []unstructured.Unstructured, phase string) int {
+	n := 0
+	for i := range requests {
+		if Phase(&requests[i]) == phase {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakeDynamic "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// newFakeClient returns a dynamic client that generates object names.
+func newFakeClient() *fakeDynamic.FakeDynamicClient {
+	client := fakeDynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			DNSChangeRequestGVR: "DNSChangeRequestList",
+		})
+	n := 0
+	client.PrependReactor("create", DNSChangeRequestGVR.Resource, func(action clienttesting.Action) (bool, runtime.Object, error) {
+		obj := action.(clienttesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		if obj.GetName() == "" {
+			n++
+			obj.SetName(fmt.Sprintf("%s%d", obj.GetGenerateName(), n))
+		}
+		return false, nil, nil
+	})
+	return client
+}
+
+func listRequests(t *testing.T, r *Registry) map[string]string {
+	t.Helper()
+	requests, err := r.list(context.Background())
+	require.NoError(t, err)
+	phases := map[string]string{}
+	for i := range requests {
+		phases[requests[i].GetName()] = Phase(&requests[i])
+	}
+	return phases
+}
+
+func create(name string) *plan.Changes {
+	return &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint(name, endpoint.RecordTypeA, "1.2.3.4")}}
+}
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+	txt, err := registry.NewTXTRegistry(p, "", "", "test", 0, "", []string{endpoint.RecordTypeA}, nil, false, nil)
+	require.NoError(t, err)
+	client := newFakeClient()
+	r := NewRegistry(txt, client, "dns")
+
+	// The same changes are submitted once.
+	require.NoError(t, r.ApplyChanges(ctx, create("a.example.com")))
+	require.NoError(t, r.ApplyChanges(ctx, create("a.example.com")))
+	assert.Equal(t, map[string]string{"dns-1": PhasePending}, listRequests(t, r))
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	req, err := client.Resource(DNSChangeRequestGVR).Namespace("dns").Get(ctx, "dns-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "test", req.GetLabels()[OwnerLabel])
+	changes, err := RequestChanges(req)
+	require.NoError(t, err)
+	require.Len(t, changes.Create, 1)
+	assert.Equal(t, "a.example.com", changes.Create[0].DNSName)
+
+	// Nothing is applied until approved.
+	require.NoError(t, r.ApplyApproved(ctx))
+	assert.Equal(t, map[string]string{"dns-1": PhasePending}, listRequests(t, r))
+
+	require.NoError(t, unstructured.SetNestedField(req.Object, PhaseApproved, "status", "phase"))
+	require.NoError(t, unstructured.SetNestedField(req.Object, "alice", "status", "approvedBy"))
+	_, err = client.Resource(DNSChangeRequestGVR).Namespace("dns").UpdateStatus(ctx, req, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	// Approved changes are not submitted again.
+	require.NoError(t, r.ApplyChanges(ctx, create("a.example.com")))
+	require.NoError(t, r.ApplyApproved(ctx))
+	assert.Equal(t, map[string]string{"dns-1": PhaseApplied}, listRequests(t, r))
+
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "a.example.com", records[0].DNSName)
+	assert.Equal(t, "test", records[0].Labels[endpoint.OwnerLabelKey])
+
+	req, err = client.Resource(DNSChangeRequestGVR).Namespace("dns").Get(ctx, "dns-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	appliedTime, _, _ := unstructured.NestedString(req.Object, "status", "appliedTime")
+	assert.NotEmpty(t, appliedTime)
+	approvedBy, _, _ := unstructured.NestedString(req.Object, "status", "approvedBy")
+	assert.Equal(t, "alice", approvedBy)
+
+	// Newer changes supersede the pending request.
+	require.NoError(t, r.ApplyChanges(ctx, create("b.example.com")))
+	require.NoError(t, r.ApplyChanges(ctx, create("c.example.com")))
+	assert.Equal(t, map[string]string{"dns-1": PhaseApplied, "dns-2": PhaseSuperseded, "dns-3": PhasePending}, listRequests(t, r))
+	assert.Equal(t, 1.0, testutil.ToFloat64(pendingRequests), "the superseded request is not pending")
+}
+
+// approve approves the request.
+func approve(t *testing.T, client *fakeDynamic.FakeDynamicClient, name string) {
+	t.Helper()
+	ctx := context.Background()
+	req, err := client.Resource(DNSChangeRequestGVR).Namespace("dns").Get(ctx, name, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NoError(t, unstructured.SetNestedField(req.Object, PhaseApproved, "status", "phase"))
+	_, err = client.Resource(DNSChangeRequestGVR).Namespace("dns").UpdateStatus(ctx, req, metav1.UpdateOptions{})
+	require.NoError(t, err)
+}
+
+// reject rejects the request.
+func reject(t *testing.T, client *fakeDynamic.FakeDynamicClient, name string) {
+	t.Helper()
+	ctx := context.Background()
+	req, err := client.Resource(DNSChangeRequestGVR).Namespace("dns").Get(ctx, name, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NoError(t, unstructured.SetNestedField(req.Object, PhaseRejected, "status", "phase"))
+	_, err = client.Resource(DNSChangeRequestGVR).Namespace("dns").UpdateStatus(ctx, req, metav1.UpdateOptions{})
+	require.NoError(t, err)
+}
+
+func TestRejectedNotResubmitted(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+	noop, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	client := newFakeClient()
+	r := NewRegistry(noop, client, "dns")
+
+	require.NoError(t, r.ApplyChanges(ctx, create("a.example.com")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(pendingRequests))
+	reject(t, client, "dns-1")
+
+	// The controller plans the same changes again: they stay rejected.
+	require.NoError(t, r.ApplyChanges(ctx, create("a.example.com")))
+	assert.Equal(t, map[string]string{"dns-1": PhaseRejected}, listRequests(t, r))
+	assert.Equal(t, 0.0, testutil.ToFloat64(pendingRequests))
+	require.NoError(t, r.ApplyApproved(ctx))
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	// Different changes are submitted.
+	require.NoError(t, r.ApplyChanges(ctx, create("b.example.com")))
+	assert.Equal(t, map[string]string{"dns-1": PhaseRejected, "dns-2": PhasePending}, listRequests(t, r))
+	assert.Equal(t, 1.0, testutil.ToFloat64(pendingRequests))
+}
+
+func TestApplyApprovedStale(t *testing.T) {
+	ctx := context.Background()
+	a := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	for _, tc := range []struct {
+		name    string
+		changes *plan.Changes
+		// meanwhile are the changes applied by someone else before the approval.
+		meanwhile *plan.Changes
+		phase     string
+	}{
+		{
+			name:      "created",
+			changes:   create("b.example.com"),
+			meanwhile: create("b.example.com"),
+			phase:     PhaseStale,
+		},
+		{
+			name:      "updated",
+			changes:   &plan.Changes{Delete: []*endpoint.Endpoint{a}},
+			meanwhile: &plan.Changes{UpdateOld: []*endpoint.Endpoint{a}, UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.5")}},
+			phase:     PhaseStale,
+		},
+		{
+			name:      "deleted",
+			changes:   &plan.Changes{UpdateOld: []*endpoint.Endpoint{a}, UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.5")}},
+			meanwhile: &plan.Changes{Delete: []*endpoint.Endpoint{a}},
+			phase:     PhaseStale,
+		},
+		{
+			name:      "unrelated",
+			changes:   &plan.Changes{Delete: []*endpoint.Endpoint{a}},
+			meanwhile: create("c.example.com"),
+			phase:     PhaseApplied,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+			require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{a.DeepCopy()}}))
+			noop, err := registry.NewNoopRegistry(p)
+			require.NoError(t, err)
+			client := newFakeClient()
+			r := NewRegistry(noop, client, "dns")
+
+			require.NoError(t, r.ApplyChanges(ctx, tc.changes))
+			require.NoError(t, p.ApplyChanges(ctx, tc.meanwhile))
+			approve(t, client, "dns-1")
+
+			require.NoError(t, r.ApplyApproved(ctx))
+			assert.Equal(t, map[string]string{"dns-1": tc.phase}, listRequests(t, r))
+		})
+	}
+}
+
+func TestApplyApprovedFailure(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewFaultyProvider(inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"})), inmemory.FaultConfig{})
+	noop, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	client := newFakeClient()
+	r := NewRegistry(noop, client, "dns")
+
+	require.NoError(t, r.ApplyChanges(ctx, create("a.example.com")))
+	approve(t, client, "dns-1")
+	p.FailNext(inmemory.MethodApplyChanges, errors.New("provider unavailable"))
+
+	require.NoError(t, r.ApplyApproved(ctx))
+	req, err := client.Resource(DNSChangeRequestGVR).Namespace("dns").Get(ctx, "dns-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, PhaseFailed, Phase(req))
+	message, _, _ := unstructured.NestedString(req.Object, "status", "message")
+	assert.Equal(t, "provider unavailable", message)
+}
+
+func TestSpecChangesHash(t *testing.T) {
+	a := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	b := endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.5")
+	_, h1, err := specChanges(&plan.Changes{Create: []*endpoint.Endpoint{a, b}})
+	require.NoError(t, err)
+	_, h2, err := specChanges(&plan.Changes{Create: []*endpoint.Endpoint{b, a}})
+	require.NoError(t, err)
+	_, h3, err := specChanges(&plan.Changes{Delete: []*endpoint.Endpoint{b, a}})
+	require.NoError(t, err)
+	assert.Equal(t, h1, h2)
+	assert.NotEqual(t, h1, h3)
+	assert.Len(t, h1, 16)
+}
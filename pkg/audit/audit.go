@@ -0,0 +1,266 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit keeps a trail of the changes applied to a provider, as JSON
+// objects in a local directory or a GCS or S3 bucket, for post-incident
+// forensics of DNS changes.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// timeFormat is used in the object names, which sort chronologically.
+const timeFormat = "20060102T150405.000000000Z"
+
+var (
+	entriesTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "audit",
+			Name:      "entries_total",
+			Help:      "Number of change sets written to the audit trail.",
+		},
+	)
+	errorsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "audit",
+			Name:      "errors_total",
+			Help:      "Number of errors writing or pruning the audit trail.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(entriesTotal)
+	prometheus.MustRegister(errorsTotal)
+}
+
+// Store holds the audit objects. Names are slash separated paths.
+type Store interface {
+	Put(ctx context.Context, name string, data []byte) error
+	Get(ctx context.Context, name string) ([]byte, error)
+	// List returns the names with the prefix, sorted.
+	List(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// Entry is an applied change set. The records before the change are in
+// Changes.UpdateOld and Changes.Delete, the records after the change in
+// Changes.Create and Changes.UpdateNew.
+type Entry struct {
+	// ID is the name of the entry in the store, set when it is read.
+	ID    string    `json:"id,omitempty"`
+	Time  time.Time `json:"time"`
+	Actor string    `json:"actor,omitempty"`
+	// Resources are the source resources of the changed records, from the
+	// resource labels.
+	Resources []string      `json:"resources,omitempty"`
+	Changes   *plan.Changes `json:"changes"`
+	// Error is set if the provider failed to apply the changes.
+	Error string `json:"error,omitempty"`
+}
+
+// Recorder writes the entries to the store, and deletes them after the retention.
+type Recorder struct {
+	Store Store
+	// Actor identifies the writer - for example the owner ID or the pod name.
+	Actor string
+	// Retention is the age of the deleted entries - zero keeps them forever.
+	Retention time.Duration
+
+	seq atomic.Uint64
+	now func() time.Time
+}
+
+// NewRecorder returns a recorder writing to the store.
+func NewRecorder(store Store, actor string, retention time.Duration) *Recorder {
+	return &Recorder{Store: store, Actor: actor, Retention: retention, now: time.Now}
+}
+
+// Record writes the changes, with the error returned by the provider if any.
+func (r *Recorder) Record(ctx context.Context, changes *plan.Changes, applyErr error) error {
+	e := Entry{
+		Time:      r.now().UTC(),
+		Actor:     r.Actor,
+		Resources: resources(changes),
+		Changes:   changes,
+	}
+	if applyErr != nil {
+		e.Error = applyErr.Error()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	// Entries written in the same nanosecond, by the same actor, keep their order.
+	name := fmt.Sprintf("%s/%s-%s-%d.json", e.Time.Format("2006/01/02"), e.Time.Format(timeFormat), safeName(r.Actor), r.seq.Add(1))
+	if err := r.Store.Put(ctx, name, data); err != nil {
+		errorsTotal.Inc()
+		return err
+	}
+	entriesTotal.Inc()
+	return nil
+}
+
+// Prune deletes the entries older than the retention, returning the number of
+// deleted entries.
+func (r *Recorder) Prune(ctx context.Context) (int, error) {
+	if r.Retention <= 0 {
+		return 0, nil
+	}
+	names, err := r.Store.List(ctx, "")
+	if err != nil {
+		errorsTotal.Inc()
+		return 0, err
+	}
+	cutoff := r.now().Add(-r.Retention)
+	deleted := 0
+	for _, name := range names {
+		t, ok := entryTime(name)
+		if !ok || !t.Before(cutoff) {
+			continue
+		}
+		if err := r.Store.Delete(ctx, name); err != nil {
+			errorsTotal.Inc()
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// Run prunes the entries every interval, until the context is done.
+func (r *Recorder) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := r.Prune(ctx); err != nil {
+			log.Errorf("Failed to prune the audit trail: %v", err)
+		} else if n > 0 {
+			log.Infof("Pruned %d audit entries older than %s", n, r.Retention)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Query returns the entries written in [since, until), oldest first. A zero
+// until has no upper bound. If name is set, only the entries changing a record
+// with this DNS name are returned.
+func Query(ctx context.Context, store Store, since, until time.Time, name string) ([]Entry, error) {
+	names, err := store.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	var entries []Entry
+	for _, n := range names {
+		t, ok := entryTime(n)
+		if !ok || t.Before(since) || (!until.IsZero() && !t.Before(until)) {
+			continue
+		}
+		e, err := getEntry(ctx, store, n)
+		if err != nil {
+			return nil, err
+		}
+		if name != "" && !changesName(e.Changes, name) {
+			continue
+		}
+		entries = append(entries, *e)
+	}
+	return entries, nil
+}
+
+// getEntry reads the entry with the name.
+func getEntry(ctx context.Context, store Store, name string) (*Entry, error) {
+	data, err := store.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	e.ID = name
+	return &e, nil
+}
+
+// entryTime returns the time of an entry from its name.
+func entryTime(name string) (time.Time, bool) {
+	base := path.Base(name)
+	if len(base) < len(timeFormat) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(timeFormat, base[:len(timeFormat)])
+	return t, err == nil
+}
+
+func changesName(changes *plan.Changes, name string) bool {
+	if changes == nil {
+		return false
+	}
+	for _, eps := range [][]*endpoint.Endpoint{changes.Create, changes.UpdateOld, changes.UpdateNew, changes.Delete} {
+		for _, ep := range eps {
+			if strings.TrimSuffix(strings.ToLower(ep.DNSName), ".") == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func resources(changes *plan.Changes) []string {
+	seen := map[string]bool{}
+	var res []string
+	for _, eps := range [][]*endpoint.Endpoint{changes.Create, changes.UpdateOld, changes.UpdateNew, changes.Delete} {
+		for _, ep := range eps {
+			if r := ep.Labels[endpoint.ResourceLabelKey]; r != "" && !seen[r] {
+				seen[r] = true
+				res = append(res, r)
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// safeName replaces the characters not safe in object names.
+func safeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, s)
+}
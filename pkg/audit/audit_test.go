@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+func withResource(ep *endpoint.Endpoint, resource string) *endpoint.Endpoint {
+	ep.Labels[endpoint.ResourceLabelKey] = resource
+	return ep
+}
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	store := &DirStore{Dir: t.TempDir()}
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	r := NewRecorder(store, "cluster-1", 24*time.Hour)
+	r.now = func() time.Time { return now }
+
+	require.NoError(t, r.Record(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{withResource(endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"), "service/default/a")},
+	}, nil))
+	now = now.Add(time.Hour)
+	require.NoError(t, r.Record(ctx, &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{withResource(endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.5"), "ingress/default/b")},
+		UpdateNew: []*endpoint.Endpoint{withResource(endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.6"), "ingress/default/b")},
+	}, errors.New("throttled")))
+
+	names, err := store.List(ctx, "2024/06/01/")
+	require.NoError(t, err)
+	require.Len(t, names, 2)
+	assert.Regexp(t, `^2024/06/01/20240601T120000.000000000Z-cluster-1-1\.json$`, names[0])
+
+	entries, err := Query(ctx, store, time.Time{}, time.Time{}, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "cluster-1", entries[0].Actor)
+	assert.Equal(t, []string{"service/default/a"}, entries[0].Resources)
+	assert.Empty(t, entries[0].Error)
+	assert.Equal(t, "throttled", entries[1].Error)
+	assert.Equal(t, endpoint.Targets{"1.2.3.5"}, entries[1].Changes.UpdateOld[0].Targets)
+
+	entries, err = Query(ctx, store, time.Time{}, time.Time{}, "B.example.com.")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, []string{"ingress/default/b"}, entries[0].Resources)
+
+	entries, err = Query(ctx, store, now, time.Time{}, "")
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	entries, err = Query(ctx, store, time.Time{}, now, "")
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// The first entry expires.
+	now = now.Add(23*time.Hour + time.Minute)
+	n, err := r.Prune(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	names, err = store.List(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, names, 1)
+}
+
+func TestDirStoreMissing(t *testing.T) {
+	store := &DirStore{Dir: t.TempDir() + "/missing"}
+	names, err := store.List(context.Background(), "")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+	assert.NoError(t, store.Delete(context.Background(), "2024/06/01/x.json"))
+}
+
+func TestProvider(t *testing.T) {
+	ctx := context.Background()
+	store := &DirStore{Dir: t.TempDir()}
+	p := NewProvider(inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"})), NewRecorder(store, "test", 0))
+
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{}))
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")}}))
+	// Failed changes are recorded too.
+	assert.Error(t, p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")}}))
+
+	entries, err := Query(ctx, store, time.Time{}, time.Time{}, "a.example.com")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Empty(t, entries[0].Error)
+	assert.NotEmpty(t, entries[1].Error)
+}
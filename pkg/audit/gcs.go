@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	storage "google.golang.org/api/storage/v1"
+)
+
+// GCSStore keeps the objects in a GCS bucket, under an optional prefix.
+type GCSStore struct {
+	Service *storage.Service
+	Bucket  string
+	Prefix  string
+}
+
+func (s *GCSStore) key(name string) string {
+	return path.Join(s.Prefix, name)
+}
+
+func (s *GCSStore) Put(ctx context.Context, name string, data []byte) error {
+	obj := &storage.Object{Name: s.key(name), ContentType: "application/json"}
+	_, err := s.Service.Objects.Insert(s.Bucket, obj).Media(bytes.NewReader(data)).Context(ctx).Do()
+	return err
+}
+
+func (s *GCSStore) Get(ctx context.Context, name string) ([]byte, error) {
+	res, err := s.Service.Objects.Get(s.Bucket, s.key(name)).Context(ctx).Download()
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	return io.ReadAll(res.Body)
+}
+
+func (s *GCSStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	root := strings.TrimSuffix(s.Prefix, "/")
+	if root != "" {
+		root += "/"
+	}
+	err := s.Service.Objects.List(s.Bucket).Prefix(root+prefix).Pages(ctx, func(objs *storage.Objects) error {
+		for _, obj := range objs.Items {
+			names = append(names, strings.TrimPrefix(obj.Name, root))
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+func (s *GCSStore) Delete(ctx context.Context, name string) error {
+	return s.Service.Objects.Delete(s.Bucket, s.key(name)).Context(ctx).Do()
+}
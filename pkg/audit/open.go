@@ -0,0 +1,55 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	storage "google.golang.org/api/storage/v1"
+)
+
+// Open returns the store for a location - gs://BUCKET/PREFIX, s3://BUCKET/PREFIX,
+// file:///DIR or a local directory. The cloud stores use the default credentials.
+func Open(ctx context.Context, location string) (Store, error) {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme == "" {
+		return &DirStore{Dir: location}, nil
+	}
+	prefix := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "file":
+		return &DirStore{Dir: u.Path}, nil
+	case "s3":
+		sess, err := session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable})
+		if err != nil {
+			return nil, err
+		}
+		return &S3Store{Client: s3.New(sess), Bucket: u.Host, Prefix: prefix}, nil
+	case "gs":
+		svc, err := storage.NewService(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &GCSStore{Service: svc, Bucket: u.Host, Prefix: prefix}, nil
+	}
+	return nil, fmt.Errorf("unsupported audit location %q", location)
+}
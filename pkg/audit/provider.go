@@ -0,0 +1,50 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// Provider wraps a provider, recording the applied changes. Failing to write
+// the audit trail is logged and counted, without failing the apply.
+type Provider struct {
+	provider.Provider
+	Recorder *Recorder
+}
+
+// NewProvider returns a provider recording the changes applied to p.
+func NewProvider(p provider.Provider, r *Recorder) *Provider {
+	return &Provider{Provider: p, Recorder: r}
+}
+
+// ApplyChanges applies and records the changes, including failed ones.
+func (p *Provider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	err := p.Provider.ApplyChanges(ctx, changes)
+	if !changes.HasChanges() {
+		return err
+	}
+	if rerr := p.Recorder.Record(context.WithoutCancel(ctx), changes, err); rerr != nil {
+		log.Errorf("Failed to record changes in the audit trail: %v", rerr)
+	}
+	return err
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// DefaultRollbackHistory is the default number of change sets which can be
+// rolled back.
+const DefaultRollbackHistory = 10
+
+var rollbacksTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "external_dns",
+		Subsystem: "audit",
+		Name:      "rollbacks_total",
+		Help:      "Number of change sets rolled back, by result (applied, conflict, failed).",
+	},
+	[]string{"result"},
+)
+
+func init() {
+	prometheus.MustRegister(rollbacksTotal)
+}
+
+// ErrRollbackConflict is returned when the records of a change set changed
+// since it was applied.
+var ErrRollbackConflict = errors.New("the records changed since the change set was applied")
+
+// ErrChangeSetNotFound is returned for a change set which is not one of the
+// last applied ones.
+var ErrChangeSetNotFound = errors.New("change set not found")
+
+// Rollback reverts the change sets of the audit trail, applying their inverse
+// to the provider.
+type Rollback struct {
+	Store Store
+	// Provider is the provider the change sets were applied to, wrapped by
+	// the audit Provider so the rollbacks are recorded too.
+	Provider provider.Provider
+	// History is the number of the last applied change sets which can be
+	// rolled back: the older ones are likely to conflict with the newer ones.
+	History int
+}
+
+// NewRollback returns a Rollback of the last history change sets of the
+// store.
+func NewRollback(store Store, p provider.Provider, history int) *Rollback {
+	return &Rollback{Store: store, Provider: p, History: history}
+}
+
+// ChangeSets returns the last applied change sets which can be rolled back,
+// newest first. The change sets the provider failed to apply are skipped.
+func (r *Rollback) ChangeSets(ctx context.Context) ([]Entry, error) {
+	names, err := r.Store.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for i := len(names) - 1; i >= 0 && len(entries) < r.History; i-- {
+		if _, ok := entryTime(names[i]); !ok {
+			continue
+		}
+		e, err := getEntry(ctx, r.Store, names[i])
+		if err != nil {
+			return nil, err
+		}
+		if e.Error != "" || e.Changes == nil {
+			continue
+		}
+		entries = append(entries, *e)
+	}
+	return entries, nil
+}
+
+// Apply applies the inverse of the change set with the ID, after checking
+// that its records were not changed since. It returns the applied changes.
+func (r *Rollback) Apply(ctx context.Context, id string) (*plan.Changes, error) {
+	entries, err := r.ChangeSets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var entry *Entry
+	for i := range entries {
+		if entries[i].ID == id {
+			entry = &entries[i]
+			break
+		}
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("%w: %s is not one of the last %d applied change sets", ErrChangeSetNotFound, id, r.History)
+	}
+	inverse := Inverse(entry.Changes)
+	records, err := r.Provider.Records(ctx)
+	if err != nil {
+		rollbacksTotal.WithLabelValues("failed").Inc()
+		return nil, err
+	}
+	if err := validateRollback(inverse, records); err != nil {
+		rollbacksTotal.WithLabelValues("conflict").Inc()
+		return nil, fmt.Errorf("rolling back %s: %w", id, err)
+	}
+	if err := r.Provider.ApplyChanges(ctx, inverse); err != nil {
+		rollbacksTotal.WithLabelValues("failed").Inc()
+		return nil, fmt.Errorf("rolling back %s: %w", id, err)
+	}
+	rollbacksTotal.WithLabelValues("applied").Inc()
+	log.Infof("Rolled back the change set %s of %s applied at %s", id, entry.Actor, entry.Time)
+	return inverse, nil
+}
+
+// Inverse returns the changes reverting the changes: the created records are
+// deleted, the deleted ones created, and the updates reverted.
+func Inverse(changes *plan.Changes) *plan.Changes {
+	return &plan.Changes{
+		Create:    changes.Delete,
+		UpdateOld: changes.UpdateNew,
+		UpdateNew: changes.UpdateOld,
+		Delete:    changes.Create,
+	}
+}
+
+// validateRollback checks that the records to delete or update by the inverse
+// changes are the current ones, and that the records to create don't exist.
+func validateRollback(inverse *plan.Changes, records []*endpoint.Endpoint) error {
+	current := make(map[endpoint.EndpointKey]*endpoint.Endpoint, len(records))
+	for _, ep := range records {
+		current[ep.Key()] = ep
+	}
+	var conflicts []string
+	for _, ep := range append(append([]*endpoint.Endpoint{}, inverse.UpdateOld...), inverse.Delete...) {
+		if cur, ok := current[ep.Key()]; !ok || !cur.Targets.Same(ep.Targets) {
+			conflicts = append(conflicts, fmt.Sprintf("%s %s", ep.RecordType, ep.DNSName))
+		}
+	}
+	for _, ep := range inverse.Create {
+		if _, ok := current[ep.Key()]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("%s %s", ep.RecordType, ep.DNSName))
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return fmt.Errorf("%w: %s", ErrRollbackConflict, strings.Join(conflicts, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+func TestRollback(t *testing.T) {
+	ctx := context.Background()
+	store := &DirStore{Dir: t.TempDir()}
+	p := NewProvider(inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"})), NewRecorder(store, "test", 0))
+	a := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	a2 := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "5.6.7.8")
+	b := endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4")
+
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{a, b}}))
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{UpdateOld: []*endpoint.Endpoint{a}, UpdateNew: []*endpoint.Endpoint{a2}, Delete: []*endpoint.Endpoint{b}}))
+	// The failed change sets can't be rolled back.
+	require.Error(t, p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{a}}))
+
+	r := NewRollback(store, p, 2)
+	changeSets, err := r.ChangeSets(ctx)
+	require.NoError(t, err)
+	require.Len(t, changeSets, 2)
+	assert.Len(t, changeSets[0].Changes.UpdateNew, 1, "newest first")
+	first, second := changeSets[1].ID, changeSets[0].ID
+
+	// The records of the first change set were changed by the second.
+	_, err = r.Apply(ctx, first)
+	assert.ErrorIs(t, err, ErrRollbackConflict)
+	assert.ErrorContains(t, err, "A a.example.com, A b.example.com")
+
+	changes, err := r.Apply(ctx, second)
+	require.NoError(t, err)
+	require.Len(t, changes.Create, 1)
+	assert.Equal(t, "b.example.com", changes.Create[0].DNSName)
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a.example.com 1.2.3.4", "b.example.com 1.2.3.4"}, recordStrings(records))
+
+	// The rollback is recorded too, and can be rolled back.
+	changeSets, err = r.ChangeSets(ctx)
+	require.NoError(t, err)
+	require.Len(t, changeSets, 2)
+	assert.Equal(t, second, changeSets[1].ID)
+	_, err = r.Apply(ctx, changeSets[0].ID)
+	require.NoError(t, err)
+
+	// Only the last change sets can be rolled back.
+	_, err = r.Apply(ctx, first)
+	assert.ErrorIs(t, err, ErrChangeSetNotFound)
+}
+
+func TestInverse(t *testing.T) {
+	a := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	b := endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	c := endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "1.2.3.4")
+	c2 := endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "5.6.7.8")
+	assert.Equal(t, &plan.Changes{
+		Create:    []*endpoint.Endpoint{b},
+		UpdateOld: []*endpoint.Endpoint{c2},
+		UpdateNew: []*endpoint.Endpoint{c},
+		Delete:    []*endpoint.Endpoint{a},
+	}, Inverse(&plan.Changes{
+		Create:    []*endpoint.Endpoint{a},
+		UpdateOld: []*endpoint.Endpoint{c},
+		UpdateNew: []*endpoint.Endpoint{c2},
+		Delete:    []*endpoint.Endpoint{b},
+	}))
+}
+
+func recordStrings(records []*endpoint.Endpoint) []string {
+	var s []string
+	for _, ep := range records {
+		s = append(s, ep.DNSName+" "+ep.Targets.String())
+	}
+	return s
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package canary applies the changes to a canary zone first - the same names
+// under a test domain - and to the production zones only once the canary
+// records are served.
+//
+// A change of foo.example.org with the canary domain canary.example.net is
+// first applied to foo.example.org.canary.example.net. The canary zone is
+// managed by the same provider, and hidden from the records of the provider,
+// so the plans never see it.
+package canary
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+var appliesTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "external_dns",
+		Subsystem: "canary",
+		Name:      "applies_total",
+		Help:      "Number of changes applied to the canary zone, by result (verified, failed, aborted).",
+	},
+	[]string{"result"},
+)
+
+func init() {
+	prometheus.MustRegister(appliesTotal)
+}
+
+// Verifier waits until the changes are served, like verify.Verifier.
+type Verifier interface {
+	WaitChanges(ctx context.Context, changes *plan.Changes) error
+}
+
+// Provider applies the changes to the canary zone, waits until the Verifier
+// sees them served, then applies them to the production zones. The changes
+// whose canary fails are not applied to the production zones: ApplyChanges
+// returns a soft error, and the next syncs plan them again.
+type Provider struct {
+	provider.Provider
+	// Domain is the canary domain, appended to the names.
+	Domain   string
+	Verifier Verifier
+}
+
+// NewProvider returns a provider applying the changes to the canary domain
+// first.
+func NewProvider(p provider.Provider, domain string, v Verifier) *Provider {
+	return &Provider{Provider: p, Domain: strings.Trim(strings.ToLower(domain), "."), Verifier: v}
+}
+
+// Records returns the records of the provider, without the canary ones.
+func (p *Provider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	records, err := p.Provider.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	production := make([]*endpoint.Endpoint, 0, len(records))
+	for _, ep := range records {
+		if !p.isCanary(ep.DNSName) {
+			production = append(production, ep)
+		}
+	}
+	return production, nil
+}
+
+// ApplyChanges applies the changes to the canary zone, then to the production
+// zones once verified.
+func (p *Provider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	if !changes.HasChanges() {
+		return p.Provider.ApplyChanges(ctx, changes)
+	}
+	log := logging.For("canary")
+	canary, err := p.canaryChanges(ctx, changes)
+	if err != nil {
+		appliesTotal.WithLabelValues("failed").Inc()
+		return provider.NewSoftError(fmt.Errorf("listing the canary records: %w", err))
+	}
+	if err := p.Provider.ApplyChanges(ctx, canary); err != nil {
+		appliesTotal.WithLabelValues("failed").Inc()
+		return provider.NewSoftError(fmt.Errorf("applying the changes to the canary zone %s: %w", p.Domain, err))
+	}
+	if err := p.Verifier.WaitChanges(ctx, canary); err != nil {
+		appliesTotal.WithLabelValues("aborted").Inc()
+		log.Error("Canary changes not served, aborting the production changes", "domain", p.Domain, "error", err)
+		return provider.NewSoftError(fmt.Errorf("canary zone %s: %w", p.Domain, err))
+	}
+	appliesTotal.WithLabelValues("verified").Inc()
+	log.Debug("Canary changes served, applying the production changes", "domain", p.Domain)
+	return p.Provider.ApplyChanges(ctx, changes)
+}
+
+func (p *Provider) isCanary(name string) bool {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	return name == p.Domain || strings.HasSuffix(name, "."+p.Domain)
+}
+
+// canaryName returns the name of a record in the canary zone.
+func (p *Provider) canaryName(name string) string {
+	return strings.TrimSuffix(name, ".") + "." + p.Domain
+}
+
+func (p *Provider) canaryEndpoint(ep *endpoint.Endpoint) *endpoint.Endpoint {
+	c := ep.DeepCopy()
+	c.DNSName = p.canaryName(ep.DNSName)
+	return c
+}
+
+// canaryChanges returns the changes of the canary zone. The canary records may
+// be missing, or outdated, like after an aborted canary: the changes are
+// planned against the current canary records.
+func (p *Provider) canaryChanges(ctx context.Context, changes *plan.Changes) (*plan.Changes, error) {
+	records, err := p.Provider.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current := map[endpoint.EndpointKey]*endpoint.Endpoint{}
+	for _, ep := range records {
+		if p.isCanary(ep.DNSName) {
+			current[ep.Key()] = ep
+		}
+	}
+
+	canary := &plan.Changes{}
+	upsert := func(ep *endpoint.Endpoint) {
+		c := p.canaryEndpoint(ep)
+		if old, ok := current[c.Key()]; ok {
+			canary.UpdateOld = append(canary.UpdateOld, old)
+			canary.UpdateNew = append(canary.UpdateNew, c)
+		} else {
+			canary.Create = append(canary.Create, c)
+		}
+		delete(current, c.Key())
+	}
+	for _, ep := range changes.Create {
+		upsert(ep)
+	}
+	for _, ep := range changes.UpdateNew {
+		upsert(ep)
+	}
+	for _, ep := range changes.Delete {
+		key := p.canaryEndpoint(ep).Key()
+		if old, ok := current[key]; ok {
+			canary.Delete = append(canary.Delete, old)
+			delete(current, key)
+		}
+	}
+	return canary, nil
+}
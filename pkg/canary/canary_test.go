@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package canary
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+// fakeVerifier records the verified changes, and fails with err.
+type fakeVerifier struct {
+	verified []*plan.Changes
+	err      error
+}
+
+func (v *fakeVerifier) WaitChanges(_ context.Context, changes *plan.Changes) error {
+	v.verified = append(v.verified, changes)
+	return v.err
+}
+
+func newProvider(t *testing.T) (*Provider, *inmemory.InMemoryProvider, *fakeVerifier) {
+	t.Helper()
+	im := inmemory.NewInMemoryProvider()
+	require.NoError(t, im.CreateZone("example.com"))
+	require.NoError(t, im.CreateZone("canary.example.net"))
+	v := &fakeVerifier{}
+	return NewProvider(im, "Canary.Example.net.", v), im, v
+}
+
+func names(t *testing.T, p provider.Provider) []string {
+	t.Helper()
+	records, err := p.Records(context.Background())
+	require.NoError(t, err)
+	var names []string
+	for _, ep := range records {
+		names = append(names, ep.DNSName+" "+ep.Targets.String())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestApplyChanges(t *testing.T) {
+	p, im, v := newProvider(t)
+	ctx := context.Background()
+	a := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "10.0.0.1")
+
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{a}}))
+	assert.Equal(t, []string{"a.example.com 10.0.0.1", "a.example.com.canary.example.net 10.0.0.1"}, names(t, im))
+	assert.Equal(t, []string{"a.example.com 10.0.0.1"}, names(t, p), "the canary records are hidden")
+	require.Len(t, v.verified, 1)
+	assert.Equal(t, "a.example.com.canary.example.net", v.verified[0].Create[0].DNSName)
+	assert.Equal(t, "a.example.com", a.DNSName, "the changes are not modified")
+
+	// A failed verification aborts the production changes.
+	v.err = errors.New("not served")
+	b := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "10.0.0.2")
+	err := p.ApplyChanges(ctx, &plan.Changes{UpdateOld: []*endpoint.Endpoint{a}, UpdateNew: []*endpoint.Endpoint{b}})
+	assert.ErrorIs(t, err, provider.SoftError)
+	assert.Equal(t, []string{"a.example.com 10.0.0.1", "a.example.com.canary.example.net 10.0.0.2"}, names(t, im))
+
+	// The next sync plans the changes again, the canary record is already updated.
+	v.err = nil
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{UpdateOld: []*endpoint.Endpoint{a}, UpdateNew: []*endpoint.Endpoint{b}}))
+	assert.Equal(t, []string{"a.example.com 10.0.0.2", "a.example.com.canary.example.net 10.0.0.2"}, names(t, im))
+
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Delete: []*endpoint.Endpoint{b}}))
+	assert.Empty(t, names(t, im))
+}
+
+func TestApplyChangesMissingCanary(t *testing.T) {
+	p, im, v := newProvider(t)
+	ctx := context.Background()
+	a := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "10.0.0.1")
+	require.NoError(t, im.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{a}}))
+
+	// The records created before the canary have no canary record.
+	b := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "10.0.0.2")
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{UpdateOld: []*endpoint.Endpoint{a}, UpdateNew: []*endpoint.Endpoint{b}}))
+	assert.Equal(t, "a.example.com.canary.example.net", v.verified[0].Create[0].DNSName)
+	assert.Equal(t, []string{"a.example.com 10.0.0.2", "a.example.com.canary.example.net 10.0.0.2"}, names(t, im))
+
+	c := endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "10.0.0.3")
+	require.NoError(t, im.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{c}}))
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Delete: []*endpoint.Endpoint{c}}))
+	assert.Empty(t, v.verified[1].Delete)
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package chaos injects failures and delays into the calls of a real provider,
+// to check in a sandbox project that the controller converges: after the
+// failures stop, the next syncs apply the remaining changes and leave no zone
+// half-applied.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+// maxCalls is the number of calls kept by the inmemory.FaultyProvider, for
+// the debugging.
+const maxCalls = 100
+
+var injectedFaults = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "external_dns",
+		Subsystem: "chaos",
+		Name:      "injected_faults_total",
+		Help:      "Number of faults injected into the provider calls, by kind.",
+	},
+	[]string{"kind"},
+)
+
+func init() {
+	prometheus.MustRegister(injectedFaults)
+}
+
+// Config is the probability of each fault, from 0 to 1.
+type Config struct {
+	// RecordsErrorRate fails Records calls.
+	RecordsErrorRate float64
+	// ErrorRate fails ApplyChanges calls before any change is applied.
+	ErrorRate float64
+	// PartialRate applies the first half of the changes of each kind of an
+	// ApplyChanges call, then fails it - like a provider failing in the
+	// middle of a batch.
+	PartialRate float64
+	// MaxDelay delays each call by a random duration up to MaxDelay.
+	MaxDelay time.Duration
+}
+
+// Enabled returns whether the configuration injects any fault.
+func (c Config) Enabled() bool {
+	return c.RecordsErrorRate > 0 || c.ErrorRate > 0 || c.PartialRate > 0 || c.MaxDelay > 0
+}
+
+// Provider wraps a provider, injecting the faults of Config with an
+// inmemory.FaultyProvider. The injected failures are soft errors, so the
+// controller retries at the next sync.
+type Provider struct {
+	*inmemory.FaultyProvider
+}
+
+// NewProvider returns a provider injecting faults into the calls of p.
+func NewProvider(p provider.Provider, config Config) *Provider {
+	return &Provider{FaultyProvider: inmemory.NewFaultInjector(p, inmemory.FaultConfig{
+		Jitter:           config.MaxDelay,
+		RecordsErrorRate: config.RecordsErrorRate,
+		ApplyErrorRate:   config.ErrorRate,
+		PartialBatchRate: config.PartialRate,
+		MaxCalls:         maxCalls,
+	})}
+}
+
+// Records returns the records of the provider, or an injected error.
+func (p *Provider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	records, err := p.FaultyProvider.Records(ctx)
+	if errors.Is(err, inmemory.ErrInjected) {
+		injectedFaults.WithLabelValues("records").Inc()
+		logging.For("chaos").Warn("Injected Records failure")
+		return nil, provider.NewSoftError(err)
+	}
+	return records, err
+}
+
+// ApplyChanges applies the changes, a part of them or none, returning an
+// injected error for the last two.
+func (p *Provider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	if !changes.HasChanges() {
+		return p.FaultyProvider.Provider.ApplyChanges(ctx, changes)
+	}
+	err := p.FaultyProvider.ApplyChanges(ctx, changes)
+	log := logging.For("chaos")
+	switch {
+	case errors.Is(err, inmemory.ErrInjected):
+		injectedFaults.WithLabelValues("apply").Inc()
+		log.Warn("Injected ApplyChanges failure")
+	case errors.Is(err, inmemory.ErrPartialBatch):
+		injectedFaults.WithLabelValues("partial").Inc()
+		log.Warn("Injected partial ApplyChanges failure")
+	default:
+		return err
+	}
+	return provider.NewSoftError(err)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/controller"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+type staticSource struct {
+	endpoints []*endpoint.Endpoint
+}
+
+func (s *staticSource) Endpoints(context.Context) ([]*endpoint.Endpoint, error) {
+	var out []*endpoint.Endpoint
+	for _, ep := range s.endpoints {
+		out = append(out, endpoint.NewEndpoint(ep.DNSName, ep.RecordType, ep.Targets...))
+	}
+	return out, nil
+}
+
+func (s *staticSource) AddEventHandler(context.Context, func()) {}
+
+func desired(round int) []*endpoint.Endpoint {
+	var endpoints []*endpoint.Endpoint
+	for i := round; i < round+10; i++ {
+		endpoints = append(endpoints, endpoint.NewEndpoint(fmt.Sprintf("host-%d.example.com", i), endpoint.RecordTypeA, fmt.Sprintf("10.0.%d.%d", round, i)))
+	}
+	return endpoints
+}
+
+func names(endpoints []*endpoint.Endpoint) []string {
+	var out []string
+	for _, ep := range endpoints {
+		out = append(out, ep.DNSName+" "+ep.Targets.String())
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestConverges(t *testing.T) {
+	ctx := context.Background()
+	im := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+	p := NewProvider(im, Config{RecordsErrorRate: 0.2, ErrorRate: 0.2, PartialRate: 0.5})
+	r, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	src := &staticSource{}
+	ctrl := &controller.Controller{
+		Source:             src,
+		Registry:           r,
+		Policy:             &plan.SyncPolicy{},
+		ManagedRecordTypes: []string{endpoint.RecordTypeA},
+	}
+
+	failed := 0
+	for round := 0; round < 30; round++ {
+		// Create, update and delete records in each round.
+		src.endpoints = desired(round / 3)
+		if err := ctrl.RunOnce(ctx); err != nil {
+			failed++
+		}
+	}
+	assert.Positive(t, failed)
+
+	// Without faults, a sync applies the remaining changes.
+	ctrl.Registry, err = registry.NewNoopRegistry(im)
+	require.NoError(t, err)
+	require.NoError(t, ctrl.RunOnce(ctx))
+	records, err := im.Records(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, names(src.endpoints), names(records))
+}
+
+func TestSoftErrors(t *testing.T) {
+	ctx := context.Background()
+	p := NewProvider(inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"})), Config{RecordsErrorRate: 1, PartialRate: 1})
+
+	_, err := p.Records(ctx)
+	assert.ErrorIs(t, err, provider.SoftError)
+	err = p.ApplyChanges(ctx, &plan.Changes{Create: desired(0)})
+	assert.ErrorIs(t, err, provider.SoftError)
+	assert.ErrorIs(t, err, inmemory.ErrPartialBatch)
+	// The changes without any change are not failed.
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{}))
+
+	assert.False(t, Config{}.Enabled())
+	assert.True(t, Config{PartialRate: 0.1}.Enabled())
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package debug serves the debug endpoints of the admin servers, enabled by a
+// flag as they expose the internal state: /debug/pprof, the runtime profiles
+// for go tool pprof, and /debug/state, a JSON dump of the components - the
+// sizes of the informer caches, the records and the last plan - to diagnose
+// the memory growth of large clusters.
+//
+// The handlers are registered on the given mux only - net/http/pprof is not
+// imported, since it registers them on http.DefaultServeMux.
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/external-dns/pkg/logging"
+)
+
+// maxProfileDuration bounds the seconds of a CPU profile.
+const maxProfileDuration = 5 * time.Minute
+
+// Handler serves /debug/pprof and /debug/state.
+type Handler struct {
+	mu     sync.Mutex
+	states map[string]func() any
+}
+
+// AddState adds a component to /debug/state: state returns its JSON state.
+func (h *Handler) AddState(name string, state func() any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.states == nil {
+		h.states = map[string]func() any{}
+	}
+	h.states[name] = state
+}
+
+// Register adds the debug endpoints to the mux.
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", servePprof)
+	mux.HandleFunc("/debug/pprof/profile", serveCPUProfile)
+	mux.Handle("/debug/state", h)
+}
+
+// RuntimeState is the state of the Go runtime, always in /debug/state.
+type RuntimeState struct {
+	Goroutines   int    `json:"goroutines"`
+	HeapAlloc    uint64 `json:"heapAlloc"`
+	HeapInuse    uint64 `json:"heapInuse"`
+	HeapObjects  uint64 `json:"heapObjects"`
+	Sys          uint64 `json:"sys"`
+	NumGC        uint32 `json:"numGC"`
+	PauseTotalNs uint64 `json:"pauseTotalNs"`
+}
+
+func runtimeState() RuntimeState {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return RuntimeState{
+		Goroutines:   runtime.NumGoroutine(),
+		HeapAlloc:    m.HeapAlloc,
+		HeapInuse:    m.HeapInuse,
+		HeapObjects:  m.HeapObjects,
+		Sys:          m.Sys,
+		NumGC:        m.NumGC,
+		PauseTotalNs: m.PauseTotalNs,
+	}
+}
+
+// ServeHTTP serves the state of the runtime and the components as JSON.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	h.mu.Lock()
+	states := make(map[string]func() any, len(h.states))
+	for name, state := range h.states {
+		states[name] = state
+	}
+	h.mu.Unlock()
+
+	res := map[string]any{"runtime": runtimeState()}
+	for name, state := range states {
+		res[name] = state()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(res); err != nil {
+		logging.For("debug").Error("Failed to encode the debug state", "error", err)
+	}
+}
+
+// servePprof serves the index of the profiles at /debug/pprof/, and the
+// profile NAME at /debug/pprof/NAME - in the pprof format, or as text with
+// ?debug=1.
+func servePprof(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, "/debug/pprof/")
+	if name == "" {
+		profiles := pprof.Profiles()
+		sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name() < profiles[j].Name() })
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, p := range profiles {
+			fmt.Fprintf(w, "%s %d\n", p.Name(), p.Count())
+		}
+		fmt.Fprintln(w, "profile (CPU, ?seconds=30)")
+		return
+	}
+	p := pprof.Lookup(name)
+	if p == nil {
+		http.Error(w, "unknown profile "+name, http.StatusNotFound)
+		return
+	}
+	debug, _ := strconv.Atoi(req.URL.Query().Get("debug"))
+	if debug > 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	}
+	if name == "heap" && req.URL.Query().Get("gc") != "" {
+		runtime.GC()
+	}
+	if err := p.WriteTo(w, debug); err != nil {
+		logging.For("debug").Error("Failed to write the profile", "profile", name, "error", err)
+	}
+}
+
+// serveCPUProfile serves a CPU profile of ?seconds (30 by default).
+func serveCPUProfile(w http.ResponseWriter, req *http.Request) {
+	d := 30 * time.Second
+	if s := req.URL.Query().Get("seconds"); s != "" {
+		sec, err := strconv.Atoi(s)
+		if err != nil || sec <= 0 {
+			http.Error(w, "invalid seconds "+s, http.StatusBadRequest)
+			return
+		}
+		d = time.Duration(sec) * time.Second
+	}
+	if d > maxProfileDuration {
+		http.Error(w, fmt.Sprintf("the profile is limited to %s", maxProfileDuration), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="profile"`)
+	if err := pprof.StartCPUProfile(w); err != nil {
+		// A profile is already running.
+		w.Header().Del("Content-Disposition")
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	select {
+	case <-time.After(d):
+	case <-req.Context().Done():
+	}
+	pprof.StopCPUProfile()
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState(t *testing.T) {
+	h := &Handler{}
+	h.AddState("controller", func() any { return map[string]int{"records": 3} })
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/state", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var state struct {
+		Runtime    RuntimeState   `json:"runtime"`
+		Controller map[string]int `json:"controller"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+	assert.Positive(t, state.Runtime.Goroutines)
+	assert.Positive(t, state.Runtime.HeapAlloc)
+	assert.Equal(t, 3, state.Controller["records"])
+}
+
+func TestPprof(t *testing.T) {
+	mux := http.NewServeMux()
+	(&Handler{}).Register(mux)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+
+	w := get("/debug/pprof/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "goroutine ")
+	assert.Contains(t, w.Body.String(), "heap ")
+
+	w = get("/debug/pprof/heap?gc=1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+	assert.NotEmpty(t, w.Body.Bytes())
+
+	w = get("/debug/pprof/goroutine?debug=1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "TestPprof")
+
+	assert.Equal(t, http.StatusNotFound, get("/debug/pprof/missing").Code)
+
+	w = get("/debug/pprof/profile?seconds=1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Body.Bytes())
+	assert.Equal(t, http.StatusBadRequest, get("/debug/pprof/profile?seconds=x").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/debug/pprof/profile?seconds=3600").Code)
+}
+
+func TestNotRegisteredOnDefaultMux(t *testing.T) {
+	_, pattern := http.DefaultServeMux.Handler(httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	assert.Empty(t, pattern)
+}
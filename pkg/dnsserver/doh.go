@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnsserver
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DoHPath is the well-known path for DNS-over-HTTPS (RFC 8484).
+	DoHPath = "/dns-query"
+
+	dohMediaType = "application/dns-message"
+)
+
+// ServeHTTP implements RFC 8484 DNS-over-HTTPS, GET and POST.
+//
+// It is registered on the same mux as the webhook, so it shares the TLS and
+// authentication of the webhook server - clients in restricted networks can
+// resolve the internal names using only HTTPS.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf []byte
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		buf, err = base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+	case http.MethodPost:
+		if r.Header.Get("Content-Type") != dohMediaType {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		buf, err = io.ReadAll(io.LimitReader(r.Body, dns.MaxMsgSize))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil || len(buf) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	req := new(dns.Msg)
+	if err := req.Unpack(buf); err != nil {
+		log.Debugf("Failed to unpack DoH request: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	res := s.Resolve(r.Context(), req)
+	out, err := res.Pack()
+	if err != nil {
+		log.Errorf("Failed to pack DoH response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", dohMediaType)
+	if ttl, ok := minTTL(res); ok {
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", ttl))
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
+
+// minTTL returns the smallest TTL in the answer, used for HTTP caching.
+func minTTL(m *dns.Msg) (uint32, bool) {
+	if len(m.Answer) == 0 {
+		return 0, false
+	}
+	ttl := m.Answer[0].Header().Ttl
+	for _, rr := range m.Answer[1:] {
+		if rr.Header().Ttl < ttl {
+			ttl = rr.Header().Ttl
+		}
+	}
+	return ttl, true
+}
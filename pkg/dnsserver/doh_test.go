@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnsserver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoH(t *testing.T) {
+	s := newTestServer(t)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	req := new(dns.Msg)
+	req.SetQuestion("a.example.com.", dns.TypeA)
+	buf, err := req.Pack()
+	require.NoError(t, err)
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		var resp *http.Response
+		if method == http.MethodGet {
+			resp, err = http.Get(ts.URL + DoHPath + "?dns=" + base64.RawURLEncoding.EncodeToString(buf))
+		} else {
+			resp, err = http.Post(ts.URL+DoHPath, dohMediaType, bytes.NewReader(buf))
+		}
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, dohMediaType, resp.Header.Get("Content-Type"))
+		assert.Equal(t, "max-age=60", resp.Header.Get("Cache-Control"))
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+
+		res := new(dns.Msg)
+		require.NoError(t, res.Unpack(body))
+		assert.Len(t, res.Answer, 2)
+	}
+}
+
+func TestDoHBadRequest(t *testing.T) {
+	s := newTestServer(t)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + DoHPath)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Post(ts.URL+DoHPath, "application/json", bytes.NewReader([]byte("{}")))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnsserver
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/pkg/tlsutils"
+)
+
+// DoTAddress is the default listen address for DNS-over-TLS (RFC 7858).
+const DoTAddress = ":853"
+
+// ListenAndServeTLS starts a DNS-over-TLS listener, for mesh resolvers that
+// require encrypted upstream transport. The certificate is reloaded when the
+// files change, so rotation doesn't require a restart.
+//
+// It blocks until the context is canceled or the listener fails.
+func (s *Server) ListenAndServeTLS(ctx context.Context, addr, certFile, keyFile string) error {
+	certs, err := tlsutils.NewCertReloader(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.ServeTLS(ctx, l, certs)
+}
+
+// ServeTLS serves DNS-over-TLS on an existing listener.
+func (s *Server) ServeTLS(ctx context.Context, l net.Listener, certs *tlsutils.CertReloader) error {
+	// dns.Server only wraps the listener in TLS when it creates it.
+	srv := &dns.Server{
+		Net:        "tcp-tls",
+		Listener:   tls.NewListener(l, certs.TLSConfig()),
+		Handler:    s,
+		TsigSecret: s.tsigSecrets(),
+	}
+
+	go func() {
+		<-ctx.Done()
+		if err := srv.ShutdownContext(context.Background()); err != nil {
+			log.Debugf("DoT shutdown: %v", err)
+		}
+	}()
+
+	log.Infof("Starting DNS-over-TLS listener on %s", l.Addr())
+	return srv.ActivateAndServe()
+}
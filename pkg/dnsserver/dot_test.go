@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnsserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/pkg/tlsutils"
+)
+
+// writeCert writes a self-signed cert with the given common name.
+func writeCert(t *testing.T, dir, cn string, modTime time.Time) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{cn},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+	return certFile, keyFile
+}
+
+func peerCN(t *testing.T, addr string) string {
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func TestDoT(t *testing.T) {
+	s := newTestServer(t)
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "dns1.example.com", time.Now().Add(-time.Minute))
+
+	certs, err := tlsutils.NewCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.ServeTLS(ctx, l, certs)
+
+	c := &dns.Client{Net: "tcp-tls", TLSConfig: &tls.Config{InsecureSkipVerify: true}}
+	req := new(dns.Msg)
+	req.SetQuestion("a.example.com.", dns.TypeA)
+
+	var res *dns.Msg
+	require.Eventually(t, func() bool {
+		res, _, err = c.Exchange(req, l.Addr().String())
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Len(t, res.Answer, 2)
+	assert.Equal(t, "dns1.example.com", peerCN(t, l.Addr().String()))
+
+	// Rotate the certificate, new connections get the new one.
+	writeCert(t, dir, "dns2.example.com", time.Now())
+	assert.Equal(t, "dns2.example.com", peerCN(t, l.Addr().String()))
+}
+
+func TestCertReloaderKeepsValidCert(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "dns1.example.com", time.Now().Add(-time.Minute))
+
+	certs, err := tlsutils.NewCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, []byte("partial"), 0600))
+	cert, err := certs.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.NotNil(t, cert)
+
+	_, err = tlsutils.NewCertReloader(filepath.Join(dir, "missing.crt"), keyFile)
+	assert.Error(t, err)
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnsserver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// defaultJournalSize is the number of change sets kept for IXFR.
+const defaultJournalSize = 100
+
+// Journal keeps the recent change sets applied to the provider, with a serial
+// number for each. It is used to answer IXFR and as the SOA serial.
+//
+// It can be attached to the in-memory provider using OnApplyChanges:
+//
+//	p.OnApplyChanges = journal.OnApplyChanges
+//
+// or to any provider with Provider, like the provider of the controller.
+type Journal struct {
+	// MaxEntries is the number of change sets to keep - older serials get a full AXFR.
+	MaxEntries int
+
+	mu      sync.Mutex
+	serial  uint32
+	entries []journalEntry
+}
+
+type journalEntry struct {
+	// serial is the zone serial after the change was applied.
+	serial  uint32
+	changes *plan.Changes
+}
+
+// NewJournal creates a journal. The initial serial is based on the current time,
+// so it keeps increasing across restarts and secondaries don't get stuck.
+func NewJournal() *Journal {
+	return &Journal{
+		MaxEntries: defaultJournalSize,
+		serial:     uint32(time.Now().Unix()),
+	}
+}
+
+// Serial returns the current serial.
+func (j *Journal) Serial() uint32 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.serial
+}
+
+// Record adds a change set, returning the new serial.
+func (j *Journal) Record(changes *plan.Changes) uint32 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.serial++
+	j.entries = append(j.entries, journalEntry{serial: j.serial, changes: changes})
+	if len(j.entries) > j.MaxEntries {
+		j.entries = j.entries[len(j.entries)-j.MaxEntries:]
+	}
+	return j.serial
+}
+
+// Reset drops the change sets and bumps the serial, so the secondaries get a
+// full transfer - for the changes that may be partially applied.
+func (j *Journal) Reset() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.serial++
+	j.entries = nil
+}
+
+// OnApplyChanges records the changes, matching the in-memory provider hook.
+func (j *Journal) OnApplyChanges(_ context.Context, changes *plan.Changes) {
+	if !changes.HasChanges() {
+		return
+	}
+	j.Record(changes)
+}
+
+// Since returns the change sets applied after the given serial. Returns false
+// if the journal doesn't go back far enough.
+func (j *Journal) Since(serial uint32) ([]journalEntry, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if serial == j.serial {
+		return nil, true
+	}
+	for i, e := range j.entries {
+		if e.serial == serial+1 {
+			return append([]journalEntry(nil), j.entries[i:]...), true
+		}
+	}
+	return nil, false
+}
+
+// journalProvider records the changes applied to the provider in a Journal.
+type journalProvider struct {
+	provider.Provider
+	journal *Journal
+}
+
+// Provider returns p, recording the changes applied to it in the journal.
+func (j *Journal) Provider(p provider.Provider) provider.Provider {
+	return &journalProvider{Provider: p, journal: j}
+}
+
+// ApplyChanges applies and records the changes.
+func (p *journalProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	if err := p.Provider.ApplyChanges(ctx, changes); err != nil {
+		if changes.HasChanges() {
+			p.journal.Reset()
+		}
+		return err
+	}
+	p.journal.OnApplyChanges(ctx, changes)
+	return nil
+}
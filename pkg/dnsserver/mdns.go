@@ -0,0 +1,165 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnsserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// MDNSAddress is the IPv4 mDNS multicast group (RFC 6762).
+	MDNSAddress = "224.0.0.251:5353"
+
+	mdnsDomain = "local."
+	mdnsPort   = 5353
+	// cacheFlush is the top bit of the class in mDNS responses, marking the records as unique.
+	cacheFlush = 1 << 15
+)
+
+// MDNSResponder answers mDNS queries for NAME.local with the records of the Server
+// for NAME.DOMAIN, for each of the configured domains. For example with domain
+// "mesh.internal", "foo.local" resolves to the records of "foo.mesh.internal".
+//
+// This is useful for home-lab and edge deployments, where clients can't be
+// configured to use the server. Only A and AAAA records are advertised.
+type MDNSResponder struct {
+	Server *Server
+
+	// Domains are the internal domains advertised under .local, in order.
+	Domains []string
+
+	// Group is the address multicast responses are sent to.
+	Group *net.UDPAddr
+}
+
+// NewMDNSResponder returns a responder advertising the names in the domains.
+func NewMDNSResponder(s *Server, domains []string) *MDNSResponder {
+	group, _ := net.ResolveUDPAddr("udp4", MDNSAddress)
+	return &MDNSResponder{Server: s, Domains: domains, Group: group}
+}
+
+// ListenAndServe joins the mDNS group on the interface (nil for the system default)
+// and answers queries until the context is canceled.
+func (m *MDNSResponder) ListenAndServe(ctx context.Context, ifi *net.Interface) error {
+	conn, err := net.ListenMulticastUDP("udp4", ifi, m.Group)
+	if err != nil {
+		return err
+	}
+	log.Infof("Starting mDNS responder for %v", m.Domains)
+	return m.Serve(ctx, conn)
+}
+
+// Serve answers the queries received on conn.
+func (m *MDNSResponder) Serve(ctx context.Context, conn net.PacketConn) error {
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, dns.MaxMsgSize)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		req := new(dns.Msg)
+		if err := req.Unpack(buf[:n]); err != nil || req.Response || req.Opcode != dns.OpcodeQuery {
+			continue
+		}
+
+		// Queries from a port other than 5353 are 'legacy unicast' - answered directly,
+		// with the ID and question of the request (RFC 6762 section 6.7).
+		legacy := false
+		if udp, ok := from.(*net.UDPAddr); ok && udp.Port != mdnsPort {
+			legacy = true
+		}
+		answers := m.answer(ctx, req, !legacy)
+		if len(answers) == 0 {
+			continue
+		}
+
+		res := new(dns.Msg)
+		res.Response = true
+		res.Authoritative = true
+		res.Answer = answers
+		to := net.Addr(m.Group)
+		if legacy {
+			res.Id = req.Id
+			res.Question = req.Question
+			to = from
+		}
+		out, err := res.Pack()
+		if err != nil {
+			log.Debugf("Failed to pack mDNS response: %v", err)
+			continue
+		}
+		if _, err := conn.WriteTo(out, to); err != nil {
+			log.Debugf("Failed to send mDNS response to %s: %v", to, err)
+		}
+	}
+}
+
+// answer returns the A and AAAA records for the .local questions.
+func (m *MDNSResponder) answer(ctx context.Context, req *dns.Msg, multicast bool) []dns.RR {
+	var answers []dns.RR
+	for _, q := range req.Question {
+		if q.Qclass&^cacheFlush != dns.ClassINET {
+			continue
+		}
+		if q.Qtype != dns.TypeA && q.Qtype != dns.TypeAAAA && q.Qtype != dns.TypeANY {
+			continue
+		}
+		name := strings.ToLower(q.Name)
+		if !dns.IsSubDomain(mdnsDomain, name) || name == mdnsDomain {
+			continue
+		}
+		prefix := strings.TrimSuffix(name, mdnsDomain)
+
+		for _, d := range m.Domains {
+			r := new(dns.Msg)
+			r.SetQuestion(prefix+fqdn(d), q.Qtype)
+			res := m.Server.Resolve(ctx, r)
+			var found []dns.RR
+			for _, rr := range res.Answer {
+				t := rr.Header().Rrtype
+				if t != dns.TypeA && t != dns.TypeAAAA {
+					continue
+				}
+				rr = dns.Copy(rr)
+				rr.Header().Name = q.Name
+				if multicast {
+					rr.Header().Class |= cacheFlush
+				}
+				found = append(found, rr)
+			}
+			if len(found) > 0 {
+				answers = append(answers, found...)
+				break
+			}
+		}
+	}
+	return answers
+}
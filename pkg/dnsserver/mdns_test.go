@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnsserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMDNSAnswer(t *testing.T) {
+	m := NewMDNSResponder(newTestServer(t), []string{"other.org", "example.com"})
+
+	req := new(dns.Msg)
+	req.SetQuestion("A.local.", dns.TypeA)
+	res := m.answer(context.Background(), req, true)
+	require.Len(t, res, 2)
+	assert.Equal(t, "A.local.", res[0].Header().Name)
+	assert.Equal(t, uint16(dns.ClassINET|cacheFlush), res[0].Header().Class)
+
+	// CNAMEs are resolved, only the addresses are advertised.
+	req.SetQuestion("alias.local.", dns.TypeA)
+	res = m.answer(context.Background(), req, false)
+	require.Len(t, res, 2)
+	assert.Equal(t, dns.TypeA, res[0].Header().Rrtype)
+	assert.Equal(t, uint16(dns.ClassINET), res[0].Header().Class)
+
+	for _, q := range []dns.Question{
+		{Name: "a.example.com.", Qtype: dns.TypeA, Qclass: dns.ClassINET},
+		{Name: "txt.local.", Qtype: dns.TypeTXT, Qclass: dns.ClassINET},
+		{Name: "missing.local.", Qtype: dns.TypeA, Qclass: dns.ClassINET},
+		{Name: "local.", Qtype: dns.TypeA, Qclass: dns.ClassINET},
+	} {
+		req.Question = []dns.Question{q}
+		assert.Empty(t, m.answer(context.Background(), req, true), q.Name)
+	}
+}
+
+func TestMDNSLegacyUnicast(t *testing.T) {
+	m := NewMDNSResponder(newTestServer(t), []string{"example.com"})
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Serve(ctx, conn)
+
+	c := &dns.Client{Timeout: 5 * time.Second}
+	req := new(dns.Msg)
+	req.SetQuestion("a.local.", dns.TypeA)
+	res, _, err := c.Exchange(req, conn.LocalAddr().String())
+	require.NoError(t, err)
+	assert.Equal(t, req.Id, res.Id)
+	assert.True(t, res.Authoritative)
+	assert.Len(t, res.Answer, 2)
+}
@@ -0,0 +1,354 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnsserver
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+const (
+	// defaultTTL is used for records without a configured TTL, same as the Google provider.
+	defaultTTL = 300
+
+	// defaultRefreshInterval is the max age of the cached records before the provider is queried again.
+	defaultRefreshInterval = 10 * time.Second
+)
+
+// Server is a minimal authoritative DNS server answering from the records of a
+// provider. It is intended for mesh-internal names, where the 'provider' is
+// the in-memory provider or a webhook - clients that can't use the real DNS
+// zones can still resolve the names.
+//
+// Records are cached and refreshed at most once per RefreshInterval.
+type Server struct {
+	Provider provider.Provider
+
+	// RefreshInterval is the max age of the cached records.
+	RefreshInterval time.Duration
+
+	// Transfer enables AXFR and IXFR for secondaries. If nil, transfers are refused.
+	Transfer *TransferConfig
+
+	// Journal is used for IXFR and the SOA serial. Optional - without it
+	// secondaries always get a full transfer.
+	Journal *Journal
+
+	mu          sync.RWMutex
+	names       map[string][]*endpoint.Endpoint
+	zones       []string
+	lastRefresh time.Time
+	// digest identifies the served records: the SOA serial without a Journal
+	// is only bumped when they change.
+	digest        uint64
+	recordsSerial uint32
+	// journalSerial is the serial of the Journal when the records were read.
+	journalSerial uint32
+}
+
+// New returns a Server answering from the records of p.
+func New(p provider.Provider) *Server {
+	return &Server{
+		Provider:        p,
+		RefreshInterval: defaultRefreshInterval,
+	}
+}
+
+// ListenAndServe serves DNS on UDP and TCP - TCP for the zone transfers and
+// the large responses.
+//
+// It blocks until the context is canceled or a listener fails.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	var servers []*dns.Server
+	errc := make(chan error, 2)
+	for _, network := range []string{"udp", "tcp"} {
+		srv := &dns.Server{Addr: addr, Net: network, Handler: s, TsigSecret: s.tsigSecrets()}
+		servers = append(servers, srv)
+		go func() {
+			errc <- srv.ListenAndServe()
+		}()
+	}
+	log.Infof("Starting DNS listener on %s", addr)
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errc:
+	}
+	for _, srv := range servers {
+		if serr := srv.ShutdownContext(context.Background()); serr != nil {
+			log.Debugf("DNS shutdown: %v", serr)
+		}
+	}
+	return err
+}
+
+// Refresh reloads the records from the provider.
+func (s *Server) Refresh(ctx context.Context) error {
+	var journalSerial uint32
+	if s.Journal != nil {
+		journalSerial = s.Journal.Serial()
+	}
+	records, err := s.Provider.Records(ctx)
+	if err != nil {
+		return err
+	}
+	s.Update(records)
+	s.mu.Lock()
+	s.journalSerial = journalSerial
+	s.mu.Unlock()
+	return nil
+}
+
+// Update replaces the served records. Can be used without a provider, for
+// example with the endpoints computed by a source.
+func (s *Server) Update(records []*endpoint.Endpoint) {
+	names := map[string][]*endpoint.Endpoint{}
+	for _, ep := range records {
+		n := fqdn(ep.DNSName)
+		names[n] = append(names[n], ep)
+	}
+
+	var zones []string
+	if s.Provider != nil {
+		for _, z := range s.Provider.GetDomainFilter().Filters {
+			if z != "" {
+				zones = append(zones, fqdn(z))
+			}
+		}
+	}
+
+	d := digest(records)
+	s.mu.Lock()
+	if s.names == nil || d != s.digest {
+		s.recordsSerial = nextSerial(s.recordsSerial)
+	}
+	s.names = names
+	s.zones = zones
+	s.digest = d
+	s.lastRefresh = time.Now()
+	s.mu.Unlock()
+}
+
+// digest returns a hash of the records, independent of their order.
+func digest(records []*endpoint.Endpoint) uint64 {
+	lines := make([]string, 0, len(records))
+	for _, ep := range records {
+		lines = append(lines, ep.String())
+	}
+	sort.Strings(lines)
+	h := fnv.New64a()
+	for _, l := range lines {
+		h.Write([]byte(l))
+		h.Write([]byte{'\n'})
+	}
+	return h.Sum64()
+}
+
+// nextSerial returns the serial after serial - based on the current time for
+// the first one, so it keeps increasing across restarts.
+func nextSerial(serial uint32) uint32 {
+	if serial == 0 {
+		return uint32(time.Now().Unix())
+	}
+	return serial + 1
+}
+
+func (s *Server) maybeRefresh(ctx context.Context) {
+	if s.Provider == nil {
+		return
+	}
+	s.mu.RLock()
+	fresh := s.names != nil && time.Since(s.lastRefresh) < s.RefreshInterval
+	// The records are read again after the changes in the journal, so the
+	// served records match the serial.
+	if s.Journal != nil && s.Journal.Serial() != s.journalSerial {
+		fresh = false
+	}
+	s.mu.RUnlock()
+	if fresh {
+		return
+	}
+	if err := s.Refresh(ctx); err != nil {
+		// Keep serving the stale records.
+		log.Warnf("Failed to refresh DNS server records: %v", err)
+	}
+}
+
+// ServeDNS implements dns.Handler, for use with a dns.Server.
+func (s *Server) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	if len(req.Question) == 1 && (req.Question[0].Qtype == dns.TypeAXFR || req.Question[0].Qtype == dns.TypeIXFR) {
+		s.serveTransfer(w, req)
+		return
+	}
+	res := s.Resolve(context.Background(), req)
+	if err := w.WriteMsg(res); err != nil {
+		log.Debugf("Failed to write DNS response: %v", err)
+	}
+}
+
+// Resolve returns the response for a DNS request.
+func (s *Server) Resolve(ctx context.Context, req *dns.Msg) *dns.Msg {
+	res := new(dns.Msg)
+	res.SetReply(req)
+	res.Authoritative = true
+
+	if len(req.Question) != 1 {
+		res.Rcode = dns.RcodeFormatError
+		return res
+	}
+	q := req.Question[0]
+	if q.Qclass != dns.ClassINET {
+		res.Rcode = dns.RcodeRefused
+		return res
+	}
+
+	s.maybeRefresh(ctx)
+
+	name := strings.ToLower(q.Name)
+	if q.Qtype == dns.TypeSOA {
+		// Secondaries poll the SOA at the zone apex to detect changes.
+		serial := s.serial()
+		s.mu.RLock()
+		apex := isZone(s.zones, name)
+		s.mu.RUnlock()
+		if apex {
+			res.Answer = append(res.Answer, soa(name, serial))
+			return res
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Follow CNAMEs within the served records - limited to avoid loops.
+	for i := 0; i < 8; i++ {
+		eps, found := s.lookup(name)
+		if !found {
+			if len(res.Answer) == 0 {
+				if s.isAuthoritative(name) {
+					res.Rcode = dns.RcodeNameError
+				} else {
+					res.Authoritative = false
+					res.Rcode = dns.RcodeRefused
+				}
+			}
+			return res
+		}
+
+		var cname *endpoint.Endpoint
+		for _, ep := range eps {
+			if ep.RecordType == endpoint.RecordTypeCNAME && q.Qtype != dns.TypeCNAME {
+				cname = ep
+			}
+			if dns.StringToType[ep.RecordType] == q.Qtype || q.Qtype == dns.TypeANY {
+				res.Answer = append(res.Answer, toRRs(q.Name, ep)...)
+			}
+		}
+		if cname == nil || len(cname.Targets) == 0 {
+			return res
+		}
+		res.Answer = append(res.Answer, toRRs(name, cname)...)
+		name = fqdn(cname.Targets[0])
+	}
+	return res
+}
+
+// lookup returns the endpoints for the name, falling back to a wildcard
+// record in the parent domain.
+func (s *Server) lookup(name string) ([]*endpoint.Endpoint, bool) {
+	if eps, ok := s.names[name]; ok {
+		return eps, true
+	}
+	labels := dns.SplitDomainName(name)
+	if len(labels) < 2 {
+		return nil, false
+	}
+	eps, ok := s.names[fqdn("*."+strings.Join(labels[1:], "."))]
+	return eps, ok
+}
+
+// isAuthoritative returns true if the name is in one of the zones of the provider.
+// If the provider doesn't declare the zones, the server is authoritative for all names.
+func (s *Server) isAuthoritative(name string) bool {
+	if len(s.zones) == 0 {
+		return true
+	}
+	for _, z := range s.zones {
+		if dns.IsSubDomain(z, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// RRs returns the served records in the zone, as DNS resource records.
+func (s *Server) RRs(ctx context.Context, zone string) []dns.RR {
+	s.maybeRefresh(ctx)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	zone = fqdn(zone)
+	var rrs []dns.RR
+	for name, eps := range s.names {
+		if !dns.IsSubDomain(zone, name) {
+			continue
+		}
+		for _, ep := range eps {
+			rrs = append(rrs, toRRs(name, ep)...)
+		}
+	}
+	return rrs
+}
+
+// toRRs converts an endpoint to DNS resource records, one for each target.
+func toRRs(name string, ep *endpoint.Endpoint) []dns.RR {
+	ttl := int64(defaultTTL)
+	if ep.RecordTTL.IsConfigured() {
+		ttl = int64(ep.RecordTTL)
+	}
+
+	var rrs []dns.RR
+	for _, t := range ep.Targets {
+		if ep.RecordType == endpoint.RecordTypeTXT && !strings.HasPrefix(t, "\"") {
+			t = fmt.Sprintf("%q", t)
+		}
+		rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", name, ttl, ep.RecordType, t))
+		if err != nil || rr == nil {
+			log.Debugf("Skipping invalid record %s: %v", ep, err)
+			continue
+		}
+		rrs = append(rrs, rr)
+	}
+	return rrs
+}
+
+func fqdn(name string) string {
+	return dns.Fqdn(strings.ToLower(name))
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnsserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+func newTestServer(t *testing.T) *Server {
+	p := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+	err := p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpointWithTTL("a.example.com", endpoint.RecordTypeA, 60, "10.0.0.1", "10.0.0.2"),
+			endpoint.NewEndpoint("alias.example.com", endpoint.RecordTypeCNAME, "a.example.com"),
+			endpoint.NewEndpoint("*.wild.example.com", endpoint.RecordTypeA, "10.0.0.3"),
+			endpoint.NewEndpoint("txt.example.com", endpoint.RecordTypeTXT, "heritage=external-dns"),
+		},
+	})
+	require.NoError(t, err)
+	return New(p)
+}
+
+func query(s *Server, name string, qtype uint16) *dns.Msg {
+	req := new(dns.Msg)
+	req.SetQuestion(name, qtype)
+	return s.Resolve(context.Background(), req)
+}
+
+func TestResolve(t *testing.T) {
+	s := newTestServer(t)
+
+	res := query(s, "a.example.com.", dns.TypeA)
+	assert.Equal(t, dns.RcodeSuccess, res.Rcode)
+	assert.True(t, res.Authoritative)
+	require.Len(t, res.Answer, 2)
+	assert.Equal(t, uint32(60), res.Answer[0].Header().Ttl)
+
+	res = query(s, "A.Example.COM.", dns.TypeA)
+	assert.Len(t, res.Answer, 2)
+
+	res = query(s, "a.example.com.", dns.TypeAAAA)
+	assert.Equal(t, dns.RcodeSuccess, res.Rcode)
+	assert.Empty(t, res.Answer)
+
+	res = query(s, "alias.example.com.", dns.TypeA)
+	require.Len(t, res.Answer, 3)
+	assert.Equal(t, dns.TypeCNAME, res.Answer[0].Header().Rrtype)
+	assert.Equal(t, uint32(defaultTTL), res.Answer[0].Header().Ttl)
+
+	res = query(s, "foo.wild.example.com.", dns.TypeA)
+	require.Len(t, res.Answer, 1)
+	assert.Equal(t, "foo.wild.example.com.", res.Answer[0].Header().Name)
+
+	res = query(s, "txt.example.com.", dns.TypeTXT)
+	require.Len(t, res.Answer, 1)
+	assert.Equal(t, []string{"heritage=external-dns"}, res.Answer[0].(*dns.TXT).Txt)
+
+	res = query(s, "missing.example.com.", dns.TypeA)
+	assert.Equal(t, dns.RcodeNameError, res.Rcode)
+}
+
+// zonedProvider declares its zones in the domain filter, like the Google provider.
+type zonedProvider struct {
+	*inmemory.InMemoryProvider
+}
+
+func (zonedProvider) GetDomainFilter() endpoint.DomainFilter {
+	return endpoint.NewDomainFilter([]string{"example.com"})
+}
+
+func TestResolveNotAuthoritative(t *testing.T) {
+	s := New(zonedProvider{inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))})
+
+	res := query(s, "missing.example.com.", dns.TypeA)
+	assert.Equal(t, dns.RcodeNameError, res.Rcode)
+
+	res = query(s, "www.other.org.", dns.TypeA)
+	assert.Equal(t, dns.RcodeRefused, res.Rcode)
+	assert.False(t, res.Authoritative)
+}
+
+func TestRRs(t *testing.T) {
+	s := newTestServer(t)
+
+	rrs := s.RRs(context.Background(), "example.com")
+	assert.Len(t, rrs, 5)
+
+	rrs = s.RRs(context.Background(), "wild.example.com")
+	assert.Len(t, rrs, 1)
+}
+
+func TestSerial(t *testing.T) {
+	s := New(zonedProvider{inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))})
+	records := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "10.0.0.1"),
+		endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "10.0.0.2"),
+	}
+	s.Update(records)
+	serial := s.serial()
+
+	// The same records, in another order.
+	s.Update([]*endpoint.Endpoint{records[1], records[0]})
+	assert.Equal(t, serial, s.serial())
+
+	s.Update(records[:1])
+	assert.Equal(t, serial+1, s.serial())
+}
+
+func TestListenAndServe(t *testing.T) {
+	s := newTestServer(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() {
+		served <- s.ListenAndServe(ctx, addr)
+	}()
+
+	req := new(dns.Msg)
+	req.SetQuestion("a.example.com.", dns.TypeA)
+	for _, network := range []string{"udp", "tcp"} {
+		c := &dns.Client{Net: network}
+		var res *dns.Msg
+		require.Eventually(t, func() bool {
+			res, _, err = c.Exchange(req, addr)
+			return err == nil
+		}, 5*time.Second, 50*time.Millisecond, network)
+		assert.Len(t, res.Answer, 2, network)
+	}
+
+	cancel()
+	require.NoError(t, <-served)
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnsserver
+
+import (
+	"context"
+	"net"
+	"net/netip"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// TransferConfig allows secondaries to mirror the zones using AXFR and IXFR.
+type TransferConfig struct {
+	// Secondaries is the list of IPs or CIDRs allowed to transfer the zones.
+	// If empty, any client with a valid TSIG key is allowed.
+	Secondaries []string
+
+	// TSIGSecrets maps key names (FQDN) to base64 secrets. It must also be set as
+	// TsigSecret on the dns.Server, which verifies the signatures. If set, transfers
+	// without a valid signature are refused.
+	TSIGSecrets map[string]string
+}
+
+// tsigSecrets returns the TSIG secrets of the transfers, for the dns.Server
+// verifying the signatures.
+func (s *Server) tsigSecrets() map[string]string {
+	if s.Transfer == nil {
+		return nil
+	}
+	return s.Transfer.TSIGSecrets
+}
+
+// allowed checks the client address and TSIG status.
+func (tc *TransferConfig) allowed(w dns.ResponseWriter, req *dns.Msg) bool {
+	if len(tc.TSIGSecrets) > 0 {
+		if req.IsTsig() == nil || w.TsigStatus() != nil {
+			return false
+		}
+	}
+	if len(tc.Secondaries) == 0 {
+		return len(tc.TSIGSecrets) > 0
+	}
+	host, _, err := net.SplitHostPort(w.RemoteAddr().String())
+	if err != nil {
+		return false
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	for _, s := range tc.Secondaries {
+		if p, err := netip.ParsePrefix(s); err == nil {
+			if p.Contains(addr.Unmap()) {
+				return true
+			}
+		} else if a, err := netip.ParseAddr(s); err == nil && a == addr.Unmap() {
+			return true
+		}
+	}
+	return false
+}
+
+// serial returns the SOA serial - from the journal if available, otherwise
+// bumped when the served records change.
+func (s *Server) serial() uint32 {
+	if s.Journal != nil {
+		return s.Journal.Serial()
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.recordsSerial
+}
+
+// soa returns a synthesized SOA for the zone.
+func soa(zone string, serial uint32) *dns.SOA {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: defaultTTL},
+		Ns:      "ns." + zone,
+		Mbox:    "hostmaster." + zone,
+		Serial:  serial,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  defaultTTL,
+	}
+}
+
+// serveTransfer handles AXFR and IXFR requests.
+func (s *Server) serveTransfer(w dns.ResponseWriter, req *dns.Msg) {
+	q := req.Question[0]
+	zone := fqdn(q.Name)
+
+	if s.Transfer == nil || !s.Transfer.allowed(w, req) {
+		log.Infof("Refused zone transfer of %s to %s", zone, w.RemoteAddr())
+		res := new(dns.Msg)
+		res.SetRcode(req, dns.RcodeRefused)
+		w.WriteMsg(res)
+		return
+	}
+
+	ctx := context.Background()
+	s.maybeRefresh(ctx)
+	s.mu.RLock()
+	auth := s.isAuthoritative(zone) && (len(s.zones) == 0 || isZone(s.zones, zone))
+	s.mu.RUnlock()
+	if !auth {
+		res := new(dns.Msg)
+		res.SetRcode(req, dns.RcodeNotAuth)
+		w.WriteMsg(res)
+		return
+	}
+
+	// Serial read before the records - a change in between will result in a new transfer.
+	serial := s.serial()
+	var rrs []dns.RR
+	if q.Qtype == dns.TypeIXFR {
+		rrs = s.ixfr(zone, serial, req)
+	}
+	if rrs == nil {
+		rrs = append([]dns.RR{soa(zone, serial)}, s.RRs(ctx, zone)...)
+		rrs = append(rrs, soa(zone, serial))
+	}
+
+	ch := make(chan *dns.Envelope)
+	tr := new(dns.Transfer)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- tr.Out(w, req, ch)
+	}()
+	// Keep the messages reasonably sized.
+	for len(rrs) > 0 {
+		n := min(len(rrs), 100)
+		ch <- &dns.Envelope{RR: rrs[:n]}
+		rrs = rrs[n:]
+	}
+	close(ch)
+	if err := <-errc; err != nil {
+		log.Warnf("Zone transfer of %s to %s failed: %v", zone, w.RemoteAddr(), err)
+	}
+	w.Hijack()
+}
+
+// ixfr returns the incremental transfer (RFC 1995) from the serial in the
+// request, or nil if a full transfer is needed.
+func (s *Server) ixfr(zone string, serial uint32, req *dns.Msg) []dns.RR {
+	if s.Journal == nil || len(req.Ns) == 0 {
+		return nil
+	}
+	clientSOA, ok := req.Ns[0].(*dns.SOA)
+	if !ok {
+		return nil
+	}
+	entries, ok := s.Journal.Since(clientSOA.Serial)
+	if !ok {
+		return nil
+	}
+	if len(entries) == 0 {
+		// Up to date - single SOA.
+		return []dns.RR{soa(zone, serial)}
+	}
+
+	rrs := []dns.RR{soa(zone, serial)}
+	from := clientSOA.Serial
+	for _, e := range entries {
+		rrs = append(rrs, soa(zone, from))
+		rrs = append(rrs, zoneRRs(zone, e.changes.UpdateOld, e.changes.Delete)...)
+		rrs = append(rrs, soa(zone, e.serial))
+		rrs = append(rrs, zoneRRs(zone, e.changes.Create, e.changes.UpdateNew)...)
+		from = e.serial
+	}
+	return append(rrs, soa(zone, serial))
+}
+
+// zoneRRs converts the endpoints in the zone to RRs.
+func zoneRRs(zone string, lists ...[]*endpoint.Endpoint) []dns.RR {
+	var rrs []dns.RR
+	for _, eps := range lists {
+		for _, ep := range eps {
+			name := fqdn(ep.DNSName)
+			if dns.IsSubDomain(zone, name) {
+				rrs = append(rrs, toRRs(name, ep)...)
+			}
+		}
+	}
+	return rrs
+}
+
+func isZone(zones []string, name string) bool {
+	for _, z := range zones {
+		if z == name {
+			return true
+		}
+	}
+	return false
+}
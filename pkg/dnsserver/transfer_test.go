@@ -0,0 +1,172 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnsserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+const (
+	testKeyName = "xfr.example.com."
+	testSecret  = "c2VjcmV0LWtleS1mb3ItdHNpZw=="
+)
+
+// startTransferServer starts a TCP server with transfers enabled for localhost.
+func startTransferServer(t *testing.T) (*Server, *inmemory.InMemoryProvider, string) {
+	p := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+	journal := NewJournal()
+	p.OnApplyChanges = journal.OnApplyChanges
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "10.0.0.1"),
+			endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "10.0.0.2"),
+		},
+	}))
+
+	s := New(zonedProvider{p})
+	s.RefreshInterval = 0
+	s.Journal = journal
+	secrets := map[string]string{testKeyName: testSecret}
+	s.Transfer = &TransferConfig{Secondaries: []string{"127.0.0.0/8"}, TSIGSecrets: secrets}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &dns.Server{Listener: l, Handler: s, TsigSecret: secrets}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+	return s, p, l.Addr().String()
+}
+
+func transfer(t *testing.T, addr string, req *dns.Msg, sign bool) ([]dns.RR, error) {
+	tr := &dns.Transfer{}
+	if sign {
+		tr.TsigSecret = map[string]string{testKeyName: testSecret}
+		req.SetTsig(testKeyName, dns.HmacSHA256, 300, time.Now().Unix())
+	}
+	var c net.Conn
+	var err error
+	require.Eventually(t, func() bool {
+		c, err = net.Dial("tcp", addr)
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	tr.Conn = &dns.Conn{Conn: c}
+
+	ch, err := tr.In(req, addr)
+	if err != nil {
+		return nil, err
+	}
+	var rrs []dns.RR
+	for env := range ch {
+		if env.Error != nil {
+			return rrs, env.Error
+		}
+		rrs = append(rrs, env.RR...)
+	}
+	return rrs, nil
+}
+
+func TestAXFR(t *testing.T) {
+	s, _, addr := startTransferServer(t)
+
+	req := new(dns.Msg)
+	req.SetAxfr("example.com.")
+	rrs, err := transfer(t, addr, req, true)
+	require.NoError(t, err)
+	require.Len(t, rrs, 4)
+	assert.Equal(t, dns.TypeSOA, rrs[0].Header().Rrtype)
+	assert.Equal(t, s.Journal.Serial(), rrs[0].(*dns.SOA).Serial)
+	assert.Equal(t, dns.TypeSOA, rrs[3].Header().Rrtype)
+
+	// Unsigned requests are refused.
+	req = new(dns.Msg)
+	req.SetAxfr("example.com.")
+	_, err = transfer(t, addr, req, false)
+	assert.Error(t, err)
+
+	// Only the zone apex can be transferred.
+	req = new(dns.Msg)
+	req.SetAxfr("a.example.com.")
+	_, err = transfer(t, addr, req, true)
+	assert.Error(t, err)
+}
+
+func TestIXFR(t *testing.T) {
+	s, p, addr := startTransferServer(t)
+	from := s.Journal.Serial()
+
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeA, "10.0.0.3")},
+		Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "10.0.0.1")},
+	}))
+
+	req := new(dns.Msg)
+	req.SetIxfr("example.com.", from, "ns.example.com.", "hostmaster.example.com.")
+	rrs, err := transfer(t, addr, req, true)
+	require.NoError(t, err)
+	// SOA(new), SOA(old), deleted, SOA(new), added, SOA(new)
+	require.Len(t, rrs, 6)
+	assert.Equal(t, from, rrs[1].(*dns.SOA).Serial)
+	assert.Equal(t, "a.example.com.", rrs[2].Header().Name)
+	assert.Equal(t, "c.example.com.", rrs[4].Header().Name)
+	assert.Equal(t, from+1, rrs[5].(*dns.SOA).Serial)
+
+	// Unknown serial falls back to a full transfer.
+	req = new(dns.Msg)
+	req.SetIxfr("example.com.", from-10, "ns.example.com.", "hostmaster.example.com.")
+	rrs, err = transfer(t, addr, req, true)
+	require.NoError(t, err)
+	assert.Len(t, rrs, 4)
+
+	// The SOA at the apex reflects the journal.
+	res := query(s, "example.com.", dns.TypeSOA)
+	require.Len(t, res.Answer, 1)
+	assert.Equal(t, from+1, res.Answer[0].(*dns.SOA).Serial)
+}
+
+func TestJournalProvider(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+	journal := NewJournal()
+	jp := journal.Provider(p)
+	from := journal.Serial()
+
+	require.NoError(t, jp.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "10.0.0.1")},
+	}))
+	entries, ok := journal.Since(from)
+	require.True(t, ok)
+	assert.Len(t, entries, 1)
+
+	// A failed change set may be partially applied: the secondaries get a full transfer.
+	require.Error(t, jp.ApplyChanges(ctx, &plan.Changes{
+		Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("missing.example.com", endpoint.RecordTypeA, "10.0.0.2")},
+	}))
+	assert.Equal(t, from+2, journal.Serial())
+	_, ok = journal.Since(from)
+	assert.False(t, ok)
+}
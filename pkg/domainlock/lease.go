@@ -0,0 +1,168 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package domainlock serializes the changes of the controllers managing the
+// same domains, like two deployments with overlapping filters during a
+// migration, with a Lease per domain.
+package domainlock
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// leasePrefix prefixes the names of the Leases, followed by the domain.
+const leasePrefix = "external-dns."
+
+// LeaseLock locks each domain with a Lease in the namespace, held by Identity
+// while applying the changes of the domain. A Lease not renewed for Duration,
+// like the one of a crashed holder, is expired. The clocks of the holders are
+// expected to be in sync.
+type LeaseLock struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Identity  string
+	Duration  time.Duration
+}
+
+// NewLeaseLock returns a lock of the domains held by identity, with Leases in
+// the namespace.
+func NewLeaseLock(client kubernetes.Interface, namespace, identity string, duration time.Duration) *LeaseLock {
+	return &LeaseLock{Client: client, Namespace: namespace, Identity: identity, Duration: duration}
+}
+
+// Lock acquires the lock of the domain, and returns its holder and whether it
+// was acquired. The lock is not acquired while held by another holder, nor if
+// another holder released it after since, the time of the records the changes
+// were planned with - the changes of the other holder are planned again first.
+func (l *LeaseLock) Lock(ctx context.Context, domain string, since time.Time) (string, bool, error) {
+	leases := l.Client.CoordinationV1().Leases(l.Namespace)
+	now := metav1.NewMicroTime(time.Now())
+	seconds := int32(l.Duration.Seconds())
+	lease, err := leases.Get(ctx, leaseName(domain), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: leaseName(domain), Namespace: l.Namespace},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &l.Identity,
+				LeaseDurationSeconds: &seconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		_, err = leases.Create(ctx, lease, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			return l.holder(ctx, domain)
+		}
+		if err != nil {
+			return "", false, err
+		}
+		return l.Identity, true, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	if holder := holderOf(lease); holder != l.Identity && holder != "" {
+		if !expired(lease, now.Time) || renewedAfter(lease, since) {
+			return holder, false, nil
+		}
+		transitions := int32(1)
+		if lease.Spec.LeaseTransitions != nil {
+			transitions += *lease.Spec.LeaseTransitions
+		}
+		lease.Spec.LeaseTransitions = &transitions
+		lease.Spec.AcquireTime = &now
+	}
+	lease.Spec.HolderIdentity = &l.Identity
+	lease.Spec.LeaseDurationSeconds = &seconds
+	lease.Spec.RenewTime = &now
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	if apierrors.IsConflict(err) {
+		// Acquired by another holder since the Get.
+		return l.holder(ctx, domain)
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return l.Identity, true, nil
+}
+
+// Unlock releases the lock of the domain, if held by Identity. The Lease keeps
+// the holder and the time of the release, for Lock.
+func (l *LeaseLock) Unlock(ctx context.Context, domain string) error {
+	leases := l.Client.CoordinationV1().Leases(l.Namespace)
+	lease, err := leases.Get(ctx, leaseName(domain), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if holderOf(lease) != l.Identity {
+		return nil
+	}
+	now := metav1.NewMicroTime(time.Now())
+	released := int32(0)
+	lease.Spec.RenewTime = &now
+	lease.Spec.LeaseDurationSeconds = &released
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// holder returns the holder of the lock of the domain, acquired by another
+// holder.
+func (l *LeaseLock) holder(ctx context.Context, domain string) (string, bool, error) {
+	lease, err := l.Client.CoordinationV1().Leases(l.Namespace).Get(ctx, leaseName(domain), metav1.GetOptions{})
+	if err != nil {
+		return "", false, err
+	}
+	return holderOf(lease), false, nil
+}
+
+func holderOf(lease *coordinationv1.Lease) string {
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+// expired returns whether the lease was not renewed for its duration.
+func expired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	return !now.Before(lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second))
+}
+
+func renewedAfter(lease *coordinationv1.Lease, since time.Time) bool {
+	return lease.Spec.RenewTime != nil && lease.Spec.RenewTime.After(since)
+}
+
+// leaseName returns the name of the Lease of the domain, with the characters
+// not allowed in a name replaced.
+func leaseName(domain string) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(strings.Trim(domain, ".")))
+	return leasePrefix + name
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package domainlock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLeaseLock(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	a := NewLeaseLock(client, "default", "a", time.Minute)
+	b := NewLeaseLock(client, "default", "b", time.Minute)
+
+	since := time.Now().Add(-time.Second)
+	holder, acquired, err := a.Lock(ctx, "Example.org.", since)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, "a", holder)
+
+	lease, err := client.CoordinationV1().Leases("default").Get(ctx, "external-dns.example.org", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "a", *lease.Spec.HolderIdentity)
+
+	// Held by a, renewed by a.
+	holder, acquired, err = b.Lock(ctx, "example.org", since)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	assert.Equal(t, "a", holder)
+	_, acquired, err = a.Lock(ctx, "example.org", since)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// Released by a after the records of b were listed.
+	require.NoError(t, a.Unlock(ctx, "example.org"))
+	_, acquired, err = b.Lock(ctx, "example.org", since)
+	require.NoError(t, err)
+	assert.False(t, acquired, "the changes of b are planned without the changes of a")
+
+	holder, acquired, err = b.Lock(ctx, "example.org", time.Now())
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, "b", holder)
+
+	// a does not release the lock of b.
+	require.NoError(t, a.Unlock(ctx, "example.org"))
+	_, acquired, err = a.Lock(ctx, "example.org", time.Now())
+	require.NoError(t, err)
+	assert.False(t, acquired)
+}
+
+func TestLeaseLockExpired(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	a := NewLeaseLock(client, "default", "a", time.Minute)
+	b := NewLeaseLock(client, "default", "b", time.Minute)
+
+	_, acquired, err := a.Lock(ctx, "example.org", time.Now())
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	// a crashed while holding the lock.
+	leases := client.CoordinationV1().Leases("default")
+	lease, err := leases.Get(ctx, "external-dns.example.org", metav1.GetOptions{})
+	require.NoError(t, err)
+	renewed := metav1.NewMicroTime(time.Now().Add(-2 * time.Minute))
+	lease.Spec.RenewTime = &renewed
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	holder, acquired, err := b.Lock(ctx, "example.org", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, "b", holder)
+
+	lease, err = leases.Get(ctx, "external-dns.example.org", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), *lease.Spec.LeaseTransitions)
+}
+
+func TestLeaseName(t *testing.T) {
+	assert.Equal(t, "external-dns.example.org", leaseName("Example.org."))
+	assert.Equal(t, "external-dns.-.example.org", leaseName("*.example.org"))
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package failover switches the changes from a primary provider to a standby
+// secondary provider when the primary is persistently unavailable, for the
+// disaster recovery setups with two DNS vendors.
+//
+// The switch is one way: the controller stays on the secondary until it is
+// restarted, so records don't flap between the vendors while the primary is
+// unstable.
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+var (
+	primaryErrors = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "failover",
+			Name:      "primary_errors_total",
+			Help:      "Number of failed calls of the primary provider.",
+		},
+	)
+	secondaryActive = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "failover",
+			Name:      "secondary_active",
+			Help:      "1 if the changes are sent to the secondary provider, 0 otherwise.",
+		},
+	)
+	switches = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "failover",
+			Name:      "switches_total",
+			Help:      "Number of switches from the primary to the secondary provider.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(primaryErrors)
+	prometheus.MustRegister(secondaryActive)
+	prometheus.MustRegister(switches)
+}
+
+// Config tells when the primary is persistently unavailable: after Threshold
+// consecutive failed calls, the first one at least After ago.
+type Config struct {
+	Threshold int
+	After     time.Duration
+}
+
+// Provider sends the calls to the primary provider, or to the secondary one
+// after a failover.
+type Provider struct {
+	primary, secondary provider.Provider
+	config             Config
+
+	mu           sync.Mutex
+	failures     int
+	failingSince time.Time
+	failedOver   bool
+
+	// now is the clock of the failures, replaced in tests.
+	now func() time.Time
+}
+
+// NewProvider returns a provider failing over from primary to secondary.
+func NewProvider(primary, secondary provider.Provider, config Config) *Provider {
+	if config.Threshold < 1 {
+		config.Threshold = 1
+	}
+	return &Provider{
+		primary:   primary,
+		secondary: secondary,
+		config:    config,
+		now:       time.Now,
+	}
+}
+
+// FailedOver returns whether the calls are sent to the secondary provider.
+func (p *Provider) FailedOver() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.failedOver
+}
+
+// Records returns the records of the active provider.
+func (p *Provider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	var records []*endpoint.Endpoint
+	err := p.call(ctx, "Records", func(pr provider.Provider) error {
+		var err error
+		records, err = pr.Records(ctx)
+		return err
+	})
+	return records, err
+}
+
+// ApplyChanges applies the changes to the active provider.
+func (p *Provider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	return p.call(ctx, "ApplyChanges", func(pr provider.Provider) error {
+		return pr.ApplyChanges(ctx, changes)
+	})
+}
+
+// AdjustEndpoints adjusts the endpoints for the active provider.
+func (p *Provider) AdjustEndpoints(endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	return p.active().AdjustEndpoints(endpoints)
+}
+
+// GetDomainFilter returns the domain filter of the active provider.
+func (p *Provider) GetDomainFilter() endpoint.DomainFilter {
+	return p.active().GetDomainFilter()
+}
+
+func (p *Provider) active() provider.Provider {
+	if p.FailedOver() {
+		return p.secondary
+	}
+	return p.primary
+}
+
+// call calls the primary provider, counting its failures, and retries the call
+// on the secondary provider when failing over.
+func (p *Provider) call(ctx context.Context, method string, f func(provider.Provider) error) error {
+	if p.FailedOver() {
+		return f(p.secondary)
+	}
+	err := f(p.primary)
+	if err == nil {
+		p.mu.Lock()
+		p.failures = 0
+		p.mu.Unlock()
+		return nil
+	}
+	// The controller stopping is not a failure of the primary.
+	if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		return err
+	}
+	if !p.fail(method, err) {
+		return err
+	}
+	return f(p.secondary)
+}
+
+// fail counts a failure of the primary, and returns whether it fails over.
+func (p *Provider) fail(method string, err error) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	primaryErrors.Inc()
+	now := p.now()
+	if p.failures == 0 {
+		p.failingSince = now
+	}
+	p.failures++
+	log := logging.For("failover")
+	if p.failures < p.config.Threshold || now.Sub(p.failingSince) < p.config.After {
+		log.Warn("Primary provider call failed", "method", method, "failures", p.failures, "error", err)
+		return false
+	}
+	p.failedOver = true
+	secondaryActive.Set(1)
+	switches.Inc()
+	log.Error("Primary provider unavailable, failing over to the secondary provider", "method", method, "failures", p.failures, "since", p.failingSince, "error", err)
+	return true
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+var errDown = errors.New("primary down")
+
+func newProviders(t *testing.T) (*inmemory.FaultyProvider, *inmemory.InMemoryProvider) {
+	t.Helper()
+	primary := inmemory.NewInMemoryProvider()
+	require.NoError(t, primary.CreateZone("example.com"))
+	secondary := inmemory.NewInMemoryProvider()
+	require.NoError(t, secondary.CreateZone("example.com"))
+	return inmemory.NewFaultyProvider(primary, inmemory.FaultConfig{}), secondary
+}
+
+func create(name string) *plan.Changes {
+	return &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint(name, endpoint.RecordTypeA, "10.0.0.1")}}
+}
+
+func TestPrimary(t *testing.T) {
+	primary, secondary := newProviders(t)
+	p := NewProvider(primary, secondary, Config{Threshold: 3})
+	ctx := context.Background()
+
+	require.NoError(t, p.ApplyChanges(ctx, create("a.example.com")))
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	// Failures below the threshold are returned, and reset by a success.
+	primary.FailNext(inmemory.MethodRecords, errDown, errDown)
+	for i := 0; i < 2; i++ {
+		_, err := p.Records(ctx)
+		assert.ErrorIs(t, err, errDown)
+	}
+	_, err = p.Records(ctx)
+	require.NoError(t, err)
+	primary.FailNext(inmemory.MethodRecords, errDown, errDown)
+	for i := 0; i < 2; i++ {
+		_, err := p.Records(ctx)
+		assert.ErrorIs(t, err, errDown)
+	}
+	assert.False(t, p.FailedOver())
+
+	records, err = secondary.Records(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestFailover(t *testing.T) {
+	primary, secondary := newProviders(t)
+	p := NewProvider(primary, secondary, Config{Threshold: 2, After: time.Minute})
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	ctx := context.Background()
+	switchesBefore := testutil.ToFloat64(switches)
+
+	primary.FailNext(inmemory.MethodApplyChanges, errDown, errDown, errDown)
+	assert.ErrorIs(t, p.ApplyChanges(ctx, create("a.example.com")), errDown)
+	// The threshold is reached, but the failures are too recent.
+	assert.ErrorIs(t, p.ApplyChanges(ctx, create("a.example.com")), errDown)
+	assert.False(t, p.FailedOver())
+
+	// The call failing over is retried on the secondary.
+	now = now.Add(time.Minute)
+	require.NoError(t, p.ApplyChanges(ctx, create("a.example.com")))
+	assert.True(t, p.FailedOver())
+	assert.Equal(t, switchesBefore+1, testutil.ToFloat64(switches))
+	assert.Equal(t, 1.0, testutil.ToFloat64(secondaryActive))
+
+	// The later calls go to the secondary, even after the primary recovers.
+	require.NoError(t, p.ApplyChanges(ctx, create("b.example.com")))
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, []string{
+		inmemory.MethodApplyChanges, inmemory.MethodApplyChanges, inmemory.MethodApplyChanges,
+	}, primary.Methods())
+}
+
+func TestCanceled(t *testing.T) {
+	primary, secondary := newProviders(t)
+	p := NewProvider(primary, secondary, Config{Threshold: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	primary.FailNext(inmemory.MethodRecords, context.Canceled)
+	_, err := p.Records(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, p.FailedOver())
+}
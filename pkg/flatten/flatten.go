@@ -0,0 +1,459 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package flatten replaces the CNAME endpoints of the sources by the A and
+// AAAA records of their targets, for the names where a CNAME is not allowed,
+// like the apex of a zone. The domains are:
+//
+//	example.org      the CNAME of example.org itself
+//	*.example.org    the CNAMEs of example.org and all its subdomains
+//
+// With Zones, the CNAMEs at the apex of the zones are flattened too, emulating
+// the ALIAS records of the providers without them, like Cloud DNS.
+//
+// The targets are resolved at sync time and cached for their TTL, which
+// becomes the TTL of the flattened records unless the endpoint sets a lower
+// one. Run resolves the cached targets again when they expire, and triggers a
+// sync when their addresses change. With a Verifier, it also checks that the
+// flattened records are served with the current addresses.
+package flatten
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
+	"sigs.k8s.io/external-dns/pkg/verify"
+)
+
+const (
+	// DefaultMinTTL is the minimum time a resolution is cached.
+	DefaultMinTTL = 30 * time.Second
+
+	// maxChain is the maximum number of CNAMEs followed from a target.
+	maxChain = 8
+)
+
+var (
+	resolutionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "flatten",
+			Name:      "resolutions_total",
+			Help:      "Number of resolutions of flattened CNAME targets, by result (success, error).",
+		},
+		[]string{"result"},
+	)
+	staleRecords = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "flatten",
+			Name:      "stale_records",
+			Help:      "Number of flattened records not served with the current addresses of their targets.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(resolutionsTotal)
+	prometheus.MustRegister(staleRecords)
+}
+
+// Domain selects the CNAMEs to flatten.
+type Domain struct {
+	Name string
+	// Subdomains flattens the CNAMEs of all the names of the domain, not only
+	// its apex.
+	Subdomains bool
+}
+
+// ParseDomains parses domains in the DOMAIN or *.DOMAIN format.
+func ParseDomains(specs []string) ([]Domain, error) {
+	var domains []Domain
+	for _, spec := range specs {
+		d := Domain{Name: spec}
+		if strings.HasPrefix(spec, "*.") {
+			d = Domain{Name: strings.TrimPrefix(spec, "*."), Subdomains: true}
+		}
+		d.Name = strings.TrimSuffix(strings.ToLower(d.Name), ".")
+		if d.Name == "" || strings.Contains(d.Name, "*") {
+			return nil, fmt.Errorf("invalid domain %q, expected DOMAIN or *.DOMAIN", spec)
+		}
+		domains = append(domains, d)
+	}
+	return domains, nil
+}
+
+// matches returns whether the CNAME of the name is flattened.
+func (d Domain) matches(name string) bool {
+	return name == d.Name || d.Subdomains && strings.HasSuffix(name, "."+d.Name)
+}
+
+// resolution are the addresses of a target.
+type resolution struct {
+	a, aaaa []string
+	ttl     uint32
+	expires time.Time
+}
+
+// Flattener is a source.Transformer flattening the CNAMEs of the domains.
+type Flattener struct {
+	Domains []Domain
+	// Resolver is the recursive DNS server resolving the targets, as HOST:PORT.
+	Resolver string
+	Client   *dns.Client
+	// MinTTL is the minimum time a resolution is cached.
+	MinTTL time.Duration
+	// Zones returns the zones whose apex CNAMEs are flattened, if set.
+	Zones func() []string
+	// Verifier checks the flattened records after each refresh, if set.
+	Verifier *verify.Verifier
+
+	now   func() time.Time
+	mu    sync.Mutex
+	cache map[string]*resolution
+	// cnames are the flattened CNAMEs of the last sync.
+	cnames []*endpoint.Endpoint
+}
+
+// New returns a Flattener of the domains using the resolver, in HOST[:PORT]
+// format. An empty resolver is the first nameserver of /etc/resolv.conf.
+func New(domains []Domain, resolver string) (*Flattener, error) {
+	if resolver == "" {
+		conf, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+		if err != nil {
+			return nil, fmt.Errorf("reading the system resolver: %w", err)
+		}
+		if len(conf.Servers) == 0 {
+			return nil, fmt.Errorf("no nameserver in /etc/resolv.conf")
+		}
+		resolver = net.JoinHostPort(conf.Servers[0], conf.Port)
+	} else if !strings.Contains(resolver, ":") || strings.HasSuffix(resolver, "]") {
+		resolver += ":53"
+	}
+	return &Flattener{
+		Domains:  domains,
+		Resolver: resolver,
+		Client:   &dns.Client{Timeout: 5 * time.Second},
+		MinTTL:   DefaultMinTTL,
+		now:      time.Now,
+		cache:    map[string]*resolution{},
+	}, nil
+}
+
+// flattened returns whether the CNAME of the name is flattened, with the
+// apexes of the zones.
+func (f *Flattener) flattened(name string, apexes map[string]bool) bool {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	if apexes[name] {
+		return true
+	}
+	for _, d := range f.Domains {
+		if d.matches(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// apexes returns the apexes of the zones.
+func (f *Flattener) apexes() map[string]bool {
+	apexes := map[string]bool{}
+	if f.Zones == nil {
+		return apexes
+	}
+	for _, z := range f.Zones() {
+		if z = strings.TrimSuffix(strings.ToLower(z), "."); z != "" {
+			apexes[z] = true
+		}
+	}
+	return apexes
+}
+
+// Transform replaces the flattened CNAMEs by A and AAAA endpoints. A target
+// that can't be resolved fails the sync, unless it was resolved before.
+func (f *Flattener) Transform(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	apexes := f.apexes()
+	result := make([]*endpoint.Endpoint, 0, len(endpoints))
+	var cnames []*endpoint.Endpoint
+	for _, ep := range endpoints {
+		if ep.RecordType != endpoint.RecordTypeCNAME || !f.flattened(ep.DNSName, apexes) {
+			result = append(result, ep)
+			continue
+		}
+		flat, err := f.flatten(ctx, ep)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, flat...)
+		cnames = append(cnames, ep)
+	}
+	f.mu.Lock()
+	f.cnames = cnames
+	f.mu.Unlock()
+	return result, nil
+}
+
+// flatten returns the A and AAAA endpoints of the CNAME.
+func (f *Flattener) flatten(ctx context.Context, ep *endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	var a, aaaa []string
+	var ttl uint32
+	for _, target := range ep.Targets {
+		r, err := f.resolution(ctx, target)
+		if err != nil {
+			return nil, fmt.Errorf("flattening the CNAME %s: %w", ep.DNSName, err)
+		}
+		a = append(a, r.a...)
+		aaaa = append(aaaa, r.aaaa...)
+		if ttl == 0 || r.ttl < ttl {
+			ttl = r.ttl
+		}
+	}
+	if ep.RecordTTL.IsConfigured() && uint32(ep.RecordTTL) < ttl {
+		ttl = uint32(ep.RecordTTL)
+	}
+	var result []*endpoint.Endpoint
+	if len(a) > 0 {
+		result = append(result, flattenedEndpoint(ep, endpoint.RecordTypeA, ttl, a))
+	}
+	if len(aaaa) > 0 {
+		result = append(result, flattenedEndpoint(ep, endpoint.RecordTypeAAAA, ttl, aaaa))
+	}
+	return result, nil
+}
+
+// flattenedEndpoint returns an endpoint of the CNAME with the addresses.
+func flattenedEndpoint(ep *endpoint.Endpoint, recordType string, ttl uint32, addresses []string) *endpoint.Endpoint {
+	flat := ep.DeepCopy()
+	flat.RecordType = recordType
+	flat.RecordTTL = endpoint.TTL(ttl)
+	flat.Targets = endpoint.NewTargets(dedup(addresses)...)
+	return flat
+}
+
+// resolution returns the cached resolution of the target, resolving it again
+// once expired.
+func (f *Flattener) resolution(ctx context.Context, target string) (*resolution, error) {
+	target = strings.TrimSuffix(strings.ToLower(target), ".")
+	f.mu.Lock()
+	cached := f.cache[target]
+	f.mu.Unlock()
+	if cached != nil && f.now().Before(cached.expires) {
+		return cached, nil
+	}
+	r, err := f.resolve(ctx, target)
+	if err != nil {
+		if cached != nil {
+			logging.For("flatten").Warn("Keeping the expired addresses", "target", target, "error", err)
+			return cached, nil
+		}
+		return nil, err
+	}
+	f.store(target, cached, r)
+	return r, nil
+}
+
+// store caches the resolution of the target. The TTL of unchanged addresses is
+// kept, so a resolver counting down its cached TTL doesn't change the records.
+func (f *Flattener) store(target string, previous, r *resolution) bool {
+	changed := previous == nil || !equal(previous.a, r.a) || !equal(previous.aaaa, r.aaaa)
+	if !changed {
+		r.ttl = previous.ttl
+	}
+	f.mu.Lock()
+	f.cache[target] = r
+	f.mu.Unlock()
+	return changed
+}
+
+// resolve returns the addresses of the target.
+func (f *Flattener) resolve(ctx context.Context, target string) (*resolution, error) {
+	r := &resolution{}
+	var err error
+	if r.a, r.ttl, err = f.lookup(ctx, target, dns.TypeA); err == nil {
+		var ttl uint32
+		r.aaaa, ttl, err = f.lookup(ctx, target, dns.TypeAAAA)
+		if len(r.a) == 0 || len(r.aaaa) > 0 && ttl < r.ttl {
+			r.ttl = ttl
+		}
+	}
+	if err == nil && len(r.a) == 0 && len(r.aaaa) == 0 {
+		err = fmt.Errorf("%s has no A or AAAA records", target)
+	}
+	if err != nil {
+		resolutionsTotal.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	resolutionsTotal.WithLabelValues("success").Inc()
+	minTTL := uint32(f.MinTTL / time.Second)
+	if r.ttl < minTTL {
+		r.ttl = minTTL
+	}
+	r.expires = f.now().Add(time.Duration(r.ttl) * time.Second)
+	return r, nil
+}
+
+// lookup returns the sorted answers of the type, following the CNAMEs of the
+// name, and the lowest TTL of the chain.
+func (f *Flattener) lookup(ctx context.Context, name string, qtype uint16) ([]string, uint32, error) {
+	name = dns.Fqdn(name)
+	var ttl uint32
+	for i := 0; i < maxChain; i++ {
+		req := new(dns.Msg)
+		req.SetQuestion(name, qtype)
+		res, _, err := f.Client.ExchangeContext(ctx, req, f.Resolver)
+		if err != nil {
+			return nil, 0, err
+		}
+		if res.Rcode != dns.RcodeSuccess && res.Rcode != dns.RcodeNameError {
+			return nil, 0, fmt.Errorf("%s: %s", f.Resolver, dns.RcodeToString[res.Rcode])
+		}
+		var answers []string
+		next := ""
+		for _, rr := range res.Answer {
+			if ttl == 0 || rr.Header().Ttl < ttl {
+				ttl = rr.Header().Ttl
+			}
+			switch rr := rr.(type) {
+			case *dns.A:
+				answers = append(answers, rr.A.String())
+			case *dns.AAAA:
+				answers = append(answers, rr.AAAA.String())
+			case *dns.CNAME:
+				next = rr.Target
+			}
+		}
+		// A recursive resolver answers the whole chain, an authoritative one
+		// only its CNAME.
+		if len(answers) > 0 || next == "" {
+			sort.Strings(answers)
+			return answers, ttl, nil
+		}
+		name = next
+	}
+	return nil, 0, fmt.Errorf("more than %d CNAMEs from %s", maxChain, name)
+}
+
+// Run resolves the cached targets again once expired, and calls onChange when
+// the addresses of one changed, until the context is done. The flattened
+// records are verified after each refresh.
+func (f *Flattener) Run(ctx context.Context, onChange func()) {
+	ticker := time.NewTicker(f.MinTTL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if f.refresh(ctx) {
+				onChange()
+			}
+			f.verify(ctx)
+		}
+	}
+}
+
+// verify checks that the flattened records of the last sync are served with
+// the current addresses of their targets, and returns the number of stale
+// records. A record changed since the last sync is stale until the next one.
+func (f *Flattener) verify(ctx context.Context) int {
+	if f.Verifier == nil {
+		return 0
+	}
+	f.mu.Lock()
+	cnames := f.cnames
+	f.mu.Unlock()
+
+	stale := 0
+	for _, cname := range cnames {
+		flat, err := f.flatten(ctx, cname)
+		if err != nil {
+			continue
+		}
+		for _, ep := range flat {
+			for _, res := range f.Verifier.Check(ctx, ep, false) {
+				if res.Error == "" && !res.Match {
+					logging.For("flatten").Warn("Stale flattened record", "endpoint", ep.DNSName, "type", ep.RecordType, "resolver", res.Resolver, "expected", res.Expected, "actual", res.Actual)
+					stale++
+					break
+				}
+			}
+		}
+	}
+	staleRecords.Set(float64(stale))
+	return stale
+}
+
+// refresh resolves the expired targets again, and returns whether the
+// addresses of one changed.
+func (f *Flattener) refresh(ctx context.Context) bool {
+	now := f.now()
+	expired := map[string]*resolution{}
+	f.mu.Lock()
+	for target, r := range f.cache {
+		if !now.Before(r.expires) {
+			expired[target] = r
+		}
+	}
+	f.mu.Unlock()
+
+	changed := false
+	for target, previous := range expired {
+		r, err := f.resolve(ctx, target)
+		if err != nil {
+			logging.For("flatten").Warn("Failed to resolve the target again", "target", target, "error", err)
+			continue
+		}
+		if f.store(target, previous, r) {
+			logging.For("flatten").Info("The addresses of the target changed", "target", target, "a", r.a, "aaaa", r.aaaa)
+			changed = true
+		}
+	}
+	return changed
+}
+
+func dedup(values []string) []string {
+	seen := map[string]bool{}
+	result := values[:0:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flatten
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/dnsserver"
+	"sigs.k8s.io/external-dns/pkg/verify"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+// startResolver serves the records of the provider over UDP.
+func startResolver(t *testing.T, p *inmemory.InMemoryProvider) string {
+	s := dnsserver.New(p)
+	s.RefreshInterval = 0
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &dns.Server{PacketConn: pc, Handler: s}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+func TestParseDomains(t *testing.T) {
+	domains, err := ParseDomains([]string{"Example.org.", "*.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, []Domain{{Name: "example.org"}, {Name: "example.com", Subdomains: true}}, domains)
+
+	for _, spec := range []string{"", "*.", "a.*.example.org"} {
+		_, err := ParseDomains([]string{spec})
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	ctx := context.Background()
+	served := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.net"}))
+	require.NoError(t, served.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("lb.example.net", endpoint.RecordTypeCNAME, 600, "lb-1.example.net"),
+		endpoint.NewEndpointWithTTL("lb-1.example.net", endpoint.RecordTypeA, 120, "10.0.0.2", "10.0.0.1"),
+		endpoint.NewEndpointWithTTL("lb-1.example.net", endpoint.RecordTypeAAAA, 120, "fd00::1"),
+	}}))
+
+	domains, err := ParseDomains([]string{"example.org", "*.example.com"})
+	require.NoError(t, err)
+	f, err := New(domains, startResolver(t, served))
+	require.NoError(t, err)
+	now := time.Now()
+	f.now = func() time.Time { return now }
+
+	endpoints, err := f.Transform(ctx, []*endpoint.Endpoint{
+		endpoint.NewEndpoint("example.org", endpoint.RecordTypeCNAME, "lb.example.net").WithSetIdentifier("a"),
+		endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeCNAME, "lb.example.net"),
+		endpoint.NewEndpointWithTTL("www.example.com", endpoint.RecordTypeCNAME, 60, "LB.example.net."),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("example.org", endpoint.RecordTypeA, 120, "10.0.0.1", "10.0.0.2").WithSetIdentifier("a"),
+		endpoint.NewEndpointWithTTL("example.org", endpoint.RecordTypeAAAA, 120, "fd00::1").WithSetIdentifier("a"),
+		endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeCNAME, "lb.example.net"),
+		endpoint.NewEndpointWithTTL("www.example.com", endpoint.RecordTypeA, 60, "10.0.0.1", "10.0.0.2"),
+		endpoint.NewEndpointWithTTL("www.example.com", endpoint.RecordTypeAAAA, 60, "fd00::1"),
+	}, endpoints)
+
+	// The addresses are re-resolved once expired.
+	require.NoError(t, served.ApplyChanges(ctx, &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("lb-1.example.net", endpoint.RecordTypeA, 120, "10.0.0.2", "10.0.0.1")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("lb-1.example.net", endpoint.RecordTypeA, 120, "10.0.0.3")},
+	}))
+	assert.False(t, f.refresh(ctx))
+	now = now.Add(2 * time.Minute)
+	assert.True(t, f.refresh(ctx))
+	assert.False(t, f.refresh(ctx))
+
+	endpoints, err = f.Transform(ctx, []*endpoint.Endpoint{endpoint.NewEndpoint("example.org", endpoint.RecordTypeCNAME, "lb.example.net")})
+	require.NoError(t, err)
+	assert.Equal(t, []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("example.org", endpoint.RecordTypeA, 120, "10.0.0.3"),
+		endpoint.NewEndpointWithTTL("example.org", endpoint.RecordTypeAAAA, 120, "fd00::1"),
+	}, endpoints)
+}
+
+func TestTransformError(t *testing.T) {
+	ctx := context.Background()
+	served := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.net"}))
+	require.NoError(t, served.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("lb.example.net", endpoint.RecordTypeA, 60, "10.0.0.1"),
+	}}))
+
+	f, err := New([]Domain{{Name: "example.org"}}, startResolver(t, served))
+	require.NoError(t, err)
+	now := time.Now()
+	f.now = func() time.Time { return now }
+
+	_, err = f.Transform(ctx, []*endpoint.Endpoint{endpoint.NewEndpoint("example.org", endpoint.RecordTypeCNAME, "missing.example.net")})
+	assert.ErrorContains(t, err, "missing.example.net has no A or AAAA records")
+
+	flat := []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("example.org", endpoint.RecordTypeA, 60, "10.0.0.1")}
+	endpoints, err := f.Transform(ctx, []*endpoint.Endpoint{endpoint.NewEndpoint("example.org", endpoint.RecordTypeCNAME, "lb.example.net")})
+	require.NoError(t, err)
+	assert.Equal(t, flat, endpoints)
+
+	// The expired addresses are kept while the resolver is down.
+	f.Resolver = "127.0.0.1:1"
+	f.Client.Timeout = 100 * time.Millisecond
+	now = now.Add(time.Hour)
+	endpoints, err = f.Transform(ctx, []*endpoint.Endpoint{endpoint.NewEndpoint("example.org", endpoint.RecordTypeCNAME, "lb.example.net")})
+	require.NoError(t, err)
+	assert.Equal(t, flat, endpoints)
+}
+
+func TestApexAlias(t *testing.T) {
+	ctx := context.Background()
+	targets := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.net"}))
+	require.NoError(t, targets.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("lb.example.net", endpoint.RecordTypeA, 60, "10.0.0.1"),
+	}}))
+	zone := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.org"}))
+
+	f, err := New(nil, startResolver(t, targets))
+	require.NoError(t, err)
+	f.Zones = func() []string { return []string{"Example.org."} }
+	f.Verifier = verify.New([]verify.Resolver{{Name: "zone", Address: startResolver(t, zone)}})
+	now := time.Now()
+	f.now = func() time.Time { return now }
+
+	endpoints, err := f.Transform(ctx, []*endpoint.Endpoint{
+		endpoint.NewEndpoint("example.org", endpoint.RecordTypeCNAME, "lb.example.net"),
+		endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeCNAME, "lb.example.net"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("example.org", endpoint.RecordTypeA, 60, "10.0.0.1"),
+		endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeCNAME, "lb.example.net"),
+	}, endpoints)
+
+	// The served record is stale once the addresses of the target change.
+	require.NoError(t, zone.ApplyChanges(ctx, &plan.Changes{Create: endpoints[:1]}))
+	assert.Equal(t, 0, f.verify(ctx))
+	require.NoError(t, targets.ApplyChanges(ctx, &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("lb.example.net", endpoint.RecordTypeA, 60, "10.0.0.1")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("lb.example.net", endpoint.RecordTypeA, 60, "10.0.0.2")},
+	}))
+	now = now.Add(time.Minute)
+	assert.True(t, f.refresh(ctx))
+	assert.Equal(t, 1, f.verify(ctx))
+}
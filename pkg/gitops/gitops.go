@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gitops writes the desired records, and the changes against the
+// provider, as files to be reviewed - for example in a git repository - and
+// applied by a separate step with 'ednsctl apply --file'.
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+const (
+	// RecordsFile holds the desired records, in the format read by ReadRecords.
+	RecordsFile = "records.yaml"
+	// ChangesFile holds the changes to apply to the provider, as written by WriteChanges.
+	ChangesFile = "changes.diff"
+)
+
+// Writer writes the records and changes files in a directory.
+type Writer struct {
+	Dir string
+	// Commit the files when they change, if the directory is a git worktree.
+	Commit bool
+}
+
+// Write writes the desired records and the changes, returning true if the files
+// changed. Unchanged files are not rewritten, so the directory only changes when
+// the desired records or the provider do.
+func (w *Writer) Write(ctx context.Context, desired []*endpoint.Endpoint, changes *plan.Changes) (bool, error) {
+	var records, diff bytes.Buffer
+	if err := WriteRecords(&records, desired, "yaml"); err != nil {
+		return false, err
+	}
+	WriteChanges(&diff, changes)
+
+	if err := os.MkdirAll(w.Dir, 0o755); err != nil {
+		return false, err
+	}
+	changed := false
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{{RecordsFile, records.Bytes()}, {ChangesFile, diff.Bytes()}} {
+		c, err := writeFile(filepath.Join(w.Dir, f.name), f.data)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || c
+	}
+	if !changed || !w.Commit {
+		return changed, nil
+	}
+	msg := fmt.Sprintf("Update DNS records: %d create, %d update, %d delete", len(changes.Create), len(changes.UpdateNew), len(changes.Delete))
+	if err := w.git(ctx, "add", RecordsFile, ChangesFile); err != nil {
+		return true, err
+	}
+	if err := w.git(ctx, "commit", "-m", msg, "--", RecordsFile, ChangesFile); err != nil {
+		return true, err
+	}
+	log.Infof("Committed %s in %s: %s", RecordsFile, w.Dir, msg)
+	return true, nil
+}
+
+func (w *Writer) git(ctx context.Context, args ...string) error {
+	out, err := exec.CommandContext(ctx, "git", append([]string{"-C", w.Dir}, args...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %s: %w: %s", args[0], err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// writeFile replaces the file, unless it has the same content.
+func writeFile(path string, data []byte) (bool, error) {
+	if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, data) {
+		return false, nil
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return false, err
+	}
+	return true, nil
+}
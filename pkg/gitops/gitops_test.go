@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitops
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestWriter(t *testing.T) {
+	ctx := context.Background()
+	w := &Writer{Dir: filepath.Join(t.TempDir(), "cluster-1")}
+	desired := []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")}
+	changes := &plan.Changes{Create: desired}
+
+	changed, err := w.Write(ctx, desired, changes)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	changed, err = w.Write(ctx, desired, changes)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	records, err := ReadRecords(filepath.Join(w.Dir, RecordsFile))
+	require.NoError(t, err)
+	assert.Equal(t, desired, records)
+	diff, err := os.ReadFile(filepath.Join(w.Dir, ChangesFile))
+	require.NoError(t, err)
+	assert.Equal(t, "+ a.example.com A - 1.2.3.4\n", string(diff))
+
+	// The provider caught up.
+	changed, err = w.Write(ctx, desired, &plan.Changes{})
+	require.NoError(t, err)
+	assert.True(t, changed)
+	diff, err = os.ReadFile(filepath.Join(w.Dir, ChangesFile))
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+}
+
+func TestWriterCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	for _, args := range [][]string{{"init", "-q"}, {"config", "user.email", "test@example.com"}, {"config", "user.name", "test"}} {
+		require.NoError(t, exec.Command("git", append([]string{"-C", dir}, args...)...).Run())
+	}
+	w := &Writer{Dir: dir, Commit: true}
+	desired := []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")}
+
+	_, err := w.Write(ctx, desired, &plan.Changes{Create: desired})
+	require.NoError(t, err)
+	// Unchanged files are not committed again.
+	_, err = w.Write(ctx, desired, &plan.Changes{Create: desired})
+	require.NoError(t, err)
+
+	out, err := exec.Command("git", "-C", dir, "log", "--format=%s").Output()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Update DNS records: 1 create, 0 update, 0 delete"}, strings.Split(strings.TrimSpace(string(out)), "\n"))
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitops
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// ReadRecords reads the records from a YAML or JSON file ("-" for stdin). The
+// file is either a list of endpoints, as written by WriteRecords, or a
+// DNSEndpoint spec with an endpoints list.
+func ReadRecords(path string) ([]*endpoint.Endpoint, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseRecords(data)
+}
+
+// ParseRecords parses a records file.
+func ParseRecords(data []byte) ([]*endpoint.Endpoint, error) {
+	var records []*endpoint.Endpoint
+	if err := yaml.Unmarshal(data, &records); err != nil {
+		var spec struct {
+			Spec *endpoint.DNSEndpointSpec `json:"spec"`
+			endpoint.DNSEndpointSpec
+		}
+		if err := yaml.Unmarshal(data, &spec); err != nil {
+			return nil, fmt.Errorf("parsing records: %w", err)
+		}
+		records = spec.Endpoints
+		if spec.Spec != nil {
+			records = spec.Spec.Endpoints
+		}
+	}
+	// The plan and registries set labels on the desired records.
+	for _, r := range records {
+		if r.Labels == nil {
+			r.Labels = endpoint.NewLabels()
+		}
+	}
+	return records, nil
+}
+
+// WriteRecords sorts the records and writes them as a table, or as YAML or JSON.
+func WriteRecords(w io.Writer, records []*endpoint.Endpoint, format string) error {
+	SortRecords(records)
+	switch format {
+	case "yaml":
+		data, err := yaml.Marshal(records)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTYPE\tTTL\tTARGETS\tOWNER\tRESOURCE")
+	for _, r := range records {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", recordName(r), r.RecordType, ttl(r), strings.Join(r.Targets, ","), r.Labels[endpoint.OwnerLabelKey], r.Labels[endpoint.ResourceLabelKey])
+	}
+	return tw.Flush()
+}
+
+// WriteChanges prints the changes as a diff - '+' for created, '~' for updated
+// and '-' for deleted records.
+func WriteChanges(w io.Writer, changes *plan.Changes) {
+	for _, r := range changes.Create {
+		fmt.Fprintf(w, "+ %s %s %s %s\n", recordName(r), r.RecordType, ttl(r), strings.Join(r.Targets, ","))
+	}
+	for i, r := range changes.UpdateNew {
+		old := r
+		if i < len(changes.UpdateOld) {
+			old = changes.UpdateOld[i]
+		}
+		fmt.Fprintf(w, "~ %s %s %s %s -> %s %s\n", recordName(r), r.RecordType, ttl(old), strings.Join(old.Targets, ","), ttl(r), strings.Join(r.Targets, ","))
+	}
+	for _, r := range changes.Delete {
+		fmt.Fprintf(w, "- %s %s %s %s\n", recordName(r), r.RecordType, ttl(r), strings.Join(r.Targets, ","))
+	}
+}
+
+// CountChanges returns the number of changed records.
+func CountChanges(changes *plan.Changes) int {
+	return len(changes.Create) + len(changes.UpdateNew) + len(changes.Delete)
+}
+
+// SortRecords sorts the records by name, type and set identifier.
+func SortRecords(records []*endpoint.Endpoint) {
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].DNSName != records[j].DNSName {
+			return records[i].DNSName < records[j].DNSName
+		}
+		if records[i].RecordType != records[j].RecordType {
+			return records[i].RecordType < records[j].RecordType
+		}
+		return records[i].SetIdentifier < records[j].SetIdentifier
+	})
+}
+
+func recordName(r *endpoint.Endpoint) string {
+	if r.SetIdentifier != "" {
+		return r.DNSName + "/" + r.SetIdentifier
+	}
+	return r.DNSName
+}
+
+func ttl(r *endpoint.Endpoint) string {
+	if !r.RecordTTL.IsConfigured() {
+		return "-"
+	}
+	return fmt.Sprint(int64(r.RecordTTL))
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitops
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestParseRecords(t *testing.T) {
+	list := `
+- dnsName: a.example.com
+  recordType: A
+  recordTTL: 300
+  targets: [1.2.3.4]
+`
+	records, err := ParseRecords([]byte(list))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, endpoint.NewEndpointWithTTL("a.example.com", endpoint.RecordTypeA, 300, "1.2.3.4"), records[0])
+
+	dnsEndpoint := `
+apiVersion: externaldns.k8s.io/v1alpha1
+kind: DNSEndpoint
+spec:
+  endpoints:
+  - dnsName: b.example.com
+    recordType: CNAME
+    targets: [a.example.com]
+`
+	records, err = ParseRecords([]byte(dnsEndpoint))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "b.example.com", records[0].DNSName)
+
+	records, err = ParseRecords([]byte(`{"endpoints": [{"dnsName": "c.example.com", "recordType": "A", "targets": ["1.2.3.5"]}]}`))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "c.example.com", records[0].DNSName)
+
+	_, err = ParseRecords([]byte("not: [valid"))
+	assert.Error(t, err)
+}
+
+func TestWriteRecordsRoundTrip(t *testing.T) {
+	records := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.5"),
+		endpoint.NewEndpointWithTTL("a.example.com", endpoint.RecordTypeA, 300, "1.2.3.4"),
+	}
+	for _, format := range []string{"yaml", "json"} {
+		var buf bytes.Buffer
+		require.NoError(t, WriteRecords(&buf, records, format))
+		parsed, err := ParseRecords(buf.Bytes())
+		require.NoError(t, err, format)
+		assert.Equal(t, records, parsed, format)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteRecords(&buf, records, "table"))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[1], "a.example.com")
+	assert.Contains(t, lines[1], "300")
+}
+
+func TestWriteChanges(t *testing.T) {
+	var buf bytes.Buffer
+	WriteChanges(&buf, &plan.Changes{
+		Create:    []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "1.2.3.5")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("b.example.com", endpoint.RecordTypeA, 60, "1.2.3.6")},
+		Delete:    []*endpoint.Endpoint{endpoint.NewEndpoint("c.example.com", endpoint.RecordTypeCNAME, "a.example.com")},
+	})
+	assert.Equal(t, `+ a.example.com A - 1.2.3.4
+~ b.example.com A - 1.2.3.5 -> 60 1.2.3.6
+- c.example.com CNAME - a.example.com
+`, buf.String())
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package health serves the liveness and readiness endpoints of the commands,
+// for the Kubernetes probes:
+//
+//	GET /healthz - the liveness checks, like the age of the last sync
+//	GET /readyz  - the readiness checks, like the informer caches and the provider
+//
+// The response is 200 if all the checks pass, 503 otherwise, with the result
+// of each check as JSON.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Check returns an error if the component is not healthy.
+type Check func(ctx context.Context) error
+
+// Handler runs the liveness and readiness checks.
+type Handler struct {
+	// Timeout limits the time of each check, 5s if zero.
+	Timeout time.Duration
+
+	mu        sync.Mutex
+	liveness  map[string]Check
+	readiness map[string]Check
+}
+
+// Result is the result of a check.
+type Result struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Report is the response of the endpoints.
+type Report struct {
+	OK     bool               `json:"ok"`
+	Checks map[string]*Result `json:"checks"`
+}
+
+// AddLivenessCheck adds a check of /healthz: the container is restarted when
+// it fails.
+func (h *Handler) AddLivenessCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.liveness == nil {
+		h.liveness = map[string]Check{}
+	}
+	h.liveness[name] = check
+}
+
+// AddReadinessCheck adds a check of /readyz: the pod is not ready while it
+// fails.
+func (h *Handler) AddReadinessCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.readiness == nil {
+		h.readiness = map[string]Check{}
+	}
+	h.readiness[name] = check
+}
+
+// RemoveReadinessCheck removes a check of /readyz, like the check of a
+// provider removed by a reload.
+func (h *Handler) RemoveReadinessCheck(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.readiness, name)
+}
+
+// Register adds the /healthz and /readyz endpoints to the mux.
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		h.serve(w, r, false)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		h.serve(w, r, true)
+	})
+}
+
+// Liveness runs the liveness checks.
+func (h *Handler) Liveness(ctx context.Context) *Report {
+	return h.run(ctx, false)
+}
+
+// Readiness runs the readiness checks.
+func (h *Handler) Readiness(ctx context.Context) *Report {
+	return h.run(ctx, true)
+}
+
+func (h *Handler) run(ctx context.Context, readiness bool) *Report {
+	h.mu.Lock()
+	checks := h.liveness
+	if readiness {
+		checks = h.readiness
+	}
+	names := make([]string, 0, len(checks))
+	byName := make(map[string]Check, len(checks))
+	for name, check := range checks {
+		names = append(names, name)
+		byName[name] = check
+	}
+	h.mu.Unlock()
+	sort.Strings(names)
+
+	timeout := h.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	report := &Report{OK: true, Checks: make(map[string]*Result, len(names))}
+	for _, name := range names {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := byName[name](checkCtx)
+		cancel()
+		result := &Result{OK: err == nil}
+		if err != nil {
+			result.Error = err.Error()
+			report.OK = false
+		}
+		report.Checks[name] = result
+	}
+	return report
+}
+
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, readiness bool) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	report := h.run(r.Context(), readiness)
+	w.Header().Set("Content-Type", "application/json")
+	if !report.OK {
+		for name, result := range report.Checks {
+			if !result.OK {
+				log.Warnf("Health check %s of %s failed: %s", name, r.URL.Path, result.Error)
+			}
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Errorf("Failed to encode the health report: %v", err)
+	}
+}
+
+// Synced returns a check failing until hasSynced returns true, like the
+// HasSynced of an informer.
+func Synced(hasSynced func() bool) Check {
+	return func(context.Context) error {
+		if !hasSynced() {
+			return errors.New("caches not synced")
+		}
+		return nil
+	}
+}
+
+// FirstSync returns a check failing until the first successful sync.
+func FirstSync(lastSync func() time.Time) Check {
+	return func(context.Context) error {
+		if lastSync().IsZero() {
+			return errors.New("no successful sync yet")
+		}
+		return nil
+	}
+}
+
+// SyncAge returns a check failing if the last successful sync is older than
+// maxAge. Before the first sync, the age is counted from the creation of the
+// check, so a slow start is not reported.
+func SyncAge(lastSync func() time.Time, maxAge time.Duration) Check {
+	start := time.Now()
+	return func(context.Context) error {
+		last := lastSync()
+		if last.IsZero() {
+			last = start
+		}
+		if age := time.Since(last); age > maxAge {
+			return fmt.Errorf("last successful sync %s ago, more than %s", age.Round(time.Second), maxAge)
+		}
+		return nil
+	}
+}
+
+// HTTPGet returns a check of the status of a GET of the url, like the
+// negotiation endpoint of a webhook provider.
+func HTTPGet(url string) Check {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("GET %s: %s", url, resp.Status)
+		}
+		return nil
+	}
+}
+
+// Cached returns the check, reusing its result for ttl - for the checks
+// calling a rate limited API, like the provider.
+func Cached(check Check, ttl time.Duration) Check {
+	var mu sync.Mutex
+	var last time.Time
+	var lastErr error
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !last.IsZero() && time.Since(last) < ttl {
+			return lastErr
+		}
+		lastErr = check(ctx)
+		last = time.Now()
+		return lastErr
+	}
+}
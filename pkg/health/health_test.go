@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	synced := false
+	h := &Handler{}
+	h.AddLivenessCheck("sync-age", func(context.Context) error { return nil })
+	h.AddReadinessCheck("informers", Synced(func() bool { return synced }))
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	get := func(path string) (int, *Report) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var report Report
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+		return w.Code, &report
+	}
+
+	code, report := get("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, report.OK)
+
+	code, report = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, report.OK)
+	assert.Equal(t, &Result{Error: "caches not synced"}, report.Checks["informers"])
+
+	synced = true
+	code, report = get("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, report.Checks["informers"].OK)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/readyz", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	h.RemoveReadinessCheck("informers")
+	_, report = get("/readyz")
+	assert.Empty(t, report.Checks)
+}
+
+func TestHandlerTimeout(t *testing.T) {
+	h := &Handler{Timeout: 10 * time.Millisecond}
+	h.AddReadinessCheck("provider", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	report := h.Readiness(context.Background())
+	assert.False(t, report.OK)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["provider"].Error)
+}
+
+func TestSyncChecks(t *testing.T) {
+	var last time.Time
+	lastSync := func() time.Time { return last }
+	ctx := context.Background()
+
+	assert.Error(t, FirstSync(lastSync)(ctx))
+	assert.NoError(t, SyncAge(lastSync, time.Minute)(ctx), "the age is counted from the start")
+	assert.Error(t, SyncAge(lastSync, 0)(ctx))
+
+	last = time.Now()
+	assert.NoError(t, FirstSync(lastSync)(ctx))
+	last = time.Now().Add(-time.Hour)
+	assert.ErrorContains(t, SyncAge(lastSync, time.Minute)(ctx), "last successful sync 1h0m0s ago")
+}
+
+func TestHTTPGet(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	assert.NoError(t, HTTPGet(server.URL)(ctx))
+	status = http.StatusInternalServerError
+	assert.ErrorContains(t, HTTPGet(server.URL)(ctx), "500")
+}
+
+func TestCached(t *testing.T) {
+	calls := 0
+	check := Cached(func(context.Context) error {
+		calls++
+		return errors.New("unreachable")
+	}, time.Hour)
+	ctx := context.Background()
+	assert.Error(t, check(ctx))
+	assert.Error(t, check(ctx))
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// IPLeaseGVR is the resource of the IPLease CRD (docs/ipam/iplease-crd.yaml).
+var IPLeaseGVR = schema.GroupVersionResource{
+	Group:    "externaldns.k8s.io",
+	Version:  "v1alpha1",
+	Resource: "ipleases",
+}
+
+// CRDStore keeps each lease as an IPLease object in the cluster. The object is
+// named after the address, so concurrent allocations of the same address by
+// different instances conflict on create.
+type CRDStore struct {
+	Client    dynamic.Interface
+	Namespace string
+}
+
+// NewCRDStore returns a store for IPLease objects in the namespace.
+func NewCRDStore(client dynamic.Interface, namespace string) *CRDStore {
+	return &CRDStore{Client: client, Namespace: namespace}
+}
+
+// List implements Store.
+func (c *CRDStore) List(ctx context.Context) ([]Lease, error) {
+	list, err := c.Client.Resource(IPLeaseGVR).Namespace(c.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var leases []Lease
+	for _, item := range list.Items {
+		ipStr, _, _ := unstructured.NestedString(item.Object, "spec", "ip")
+		owner, _, _ := unstructured.NestedString(item.Object, "spec", "owner")
+		ip, err := netip.ParseAddr(ipStr)
+		if err != nil {
+			log.Warnf("Ignoring IPLease %s with invalid address %q", item.GetName(), ipStr)
+			continue
+		}
+		lease := Lease{IP: ip, Owner: owner, Created: item.GetCreationTimestamp().Time}
+		if renewed, _, _ := unstructured.NestedString(item.Object, "spec", "renewed"); renewed != "" {
+			lease.Renewed, _ = time.Parse(time.RFC3339, renewed)
+		}
+		leases = append(leases, lease)
+	}
+	return leases, nil
+}
+
+// Put implements Store. Renewing a lease updates the existing object, while an
+// object owned by a different owner results in ErrInUse.
+func (c *CRDStore) Put(ctx context.Context, lease Lease) error {
+	spec := map[string]interface{}{
+		"ip":    lease.IP.String(),
+		"owner": lease.Owner,
+	}
+	if !lease.Renewed.IsZero() {
+		spec["renewed"] = lease.Renewed.UTC().Format(time.RFC3339)
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(IPLeaseGVR.GroupVersion().String())
+	obj.SetKind("IPLease")
+	obj.SetName(leaseName(lease.IP))
+	obj.SetNamespace(c.Namespace)
+	obj.Object["spec"] = spec
+	client := c.Client.Resource(IPLeaseGVR).Namespace(c.Namespace)
+	_, err := client.Create(ctx, obj, metav1.CreateOptions{FieldManager: "external-dns"})
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, err := client.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if owner, _, _ := unstructured.NestedString(existing.Object, "spec", "owner"); owner != lease.Owner {
+		return fmt.Errorf("%w: %s is held by %s", ErrInUse, lease.IP, owner)
+	}
+	existing.Object["spec"] = spec
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{FieldManager: "external-dns"})
+	return err
+}
+
+// Delete implements Store.
+func (c *CRDStore) Delete(ctx context.Context, lease Lease) error {
+	err := c.Client.Resource(IPLeaseGVR).Namespace(c.Namespace).Delete(ctx, leaseName(lease.IP), metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// leaseName returns a valid object name for the address.
+func leaseName(ip netip.Addr) string {
+	return strings.NewReplacer(".", "-", ":", "-").Replace(ip.String())
+}
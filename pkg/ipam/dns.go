@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"context"
+	"net/netip"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// RecordLister returns the current DNS records - implemented by providers and
+// registries.
+type RecordLister interface {
+	Records(ctx context.Context) ([]*endpoint.Endpoint, error)
+}
+
+// DNSStore derives the leases from the A and AAAA records in DNS, so allocations
+// made by other clusters or tools sharing the zone are respected. With a registry,
+// the owner is the resource label of the record, otherwise the DNS name.
+//
+// Put and Delete are no-ops: the records are created by the normal sync, so a new
+// lease is only visible to others after the next ApplyChanges.
+type DNSStore struct {
+	Records RecordLister
+}
+
+// NewDNSStore returns a store reading the leases from the records.
+func NewDNSStore(records RecordLister) *DNSStore {
+	return &DNSStore{Records: records}
+}
+
+// List implements Store.
+func (d *DNSStore) List(ctx context.Context) ([]Lease, error) {
+	records, err := d.Records.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var leases []Lease
+	for _, ep := range records {
+		if ep.RecordType != endpoint.RecordTypeA && ep.RecordType != endpoint.RecordTypeAAAA {
+			continue
+		}
+		owner := ep.Labels[endpoint.ResourceLabelKey]
+		if owner == "" {
+			owner = ep.DNSName
+		}
+		for _, t := range ep.Targets {
+			ip, err := netip.ParseAddr(t)
+			if err != nil {
+				continue
+			}
+			leases = append(leases, Lease{IP: ip, Owner: owner})
+		}
+	}
+	return leases, nil
+}
+
+// Put implements Store.
+func (d *DNSStore) Put(context.Context, Lease) error { return nil }
+
+// Delete implements Store.
+func (d *DNSStore) Delete(context.Context, Lease) error { return nil }
@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// FileStore keeps the leases in a JSON file. It is intended for single instance
+// deployments and the offline (CI/CD) mode, where the file can be reviewed and
+// checked in.
+type FileStore struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// NewFileStore returns a store using the file at path. The file is created on the
+// first allocation.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+// List implements Store.
+func (f *FileStore) List(context.Context) ([]Lease, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.read()
+}
+
+// Put implements Store.
+func (f *FileStore) Put(_ context.Context, lease Lease) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	leases, err := f.read()
+	if err != nil {
+		return err
+	}
+	leases = removeLease(leases, lease)
+	return f.write(append(leases, lease))
+}
+
+// Delete implements Store.
+func (f *FileStore) Delete(_ context.Context, lease Lease) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	leases, err := f.read()
+	if err != nil {
+		return err
+	}
+	return f.write(removeLease(leases, lease))
+}
+
+func (f *FileStore) read() ([]Lease, error) {
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var leases []Lease
+	if err := json.Unmarshal(data, &leases); err != nil {
+		return nil, err
+	}
+	return leases, nil
+}
+
+// write replaces the file atomically, so a crash doesn't lose the leases.
+func (f *FileStore) write(leases []Lease) error {
+	sort.Slice(leases, func(i, j int) bool { return leases[i].IP.Less(leases[j].IP) })
+	data, err := json.MarshalIndent(leases, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), ".ipam-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+// removeLease removes the leases with the same address.
+func removeLease(leases []Lease, lease Lease) []Lease {
+	res := leases[:0]
+	for _, l := range leases {
+		if l.IP != lease.IP {
+			res = append(res, l)
+		}
+	}
+	return res
+}
@@ -0,0 +1,359 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ipam allocates VIPs from configured CIDRs, for ServiceEntry and egress
+// gateway auto-allocation. Leases are persisted using a pluggable Store.
+package ipam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrExhausted is returned when all addresses in the pools are allocated.
+	ErrExhausted = errors.New("no free address in the IPAM pools")
+
+	// ErrInUse is returned when reserving an address held by a different owner.
+	ErrInUse = errors.New("address is allocated to a different owner")
+)
+
+// Lease is an address allocated to an owner - typically the resource, like
+// "serviceentry/NAMESPACE/NAME".
+type Lease struct {
+	IP      netip.Addr `json:"ip"`
+	Owner   string     `json:"owner"`
+	Created time.Time  `json:"created"`
+
+	// Renewed is the last time the owner used the lease. Leases not renewed
+	// within the TTL of the allocator are expired.
+	Renewed time.Time `json:"renewed,omitempty"`
+}
+
+// lastUsed returns the time the lease was last renewed or created.
+func (l Lease) lastUsed() time.Time {
+	if l.Renewed.After(l.Created) {
+		return l.Renewed
+	}
+	return l.Created
+}
+
+// Store persists the leases. Implementations must be safe for concurrent use.
+type Store interface {
+	List(ctx context.Context) ([]Lease, error)
+	Put(ctx context.Context, lease Lease) error
+	Delete(ctx context.Context, lease Lease) error
+}
+
+// Allocator hands out addresses from a set of CIDRs. Each owner gets a single
+// address, and repeated calls return the same lease.
+type Allocator struct {
+	// TTL is the lease duration - Allocate and Reserve renew the lease of the owner.
+	// Zero means leases don't expire and are only reclaimed if the owner is deleted.
+	TTL time.Duration
+
+	pools []netip.Prefix
+	store Store
+
+	mu      sync.Mutex
+	loaded  bool
+	byOwner map[string]Lease
+	byIP    map[netip.Addr]string
+}
+
+// NewAllocator returns an allocator for the CIDRs - plain addresses are treated as
+// single address pools. If store is nil, the leases are only kept in memory.
+func NewAllocator(cidrs []string, store Store) (*Allocator, error) {
+	a := &Allocator{
+		store:   store,
+		byOwner: map[string]Lease{},
+		byIP:    map[netip.Addr]string{},
+	}
+	for _, c := range cidrs {
+		p, err := parsePrefix(c)
+		if err != nil {
+			return nil, err
+		}
+		a.pools = append(a.pools, p)
+	}
+	if len(a.pools) == 0 {
+		return nil, errors.New("ipam requires at least one CIDR")
+	}
+	if a.store == nil {
+		a.store = &memoryStore{}
+	}
+	return a, nil
+}
+
+func parsePrefix(c string) (netip.Prefix, error) {
+	if !strings.Contains(c, "/") {
+		addr, err := netip.ParseAddr(c)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid IPAM address %q: %w", c, err)
+		}
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	p, err := netip.ParsePrefix(c)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid IPAM CIDR %q: %w", c, err)
+	}
+	return p.Masked(), nil
+}
+
+// Contains returns true if the address is in one of the pools.
+func (a *Allocator) Contains(ip netip.Addr) bool {
+	for _, p := range a.pools {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads the leases from the store, replacing the cached state.
+func (a *Allocator) Load(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.load(ctx)
+}
+
+func (a *Allocator) load(ctx context.Context) error {
+	leases, err := a.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	a.byOwner = map[string]Lease{}
+	a.byIP = map[netip.Addr]string{}
+	for _, l := range leases {
+		if !a.Contains(l.IP) {
+			log.Debugf("Ignoring lease %s for %s outside the IPAM pools", l.IP, l.Owner)
+			continue
+		}
+		if other, ok := a.byIP[l.IP]; ok {
+			log.Debugf("Duplicate lease for %s: %s and %s", l.IP, other, l.Owner)
+			continue
+		}
+		a.byOwner[l.Owner] = l
+		a.byIP[l.IP] = l.Owner
+	}
+	a.loaded = true
+	return nil
+}
+
+func (a *Allocator) ensureLoaded(ctx context.Context) error {
+	if a.loaded {
+		return nil
+	}
+	return a.load(ctx)
+}
+
+// Lookup returns the address allocated to the owner.
+func (a *Allocator) Lookup(owner string) (netip.Addr, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	l, ok := a.byOwner[owner]
+	return l.IP, ok
+}
+
+// Allocate returns the address of the owner, allocating a free one if needed.
+func (a *Allocator) Allocate(ctx context.Context, owner string) (netip.Addr, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.ensureLoaded(ctx); err != nil {
+		return netip.Addr{}, err
+	}
+	if l, ok := a.byOwner[owner]; ok {
+		return l.IP, a.renew(ctx, l)
+	}
+
+	ip, ok := a.free()
+	if !ok {
+		return netip.Addr{}, ErrExhausted
+	}
+	if err := a.put(ctx, Lease{IP: ip, Owner: owner, Created: time.Now()}); err != nil {
+		return netip.Addr{}, err
+	}
+	log.Infof("Allocated %s to %s", ip, owner)
+	return ip, nil
+}
+
+// Reserve records an address already used by the owner, for example one set in
+// the ServiceEntry, so it is not handed out again.
+func (a *Allocator) Reserve(ctx context.Context, owner string, ip netip.Addr) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.ensureLoaded(ctx); err != nil {
+		return err
+	}
+	if !a.Contains(ip) {
+		return fmt.Errorf("address %s is not in the IPAM pools", ip)
+	}
+	if other, ok := a.byIP[ip]; ok {
+		if other == owner {
+			return a.renew(ctx, a.byOwner[owner])
+		}
+		return fmt.Errorf("%w: %s is held by %s", ErrInUse, ip, other)
+	}
+	if l, ok := a.byOwner[owner]; ok {
+		// The owner moved to a different address.
+		if err := a.delete(ctx, l); err != nil {
+			return err
+		}
+	}
+	return a.put(ctx, Lease{IP: ip, Owner: owner, Created: time.Now()})
+}
+
+// Release frees the address of the owner.
+func (a *Allocator) Release(ctx context.Context, owner string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.ensureLoaded(ctx); err != nil {
+		return err
+	}
+	l, ok := a.byOwner[owner]
+	if !ok {
+		return nil
+	}
+	log.Infof("Released %s from %s", l.IP, owner)
+	return a.delete(ctx, l)
+}
+
+// Leases returns the current leases, sorted by address.
+func (a *Allocator) Leases() []Lease {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	leases := make([]Lease, 0, len(a.byOwner))
+	for _, l := range a.byOwner {
+		leases = append(leases, l)
+	}
+	sort.Slice(leases, func(i, j int) bool { return leases[i].IP.Less(leases[j].IP) })
+	return leases
+}
+
+// Expired returns the leases not renewed within the TTL. Leases without a
+// timestamp, like the ones derived from DNS, don't expire.
+func (a *Allocator) Expired(now time.Time) []Lease {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var expired []Lease
+	if a.TTL == 0 {
+		return expired
+	}
+	for _, l := range a.byOwner {
+		if t := l.lastUsed(); !t.IsZero() && now.Sub(t) > a.TTL {
+			expired = append(expired, l)
+		}
+	}
+	return expired
+}
+
+// renew updates the lease timestamp. To limit the writes to the store, it is only
+// persisted after half of the TTL.
+func (a *Allocator) renew(ctx context.Context, l Lease) error {
+	if a.TTL == 0 || l.lastUsed().IsZero() || time.Since(l.lastUsed()) < a.TTL/2 {
+		return nil
+	}
+	l.Renewed = time.Now()
+	return a.put(ctx, l)
+}
+
+func (a *Allocator) put(ctx context.Context, l Lease) error {
+	if err := a.store.Put(ctx, l); err != nil {
+		return err
+	}
+	a.byOwner[l.Owner] = l
+	a.byIP[l.IP] = l.Owner
+	return nil
+}
+
+func (a *Allocator) delete(ctx context.Context, l Lease) error {
+	if err := a.store.Delete(ctx, l); err != nil {
+		return err
+	}
+	delete(a.byOwner, l.Owner)
+	delete(a.byIP, l.IP)
+	return nil
+}
+
+// free returns the first unallocated address. The network and broadcast
+// addresses of IPv4 pools are skipped.
+func (a *Allocator) free() (netip.Addr, bool) {
+	for _, p := range a.pools {
+		first, last := p.Addr(), lastAddr(p)
+		if p.Addr().Is4() && p.Bits() < 31 {
+			first, last = first.Next(), last.Prev()
+		}
+		for ip := first; ip.IsValid() && p.Contains(ip); ip = ip.Next() {
+			if _, used := a.byIP[ip]; !used {
+				return ip, true
+			}
+			if ip == last {
+				break
+			}
+		}
+	}
+	return netip.Addr{}, false
+}
+
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// memoryStore keeps the leases in memory only.
+type memoryStore struct {
+	mu     sync.Mutex
+	leases map[netip.Addr]Lease
+}
+
+func (m *memoryStore) List(context.Context) ([]Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	leases := make([]Lease, 0, len(m.leases))
+	for _, l := range m.leases {
+		leases = append(leases, l)
+	}
+	return leases, nil
+}
+
+func (m *memoryStore) Put(_ context.Context, l Lease) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.leases == nil {
+		m.leases = map[netip.Addr]Lease{}
+	}
+	m.leases[l.IP] = l
+	return nil
+}
+
+func (m *memoryStore) Delete(_ context.Context, l Lease) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.leases, l.IP)
+	return nil
+}
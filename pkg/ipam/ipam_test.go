@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"context"
+	"net/netip"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestAllocate(t *testing.T) {
+	ctx := context.Background()
+	_, err := NewAllocator(nil, nil)
+	assert.Error(t, err)
+	_, err = NewAllocator([]string{"10.0.0.0/33"}, nil)
+	assert.Error(t, err)
+
+	a, err := NewAllocator([]string{"10.0.0.0/30", "10.1.0.5"}, nil)
+	require.NoError(t, err)
+
+	ip, err := a.Allocate(ctx, "serviceentry/ns/a")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", ip.String())
+
+	// Same owner, same address.
+	ip, err = a.Allocate(ctx, "serviceentry/ns/a")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", ip.String())
+
+	ip, err = a.Allocate(ctx, "serviceentry/ns/b")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", ip.String())
+
+	// Broadcast is skipped, next pool.
+	ip, err = a.Allocate(ctx, "serviceentry/ns/c")
+	require.NoError(t, err)
+	assert.Equal(t, "10.1.0.5", ip.String())
+
+	_, err = a.Allocate(ctx, "serviceentry/ns/d")
+	assert.ErrorIs(t, err, ErrExhausted)
+
+	require.NoError(t, a.Release(ctx, "serviceentry/ns/b"))
+	ip, err = a.Allocate(ctx, "serviceentry/ns/d")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", ip.String())
+	assert.Len(t, a.Leases(), 3)
+}
+
+func TestReserve(t *testing.T) {
+	ctx := context.Background()
+	a, err := NewAllocator([]string{"10.0.0.0/24", "fd00::/120"}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, a.Reserve(ctx, "serviceentry/ns/a", netip.MustParseAddr("10.0.0.1")))
+	require.NoError(t, a.Reserve(ctx, "serviceentry/ns/a", netip.MustParseAddr("10.0.0.1")))
+	assert.ErrorIs(t, a.Reserve(ctx, "serviceentry/ns/b", netip.MustParseAddr("10.0.0.1")), ErrInUse)
+	assert.Error(t, a.Reserve(ctx, "serviceentry/ns/b", netip.MustParseAddr("192.168.0.1")))
+
+	ip, err := a.Allocate(ctx, "serviceentry/ns/b")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", ip.String())
+
+	// Moving an owner frees the old address.
+	require.NoError(t, a.Reserve(ctx, "serviceentry/ns/a", netip.MustParseAddr("fd00::10")))
+	ip, err = a.Allocate(ctx, "serviceentry/ns/c")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", ip.String())
+
+	ip, ok := a.Lookup("serviceentry/ns/a")
+	assert.True(t, ok)
+	assert.Equal(t, "fd00::10", ip.String())
+}
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileStore(filepath.Join(t.TempDir(), "leases.json"))
+	a, err := NewAllocator([]string{"10.0.0.0/24"}, store)
+	require.NoError(t, err)
+	_, err = a.Allocate(ctx, "serviceentry/ns/a")
+	require.NoError(t, err)
+	_, err = a.Allocate(ctx, "serviceentry/ns/b")
+	require.NoError(t, err)
+	require.NoError(t, a.Release(ctx, "serviceentry/ns/a"))
+
+	// A new allocator, e.g. after restart, sees the persisted leases.
+	a2, err := NewAllocator([]string{"10.0.0.0/24"}, store)
+	require.NoError(t, err)
+	require.NoError(t, a2.Load(ctx))
+	ip, ok := a2.Lookup("serviceentry/ns/b")
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.0.2", ip.String())
+	_, ok = a2.Lookup("serviceentry/ns/a")
+	assert.False(t, ok)
+}
+
+type fakeRecords []*endpoint.Endpoint
+
+func (f fakeRecords) Records(context.Context) ([]*endpoint.Endpoint, error) {
+	return f, nil
+}
+
+func TestDNSStore(t *testing.T) {
+	owned := endpoint.NewEndpoint("a.mesh.internal", endpoint.RecordTypeA, "10.0.0.1")
+	owned.Labels[endpoint.ResourceLabelKey] = "serviceentry/ns/a"
+	records := fakeRecords{
+		owned,
+		endpoint.NewEndpoint("b.mesh.internal", endpoint.RecordTypeA, "10.0.0.2", "10.0.1.5"),
+		endpoint.NewEndpoint("c.mesh.internal", endpoint.RecordTypeCNAME, "a.mesh.internal"),
+	}
+
+	a, err := NewAllocator([]string{"10.0.0.0/24"}, NewDNSStore(records))
+	require.NoError(t, err)
+	ip, err := a.Allocate(context.Background(), "serviceentry/ns/new")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.3", ip.String())
+
+	ip, ok := a.Lookup("serviceentry/ns/a")
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.0.1", ip.String())
+	_, ok = a.Lookup("b.mesh.internal")
+	assert.True(t, ok)
+}
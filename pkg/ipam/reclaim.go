@@ -0,0 +1,195 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// defaultGracePeriod is how long an owner must be missing before its lease is reclaimed.
+const defaultGracePeriod = 10 * time.Minute
+
+var reclaimedLeases = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: "external_dns",
+		Subsystem: "ipam",
+		Name:      "reclaimed_leases_total",
+		Help:      "Number of VIP leases freed because the owner was deleted or the lease expired.",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(reclaimedLeases)
+}
+
+// OwnerExists returns false if the owner of a lease was deleted.
+type OwnerExists func(ctx context.Context, owner string) (bool, error)
+
+// Reclaimer frees the leases whose owner no longer exists, after a grace period,
+// and the leases expired by the allocator TTL. This prevents exhausting the pools
+// in meshes where ServiceEntries and pods come and go.
+type Reclaimer struct {
+	Allocator *Allocator
+
+	// Exists checks the owners. Optional - without it only expired leases are reclaimed.
+	Exists OwnerExists
+
+	// GracePeriod is how long an owner must be missing, to tolerate informer lag
+	// and objects that are deleted and re-created.
+	GracePeriod time.Duration
+
+	// OnReclaim is called for each freed lease, typically RemoveRecords.
+	OnReclaim func(ctx context.Context, lease Lease) error
+
+	missing map[string]time.Time
+	now     func() time.Time
+}
+
+// NewReclaimer returns a reclaimer for the allocator.
+func NewReclaimer(a *Allocator, exists OwnerExists) *Reclaimer {
+	return &Reclaimer{
+		Allocator:   a,
+		Exists:      exists,
+		GracePeriod: defaultGracePeriod,
+		missing:     map[string]time.Time{},
+		now:         time.Now,
+	}
+}
+
+// Run reclaims leases periodically until the context is canceled.
+func (r *Reclaimer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Reclaim(ctx); err != nil {
+			log.Errorf("Failed to reclaim VIP leases: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reclaim does a single pass, returning the freed leases.
+func (r *Reclaimer) Reclaim(ctx context.Context) ([]Lease, error) {
+	now := r.now()
+	reclaim := map[string]Lease{}
+	for _, l := range r.Allocator.Expired(now) {
+		reclaim[l.Owner] = l
+	}
+
+	if r.Exists != nil {
+		seen := map[string]bool{}
+		for _, l := range r.Allocator.Leases() {
+			seen[l.Owner] = true
+			exists, err := r.Exists(ctx, l.Owner)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				delete(r.missing, l.Owner)
+				continue
+			}
+			since, ok := r.missing[l.Owner]
+			if !ok {
+				log.Debugf("Owner %s of %s is missing, reclaiming after %s", l.Owner, l.IP, r.GracePeriod)
+				r.missing[l.Owner] = now
+				continue
+			}
+			if now.Sub(since) >= r.GracePeriod {
+				reclaim[l.Owner] = l
+			}
+		}
+		for owner := range r.missing {
+			if !seen[owner] {
+				delete(r.missing, owner)
+			}
+		}
+	}
+
+	var freed []Lease
+	for owner, l := range reclaim {
+		if err := r.Allocator.Release(ctx, owner); err != nil {
+			return freed, err
+		}
+		delete(r.missing, owner)
+		reclaimedLeases.Inc()
+		freed = append(freed, l)
+		if r.OnReclaim != nil {
+			if err := r.OnReclaim(ctx, l); err != nil {
+				log.Warnf("Failed to clean up after reclaiming %s from %s: %v", l.IP, owner, err)
+			}
+		}
+	}
+	return freed, nil
+}
+
+// RecordApplier is implemented by providers and registries.
+type RecordApplier interface {
+	RecordLister
+	ApplyChanges(ctx context.Context, changes *plan.Changes) error
+}
+
+// RemoveRecords returns an OnReclaim function removing the address from the A and
+// AAAA records. Records left without targets are deleted. Use the registry rather
+// than the provider, so the ownership records are deleted too.
+func RemoveRecords(p RecordApplier) func(ctx context.Context, lease Lease) error {
+	return func(ctx context.Context, lease Lease) error {
+		records, err := p.Records(ctx)
+		if err != nil {
+			return err
+		}
+		ip := lease.IP.String()
+		changes := &plan.Changes{}
+		for _, ep := range records {
+			if ep.RecordType != endpoint.RecordTypeA && ep.RecordType != endpoint.RecordTypeAAAA {
+				continue
+			}
+			var targets endpoint.Targets
+			for _, t := range ep.Targets {
+				if t != ip {
+					targets = append(targets, t)
+				}
+			}
+			if len(targets) == len(ep.Targets) {
+				continue
+			}
+			if len(targets) == 0 {
+				changes.Delete = append(changes.Delete, ep)
+				continue
+			}
+			updated := ep.DeepCopy()
+			updated.Targets = targets
+			changes.UpdateOld = append(changes.UpdateOld, ep)
+			changes.UpdateNew = append(changes.UpdateNew, updated)
+		}
+		if !changes.HasChanges() {
+			return nil
+		}
+		log.Infof("Removing DNS records of reclaimed %s", ip)
+		return p.ApplyChanges(ctx, changes)
+	}
+}
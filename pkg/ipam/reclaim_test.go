@@ -0,0 +1,123 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+func TestReclaimMissingOwner(t *testing.T) {
+	ctx := context.Background()
+	a, err := NewAllocator([]string{"10.0.0.0/24"}, nil)
+	require.NoError(t, err)
+	ipA, err := a.Allocate(ctx, "serviceentry/ns/a")
+	require.NoError(t, err)
+	_, err = a.Allocate(ctx, "serviceentry/ns/b")
+	require.NoError(t, err)
+
+	p := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, ipA.String()),
+			endpoint.NewEndpoint("shared.example.com", endpoint.RecordTypeA, ipA.String(), "10.0.0.2"),
+		},
+	}))
+
+	existing := map[string]bool{"serviceentry/ns/b": true}
+	r := NewReclaimer(a, func(_ context.Context, owner string) (bool, error) {
+		return existing[owner], nil
+	})
+	r.OnReclaim = RemoveRecords(p)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	// Within the grace period.
+	freed, err := r.Reclaim(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, freed)
+
+	now = now.Add(r.GracePeriod)
+	freed, err = r.Reclaim(ctx)
+	require.NoError(t, err)
+	require.Len(t, freed, 1)
+	assert.Equal(t, "serviceentry/ns/a", freed[0].Owner)
+	_, ok := a.Lookup("serviceentry/ns/a")
+	assert.False(t, ok)
+	_, ok = a.Lookup("serviceentry/ns/b")
+	assert.True(t, ok)
+
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "shared.example.com", records[0].DNSName)
+	assert.Equal(t, endpoint.Targets{"10.0.0.2"}, records[0].Targets)
+}
+
+func TestReclaimRecreatedOwner(t *testing.T) {
+	ctx := context.Background()
+	a, err := NewAllocator([]string{"10.0.0.0/24"}, nil)
+	require.NoError(t, err)
+	_, err = a.Allocate(ctx, "pod/ns/a")
+	require.NoError(t, err)
+
+	exists := false
+	r := NewReclaimer(a, func(context.Context, string) (bool, error) { return exists, nil })
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	_, err = r.Reclaim(ctx)
+	require.NoError(t, err)
+	exists = true
+	_, err = r.Reclaim(ctx)
+	require.NoError(t, err)
+	exists = false
+	now = now.Add(r.GracePeriod)
+
+	// The grace period restarts after the owner reappeared.
+	freed, err := r.Reclaim(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, freed)
+}
+
+func TestReclaimExpired(t *testing.T) {
+	ctx := context.Background()
+	a, err := NewAllocator([]string{"10.0.0.0/24"}, nil)
+	require.NoError(t, err)
+	a.TTL = time.Hour
+	_, err = a.Allocate(ctx, "serviceentry/ns/a")
+	require.NoError(t, err)
+
+	r := NewReclaimer(a, nil)
+	freed, err := r.Reclaim(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, freed)
+
+	r.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	freed, err = r.Reclaim(ctx)
+	require.NoError(t, err)
+	assert.Len(t, freed, 1)
+	assert.Empty(t, a.Leases())
+}
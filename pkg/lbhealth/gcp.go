@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lbhealth
+
+import (
+	"context"
+	"path"
+
+	"github.com/linki/instrumented_http"
+	"golang.org/x/oauth2/google"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+)
+
+// GCP reads the health of the backend services of a project.
+type GCP struct {
+	Project string
+	service *compute.Service
+}
+
+// NewGCP returns the health API of the backend services of the project.
+func NewGCP(ctx context.Context, project string) (*GCP, error) {
+	client, err := google.DefaultClient(ctx, compute.ComputeReadonlyScope)
+	if err != nil {
+		return nil, err
+	}
+	client = instrumented_http.NewClient(client, &instrumented_http.Callbacks{})
+	service, err := compute.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, err
+	}
+	return &GCP{Project: project, service: service}, nil
+}
+
+// Health returns the health of the forwarding rule IPs and of the backend
+// instance IPs of all the backend services, global and regional.
+func (g *GCP) Health(ctx context.Context) (map[string]bool, error) {
+	var statuses []*compute.HealthStatus
+	err := g.service.BackendServices.AggregatedList(g.Project).Context(ctx).Pages(ctx, func(page *compute.BackendServiceAggregatedList) error {
+		for _, scoped := range page.Items {
+			for _, bs := range scoped.BackendServices {
+				for _, backend := range bs.Backends {
+					health, err := g.backendHealth(ctx, bs, backend.Group)
+					if err != nil {
+						return err
+					}
+					statuses = append(statuses, health.HealthStatus...)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return healthOf(statuses), nil
+}
+
+func (g *GCP) backendHealth(ctx context.Context, bs *compute.BackendService, group string) (*compute.BackendServiceGroupHealth, error) {
+	ref := &compute.ResourceGroupReference{Group: group}
+	if bs.Region == "" {
+		return g.service.BackendServices.GetHealth(g.Project, bs.Name, ref).Context(ctx).Do()
+	}
+	return g.service.RegionBackendServices.GetHealth(g.Project, path.Base(bs.Region), bs.Name, ref).Context(ctx).Do()
+}
+
+// healthOf returns the health of the IPs of the statuses: an instance is
+// healthy if HEALTHY, a forwarding rule if one of its backends is.
+func healthOf(statuses []*compute.HealthStatus) map[string]bool {
+	health := map[string]bool{}
+	for _, s := range statuses {
+		healthy := s.HealthState == "HEALTHY"
+		if s.IpAddress != "" {
+			health[s.IpAddress] = health[s.IpAddress] || healthy
+		}
+		if s.ForwardingRuleIp != "" {
+			health[s.ForwardingRuleIp] = health[s.ForwardingRuleIp] || healthy
+		}
+	}
+	return health
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lbhealth withdraws the unhealthy targets of the multi-value A and
+// AAAA records, from the health of the backends of the load balancers - a
+// basic DNS failover for the services without a global load balancer.
+//
+// A target is the IP of a forwarding rule, healthy if one of its backends is,
+// or of a backend instance. It is withdrawn after UnhealthyThreshold
+// unhealthy checks in a row, and restored after HealthyThreshold healthy ones.
+// The targets unknown to the load balancers are kept, and so are all the
+// targets of a record when none is healthy.
+package lbhealth
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
+)
+
+var (
+	checksTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "lbhealth",
+			Name:      "checks_total",
+			Help:      "Number of reads of the health of the load balancer backends, by result (success, error).",
+		},
+		[]string{"result"},
+	)
+	withdrawnTargets = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "lbhealth",
+			Name:      "withdrawn_targets",
+			Help:      "Number of targets withdrawn from the records as unhealthy.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(checksTotal)
+	prometheus.MustRegister(withdrawnTargets)
+}
+
+// HealthAPI returns the health of the targets known to the load balancers.
+type HealthAPI interface {
+	Health(ctx context.Context) (map[string]bool, error)
+}
+
+// state is the hysteresis of a target.
+type state struct {
+	withdrawn bool
+	// streak is the number of checks in a row against the current state.
+	streak int
+}
+
+// Withdrawer is a source.Transformer removing the withdrawn targets.
+type Withdrawer struct {
+	API                HealthAPI
+	UnhealthyThreshold int
+	HealthyThreshold   int
+
+	mu      sync.Mutex
+	targets map[string]*state
+}
+
+// New returns a Withdrawer of the health API.
+func New(api HealthAPI, unhealthyThreshold, healthyThreshold int) *Withdrawer {
+	return &Withdrawer{
+		API:                api,
+		UnhealthyThreshold: unhealthyThreshold,
+		HealthyThreshold:   healthyThreshold,
+		targets:            map[string]*state{},
+	}
+}
+
+// Check reads the health of the targets, and returns whether a target was
+// withdrawn or restored. A target missing from the health is forgotten.
+func (w *Withdrawer) Check(ctx context.Context) (bool, error) {
+	health, err := w.API.Health(ctx)
+	if err != nil {
+		checksTotal.WithLabelValues("error").Inc()
+		return false, err
+	}
+	checksTotal.WithLabelValues("success").Inc()
+
+	log := logging.For("lbhealth")
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	changed := false
+	for target := range w.targets {
+		if _, ok := health[target]; !ok {
+			changed = changed || w.targets[target].withdrawn
+			delete(w.targets, target)
+		}
+	}
+	withdrawn := 0
+	for target, healthy := range health {
+		s := w.targets[target]
+		if s == nil {
+			s = &state{}
+			w.targets[target] = s
+		}
+		if healthy == !s.withdrawn {
+			s.streak = 0
+		} else {
+			s.streak++
+			threshold := w.UnhealthyThreshold
+			if s.withdrawn {
+				threshold = w.HealthyThreshold
+			}
+			if s.streak >= threshold {
+				s.withdrawn = !s.withdrawn
+				s.streak = 0
+				changed = true
+				if s.withdrawn {
+					log.Warn("Withdrawing the unhealthy target", "target", target)
+				} else {
+					log.Info("Restoring the healthy target", "target", target)
+				}
+			}
+		}
+		if s.withdrawn {
+			withdrawn++
+		}
+	}
+	withdrawnTargets.Set(float64(withdrawn))
+	return changed, nil
+}
+
+// Withdrawn returns the withdrawn targets.
+func (w *Withdrawer) Withdrawn() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var targets []string
+	for target, s := range w.targets {
+		if s.withdrawn {
+			targets = append(targets, target)
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// Transform removes the withdrawn targets of the A and AAAA endpoints, unless
+// all their targets are withdrawn.
+func (w *Withdrawer) Transform(_ context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, ep := range endpoints {
+		if ep.RecordType != endpoint.RecordTypeA && ep.RecordType != endpoint.RecordTypeAAAA || len(ep.Targets) < 2 {
+			continue
+		}
+		healthy := endpoint.Targets{}
+		for _, t := range ep.Targets {
+			if s := w.targets[t]; s == nil || !s.withdrawn {
+				healthy = append(healthy, t)
+			}
+		}
+		if len(healthy) > 0 && len(healthy) < len(ep.Targets) {
+			ep.Targets = healthy
+		}
+	}
+	return endpoints, nil
+}
+
+// Run checks the health every interval, and calls onChange when a target is
+// withdrawn or restored, until the context is done.
+func (w *Withdrawer) Run(ctx context.Context, interval time.Duration, onChange func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		changed, err := w.Check(ctx)
+		if err != nil {
+			logging.For("lbhealth").Warn("Failed to read the health of the load balancers", "error", err)
+		} else if changed {
+			onChange()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
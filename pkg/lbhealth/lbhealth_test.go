@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lbhealth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	compute "google.golang.org/api/compute/v1"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+type fakeAPI struct {
+	health map[string]bool
+	err    error
+}
+
+func (f *fakeAPI) Health(context.Context) (map[string]bool, error) {
+	return f.health, f.err
+}
+
+func TestWithdrawer(t *testing.T) {
+	ctx := context.Background()
+	api := &fakeAPI{health: map[string]bool{"10.0.0.1": true, "10.0.0.2": false, "10.0.0.3": false}}
+	w := New(api, 2, 2)
+	records := func() []*endpoint.Endpoint {
+		return []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "10.0.0.1", "10.0.0.2", "10.0.1.1"),
+			endpoint.NewEndpoint("down.example.org", endpoint.RecordTypeA, "10.0.0.2", "10.0.0.3"),
+			endpoint.NewEndpoint("single.example.org", endpoint.RecordTypeA, "10.0.0.2"),
+		}
+	}
+
+	// A target is withdrawn after UnhealthyThreshold unhealthy checks.
+	changed, err := w.Check(ctx)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Empty(t, w.Withdrawn())
+	changed, err = w.Check(ctx)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []string{"10.0.0.2", "10.0.0.3"}, w.Withdrawn())
+
+	endpoints, err := w.Transform(ctx, records())
+	require.NoError(t, err)
+	assert.Equal(t, []*endpoint.Endpoint{
+		endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "10.0.0.1", "10.0.1.1"),
+		endpoint.NewEndpoint("down.example.org", endpoint.RecordTypeA, "10.0.0.2", "10.0.0.3"),
+		endpoint.NewEndpoint("single.example.org", endpoint.RecordTypeA, "10.0.0.2"),
+	}, endpoints)
+
+	// A flapping target stays withdrawn.
+	api.health["10.0.0.2"] = true
+	changed, err = w.Check(ctx)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	api.health["10.0.0.2"] = false
+	changed, err = w.Check(ctx)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, []string{"10.0.0.2", "10.0.0.3"}, w.Withdrawn())
+
+	// It is restored after HealthyThreshold healthy checks.
+	api.health["10.0.0.2"] = true
+	for _, expected := range []bool{false, true} {
+		changed, err = w.Check(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, expected, changed)
+	}
+	assert.Equal(t, []string{"10.0.0.3"}, w.Withdrawn())
+
+	// A failed check keeps the state, a target gone from the load balancers is forgotten.
+	api.err = errors.New("unavailable")
+	_, err = w.Check(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"10.0.0.3"}, w.Withdrawn())
+	api.err = nil
+	delete(api.health, "10.0.0.3")
+	changed, err = w.Check(ctx)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Empty(t, w.Withdrawn())
+}
+
+func TestHealthOf(t *testing.T) {
+	assert.Equal(t, map[string]bool{
+		"10.0.0.1":   true,
+		"10.0.0.2":   false,
+		"10.0.0.3":   false,
+		"10.128.0.1": true,
+		"10.128.0.2": false,
+	}, healthOf([]*compute.HealthStatus{
+		{IpAddress: "10.0.0.1", ForwardingRuleIp: "10.128.0.1", HealthState: "HEALTHY"},
+		{IpAddress: "10.0.0.2", ForwardingRuleIp: "10.128.0.1", HealthState: "UNHEALTHY"},
+		{IpAddress: "10.0.0.3", ForwardingRuleIp: "10.128.0.2", HealthState: "UNHEALTHY"},
+	}))
+}
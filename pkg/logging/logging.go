@@ -0,0 +1,416 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logging configures log/slog as the single logging backend: text, JSON
+// or GCP (Cloud Logging severity) output, and levels per component and per zone
+// that can be changed at runtime. Messages logged with logrus are forwarded to
+// slog, so packages can be converted one at a time. Messages logged with a
+// context of WithTrace have the trace attribute, to correlate the messages of
+// a sync or of a webhook request.
+//
+// Packages get a logger with For, and add a "zone" attribute to messages about
+// a DNS zone:
+//
+//	log := logging.For("provider/google")
+//	log.Debug("Change zone", "zone", zone, "batch", batch)
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ComponentKey is the attribute with the component of a message, such as
+	// "controller", "source/istio-se" or "provider/google".
+	ComponentKey = "component"
+	// ZoneKey is the attribute with the DNS zone of a message.
+	ZoneKey = "zone"
+
+	// LevelTrace is below debug, for logrus trace messages.
+	LevelTrace = slog.LevelDebug - 4
+	// LevelFatal is above error, for logrus fatal and panic messages.
+	LevelFatal = slog.LevelError + 4
+
+	zonePrefix = "zone/"
+)
+
+// For returns a logger for the component, using the current default handler.
+func For(component string) *slog.Logger {
+	return slog.Default().With(ComponentKey, component)
+}
+
+// Levels holds the minimum level of messages, by default and by component or
+// zone - keyed "zone/ZONE". A message is logged if its level reaches any of the
+// levels of its component and zone, so debug can be enabled for a single zone
+// of a provider logging at info.
+type Levels struct {
+	mu     sync.RWMutex
+	def    slog.Level
+	levels map[string]slog.Level
+	// min is the lowest level, for the fast check of Handler.Enabled.
+	min atomic.Int64
+
+	// OnChange is called with the lowest level, when the levels change.
+	OnChange func(min slog.Level)
+}
+
+// NewLevels returns levels with the default level.
+func NewLevels(def slog.Level) *Levels {
+	l := &Levels{def: def, levels: map[string]slog.Level{}}
+	l.min.Store(int64(def))
+	return l
+}
+
+// SetDefault sets the level of the messages without a configured component or zone.
+func (l *Levels) SetDefault(level slog.Level) {
+	l.mu.Lock()
+	l.def = level
+	l.mu.Unlock()
+	l.changed()
+}
+
+// Set sets the level of a component, or of a zone with a "zone/" prefix.
+func (l *Levels) Set(key string, level slog.Level) {
+	l.mu.Lock()
+	l.levels[key] = level
+	l.mu.Unlock()
+	l.changed()
+}
+
+// Delete resets a component or zone to the default level.
+func (l *Levels) Delete(key string) {
+	l.mu.Lock()
+	delete(l.levels, key)
+	l.mu.Unlock()
+	l.changed()
+}
+
+// Parse sets levels from a comma separated list of KEY=LEVEL, for example
+// "controller=debug,zone/example.com=debug,source/istio-se=warn".
+func (l *Levels) Parse(spec string) error {
+	for _, kv := range strings.Split(spec, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		i := strings.LastIndex(kv, "=")
+		if i <= 0 {
+			return fmt.Errorf("invalid log level %q, expected KEY=LEVEL", kv)
+		}
+		level, err := ParseLevel(kv[i+1:])
+		if err != nil {
+			return err
+		}
+		l.Set(kv[:i], level)
+	}
+	return nil
+}
+
+// Level returns the level of the messages of a component and zone, either of
+// which can be empty.
+func (l *Levels) Level(component, zone string) slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	level, found := slog.Level(math.MaxInt), false
+	if lc, ok := l.levels[component]; ok && component != "" {
+		level, found = lc, true
+	}
+	if lz, ok := l.levels[zonePrefix+strings.TrimSuffix(zone, ".")]; ok && zone != "" && lz < level {
+		level, found = lz, true
+	}
+	if !found {
+		return l.def
+	}
+	return level
+}
+
+// Min returns the lowest configured level.
+func (l *Levels) Min() slog.Level {
+	return slog.Level(l.min.Load())
+}
+
+func (l *Levels) changed() {
+	l.mu.RLock()
+	min := l.def
+	for _, level := range l.levels {
+		if level < min {
+			min = level
+		}
+	}
+	l.mu.RUnlock()
+	l.min.Store(int64(min))
+	if l.OnChange != nil {
+		l.OnChange(min)
+	}
+}
+
+// ServeHTTP shows the levels as JSON, and changes them with
+// POST ?key=KEY&level=LEVEL - an empty key sets the default level, an empty
+// level resets the key to the default.
+func (l *Levels) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost, http.MethodPut:
+		key, value := r.FormValue("key"), r.FormValue("level")
+		switch {
+		case key == "" && value == "":
+			http.Error(w, "key or level is required", http.StatusBadRequest)
+			return
+		case value == "":
+			l.Delete(key)
+		default:
+			level, err := ParseLevel(value)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if key == "" {
+				l.SetDefault(level)
+			} else {
+				l.Set(key, level)
+			}
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	l.mu.RLock()
+	out := map[string]string{"default": levelName(l.def)}
+	keys := make([]string, 0, len(l.levels))
+	for k := range l.levels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		out[k] = levelName(l.levels[k])
+	}
+	l.mu.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// ParseLevel parses a slog or logrus level name.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "trace":
+		return LevelTrace, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	case "fatal", "panic":
+		return LevelFatal, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
+func levelName(level slog.Level) string {
+	switch level {
+	case LevelTrace:
+		return "trace"
+	case LevelFatal:
+		return "fatal"
+	}
+	return strings.ToLower(level.String())
+}
+
+// Handler filters the messages of the inner handler by the levels of their
+// component and zone.
+type Handler struct {
+	inner  slog.Handler
+	levels *Levels
+	// component and zone bound with WithAttrs, outside of groups.
+	component, zone string
+	grouped         bool
+}
+
+// NewHandler returns a handler writing text, JSON or GCP structured logs.
+func NewHandler(w io.Writer, format string, levels *Levels) (*Handler, error) {
+	// The inner handler gets all the messages, the levels are checked in Handle.
+	opts := &slog.HandlerOptions{Level: slog.Level(math.MinInt)}
+	var inner slog.Handler
+	switch format {
+	case "", "text":
+		inner = slog.NewTextHandler(w, opts)
+	case "json":
+		inner = slog.NewJSONHandler(w, opts)
+	case "gcp":
+		opts.ReplaceAttr = gcpAttr(gcpProject())
+		inner = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+	return &Handler{inner: inner, levels: levels}, nil
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.levels.Min()
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	component, zone := h.component, h.zone
+	if !h.grouped {
+		r.Attrs(func(a slog.Attr) bool {
+			switch a.Key {
+			case ComponentKey:
+				component = a.Value.String()
+			case ZoneKey:
+				zone = a.Value.String()
+			}
+			return true
+		})
+	}
+	if r.Level < h.levels.Level(component, zone) {
+		return nil
+	}
+	if id, span := TraceFrom(ctx); id != "" {
+		r = r.Clone()
+		r.AddAttrs(slog.String(TraceKey, id))
+		if span != "" {
+			r.AddAttrs(slog.String(SpanKey, span))
+		}
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.inner = h.inner.WithAttrs(attrs)
+	if !h.grouped {
+		for _, a := range attrs {
+			switch a.Key {
+			case ComponentKey:
+				h2.component = a.Value.String()
+			case ZoneKey:
+				h2.zone = a.Value.String()
+			}
+		}
+	}
+	return &h2
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.inner = h.inner.WithGroup(name)
+	h2.grouped = true
+	return &h2
+}
+
+// gcpAttr returns a function mapping the attributes to the Cloud Logging
+// structured log fields: the severity, the trace of the project - linked to
+// Cloud Trace - and the component as a label, indexed for the log-based
+// metrics and alerts.
+func gcpAttr(project string) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) > 0 {
+			return a
+		}
+		switch a.Key {
+		case slog.MessageKey:
+			a.Key = "message"
+		case slog.LevelKey:
+			a.Key = "severity"
+			level, _ := a.Value.Any().(slog.Level)
+			a.Value = slog.StringValue(gcpSeverity(level))
+		case slog.SourceKey:
+			a.Key = "logging.googleapis.com/sourceLocation"
+		case TraceKey:
+			a.Key = "logging.googleapis.com/trace"
+			if project != "" {
+				a.Value = slog.StringValue("projects/" + project + "/traces/" + a.Value.String())
+			}
+		case SpanKey:
+			a.Key = "logging.googleapis.com/spanId"
+		case ComponentKey:
+			a = slog.Group("logging.googleapis.com/labels", slog.String(ComponentKey, a.Value.String()))
+		}
+		return a
+	}
+}
+
+// gcpProject returns the project of the traces, from the environment.
+func gcpProject() string {
+	for _, env := range []string{"GOOGLE_CLOUD_PROJECT", "PROJECT_ID", "GOOGLE_PROJECT_ID"} {
+		if p := os.Getenv(env); p != "" {
+			return p
+		}
+	}
+	return ""
+}
+
+func gcpSeverity(level slog.Level) string {
+	switch {
+	case level >= LevelFatal:
+		return "CRITICAL"
+	case level >= slog.LevelError:
+		return "ERROR"
+	case level >= slog.LevelWarn:
+		return "WARNING"
+	case level >= slog.LevelInfo:
+		return "INFO"
+	}
+	return "DEBUG"
+}
+
+// Setup makes a handler writing to w the default slog handler, and forwards the
+// logrus messages to it. level is the default level, and componentLevels a
+// list for Levels.Parse.
+func Setup(w io.Writer, format, level, componentLevels string) (*Levels, error) {
+	def, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	levels := NewLevels(def)
+	if err := levels.Parse(componentLevels); err != nil {
+		return nil, err
+	}
+	h, err := NewHandler(w, format, levels)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(slog.New(h))
+
+	logrus.SetOutput(io.Discard)
+	logrus.SetFormatter(nopFormatter{})
+	logrus.AddHook(logrusHook{})
+	levels.OnChange = func(min slog.Level) {
+		logrus.SetLevel(logrusLevel(min))
+	}
+	levels.OnChange(levels.Min())
+	return levels, nil
+}
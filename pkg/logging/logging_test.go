@@ -0,0 +1,165 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lines(buf *bytes.Buffer) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		m := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &m); err == nil {
+			out = append(out, m)
+		}
+	}
+	buf.Reset()
+	return out
+}
+
+func TestLevels(t *testing.T) {
+	levels := NewLevels(slog.LevelInfo)
+	require.NoError(t, levels.Parse("provider/google=warn, zone/example.com=debug"))
+	assert.Equal(t, slog.LevelInfo, levels.Level("", ""))
+	assert.Equal(t, slog.LevelInfo, levels.Level("controller", ""))
+	assert.Equal(t, slog.LevelWarn, levels.Level("provider/google", "example.org"))
+	assert.Equal(t, slog.LevelDebug, levels.Level("provider/google", "example.com."))
+	assert.Equal(t, slog.LevelDebug, levels.Min())
+
+	levels.Delete("zone/example.com")
+	assert.Equal(t, slog.LevelInfo, levels.Min())
+
+	assert.Error(t, levels.Parse("controller"))
+	assert.Error(t, levels.Parse("controller=verbose"))
+}
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	levels := NewLevels(slog.LevelInfo)
+	h, err := NewHandler(&buf, "json", levels)
+	require.NoError(t, err)
+	log := slog.New(h).With(ComponentKey, "provider/google")
+
+	log.Debug("hidden", ZoneKey, "example.com")
+	assert.Empty(t, lines(&buf))
+
+	levels.Set("zone/example.com", slog.LevelDebug)
+	log.Debug("zone", ZoneKey, "example.com")
+	log.Debug("other zone", ZoneKey, "example.org")
+	log.With(ZoneKey, "example.com").Debug("bound zone")
+	out := lines(&buf)
+	require.Len(t, out, 2)
+	assert.Equal(t, "zone", out[0]["msg"])
+	assert.Equal(t, "provider/google", out[0][ComponentKey])
+	assert.Equal(t, "bound zone", out[1]["msg"])
+
+	levels.Set("provider/google", slog.LevelError)
+	log.Warn("hidden")
+	log.Error("shown")
+	assert.Len(t, lines(&buf), 1)
+}
+
+func TestGCPFormat(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := NewHandler(&buf, "gcp", NewLevels(slog.LevelInfo))
+	require.NoError(t, err)
+	log := slog.New(h)
+	log.Warn("careful", "records", 3)
+	log.Log(context.Background(), LevelFatal, "down")
+
+	out := lines(&buf)
+	require.Len(t, out, 2)
+	assert.Equal(t, "WARNING", out[0]["severity"])
+	assert.Equal(t, "careful", out[0]["message"])
+	assert.Equal(t, 3.0, out[0]["records"])
+	assert.Equal(t, "CRITICAL", out[1]["severity"])
+
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "my-project")
+	buf.Reset()
+	h, err = NewHandler(&buf, "gcp", NewLevels(slog.LevelInfo))
+	require.NoError(t, err)
+	ctx := WithTrace(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7")
+	slog.New(h).With(ComponentKey, "provider/google").InfoContext(ctx, "changed")
+	out = lines(&buf)
+	require.Len(t, out, 1)
+	assert.Equal(t, "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736", out[0]["logging.googleapis.com/trace"])
+	assert.Equal(t, "00f067aa0ba902b7", out[0]["logging.googleapis.com/spanId"])
+	assert.Equal(t, map[string]any{"component": "provider/google"}, out[0]["logging.googleapis.com/labels"])
+
+	_, err = NewHandler(&buf, "xml", nil)
+	assert.Error(t, err)
+}
+
+func TestSetupLogrus(t *testing.T) {
+	defer func(l *slog.Logger) { slog.SetDefault(l) }(slog.Default())
+	defer logrus.StandardLogger().ReplaceHooks(logrus.LevelHooks{})
+
+	var buf bytes.Buffer
+	levels, err := Setup(&buf, "json", "info", "controller=debug")
+	require.NoError(t, err)
+	assert.Equal(t, logrus.DebugLevel, logrus.GetLevel())
+
+	logrus.WithField(ComponentKey, "controller").Debugf("planned %d changes", 2)
+	logrus.Debug("hidden")
+	For("source/istio-se").Info("synced")
+	out := lines(&buf)
+	require.Len(t, out, 2)
+	assert.Equal(t, "planned 2 changes", out[0]["msg"])
+	assert.Equal(t, "DEBUG", out[0]["level"])
+	assert.Equal(t, "source/istio-se", out[1][ComponentKey])
+
+	levels.Delete("controller")
+	assert.Equal(t, logrus.InfoLevel, logrus.GetLevel())
+}
+
+func TestServeHTTP(t *testing.T) {
+	levels := NewLevels(slog.LevelInfo)
+
+	w := httptest.NewRecorder()
+	levels.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/loglevel?key=zone/example.com&level=debug", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"default": "info", "zone/example.com": "debug"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	levels.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/loglevel?level=warning", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, slog.LevelWarn, levels.Level("", ""))
+
+	w = httptest.NewRecorder()
+	levels.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/loglevel?key=zone/example.com", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"default": "warn"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	levels.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/loglevel?key=controller&level=loud", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
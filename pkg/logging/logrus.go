@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// logrusHook forwards the logrus messages to the default slog handler, with
+// the logrus fields as attributes.
+type logrusHook struct{}
+
+func (logrusHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (logrusHook) Fire(e *logrus.Entry) error {
+	ctx := e.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	h := slog.Default().Handler()
+	level := slogLevel(e.Level)
+	if !h.Enabled(ctx, level) {
+		return nil
+	}
+	r := slog.NewRecord(e.Time, level, e.Message, 0)
+	for k, v := range e.Data {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		r.AddAttrs(slog.Any(k, v))
+	}
+	return h.Handle(ctx, r)
+}
+
+// nopFormatter skips the formatting of the logrus messages, written by the hook.
+type nopFormatter struct{}
+
+func (nopFormatter) Format(*logrus.Entry) ([]byte, error) {
+	return nil, nil
+}
+
+func slogLevel(level logrus.Level) slog.Level {
+	switch level {
+	case logrus.TraceLevel:
+		return LevelTrace
+	case logrus.DebugLevel:
+		return slog.LevelDebug
+	case logrus.InfoLevel:
+		return slog.LevelInfo
+	case logrus.WarnLevel:
+		return slog.LevelWarn
+	case logrus.ErrorLevel:
+		return slog.LevelError
+	}
+	return LevelFatal
+}
+
+func logrusLevel(level slog.Level) logrus.Level {
+	switch {
+	case level <= LevelTrace:
+		return logrus.TraceLevel
+	case level <= slog.LevelDebug:
+		return logrus.DebugLevel
+	case level <= slog.LevelInfo:
+		return logrus.InfoLevel
+	case level <= slog.LevelWarn:
+		return logrus.WarnLevel
+	case level <= slog.LevelError:
+		return logrus.ErrorLevel
+	}
+	return logrus.FatalLevel
+}
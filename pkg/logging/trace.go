@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// TraceKey is the attribute with the trace ID of the messages logged with
+	// a context of WithTrace - a sync of the controller or a webhook request.
+	TraceKey = "trace"
+	// SpanKey is the attribute with the span ID of the trace.
+	SpanKey = "spanId"
+
+	traceparentHeader = "traceparent"
+	cloudTraceHeader  = "X-Cloud-Trace-Context"
+)
+
+type traceContextKey struct{}
+
+type trace struct {
+	id, span string
+}
+
+// WithTrace returns a context with the trace and span IDs, in hex, added to
+// the messages logged with it.
+func WithTrace(ctx context.Context, id, span string) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, trace{id: id, span: span})
+}
+
+// NewTrace returns a context with a new random trace, to correlate the
+// messages of an operation like a sync.
+func NewTrace(ctx context.Context) context.Context {
+	return WithTrace(ctx, randomHex(16), randomHex(8))
+}
+
+// TraceFrom returns the trace and span IDs of the context, empty without.
+func TraceFrom(ctx context.Context) (id, span string) {
+	t, _ := ctx.Value(traceContextKey{}).(trace)
+	return t.id, t.span
+}
+
+// SetTraceHeader sets the W3C traceparent header of an outgoing request to the
+// trace of the context, so the server logs the messages with the same trace.
+func SetTraceHeader(ctx context.Context, h http.Header) {
+	id, span := TraceFrom(ctx)
+	if id == "" {
+		return
+	}
+	if span == "" {
+		span = randomHex(8)
+	}
+	h.Set(traceparentHeader, fmt.Sprintf("00-%s-%s-01", id, span))
+}
+
+// TraceHandler adds the trace of the traceparent or X-Cloud-Trace-Context
+// header to the request context - a new trace without them.
+func TraceHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, span := parseTraceHeaders(r.Header)
+		if id == "" {
+			id, span = randomHex(16), randomHex(8)
+		}
+		next.ServeHTTP(w, r.WithContext(WithTrace(r.Context(), id, span)))
+	})
+}
+
+// parseTraceHeaders returns the trace of the traceparent header, like
+// 00-TRACE-SPAN-01, or of the X-Cloud-Trace-Context header, like
+// TRACE/SPAN;o=1 with a decimal span, converted to hex.
+func parseTraceHeaders(h http.Header) (id, span string) {
+	if parts := strings.Split(h.Get(traceparentHeader), "-"); len(parts) == 4 && len(parts[1]) == 32 && len(parts[2]) == 16 {
+		return parts[1], parts[2]
+	}
+	if v := h.Get(cloudTraceHeader); v != "" {
+		v, _, _ = strings.Cut(v, ";")
+		id, span, _ = strings.Cut(v, "/")
+		if len(id) != 32 {
+			return "", ""
+		}
+		if n, err := strconv.ParseUint(span, 10, 64); err == nil {
+			return id, fmt.Sprintf("%016x", n)
+		}
+		return id, ""
+	}
+	return "", ""
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
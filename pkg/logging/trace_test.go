@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceHandler(t *testing.T) {
+	var id, span string
+	h := TraceHandler(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		id, span = TraceFrom(r.Context())
+	}))
+
+	for name, tc := range map[string]struct {
+		header, value, id, span string
+	}{
+		"traceparent": {"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"},
+		"cloud trace": {"X-Cloud-Trace-Context", "4bf92f3577b34da6a3ce929d0e0e4736/255;o=1", "4bf92f3577b34da6a3ce929d0e0e4736", "00000000000000ff"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/records", nil)
+			r.Header.Set(tc.header, tc.value)
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			assert.Equal(t, tc.id, id)
+			assert.Equal(t, tc.span, span)
+		})
+	}
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/records", nil))
+	assert.Len(t, id, 32, "a new trace without headers")
+	assert.Len(t, span, 16)
+}
+
+func TestSetTraceHeader(t *testing.T) {
+	h := http.Header{}
+	SetTraceHeader(context.Background(), h)
+	assert.Empty(t, h.Get("traceparent"))
+
+	ctx := WithTrace(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7")
+	SetTraceHeader(ctx, h)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", h.Get("traceparent"))
+
+	id, span := parseTraceHeaders(h)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", id)
+	assert.Equal(t, "00f067aa0ba902b7", span)
+}
+
+func TestTraceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := NewHandler(&buf, "json", NewLevels(slog.LevelInfo))
+	require.NoError(t, err)
+	ctx := NewTrace(context.Background())
+	id, _ := TraceFrom(ctx)
+
+	log := slog.New(h)
+	log.InfoContext(ctx, "sync")
+	log.Info("no trace")
+	out := lines(&buf)
+	require.Len(t, out, 2)
+	assert.Equal(t, id, out[0][TraceKey])
+	assert.NotContains(t, out[1], TraceKey)
+}
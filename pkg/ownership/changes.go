@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ownership
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/planpolicy"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// The reasons of the events of the changes.
+const (
+	ReasonCreated  = "DNSRecordCreated"
+	ReasonUpdated  = "DNSRecordUpdated"
+	ReasonDeleted  = "DNSRecordDeleted"
+	ReasonRejected = "DNSChangeRejected"
+)
+
+// ReportChanges implements controller.ChangeReporter. Each resource gets an
+// event by reason listing its records: Normal events for the applied changes,
+// and Warning events for the changes denied by a policy. The other errors are
+// not reported, the changes are retried by the next syncs.
+func (r *EventReporter) ReportChanges(ctx context.Context, changes *plan.Changes, err error) {
+	if err != nil {
+		var denied *planpolicy.DeniedError
+		if !errors.As(err, &denied) {
+			return
+		}
+		var all []*endpoint.Endpoint
+		all = append(append(append(all, changes.Create...), changes.UpdateNew...), changes.Delete...)
+		r.reportRecords(ctx, all, ReasonRejected, corev1.EventTypeWarning, fmt.Sprintf("The changes of the DNS records %%s were rejected by the %s: %s", denied.Policy, denied.Reason))
+		return
+	}
+	r.reportRecords(ctx, changes.Create, ReasonCreated, corev1.EventTypeNormal, "Created the DNS records %s")
+	r.reportRecords(ctx, changes.UpdateNew, ReasonUpdated, corev1.EventTypeNormal, "Updated the DNS records %s")
+	r.reportRecords(ctx, changes.Delete, ReasonDeleted, corev1.EventTypeNormal, "Deleted the DNS records %s")
+}
+
+// reportRecords creates an event for each resource of the endpoints. The %s
+// of the message is replaced by the list of the records of the resource.
+func (r *EventReporter) reportRecords(ctx context.Context, endpoints []*endpoint.Endpoint, reason, eventType, message string) {
+	records := map[string][]string{}
+	for _, ep := range endpoints {
+		resource := ep.Labels[endpoint.ResourceLabelKey]
+		records[resource] = append(records[resource], fmt.Sprintf("%s %s", ep.RecordType, ep.DNSName))
+	}
+	for resource, names := range records {
+		ref, ok := objectReference(resource)
+		if !ok {
+			continue
+		}
+		sort.Strings(names)
+		r.createEvent(ctx, ref, reason, eventType, strings.Replace(message, "%s", strings.Join(names, ", "), 1))
+	}
+}
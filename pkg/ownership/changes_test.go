@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ownership
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/planpolicy"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+func resourceEndpoint(name, resource string) *endpoint.Endpoint {
+	ep := endpoint.NewEndpoint(name, endpoint.RecordTypeA, "1.2.3.4")
+	if resource != "" {
+		ep.Labels[endpoint.ResourceLabelKey] = resource
+	}
+	return ep
+}
+
+// listEvents returns the events of the namespace as kind/name reason type: message.
+func listEvents(t *testing.T, client *fake.Clientset, namespace string) []string {
+	t.Helper()
+	events, err := client.CoreV1().Events(namespace).List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	var list []string
+	for _, e := range events.Items {
+		list = append(list, fmt.Sprintf("%s/%s %s %s: %s", e.InvolvedObject.Kind, e.InvolvedObject.Name, e.Reason, e.Type, e.Message))
+	}
+	sort.Strings(list)
+	return list
+}
+
+func TestReportChanges(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	r := NewEventReporter(client)
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			resourceEndpoint("b.example.org", "serviceentry/default/foo"),
+			resourceEndpoint("a.example.org", "serviceentry/default/foo"),
+			resourceEndpoint("node.example.org", "node/node-1"),
+			resourceEndpoint("none.example.org", ""),
+		},
+		UpdateOld: []*endpoint.Endpoint{resourceEndpoint("ds.example.org", "daemonset/default/ds")},
+		UpdateNew: []*endpoint.Endpoint{resourceEndpoint("ds.example.org", "daemonset/default/ds")},
+		Delete:    []*endpoint.Endpoint{resourceEndpoint("gw.example.org", "httproute/default/gw")},
+	}
+
+	r.ReportChanges(context.Background(), changes, nil)
+	assert.Equal(t, []string{
+		"DaemonSet/ds DNSRecordUpdated Normal: Updated the DNS records A ds.example.org",
+		"HTTPRoute/gw DNSRecordDeleted Normal: Deleted the DNS records A gw.example.org",
+		"ServiceEntry/foo DNSRecordCreated Normal: Created the DNS records A a.example.org, A b.example.org",
+	}, listEvents(t, client, "default"))
+}
+
+func TestReportChangesRejected(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	r := NewEventReporter(client)
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{resourceEndpoint("a.example.org", "service/default/foo")},
+		Delete: []*endpoint.Endpoint{resourceEndpoint("b.example.org", "service/default/foo")},
+	}
+
+	// The failures are retried, not reported.
+	r.ReportChanges(context.Background(), changes, provider.NewSoftError(errors.New("timeout")))
+	assert.Empty(t, listEvents(t, client, "default"))
+
+	r.ReportChanges(context.Background(), changes, provider.NewSoftError(&planpolicy.DeniedError{Policy: "policy webhook", Reason: "100% frozen"}))
+	assert.Equal(t, []string{
+		"Service/foo DNSChangeRejected Warning: The changes of the DNS records A a.example.org, A b.example.org were rejected by the policy webhook: 100% frozen",
+	}, listEvents(t, client, "default"))
+}
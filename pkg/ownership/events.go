@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ownership reports the ownership conflicts of the plans, and the
+// changes of the records, as Kubernetes events of the resources of the
+// endpoints.
+package ownership
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// EventReason is the reason of the events of the ownership conflicts.
+const EventReason = "OwnershipConflict"
+
+// kinds are the kinds of the resources of the endpoints, as labeled by the
+// sources, whose name is not the lower case kind.
+var kinds = map[string]string{
+	"crd":             "DNSEndpoint",
+	"daemonset":       "DaemonSet",
+	"grpcroute":       "GRPCRoute",
+	"httproute":       "HTTPRoute",
+	"ingressroute":    "IngressRoute",
+	"ingressroutetcp": "IngressRouteTCP",
+	"ingressrouteudp": "IngressRouteUDP",
+	"proxy":           "Proxy",
+	"replicaset":      "ReplicaSet",
+	"route":           "Route",
+	"routegroup":      "RouteGroup",
+	"serviceentry":    "ServiceEntry",
+	"serviceimport":   "ServiceImport",
+	"statefulset":     "StatefulSet",
+	"tcpingress":      "TCPIngress",
+	"tcproute":        "TCPRoute",
+	"tlsroute":        "TLSRoute",
+	"udproute":        "UDPRoute",
+	"virtualservice":  "VirtualService",
+}
+
+// EventReporter reports each conflict as a Warning event of the resource of
+// the desired endpoint - like the Service or Ingress - in its namespace. The
+// conflicts of the deletes, or of the endpoints without a namespaced
+// resource, are not reported.
+type EventReporter struct {
+	Client kubernetes.Interface
+	// Component is the source of the events.
+	Component string
+}
+
+// NewEventReporter returns a reporter creating the events with the client.
+func NewEventReporter(client kubernetes.Interface) *EventReporter {
+	return &EventReporter{Client: client, Component: "external-dns"}
+}
+
+// ReportConflicts implements controller.ConflictReporter.
+func (r *EventReporter) ReportConflicts(ctx context.Context, conflicts []plan.OwnershipConflict) {
+	for _, conflict := range conflicts {
+		if conflict.Desired == nil {
+			continue
+		}
+		ref, ok := objectReference(conflict.Desired.Labels[endpoint.ResourceLabelKey])
+		if !ok {
+			continue
+		}
+		r.createEvent(ctx, ref, EventReason, corev1.EventTypeWarning, message(conflict))
+	}
+}
+
+// createEvent creates an event of the object.
+func (r *EventReporter) createEvent(ctx context.Context, ref corev1.ObjectReference, reason, eventType, msg string) {
+	now := metav1.NewTime(time.Now())
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", ref.Name, now.UnixNano()),
+			Namespace: ref.Namespace,
+		},
+		InvolvedObject: ref,
+		Reason:         reason,
+		Message:        msg,
+		Source:         corev1.EventSource{Component: r.Component},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Type:           eventType,
+	}
+	if _, err := r.Client.CoreV1().Events(ref.Namespace).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+		log.Warnf("Failed to create the %s event of %s %s/%s: %v", reason, ref.Kind, ref.Namespace, ref.Name, err)
+	}
+}
+
+// objectReference returns the reference of the resource label of an endpoint,
+// kind/namespace/name.
+func objectReference(resource string) (corev1.ObjectReference, bool) {
+	parts := strings.Split(resource, "/")
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return corev1.ObjectReference{}, false
+	}
+	kind, ok := kinds[parts[0]]
+	if !ok {
+		kind = strings.ToUpper(parts[0][:1]) + parts[0][1:]
+	}
+	return corev1.ObjectReference{Kind: kind, Namespace: parts[1], Name: parts[2]}, true
+}
+
+func message(conflict plan.OwnershipConflict) string {
+	owner := "no owner"
+	if conflict.Owner != "" {
+		owner = "owner " + conflict.Owner
+	}
+	return fmt.Sprintf("The %s of the %s record %s conflicts with the %s record of %s", conflict.Change, conflict.Desired.RecordType, conflict.Desired.DNSName, conflict.Record.RecordType, owner)
+}
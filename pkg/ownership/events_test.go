@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ownership
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestEventReporter(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	record := endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeCNAME, "lb.example.org")
+	record.Labels[endpoint.OwnerLabelKey] = "other"
+	desired := endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeCNAME, "lb2.example.org")
+	desired.Labels[endpoint.ResourceLabelKey] = "service/default/foo"
+	node := endpoint.NewEndpoint("node.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	node.Labels[endpoint.ResourceLabelKey] = "node/node-1"
+
+	NewEventReporter(client).ReportConflicts(context.Background(), []plan.OwnershipConflict{
+		{Change: plan.ConflictUpdate, Record: record, Desired: desired, Owner: "other"},
+		{Change: plan.ConflictDelete, Record: record, Owner: "other"},
+		{Change: plan.ConflictCreate, Record: record, Desired: node, Owner: "other"},
+	})
+
+	events, err := client.CoreV1().Events("default").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, events.Items, 1)
+	event := events.Items[0]
+	assert.Equal(t, corev1.ObjectReference{Kind: "Service", Namespace: "default", Name: "foo"}, event.InvolvedObject)
+	assert.Equal(t, EventReason, event.Reason)
+	assert.Equal(t, corev1.EventTypeWarning, event.Type)
+	assert.Equal(t, "The update of the CNAME record foo.example.org conflicts with the CNAME record of owner other", event.Message)
+}
+
+func TestObjectReference(t *testing.T) {
+	ref, ok := objectReference("crd/default/foo")
+	assert.True(t, ok)
+	assert.Equal(t, corev1.ObjectReference{Kind: "DNSEndpoint", Namespace: "default", Name: "foo"}, ref)
+
+	_, ok = objectReference("plugin/foo")
+	assert.False(t, ok)
+}
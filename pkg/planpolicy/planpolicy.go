@@ -0,0 +1,282 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package planpolicy reviews the changes with a policy before they are
+// applied: an external policy webhook - an OPA server or a custom service -
+// or Rego policies evaluated in the controller.
+//
+// The policy gets a Review with the changes and answers with a Decision:
+// allow applies the changes, annotate applies them and logs the annotations,
+// deny skips them, and modify applies the changes of the decision instead. The
+// modified changes can only delete or update the records the plan deletes or
+// updates, so the policy can't take over records the registry doesn't own.
+package planpolicy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// The decisions of the webhook.
+const (
+	Allow    = "allow"
+	Annotate = "annotate"
+	Deny     = "deny"
+	Modify   = "modify"
+)
+
+// ErrDenied is the error of the changes denied by the policy.
+var ErrDenied = errors.New("changes denied")
+
+// DeniedError is the error of the changes denied by a policy, it matches
+// ErrDenied.
+type DeniedError struct {
+	// Policy is the Name of the Registry.
+	Policy string
+	Reason string
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("%v by the %s: %s", ErrDenied, e.Policy, e.Reason)
+}
+
+// Is makes errors.Is match ErrDenied.
+func (e *DeniedError) Is(target error) bool {
+	return target == ErrDenied
+}
+
+// maxDecisionSize bounds the decisions read.
+const maxDecisionSize = 16 << 20
+
+var decisionsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "external_dns",
+		Subsystem: "plan_policy",
+		Name:      "decisions_total",
+		Help:      "Number of changes reviewed by the policy, by decision (allow, annotate, deny, modify, error).",
+	},
+	[]string{"decision"},
+)
+
+func init() {
+	prometheus.MustRegister(decisionsTotal)
+}
+
+// Changes are the changes of a Review or a Decision.
+type Changes struct {
+	Create    []*endpoint.Endpoint `json:"create,omitempty"`
+	UpdateOld []*endpoint.Endpoint `json:"updateOld,omitempty"`
+	UpdateNew []*endpoint.Endpoint `json:"updateNew,omitempty"`
+	Delete    []*endpoint.Endpoint `json:"delete,omitempty"`
+}
+
+// Review is the input of the policy, POSTed to the webhook.
+type Review struct {
+	OwnerID string  `json:"ownerID"`
+	Changes Changes `json:"changes"`
+	// Resources are the resources of the changed endpoints, like
+	// service/default/foo.
+	Resources []string `json:"resources,omitempty"`
+	// Zones are the domains managed by the registry.
+	Zones []string `json:"zones,omitempty"`
+}
+
+// Decision is the answer of the policy.
+type Decision struct {
+	Decision string `json:"decision"`
+	Reason   string `json:"reason,omitempty"`
+	// Annotations are logged with the annotate decision.
+	Annotations []string `json:"annotations,omitempty"`
+	// Changes replace the reviewed changes with the modify decision.
+	Changes *Changes `json:"changes,omitempty"`
+}
+
+// Reviewer decides on the changes.
+type Reviewer interface {
+	Review(ctx context.Context, review *Review) (*Decision, error)
+}
+
+// Registry reviews the changes before applying them to the wrapped registry.
+// The changes are not applied when the Reviewer fails: ApplyChanges returns a
+// soft error, and the next syncs review them again.
+type Registry struct {
+	registry.Registry
+	Reviewer Reviewer
+	// Name identifies the Reviewer in the logs and errors.
+	Name string
+}
+
+// NewRegistry returns a registry reviewing the changes with the Reviewer.
+func NewRegistry(r registry.Registry, reviewer Reviewer, name string) *Registry {
+	return &Registry{Registry: r, Reviewer: reviewer, Name: name}
+}
+
+// ApplyChanges applies the changes allowed, annotated or modified by the
+// Reviewer.
+func (r *Registry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	if !changes.HasChanges() {
+		return r.Registry.ApplyChanges(ctx, changes)
+	}
+	log := logging.For("plan-policy").With("policy", r.Name)
+	decision, err := r.review(ctx, changes)
+	if err != nil {
+		decisionsTotal.WithLabelValues("error").Inc()
+		return provider.NewSoftError(fmt.Errorf("reviewing the changes with the %s: %w", r.Name, err))
+	}
+	decisionsTotal.WithLabelValues(decision.Decision).Inc()
+	switch decision.Decision {
+	case Deny:
+		log.Warn("The policy denied the changes", "reason", decision.Reason)
+		return provider.NewSoftError(&DeniedError{Policy: r.Name, Reason: decision.Reason})
+	case Annotate:
+		for _, annotation := range decision.Annotations {
+			log.Info("Policy annotation", "annotation", annotation)
+		}
+	case Modify:
+		log.Info("The policy modified the changes", "reason", decision.Reason)
+		changes = &plan.Changes{
+			Create:    decision.Changes.Create,
+			UpdateOld: decision.Changes.UpdateOld,
+			UpdateNew: decision.Changes.UpdateNew,
+			Delete:    decision.Changes.Delete,
+		}
+	}
+	return r.Registry.ApplyChanges(ctx, changes)
+}
+
+// review asks the Reviewer for a decision and checks it.
+func (r *Registry) review(ctx context.Context, changes *plan.Changes) (*Decision, error) {
+	review := &Review{
+		OwnerID: r.OwnerID(),
+		Changes: Changes{
+			Create:    changes.Create,
+			UpdateOld: changes.UpdateOld,
+			UpdateNew: changes.UpdateNew,
+			Delete:    changes.Delete,
+		},
+		Resources: resources(changes),
+		Zones:     r.GetDomainFilter().Filters,
+	}
+	decision, err := r.Reviewer.Review(ctx, review)
+	if err != nil {
+		return nil, err
+	}
+	if err := decision.validate(changes); err != nil {
+		return nil, err
+	}
+	return decision, nil
+}
+
+// resources returns the sorted resources of the changed endpoints.
+func resources(changes *plan.Changes) []string {
+	seen := map[string]bool{}
+	for _, endpoints := range [][]*endpoint.Endpoint{changes.Create, changes.UpdateOld, changes.UpdateNew, changes.Delete} {
+		for _, ep := range endpoints {
+			if resource := ep.Labels[endpoint.ResourceLabelKey]; resource != "" {
+				seen[resource] = true
+			}
+		}
+	}
+	resources := make([]string, 0, len(seen))
+	for resource := range seen {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	return resources
+}
+
+// Webhook is a Reviewer POSTing the Review to an external policy webhook.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhook returns a Reviewer calling the webhook at the URL, timing out
+// after timeout.
+func NewWebhook(url string, timeout time.Duration) *Webhook {
+	return &Webhook{URL: url, Client: &http.Client{Timeout: timeout}}
+}
+
+// Review implements Reviewer.
+func (w *Webhook) Review(ctx context.Context, review *Review) (*Decision, error) {
+	body, err := json.Marshal(review)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var decision Decision
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDecisionSize)).Decode(&decision); err != nil {
+		return nil, fmt.Errorf("decoding the decision: %w", err)
+	}
+	return &decision, nil
+}
+
+// validate checks the decision against the reviewed changes.
+func (d *Decision) validate(changes *plan.Changes) error {
+	switch d.Decision {
+	case Allow, Annotate, Deny:
+		return nil
+	case Modify:
+	default:
+		return fmt.Errorf("unknown decision %q", d.Decision)
+	}
+	if d.Changes == nil {
+		return errors.New("modify decision without changes")
+	}
+	if len(d.Changes.UpdateOld) != len(d.Changes.UpdateNew) {
+		return errors.New("modified changes with different numbers of old and new updates")
+	}
+	current := map[endpoint.EndpointKey]bool{}
+	for _, ep := range changes.UpdateOld {
+		current[ep.Key()] = true
+	}
+	for _, ep := range changes.Delete {
+		current[ep.Key()] = true
+	}
+	for _, ep := range append(append([]*endpoint.Endpoint{}, d.Changes.UpdateOld...), d.Changes.Delete...) {
+		if ep == nil || !current[ep.Key()] {
+			return errors.New("modified changes updating or deleting records not updated or deleted by the plan")
+		}
+	}
+	return nil
+}
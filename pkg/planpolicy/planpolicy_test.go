@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package planpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// newRegistry returns a registry reviewing the changes with a webhook
+// answering decide, and the wrapped registry.
+func newRegistry(t *testing.T, decide func(Review) Decision) (*Registry, registry.Registry) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var review Review
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&review))
+		assert.Equal(t, "test", review.OwnerID)
+		require.NoError(t, json.NewEncoder(w).Encode(decide(review)))
+	}))
+	t.Cleanup(srv.Close)
+	p := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+	noop, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	txt, err := registry.NewTXTRegistry(p, "", "", "test", 0, "", []string{endpoint.RecordTypeA}, nil, false, nil)
+	require.NoError(t, err)
+	return NewRegistry(txt, NewWebhook(srv.URL, time.Second), "policy webhook"), noop
+}
+
+func names(t *testing.T, r registry.Registry) []string {
+	t.Helper()
+	records, err := r.Records(context.Background())
+	require.NoError(t, err)
+	var names []string
+	for _, ep := range records {
+		if ep.RecordType == endpoint.RecordTypeA {
+			names = append(names, ep.DNSName)
+		}
+	}
+	return names
+}
+
+func create(name string) *plan.Changes {
+	return &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint(name, endpoint.RecordTypeA, "1.2.3.4")}}
+}
+
+func TestRegistryAllow(t *testing.T) {
+	r, records := newRegistry(t, func(review Review) Decision {
+		assert.Len(t, review.Changes.Create, 1)
+		assert.Equal(t, []string{"service/default/foo"}, review.Resources)
+		return Decision{Decision: Allow}
+	})
+	changes := create("foo.example.com")
+	changes.Create[0].Labels = endpoint.Labels{endpoint.ResourceLabelKey: "service/default/foo"}
+	require.NoError(t, r.ApplyChanges(context.Background(), changes))
+	assert.Equal(t, []string{"foo.example.com"}, names(t, records))
+}
+
+func TestRegistryDeny(t *testing.T) {
+	r, records := newRegistry(t, func(Review) Decision {
+		return Decision{Decision: Deny, Reason: "frozen"}
+	})
+	err := r.ApplyChanges(context.Background(), create("foo.example.com"))
+	require.ErrorIs(t, err, provider.SoftError)
+	assert.ErrorIs(t, err, ErrDenied)
+	assert.ErrorContains(t, err, "frozen")
+	assert.Empty(t, names(t, records))
+}
+
+func TestRegistryModify(t *testing.T) {
+	r, records := newRegistry(t, func(review Review) Decision {
+		return Decision{Decision: Modify, Changes: &Changes{Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("bar.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		}}}
+	})
+	require.NoError(t, r.ApplyChanges(context.Background(), create("foo.example.com")))
+	assert.Equal(t, []string{"bar.example.com"}, names(t, records))
+}
+
+func TestRegistryInvalidDecision(t *testing.T) {
+	for name, decision := range map[string]Decision{
+		"unknown":         {Decision: "maybe"},
+		"missing changes": {Decision: Modify},
+		"unowned delete": {Decision: Modify, Changes: &Changes{Delete: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("other.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		}}},
+		"unpaired update": {Decision: Modify, Changes: &Changes{UpdateNew: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, records := newRegistry(t, func(Review) Decision { return decision })
+			err := r.ApplyChanges(context.Background(), create("foo.example.com"))
+			assert.ErrorIs(t, err, provider.SoftError)
+			assert.Empty(t, names(t, records))
+		})
+	}
+}
+
+func TestRegistryUnreachable(t *testing.T) {
+	p := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+	noop, err := registry.NewNoopRegistry(p)
+	require.NoError(t, err)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	r := NewRegistry(noop, NewWebhook(srv.URL, time.Second), "policy webhook")
+
+	err = r.ApplyChanges(context.Background(), create("foo.example.com"))
+	assert.ErrorIs(t, err, provider.SoftError)
+	assert.Empty(t, names(t, noop))
+
+	// Nothing to review.
+	assert.NoError(t, r.ApplyChanges(context.Background(), &plan.Changes{}))
+}
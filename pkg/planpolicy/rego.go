@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package planpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// RegoPackage is the package of the Rego policies. The policies define the
+// sets of messages deny and annotate, with the Review as input:
+//
+//	package externaldns
+//
+//	import rego.v1
+//
+//	deny contains msg if {
+//		some ep in input.changes.delete
+//		endswith(ep.dnsName, ".prod.example.org")
+//		msg := sprintf("%s can't be deleted", [ep.dnsName])
+//	}
+const RegoPackage = "externaldns"
+
+// Rego is a Reviewer evaluating Rego policies in the controller, for the
+// environments that can't call a policy webhook. The changes are denied if
+// deny has messages, and annotated if annotate has messages.
+type Rego struct {
+	query rego.PreparedEvalQuery
+}
+
+// LoadRego compiles the Rego policies of the files or directories.
+func LoadRego(ctx context.Context, paths []string) (*Rego, error) {
+	query, err := rego.New(
+		rego.Query("data."+RegoPackage),
+		rego.Load(paths, nil),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("compiling the Rego policies: %w", err)
+	}
+	return &Rego{query: query}, nil
+}
+
+// Review implements Reviewer.
+func (r *Rego) Review(ctx context.Context, review *Review) (*Decision, error) {
+	// The input is converted to its JSON form, so the policies see the same
+	// documents as a webhook.
+	data, err := json.Marshal(review)
+	if err != nil {
+		return nil, err
+	}
+	var input interface{}
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, err
+	}
+	results, err := r.query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return nil, fmt.Errorf("evaluating the Rego policies: %w", err)
+	}
+	var doc map[string]interface{}
+	if len(results) > 0 && len(results[0].Expressions) > 0 {
+		doc, _ = results[0].Expressions[0].Value.(map[string]interface{})
+	}
+	deny, err := messages(doc, "deny")
+	if err != nil {
+		return nil, err
+	}
+	if len(deny) > 0 {
+		return &Decision{Decision: Deny, Reason: strings.Join(deny, "; ")}, nil
+	}
+	annotate, err := messages(doc, "annotate")
+	if err != nil {
+		return nil, err
+	}
+	if len(annotate) > 0 {
+		return &Decision{Decision: Annotate, Annotations: annotate}, nil
+	}
+	return &Decision{Decision: Allow}, nil
+}
+
+// messages returns the sorted messages of the set rule of the document.
+func messages(doc map[string]interface{}, rule string) ([]string, error) {
+	value, ok := doc[rule]
+	if !ok {
+		return nil, nil
+	}
+	set, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the Rego rule %s.%s is not a set", RegoPackage, rule)
+	}
+	msgs := make([]string, 0, len(set))
+	for _, v := range set {
+		msg, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("the Rego rule %s.%s has a message that is not a string: %v", RegoPackage, rule, v)
+		}
+		msgs = append(msgs, msg)
+	}
+	sort.Strings(msgs)
+	return msgs, nil
+}
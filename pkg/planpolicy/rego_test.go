@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package planpolicy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+const testPolicy = `package externaldns
+
+import rego.v1
+
+deny contains msg if {
+	some ep in input.changes.delete
+	endswith(ep.dnsName, ".prod.example.com")
+	msg := sprintf("%s can't be deleted", [ep.dnsName])
+}
+
+annotate contains msg if {
+	some ep in input.changes.create
+	not startswith(ep.dnsName, "www.")
+	msg := sprintf("%s in zones %v", [ep.dnsName, input.zones])
+}
+`
+
+func loadTestPolicy(t *testing.T, policy string) *Rego {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.rego")
+	require.NoError(t, os.WriteFile(path, []byte(policy), 0o600))
+	r, err := LoadRego(context.Background(), []string{path})
+	require.NoError(t, err)
+	return r
+}
+
+func TestRego(t *testing.T) {
+	r := loadTestPolicy(t, testPolicy)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name     string
+		changes  Changes
+		expected *Decision
+	}{
+		{
+			name:     "allow",
+			changes:  Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeA, "1.2.3.4")}},
+			expected: &Decision{Decision: Allow},
+		},
+		{
+			name:     "annotate",
+			changes:  Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("api.example.com", endpoint.RecordTypeA, "1.2.3.4")}},
+			expected: &Decision{Decision: Annotate, Annotations: []string{`api.example.com in zones ["example.com"]`}},
+		},
+		{
+			name: "deny",
+			changes: Changes{
+				Create: []*endpoint.Endpoint{endpoint.NewEndpoint("api.example.com", endpoint.RecordTypeA, "1.2.3.4")},
+				Delete: []*endpoint.Endpoint{
+					endpoint.NewEndpoint("b.prod.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+					endpoint.NewEndpoint("a.prod.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+				},
+			},
+			expected: &Decision{Decision: Deny, Reason: "a.prod.example.com can't be deleted; b.prod.example.com can't be deleted"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			decision, err := r.Review(ctx, &Review{OwnerID: "test", Changes: tc.changes, Zones: []string{"example.com"}})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, decision)
+		})
+	}
+}
+
+func TestRegoRegistry(t *testing.T) {
+	p, records := newRegistry(t, nil)
+	reg := NewRegistry(p.Registry, loadTestPolicy(t, testPolicy), "Rego policies")
+	ctx := context.Background()
+
+	require.NoError(t, reg.ApplyChanges(ctx, create("api.example.com")))
+	assert.Equal(t, []string{"api.example.com"}, names(t, records))
+
+	err := reg.ApplyChanges(ctx, &plan.Changes{Delete: []*endpoint.Endpoint{endpoint.NewEndpoint("a.prod.example.com", endpoint.RecordTypeA, "1.2.3.4")}})
+	assert.ErrorContains(t, err, "a.prod.example.com can't be deleted")
+}
+
+func TestRegoInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.rego")
+	require.NoError(t, os.WriteFile(path, []byte("package externaldns\n\ndeny := "), 0o600))
+	_, err := LoadRego(context.Background(), []string{path})
+	assert.Error(t, err)
+
+	r := loadTestPolicy(t, "package externaldns\n\ndeny := 1\n")
+	_, err = r.Review(context.Background(), &Review{})
+	assert.ErrorContains(t, err, "not a set")
+}
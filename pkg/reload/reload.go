@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package reload reloads the configuration of the commands without restarting
+// them, on SIGHUP or a POST to the /reload endpoint of the metrics address -
+// so a filter change doesn't start the informers again, with a gap in the DNS
+// updates.
+package reload
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"sigs.k8s.io/external-dns/pkg/logging"
+)
+
+var (
+	reloadsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "config",
+			Name:      "reloads_total",
+			Help:      "Number of configuration reloads, by result (success, error).",
+		},
+		[]string{"result"},
+	)
+	lastReloadTimestamp = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "config",
+			Name:      "last_reload_success_timestamp_seconds",
+			Help:      "Timestamp of the last successful configuration reload.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(reloadsTotal)
+	prometheus.MustRegister(lastReloadTimestamp)
+}
+
+// Reloader calls Reload on SIGHUP and on POST /reload, one at a time.
+type Reloader struct {
+	// Reload reads the configuration again and applies it. On error, the
+	// current configuration is kept.
+	Reload func(ctx context.Context) error
+
+	mu sync.Mutex
+}
+
+// Run calls Reload on each SIGHUP, until the context is done.
+func (r *Reloader) Run(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			// The error is logged.
+			_ = r.Do(ctx, "SIGHUP")
+		}
+	}
+}
+
+// Do calls Reload, and logs and counts the result; trigger tells what
+// requested the reload.
+func (r *Reloader) Do(ctx context.Context, trigger string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	log := logging.For("reload")
+	if err := r.Reload(ctx); err != nil {
+		reloadsTotal.WithLabelValues("error").Inc()
+		log.Error("Failed to reload the configuration, keeping the current one", "trigger", trigger, "error", err)
+		return err
+	}
+	reloadsTotal.WithLabelValues("success").Inc()
+	lastReloadTimestamp.SetToCurrentTime()
+	log.Info("Reloaded the configuration", "trigger", trigger)
+	return nil
+}
+
+// ServeHTTP reloads the configuration on POST: 200 if it is applied, 500 with
+// the error otherwise.
+func (r *Reloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.Do(req.Context(), "http"); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte("reloaded\n"))
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reload
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloaderHTTP(t *testing.T) {
+	var err error
+	calls := 0
+	r := &Reloader{Reload: func(context.Context) error {
+		calls++
+		return err
+	}}
+	post := func(method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, "/reload", nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusMethodNotAllowed, post(http.MethodGet).Code)
+	assert.Equal(t, 0, calls)
+
+	w := post(http.MethodPost)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, calls)
+
+	err = errors.New("invalid domain filter")
+	w = post(http.MethodPost)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid domain filter")
+}
+
+func TestReloaderSignal(t *testing.T) {
+	// SIGHUP doesn't terminate the test before Run is notified.
+	ignored := make(chan os.Signal, 1)
+	signal.Notify(ignored, syscall.SIGHUP)
+	defer signal.Stop(ignored)
+
+	reloaded := make(chan struct{}, 1)
+	r := &Reloader{Reload: func(context.Context) error {
+		reloaded <- struct{}{}
+		return nil
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+
+	// Run may not be waiting for the signal yet: send it until it is received.
+	require.Eventually(t, func() bool {
+		require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGHUP))
+		select {
+		case <-reloaded:
+			return true
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 20*time.Millisecond)
+	cancel()
+	<-done
+}
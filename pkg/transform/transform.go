@@ -0,0 +1,214 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package transform rewrites the endpoints of the sources with declarative
+// rules, for the common renaming needs:
+//
+//	rules:
+//	- name: internal-suffix
+//	  match:
+//	    name: '^[^.]+$'
+//	    source: '^service/'
+//	    types: [A, AAAA]
+//	  addSuffix: .internal.example.com
+//	  setTTL: 60
+//	  addLabels:
+//	    team: platform
+//	- name: legacy-lb
+//	  match:
+//	    types: [CNAME]
+//	  rewriteTarget:
+//	    regex: '^(.*)\.old-lb\.example\.net$'
+//	    replacement: '$1.lb.example.net'
+//
+// The rules are applied in order to each endpoint, a rule sees the changes of
+// the previous ones.
+package transform
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
+)
+
+// Config is the YAML configuration of the rules.
+type Config struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule changes the endpoints matching all the fields of Match.
+type Rule struct {
+	// Name identifies the rule in errors and logs.
+	Name  string `json:"name,omitempty"`
+	Match Match  `json:"match,omitempty"`
+
+	// AddSuffix is appended to the DNS name, unless it already ends with it.
+	AddSuffix string `json:"addSuffix,omitempty"`
+	// RewriteTarget replaces the targets matching a regular expression.
+	RewriteTarget *Rewrite `json:"rewriteTarget,omitempty"`
+	// SetTTL sets the TTL of the records, in seconds.
+	SetTTL *int64 `json:"setTTL,omitempty"`
+	// AddLabels are set on the endpoints, replacing the existing values.
+	AddLabels map[string]string `json:"addLabels,omitempty"`
+}
+
+// Match selects endpoints. Empty fields match all the endpoints.
+type Match struct {
+	// Name is a regular expression matching the DNS name.
+	Name string `json:"name,omitempty"`
+	// Source is a regular expression matching the resource of the endpoint, such
+	// as "service/default/app" or "ingress/default/web".
+	Source string `json:"source,omitempty"`
+	// Types are the record types.
+	Types []string `json:"types,omitempty"`
+}
+
+// Rewrite replaces the targets matching Regex with Replacement, which can refer
+// to the groups of Regex as $1.
+type Rewrite struct {
+	Regex       string `json:"regex"`
+	Replacement string `json:"replacement"`
+}
+
+// Rules is the compiled configuration. It implements source.Transformer.
+type Rules struct {
+	rules []rule
+}
+
+type rule struct {
+	Rule
+	name, source, target *regexp.Regexp
+	types                map[string]bool
+}
+
+// Load reads the rules from the YAML file.
+func Load(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return New(cfg)
+}
+
+// New compiles the rules of the configuration.
+func New(cfg Config) (*Rules, error) {
+	rs := &Rules{}
+	for i, r := range cfg.Rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("#%d", i+1)
+		}
+		cr := rule{Rule: r}
+		var err error
+		if cr.name, err = compile(r.Match.Name); err != nil {
+			return nil, fmt.Errorf("rule %s: name: %w", r.Name, err)
+		}
+		if cr.source, err = compile(r.Match.Source); err != nil {
+			return nil, fmt.Errorf("rule %s: source: %w", r.Name, err)
+		}
+		if len(r.Match.Types) > 0 {
+			cr.types = map[string]bool{}
+			for _, t := range r.Match.Types {
+				cr.types[strings.ToUpper(t)] = true
+			}
+		}
+		if r.RewriteTarget != nil {
+			if r.RewriteTarget.Regex == "" {
+				return nil, fmt.Errorf("rule %s: rewriteTarget requires a regex", r.Name)
+			}
+			if cr.target, err = compile(r.RewriteTarget.Regex); err != nil {
+				return nil, fmt.Errorf("rule %s: rewriteTarget: %w", r.Name, err)
+			}
+		}
+		if r.SetTTL != nil && *r.SetTTL < 0 {
+			return nil, fmt.Errorf("rule %s: setTTL must not be negative", r.Name)
+		}
+		if r.AddSuffix == "" && r.RewriteTarget == nil && r.SetTTL == nil && len(r.AddLabels) == 0 {
+			return nil, fmt.Errorf("rule %s: no action", r.Name)
+		}
+		rs.rules = append(rs.rules, cr)
+	}
+	return rs, nil
+}
+
+func compile(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return regexp.Compile(expr)
+}
+
+// Transform applies the rules to the endpoints, in place.
+func (rs *Rules) Transform(_ context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	log := logging.For("transform")
+	for _, ep := range endpoints {
+		for _, r := range rs.rules {
+			if !r.matches(ep) {
+				continue
+			}
+			log.Debug("Applying rule", "rule", r.Name, "endpoint", ep.DNSName, "type", ep.RecordType)
+			r.apply(ep)
+		}
+	}
+	return endpoints, nil
+}
+
+func (r *rule) matches(ep *endpoint.Endpoint) bool {
+	if r.name != nil && !r.name.MatchString(ep.DNSName) {
+		return false
+	}
+	if r.source != nil && !r.source.MatchString(ep.Labels[endpoint.ResourceLabelKey]) {
+		return false
+	}
+	if r.types != nil && !r.types[ep.RecordType] {
+		return false
+	}
+	return true
+}
+
+func (r *rule) apply(ep *endpoint.Endpoint) {
+	if r.AddSuffix != "" && !strings.HasSuffix(ep.DNSName, r.AddSuffix) {
+		ep.DNSName += r.AddSuffix
+	}
+	if r.target != nil {
+		targets := make(endpoint.Targets, 0, len(ep.Targets))
+		for _, t := range ep.Targets {
+			targets = append(targets, r.target.ReplaceAllString(t, r.RewriteTarget.Replacement))
+		}
+		ep.Targets = targets
+	}
+	if r.SetTTL != nil {
+		ep.RecordTTL = endpoint.TTL(*r.SetTTL)
+	}
+	if len(r.AddLabels) > 0 {
+		if ep.Labels == nil {
+			ep.Labels = endpoint.NewLabels()
+		}
+		for k, v := range r.AddLabels {
+			ep.Labels[k] = v
+		}
+	}
+}
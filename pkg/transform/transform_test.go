@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transform
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const rulesYAML = `
+rules:
+- name: internal-suffix
+  match:
+    name: '^[^.]+$'
+    source: '^service/'
+    types: [a, AAAA]
+  addSuffix: .internal.example.com
+  setTTL: 60
+  addLabels:
+    team: platform
+- name: legacy-lb
+  match:
+    types: [CNAME]
+  rewriteTarget:
+    regex: '^(.*)\.old-lb\.example\.net$'
+    replacement: '$1.lb.example.net'
+`
+
+func newEndpoint(name, recordType, resource string, targets ...string) *endpoint.Endpoint {
+	ep := endpoint.NewEndpoint(name, recordType, targets...)
+	if resource != "" {
+		ep.Labels[endpoint.ResourceLabelKey] = resource
+	}
+	return ep
+}
+
+func TestTransform(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(rulesYAML), 0o644))
+	rules, err := Load(path)
+	require.NoError(t, err)
+
+	endpoints, err := rules.Transform(context.Background(), []*endpoint.Endpoint{
+		newEndpoint("app", endpoint.RecordTypeA, "service/default/app", "10.0.0.1"),
+		newEndpoint("app.example.com", endpoint.RecordTypeA, "service/default/app2", "10.0.0.2"),
+		newEndpoint("web", endpoint.RecordTypeA, "ingress/default/web", "10.0.0.3"),
+		newEndpoint("app", endpoint.RecordTypeTXT, "service/default/app", "text"),
+		newEndpoint("www.example.com", endpoint.RecordTypeCNAME, "", "a.old-lb.example.net", "b.lb.example.net"),
+	})
+	require.NoError(t, err)
+	require.Len(t, endpoints, 5)
+
+	assert.Equal(t, "app.internal.example.com", endpoints[0].DNSName)
+	assert.Equal(t, endpoint.TTL(60), endpoints[0].RecordTTL)
+	assert.Equal(t, "platform", endpoints[0].Labels["team"])
+	assert.Equal(t, "service/default/app", endpoints[0].Labels[endpoint.ResourceLabelKey])
+
+	for _, ep := range endpoints[1:4] {
+		assert.NotContains(t, ep.DNSName, "internal")
+		assert.Empty(t, ep.Labels["team"])
+	}
+	assert.Equal(t, endpoint.Targets{"a.lb.example.net", "b.lb.example.net"}, endpoints[4].Targets)
+
+	// The suffix is added once.
+	endpoints, err = rules.Transform(context.Background(), endpoints[:1])
+	require.NoError(t, err)
+	assert.Equal(t, "app.internal.example.com", endpoints[0].DNSName)
+}
+
+func TestNewInvalid(t *testing.T) {
+	ttl := int64(-1)
+	for name, rule := range map[string]Rule{
+		"regex":      {Match: Match{Name: "("}, AddSuffix: ".example.com"},
+		"source":     {Match: Match{Source: "["}, AddSuffix: ".example.com"},
+		"no action":  {Match: Match{Name: "app"}},
+		"no rewrite": {RewriteTarget: &Rewrite{Replacement: "x"}},
+		"ttl":        {SetTTL: &ttl},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(Config{Rules: []Rule{rule}})
+			assert.ErrorContains(t, err, "rule #1")
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("rules:\n- addSufix: .example.com\n"), 0o644))
+	_, err := Load(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ttlpolicy enforces the TTL rules of the domains on the endpoints of
+// the sources, after the transformations and before the providers:
+//
+//	default: 300
+//	min: 30
+//	domains:
+//	- domain: internal.example.org
+//	  default: 60
+//	  max: 300
+//
+// The endpoints without a TTL get the default, the other TTLs are kept within
+// the minimum and the maximum. The rule of a name is the one of its longest
+// matching domain, its unset fields inherit the top-level ones.
+package ttlpolicy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
+)
+
+// DefaultTTL is the TTL of the records without a TTL, for the providers
+// requiring one, when the policy sets no default.
+const DefaultTTL = 300
+
+// Config is the YAML configuration of the policy. The TTLs are in seconds, 0
+// is unset.
+type Config struct {
+	Limits
+	Domains []Domain `json:"domains,omitempty"`
+}
+
+// Limits are the TTL rules of a domain.
+type Limits struct {
+	// Default is the TTL of the endpoints without a TTL.
+	Default int64 `json:"default,omitempty"`
+	Min     int64 `json:"min,omitempty"`
+	Max     int64 `json:"max,omitempty"`
+}
+
+// Domain are the rules of a domain and its subdomains.
+type Domain struct {
+	Domain string `json:"domain"`
+	Limits
+}
+
+// Policy is the compiled configuration. It implements source.Transformer.
+type Policy struct {
+	global Limits
+	// domains are sorted by decreasing length, for the longest match.
+	domains []Domain
+}
+
+// Load reads the policy from the YAML file.
+func Load(path string) (*Policy, error) {
+	cfg, err := ReadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg)
+}
+
+// ReadConfig reads the configuration of the YAML file.
+func ReadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// New validates the configuration.
+func New(cfg Config) (*Policy, error) {
+	if err := cfg.Limits.validate(); err != nil {
+		return nil, err
+	}
+	p := &Policy{global: cfg.Limits}
+	seen := map[string]bool{}
+	for _, d := range cfg.Domains {
+		d.Domain = strings.TrimSuffix(strings.ToLower(d.Domain), ".")
+		if d.Domain == "" {
+			return nil, fmt.Errorf("domain rule without a domain")
+		}
+		if seen[d.Domain] {
+			return nil, fmt.Errorf("domain %s: duplicate rule", d.Domain)
+		}
+		seen[d.Domain] = true
+		d.Limits = d.Limits.inherit(cfg.Limits)
+		if err := d.Limits.validate(); err != nil {
+			return nil, fmt.Errorf("domain %s: %w", d.Domain, err)
+		}
+		p.domains = append(p.domains, d)
+	}
+	sort.SliceStable(p.domains, func(i, j int) bool { return len(p.domains[i].Domain) > len(p.domains[j].Domain) })
+	return p, nil
+}
+
+func (l Limits) validate() error {
+	switch {
+	case l.Default < 0 || l.Min < 0 || l.Max < 0:
+		return fmt.Errorf("the TTLs must not be negative")
+	case l.Max > 0 && l.Min > l.Max:
+		return fmt.Errorf("min %d is above max %d", l.Min, l.Max)
+	case l.Default > 0 && (l.Default < l.Min || l.Max > 0 && l.Default > l.Max):
+		return fmt.Errorf("default %d is not within min %d and max %d", l.Default, l.Min, l.Max)
+	}
+	return nil
+}
+
+// inherit returns the limits with the unset fields of the parent ones.
+func (l Limits) inherit(parent Limits) Limits {
+	if l.Default == 0 {
+		l.Default = parent.Default
+	}
+	if l.Min == 0 {
+		l.Min = parent.Min
+	}
+	if l.Max == 0 {
+		l.Max = parent.Max
+	}
+	return l
+}
+
+// limits returns the limits of a DNS name.
+func (p *Policy) limits(name string) Limits {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	for _, d := range p.domains {
+		if name == d.Domain || strings.HasSuffix(name, "."+d.Domain) {
+			return d.Limits
+		}
+	}
+	return p.global
+}
+
+// TTL returns the TTL of a record of the name with the policy.
+func (p *Policy) TTL(name string, ttl endpoint.TTL) endpoint.TTL {
+	l := p.limits(name)
+	if !ttl.IsConfigured() {
+		if l.Default == 0 {
+			return ttl
+		}
+		ttl = endpoint.TTL(l.Default)
+	}
+	if l.Min > 0 && int64(ttl) < l.Min {
+		ttl = endpoint.TTL(l.Min)
+	}
+	if l.Max > 0 && int64(ttl) > l.Max {
+		ttl = endpoint.TTL(l.Max)
+	}
+	return ttl
+}
+
+// Transform sets the TTLs of the endpoints, in place.
+func (p *Policy) Transform(_ context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	log := logging.For("ttlpolicy")
+	for _, ep := range endpoints {
+		ttl := p.TTL(ep.DNSName, ep.RecordTTL)
+		if ttl == ep.RecordTTL {
+			continue
+		}
+		if ep.RecordTTL.IsConfigured() {
+			log.Debug("Limiting the TTL", "endpoint", ep.DNSName, "type", ep.RecordType, "ttl", int64(ep.RecordTTL), "limited", int64(ttl))
+		}
+		ep.RecordTTL = ttl
+	}
+	return endpoints, nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ttlpolicy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const policyYAML = `
+default: 300
+min: 30
+domains:
+- domain: example.org
+  max: 3600
+- domain: Internal.Example.org.
+  default: 60
+  max: 300
+`
+
+func TestTransform(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ttl.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(policyYAML), 0o644))
+	p, err := Load(path)
+	require.NoError(t, err)
+
+	endpoints := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("foo.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpointWithTTL("bar.example.com", endpoint.RecordTypeA, 10, "1.2.3.4"),
+		endpoint.NewEndpointWithTTL("foo.example.org", endpoint.RecordTypeA, 86400, "1.2.3.4"),
+		endpoint.NewEndpoint("foo.internal.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpointWithTTL("internal.example.org", endpoint.RecordTypeA, 600, "1.2.3.4"),
+		endpoint.NewEndpointWithTTL("notinternal.example.org", endpoint.RecordTypeA, 600, "1.2.3.4"),
+	}
+	endpoints, err = p.Transform(context.Background(), endpoints)
+	require.NoError(t, err)
+
+	var ttls []endpoint.TTL
+	for _, ep := range endpoints {
+		ttls = append(ttls, ep.RecordTTL)
+	}
+	assert.Equal(t, []endpoint.TTL{300, 30, 3600, 60, 300, 600}, ttls)
+}
+
+func TestTTLWithoutDefault(t *testing.T) {
+	p, err := New(Config{Limits: Limits{Max: 600}})
+	require.NoError(t, err)
+
+	assert.Equal(t, endpoint.TTL(0), p.TTL("foo.example.org", 0), "the TTL stays unset without a default")
+	assert.Equal(t, endpoint.TTL(600), p.TTL("foo.example.org", 3600))
+}
+
+func TestNewErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		cfg  Config
+		err  string
+	}{
+		{"negative", Config{Limits: Limits{Min: -1}}, "negative"},
+		{"min above max", Config{Limits: Limits{Min: 600, Max: 60}}, "min 600 is above max 60"},
+		{"default outside", Config{Limits: Limits{Default: 30, Min: 60}}, "default 30 is not within"},
+		{"inherited default outside", Config{Limits: Limits{Default: 300}, Domains: []Domain{{Domain: "example.org", Limits: Limits{Max: 60}}}}, "domain example.org: default 300"},
+		{"no domain", Config{Domains: []Domain{{Limits: Limits{Max: 60}}}}, "without a domain"},
+		{"duplicate", Config{Domains: []Domain{{Domain: "example.org"}, {Domain: "example.org."}}}, "duplicate"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(tc.cfg)
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
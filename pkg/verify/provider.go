@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verify
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// Provider wraps a provider, verifying the changes after they are applied.
+//
+// By default the verification runs in the background, with the records reported
+// as pending until served, so the sync loop is not delayed. With Block set,
+// ApplyChanges returns only after the records are served, or a soft error on
+// timeout.
+type Provider struct {
+	provider.Provider
+	Verifier *Verifier
+	Block    bool
+}
+
+// NewProvider returns a provider verifying the changes applied to p.
+func NewProvider(p provider.Provider, v *Verifier) *Provider {
+	return &Provider{Provider: p, Verifier: v}
+}
+
+// ApplyChanges applies the changes and starts the verification if successful.
+func (p *Provider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	if err := p.Provider.ApplyChanges(ctx, changes); err != nil {
+		return err
+	}
+	if !changes.HasChanges() {
+		return nil
+	}
+	if p.Block {
+		if err := p.Verifier.WaitChanges(ctx, changes); err != nil {
+			return provider.NewSoftError(err)
+		}
+		return nil
+	}
+	go func() {
+		if err := p.Verifier.WaitChanges(context.WithoutCancel(ctx), changes); err != nil {
+			log.Warnf("Applied changes not served: %v", err)
+		}
+	}()
+	return nil
+}
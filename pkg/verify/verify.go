@@ -0,0 +1,270 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package verify checks that the changes applied to a provider are served, by
+// resolving the names against a set of resolvers and comparing the answers.
+package verify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+const defaultTimeout = 5 * time.Second
+
+var (
+	checksTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "verify",
+			Name:      "checks_total",
+			Help:      "Number of record checks, by resolver and result (match, mismatch, error).",
+		},
+		[]string{"resolver", "result"},
+	)
+	driftedRecords = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "verify",
+			Name:      "drifted_records",
+			Help:      "Number of applied records not served as expected, by resolver.",
+		},
+		[]string{"resolver"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(checksTotal)
+	prometheus.MustRegister(driftedRecords)
+}
+
+// Resolver is a DNS server used for verification - the provider nameservers,
+// a public resolver or the in-VPC resolver.
+type Resolver struct {
+	Name    string
+	Address string
+}
+
+// ParseResolvers parses resolvers in the NAME=HOST[:PORT] or HOST[:PORT] format.
+func ParseResolvers(specs []string) []Resolver {
+	var resolvers []Resolver
+	for _, s := range specs {
+		name, addr, ok := strings.Cut(s, "=")
+		if !ok {
+			addr = name
+		}
+		if !strings.Contains(addr, ":") || strings.HasSuffix(addr, "]") {
+			addr = addr + ":53"
+		}
+		resolvers = append(resolvers, Resolver{Name: name, Address: addr})
+	}
+	return resolvers
+}
+
+// Result is the outcome of checking a record against one resolver.
+type Result struct {
+	DNSName    string `json:"dnsName"`
+	RecordType string `json:"recordType"`
+	Resolver   string `json:"resolver"`
+
+	// Deleted is true if the record is expected to be absent.
+	Deleted  bool      `json:"deleted,omitempty"`
+	Expected []string  `json:"expected"`
+	Actual   []string  `json:"actual"`
+	Match    bool      `json:"match"`
+	Error    string    `json:"error,omitempty"`
+	Checked  time.Time `json:"checked"`
+}
+
+func (r Result) key() string {
+	return r.Resolver + "/" + r.RecordType + "/" + r.DNSName
+}
+
+// Verifier resolves the applied records and keeps the latest result for each
+// record and resolver.
+type Verifier struct {
+	Resolvers []Resolver
+	Client    *dns.Client
+
+	// Wait configures WaitServed and WaitChanges.
+	Wait WaitConfig
+
+	mu      sync.Mutex
+	results map[string]Result
+	pending map[string]int
+}
+
+// New returns a verifier using the resolvers.
+func New(resolvers []Resolver) *Verifier {
+	return &Verifier{
+		Resolvers: resolvers,
+		Client:    &dns.Client{Timeout: defaultTimeout},
+		Wait: WaitConfig{
+			Timeout:         defaultWaitTimeout,
+			InitialInterval: defaultInitialInterval,
+			MaxInterval:     defaultMaxInterval,
+		},
+		results: map[string]Result{},
+		pending: map[string]int{},
+	}
+}
+
+// Verify checks the created, updated and deleted records of the changes.
+func (v *Verifier) Verify(ctx context.Context, changes *plan.Changes) []Result {
+	var results []Result
+	for _, ep := range append(append([]*endpoint.Endpoint{}, changes.Create...), changes.UpdateNew...) {
+		results = append(results, v.Check(ctx, ep, false)...)
+	}
+	for _, ep := range changes.Delete {
+		results = append(results, v.Check(ctx, ep, true)...)
+	}
+	v.updateGauges()
+	return results
+}
+
+// Check resolves the record against all resolvers. If deleted is set, the
+// record is expected to be absent.
+func (v *Verifier) Check(ctx context.Context, ep *endpoint.Endpoint, deleted bool) []Result {
+	qtype, ok := dns.StringToType[ep.RecordType]
+	if !ok {
+		return nil
+	}
+	var expected []string
+	if !deleted {
+		for _, t := range ep.Targets {
+			expected = append(expected, normalize(ep.RecordType, t))
+		}
+		sort.Strings(expected)
+	}
+
+	var results []Result
+	for _, r := range v.Resolvers {
+		res := Result{
+			DNSName:    ep.DNSName,
+			RecordType: ep.RecordType,
+			Resolver:   r.Name,
+			Deleted:    deleted,
+			Expected:   expected,
+			Checked:    time.Now(),
+		}
+		actual, err := v.resolve(ctx, r, dns.Fqdn(ep.DNSName), qtype)
+		if err != nil {
+			res.Error = err.Error()
+			checksTotal.WithLabelValues(r.Name, "error").Inc()
+		} else {
+			res.Actual = actual
+			res.Match = equal(expected, actual)
+			if res.Match {
+				checksTotal.WithLabelValues(r.Name, "match").Inc()
+			} else {
+				checksTotal.WithLabelValues(r.Name, "mismatch").Inc()
+				log.Debugf("Record %s %s not served by %s: expected %v, got %v", ep.DNSName, ep.RecordType, r.Name, expected, actual)
+			}
+		}
+		v.mu.Lock()
+		v.results[res.key()] = res
+		v.mu.Unlock()
+		results = append(results, res)
+	}
+	return results
+}
+
+// Results returns the latest result for each record and resolver.
+func (v *Verifier) Results() []Result {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	results := make([]Result, 0, len(v.results))
+	for _, r := range v.results {
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].key() < results[j].key() })
+	return results
+}
+
+func (v *Verifier) updateGauges() {
+	drifted := map[string]float64{}
+	for _, r := range v.Resolvers {
+		drifted[r.Name] = 0
+	}
+	for _, res := range v.Results() {
+		if !res.Match {
+			drifted[res.Resolver]++
+		}
+	}
+	for name, n := range drifted {
+		driftedRecords.WithLabelValues(name).Set(n)
+	}
+}
+
+// resolve returns the normalized answers of the requested type. A missing
+// name is not an error - the answer is empty.
+func (v *Verifier) resolve(ctx context.Context, r Resolver, name string, qtype uint16) ([]string, error) {
+	req := new(dns.Msg)
+	req.SetQuestion(name, qtype)
+	res, _, err := v.Client.ExchangeContext(ctx, req, r.Address)
+	if err != nil {
+		return nil, err
+	}
+	if res.Rcode != dns.RcodeSuccess && res.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("%s: %s", r.Address, dns.RcodeToString[res.Rcode])
+	}
+	var actual []string
+	for _, rr := range res.Answer {
+		if rr.Header().Rrtype != qtype || !strings.EqualFold(rr.Header().Name, name) {
+			continue
+		}
+		rtype := dns.TypeToString[qtype]
+		if txt, ok := rr.(*dns.TXT); ok {
+			actual = append(actual, normalize(rtype, strings.Join(txt.Txt, "")))
+			continue
+		}
+		actual = append(actual, normalize(rtype, strings.TrimPrefix(rr.String(), rr.Header().String())))
+	}
+	sort.Strings(actual)
+	return actual, nil
+}
+
+// normalize returns a comparable form of a target - names are case-insensitive
+// and may have a trailing dot.
+func normalize(recordType, target string) string {
+	if recordType == endpoint.RecordTypeTXT {
+		return strings.Trim(target, "\"")
+	}
+	return strings.TrimSuffix(strings.ToLower(target), ".")
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
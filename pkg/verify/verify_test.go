@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verify
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/dnsserver"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+// startResolver serves the records of the provider over UDP.
+func startResolver(t *testing.T, p *inmemory.InMemoryProvider) string {
+	s := dnsserver.New(p)
+	s.RefreshInterval = 0
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &dns.Server{PacketConn: pc, Handler: s}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+func TestParseResolvers(t *testing.T) {
+	assert.Equal(t, []Resolver{
+		{Name: "8.8.8.8", Address: "8.8.8.8:53"},
+		{Name: "vpc", Address: "10.0.0.2:5353"},
+		{Name: "v6", Address: "[fd00::1]:53"},
+	}, ParseResolvers([]string{"8.8.8.8", "vpc=10.0.0.2:5353", "v6=[fd00::1]"}))
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	served := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+	stale := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+
+	v := New([]Resolver{{Name: "served", Address: startResolver(t, served)}, {Name: "stale", Address: startResolver(t, stale)}})
+	p := NewProvider(served, v)
+
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "10.0.0.1", "10.0.0.2"),
+			endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeCNAME, "A.example.com"),
+			endpoint.NewEndpoint("txt.example.com", endpoint.RecordTypeTXT, "heritage=external-dns"),
+		},
+	}
+	require.NoError(t, p.ApplyChanges(ctx, changes))
+	results := v.Verify(ctx, changes)
+	require.Len(t, results, 6)
+	for _, r := range results {
+		assert.Equal(t, r.Resolver == "served", r.Match, "%s %s %s", r.Resolver, r.DNSName, r.Actual)
+		assert.Empty(t, r.Error)
+	}
+
+	// Deleted records are expected to be absent.
+	deleted := &plan.Changes{Delete: []*endpoint.Endpoint{changes.Create[0]}}
+	require.NoError(t, served.ApplyChanges(ctx, deleted))
+	results = v.Verify(ctx, deleted)
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Match)
+	assert.True(t, results[1].Match)
+
+	assert.Len(t, v.Results(), 6)
+}
+
+func TestVerifyError(t *testing.T) {
+	v := New([]Resolver{{Name: "down", Address: "127.0.0.1:1"}})
+	v.Client.Timeout = 100 * time.Millisecond
+	results := v.Check(context.Background(), endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "10.0.0.1"), false)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Match)
+	assert.NotEmpty(t, results[0].Error)
+}
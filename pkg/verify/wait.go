@@ -0,0 +1,191 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+const (
+	// StatusServed means all resolvers return the expected answers.
+	StatusServed = "served"
+	// StatusPending means the record was applied, and is rechecked until served or timeout.
+	StatusPending = "pending"
+	// StatusNotServed means the last check didn't return the expected answers.
+	StatusNotServed = "not-served"
+
+	defaultWaitTimeout     = 5 * time.Minute
+	defaultInitialInterval = time.Second
+	defaultMaxInterval     = 30 * time.Second
+)
+
+// ErrNotServed is returned when a record is not served before the timeout.
+var ErrNotServed = errors.New("record not served before timeout")
+
+// WaitConfig controls the rechecks of applied records.
+type WaitConfig struct {
+	// Timeout is the max time to wait for each record.
+	Timeout time.Duration
+	// InitialInterval is the first recheck interval, doubled up to MaxInterval.
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+}
+
+func recordKey(recordType, name string) string {
+	return recordType + "/" + strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// WaitServed checks the record until all resolvers serve the expected answers,
+// with exponential backoff between checks. The record is reported as pending
+// while waiting.
+func (v *Verifier) WaitServed(ctx context.Context, ep *endpoint.Endpoint, deleted bool) error {
+	key := recordKey(ep.RecordType, ep.DNSName)
+	v.mu.Lock()
+	v.pending[key]++
+	v.mu.Unlock()
+	defer func() {
+		v.mu.Lock()
+		if v.pending[key]--; v.pending[key] == 0 {
+			delete(v.pending, key)
+		}
+		v.mu.Unlock()
+	}()
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = v.Wait.InitialInterval
+	b.MaxInterval = v.Wait.MaxInterval
+	b.MaxElapsedTime = v.Wait.Timeout
+
+	err := backoff.Retry(func() error {
+		for _, r := range v.Check(ctx, ep, deleted) {
+			if !r.Match {
+				return fmt.Errorf("%s %s on %s: expected %v, got %v %s", ep.DNSName, ep.RecordType, r.Resolver, r.Expected, r.Actual, r.Error)
+			}
+		}
+		return nil
+	}, backoff.WithContext(b, ctx))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotServed, err)
+	}
+	return nil
+}
+
+// WaitChanges waits until all records of the changes are served, or deleted.
+// Records are checked concurrently, and a failed record doesn't stop the others.
+func (v *Verifier) WaitChanges(ctx context.Context, changes *plan.Changes) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	wait := func(ep *endpoint.Endpoint, deleted bool) {
+		defer wg.Done()
+		if err := v.WaitServed(ctx, ep, deleted); err != nil {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}
+	}
+	for _, ep := range append(append([]*endpoint.Endpoint{}, changes.Create...), changes.UpdateNew...) {
+		wg.Add(1)
+		go wait(ep, false)
+	}
+	for _, ep := range changes.Delete {
+		wg.Add(1)
+		go wait(ep, true)
+	}
+	wg.Wait()
+	v.updateGauges()
+	return errors.Join(errs...)
+}
+
+// Status returns the status of the record, and the latest results.
+func (v *Verifier) Status(recordType, name string) (string, []Result) {
+	key := recordKey(recordType, name)
+	var results []Result
+	for _, r := range v.Results() {
+		if recordKey(r.RecordType, r.DNSName) == key {
+			results = append(results, r)
+		}
+	}
+
+	v.mu.Lock()
+	pending := v.pending[key] > 0
+	v.mu.Unlock()
+	if pending {
+		return StatusPending, results
+	}
+	if len(results) == 0 {
+		return "", nil
+	}
+	for _, r := range results {
+		if !r.Match {
+			return StatusNotServed, results
+		}
+	}
+	return StatusServed, results
+}
+
+type statusResponse struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Status  string   `json:"status"`
+	Results []Result `json:"results,omitempty"`
+}
+
+// ServeHTTP returns the status of a record as JSON - for example for automation
+// issuing certificates or shifting traffic once the record is served:
+//
+//	GET /verify?name=www.example.com&type=A
+//
+// The response code is 200 if served, 503 if pending or not served and 404 if unknown.
+func (v *Verifier) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "missing name", http.StatusBadRequest)
+		return
+	}
+	recordType := r.URL.Query().Get("type")
+	if recordType == "" {
+		recordType = endpoint.RecordTypeA
+	}
+	status, results := v.Status(strings.ToUpper(recordType), name)
+
+	code := http.StatusServiceUnavailable
+	switch status {
+	case StatusServed:
+		code = http.StatusOK
+	case "":
+		code = http.StatusNotFound
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(statusResponse{Name: name, Type: recordType, Status: status, Results: results}); err != nil {
+		log.Debugf("Failed to write verify status: %v", err)
+	}
+}
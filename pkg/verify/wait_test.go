@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package verify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+func fastVerifier(addr string) *Verifier {
+	v := New([]Resolver{{Name: "test", Address: addr}})
+	v.Wait = WaitConfig{Timeout: 2 * time.Second, InitialInterval: 10 * time.Millisecond, MaxInterval: 50 * time.Millisecond}
+	return v
+}
+
+func statusCode(v *Verifier, name string) int {
+	w := httptest.NewRecorder()
+	v.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/verify?name="+name, nil))
+	return w.Code
+}
+
+func TestWaitServed(t *testing.T) {
+	ctx := context.Background()
+	served := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+	v := fastVerifier(startResolver(t, served))
+	ep := endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "10.0.0.1")
+	assert.Equal(t, http.StatusNotFound, statusCode(v, "a.example.com"))
+
+	// The record is served after a delay, like a slow propagating provider.
+	done := make(chan error)
+	go func() { done <- v.WaitServed(ctx, ep, false) }()
+	require.Eventually(t, func() bool {
+		return statusCode(v, "a.example.com") == http.StatusServiceUnavailable
+	}, time.Second, 5*time.Millisecond)
+	status, _ := v.Status(endpoint.RecordTypeA, "a.example.com")
+	assert.Equal(t, StatusPending, status)
+
+	require.NoError(t, served.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{ep}}))
+	require.NoError(t, <-done)
+	assert.Equal(t, http.StatusOK, statusCode(v, "a.example.com"))
+
+	// Never served.
+	v.Wait.Timeout = 100 * time.Millisecond
+	err := v.WaitServed(ctx, endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "10.0.0.2"), false)
+	assert.ErrorIs(t, err, ErrNotServed)
+	status, _ = v.Status(endpoint.RecordTypeA, "b.example.com")
+	assert.Equal(t, StatusNotServed, status)
+}
+
+// unservedProvider accepts the changes, without serving them.
+type unservedProvider struct {
+	*inmemory.InMemoryProvider
+}
+
+func (unservedProvider) ApplyChanges(context.Context, *plan.Changes) error { return nil }
+
+func TestBlockingProvider(t *testing.T) {
+	ctx := context.Background()
+	served := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+	v := fastVerifier(startResolver(t, served))
+	changes := &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "10.0.0.1")}}
+
+	p := NewProvider(served, v)
+	p.Block = true
+	require.NoError(t, p.ApplyChanges(ctx, changes))
+
+	v.Wait.Timeout = 100 * time.Millisecond
+	p = NewProvider(unservedProvider{served}, v)
+	p.Block = true
+	err := p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "10.0.0.2")}})
+	assert.True(t, errors.Is(err, provider.SoftError))
+	assert.ErrorIs(t, err, ErrNotServed)
+}
@@ -0,0 +1,10 @@
+;; A transformation returning constant endpoints, ignoring its input.
+;; The binary is built by hand - constant.wasm.
+(module
+  (memory (export "memory") 1)
+  (data (i32.const 0) "[{\"dnsName\":\"renamed.example.com\",\"recordType\":\"A\",\"targets\":[\"1.2.3.4\"]}]")
+  (func (export "alloc") (param i32) (result i32)
+    i32.const 1024)
+  (func (export "transform") (param i32 i32) (result i64)
+    ;; Offset 0 in the high 32 bits, the length of the data in the low 32 bits.
+    i64.const 74))
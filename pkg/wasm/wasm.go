@@ -0,0 +1,201 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package wasm runs WebAssembly modules rewriting the endpoints of the sources
+// before they are planned, for policies that the flags can't express.
+//
+// A module is a WASI reactor exporting:
+//
+//   - memory: the linear memory.
+//   - alloc(size i32) i32: returns a buffer of size bytes for the input.
+//   - transform(ptr i32, len i32) i64: gets the endpoints as a JSON list in the
+//     buffer, and returns the transformed endpoints as a JSON list, located by
+//     the offset in the high 32 bits and the length in the low 32 bits.
+//
+// A module is instantiated for each transformation, so it keeps no state between
+// syncs. The output of the module goes to the log.
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
+)
+
+const (
+	// DefaultTimeout is the default limit of the duration of a transformation.
+	DefaultTimeout = 10 * time.Second
+	// DefaultMemoryLimit is the default limit of the memory of a module, in bytes.
+	DefaultMemoryLimit = 64 << 20
+
+	pageSize = 64 << 10
+)
+
+// Transformer rewrites endpoints with a WebAssembly module. It implements
+// source.Transformer.
+type Transformer struct {
+	name     string
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	timeout  time.Duration
+	log      *slog.Logger
+}
+
+// Options are the limits of a module. Zero values select the defaults.
+type Options struct {
+	// Timeout limits the duration of a transformation.
+	Timeout time.Duration
+	// MemoryLimit limits the memory of the module, in bytes.
+	MemoryLimit uint32
+}
+
+// Load compiles the module in the file.
+func Load(ctx context.Context, path string, opts Options) (*Transformer, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(ctx, path, code, opts)
+}
+
+// New compiles the module. name identifies the module in errors and logs.
+func New(ctx context.Context, name string, code []byte, opts Options) (*Transformer, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MemoryLimit == 0 {
+		opts.MemoryLimit = DefaultMemoryLimit
+	}
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(max(opts.MemoryLimit/pageSize, 1)))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		r.Close(ctx)
+		return nil, fmt.Errorf("wasm %s: %w", name, err)
+	}
+	compiled, err := r.CompileModule(ctx, code)
+	if err != nil {
+		r.Close(ctx)
+		return nil, fmt.Errorf("wasm %s: %w", name, err)
+	}
+	for _, fn := range []string{"alloc", "transform"} {
+		if _, ok := compiled.ExportedFunctions()[fn]; !ok {
+			r.Close(ctx)
+			return nil, fmt.Errorf("wasm %s: the module doesn't export %s", name, fn)
+		}
+	}
+	if _, ok := compiled.ExportedMemories()["memory"]; !ok {
+		r.Close(ctx)
+		return nil, fmt.Errorf("wasm %s: the module doesn't export memory", name)
+	}
+	return &Transformer{
+		name:     name,
+		runtime:  r,
+		compiled: compiled,
+		timeout:  opts.Timeout,
+		log:      logging.For("wasm").With("module", name),
+	}, nil
+}
+
+// Close releases the compiled module.
+func (t *Transformer) Close(ctx context.Context) error {
+	return t.runtime.Close(ctx)
+}
+
+// Transform runs the module with the endpoints, and returns its endpoints.
+func (t *Transformer) Transform(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	if endpoints == nil {
+		endpoints = []*endpoint.Endpoint{}
+	}
+	in, err := json.Marshal(endpoints)
+	if err != nil {
+		return nil, err
+	}
+	out, err := t.call(ctx, in)
+	if err != nil {
+		return nil, fmt.Errorf("wasm %s: %w", t.name, err)
+	}
+
+	var result []*endpoint.Endpoint
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("wasm %s: parsing the output: %w", t.name, err)
+	}
+	for _, ep := range result {
+		if ep.Labels == nil {
+			ep.Labels = endpoint.NewLabels()
+		}
+	}
+	t.log.Debug("Transformed endpoints", "in", len(endpoints), "out", len(result))
+	return result, nil
+}
+
+func (t *Transformer) call(ctx context.Context, in []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	output := &logWriter{log: t.log}
+	// An anonymous module, so concurrent transformations don't conflict.
+	mod, err := t.runtime.InstantiateModule(ctx, t.compiled, wazero.NewModuleConfig().
+		WithName("").
+		WithStdout(output).
+		WithStderr(output).
+		WithStartFunctions("_initialize"))
+	if err != nil {
+		return nil, err
+	}
+	defer mod.Close(ctx)
+
+	res, err := mod.ExportedFunction("alloc").Call(ctx, api.EncodeU32(uint32(len(in))))
+	if err != nil {
+		return nil, fmt.Errorf("alloc: %w", err)
+	}
+	ptr := api.DecodeU32(res[0])
+	if !mod.Memory().Write(ptr, in) {
+		return nil, fmt.Errorf("alloc returned %d, out of the memory", ptr)
+	}
+	res, err = mod.ExportedFunction("transform").Call(ctx, api.EncodeU32(ptr), api.EncodeU32(uint32(len(in))))
+	if err != nil {
+		return nil, fmt.Errorf("transform: %w", err)
+	}
+	outPtr, outLen := uint32(res[0]>>32), uint32(res[0])
+	out, ok := mod.Memory().Read(outPtr, outLen)
+	if !ok {
+		return nil, fmt.Errorf("transform returned %d bytes at %d, out of the memory", outLen, outPtr)
+	}
+	// Read returns a view of the memory, closed with the module.
+	return append([]byte(nil), out...), nil
+}
+
+// logWriter logs the output of a module.
+type logWriter struct {
+	log *slog.Logger
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.log.Info(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
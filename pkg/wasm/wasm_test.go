@@ -0,0 +1,60 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestTransform(t *testing.T) {
+	ctx := context.Background()
+	tr, err := Load(ctx, "testdata/constant.wasm", Options{})
+	require.NoError(t, err)
+	defer tr.Close(ctx)
+
+	endpoints, err := tr.Transform(ctx, []*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.com", endpoint.RecordTypeA, "1.2.3.4"),
+	})
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, "renamed.example.com", endpoints[0].DNSName)
+	assert.Equal(t, endpoint.Targets{"1.2.3.4"}, endpoints[0].Targets)
+	assert.NotNil(t, endpoints[0].Labels)
+
+	// A module is instantiated for each call.
+	_, err = tr.Transform(ctx, nil)
+	require.NoError(t, err)
+}
+
+func TestNewInvalid(t *testing.T) {
+	ctx := context.Background()
+	_, err := New(ctx, "garbage", []byte("not wasm"), Options{})
+	assert.ErrorContains(t, err, "wasm garbage")
+
+	// An empty module, without the exports.
+	_, err = New(ctx, "empty", []byte("\x00asm\x01\x00\x00\x00"), Options{})
+	assert.ErrorContains(t, err, "doesn't export alloc")
+
+	_, err = Load(ctx, "testdata/missing.wasm", Options{})
+	assert.Error(t, err)
+}
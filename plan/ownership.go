@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// The changes of an OwnershipConflict.
+const (
+	ConflictCreate = "create"
+	ConflictUpdate = "update"
+	ConflictDelete = "delete"
+)
+
+// OwnershipConflict is a change of a desired name to a record of another
+// owner, or of no owner, as labeled by the registry.
+type OwnershipConflict struct {
+	// Change is ConflictCreate for a record created next to the record of
+	// another owner, ConflictUpdate or ConflictDelete for the record itself.
+	Change string `json:"change"`
+	// Record is the current record of the other owner.
+	Record *endpoint.Endpoint `json:"record"`
+	// Desired is the desired record, nil for a delete.
+	Desired *endpoint.Endpoint `json:"desired,omitempty"`
+	// Owner is the owner of the record, empty if unowned.
+	Owner string `json:"owner"`
+}
+
+func newOwnershipConflict(change string, record, desired *endpoint.Endpoint) OwnershipConflict {
+	return OwnershipConflict{Change: change, Record: record, Desired: desired, Owner: record.Labels[endpoint.OwnerLabelKey]}
+}
+
+// foreignDeletes returns the conflicts of the deletes of records of another
+// owner for desired names, like a record type not desired anymore. They are
+// not planned, even with AdoptForeign; the deletes of the names not desired
+// are not conflicts, the records of other owners are left alone.
+func (p *Plan) foreignDeletes(t planTable, deletes []*endpoint.Endpoint) []OwnershipConflict {
+	var conflicts []OwnershipConflict
+	for _, ep := range deletes {
+		if ep.IsOwnedBy(p.OwnerID) {
+			continue
+		}
+		row := t.rows[planKey{dnsName: normalizeDNSName(ep.DNSName), setIdentifier: ep.SetIdentifier, view: ep.View()}]
+		if row != nil && len(row.candidates) > 0 {
+			conflicts = append(conflicts, newOwnershipConflict(ConflictDelete, ep, nil))
+		}
+	}
+	return conflicts
+}
+
+// filterForeignUpdates removes the updates of the records of another owner
+// from the changes, and returns their conflicts. With AdoptForeign, the
+// updates are kept, and the new records owned by OwnerID; with AdoptUnowned,
+// the ones of the records of no owner.
+func (p *Plan) filterForeignUpdates(changes *Changes) []OwnershipConflict {
+	var conflicts []OwnershipConflict
+	updateOld, updateNew := []*endpoint.Endpoint{}, []*endpoint.Endpoint{}
+	for i, old := range changes.UpdateOld {
+		update := changes.UpdateNew[i]
+		if !old.IsOwnedBy(p.OwnerID) {
+			conflicts = append(conflicts, newOwnershipConflict(ConflictUpdate, old, update))
+			if !p.adopts(old) {
+				continue
+			}
+			update.Labels[endpoint.OwnerLabelKey] = p.OwnerID
+		}
+		updateOld, updateNew = append(updateOld, old), append(updateNew, update)
+	}
+	changes.UpdateOld, changes.UpdateNew = updateOld, updateNew
+	return conflicts
+}
+
+// adopts returns true if the plan takes the ownership of the record of
+// another owner, or of no owner.
+func (p *Plan) adopts(ep *endpoint.Endpoint) bool {
+	return p.AdoptForeign || (p.AdoptUnowned && ep.Labels[endpoint.OwnerLabelKey] == "")
+}
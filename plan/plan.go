@@ -49,6 +49,20 @@ type Plan struct {
 	ExcludeRecords []string
 	// OwnerID of records to manage
 	OwnerID string
+	// AdoptForeign plans the changes of the desired names to the records of
+	// another owner, or of no owner, taking their ownership: the updates and
+	// creates of the OwnershipConflicts are planned, but not the deletes, nor
+	// the creates of a name with a record of another owner to delete.
+	AdoptForeign bool
+	// AdoptUnowned takes the ownership of the records of no owner of the
+	// desired names, like the records created by hand in a zone migrated to
+	// ExternalDNS: as AdoptForeign for the records of no owner, and the
+	// records matching the desired ones are claimed as they are.
+	AdoptUnowned bool
+	// OwnershipConflicts are the changes of the desired names to the records
+	// of another owner, or of no owner, not planned unless adopted.
+	// Populated after calling Calculate()
+	OwnershipConflicts []OwnershipConflict
 }
 
 // Changes holds lists of actions to be executed by dns providers
@@ -67,6 +81,8 @@ type Changes struct {
 type planKey struct {
 	dnsName       string
 	setIdentifier string
+	// view separates the records of the split views of a name.
+	view string
 }
 
 // planTable is a supplementary struct for Plan
@@ -139,6 +155,7 @@ func (t *planTable) newPlanKey(e *endpoint.Endpoint) planKey {
 	key := planKey{
 		dnsName:       normalizeDNSName(e.DNSName),
 		setIdentifier: e.SetIdentifier,
+		view:          e.View(),
 	}
 
 	if _, ok := t.rows[key]; !ok {
@@ -179,6 +196,7 @@ func (p *Plan) Calculate() *Plan {
 	}
 
 	changes := &Changes{}
+	var conflicts []OwnershipConflict
 
 	for key, row := range t.rows {
 		// dns name not taken
@@ -199,6 +217,8 @@ func (p *Plan) Calculate() *Plan {
 		// dns name is taken
 		if len(row.current) > 0 && len(row.candidates) > 0 {
 			creates := []*endpoint.Endpoint{}
+			// the records of other owners are never deleted, even adopted
+			foreignDeleted := false
 
 			// apply changes for each record type
 			recordsByType := t.resolver.ResolveRecordTypes(key, row)
@@ -206,6 +226,7 @@ func (p *Plan) Calculate() *Plan {
 				// record type not desired
 				if records.current != nil && len(records.candidates) == 0 {
 					changes.Delete = append(changes.Delete, records.current)
+					foreignDeleted = foreignDeleted || (p.OwnerID != "" && !records.current.IsOwnedBy(p.OwnerID))
 				}
 
 				// new record type desired
@@ -225,22 +246,35 @@ func (p *Plan) Calculate() *Plan {
 						inheritOwner(records.current, update)
 						changes.UpdateNew = append(changes.UpdateNew, update)
 						changes.UpdateOld = append(changes.UpdateOld, records.current)
+					} else if p.OwnerID != "" && p.AdoptUnowned && records.current.Labels[endpoint.OwnerLabelKey] == "" {
+						// claim the record as it is, labeled by filterForeignUpdates
+						claimed := records.current.DeepCopy()
+						inheritOwner(records.current, claimed)
+						changes.UpdateNew = append(changes.UpdateNew, claimed)
+						changes.UpdateOld = append(changes.UpdateOld, records.current)
 					}
 				}
 			}
 
 			if len(creates) > 0 {
 				// only add creates if the external dns has ownership claim on the domain
-				ownersMatch := true
+				var foreign *endpoint.Endpoint
+				adopted := true
 				for _, current := range row.current {
 					if p.OwnerID != "" && !current.IsOwnedBy(p.OwnerID) {
-						ownersMatch = false
+						foreign = current
+						adopted = adopted && p.adopts(current)
 					}
 				}
 
-				if ownersMatch {
+				if foreign == nil || (adopted && !foreignDeleted) {
 					changes.Create = append(changes.Create, creates...)
 				}
+				if foreign != nil {
+					for _, create := range creates {
+						conflicts = append(conflicts, newOwnershipConflict(ConflictCreate, foreign, create))
+					}
+				}
 			}
 		}
 	}
@@ -251,17 +285,20 @@ func (p *Plan) Calculate() *Plan {
 
 	// filter out updates this external dns does not have ownership claim over
 	if p.OwnerID != "" {
-		changes.Delete = endpoint.FilterEndpointsByOwnerID(p.OwnerID, changes.Delete)
 		changes.Delete = endpoint.RemoveDuplicates(changes.Delete)
-		changes.UpdateOld = endpoint.FilterEndpointsByOwnerID(p.OwnerID, changes.UpdateOld)
-		changes.UpdateNew = endpoint.FilterEndpointsByOwnerID(p.OwnerID, changes.UpdateNew)
+		conflicts = append(conflicts, p.foreignDeletes(t, changes.Delete)...)
+		changes.Delete = endpoint.FilterEndpointsByOwnerID(p.OwnerID, changes.Delete)
+		conflicts = append(conflicts, p.filterForeignUpdates(changes)...)
 	}
 
 	plan := &Plan{
-		Current:        p.Current,
-		Desired:        p.Desired,
-		Changes:        changes,
-		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME},
+		Current:            p.Current,
+		Desired:            p.Desired,
+		Changes:            changes,
+		ManagedRecords:     []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME},
+		AdoptForeign:       p.AdoptForeign,
+		AdoptUnowned:       p.AdoptUnowned,
+		OwnershipConflicts: conflicts,
 	}
 
 	return plan
@@ -568,6 +568,114 @@ func (suite *PlanTestSuite) TestExistingOwnerNotMatchingDualStackDesired() {
 	validateEntries(suite.T(), changes.Delete, expectedDelete)
 }
 
+func (suite *PlanTestSuite) TestOwnershipConflictUpdate() {
+	current := []*endpoint.Endpoint{suite.fooV1Cname}
+	desired := []*endpoint.Endpoint{suite.fooV2Cname}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME},
+		OwnerID:        "other",
+	}
+
+	plan := p.Calculate()
+	validateEntries(suite.T(), plan.Changes.UpdateNew, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), plan.Changes.UpdateOld, []*endpoint.Endpoint{})
+	suite.Require().Len(plan.OwnershipConflicts, 1)
+	suite.Equal(OwnershipConflict{Change: ConflictUpdate, Record: suite.fooV1Cname, Desired: suite.fooV2Cname, Owner: "pwner"}, plan.OwnershipConflicts[0])
+
+	p.AdoptForeign = true
+	plan = p.Calculate()
+	validateEntries(suite.T(), plan.Changes.UpdateNew, []*endpoint.Endpoint{suite.fooV2Cname})
+	validateEntries(suite.T(), plan.Changes.UpdateOld, []*endpoint.Endpoint{suite.fooV1Cname})
+	suite.Equal("other", plan.Changes.UpdateNew[0].Labels[endpoint.OwnerLabelKey])
+	suite.Equal("pwner", plan.Changes.UpdateOld[0].Labels[endpoint.OwnerLabelKey])
+	suite.Len(plan.OwnershipConflicts, 1)
+}
+
+func (suite *PlanTestSuite) TestOwnershipConflictCreate() {
+	suite.fooA5.Labels = nil
+	current := []*endpoint.Endpoint{suite.fooA5}
+	desired := []*endpoint.Endpoint{suite.fooA5, suite.fooAAAA}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME},
+		OwnerID:        "pwner",
+	}
+
+	plan := p.Calculate()
+	validateEntries(suite.T(), plan.Changes.Create, []*endpoint.Endpoint{})
+	suite.Equal([]OwnershipConflict{{Change: ConflictCreate, Record: suite.fooA5, Desired: suite.fooAAAA}}, plan.OwnershipConflicts)
+
+	p.AdoptForeign = true
+	plan = p.Calculate()
+	validateEntries(suite.T(), plan.Changes.Create, []*endpoint.Endpoint{suite.fooAAAA})
+}
+
+func (suite *PlanTestSuite) TestAdoptUnowned() {
+	handmade := &endpoint.Endpoint{DNSName: "foo", Targets: endpoint.Targets{"5.5.5.5"}, RecordType: "A", RecordTTL: 3600, Labels: endpoint.Labels{}}
+	foreign := &endpoint.Endpoint{DNSName: "bar", Targets: endpoint.Targets{"127.0.0.2"}, RecordType: "A", Labels: endpoint.Labels{endpoint.OwnerLabelKey: "pwner"}}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        []*endpoint.Endpoint{handmade, foreign},
+		Desired:        []*endpoint.Endpoint{suite.fooA5, suite.fooAAAA, suite.bar127A},
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME},
+		OwnerID:        "other",
+		AdoptUnowned:   true,
+	}
+
+	// The record of no owner matching the desired one is claimed as it is,
+	// and the record of another owner is left alone.
+	plan := p.Calculate()
+	validateEntries(suite.T(), plan.Changes.Create, []*endpoint.Endpoint{suite.fooAAAA})
+	validateEntries(suite.T(), plan.Changes.UpdateOld, []*endpoint.Endpoint{handmade})
+	suite.Require().Len(plan.Changes.UpdateNew, 1)
+	claimed := plan.Changes.UpdateNew[0]
+	suite.Equal(endpoint.Targets{"5.5.5.5"}, claimed.Targets)
+	suite.Equal(endpoint.TTL(3600), claimed.RecordTTL)
+	suite.Equal("other", claimed.Labels[endpoint.OwnerLabelKey])
+	suite.Empty(handmade.Labels[endpoint.OwnerLabelKey])
+	suite.Len(plan.OwnershipConflicts, 3)
+
+	// Once claimed, there is nothing to do.
+	aaaa := suite.fooAAAA.DeepCopy()
+	aaaa.Labels[endpoint.OwnerLabelKey] = "other"
+	p.Current = []*endpoint.Endpoint{claimed, aaaa, foreign}
+	plan = p.Calculate()
+	suite.False(plan.Changes.HasChanges())
+	suite.Len(plan.OwnershipConflicts, 1)
+}
+
+func (suite *PlanTestSuite) TestOwnershipConflictDelete() {
+	current := []*endpoint.Endpoint{suite.fooV1Cname, suite.bar127A}
+	desired := []*endpoint.Endpoint{suite.fooA5}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME},
+		OwnerID:        "other",
+		AdoptForeign:   true,
+	}
+
+	// The foreign CNAME is not deleted, so the A record is not created; the
+	// record of a name not desired is not a conflict.
+	plan := p.Calculate()
+	validateEntries(suite.T(), plan.Changes.Create, []*endpoint.Endpoint{})
+	validateEntries(suite.T(), plan.Changes.Delete, []*endpoint.Endpoint{})
+	suite.ElementsMatch([]OwnershipConflict{
+		{Change: ConflictCreate, Record: suite.fooV1Cname, Desired: suite.fooA5, Owner: "pwner"},
+		{Change: ConflictDelete, Record: suite.fooV1Cname, Owner: "pwner"},
+	}, plan.OwnershipConflicts)
+}
+
 // TestConflictingCurrentNonConflictingDesired is a bit of a corner case as it would indicate
 // that the provider is not following valid DNS rules or there may be some
 // caching issues. In this case since the desired records are not conflicting
@@ -830,6 +938,34 @@ func (suite *PlanTestSuite) TestSetIdentifierUpdateCreatesAndDeletes() {
 	validateEntries(suite.T(), changes.Delete, expectedDelete)
 }
 
+func (suite *PlanTestSuite) TestViews() {
+	current := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "34.1.2.3").WithSetIdentifier("blue").WithView(endpoint.ViewPublic),
+		endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "10.0.0.1").WithSetIdentifier("blue").WithView(endpoint.ViewPrivate),
+	}
+	desired := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "34.1.2.3").WithSetIdentifier("blue").WithView(endpoint.ViewPublic),
+		endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "10.0.0.2").WithSetIdentifier("blue").WithView(endpoint.ViewPrivate),
+	}
+	expectedCreate := []*endpoint.Endpoint{}
+	expectedUpdateOld := []*endpoint.Endpoint{current[1]}
+	expectedUpdateNew := []*endpoint.Endpoint{desired[1]}
+	expectedDelete := []*endpoint.Endpoint{}
+
+	p := &Plan{
+		Policies:       []Policy{&SyncPolicy{}},
+		Current:        current,
+		Desired:        desired,
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+	}
+
+	changes := p.Calculate().Changes
+	validateEntries(suite.T(), changes.Create, expectedCreate)
+	validateEntries(suite.T(), changes.UpdateNew, expectedUpdateNew)
+	validateEntries(suite.T(), changes.UpdateOld, expectedUpdateOld)
+	validateEntries(suite.T(), changes.Delete, expectedDelete)
+}
+
 func (suite *PlanTestSuite) TestDomainFiltersInitial() {
 	current := []*endpoint.Endpoint{suite.domainFilterExcluded}
 	desired := []*endpoint.Endpoint{suite.domainFilterExcluded, suite.domainFilterFiltered1, suite.domainFilterFiltered2, suite.domainFilterFiltered3}
@@ -0,0 +1,245 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"math/rand"
+	"net/netip"
+	"sort"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// The property tests plan random desired endpoints against random records of
+// a provider, apply the changes, and check the invariants of the plan:
+//   - the records of other owners, or of no owner, are never changed;
+//   - a second plan has no changes;
+//   - the names without records of other owners have the desired records.
+//
+// The provider normalizes the records like the real ones, so the differences
+// between the desired endpoints and the records - the trailing dots, the case
+// of the names, the forms of the IPv6 addresses - must not be changes.
+
+const (
+	propertyOwner      = "owner"
+	propertyIterations = 1000
+)
+
+var (
+	propertyNames   = []string{"a.example.org", "b.example.org", "c.example.org", "d.example.org"}
+	propertyOwners  = []string{propertyOwner, "other", ""}
+	propertyTypes   = []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME, endpoint.RecordTypeTXT, endpoint.RecordTypeMX}
+	propertyTargets = map[string][]string{
+		endpoint.RecordTypeA:     {"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+		endpoint.RecordTypeAAAA:  {"2001:db8:0:0::1", "2001:db8::2", "2001:0db8::3"},
+		endpoint.RecordTypeCNAME: {"lb.example.net", "lb.example.net.", "other.example.net"},
+		endpoint.RecordTypeTXT:   {"\"v=1\"", "\"v=2\""},
+		endpoint.RecordTypeMX:    {"10 mail.example.org", "10 mail.example.org.", "20 backup.example.org"},
+	}
+)
+
+// propertyProvider is the records of a provider, by the normalized key.
+type propertyProvider map[endpoint.EndpointKey]*endpoint.Endpoint
+
+func propertyKey(e *endpoint.Endpoint) endpoint.EndpointKey {
+	return endpoint.EndpointKey{
+		DNSName:       strings.ToLower(strings.TrimSuffix(e.DNSName, ".")),
+		RecordType:    e.RecordType,
+		SetIdentifier: e.SetIdentifier,
+	}
+}
+
+// add stores the endpoint as a provider would return it: the name in lower
+// case, the names and the targets without trailing dot, the IPv6 addresses
+// in their canonical form.
+func (p propertyProvider) add(e *endpoint.Endpoint) *endpoint.Endpoint {
+	r := endpoint.NewEndpointWithTTL(strings.ToLower(e.DNSName), e.RecordType, e.RecordTTL, e.Targets...)
+	if r.RecordType == endpoint.RecordTypeAAAA {
+		for i, target := range r.Targets {
+			r.Targets[i] = netip.MustParseAddr(target).String()
+		}
+	}
+	for k, v := range e.Labels {
+		r.Labels[k] = v
+	}
+	r.SetIdentifier = e.SetIdentifier
+	p[propertyKey(r)] = r
+	return r
+}
+
+func (p propertyProvider) records() []*endpoint.Endpoint {
+	records := make([]*endpoint.Endpoint, 0, len(p))
+	for _, r := range p {
+		records = append(records, r.DeepCopy())
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].DNSName+records[i].RecordType < records[j].DNSName+records[j].RecordType
+	})
+	return records
+}
+
+// apply applies the changes, failing on the changes of missing records and
+// the creates of existing ones. The created records are owned, like with a
+// registry.
+func (p propertyProvider) apply(t *testing.T, seed int64, changes *Changes) {
+	for _, e := range append(append([]*endpoint.Endpoint{}, changes.UpdateOld...), changes.Delete...) {
+		if _, ok := p[propertyKey(e)]; !ok {
+			t.Errorf("seed %d: the changed record %s doesn't exist", seed, e)
+		}
+		delete(p, propertyKey(e))
+	}
+	for _, e := range changes.UpdateNew {
+		p.add(e)
+	}
+	for _, e := range changes.Create {
+		if _, ok := p[propertyKey(e)]; ok {
+			t.Errorf("seed %d: the created record %s exists", seed, e)
+		}
+		p.add(e).Labels[endpoint.OwnerLabelKey] = propertyOwner
+	}
+}
+
+// randomEndpoint returns an endpoint of a random name, record type and targets.
+func randomEndpoint(r *rand.Rand) *endpoint.Endpoint {
+	name := propertyNames[r.Intn(len(propertyNames))]
+	switch r.Intn(3) {
+	case 1:
+		name += "."
+	case 2:
+		name = strings.ToUpper(name[:1]) + name[1:]
+	}
+	recordType := propertyTypes[r.Intn(len(propertyTypes))]
+	pool := propertyTargets[recordType]
+	n := 1
+	if recordType != endpoint.RecordTypeCNAME {
+		n += r.Intn(2)
+	}
+	targets := []string{}
+	for _, i := range r.Perm(len(pool))[:n] {
+		targets = append(targets, pool[i])
+	}
+	ttl := endpoint.TTL(0)
+	if r.Intn(2) == 0 {
+		ttl = 300
+	}
+	e := endpoint.NewEndpointWithTTL(name, recordType, ttl, targets...)
+	// Unlike the targets, the names of some sources keep their trailing dot.
+	e.DNSName = name
+	return e
+}
+
+func propertyPlan(current, desired []*endpoint.Endpoint) *Changes {
+	p := &Plan{
+		Current:        current,
+		Desired:        desired,
+		Policies:       []Policy{&SyncPolicy{}},
+		ManagedRecords: propertyTypes,
+		OwnerID:        propertyOwner,
+	}
+	return p.Calculate().Changes
+}
+
+func TestPlanProperties(t *testing.T) {
+	for seed := int64(0); seed < propertyIterations && !t.Failed(); seed++ {
+		r := rand.New(rand.NewSource(seed))
+		provider := propertyProvider{}
+		for i := r.Intn(6); i > 0; i-- {
+			e := randomEndpoint(r)
+			if owner := propertyOwners[r.Intn(len(propertyOwners))]; owner != "" {
+				e.Labels[endpoint.OwnerLabelKey] = owner
+			}
+			provider.add(e)
+		}
+		desired := []*endpoint.Endpoint{}
+		for i := r.Intn(6); i > 0; i-- {
+			desired = append(desired, randomEndpoint(r))
+		}
+		initial := provider.records()
+
+		changes := propertyPlan(provider.records(), desired)
+		for _, e := range append(append([]*endpoint.Endpoint{}, changes.UpdateOld...), changes.Delete...) {
+			if !e.IsOwnedBy(propertyOwner) {
+				t.Errorf("seed %d: the record %s of another owner is changed", seed, e)
+			}
+		}
+		provider.apply(t, seed, changes)
+
+		// The records of other owners are kept.
+		foreign := map[string]bool{}
+		for _, e := range initial {
+			if e.IsOwnedBy(propertyOwner) {
+				continue
+			}
+			foreign[e.DNSName] = true
+			if rec, ok := provider[propertyKey(e)]; !ok || !rec.Targets.Same(e.Targets) || rec.RecordTTL != e.RecordTTL {
+				t.Errorf("seed %d: the record %s of another owner is not kept", seed, e)
+			}
+		}
+
+		// The changes converge.
+		if again := propertyPlan(provider.records(), desired); again.HasChanges() {
+			t.Errorf("seed %d: the second plan has changes: %+v", seed, again)
+		}
+
+		// The names without records of other owners have the desired records:
+		// a record of each desired record type, without the CNAME if other
+		// types are desired, with the targets of one of the endpoints.
+		candidates := map[endpoint.EndpointKey][]*endpoint.Endpoint{}
+		types := map[string]map[string]bool{}
+		for _, e := range desired {
+			key := propertyKey(e)
+			candidates[key] = append(candidates[key], e)
+			if types[key.DNSName] == nil {
+				types[key.DNSName] = map[string]bool{}
+			}
+			types[key.DNSName][e.RecordType] = true
+		}
+		for _, ts := range types {
+			if len(ts) > 1 {
+				delete(ts, endpoint.RecordTypeCNAME)
+			}
+		}
+		for key, rec := range provider {
+			if foreign[key.DNSName] {
+				continue
+			}
+			if !types[key.DNSName][key.RecordType] {
+				t.Errorf("seed %d: the record %s is not desired", seed, rec)
+				continue
+			}
+			same := false
+			for _, c := range candidates[key] {
+				same = same || rec.Targets.Same(c.Targets)
+			}
+			if !same {
+				t.Errorf("seed %d: the record %s doesn't have the desired targets", seed, rec)
+			}
+		}
+		for name, ts := range types {
+			if foreign[name] {
+				continue
+			}
+			for recordType := range ts {
+				if _, ok := provider[endpoint.EndpointKey{DNSName: name, RecordType: recordType}]; !ok {
+					t.Errorf("seed %d: the desired %s record %s is missing", seed, recordType, name)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Shadow is a desired endpoint shadowing a current record.
+type Shadow struct {
+	Desired *endpoint.Endpoint
+	// Record is the shadowed record: a record of another owner, or of no
+	// owner, or with Delegation, an NS record delegating a subzone.
+	Record     *endpoint.Endpoint
+	Delegation bool
+}
+
+func (s Shadow) String() string {
+	if s.Delegation {
+		return fmt.Sprintf("%s %s is in the subzone %s, delegated by NS records to %s", s.Desired.DNSName, s.Desired.RecordType, s.Record.DNSName, strings.Join(s.Record.Targets, ", "))
+	}
+	owner := "no owner"
+	if o := s.Record.Labels[endpoint.OwnerLabelKey]; o != "" {
+		owner = "the owner " + o
+	}
+	return fmt.Sprintf("%s %s shadows the %s record of %s", s.Desired.DNSName, s.Desired.RecordType, s.Record.RecordType, owner)
+}
+
+// ShadowingError is the error of the desired endpoints shadowing records.
+type ShadowingError []Shadow
+
+func (e ShadowingError) Error() string {
+	lines := make([]string, 0, len(e))
+	for _, s := range e {
+		lines = append(lines, s.String())
+	}
+	return fmt.Sprintf("%d desired records shadow current records: %s", len(e), strings.Join(lines, "; "))
+}
+
+// Shadowed returns the desired endpoints shadowing the current records, which
+// the changes would clobber or hide:
+//   - a record of another owner, or of no owner, of the same name and type,
+//     or with a CNAME of the same name, if the plan has an OwnerID.
+//   - the NS records delegating a subzone, with a name below, or of the
+//     delegation itself other than NS and DS. The NS records of a name with NS
+//     records above, like the ones of the zone apex, delegate a subzone.
+func (p *Plan) Shadowed() []Shadow {
+	byName := map[string][]*endpoint.Endpoint{}
+	ns := map[string]*endpoint.Endpoint{}
+	for _, current := range p.Current {
+		name := normalizeDNSName(current.DNSName)
+		byName[name] = append(byName[name], current)
+		if current.RecordType == endpoint.RecordTypeNS {
+			ns[name] = current
+		}
+	}
+	delegations := map[string]*endpoint.Endpoint{}
+	for name, record := range ns {
+		for parent := parentName(name); parent != ""; parent = parentName(parent) {
+			if ns[parent] != nil {
+				delegations[name] = record
+				break
+			}
+		}
+	}
+
+	var shadows []Shadow
+	for _, desired := range filterRecordsForPlan(p.Desired, p.DomainFilter, p.ManagedRecords, p.ExcludeRecords) {
+		name := normalizeDNSName(desired.DNSName)
+		if record := delegationOf(delegations, name, desired.RecordType); record != nil {
+			shadows = append(shadows, Shadow{Desired: desired, Record: record, Delegation: true})
+			continue
+		}
+		if p.OwnerID == "" {
+			continue
+		}
+		for _, current := range byName[name] {
+			if current.SetIdentifier != desired.SetIdentifier || current.IsOwnedBy(p.OwnerID) {
+				continue
+			}
+			if current.RecordType == desired.RecordType || current.RecordType == endpoint.RecordTypeCNAME || desired.RecordType == endpoint.RecordTypeCNAME {
+				shadows = append(shadows, Shadow{Desired: desired, Record: current})
+				break
+			}
+		}
+	}
+	sort.Slice(shadows, func(i, j int) bool {
+		if shadows[i].Desired.DNSName != shadows[j].Desired.DNSName {
+			return shadows[i].Desired.DNSName < shadows[j].Desired.DNSName
+		}
+		return shadows[i].Desired.RecordType < shadows[j].Desired.RecordType
+	})
+	return shadows
+}
+
+// delegationOf returns the NS record of the subzone delegation of a record.
+func delegationOf(delegations map[string]*endpoint.Endpoint, name, recordType string) *endpoint.Endpoint {
+	if record := delegations[name]; record != nil && recordType != endpoint.RecordTypeNS && recordType != "DS" {
+		return record
+	}
+	for parent := parentName(name); parent != ""; parent = parentName(parent) {
+		if record := delegations[parent]; record != nil {
+			return record
+		}
+	}
+	return nil
+}
+
+// parentName returns the parent of a normalized name, empty for a top-level
+// domain.
+func parentName(name string) string {
+	i := strings.Index(name, ".")
+	if i < 0 || i == len(name)-1 {
+		return ""
+	}
+	return name[i+1:]
+}
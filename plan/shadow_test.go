@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestShadowedDelegation(t *testing.T) {
+	apex := endpoint.NewEndpoint("example.org", endpoint.RecordTypeNS, "ns1.example.net")
+	delegation := endpoint.NewEndpoint("team.example.org", endpoint.RecordTypeNS, "ns1.team.example.net", "ns2.team.example.net")
+	p := &Plan{
+		Current: []*endpoint.Endpoint{apex, delegation},
+		Desired: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("foo.team.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("team.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+			endpoint.NewEndpoint("team.example.org", "DS", "12345 13 2 abcdef"),
+		},
+		ManagedRecords: []string{endpoint.RecordTypeA, "DS"},
+	}
+
+	shadows := p.Shadowed()
+	require.Len(t, shadows, 2, "the apex NS records are not a delegation, nor DS records a shadow")
+	assert.Equal(t, "foo.team.example.org", shadows[0].Desired.DNSName)
+	assert.Equal(t, "team.example.org", shadows[1].Desired.DNSName)
+	for _, shadow := range shadows {
+		assert.True(t, shadow.Delegation)
+		assert.Equal(t, delegation, shadow.Record)
+	}
+	assert.Equal(t, "foo.team.example.org A is in the subzone team.example.org, delegated by NS records to ns1.team.example.net, ns2.team.example.net", shadows[0].String())
+}
+
+func TestShadowedForeign(t *testing.T) {
+	foreign := endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeCNAME, "lb.example.org")
+	foreign.Labels[endpoint.OwnerLabelKey] = "other"
+	owned := endpoint.NewEndpoint("bar.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	owned.Labels[endpoint.OwnerLabelKey] = "owner"
+	unowned := endpoint.NewEndpoint("baz.example.org", endpoint.RecordTypeA, "1.2.3.4")
+	p := &Plan{
+		Current: []*endpoint.Endpoint{foreign, owned, unowned},
+		Desired: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("foo.example.org", endpoint.RecordTypeA, "5.6.7.8"),
+			endpoint.NewEndpoint("bar.example.org", endpoint.RecordTypeA, "5.6.7.8"),
+			endpoint.NewEndpoint("baz.example.org", endpoint.RecordTypeA, "5.6.7.8"),
+		},
+		ManagedRecords: []string{endpoint.RecordTypeA, endpoint.RecordTypeCNAME},
+		OwnerID:        "owner",
+	}
+
+	shadows := p.Shadowed()
+	require.Len(t, shadows, 2)
+	assert.Equal(t, unowned, shadows[0].Record)
+	assert.Equal(t, foreign, shadows[1].Record)
+	assert.EqualError(t, ShadowingError(shadows), "2 desired records shadow current records: baz.example.org A shadows the A record of no owner; foo.example.org A shadows the CNAME record of the owner other")
+
+	p.OwnerID = ""
+	assert.Empty(t, p.Shadowed(), "the records have no owner without an OwnerID")
+}
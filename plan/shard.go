@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"hash/fnv"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Shard returns the shard of a DNS name, between 0 and shards-1. The records
+// of a name, whatever their type and set identifier, are in the same shard -
+// the plans of the shards are independent.
+func Shard(dnsName string, shards int) int {
+	if shards <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(normalizeDNSName(dnsName)))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// ShardEndpoints returns the endpoints of the shard.
+func ShardEndpoints(endpoints []*endpoint.Endpoint, shard, shards int) []*endpoint.Endpoint {
+	filtered := []*endpoint.Endpoint{}
+	for _, ep := range endpoints {
+		if Shard(ep.DNSName, shards) == shard {
+			filtered = append(filtered, ep)
+		}
+	}
+	return filtered
+}
+
+// ReplicaOwner returns the replica owning a DNS name, by rendezvous hashing:
+// the replica with the highest hash of its name and the DNS name. Adding or
+// removing a replica only moves the names it gains or loses, and the replicas
+// agree on the owner as long as they are configured with the same replicas,
+// in any order.
+func ReplicaOwner(dnsName string, replicas []string) string {
+	name := normalizeDNSName(dnsName)
+	var owner string
+	var best uint64
+	for _, replica := range replicas {
+		h := fnv.New64a()
+		h.Write([]byte(replica))
+		h.Write([]byte{0})
+		h.Write([]byte(name))
+		if sum := h.Sum64(); owner == "" || sum > best || (sum == best && replica < owner) {
+			owner, best = replica, sum
+		}
+	}
+	return owner
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestShard(t *testing.T) {
+	assert.Equal(t, 0, Shard("a.example.org", 0))
+	assert.Equal(t, 0, Shard("a.example.org", 1))
+	assert.Equal(t, Shard("a.example.org", 16), Shard("A.Example.org.", 16))
+
+	endpoints := []*endpoint.Endpoint{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		endpoints = append(endpoints, endpoint.NewEndpoint(name+".example.org", endpoint.RecordTypeA, "1.2.3.4"))
+	}
+	seen := 0
+	for shard := 0; shard < 3; shard++ {
+		for _, ep := range ShardEndpoints(endpoints, shard, 3) {
+			assert.Equal(t, shard, Shard(ep.DNSName, 3))
+			seen++
+		}
+	}
+	assert.Equal(t, len(endpoints), seen, "each endpoint is in one shard")
+}
+
+func TestReplicaOwner(t *testing.T) {
+	assert.Empty(t, ReplicaOwner("a.example.org", nil))
+	assert.Equal(t, "r0", ReplicaOwner("a.example.org", []string{"r0"}))
+
+	replicas := []string{"r0", "r1", "r2"}
+	owners := map[string]string{}
+	counts := map[string]int{}
+	for i := 0; i < 300; i++ {
+		name := fmt.Sprintf("name-%d.example.org", i)
+		owner := ReplicaOwner(name, replicas)
+		assert.Equal(t, owner, ReplicaOwner(strings.ToUpper(name)+".", []string{"r2", "r0", "r1"}), "the order of the replicas doesn't matter")
+		owners[name] = owner
+		counts[owner]++
+	}
+	for _, replica := range replicas {
+		assert.Greater(t, counts[replica], 50, replica)
+	}
+
+	// Adding a replica only moves names to it.
+	for name, owner := range owners {
+		if moved := ReplicaOwner(name, append(replicas, "r3")); moved != owner {
+			assert.Equal(t, "r3", moved, name)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package consul
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/linki/instrumented_http"
+)
+
+// consulAPI declares the catalog actions performed against the Consul server.
+type consulAPI interface {
+	// nodeServices returns the services registered on the node.
+	nodeServices(ctx context.Context) ([]*service, error)
+	// register registers the service on the node, replacing the service with the same ID.
+	register(ctx context.Context, svc *service) error
+	// deregister removes the service from the node.
+	deregister(ctx context.Context, id string) error
+}
+
+// service is a service of the Consul catalog.
+type service struct {
+	ID      string            `json:"ID"`
+	Service string            `json:"Service"`
+	Address string            `json:"Address"`
+	Meta    map[string]string `json:"Meta,omitempty"`
+}
+
+// consulClient implements consulAPI with the HTTP API of Consul.
+type consulClient struct {
+	cfg        ConsulConfig
+	httpClient *http.Client
+}
+
+func newConsulClient(cfg ConsulConfig) (consulAPI, error) {
+	if cfg.Address == "" {
+		return nil, ErrNoConsulAddress
+	}
+	if !strings.Contains(cfg.Address, "://") {
+		cfg.Address = "http://" + cfg.Address
+	}
+	cfg.Address = strings.TrimSuffix(cfg.Address, "/")
+	return &consulClient{
+		cfg:        cfg,
+		httpClient: instrumented_http.NewClient(&http.Client{}, &instrumented_http.Callbacks{}),
+	}, nil
+}
+
+func (c *consulClient) nodeServices(ctx context.Context) ([]*service, error) {
+	var res struct {
+		Services map[string]*service `json:"Services"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/catalog/node/"+url.PathEscape(c.cfg.Node), nil, &res); err != nil {
+		return nil, err
+	}
+	// A missing node is returned as null: no services.
+	services := make([]*service, 0, len(res.Services))
+	for _, svc := range res.Services {
+		services = append(services, svc)
+	}
+	return services, nil
+}
+
+func (c *consulClient) register(ctx context.Context, svc *service) error {
+	return c.do(ctx, http.MethodPut, "/v1/catalog/register", map[string]interface{}{
+		"Datacenter": c.cfg.Datacenter,
+		"Node":       c.cfg.Node,
+		// The node is external: Consul serves the addresses of its services, and
+		// doesn't run health checks for it.
+		"Address":  nodeAddress,
+		"NodeMeta": map[string]string{"external-node": "true", "external-probe": "false"},
+		"Service":  svc,
+	}, nil)
+}
+
+func (c *consulClient) deregister(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPut, "/v1/catalog/deregister", map[string]string{
+		"Datacenter": c.cfg.Datacenter,
+		"Node":       c.cfg.Node,
+		"ServiceID":  id,
+	}, nil)
+}
+
+// do sends the request with the JSON body in, and decodes the JSON response
+// into out if not nil.
+func (c *consulClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	u := c.cfg.Address + path
+	if c.cfg.Datacenter != "" {
+		u += "?dc=" + url.QueryEscape(c.cfg.Datacenter)
+	}
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	if c.cfg.Token != "" {
+		req.Header.Set("X-Consul-Token", c.cfg.Token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("consul %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
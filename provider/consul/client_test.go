@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConsul implements the catalog endpoints of the Consul HTTP API.
+type fakeConsul struct {
+	t     *testing.T
+	token string
+
+	mu       sync.Mutex
+	nodes    map[string]map[string]*service
+	requests []string
+}
+
+func newFakeConsul(t *testing.T, token string) (*fakeConsul, *httptest.Server) {
+	f := &fakeConsul{t: t, token: token, nodes: map[string]map[string]*service{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.String())
+	if r.Header.Get("X-Consul-Token") != f.token {
+		http.Error(w, "ACL not found", http.StatusForbidden)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/catalog/node/"):
+		services, ok := f.nodes[strings.TrimPrefix(r.URL.Path, "/v1/catalog/node/")]
+		if !ok {
+			w.Write([]byte("null"))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"Services": services})
+	case r.Method == http.MethodPut && r.URL.Path == "/v1/catalog/register":
+		var req struct {
+			Node     string
+			Address  string
+			NodeMeta map[string]string
+			Service  *service
+		}
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(f.t, "true", req.NodeMeta["external-node"])
+		if f.nodes[req.Node] == nil {
+			f.nodes[req.Node] = map[string]*service{}
+		}
+		f.nodes[req.Node][req.Service.ID] = req.Service
+		w.Write([]byte("true"))
+	case r.Method == http.MethodPut && r.URL.Path == "/v1/catalog/deregister":
+		var req struct {
+			Node      string
+			ServiceID string
+		}
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&req))
+		delete(f.nodes[req.Node], req.ServiceID)
+		w.Write([]byte("true"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestNewConsulClient(t *testing.T) {
+	_, err := newConsulClient(ConsulConfig{})
+	assert.ErrorIs(t, err, ErrNoConsulAddress)
+
+	cl, err := newConsulClient(ConsulConfig{Address: "127.0.0.1:8500/"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:8500", cl.(*consulClient).cfg.Address)
+}
+
+func TestConsulClient(t *testing.T) {
+	f, srv := newFakeConsul(t, "secret")
+	cl, err := newConsulClient(ConsulConfig{Address: srv.URL, Token: "secret", Datacenter: "dc1", Node: "external-dns"})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	services, err := cl.nodeServices(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, services)
+
+	svc := &service{ID: "web-1", Service: "web", Address: "10.0.0.1", Meta: map[string]string{"k": "v"}}
+	require.NoError(t, cl.register(ctx, svc))
+	services, err = cl.nodeServices(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*service{svc}, services)
+
+	require.NoError(t, cl.deregister(ctx, "web-1"))
+	services, err = cl.nodeServices(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, services)
+	assert.Contains(t, f.requests, "GET /v1/catalog/node/external-dns?dc=dc1")
+
+	cl, err = newConsulClient(ConsulConfig{Address: srv.URL, Token: "wrong", Node: "external-dns"})
+	require.NoError(t, err)
+	_, err = cl.nodeServices(ctx)
+	assert.ErrorContains(t, err, "403")
+}
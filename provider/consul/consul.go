@@ -0,0 +1,247 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package consul registers the endpoints as services of the Consul catalog,
+// served by Consul DNS as <service>.service.consul.
+package consul
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// ErrNoConsulAddress is returned when no Consul server is configured.
+var ErrNoConsulAddress = errors.New("no consul address specified")
+
+const (
+	// nodeAddress is the address of the external node of the services. Each
+	// service has its own address, the node address is not served.
+	nodeAddress = "127.0.0.1"
+
+	// The meta of the services, mapping them back to the endpoints.
+	metaName = "external-dns-name"
+	metaType = "external-dns-type"
+	metaTTL  = "external-dns-ttl"
+)
+
+// serviceNameRegex matches the service names valid in Consul DNS.
+var serviceNameRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// ConsulProvider is an implementation of Provider registering the endpoints
+// as services of an external node of the Consul catalog. Each target is an
+// instance of the service: Consul DNS answers with the A, AAAA or CNAME records
+// of the instances.
+type ConsulProvider struct {
+	provider.BaseProvider
+	api consulAPI
+	cfg ConsulConfig
+}
+
+// ConsulConfig is used for configuring a ConsulProvider.
+type ConsulConfig struct {
+	// The address of the Consul HTTP API, like http://127.0.0.1:8500.
+	Address string
+	// An optional ACL token, with write access to the node and the services.
+	Token string
+	// The datacenter of the catalog, the datacenter of the server if empty.
+	Datacenter string
+	// The node of the services, owned by ExternalDNS.
+	Node string
+	// The domain removed from the DNS names: app.example.com is registered as
+	// the service app with the domain example.com. The other dots of the DNS
+	// names are replaced with dashes.
+	Domain string
+	// A filter to apply when looking up and applying records.
+	DomainFilter endpoint.DomainFilter
+	// Do nothing and log what would have changed.
+	DryRun bool
+}
+
+// NewConsulProvider initializes a new Consul catalog based Provider.
+func NewConsulProvider(cfg ConsulConfig) (*ConsulProvider, error) {
+	if cfg.Node == "" {
+		return nil, errors.New("no consul node specified")
+	}
+	api, err := newConsulClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &ConsulProvider{api: api, cfg: cfg}, nil
+}
+
+// GetDomainFilter returns the domain filter of the provider.
+func (p *ConsulProvider) GetDomainFilter() endpoint.DomainFilter {
+	return p.cfg.DomainFilter
+}
+
+// Records implements Provider, returning the endpoints of the services of the node.
+func (p *ConsulProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	services, err := p.api.nodeServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	type key struct{ name, recordType string }
+	byKey := map[key]*endpoint.Endpoint{}
+	for _, svc := range services {
+		name, recordType := svc.Meta[metaName], svc.Meta[metaType]
+		// Not registered by ExternalDNS.
+		if name == "" || recordType == "" {
+			continue
+		}
+		if !p.cfg.DomainFilter.Match(name) {
+			continue
+		}
+		k := key{name, recordType}
+		ep := byKey[k]
+		if ep == nil {
+			ep = endpoint.NewEndpoint(name, recordType)
+			if ttl, err := strconv.ParseInt(svc.Meta[metaTTL], 10, 64); err == nil {
+				ep.RecordTTL = endpoint.TTL(ttl)
+			}
+			byKey[k] = ep
+		}
+		ep.Targets = append(ep.Targets, svc.Address)
+	}
+	endpoints := make([]*endpoint.Endpoint, 0, len(byKey))
+	for _, ep := range byKey {
+		sort.Strings(ep.Targets)
+		endpoints = append(endpoints, ep)
+	}
+	return endpoints, nil
+}
+
+// ApplyChanges implements Provider, registering and deregistering the
+// instances of the services.
+func (p *ConsulProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	for _, ep := range changes.Delete {
+		if err := p.deregister(ctx, ep, nil); err != nil {
+			return err
+		}
+	}
+	for i, ep := range changes.UpdateOld {
+		var keep *endpoint.Endpoint
+		if i < len(changes.UpdateNew) {
+			keep = changes.UpdateNew[i]
+		}
+		if err := p.deregister(ctx, ep, keep); err != nil {
+			return err
+		}
+	}
+	// Registering replaces the instances kept by the updates.
+	for _, eps := range [][]*endpoint.Endpoint{changes.UpdateNew, changes.Create} {
+		for _, ep := range eps {
+			if err := p.register(ctx, ep); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// register registers an instance of the service of the endpoint for each target.
+func (p *ConsulProvider) register(ctx context.Context, ep *endpoint.Endpoint) error {
+	name, ok := p.serviceName(ep)
+	if !ok {
+		return nil
+	}
+	for _, target := range ep.Targets {
+		svc := &service{
+			ID:      serviceID(ep, target),
+			Service: name,
+			Address: target,
+			Meta: map[string]string{
+				metaName: ep.DNSName,
+				metaType: ep.RecordType,
+			},
+		}
+		if ep.RecordTTL.IsConfigured() {
+			svc.Meta[metaTTL] = strconv.FormatInt(int64(ep.RecordTTL), 10)
+		}
+		log.Infof("Registering Consul service %s: %s %s %s", name, ep.DNSName, ep.RecordType, target)
+		if p.cfg.DryRun {
+			continue
+		}
+		if err := p.api.register(ctx, svc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deregister removes the instances of the endpoint, except the targets of keep.
+func (p *ConsulProvider) deregister(ctx context.Context, ep, keep *endpoint.Endpoint) error {
+	name, ok := p.serviceName(ep)
+	if !ok {
+		return nil
+	}
+	kept := map[string]bool{}
+	if keep != nil && keep.DNSName == ep.DNSName && keep.RecordType == ep.RecordType {
+		for _, target := range keep.Targets {
+			kept[target] = true
+		}
+	}
+	for _, target := range ep.Targets {
+		if kept[target] {
+			continue
+		}
+		log.Infof("Deregistering Consul service %s: %s %s %s", name, ep.DNSName, ep.RecordType, target)
+		if p.cfg.DryRun {
+			continue
+		}
+		if err := p.api.deregister(ctx, serviceID(ep, target)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serviceName returns the Consul service of the endpoint, and whether the
+// endpoint can be registered.
+func (p *ConsulProvider) serviceName(ep *endpoint.Endpoint) (string, bool) {
+	switch ep.RecordType {
+	case endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME:
+	default:
+		log.Debugf("Skipping %s %s: unsupported record type", ep.DNSName, ep.RecordType)
+		return "", false
+	}
+	name := strings.ToLower(strings.TrimSuffix(ep.DNSName, "."))
+	if p.cfg.Domain != "" {
+		name = strings.TrimSuffix(name, "."+strings.ToLower(p.cfg.Domain))
+	}
+	name = strings.ReplaceAll(name, ".", "-")
+	if !serviceNameRegex.MatchString(name) {
+		log.Warnf("Skipping %s: %q is not a valid Consul service name", ep.DNSName, name)
+		return "", false
+	}
+	return name, true
+}
+
+// serviceID is the ID of the instance of the endpoint with the target, unique
+// on the node.
+func serviceID(ep *endpoint.Endpoint, target string) string {
+	return fmt.Sprintf("external-dns:%s:%s:%s", ep.DNSName, ep.RecordType, target)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package consul
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func newTestProvider(t *testing.T, dryRun bool) (*fakeConsul, *ConsulProvider) {
+	f, srv := newFakeConsul(t, "")
+	p, err := NewConsulProvider(ConsulConfig{
+		Address:      srv.URL,
+		Node:         "external-dns",
+		Domain:       "example.com",
+		DomainFilter: endpoint.NewDomainFilter([]string{"example.com"}),
+		DryRun:       dryRun,
+	})
+	require.NoError(t, err)
+	return f, p
+}
+
+func records(t *testing.T, p *ConsulProvider) []string {
+	t.Helper()
+	eps, err := p.Records(context.Background())
+	require.NoError(t, err)
+	var out []string
+	for _, ep := range eps {
+		out = append(out, ep.String())
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestNewConsulProvider(t *testing.T) {
+	_, err := NewConsulProvider(ConsulConfig{Address: "http://127.0.0.1:8500"})
+	assert.Error(t, err)
+	_, err = NewConsulProvider(ConsulConfig{Node: "external-dns"})
+	assert.ErrorIs(t, err, ErrNoConsulAddress)
+}
+
+func TestConsulApplyChanges(t *testing.T) {
+	f, p := newTestProvider(t, false)
+	ctx := context.Background()
+
+	web := endpoint.NewEndpointWithTTL("web.example.com", endpoint.RecordTypeA, 60, "10.0.0.1", "10.0.0.2")
+	api := endpoint.NewEndpoint("api.prod.example.com", endpoint.RecordTypeCNAME, "lb.example.net")
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{
+		web,
+		api,
+		// Not registered: unsupported record type, invalid service name.
+		endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeTXT, "heritage=external-dns"),
+		endpoint.NewEndpoint("*.example.com", endpoint.RecordTypeA, "10.0.0.3"),
+	}}))
+	assert.Equal(t, []string{
+		"api.prod.example.com 0 IN CNAME  lb.example.net []",
+		"web.example.com 60 IN A  10.0.0.1;10.0.0.2 []",
+	}, records(t, p))
+	svc := f.nodes["external-dns"]["external-dns:api.prod.example.com:CNAME:lb.example.net"]
+	require.NotNil(t, svc)
+	assert.Equal(t, "api-prod", svc.Service)
+	assert.Len(t, f.nodes["external-dns"], 3)
+
+	webNew := endpoint.NewEndpointWithTTL("web.example.com", endpoint.RecordTypeA, 60, "10.0.0.2", "10.0.0.4")
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{web},
+		UpdateNew: []*endpoint.Endpoint{webNew},
+		Delete:    []*endpoint.Endpoint{api},
+	}))
+	assert.Equal(t, []string{"web.example.com 60 IN A  10.0.0.2;10.0.0.4 []"}, records(t, p))
+}
+
+func TestConsulRecordsIgnoresOtherServices(t *testing.T) {
+	f, p := newTestProvider(t, false)
+	f.nodes["external-dns"] = map[string]*service{
+		"manual":  {ID: "manual", Service: "manual", Address: "10.0.0.9"},
+		"foreign": {ID: "foreign", Service: "foreign", Address: "10.0.0.8", Meta: map[string]string{metaName: "foreign.example.org", metaType: "A"}},
+	}
+	assert.Empty(t, records(t, p))
+}
+
+func TestConsulDryRun(t *testing.T) {
+	f, p := newTestProvider(t, true)
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeA, "10.0.0.1"),
+	}}))
+	assert.Empty(t, f.nodes)
+}
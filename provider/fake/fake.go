@@ -0,0 +1,220 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake is a provider for the tests of the sources, the registries and
+// the controller. Unlike the inmemory provider it has no zones: it stores
+// the records of any name. The calls are recorded, and the programmed errors
+// returned, by the inmemory.FaultyProvider wrapping the records. It is safe
+// for concurrent use.
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+var (
+	// ErrRecordAlreadyExists is returned when a created record exists.
+	ErrRecordAlreadyExists = errors.New("record already exists")
+	// ErrRecordNotFound is returned when an updated or deleted record doesn't exist.
+	ErrRecordNotFound = errors.New("record not found")
+)
+
+// The methods of the calls, for FailNext.
+const (
+	MethodRecords      = inmemory.MethodRecords
+	MethodApplyChanges = inmemory.MethodApplyChanges
+)
+
+// Provider is a fake provider. FailNext, Calls, Methods and Reset are those of
+// the FaultyProvider, which injects no random faults. The records are copied
+// in and out, so a test can't change them by mistake.
+type Provider struct {
+	*inmemory.FaultyProvider
+	records *records
+}
+
+// Option configures a Provider.
+type Option func(*records)
+
+// WithRecords adds the records to the provider.
+func WithRecords(endpoints ...*endpoint.Endpoint) Option {
+	return func(r *records) {
+		for _, e := range endpoints {
+			r.records[e.Key()] = e.DeepCopy()
+		}
+	}
+}
+
+// WithDomainFilter sets the domain filter of the provider.
+func WithDomainFilter(df endpoint.DomainFilter) Option {
+	return func(r *records) {
+		r.domainFilter = df
+	}
+}
+
+// WithAdjustEndpoints sets the function of AdjustEndpoints, which returns the
+// endpoints by default.
+func WithAdjustEndpoints(adjust func([]*endpoint.Endpoint) ([]*endpoint.Endpoint, error)) Option {
+	return func(r *records) {
+		r.adjust = adjust
+	}
+}
+
+// New returns a fake provider.
+func New(opts ...Option) *Provider {
+	r := &records{records: map[endpoint.EndpointKey]*endpoint.Endpoint{}}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return &Provider{
+		FaultyProvider: inmemory.NewFaultInjector(r, inmemory.FaultConfig{}),
+		records:        r,
+	}
+}
+
+var _ provider.Provider = &Provider{}
+
+// SetDomainFilter changes the domain filter of the provider.
+func (p *Provider) SetDomainFilter(df endpoint.DomainFilter) {
+	p.records.mu.Lock()
+	defer p.records.mu.Unlock()
+	p.records.domainFilter = df
+}
+
+// ApplyChanges applies a copy of the changes, so the recorded changes stay as
+// they were passed.
+func (p *Provider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	return p.FaultyProvider.ApplyChanges(ctx, copyChanges(changes))
+}
+
+// Changes returns the changes of the ApplyChanges calls, in order.
+func (p *Provider) Changes() []*plan.Changes {
+	changes := []*plan.Changes{}
+	for _, c := range p.Calls() {
+		if c.Method == MethodApplyChanges {
+			changes = append(changes, c.Changes)
+		}
+	}
+	return changes
+}
+
+// records are the records of a Provider, of any name.
+type records struct {
+	mu           sync.Mutex
+	records      map[endpoint.EndpointKey]*endpoint.Endpoint
+	domainFilter endpoint.DomainFilter
+	adjust       func([]*endpoint.Endpoint) ([]*endpoint.Endpoint, error)
+}
+
+// Records returns the records, sorted by name, record type and set identifier.
+func (r *records) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	endpoints := make([]*endpoint.Endpoint, 0, len(r.records))
+	for _, e := range r.records {
+		endpoints = append(endpoints, e.DeepCopy())
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		a, b := endpoints[i].Key(), endpoints[j].Key()
+		if a.DNSName != b.DNSName {
+			return a.DNSName < b.DNSName
+		}
+		if a.RecordType != b.RecordType {
+			return a.RecordType < b.RecordType
+		}
+		return a.SetIdentifier < b.SetIdentifier
+	})
+	return endpoints, nil
+}
+
+// ApplyChanges applies the changes like a provider with atomic batches: all
+// the changes are applied, or none if a created record exists or an updated
+// or deleted one doesn't.
+func (r *records) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range changes.Create {
+		if _, ok := r.records[e.Key()]; ok {
+			return fmt.Errorf("%w: %s %s", ErrRecordAlreadyExists, e.RecordType, e.DNSName)
+		}
+	}
+	for _, e := range append(append([]*endpoint.Endpoint{}, changes.UpdateOld...), changes.Delete...) {
+		if _, ok := r.records[e.Key()]; !ok {
+			return fmt.Errorf("%w: %s %s", ErrRecordNotFound, e.RecordType, e.DNSName)
+		}
+	}
+	for _, e := range changes.Delete {
+		delete(r.records, e.Key())
+	}
+	for _, e := range changes.UpdateOld {
+		delete(r.records, e.Key())
+	}
+	for _, e := range changes.UpdateNew {
+		r.records[e.Key()] = e.DeepCopy()
+	}
+	for _, e := range changes.Create {
+		r.records[e.Key()] = e.DeepCopy()
+	}
+	return nil
+}
+
+// AdjustEndpoints returns the endpoints, adjusted by the function of
+// WithAdjustEndpoints.
+func (r *records) AdjustEndpoints(endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	r.mu.Lock()
+	adjust := r.adjust
+	r.mu.Unlock()
+	if adjust == nil {
+		return endpoints, nil
+	}
+	return adjust(endpoints)
+}
+
+// GetDomainFilter returns the domain filter of the provider.
+func (r *records) GetDomainFilter() endpoint.DomainFilter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.domainFilter
+}
+
+func copyEndpoints(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	if endpoints == nil {
+		return nil
+	}
+	c := make([]*endpoint.Endpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		c = append(c, e.DeepCopy())
+	}
+	return c
+}
+
+func copyChanges(changes *plan.Changes) *plan.Changes {
+	return &plan.Changes{
+		Create:    copyEndpoints(changes.Create),
+		UpdateOld: copyEndpoints(changes.UpdateOld),
+		UpdateNew: copyEndpoints(changes.UpdateNew),
+		Delete:    copyEndpoints(changes.Delete),
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestProviderApplyChanges(t *testing.T) {
+	ctx := context.Background()
+	a := endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "10.0.0.1")
+	p := New(WithRecords(a))
+
+	b := endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeCNAME, "a.example.org")
+	a2 := endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "10.0.0.2")
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{b}, UpdateOld: []*endpoint.Endpoint{a}, UpdateNew: []*endpoint.Endpoint{a2}}))
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*endpoint.Endpoint{a2, b}, records)
+
+	// The batches are atomic.
+	err = p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint("c.example.org", endpoint.RecordTypeA, "10.0.0.3"), b}})
+	assert.ErrorIs(t, err, ErrRecordAlreadyExists)
+	err = p.ApplyChanges(ctx, &plan.Changes{Delete: []*endpoint.Endpoint{b, endpoint.NewEndpoint("c.example.org", endpoint.RecordTypeA, "10.0.0.3")}})
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+	records, err = p.Records(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*endpoint.Endpoint{a2, b}, records)
+
+	// The records are copied.
+	records[0].Targets[0] = "10.0.0.9"
+	b.Targets[0] = "c.example.org"
+	records, err = p.Records(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, endpoint.Targets{"10.0.0.2"}, records[0].Targets)
+	assert.Equal(t, endpoint.Targets{"a.example.org"}, records[1].Targets)
+
+	changes := p.Changes()
+	require.Len(t, changes, 3)
+	assert.Equal(t, endpoint.Targets{"a.example.org"}, changes[0].Create[0].Targets)
+	assert.Equal(t, []string{MethodApplyChanges, MethodRecords, MethodApplyChanges, MethodApplyChanges, MethodRecords, MethodRecords}, p.Methods())
+}
+
+func TestProviderErrors(t *testing.T) {
+	ctx := context.Background()
+	p := New()
+	p.FailNext(MethodRecords, errors.New("first"), errors.New("second"))
+
+	_, err := p.Records(ctx)
+	assert.EqualError(t, err, "first")
+	_, err = p.Records(ctx)
+	assert.EqualError(t, err, "second")
+	_, err = p.Records(ctx)
+	assert.NoError(t, err)
+
+	p.FailNext(MethodApplyChanges, errors.New("apply"))
+	p.Reset()
+	assert.NoError(t, p.ApplyChanges(ctx, &plan.Changes{}))
+	assert.Len(t, p.Calls(), 1)
+}
+
+func TestProviderAdjustEndpointsAndDomainFilter(t *testing.T) {
+	p := New(
+		WithDomainFilter(endpoint.NewDomainFilter([]string{"example.org"})),
+		WithAdjustEndpoints(func(endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+			for _, e := range endpoints {
+				e.RecordTTL = 300
+			}
+			return endpoints, nil
+		}),
+	)
+	adjusted, err := p.AdjustEndpoints([]*endpoint.Endpoint{endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "10.0.0.1")})
+	require.NoError(t, err)
+	assert.Equal(t, endpoint.TTL(300), adjusted[0].RecordTTL)
+
+	assert.True(t, p.GetDomainFilter().Match("a.example.org"))
+	p.SetDomainFilter(endpoint.NewDomainFilter([]string{"example.com"}))
+	assert.False(t, p.GetDomainFilter().Match("a.example.org"))
+}
+
+func TestProviderConcurrent(t *testing.T) {
+	ctx := context.Background()
+	p := New()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := string(rune('a'+i)) + ".example.org"
+			assert.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{endpoint.NewEndpoint(name, endpoint.RecordTypeA, "10.0.0.1")}}))
+			_, err := p.Records(ctx)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.Len(t, records, 10)
+}
@@ -8,33 +8,8 @@ import (
 	"testing"
 
 	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
-	webhookapi "sigs.k8s.io/external-dns/provider/webhook/api"
 )
 
-// main starts a barebones webhook for the Google provider.
-// Primary goal is to validate the minimal configuration and identify the size of the binary vs exernal-dns.
-// Initial test shows 31M versus 138M with all providers (23 vs 98 stripped)
-
-func InitGoogleProvider() {
-	cfgs := os.Getenv("CFG")
-
-	edns := &externaldns.Config{}
-	json.Unmarshal([]byte(cfgs), edns)
-
-	ctx := context.Background()
-
-	p, err := NewGoogleProvider(ctx, &edns.ProviderConfig, nil, nil,  false)
-	if err != nil {
-		panic(err)
-	}
-
-	ch := make(chan struct{})
-	go webhookapi.StartHTTPApi(p, ch, 0, 0, "8080")
-	<-ch
-
-}
-
-
 // TestDnsGoogle is an e2e test that validates the Google Cloud DNS provider implementation
 // and the new config model.
 func TestDnsGoogle(t *testing.T) {
@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package google
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	dns "google.golang.org/api/dns/v1"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+func geoEndpoint(location string, ttl endpoint.TTL, targets ...string) *endpoint.Endpoint {
+	return endpoint.NewEndpointWithTTL("geo.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, ttl, targets...).
+		WithSetIdentifier(location).
+		WithProviderSpecific(providerSpecificGeoLocation, location)
+}
+
+func TestGoogleGeoRoutingPolicy(t *testing.T) {
+	ctx := context.Background()
+	p := newGoogleProvider(t, endpoint.NewDomainFilter([]string{"ext-dns-test-2.gcp.zalan.do."}), provider.NewZoneIDFilter([]string{""}), false, []*endpoint.Endpoint{})
+	zone := zoneKey(p.GoogleProject, "zone-1-ext-dns-test-2-gcp-zalan-do")
+	geoRecord := func() *dns.ResourceRecordSet {
+		return testRecords[zone][recordKey(endpoint.RecordTypeA, "geo.zone-1.ext-dns-test-2.gcp.zalan.do.")]
+	}
+
+	// The endpoints of the regions are the items of a record set.
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{
+		geoEndpoint("us-central1", 60, "10.0.0.1"),
+		geoEndpoint("europe-west1", 120, "10.1.0.1", "10.1.0.2"),
+	}}))
+	assert.Equal(t, &dns.ResourceRecordSet{
+		Name: "geo.zone-1.ext-dns-test-2.gcp.zalan.do.",
+		Type: endpoint.RecordTypeA,
+		Ttl:  60,
+		RoutingPolicy: &dns.RRSetRoutingPolicy{Geo: &dns.RRSetRoutingPolicyGeoPolicy{Items: []*dns.RRSetRoutingPolicyGeoPolicyGeoPolicyItem{
+			{Location: "europe-west1", Rrdatas: []string{"10.1.0.1", "10.1.0.2"}},
+			{Location: "us-central1", Rrdatas: []string{"10.0.0.1"}},
+		}}},
+	}, geoRecord())
+
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	validateEndpoints(t, records, []*endpoint.Endpoint{
+		geoEndpoint("us-central1", 60, "10.0.0.1"),
+		geoEndpoint("europe-west1", 60, "10.1.0.1", "10.1.0.2"),
+	})
+
+	// The changes of a region keep the items of the other regions.
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{geoEndpoint("us-central1", 60, "10.0.0.1")},
+		UpdateNew: []*endpoint.Endpoint{geoEndpoint("us-central1", 60, "10.0.0.3")},
+		Create:    []*endpoint.Endpoint{geoEndpoint("asia-east1", 60, "10.2.0.1")},
+	}))
+	records, err = p.Records(ctx)
+	require.NoError(t, err)
+	validateEndpoints(t, records, []*endpoint.Endpoint{
+		geoEndpoint("asia-east1", 60, "10.2.0.1"),
+		geoEndpoint("europe-west1", 60, "10.1.0.1", "10.1.0.2"),
+		geoEndpoint("us-central1", 60, "10.0.0.3"),
+	})
+
+	// The record set is deleted with its last item.
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Delete: records}))
+	assert.Nil(t, geoRecord())
+}
@@ -13,6 +13,8 @@ require (
 
 	github.com/linki/instrumented_http v0.3.0
 
+	github.com/prometheus/client_golang v1.19.1
+
 	github.com/sirupsen/logrus v1.9.3
 
 	github.com/stretchr/testify v1.9.0
@@ -51,7 +53,6 @@ require (
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
-	github.com/prometheus/client_golang v1.19.1 // indirect
 	github.com/prometheus/client_model v0.6.1 // indirect
 	github.com/prometheus/common v0.53.0 // indirect
 	github.com/prometheus/procfs v0.12.0 // indirect
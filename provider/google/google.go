@@ -18,29 +18,41 @@ package google
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/compute/metadata"
 	"github.com/linki/instrumented_http"
-	log "github.com/sirupsen/logrus"
 	"golang.org/x/oauth2/google"
 	dns "google.golang.org/api/dns/v1"
 	googleapi "google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/pkg/logging"
+	"sigs.k8s.io/external-dns/pkg/ttlpolicy"
 
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
 	"sigs.k8s.io/external-dns/provider"
 )
 
-const (
-	googleRecordTTL = 300
-)
+// logger returns the logger of the provider. Messages about a zone have its
+// DNS name in the logging.ZoneKey attribute, for per-zone levels.
+func logger() *slog.Logger {
+	return logging.For("provider/google")
+}
+
+// ErrReadOnly is returned by ApplyChanges with GoogleReadOnly, as a soft error
+// so the controller keeps running.
+var ErrReadOnly = errors.New("the Google provider is read-only")
 
 type managedZonesCreateCallInterface interface {
 	Do(opts ...googleapi.CallOption) (*dns.ManagedZone, error)
@@ -67,8 +79,13 @@ type changesCreateCallInterface interface {
 	Do(opts ...googleapi.CallOption) (*dns.Change, error)
 }
 
+type changesListCallInterface interface {
+	Pages(ctx context.Context, f func(*dns.ChangesListResponse) error) error
+}
+
 type changesServiceInterface interface {
 	Create(project string, managedZone string, change *dns.Change) changesCreateCallInterface
+	List(project string, managedZone string) changesListCallInterface
 }
 
 type resourceRecordSetsService struct {
@@ -99,6 +116,11 @@ func (c changesService) Create(project string, managedZone string, change *dns.C
 	return c.service.Create(project, managedZone, change)
 }
 
+// List returns the changes of the zone, newest first.
+func (c changesService) List(project string, managedZone string) changesListCallInterface {
+	return c.service.List(project, managedZone).SortBy("changeSequence").SortOrder("descending")
+}
+
 // GoogleProvider is an implementation of Provider for Google CloudDNS.
 type GoogleProvider struct {
 	provider.BaseProvider
@@ -128,8 +150,30 @@ type GoogleProvider struct {
 
 	// Cached zone to domain mapping, used if zones are not explicitly set and we
 	// need to query. Cached for 30sec (TODO: make it configurable)
+	zoneNamesMu        sync.Mutex
 	zoneNames          map[string]string
 	zoneNamesTimestamp time.Time
+	// The visibility of the cached zones, their view with split views.
+	zoneViews map[string]string
+	// The forwarding and peering zones matching the filters but skipped, by
+	// name
+	skippedZones map[string]SkippedZone
+
+	// The batch settings of the zones of GoogleZoneBatchChanges, by name
+	zoneBatches map[string]zoneBatch
+
+	// The IDs of the changes submitted by the provider, by zone, left out
+	// of the journal
+	ownChangesMu sync.Mutex
+	ownChanges   map[string]map[string]bool
+
+	// The set identifiers of the items of the weighted record sets written by
+	// the provider, by view
+	weightedSetsMu sync.Mutex
+	weightedSets   map[string]map[routingKey][]string
+
+	// The zones whose changes failed to apply, retried after a backoff
+	zoneRetries zoneRetries
 }
 
 // NewGoogleProvider initializes a new Google CloudDNS based Provider.
@@ -164,9 +208,13 @@ func NewGoogleProvider(ctx context.Context, cfg *externaldns.ProviderConfig, dom
 		if mErr != nil {
 			return nil, fmt.Errorf("failed to auto-detect the project id: %w", mErr)
 		}
-		log.Infof("Google project auto-detected: %s", mProject)
+		logger().Info("Google project auto-detected", "project", mProject)
 		cfg.GoogleProject = mProject
 	}
+	zoneBatches, err := parseZoneBatches(cfg.GoogleZoneBatchChanges, cfg.GoogleBatchChangeSize, cfg.GoogleBatchChangeInterval)
+	if err != nil {
+		return nil, err
+	}
 	if domainFilter == nil {
 		df := endpoint.NewDomainFilter([]string{})
 		domainFilter = &df
@@ -185,6 +233,7 @@ func NewGoogleProvider(ctx context.Context, cfg *externaldns.ProviderConfig, dom
 		managedZonesClient:       managedZonesService{dnsClient.ManagedZones},
 		changesClient:            changesService{dnsClient.Changes},
 		ctx:                      ctx,
+		zoneBatches:              zoneBatches,
 	}
 
 	if zoneVisibility != "" {
@@ -198,7 +247,7 @@ func NewGoogleProvider(ctx context.Context, cfg *externaldns.ProviderConfig, dom
 			return nil, err
 		}
 		for _, z := range zones {
-			log.Info("Zone", " name", z.Name, " dns=", z.DnsName, " visibility=", z.Visibility)
+			logger().Info("Zone", "name", z.Name, logging.ZoneKey, z.DnsName, "visibility", z.Visibility)
 		}
 	}
 
@@ -236,20 +285,50 @@ func (p *GoogleProvider) Zone2Domain(ctx context.Context) (map[string]string, er
 		// Explicitly set by user - probably no permissions to list zones or user doesn't want all zones.
 		return p.ProviderConfig.Zones, nil
 	}
+	p.zoneNamesMu.Lock()
 	if p.zoneNames != nil && time.Since(p.zoneNamesTimestamp) < 30*time.Second {
+		defer p.zoneNamesMu.Unlock()
 		return p.zoneNames, nil
 	}
+	p.zoneNamesMu.Unlock()
 	z, err := p.Zones(ctx)
 	if err != nil {
 		return nil, err
 	}
-	p.zoneNames = map[string]string{}
-
+	zoneNames := map[string]string{}
+	zoneViews := map[string]string{}
 	for _, zi := range z {
-		p.zoneNames[zi.Name] = zi.DnsName
+		zoneNames[zi.Name] = zi.DnsName
+		zoneViews[zi.Name] = zi.Visibility
 	}
+
+	p.zoneNamesMu.Lock()
+	defer p.zoneNamesMu.Unlock()
+	p.zoneNames = zoneNames
+	p.zoneViews = zoneViews
 	p.zoneNamesTimestamp = time.Now()
-	return p.zoneNames, nil
+	return zoneNames, nil
+}
+
+// DebugState is the project and the zones of the provider, for /debug/state.
+type DebugState struct {
+	Project string `json:"project"`
+	// Zones are the configured zones, or the cached zones of the project.
+	Zones     map[string]string `json:"zones"`
+	ZonesTime time.Time         `json:"zonesTime,omitempty"`
+	// SkippedZones are the forwarding and peering zones matching the filters
+	// but not managed, by name.
+	SkippedZones map[string]SkippedZone `json:"skippedZones,omitempty"`
+}
+
+// DebugState returns the project and the zones, without listing them.
+func (p *GoogleProvider) DebugState() DebugState {
+	if p.ProviderConfig.Zones != nil {
+		return DebugState{Project: p.GoogleProject, Zones: p.ProviderConfig.Zones}
+	}
+	p.zoneNamesMu.Lock()
+	defer p.zoneNamesMu.Unlock()
+	return DebugState{Project: p.GoogleProject, Zones: p.zoneNames, ZonesTime: p.zoneNamesTimestamp, SkippedZones: p.skippedZones}
 }
 
 func (p *GoogleProvider) GetDomainFilter() endpoint.DomainFilter {
@@ -277,36 +356,35 @@ func (p *GoogleProvider) Zones(ctx context.Context) (map[string]*dns.ManagedZone
 
 	f := func(resp *dns.ManagedZonesListResponse) error {
 		for _, zone := range resp.ManagedZones {
-			if zone.PeeringConfig != nil {
-				log.Debugf("Filtered peering zone %s (zone: %s) (visibility: %s)", zone.DnsName, zone.Name, zone.Visibility)
-				continue
-			}
 			if strings.HasPrefix(zone.Name, "gke-") {
-				log.Debugf("Filtered gke zone %s (zone: %s) (visibility: %s)", zone.DnsName, zone.Name, zone.Visibility)
+				logger().Debug("Filtered gke zone", logging.ZoneKey, zone.DnsName, "name", zone.Name, "visibility", zone.Visibility)
 				continue
 			}
 			if p.domainFilter.Match(zone.DnsName) && p.zoneTypeFilter.Match(zone.Visibility) && (p.zoneIDFilter.Match(fmt.Sprintf("%v", zone.Id)) || p.zoneIDFilter.Match(fmt.Sprintf("%v", zone.Name))) {
 				zones[zone.Name] = zone
-				log.Debugf("Matched %s (zone: %s) (visibility: %s)", zone.DnsName, zone.Name, zone.Visibility)
+				logger().Debug("Matched zone", logging.ZoneKey, zone.DnsName, "name", zone.Name, "visibility", zone.Visibility)
 			} else {
-				log.Debugf("Filtered %s (zone: %s) (visibility: %s)", zone.DnsName, zone.Name, zone.Visibility)
+				logger().Debug("Filtered zone", logging.ZoneKey, zone.DnsName, "name", zone.Name, "visibility", zone.Visibility)
 			}
 		}
 
 		return nil
 	}
 
-	log.Debugf("Matching zones against domain filters: %v", p.domainFilter)
+	logger().Debug("Matching zones against domain filters", "domainFilter", p.domainFilter.Filters)
 	if err := p.managedZonesClient.List(p.GoogleProject).Pages(ctx, f); err != nil {
 		return nil, err
 	}
+	if err := p.forwardingZones(zones); err != nil {
+		return nil, err
+	}
 
 	if len(zones) == 0 {
-		log.Warnf("No zones in the project, %s, match domain filters: %v", p.GoogleProject, p.domainFilter)
+		logger().Warn("No zones in the project match domain filters", "project", p.GoogleProject, "domainFilter", p.domainFilter.Filters)
 	}
 
 	for _, zone := range zones {
-		log.Debugf("Considering zone: %s (domain: %s)", zone.Name, zone.DnsName)
+		logger().Debug("Considering zone", "name", zone.Name, logging.ZoneKey, zone.DnsName)
 	}
 
 	// TODO: filter out .cluster.local zones and other GKE-reconciled zones.
@@ -316,44 +394,137 @@ func (p *GoogleProvider) Zones(ctx context.Context) (map[string]*dns.ManagedZone
 
 // Records returns the list of records in all relevant zones.
 func (p *GoogleProvider) Records(ctx context.Context) (endpoints []*endpoint.Endpoint, _ error) {
-	f := func(resp *dns.ResourceRecordSetsListResponse) error {
-		for _, r := range resp.Rrsets {
-			if !p.SupportedRecordType(r.Type) {
-				continue
-			}
-			// May also include Singatures
-			endpoints = append(endpoints, endpoint.NewEndpointWithTTL(r.Name, r.Type, endpoint.TTL(r.Ttl), r.Rrdatas...))
-		}
-
+	err := p.StreamRecords(ctx, func(ep *endpoint.Endpoint) error {
+		endpoints = append(endpoints, ep)
 		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return endpoints, nil
+}
 
+// StreamRecords calls fn with the records of all relevant zones, page by page.
+func (p *GoogleProvider) StreamRecords(ctx context.Context, fn func(*endpoint.Endpoint) error) error {
 	zones, err := p.Zone2Domain(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	for n, _ := range zones {
+	for n := range zones {
+		count := 0
+		view := p.zoneView(n)
+		f := func(resp *dns.ResourceRecordSetsListResponse) error {
+			for _, r := range resp.Rrsets {
+				if !p.SupportedRecordType(r.Type) {
+					continue
+				}
+				count++
+				if hasRoutingPolicy(r) {
+					for _, ep := range p.routedEndpoints(r, view) {
+						if err := fn(withZoneView(ep, view)); err != nil {
+							return err
+						}
+					}
+					continue
+				}
+				// May also include Singatures
+				if err := fn(withZoneView(endpoint.NewEndpointWithTTL(r.Name, r.Type, endpoint.TTL(r.Ttl), r.Rrdatas...), view)); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
 		if err := p.resourceRecordSetsClient.List(p.GoogleProject, n).Pages(ctx, f); err != nil {
-			return nil, err
+			return err
 		}
+		zoneRecords.WithLabelValues(p.GoogleProject, n).Set(float64(count))
 	}
 
-	return endpoints, nil
+	return nil
 }
 
 // ApplyChanges applies a given set of changes in a given zone. Only DNS domains that are configured are allowed.
+// The record sets with a geo or weighted routing policy are replaced with all their items.
+// With split views, the changes of a view are applied in the zones of the view.
+// With GoogleReadOnly, any change fails with a soft ErrReadOnly.
 func (p *GoogleProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	if p.GoogleReadOnly && changes.HasChanges() {
+		return provider.NewSoftError(ErrReadOnly)
+	}
+	views := viewChanges(changes)
+	names := make([]string, 0, len(views))
+	for view := range views {
+		names = append(names, view)
+	}
+	sort.Strings(names)
+	for _, view := range names {
+		if err := p.applyChanges(ctx, views[view], view); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyChanges applies the changes in the zones of the view.
+func (p *GoogleProvider) applyChanges(ctx context.Context, changes *plan.Changes, view string) error {
+	create, routedCreate := splitRouted(changes.Create)
+	updateNew, routedUpdateNew := splitRouted(changes.UpdateNew)
+	updateOld, routedUpdateOld := splitRouted(changes.UpdateOld)
+	deleted, routedDelete := splitRouted(changes.Delete)
+
 	change := &dns.Change{}
 
-	change.Additions = append(change.Additions, p.newFilteredRecords(changes.Create)...)
+	change.Additions = append(change.Additions, p.newFilteredRecords(create)...)
 
-	change.Additions = append(change.Additions, p.newFilteredRecords(changes.UpdateNew)...)
-	change.Deletions = append(change.Deletions, p.newFilteredRecords(changes.UpdateOld)...)
+	deletions, additions := dropUnchanged(p.newFilteredRecords(updateOld), p.newFilteredRecords(updateNew))
+	change.Additions = append(change.Additions, additions...)
+	change.Deletions = append(change.Deletions, deletions...)
 
-	change.Deletions = append(change.Deletions, p.newFilteredRecords(changes.Delete)...)
+	change.Deletions = append(change.Deletions, p.newFilteredRecords(deleted)...)
 
-	return p.submitChange(ctx, change)
+	routing, sets, err := p.routingChange(ctx, append(routedUpdateOld, routedDelete...), append(routedCreate, routedUpdateNew...), view)
+	if err != nil {
+		return err
+	}
+	change.Additions = append(change.Additions, routing.Additions...)
+	change.Deletions = append(change.Deletions, routing.Deletions...)
+
+	if err := p.submitChange(ctx, change, view); err != nil {
+		return err
+	}
+	if !p.dryRun {
+		p.setWeightedSets(view, sets)
+	}
+	return nil
+}
+
+// dropUnchanged drops the updates that don't change their record set, like the
+// records of no owner claimed as they are (see plan.Plan.AdoptUnowned): only
+// their TXT records are created.
+func dropUnchanged(deletions, additions []*dns.ResourceRecordSet) ([]*dns.ResourceRecordSet, []*dns.ResourceRecordSet) {
+	old := map[string]*dns.ResourceRecordSet{}
+	for _, d := range deletions {
+		old[d.Name+"/"+d.Type] = d
+	}
+	unchanged := map[string]bool{}
+	added := []*dns.ResourceRecordSet{}
+	for _, a := range additions {
+		key := a.Name + "/" + a.Type
+		if d := old[key]; d != nil && reflect.DeepEqual(d, a) {
+			unchanged[key] = true
+			continue
+		}
+		added = append(added, a)
+	}
+	deleted := []*dns.ResourceRecordSet{}
+	for _, d := range deletions {
+		if !unchanged[d.Name+"/"+d.Type] {
+			deleted = append(deleted, d)
+		}
+	}
+	return deleted, added
 }
 
 // SupportedRecordType returns true if the record type is supported by the provider
@@ -379,46 +550,125 @@ func (p *GoogleProvider) newFilteredRecords(endpoints []*endpoint.Endpoint) []*d
 	return records
 }
 
-// submitChange takes a zone and a Change and sends it to Google.
-func (p *GoogleProvider) submitChange(ctx context.Context, change *dns.Change) error {
+// submitChange takes a Change and sends it to Google, in the zones of the view.
+func (p *GoogleProvider) submitChange(ctx context.Context, change *dns.Change, view string) error {
 	if len(change.Additions) == 0 && len(change.Deletions) == 0 {
-		log.Debug("All records are already up to date")
+		logger().DebugContext(ctx, "All records are already up to date")
 		return nil
 	}
 
-	zones, err := p.Zone2Domain(ctx)
+	zones, err := p.zonesOfView(ctx, view)
 	if err != nil {
 		return err
 	}
 
 	// separate into per-zone change sets to be passed to the domain name.
 	changes := separateChange(zones, change)
+	names := make([]string, 0, len(changes))
+	for zone := range changes {
+		names = append(names, zone)
+	}
+	sort.Strings(names)
 
-	for zone, change := range changes {
-		for batch, c := range batchChange(change, p.GoogleBatchChangeSize) {
-			log.Infof("Change zone: %v batch #%d", zone, batch)
-			for _, del := range c.Deletions {
-				log.Infof("Del records: %s %s %s %d", del.Name, del.Type, del.Rrdatas, del.Ttl)
-			}
-			for _, add := range c.Additions {
-				log.Infof("Add records: %s %s %s %d", add.Name, add.Type, add.Rrdatas, add.Ttl)
-			}
+	// A zone failing to apply its changes doesn't abort the other zones.
+	var errs []error
+	for _, zone := range names {
+		if err := p.submitZoneChange(ctx, zone, zones[zone], changes[zone]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		// The failed zones are retried after their backoff.
+		return provider.NewSoftError(errors.Join(errs...))
+	}
+	return nil
+}
 
-			if p.dryRun {
-				continue
-			}
+// submitZoneChange sends the change of the zone in batches, or queues the zone
+// for a retry with a backoff if a batch fails. The changes of a zone backing
+// off are skipped until the retry.
+func (p *GoogleProvider) submitZoneChange(ctx context.Context, zone, domain string, change *dns.Change) error {
+	zlog := logger().With("name", zone, logging.ZoneKey, domain)
+	if retry, ok := p.zoneRetries.backingOff(zone); ok {
+		zlog.WarnContext(ctx, "Skipping the changes of the zone until its retry", "retry", retry.next, "error", retry.err)
+		return fmt.Errorf("zone %s: retrying at %s after: %w", zone, retry.next.Format(time.RFC3339), retry.err)
+	}
+
+	size, interval := p.batch(zone)
+	for batch, c := range batchChange(change, size) {
+		zlog.InfoContext(ctx, "Change zone", "batch", batch)
+		for _, del := range c.Deletions {
+			zlog.InfoContext(ctx, "Del records", "record", del.Name, "type", del.Type, "rrdatas", del.Rrdatas, "ttl", del.Ttl)
+		}
+		for _, add := range c.Additions {
+			zlog.InfoContext(ctx, "Add records", "record", add.Name, "type", add.Type, "rrdatas", add.Rrdatas, "ttl", add.Ttl)
+		}
 
-			if _, err := p.changesClient.Create(p.GoogleProject, zone, c).Do(); err != nil {
-				return err
-			}
+		if p.dryRun {
+			continue
+		}
 
-			time.Sleep(p.GoogleBatchChangeInterval)
+		submitted, err := p.changesClient.Create(p.GoogleProject, zone, c).Do()
+		if err != nil {
+			changeErrorsTotal.WithLabelValues(p.GoogleProject, zone).Inc()
+			backoff := p.zoneRetries.failed(p.GoogleProject, zone, err)
+			zlog.ErrorContext(ctx, "Failed to apply the changes of the zone", "batch", batch, "error", err, "backoff", backoff)
+			return fmt.Errorf("zone %s: %w", zone, err)
 		}
-	}
+		p.addOwnChange(zone, submitted)
+		changesTotal.WithLabelValues(p.GoogleProject, zone, "add").Add(float64(len(c.Additions)))
+		changesTotal.WithLabelValues(p.GoogleProject, zone, "delete").Add(float64(len(c.Deletions)))
 
+		time.Sleep(interval)
+	}
+	p.zoneRetries.succeeded(p.GoogleProject, zone)
 	return nil
 }
 
+// zoneBatch is the batch size and interval of the changes of a zone.
+type zoneBatch struct {
+	size     int
+	interval time.Duration
+}
+
+// parseZoneBatches parses the batch settings of the zones, ZONE=SIZE/INTERVAL,
+// ZONE=SIZE or ZONE=/INTERVAL, the missing ones set to size and interval.
+func parseZoneBatches(values []string, size int, interval time.Duration) (map[string]zoneBatch, error) {
+	batches := make(map[string]zoneBatch, len(values))
+	for _, v := range values {
+		zone, settings, ok := strings.Cut(v, "=")
+		if !ok || zone == "" || settings == "" {
+			return nil, fmt.Errorf("invalid zone batch change %q, use ZONE=SIZE/INTERVAL", v)
+		}
+		b := zoneBatch{size: size, interval: interval}
+		sizeValue, intervalValue, _ := strings.Cut(settings, "/")
+		if sizeValue != "" {
+			n, err := strconv.Atoi(sizeValue)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid batch size of zone %s: %q", zone, sizeValue)
+			}
+			b.size = n
+		}
+		if intervalValue != "" {
+			d, err := time.ParseDuration(intervalValue)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("invalid batch interval of zone %s: %q", zone, intervalValue)
+			}
+			b.interval = d
+		}
+		batches[zone] = b
+	}
+	return batches, nil
+}
+
+// batch returns the batch size and interval of the changes of the zone.
+func (p *GoogleProvider) batch(zone string) (int, time.Duration) {
+	if b, ok := p.zoneBatches[zone]; ok {
+		return b.size, b.interval
+	}
+	return p.GoogleBatchChangeSize, p.GoogleBatchChangeInterval
+}
+
 // batchChange separates a zone in multiple transaction.
 func batchChange(change *dns.Change, batchSize int) []*dns.Change {
 	changes := []*dns.Change{}
@@ -468,8 +718,7 @@ func batchChange(change *dns.Change, batchSize int) []*dns.Change {
 		totalChangesByName := len(c.additions) + len(c.deletions)
 
 		if totalChangesByName > batchSize {
-			log.Warnf("Total changes for %s exceeds max batch size of %d, total changes: %d", name,
-				batchSize, totalChangesByName)
+			logger().Warn("Total changes for a name exceed the max batch size", "record", name, "batchSize", batchSize, "changes", totalChangesByName)
 			continue
 		}
 
@@ -507,7 +756,7 @@ func separateChange(zones map[string]string, change *dns.Change) map[string]*dns
 		if zoneName, _ := zoneNameIDMapper.FindZone(provider.EnsureTrailingDot(a.Name)); zoneName != "" {
 			changes[zoneName].Additions = append(changes[zoneName].Additions, a)
 		} else {
-			log.Warnf("No matching zone for record addition: %s %s %s %d", a.Name, a.Type, a.Rrdatas, a.Ttl)
+			logger().Warn("No matching zone for record addition", "record", a.Name, "type", a.Type, "rrdatas", a.Rrdatas, "ttl", a.Ttl)
 		}
 	}
 
@@ -515,7 +764,7 @@ func separateChange(zones map[string]string, change *dns.Change) map[string]*dns
 		if zoneName, _ := zoneNameIDMapper.FindZone(provider.EnsureTrailingDot(d.Name)); zoneName != "" {
 			changes[zoneName].Deletions = append(changes[zoneName].Deletions, d)
 		} else {
-			log.Warnf("No matching zone for record deletion: %s %s %s %d", d.Name, d.Type, d.Rrdatas, d.Ttl)
+			logger().Warn("No matching zone for record deletion", "record", d.Name, "type", d.Type, "rrdatas", d.Rrdatas, "ttl", d.Ttl)
 		}
 	}
 
@@ -553,8 +802,9 @@ func newRecord(ep *endpoint.Endpoint) *dns.ResourceRecordSet {
 		}
 	}
 
-	// no annotation results in a Ttl of 0, default to 300 for backwards-compatibility
-	var ttl int64 = googleRecordTTL
+	// no annotation, nor default of the TTL policy, results in a Ttl of 0,
+	// default to 300 for backwards-compatibility
+	var ttl int64 = ttlpolicy.DefaultTTL
 	if ep.RecordTTL.IsConfigured() {
 		ttl = int64(ep.RecordTTL)
 	}
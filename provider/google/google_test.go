@@ -20,9 +20,12 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
@@ -31,6 +34,8 @@ import (
 
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/internal/testutils"
+	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/pkg/ttlpolicy"
 	"sigs.k8s.io/external-dns/plan"
 	"sigs.k8s.io/external-dns/provider"
 )
@@ -38,6 +43,7 @@ import (
 var (
 	testZones                    = map[string]*dns.ManagedZone{}
 	testRecords                  = map[string]map[string]*dns.ResourceRecordSet{}
+	testChanges                  = map[string][]*dns.Change{}
 	googleDefaultBatchChangeSize = 4000
 )
 
@@ -147,7 +153,33 @@ func (m *mockChangesCreateCall) Do(opts ...googleapi.CallOption) (*dns.Change, e
 		testRecords[zoneKey][recordKey] = add
 	}
 
-	return m.change, nil
+	change := *m.change
+	change.Id = strconv.Itoa(len(testChanges[zoneKey]) + 1)
+	testChanges[zoneKey] = append(testChanges[zoneKey], &change)
+	return &change, nil
+}
+
+type mockChangesListCall struct {
+	project     string
+	managedZone string
+}
+
+func (m *mockChangesListCall) Pages(ctx context.Context, f func(*dns.ChangesListResponse) error) error {
+	zoneKey := zoneKey(m.project, m.managedZone)
+
+	if _, ok := testZones[zoneKey]; !ok {
+		return &googleapi.Error{Code: http.StatusNotFound}
+	}
+
+	changes := testChanges[zoneKey]
+	resp := make([]*dns.Change, 0, len(changes)+1)
+	for i := len(changes) - 1; i >= 0; i-- {
+		resp = append(resp, changes[i])
+	}
+	// The change creating the zone.
+	resp = append(resp, &dns.Change{Id: "0"})
+
+	return f(&dns.ChangesListResponse{Changes: resp})
 }
 
 type mockChangesClient struct{}
@@ -156,6 +188,10 @@ func (m *mockChangesClient) Create(project string, managedZone string, change *d
 	return &mockChangesCreateCall{project: project, managedZone: managedZone, change: change}
 }
 
+func (m *mockChangesClient) List(project string, managedZone string) changesListCallInterface {
+	return &mockChangesListCall{project: project, managedZone: managedZone}
+}
+
 func zoneKey(project, zoneName string) string {
 	return project + "/" + zoneName
 }
@@ -261,6 +297,19 @@ func TestGoogleZones(t *testing.T) {
 	})
 }
 
+func TestGoogleDebugState(t *testing.T) {
+	assert.Empty(t, (&GoogleProvider{}).DebugState().Zones, "the zones are not listed")
+
+	provider := newGoogleProvider(t, endpoint.NewDomainFilter([]string{"ext-dns-test-2.gcp.zalan.do."}), provider.NewZoneIDFilter([]string{""}), false, []*endpoint.Endpoint{})
+
+	_, err := provider.Zone2Domain(context.Background())
+	require.NoError(t, err)
+	state := provider.DebugState()
+	assert.Equal(t, provider.GoogleProject, state.Project)
+	assert.Equal(t, "zone-1.ext-dns-test-2.gcp.zalan.do.", state.Zones["zone-1-ext-dns-test-2-gcp-zalan-do"])
+	assert.False(t, state.ZonesTime.IsZero())
+}
+
 func TestGoogleRecords(t *testing.T) {
 	originalEndpoints := []*endpoint.Endpoint{
 		endpoint.NewEndpointWithTTL("list-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, endpoint.TTL(1), "1.2.3.4"),
@@ -276,14 +325,35 @@ func TestGoogleRecords(t *testing.T) {
 	validateEndpoints(t, records, originalEndpoints)
 }
 
+func TestGoogleMetrics(t *testing.T) {
+	provider := newGoogleProvider(t, endpoint.NewDomainFilter([]string{"ext-dns-test-2.gcp.zalan.do."}), provider.NewZoneIDFilter([]string{""}), false, []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("list-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, endpoint.TTL(1), "1.2.3.4"),
+	})
+	zone := "zone-1-ext-dns-test-2-gcp-zalan-do"
+	records := zoneRecords.WithLabelValues(provider.GoogleProject, zone)
+	added := changesTotal.WithLabelValues(provider.GoogleProject, zone, "add")
+
+	_, err := provider.Records(context.Background())
+	require.NoError(t, err)
+	before, addedBefore := testutil.ToFloat64(records), testutil.ToFloat64(added)
+
+	require.NoError(t, provider.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpoint("create-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, "8.8.8.8"),
+	}}))
+	_, err = provider.Records(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, before+1, testutil.ToFloat64(records))
+	assert.Equal(t, addedBefore+1, testutil.ToFloat64(added))
+}
+
 func TestGoogleRecordsFilter(t *testing.T) {
 	originalEndpoints := []*endpoint.Endpoint{
-		endpoint.NewEndpointWithTTL("update-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, googleRecordTTL, "8.8.8.8"),
-		endpoint.NewEndpointWithTTL("delete-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, googleRecordTTL, "8.8.8.8"),
-		endpoint.NewEndpointWithTTL("update-test.zone-2.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, googleRecordTTL, "8.8.4.4"),
-		endpoint.NewEndpointWithTTL("delete-test.zone-2.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, googleRecordTTL, "8.8.4.4"),
-		endpoint.NewEndpointWithTTL("update-test-cname.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeCNAME, googleRecordTTL, "bar.elb.amazonaws.com"),
-		endpoint.NewEndpointWithTTL("delete-test-cname.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeCNAME, googleRecordTTL, "qux.elb.amazonaws.com"),
+		endpoint.NewEndpointWithTTL("update-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, ttlpolicy.DefaultTTL, "8.8.8.8"),
+		endpoint.NewEndpointWithTTL("delete-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, ttlpolicy.DefaultTTL, "8.8.8.8"),
+		endpoint.NewEndpointWithTTL("update-test.zone-2.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, ttlpolicy.DefaultTTL, "8.8.4.4"),
+		endpoint.NewEndpointWithTTL("delete-test.zone-2.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, ttlpolicy.DefaultTTL, "8.8.4.4"),
+		endpoint.NewEndpointWithTTL("update-test-cname.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeCNAME, ttlpolicy.DefaultTTL, "bar.elb.amazonaws.com"),
+		endpoint.NewEndpointWithTTL("delete-test-cname.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeCNAME, ttlpolicy.DefaultTTL, "qux.elb.amazonaws.com"),
 	}
 
 	provider := newGoogleProvider(
@@ -336,12 +406,12 @@ func TestGoogleApplyChanges(t *testing.T) {
 		provider.NewZoneIDFilter([]string{""}),
 		false,
 		[]*endpoint.Endpoint{
-			endpoint.NewEndpointWithTTL("update-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, googleRecordTTL, "8.8.8.8"),
-			endpoint.NewEndpointWithTTL("delete-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, googleRecordTTL, "8.8.8.8"),
+			endpoint.NewEndpointWithTTL("update-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, ttlpolicy.DefaultTTL, "8.8.8.8"),
+			endpoint.NewEndpointWithTTL("delete-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, ttlpolicy.DefaultTTL, "8.8.8.8"),
 			endpoint.NewEndpointWithTTL("update-test-ttl.zone-2.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, endpoint.TTL(10), "8.8.4.4"),
-			endpoint.NewEndpointWithTTL("delete-test.zone-2.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, googleRecordTTL, "8.8.4.4"),
-			endpoint.NewEndpointWithTTL("update-test-cname.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeCNAME, googleRecordTTL, "bar.elb.amazonaws.com"),
-			endpoint.NewEndpointWithTTL("delete-test-cname.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeCNAME, googleRecordTTL, "qux.elb.amazonaws.com"),
+			endpoint.NewEndpointWithTTL("delete-test.zone-2.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, ttlpolicy.DefaultTTL, "8.8.4.4"),
+			endpoint.NewEndpointWithTTL("update-test-cname.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeCNAME, ttlpolicy.DefaultTTL, "bar.elb.amazonaws.com"),
+			endpoint.NewEndpointWithTTL("delete-test-cname.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeCNAME, ttlpolicy.DefaultTTL, "qux.elb.amazonaws.com"),
 		},
 	)
 
@@ -388,23 +458,23 @@ func TestGoogleApplyChanges(t *testing.T) {
 	require.NoError(t, err)
 
 	validateEndpoints(t, records, []*endpoint.Endpoint{
-		endpoint.NewEndpointWithTTL("create-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, googleRecordTTL, "8.8.8.8"),
-		endpoint.NewEndpointWithTTL("update-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, googleRecordTTL, "1.2.3.4"),
+		endpoint.NewEndpointWithTTL("create-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, ttlpolicy.DefaultTTL, "8.8.8.8"),
+		endpoint.NewEndpointWithTTL("update-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, ttlpolicy.DefaultTTL, "1.2.3.4"),
 		endpoint.NewEndpointWithTTL("create-test-ttl.zone-2.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, endpoint.TTL(15), "8.8.4.4"),
 		endpoint.NewEndpointWithTTL("update-test-ttl.zone-2.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, endpoint.TTL(25), "4.3.2.1"),
-		endpoint.NewEndpointWithTTL("create-test-cname.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeCNAME, googleRecordTTL, "foo.elb.amazonaws.com"),
-		endpoint.NewEndpointWithTTL("update-test-cname.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeCNAME, googleRecordTTL, "baz.elb.amazonaws.com"),
+		endpoint.NewEndpointWithTTL("create-test-cname.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeCNAME, ttlpolicy.DefaultTTL, "foo.elb.amazonaws.com"),
+		endpoint.NewEndpointWithTTL("update-test-cname.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeCNAME, ttlpolicy.DefaultTTL, "baz.elb.amazonaws.com"),
 	})
 }
 
 func TestGoogleApplyChangesDryRun(t *testing.T) {
 	originalEndpoints := []*endpoint.Endpoint{
-		endpoint.NewEndpointWithTTL("update-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, googleRecordTTL, "8.8.8.8"),
-		endpoint.NewEndpointWithTTL("delete-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, googleRecordTTL, "8.8.8.8"),
-		endpoint.NewEndpointWithTTL("update-test.zone-2.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, googleRecordTTL, "8.8.4.4"),
-		endpoint.NewEndpointWithTTL("delete-test.zone-2.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, googleRecordTTL, "8.8.4.4"),
-		endpoint.NewEndpointWithTTL("update-test-cname.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeCNAME, googleRecordTTL, "bar.elb.amazonaws.com"),
-		endpoint.NewEndpointWithTTL("delete-test-cname.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeCNAME, googleRecordTTL, "qux.elb.amazonaws.com"),
+		endpoint.NewEndpointWithTTL("update-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, ttlpolicy.DefaultTTL, "8.8.8.8"),
+		endpoint.NewEndpointWithTTL("delete-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, ttlpolicy.DefaultTTL, "8.8.8.8"),
+		endpoint.NewEndpointWithTTL("update-test.zone-2.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, ttlpolicy.DefaultTTL, "8.8.4.4"),
+		endpoint.NewEndpointWithTTL("delete-test.zone-2.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, ttlpolicy.DefaultTTL, "8.8.4.4"),
+		endpoint.NewEndpointWithTTL("update-test-cname.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeCNAME, ttlpolicy.DefaultTTL, "bar.elb.amazonaws.com"),
+		endpoint.NewEndpointWithTTL("delete-test-cname.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeCNAME, ttlpolicy.DefaultTTL, "qux.elb.amazonaws.com"),
 	}
 
 	provider := newGoogleProvider(t, endpoint.NewDomainFilter([]string{"ext-dns-test-2.gcp.zalan.do."}), provider.NewZoneIDFilter([]string{""}), true, originalEndpoints)
@@ -448,6 +518,28 @@ func TestGoogleApplyChangesDryRun(t *testing.T) {
 	validateEndpoints(t, records, originalEndpoints)
 }
 
+func TestGoogleApplyChangesReadOnly(t *testing.T) {
+	originalEndpoints := []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("delete-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, ttlpolicy.DefaultTTL, "8.8.8.8"),
+	}
+	p := newGoogleProvider(t, endpoint.NewDomainFilter([]string{"ext-dns-test-2.gcp.zalan.do."}), provider.NewZoneIDFilter([]string{""}), false, originalEndpoints)
+	p.GoogleReadOnly = true
+
+	ctx := context.Background()
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("create-test.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, "8.8.8.8")},
+		Delete: originalEndpoints,
+	}
+	err := p.ApplyChanges(ctx, changes)
+	assert.ErrorIs(t, err, ErrReadOnly)
+	assert.ErrorIs(t, err, provider.SoftError, "the controller keeps running")
+	assert.NoError(t, p.ApplyChanges(ctx, &plan.Changes{}))
+
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	validateEndpoints(t, records, originalEndpoints)
+}
+
 func TestGoogleApplyChangesEmpty(t *testing.T) {
 	provider := newGoogleProvider(t, endpoint.NewDomainFilter([]string{"ext-dns-test-2.gcp.zalan.do."}), provider.NewZoneIDFilter([]string{""}), false, []*endpoint.Endpoint{})
 	assert.NoError(t, provider.ApplyChanges(context.Background(), &plan.Changes{}))
@@ -478,6 +570,17 @@ func TestNewFilteredRecords(t *testing.T) {
 	})
 }
 
+func TestDropUnchanged(t *testing.T) {
+	claimed := &dns.ResourceRecordSet{Name: "claimed.zone-1.ext-dns-test-2.gcp.zalan.do.", Rrdatas: []string{"8.8.8.8"}, Type: "A", Ttl: 3600}
+	oldRecord := &dns.ResourceRecordSet{Name: "update.zone-1.ext-dns-test-2.gcp.zalan.do.", Rrdatas: []string{"8.8.8.8"}, Type: "A", Ttl: 300}
+	newRecord := &dns.ResourceRecordSet{Name: "update.zone-1.ext-dns-test-2.gcp.zalan.do.", Rrdatas: []string{"1.2.3.4"}, Type: "A", Ttl: 300}
+	claimedCopy := *claimed
+
+	deletions, additions := dropUnchanged([]*dns.ResourceRecordSet{claimed, oldRecord}, []*dns.ResourceRecordSet{&claimedCopy, newRecord})
+	validateChangeRecords(t, deletions, []*dns.ResourceRecordSet{oldRecord})
+	validateChangeRecords(t, additions, []*dns.ResourceRecordSet{newRecord})
+}
+
 func TestSeparateChanges(t *testing.T) {
 	change := &dns.Change{
 		Additions: []*dns.ResourceRecordSet{
@@ -490,19 +593,10 @@ func TestSeparateChanges(t *testing.T) {
 		},
 	}
 
-	zones := map[string]*dns.ManagedZone{
-		"foo-example-org": {
-			Name:    "foo-example-org",
-			DnsName: "foo.example.org.",
-		},
-		"bar-example-org": {
-			Name:    "bar-example-org",
-			DnsName: "bar.example.org.",
-		},
-		"baz-example-org": {
-			Name:    "baz-example-org",
-			DnsName: "baz.example.org.",
-		},
+	zones := map[string]string{
+		"foo-example-org": "foo.example.org.",
+		"bar-example-org": "bar.example.org.",
+		"baz-example-org": "baz.example.org.",
 	}
 
 	changes := separateChange(zones, change)
@@ -549,6 +643,30 @@ func TestGoogleBatchChangeSet(t *testing.T) {
 	validateChange(t, batchCs[0], cs)
 }
 
+func TestParseZoneBatches(t *testing.T) {
+	batches, err := parseZoneBatches([]string{"pods=100/5s", "public=10", "internal=/100ms"}, 1000, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]zoneBatch{
+		"pods":     {size: 100, interval: 5 * time.Second},
+		"public":   {size: 10, interval: time.Second},
+		"internal": {size: 1000, interval: 100 * time.Millisecond},
+	}, batches)
+
+	p := &GoogleProvider{zoneBatches: batches}
+	p.GoogleBatchChangeSize, p.GoogleBatchChangeInterval = 1000, time.Second
+	size, interval := p.batch("pods")
+	assert.Equal(t, 100, size)
+	assert.Equal(t, 5*time.Second, interval)
+	size, interval = p.batch("other")
+	assert.Equal(t, 1000, size)
+	assert.Equal(t, time.Second, interval)
+
+	for _, v := range []string{"pods", "pods=", "=10", "pods=ten", "pods=10/soon", "pods=-1"} {
+		_, err := parseZoneBatches([]string{v}, 1000, time.Second)
+		assert.Error(t, err, v)
+	}
+}
+
 func TestGoogleBatchChangeSetExceeding(t *testing.T) {
 	cs := &dns.Change{}
 	const testCount = 50
@@ -649,10 +767,10 @@ func validateChangeRecord(t *testing.T, record *dns.ResourceRecordSet, expected
 
 func newGoogleProviderZoneOverlap(t *testing.T, domainFilter endpoint.DomainFilter, zoneIDFilter provider.ZoneIDFilter, zoneTypeFilter provider.ZoneTypeFilter, dryRun bool, records []*endpoint.Endpoint) *GoogleProvider {
 	provider := &GoogleProvider{
-		Project:                  "zalando-external-dns-test",
+		ProviderConfig:           externaldns.ProviderConfig{GoogleProject: "zalando-external-dns-test"},
 		dryRun:                   false,
-		domainFilter:             domainFilter,
-		zoneIDFilter:             zoneIDFilter,
+		domainFilter:             &domainFilter,
+		zoneIDFilter:             &zoneIDFilter,
 		zoneTypeFilter:           zoneTypeFilter,
 		resourceRecordSetsClient: &mockResourceRecordSetsClient{},
 		managedZonesClient:       &mockManagedZonesClient{},
@@ -717,10 +835,10 @@ func newGoogleProviderZoneOverlap(t *testing.T, domainFilter endpoint.DomainFilt
 
 func newGoogleProvider(t *testing.T, domainFilter endpoint.DomainFilter, zoneIDFilter provider.ZoneIDFilter, dryRun bool, records []*endpoint.Endpoint) *GoogleProvider {
 	provider := &GoogleProvider{
-		Project:                  "zalando-external-dns-test",
+		ProviderConfig:           externaldns.ProviderConfig{GoogleProject: "zalando-external-dns-test"},
 		dryRun:                   false,
-		domainFilter:             domainFilter,
-		zoneIDFilter:             zoneIDFilter,
+		domainFilter:             &domainFilter,
+		zoneIDFilter:             &zoneIDFilter,
 		resourceRecordSetsClient: &mockResourceRecordSetsClient{},
 		managedZonesClient:       &mockManagedZonesClient{},
 		changesClient:            &mockChangesClient{},
@@ -787,7 +905,7 @@ func setupGoogleRecords(t *testing.T, provider *GoogleProvider, endpoints []*end
 
 func clearGoogleRecords(t *testing.T, provider *GoogleProvider, zone string) {
 	recordSets := []*dns.ResourceRecordSet{}
-	require.NoError(t, provider.resourceRecordSetsClient.List(provider.Project, zone).Pages(context.Background(), func(resp *dns.ResourceRecordSetsListResponse) error {
+	require.NoError(t, provider.resourceRecordSetsClient.List(provider.GoogleProject, zone).Pages(context.Background(), func(resp *dns.ResourceRecordSetsListResponse) error {
 		for _, r := range resp.Rrsets {
 			switch r.Type {
 			case endpoint.RecordTypeA, endpoint.RecordTypeCNAME:
@@ -798,7 +916,7 @@ func clearGoogleRecords(t *testing.T, provider *GoogleProvider, zone string) {
 	}))
 
 	if len(recordSets) != 0 {
-		_, err := provider.changesClient.Create(provider.Project, zone, &dns.Change{
+		_, err := provider.changesClient.Create(provider.GoogleProject, zone, &dns.Change{
 			Deletions: recordSets,
 		}).Do()
 		require.NoError(t, err)
@@ -0,0 +1,158 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package google
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	dns "google.golang.org/api/dns/v1"
+
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// errJournalEnd stops the listing of the changes of a zone at the cursor.
+var errJournalEnd = errors.New("end of the journal")
+
+// Changes implements provider.Journal with the changes of the zones, listed
+// newest first until the sequence of the cursor. The cursor holds the last
+// change sequence of each zone - a zone missing from it expires the cursor.
+func (p *GoogleProvider) Changes(ctx context.Context, cursor string) (*provider.JournalChanges, error) {
+	since, err := parseJournalCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	zones, err := p.Zone2Domain(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	next := map[string]int64{}
+	names := map[string]bool{}
+	for zone := range zones {
+		last, ok := since[zone]
+		if cursor != "" && !ok {
+			return nil, provider.ErrJournalExpired
+		}
+		next[zone] = last
+		if cursor == "" {
+			p.resetOwnChanges(zone)
+		}
+		err := p.changesClient.List(p.GoogleProject, zone).Pages(ctx, func(resp *dns.ChangesListResponse) error {
+			for _, change := range resp.Changes {
+				seq, err := strconv.ParseInt(change.Id, 10, 64)
+				if err != nil {
+					return fmt.Errorf("change %q of zone %s: %w", change.Id, zone, err)
+				}
+				if seq > next[zone] {
+					next[zone] = seq
+				}
+				if cursor == "" || seq <= last {
+					return errJournalEnd
+				}
+				if p.takeOwnChange(zone, change.Id) {
+					continue
+				}
+				for _, r := range change.Additions {
+					names[strings.TrimSuffix(r.Name, ".")] = true
+				}
+				for _, r := range change.Deletions {
+					names[strings.TrimSuffix(r.Name, ".")] = true
+				}
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errJournalEnd) {
+			return nil, err
+		}
+	}
+
+	changes := &provider.JournalChanges{Cursor: formatJournalCursor(next)}
+	for name := range names {
+		changes.Names = append(changes.Names, name)
+	}
+	sort.Strings(changes.Names)
+	return changes, nil
+}
+
+// addOwnChange records a change submitted by the provider.
+func (p *GoogleProvider) addOwnChange(zone string, change *dns.Change) {
+	if change == nil || change.Id == "" {
+		return
+	}
+	p.ownChangesMu.Lock()
+	defer p.ownChangesMu.Unlock()
+	if p.ownChanges == nil {
+		p.ownChanges = map[string]map[string]bool{}
+	}
+	if p.ownChanges[zone] == nil {
+		p.ownChanges[zone] = map[string]bool{}
+	}
+	p.ownChanges[zone][change.Id] = true
+}
+
+// takeOwnChange returns whether the change was submitted by the provider,
+// and forgets it - the journal reports each change once.
+func (p *GoogleProvider) takeOwnChange(zone, id string) bool {
+	p.ownChangesMu.Lock()
+	defer p.ownChangesMu.Unlock()
+	if !p.ownChanges[zone][id] {
+		return false
+	}
+	delete(p.ownChanges[zone], id)
+	return true
+}
+
+// resetOwnChanges forgets the changes submitted before the current position.
+func (p *GoogleProvider) resetOwnChanges(zone string) {
+	p.ownChangesMu.Lock()
+	defer p.ownChangesMu.Unlock()
+	delete(p.ownChanges, zone)
+}
+
+// parseJournalCursor parses a cursor like 'zone-1=12,zone-2=7'.
+func parseJournalCursor(cursor string) (map[string]int64, error) {
+	since := map[string]int64{}
+	if cursor == "" {
+		return since, nil
+	}
+	for _, part := range strings.Split(cursor, ",") {
+		zone, seq, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid journal cursor %q", cursor)
+		}
+		n, err := strconv.ParseInt(seq, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid journal cursor %q: %w", cursor, err)
+		}
+		since[zone] = n
+	}
+	return since, nil
+}
+
+func formatJournalCursor(next map[string]int64) string {
+	parts := make([]string, 0, len(next))
+	for zone, seq := range next {
+		parts = append(parts, zone+"="+strconv.FormatInt(seq, 10))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package google
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dns "google.golang.org/api/dns/v1"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+func TestGoogleChanges(t *testing.T) {
+	domainFilter := endpoint.NewDomainFilter([]string{"journal.gcp.zalan.do."})
+	zoneIDFilter := provider.NewZoneIDFilter([]string{""})
+	p := &GoogleProvider{
+		ProviderConfig:           externaldns.ProviderConfig{GoogleProject: "journal-test"},
+		domainFilter:             &domainFilter,
+		zoneIDFilter:             &zoneIDFilter,
+		resourceRecordSetsClient: &mockResourceRecordSetsClient{},
+		managedZonesClient:       &mockManagedZonesClient{},
+		changesClient:            &mockChangesClient{},
+	}
+	_, err := p.managedZonesClient.Create("journal-test", &dns.ManagedZone{Name: "journal", DnsName: "journal.gcp.zalan.do."}).Do()
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	start, err := p.Changes(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, "journal=0", start.Cursor)
+	assert.Empty(t, start.Names)
+
+	// The changes applied by the provider are left out.
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("a.journal.gcp.zalan.do", endpoint.RecordTypeA, "1.2.3.4")},
+	}))
+	changes, err := p.Changes(ctx, start.Cursor)
+	require.NoError(t, err)
+	assert.Equal(t, "journal=1", changes.Cursor)
+	assert.Empty(t, changes.Names)
+
+	_, err = p.changesClient.Create("journal-test", "journal", &dns.Change{
+		Additions: []*dns.ResourceRecordSet{{Name: "manual.journal.gcp.zalan.do.", Type: "A", Ttl: 300, Rrdatas: []string{"1.2.3.5"}}},
+	}).Do()
+	require.NoError(t, err)
+	changes, err = p.Changes(ctx, changes.Cursor)
+	require.NoError(t, err)
+	assert.Equal(t, "journal=2", changes.Cursor)
+	assert.Equal(t, []string{"manual.journal.gcp.zalan.do"}, changes.Names)
+
+	changes, err = p.Changes(ctx, changes.Cursor)
+	require.NoError(t, err)
+	assert.Equal(t, "journal=2", changes.Cursor)
+	assert.Empty(t, changes.Names)
+
+	_, err = p.Changes(ctx, "other=3")
+	assert.ErrorIs(t, err, provider.ErrJournalExpired)
+	_, err = p.Changes(ctx, "journal")
+	assert.Error(t, err)
+}
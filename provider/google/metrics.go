@@ -0,0 +1,87 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package google
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	zoneRecords = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "google_provider",
+			Name:      "zone_records",
+			Help:      "Number of records of the zone, at the last Records call.",
+		},
+		[]string{"project", "zone"},
+	)
+	changesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "google_provider",
+			Name:      "changes_total",
+			Help:      "Number of record sets added or deleted by the applied changes, by action (add, delete).",
+		},
+		[]string{"project", "zone", "action"},
+	)
+	changeErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "google_provider",
+			Name:      "change_errors_total",
+			Help:      "Number of batches of changes that failed to apply.",
+		},
+		[]string{"project", "zone"},
+	)
+	zoneApplyFailures = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "google_provider",
+			Name:      "zone_apply_failures",
+			Help:      "Number of consecutive failures to apply the changes of the zone, 0 once applied.",
+		},
+		[]string{"project", "zone"},
+	)
+	zoneRetryTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "google_provider",
+			Name:      "zone_retry_timestamp_seconds",
+			Help:      "Time of the next retry of the changes of a zone that failed to apply, 0 if none.",
+		},
+		[]string{"project", "zone"},
+	)
+	skippedZones = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "google_provider",
+			Name:      "skipped_zones",
+			Help:      "Number of zones matching the filters but skipped, by kind (forwarding, peering).",
+		},
+		[]string{"project", "kind"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(zoneRecords)
+	prometheus.MustRegister(changesTotal)
+	prometheus.MustRegister(changeErrorsTotal)
+	prometheus.MustRegister(zoneApplyFailures)
+	prometheus.MustRegister(zoneRetryTimestamp)
+	prometheus.MustRegister(skippedZones)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package google
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// zoneRetryMinBackoff is the backoff after the first failure to apply
+	// the changes of a zone, doubled after each failure up to
+	// zoneRetryMaxBackoff.
+	zoneRetryMinBackoff = 10 * time.Second
+	zoneRetryMaxBackoff = 10 * time.Minute
+)
+
+// zoneRetry is the retry state of a zone whose changes failed to apply.
+type zoneRetry struct {
+	failures int
+	next     time.Time
+	err      error
+}
+
+// zoneRetries is the retry queue of the zones whose changes failed to apply,
+// like a zone over its quota or without the permission: their changes are
+// skipped until their backoff expires, and the other zones are applied.
+type zoneRetries struct {
+	mu    sync.Mutex
+	zones map[string]*zoneRetry
+	// now returns the current time, replaced by the tests.
+	now func() time.Time
+}
+
+func (r *zoneRetries) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+// backingOff returns the retry of the zone if it is backing off.
+func (r *zoneRetries) backingOff(zone string) (zoneRetry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	retry, ok := r.zones[zone]
+	if !ok || !r.clock().Before(retry.next) {
+		return zoneRetry{}, false
+	}
+	return *retry, true
+}
+
+// failed queues the zone for a retry after its backoff, returned.
+func (r *zoneRetries) failed(project, zone string, err error) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.zones == nil {
+		r.zones = map[string]*zoneRetry{}
+	}
+	retry, ok := r.zones[zone]
+	if !ok {
+		retry = &zoneRetry{}
+		r.zones[zone] = retry
+	}
+	retry.failures++
+	retry.err = err
+	backoff := zoneRetryMinBackoff
+	for i := 1; i < retry.failures && backoff < zoneRetryMaxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, zoneRetryMaxBackoff)
+	retry.next = r.clock().Add(backoff)
+	zoneApplyFailures.WithLabelValues(project, zone).Set(float64(retry.failures))
+	zoneRetryTimestamp.WithLabelValues(project, zone).Set(float64(retry.next.Unix()))
+	return backoff
+}
+
+// succeeded removes the zone from the retry queue.
+func (r *zoneRetries) succeeded(project, zone string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.zones[zone]; !ok {
+		return
+	}
+	delete(r.zones, zone)
+	zoneApplyFailures.WithLabelValues(project, zone).Set(0)
+	zoneRetryTimestamp.WithLabelValues(project, zone).Set(0)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package google
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dns "google.golang.org/api/dns/v1"
+	"google.golang.org/api/googleapi"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/ttlpolicy"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// failingChangesCall is a change failing with err.
+type failingChangesCall struct {
+	err error
+}
+
+func (c *failingChangesCall) Do(opts ...googleapi.CallOption) (*dns.Change, error) {
+	return nil, c.err
+}
+
+// failingChangesClient fails the changes of the zones of failing.
+type failingChangesClient struct {
+	mockChangesClient
+	failing map[string]bool
+}
+
+func (c *failingChangesClient) Create(project string, managedZone string, change *dns.Change) changesCreateCallInterface {
+	if c.failing[managedZone] {
+		return &failingChangesCall{err: &googleapi.Error{Code: http.StatusForbidden, Message: "quota exceeded"}}
+	}
+	return c.mockChangesClient.Create(project, managedZone, change)
+}
+
+func TestGoogleApplyChangesZoneRetry(t *testing.T) {
+	p := newGoogleProvider(t, endpoint.NewDomainFilter([]string{"ext-dns-test-2.gcp.zalan.do."}), provider.NewZoneIDFilter([]string{""}), false, []*endpoint.Endpoint{})
+	client := &failingChangesClient{failing: map[string]bool{"zone-1-ext-dns-test-2-gcp-zalan-do": true}}
+	p.changesClient = client
+	now := time.Now()
+	p.zoneRetries.now = func() time.Time { return now }
+
+	zone1 := endpoint.NewEndpointWithTTL("a.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, ttlpolicy.DefaultTTL, "8.8.8.8")
+	zone2 := endpoint.NewEndpointWithTTL("a.zone-2.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, ttlpolicy.DefaultTTL, "8.8.4.4")
+	ctx := context.Background()
+
+	// The failure of zone-1 doesn't abort zone-2.
+	err := p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{zone1, zone2}})
+	require.ErrorIs(t, err, provider.SoftError)
+	assert.ErrorContains(t, err, "zone zone-1-ext-dns-test-2-gcp-zalan-do: googleapi: Error 403: quota exceeded")
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	validateEndpoints(t, records, []*endpoint.Endpoint{zone2})
+
+	// zone-1 is skipped until its backoff expires, even once it would apply.
+	client.failing = nil
+	err = p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{zone1}})
+	assert.ErrorContains(t, err, "zone zone-1-ext-dns-test-2-gcp-zalan-do: retrying at")
+	records, err = p.Records(ctx)
+	require.NoError(t, err)
+	validateEndpoints(t, records, []*endpoint.Endpoint{zone2})
+
+	now = now.Add(zoneRetryMinBackoff)
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{zone1}}))
+	records, err = p.Records(ctx)
+	require.NoError(t, err)
+	validateEndpoints(t, records, []*endpoint.Endpoint{zone1, zone2})
+	_, ok := p.zoneRetries.backingOff("zone-1-ext-dns-test-2-gcp-zalan-do")
+	assert.False(t, ok)
+}
+
+func TestZoneRetriesBackoff(t *testing.T) {
+	r := &zoneRetries{}
+	var backoffs []time.Duration
+	for i := 0; i < 8; i++ {
+		backoffs = append(backoffs, r.failed("project", "zone", assert.AnError))
+	}
+	assert.Equal(t, []time.Duration{
+		10 * time.Second, 20 * time.Second, 40 * time.Second, 80 * time.Second,
+		160 * time.Second, 320 * time.Second, 10 * time.Minute, 10 * time.Minute,
+	}, backoffs)
+	retry, ok := r.backingOff("zone")
+	require.True(t, ok)
+	assert.Equal(t, 8, retry.failures)
+	assert.Equal(t, assert.AnError, retry.err)
+
+	r.succeeded("project", "zone")
+	_, ok = r.backingOff("zone")
+	assert.False(t, ok)
+	assert.Equal(t, zoneRetryMinBackoff, r.failed("project", "zone", assert.AnError))
+}
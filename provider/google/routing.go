@@ -0,0 +1,316 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package google
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	dns "google.golang.org/api/dns/v1"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+const (
+	// providerSpecificGeoLocation is the region of an endpoint in the geo
+	// routing policy of its record set, like us-central1. Each region of a
+	// name is an endpoint, with the region as set identifier, so the clusters
+	// of several regions own their own items of the record set.
+	providerSpecificGeoLocation = "google/geo-location"
+	// providerSpecificWeight is the weight of an endpoint in the weighted
+	// round robin routing policy of its record set. Each set identifier of a
+	// name is an endpoint, with a weight of 1 by default.
+	providerSpecificWeight = "google/weight"
+
+	defaultWeight = "1"
+)
+
+// routingKey identifies a record set with a routing policy.
+type routingKey struct {
+	name, recordType string
+}
+
+func newRoutingKey(ep *endpoint.Endpoint) routingKey {
+	return routingKey{provider.EnsureTrailingDot(ep.DNSName), ep.RecordType}
+}
+
+func geoLocation(ep *endpoint.Endpoint) (string, bool) {
+	location, ok := ep.GetProviderSpecificProperty(providerSpecificGeoLocation)
+	return location, ok && location != ""
+}
+
+// routed returns whether the endpoint is an item of the routing policy of its
+// record set: with a geo location, a weight or a set identifier other than
+// the one of its view (see endpoint.Endpoint.WithView).
+func routed(ep *endpoint.Endpoint) bool {
+	if _, ok := geoLocation(ep); ok {
+		return true
+	}
+	_, weighted := ep.GetProviderSpecificProperty(providerSpecificWeight)
+	return weighted || (ep.SetIdentifier != "" && ep.SetIdentifier != ep.View())
+}
+
+// itemID identifies the item of an endpoint in the routing policy of its
+// record set: the geo location, or the set identifier.
+func itemID(ep *endpoint.Endpoint) string {
+	if location, ok := geoLocation(ep); ok {
+		return location
+	}
+	return ep.SetIdentifier
+}
+
+// splitRouted returns the endpoints without and with a routing policy.
+func splitRouted(endpoints []*endpoint.Endpoint) (plain, routing []*endpoint.Endpoint) {
+	for _, ep := range endpoints {
+		if routed(ep) {
+			routing = append(routing, ep)
+		} else {
+			plain = append(plain, ep)
+		}
+	}
+	return plain, routing
+}
+
+// hasRoutingPolicy returns whether the record set has a geo or weighted
+// routing policy.
+func hasRoutingPolicy(r *dns.ResourceRecordSet) bool {
+	return r.RoutingPolicy != nil && (r.RoutingPolicy.Geo != nil || r.RoutingPolicy.Wrr != nil)
+}
+
+// adjustRouting sets the set identifier of the endpoints with a geo location
+// to the location, and the weight of the other endpoints with a set
+// identifier, as read from the record sets. The endpoints with an invalid
+// weight are dropped.
+func adjustRouting(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	adjusted := make([]*endpoint.Endpoint, 0, len(endpoints))
+	for _, ep := range endpoints {
+		if location, ok := geoLocation(ep); ok {
+			ep.SetIdentifier = location
+		} else if routed(ep) {
+			weight, ok := ep.GetProviderSpecificProperty(providerSpecificWeight)
+			if !ok {
+				weight = defaultWeight
+			}
+			w, err := strconv.ParseFloat(weight, 64)
+			if err != nil || w < 0 {
+				log.Warnf("Dropping the endpoint %s with the invalid weight %q", ep, weight)
+				continue
+			}
+			ep.SetProviderSpecificProperty(providerSpecificWeight, formatWeight(w))
+		}
+		adjusted = append(adjusted, ep)
+	}
+	return adjusted
+}
+
+func formatWeight(w float64) string {
+	return strconv.FormatFloat(w, 'f', -1, 64)
+}
+
+// routedEndpoints returns an endpoint for each item of the geo or weighted
+// routing policy of the record set of the view. The items of a weighted
+// routing policy have no identifier: they get the set identifiers of the
+// items written by the provider, or their index.
+func (p *GoogleProvider) routedEndpoints(r *dns.ResourceRecordSet, view string) []*endpoint.Endpoint {
+	var endpoints []*endpoint.Endpoint
+	if r.RoutingPolicy.Geo != nil {
+		for _, item := range r.RoutingPolicy.Geo.Items {
+			endpoints = append(endpoints, endpoint.NewEndpointWithTTL(r.Name, r.Type, endpoint.TTL(r.Ttl), item.Rrdatas...).
+				WithSetIdentifier(item.Location).
+				WithProviderSpecific(providerSpecificGeoLocation, item.Location))
+		}
+		return endpoints
+	}
+	sets := p.weightedSetsOf(view, routingKey{provider.EnsureTrailingDot(r.Name), r.Type})
+	for i, item := range r.RoutingPolicy.Wrr.Items {
+		setIdentifier := strconv.Itoa(i)
+		if len(sets) == len(r.RoutingPolicy.Wrr.Items) {
+			setIdentifier = sets[i]
+		}
+		endpoints = append(endpoints, endpoint.NewEndpointWithTTL(r.Name, r.Type, endpoint.TTL(r.Ttl), item.Rrdatas...).
+			WithSetIdentifier(setIdentifier).
+			WithProviderSpecific(providerSpecificWeight, formatWeight(item.Weight)))
+	}
+	return endpoints
+}
+
+// newRoutedRecord returns the record set of the endpoints of a name, one item
+// per endpoint: a geo routing policy if they have a geo location, a weighted
+// routing policy otherwise, with the set identifiers of its items. The TTL is
+// the lowest of the endpoints.
+func newRoutedRecord(endpoints []*endpoint.Endpoint) (*dns.ResourceRecordSet, []string, error) {
+	sort.Slice(endpoints, func(i, j int) bool { return itemID(endpoints[i]) < itemID(endpoints[j]) })
+	var record *dns.ResourceRecordSet
+	geo := &dns.RRSetRoutingPolicyGeoPolicy{}
+	wrr := &dns.RRSetRoutingPolicyWrrPolicy{}
+	var sets []string
+	for _, ep := range endpoints {
+		r := newRecord(ep)
+		if location, ok := geoLocation(ep); ok {
+			geo.Items = append(geo.Items, &dns.RRSetRoutingPolicyGeoPolicyGeoPolicyItem{Location: location, Rrdatas: r.Rrdatas})
+		} else {
+			weight, ok := ep.GetProviderSpecificProperty(providerSpecificWeight)
+			if !ok {
+				weight = defaultWeight
+			}
+			w, err := strconv.ParseFloat(weight, 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid weight %q of %s: %w", weight, ep, err)
+			}
+			wrr.Items = append(wrr.Items, &dns.RRSetRoutingPolicyWrrPolicyWrrPolicyItem{Weight: w, Rrdatas: r.Rrdatas})
+			sets = append(sets, ep.SetIdentifier)
+		}
+		if record == nil || r.Ttl < record.Ttl {
+			record = r
+		}
+	}
+	policy := &dns.RRSetRoutingPolicy{Geo: geo}
+	switch {
+	case len(geo.Items) > 0 && len(wrr.Items) > 0:
+		return nil, nil, fmt.Errorf("the record set %s %s has endpoints with and without a geo location", record.Name, record.Type)
+	case len(wrr.Items) > 0:
+		policy = &dns.RRSetRoutingPolicy{Wrr: wrr}
+	}
+	return &dns.ResourceRecordSet{
+		Name:          record.Name,
+		Type:          record.Type,
+		Ttl:           record.Ttl,
+		RoutingPolicy: policy,
+	}, sets, nil
+}
+
+// routingChange returns the change replacing the record sets of the deleted
+// and added routed endpoints of the view: a record set is replaced as a
+// whole, with the current items of the other locations or set identifiers.
+// It also returns the set identifiers of the items of the weighted record
+// sets, nil for the others.
+func (p *GoogleProvider) routingChange(ctx context.Context, deleted, added []*endpoint.Endpoint, view string) (*dns.Change, map[routingKey][]string, error) {
+	change := &dns.Change{}
+	sets := map[routingKey][]string{}
+	changed := map[routingKey]bool{}
+	for _, ep := range append(append([]*endpoint.Endpoint{}, deleted...), added...) {
+		if p.domainFilter.Match(ep.DNSName) {
+			changed[newRoutingKey(ep)] = true
+		}
+	}
+	if len(changed) == 0 {
+		return change, sets, nil
+	}
+	current, err := p.routedRecords(ctx, changed, view)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys := make([]routingKey, 0, len(changed))
+	for key := range changed {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].name < keys[j].name || keys[i].name == keys[j].name && keys[i].recordType < keys[j].recordType
+	})
+	for _, key := range keys {
+		items := map[string]*endpoint.Endpoint{}
+		if r := current[key]; r != nil {
+			for _, ep := range p.routedEndpoints(r, view) {
+				items[itemID(ep)] = ep
+			}
+			change.Deletions = append(change.Deletions, r)
+		}
+		for _, ep := range deleted {
+			if newRoutingKey(ep) == key {
+				delete(items, itemID(ep))
+			}
+		}
+		for _, ep := range added {
+			if newRoutingKey(ep) == key {
+				items[itemID(ep)] = ep
+			}
+		}
+		sets[key] = nil
+		if len(items) > 0 {
+			next := make([]*endpoint.Endpoint, 0, len(items))
+			for _, ep := range items {
+				next = append(next, ep)
+			}
+			record, recordSets, err := newRoutedRecord(next)
+			if err != nil {
+				return nil, nil, err
+			}
+			change.Additions = append(change.Additions, record)
+			sets[key] = recordSets
+		}
+	}
+	return change, sets, nil
+}
+
+// routedRecords returns the current record sets with a geo or weighted
+// routing policy of the keys in the zones of the view, as listed - a deletion
+// must match the record set.
+func (p *GoogleProvider) routedRecords(ctx context.Context, keys map[routingKey]bool, view string) (map[routingKey]*dns.ResourceRecordSet, error) {
+	zones, err := p.zonesOfView(ctx, view)
+	if err != nil {
+		return nil, err
+	}
+	records := map[routingKey]*dns.ResourceRecordSet{}
+	for zone := range zones {
+		err := p.resourceRecordSetsClient.List(p.GoogleProject, zone).Pages(ctx, func(resp *dns.ResourceRecordSetsListResponse) error {
+			for _, r := range resp.Rrsets {
+				key := routingKey{provider.EnsureTrailingDot(r.Name), r.Type}
+				if keys[key] && hasRoutingPolicy(r) {
+					records[key] = r
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// weightedSetsOf returns the set identifiers of the items of a weighted record
+// set of the view written by the provider.
+func (p *GoogleProvider) weightedSetsOf(view string, key routingKey) []string {
+	p.weightedSetsMu.Lock()
+	defer p.weightedSetsMu.Unlock()
+	return p.weightedSets[view][key]
+}
+
+// setWeightedSets records the set identifiers of the items of the weighted
+// record sets of the view written by the provider.
+func (p *GoogleProvider) setWeightedSets(view string, sets map[routingKey][]string) {
+	p.weightedSetsMu.Lock()
+	defer p.weightedSetsMu.Unlock()
+	if p.weightedSets == nil {
+		p.weightedSets = map[string]map[routingKey][]string{}
+	}
+	if p.weightedSets[view] == nil {
+		p.weightedSets[view] = map[routingKey][]string{}
+	}
+	for key, ids := range sets {
+		if len(ids) == 0 {
+			delete(p.weightedSets[view], key)
+		} else {
+			p.weightedSets[view][key] = ids
+		}
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package google
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	dns "google.golang.org/api/dns/v1"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+func weightedEndpoint(setIdentifier, weight string, targets ...string) *endpoint.Endpoint {
+	return endpoint.NewEndpointWithTTL("wrr.zone-1.ext-dns-test-2.gcp.zalan.do", endpoint.RecordTypeA, 60, targets...).
+		WithSetIdentifier(setIdentifier).
+		WithProviderSpecific(providerSpecificWeight, weight)
+}
+
+func TestGoogleWeightedRoutingPolicy(t *testing.T) {
+	ctx := context.Background()
+	p := newGoogleProvider(t, endpoint.NewDomainFilter([]string{"ext-dns-test-2.gcp.zalan.do."}), provider.NewZoneIDFilter([]string{""}), false, []*endpoint.Endpoint{})
+	zone := zoneKey(p.GoogleProject, "zone-1-ext-dns-test-2-gcp-zalan-do")
+	wrrRecord := func() *dns.ResourceRecordSet {
+		return testRecords[zone][recordKey(endpoint.RecordTypeA, "wrr.zone-1.ext-dns-test-2.gcp.zalan.do.")]
+	}
+
+	// The endpoints of the set identifiers are the items of a record set.
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{
+		weightedEndpoint("green", "90", "10.0.0.2"),
+		weightedEndpoint("blue", "10", "10.0.0.1"),
+	}}))
+	assert.Equal(t, &dns.ResourceRecordSet{
+		Name: "wrr.zone-1.ext-dns-test-2.gcp.zalan.do.",
+		Type: endpoint.RecordTypeA,
+		Ttl:  60,
+		RoutingPolicy: &dns.RRSetRoutingPolicy{Wrr: &dns.RRSetRoutingPolicyWrrPolicy{Items: []*dns.RRSetRoutingPolicyWrrPolicyWrrPolicyItem{
+			{Weight: 10, Rrdatas: []string{"10.0.0.1"}},
+			{Weight: 90, Rrdatas: []string{"10.0.0.2"}},
+		}}},
+	}, wrrRecord())
+
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	validateEndpoints(t, records, []*endpoint.Endpoint{
+		weightedEndpoint("blue", "10", "10.0.0.1"),
+		weightedEndpoint("green", "90", "10.0.0.2"),
+	})
+
+	// The changes of a set identifier keep the other items.
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{weightedEndpoint("blue", "10", "10.0.0.1")},
+		UpdateNew: []*endpoint.Endpoint{weightedEndpoint("blue", "0", "10.0.0.1")},
+		Create:    []*endpoint.Endpoint{weightedEndpoint("aqua", "10", "10.0.0.3")},
+	}))
+	records, err = p.Records(ctx)
+	require.NoError(t, err)
+	validateEndpoints(t, records, []*endpoint.Endpoint{
+		weightedEndpoint("aqua", "10", "10.0.0.3"),
+		weightedEndpoint("blue", "0", "10.0.0.1"),
+		weightedEndpoint("green", "90", "10.0.0.2"),
+	})
+
+	// The set identifiers of the items not written by the provider, like
+	// after a restart, are their index.
+	p.weightedSets = nil
+	records, err = p.Records(ctx)
+	require.NoError(t, err)
+	validateEndpoints(t, records, []*endpoint.Endpoint{
+		weightedEndpoint("0", "10", "10.0.0.3"),
+		weightedEndpoint("1", "0", "10.0.0.1"),
+		weightedEndpoint("2", "90", "10.0.0.2"),
+	})
+
+	// The record set is deleted with its last item.
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Delete: records}))
+	assert.Nil(t, wrrRecord())
+}
+
+func TestGoogleRoutingMixed(t *testing.T) {
+	_, _, err := newRoutedRecord([]*endpoint.Endpoint{
+		geoEndpoint("us-central1", 60, "10.0.0.1"),
+		weightedEndpoint("blue", "10", "10.0.0.2"),
+	})
+	assert.Error(t, err)
+}
+
+func TestAdjustRouting(t *testing.T) {
+	adjusted := adjustRouting([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("plain.example.com", endpoint.RecordTypeA, "10.0.0.1"),
+		endpoint.NewEndpoint("geo.example.com", endpoint.RecordTypeA, "10.0.0.1").
+			WithSetIdentifier("blue").
+			WithProviderSpecific(providerSpecificGeoLocation, "us-central1"),
+		endpoint.NewEndpoint("wrr.example.com", endpoint.RecordTypeA, "10.0.0.1").WithSetIdentifier("blue"),
+		endpoint.NewEndpoint("wrr.example.com", endpoint.RecordTypeA, "10.0.0.2").
+			WithSetIdentifier("green").
+			WithProviderSpecific(providerSpecificWeight, "10.0"),
+		endpoint.NewEndpoint("wrr.example.com", endpoint.RecordTypeA, "10.0.0.3").
+			WithSetIdentifier("red").
+			WithProviderSpecific(providerSpecificWeight, "heavy"),
+	})
+	require.Len(t, adjusted, 4)
+	assert.Empty(t, adjusted[0].ProviderSpecific)
+	assert.Equal(t, "us-central1", adjusted[1].SetIdentifier)
+	weight, _ := adjusted[2].GetProviderSpecificProperty(providerSpecificWeight)
+	assert.Equal(t, "1", weight)
+	weight, _ = adjusted[3].GetProviderSpecificProperty(providerSpecificWeight)
+	assert.Equal(t, "10", weight)
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package google
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// With split views, the view of a listed zone is its visibility, public or
+// private: the records of the private zones are answered to the networks of
+// the zones, instead of those of the public zones of the same domains.
+
+// zoneView returns the view of the zone, empty without split views or for
+// the configured zones.
+func (p *GoogleProvider) zoneView(zone string) string {
+	if !p.GoogleSplitView {
+		return ""
+	}
+	p.zoneNamesMu.Lock()
+	defer p.zoneNamesMu.Unlock()
+	return p.zoneViews[zone]
+}
+
+// zoneViewPrefix prefixes the name of the zone of the endpoints with a
+// endpoint.ZoneLabelKey: their changes are in the view of their zone only.
+const zoneViewPrefix = "zone/"
+
+// zonesOfView returns the zones of the view, all the zones for an empty view.
+func (p *GoogleProvider) zonesOfView(ctx context.Context, view string) (map[string]string, error) {
+	zones, err := p.Zone2Domain(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if name, ok := strings.CutPrefix(view, zoneViewPrefix); ok {
+		domain, ok := zones[name]
+		if !ok {
+			logger().WarnContext(ctx, "Skipping the changes of an unknown zone", "name", name)
+			return map[string]string{}, nil
+		}
+		return map[string]string{name: domain}, nil
+	}
+	if view == "" || !p.GoogleSplitView {
+		return zones, nil
+	}
+	of := map[string]string{}
+	for zone, domain := range zones {
+		if p.zoneView(zone) == view {
+			of[zone] = domain
+		}
+	}
+	return of, nil
+}
+
+// withZoneView sets the view of the zone of a listed endpoint.
+func withZoneView(ep *endpoint.Endpoint, view string) *endpoint.Endpoint {
+	if view == "" {
+		return ep
+	}
+	return ep.WithView(view)
+}
+
+// AdjustEndpoints answers the endpoints without a view in each view with the
+// most specific zone of their name, with split views: a private subdomain of
+// a public domain is only answered in the private view. The set identifiers and weights of
+// the routed endpoints are set as read (see adjustRouting).
+func (p *GoogleProvider) AdjustEndpoints(endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	endpoints = adjustRouting(endpoints)
+	if !p.GoogleSplitView {
+		return endpoints, nil
+	}
+	zones, err := p.Zone2Domain(p.ctx)
+	if err != nil {
+		return nil, err
+	}
+	views := map[string]provider.ZoneIDName{}
+	for zone, domain := range zones {
+		view := p.zoneView(zone)
+		if view == "" {
+			continue
+		}
+		if views[view] == nil {
+			views[view] = provider.ZoneIDName{}
+		}
+		views[view].Add(zone, domain)
+	}
+	names := make([]string, 0, len(views))
+	for view := range views {
+		names = append(names, view)
+	}
+	sort.Strings(names)
+
+	adjusted := make([]*endpoint.Endpoint, 0, len(endpoints))
+	for _, ep := range endpoints {
+		if ep.View() != "" {
+			adjusted = append(adjusted, ep)
+			continue
+		}
+		var split []*endpoint.Endpoint
+		longest := 0
+		for _, view := range names {
+			zone, domain := views[view].FindZone(provider.EnsureTrailingDot(ep.DNSName))
+			switch {
+			case zone == "" || len(domain) < longest:
+				continue
+			case len(domain) > longest:
+				split = split[:0]
+				longest = len(domain)
+			}
+			split = append(split, ep.DeepCopy().WithView(view))
+		}
+		if len(split) == 0 {
+			split = append(split, ep)
+		}
+		adjusted = append(adjusted, split...)
+	}
+	return adjusted, nil
+}
+
+// viewChanges returns the changes of each view, and of each zone of the
+// endpoints with a endpoint.ZoneLabelKey.
+func viewChanges(changes *plan.Changes) map[string]*plan.Changes {
+	views := map[string]*plan.Changes{}
+	of := func(ep *endpoint.Endpoint) *plan.Changes {
+		view := ep.View()
+		if zone := ep.Labels[endpoint.ZoneLabelKey]; zone != "" {
+			view = zoneViewPrefix + zone
+		}
+		c := views[view]
+		if c == nil {
+			c = &plan.Changes{}
+			views[view] = c
+		}
+		return c
+	}
+	for _, ep := range changes.Create {
+		c := of(ep)
+		c.Create = append(c.Create, ep)
+	}
+	for _, ep := range changes.UpdateOld {
+		c := of(ep)
+		c.UpdateOld = append(c.UpdateOld, ep)
+	}
+	for _, ep := range changes.UpdateNew {
+		c := of(ep)
+		c.UpdateNew = append(c.UpdateNew, ep)
+	}
+	for _, ep := range changes.Delete {
+		c := of(ep)
+		c.Delete = append(c.Delete, ep)
+	}
+	if len(views) == 0 {
+		views[""] = changes
+	}
+	return views
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package google
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	dns "google.golang.org/api/dns/v1"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
+	"sigs.k8s.io/external-dns/pkg/ttlpolicy"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+func TestGoogleSplitView(t *testing.T) {
+	ctx := context.Background()
+	domainFilter := endpoint.NewDomainFilter([]string{"view.example.org"})
+	zoneIDFilter := provider.NewZoneIDFilter([]string{""})
+	p := &GoogleProvider{
+		ProviderConfig:           externaldns.ProviderConfig{GoogleProject: "zalando-external-dns-test", GoogleSplitView: true},
+		domainFilter:             &domainFilter,
+		zoneIDFilter:             &zoneIDFilter,
+		resourceRecordSetsClient: &mockResourceRecordSetsClient{},
+		managedZonesClient:       &mockManagedZonesClient{},
+		changesClient:            &mockChangesClient{},
+		ctx:                      ctx,
+	}
+	createZone(t, p, &dns.ManagedZone{Name: "view-public", DnsName: "view.example.org.", Visibility: "public"})
+	createZone(t, p, &dns.ManagedZone{Name: "view-private", DnsName: "view.example.org.", Visibility: "private"})
+	createZone(t, p, &dns.ManagedZone{Name: "internal-view-private", DnsName: "internal.view.example.org.", Visibility: "private"})
+
+	// The endpoints without a view are answered in each view of their name.
+	desired, err := p.AdjustEndpoints([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("app.view.example.org", endpoint.RecordTypeA, "34.1.2.3").WithView(endpoint.ViewPublic),
+		endpoint.NewEndpoint("app.view.example.org", endpoint.RecordTypeA, "10.0.0.1").WithView(endpoint.ViewPrivate),
+		endpoint.NewEndpoint("www.view.example.org", endpoint.RecordTypeCNAME, "app.view.example.org"),
+		endpoint.NewEndpoint("db.internal.view.example.org", endpoint.RecordTypeA, "10.0.1.1"),
+	})
+	require.NoError(t, err)
+	expected := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("app.view.example.org", endpoint.RecordTypeA, "34.1.2.3").WithView(endpoint.ViewPublic),
+		endpoint.NewEndpoint("app.view.example.org", endpoint.RecordTypeA, "10.0.0.1").WithView(endpoint.ViewPrivate),
+		endpoint.NewEndpoint("www.view.example.org", endpoint.RecordTypeCNAME, "app.view.example.org").WithView(endpoint.ViewPrivate),
+		endpoint.NewEndpoint("www.view.example.org", endpoint.RecordTypeCNAME, "app.view.example.org").WithView(endpoint.ViewPublic),
+		endpoint.NewEndpoint("db.internal.view.example.org", endpoint.RecordTypeA, "10.0.1.1").WithView(endpoint.ViewPrivate),
+	}
+	validateEndpoints(t, desired, expected)
+
+	// The endpoints of a view are in the zones of the view.
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Create: desired}))
+	assert.Equal(t, []string{"34.1.2.3"}, testRecords[zoneKey(p.GoogleProject, "view-public")][recordKey(endpoint.RecordTypeA, "app.view.example.org.")].Rrdatas)
+	assert.Equal(t, []string{"10.0.0.1"}, testRecords[zoneKey(p.GoogleProject, "view-private")][recordKey(endpoint.RecordTypeA, "app.view.example.org.")].Rrdatas)
+	assert.Nil(t, testRecords[zoneKey(p.GoogleProject, "view-public")][recordKey(endpoint.RecordTypeA, "db.internal.view.example.org.")])
+
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	for _, ep := range expected {
+		ep.RecordTTL = ttlpolicy.DefaultTTL
+	}
+	validateEndpoints(t, records, expected)
+}
+
+func TestGoogleZoneLabel(t *testing.T) {
+	ctx := context.Background()
+	domainFilter := endpoint.NewDomainFilter([]string{"label.example.org"})
+	zoneIDFilter := provider.NewZoneIDFilter([]string{""})
+	p := &GoogleProvider{
+		ProviderConfig:           externaldns.ProviderConfig{GoogleProject: "zalando-external-dns-test"},
+		domainFilter:             &domainFilter,
+		zoneIDFilter:             &zoneIDFilter,
+		resourceRecordSetsClient: &mockResourceRecordSetsClient{},
+		managedZonesClient:       &mockManagedZonesClient{},
+		changesClient:            &mockChangesClient{},
+		ctx:                      ctx,
+	}
+	createZone(t, p, &dns.ManagedZone{Name: "label-public", DnsName: "label.example.org.", Visibility: "public"})
+	createZone(t, p, &dns.ManagedZone{Name: "label-internal", DnsName: "label.example.org.", Visibility: "private"})
+
+	internal := endpoint.NewEndpoint("app.label.example.org", endpoint.RecordTypeA, "10.0.0.1")
+	internal.Labels[endpoint.ZoneLabelKey] = "label-internal"
+	unknown := endpoint.NewEndpoint("db.label.example.org", endpoint.RecordTypeA, "10.0.0.2")
+	unknown.Labels[endpoint.ZoneLabelKey] = "label-unknown"
+
+	// The endpoints of a zone are only in their zone, the ones of an unknown
+	// zone are skipped.
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{internal, unknown}}))
+	assert.Equal(t, []string{"10.0.0.1"}, testRecords[zoneKey(p.GoogleProject, "label-internal")][recordKey(endpoint.RecordTypeA, "app.label.example.org.")].Rrdatas)
+	assert.Nil(t, testRecords[zoneKey(p.GoogleProject, "label-public")][recordKey(endpoint.RecordTypeA, "app.label.example.org.")])
+	assert.Nil(t, testRecords[zoneKey(p.GoogleProject, "label-public")][recordKey(endpoint.RecordTypeA, "db.label.example.org.")])
+	assert.Nil(t, testRecords[zoneKey(p.GoogleProject, "label-internal")][recordKey(endpoint.RecordTypeA, "db.label.example.org.")])
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package google
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	dns "google.golang.org/api/dns/v1"
+
+	"sigs.k8s.io/external-dns/pkg/logging"
+)
+
+// The policies of the forwarding and peering zones matching the filters,
+// GoogleForwardingZonePolicy.
+const (
+	// ForwardingZoneSkip skips the forwarding and peering zones.
+	ForwardingZoneSkip = "skip"
+	// ForwardingZoneError fails the listing of the zones.
+	ForwardingZoneError = "error"
+	// ForwardingZoneManage manages the forwarding zones like the other zones,
+	// and skips the peering zones, which can't have records. It is the default.
+	ForwardingZoneManage = "manage"
+)
+
+// The kinds of the zones whose queries are answered by other name servers.
+const (
+	zoneKindForwarding = "forwarding"
+	zoneKindPeering    = "peering"
+)
+
+// ErrForwardingZone is returned with ForwardingZoneError when forwarding or
+// peering zones match the filters.
+var ErrForwardingZone = errors.New("forwarding or peering zones match the filters")
+
+// SkippedZone is a zone matching the filters but not managed.
+type SkippedZone struct {
+	DNSName string `json:"dnsName"`
+	// Kind is forwarding or peering.
+	Kind string `json:"kind"`
+}
+
+// zoneKind returns the kind of a forwarding or peering zone, or "" for a zone
+// answering its records.
+func zoneKind(zone *dns.ManagedZone) string {
+	switch {
+	case zone.PeeringConfig != nil:
+		return zoneKindPeering
+	case zone.ForwardingConfig != nil:
+		return zoneKindForwarding
+	}
+	return ""
+}
+
+// forwardingZones applies the GoogleForwardingZonePolicy to the forwarding
+// and peering zones matching the filters, by name. It removes the skipped
+// zones from the matched zones, and records them for DebugState.
+func (p *GoogleProvider) forwardingZones(matched map[string]*dns.ManagedZone) error {
+	policy := p.GoogleForwardingZonePolicy
+	if policy == "" {
+		policy = ForwardingZoneManage
+	}
+	skipped := map[string]SkippedZone{}
+	var names []string
+	for name, zone := range matched {
+		kind := zoneKind(zone)
+		if kind == "" || (kind == zoneKindForwarding && policy == ForwardingZoneManage) {
+			continue
+		}
+		skipped[name] = SkippedZone{DNSName: zone.DnsName, Kind: kind}
+		names = append(names, fmt.Sprintf("%s (%s %s)", name, kind, zone.DnsName))
+		delete(matched, name)
+	}
+
+	counts := map[string]int{}
+	for _, zone := range skipped {
+		counts[zone.Kind]++
+	}
+	for _, kind := range []string{zoneKindForwarding, zoneKindPeering} {
+		skippedZones.WithLabelValues(p.GoogleProject, kind).Set(float64(counts[kind]))
+	}
+
+	p.zoneNamesMu.Lock()
+	previous := p.skippedZones
+	p.skippedZones = skipped
+	p.zoneNamesMu.Unlock()
+
+	if policy == ForwardingZoneError && len(names) > 0 {
+		sort.Strings(names)
+		return fmt.Errorf("%w: %s", ErrForwardingZone, strings.Join(names, ", "))
+	}
+	for name, zone := range skipped {
+		if _, ok := previous[name]; !ok {
+			logger().Warn("Skipped zone answered by other name servers", logging.ZoneKey, zone.DNSName, "name", name, "kind", zone.Kind, "policy", policy)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package google
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dns "google.golang.org/api/dns/v1"
+
+	"sigs.k8s.io/external-dns/pkg/apis/externaldns"
+)
+
+// forwardingTestZones are a zone answering its records, a forwarding zone and
+// a peering zone.
+func forwardingTestZones() map[string]*dns.ManagedZone {
+	return map[string]*dns.ManagedZone{
+		"zone-1": {Name: "zone-1", DnsName: "zone-1.example.com."},
+		"forwarding-1": {
+			Name:             "forwarding-1",
+			DnsName:          "corp.example.com.",
+			ForwardingConfig: &dns.ManagedZoneForwardingConfig{},
+		},
+		"peering-1": {
+			Name:          "peering-1",
+			DnsName:       "shared.example.com.",
+			PeeringConfig: &dns.ManagedZonePeeringConfig{},
+		},
+	}
+}
+
+func TestForwardingZones(t *testing.T) {
+	for _, tc := range []struct {
+		policy  string
+		managed []string
+		skipped map[string]SkippedZone
+	}{
+		{
+			policy:  "",
+			managed: []string{"forwarding-1", "zone-1"},
+			skipped: map[string]SkippedZone{"peering-1": {DNSName: "shared.example.com.", Kind: "peering"}},
+		},
+		{
+			policy:  ForwardingZoneManage,
+			managed: []string{"forwarding-1", "zone-1"},
+			skipped: map[string]SkippedZone{"peering-1": {DNSName: "shared.example.com.", Kind: "peering"}},
+		},
+		{
+			policy:  ForwardingZoneSkip,
+			managed: []string{"zone-1"},
+			skipped: map[string]SkippedZone{
+				"forwarding-1": {DNSName: "corp.example.com.", Kind: "forwarding"},
+				"peering-1":    {DNSName: "shared.example.com.", Kind: "peering"},
+			},
+		},
+	} {
+		t.Run(tc.policy, func(t *testing.T) {
+			p := &GoogleProvider{ProviderConfig: externaldns.ProviderConfig{GoogleProject: "project", GoogleForwardingZonePolicy: tc.policy}}
+			zones := forwardingTestZones()
+			require.NoError(t, p.forwardingZones(zones))
+
+			var managed []string
+			for name := range zones {
+				managed = append(managed, name)
+			}
+			assert.ElementsMatch(t, tc.managed, managed)
+			assert.Equal(t, tc.skipped, p.DebugState().SkippedZones)
+		})
+	}
+}
+
+func TestForwardingZonesError(t *testing.T) {
+	p := &GoogleProvider{ProviderConfig: externaldns.ProviderConfig{GoogleProject: "project", GoogleForwardingZonePolicy: ForwardingZoneError}}
+	err := p.forwardingZones(forwardingTestZones())
+	require.ErrorIs(t, err, ErrForwardingZone)
+	assert.Contains(t, err.Error(), "forwarding-1 (forwarding corp.example.com.), peering-1 (peering shared.example.com.)")
+
+	// Without forwarding or peering zones, the zones are listed.
+	zones := map[string]*dns.ManagedZone{"zone-1": {Name: "zone-1", DnsName: "zone-1.example.com."}}
+	require.NoError(t, p.forwardingZones(zones))
+	assert.Len(t, zones, 1)
+	assert.Empty(t, p.DebugState().SkippedZones)
+}
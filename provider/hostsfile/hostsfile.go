@@ -0,0 +1,303 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package hostsfile writes the A and AAAA records to a file in the /etc/hosts
+// format, also read by the hosts plugin of CoreDNS - for NodeLocal DNSCache
+// overrides, or edge nodes without a DNS server.
+//
+// The records are written between two marker lines; the other lines of the
+// file are kept, so the provider can share /etc/hosts with the entries of the
+// system:
+//
+//	127.0.0.1	localhost
+//	# BEGIN external-dns
+//	10.0.0.1	web.example.com
+//	# END external-dns
+package hostsfile
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+const (
+	beginMarker = "# BEGIN external-dns"
+	endMarker   = "# END external-dns"
+)
+
+// HostsFileProvider is an implementation of Provider writing the records to a
+// hosts file.
+type HostsFileProvider struct {
+	provider.BaseProvider
+	cfg HostsFileConfig
+
+	// mu serializes the updates of the file.
+	mu sync.Mutex
+}
+
+// HostsFileConfig is used for configuring a HostsFileProvider.
+type HostsFileConfig struct {
+	// The path of the hosts file, created if missing.
+	Path string
+	// A filter to apply when looking up and applying records.
+	DomainFilter endpoint.DomainFilter
+	// Do nothing and log what would have changed.
+	DryRun bool
+}
+
+// NewHostsFileProvider initializes a new hosts file based Provider.
+func NewHostsFileProvider(cfg HostsFileConfig) (*HostsFileProvider, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("no hosts file path specified")
+	}
+	return &HostsFileProvider{cfg: cfg}, nil
+}
+
+// GetDomainFilter returns the domain filter of the provider.
+func (p *HostsFileProvider) GetDomainFilter() endpoint.DomainFilter {
+	return p.cfg.DomainFilter
+}
+
+// AdjustEndpoints drops the TTL, not written to the file.
+func (p *HostsFileProvider) AdjustEndpoints(endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	for _, ep := range endpoints {
+		ep.RecordTTL = 0
+	}
+	return endpoints, nil
+}
+
+// Records implements Provider, returning the records between the markers.
+func (p *HostsFileProvider) Records(context.Context) ([]*endpoint.Endpoint, error) {
+	f, err := p.read()
+	if err != nil {
+		return nil, err
+	}
+	var endpoints []*endpoint.Endpoint
+	for _, ep := range f.records {
+		if p.cfg.DomainFilter.Match(ep.DNSName) {
+			endpoints = append(endpoints, ep)
+		}
+	}
+	return endpoints, nil
+}
+
+// ApplyChanges implements Provider, replacing the records between the markers
+// and renaming the new file over the old one.
+func (p *HostsFileProvider) ApplyChanges(_ context.Context, changes *plan.Changes) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	f, err := p.read()
+	if err != nil {
+		return err
+	}
+	records := map[recordKey]*endpoint.Endpoint{}
+	for _, ep := range f.records {
+		records[keyOf(ep)] = ep
+	}
+	for _, eps := range [][]*endpoint.Endpoint{changes.Delete, changes.UpdateOld} {
+		for _, ep := range eps {
+			delete(records, keyOf(ep))
+		}
+	}
+	for _, eps := range [][]*endpoint.Endpoint{changes.UpdateNew, changes.Create} {
+		for _, ep := range eps {
+			if err := validate(ep); err != nil {
+				log.Warnf("Skipping %s %s: %v", ep.DNSName, ep.RecordType, err)
+				continue
+			}
+			records[keyOf(ep)] = ep
+		}
+	}
+	f.records = f.records[:0]
+	for _, ep := range records {
+		f.records = append(f.records, ep)
+	}
+
+	data := f.render()
+	if p.cfg.DryRun {
+		log.Infof("Would write %s:\n%s", p.cfg.Path, data)
+		return nil
+	}
+	if err := writeFile(p.cfg.Path, data); err != nil {
+		return err
+	}
+	log.Infof("Wrote %d records to %s", len(f.records), p.cfg.Path)
+	return nil
+}
+
+// validate returns an error if the endpoint can't be written to the file.
+func validate(ep *endpoint.Endpoint) error {
+	if ep.RecordType != endpoint.RecordTypeA && ep.RecordType != endpoint.RecordTypeAAAA {
+		return errors.New("unsupported record type")
+	}
+	if strings.ContainsAny(ep.DNSName, " \t#") {
+		return errors.New("invalid name")
+	}
+	for _, target := range ep.Targets {
+		addr, err := netip.ParseAddr(target)
+		if err != nil || addr.Is6() != (ep.RecordType == endpoint.RecordTypeAAAA) {
+			return fmt.Errorf("invalid target %s", target)
+		}
+	}
+	return nil
+}
+
+type recordKey struct {
+	name, recordType string
+}
+
+func keyOf(ep *endpoint.Endpoint) recordKey {
+	return recordKey{ep.DNSName, ep.RecordType}
+}
+
+// hostsFile is a parsed hosts file.
+type hostsFile struct {
+	// before and after are the lines outside the markers.
+	before, after []string
+	records       []*endpoint.Endpoint
+}
+
+func (p *HostsFileProvider) read() (*hostsFile, error) {
+	data, err := os.ReadFile(p.cfg.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &hostsFile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parse(data)
+}
+
+func parse(data []byte) (*hostsFile, error) {
+	f := &hostsFile{}
+	records := map[recordKey]*endpoint.Endpoint{}
+	// 0 before the block, 1 in the block, 2 after it.
+	section := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case section == 0 && strings.TrimSpace(line) == beginMarker:
+			section = 1
+		case section == 1 && strings.TrimSpace(line) == endMarker:
+			section = 2
+		case section == 0:
+			f.before = append(f.before, line)
+		case section == 2:
+			f.after = append(f.after, line)
+		default:
+			fields := strings.Fields(line)
+			if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+			addr, err := netip.ParseAddr(fields[0])
+			if err != nil || len(fields) < 2 {
+				return nil, fmt.Errorf("invalid line in the external-dns block: %q", line)
+			}
+			recordType := endpoint.RecordTypeA
+			if addr.Is6() {
+				recordType = endpoint.RecordTypeAAAA
+			}
+			for _, name := range fields[1:] {
+				k := recordKey{name, recordType}
+				if records[k] == nil {
+					records[k] = endpoint.NewEndpoint(name, recordType)
+					f.records = append(f.records, records[k])
+				}
+				records[k].Targets = append(records[k].Targets, addr.String())
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if section == 1 {
+		return nil, fmt.Errorf("missing %q line", endMarker)
+	}
+	return f, nil
+}
+
+// render returns the file, with a line per record target sorted by name.
+func (f *hostsFile) render() []byte {
+	sort.Slice(f.records, func(i, j int) bool {
+		if f.records[i].DNSName != f.records[j].DNSName {
+			return f.records[i].DNSName < f.records[j].DNSName
+		}
+		return f.records[i].RecordType < f.records[j].RecordType
+	})
+	var b bytes.Buffer
+	for _, line := range f.before {
+		fmt.Fprintln(&b, line)
+	}
+	fmt.Fprintln(&b, beginMarker)
+	for _, ep := range f.records {
+		targets := append([]string(nil), ep.Targets...)
+		sort.Strings(targets)
+		for _, target := range targets {
+			fmt.Fprintf(&b, "%s\t%s\n", target, ep.DNSName)
+		}
+	}
+	fmt.Fprintln(&b, endMarker)
+	for _, line := range f.after {
+		fmt.Fprintln(&b, line)
+	}
+	return b.Bytes()
+}
+
+// writeFile replaces the file atomically, keeping its mode: readers like the
+// hosts plugin of CoreDNS never see a partial file.
+func writeFile(path string, data []byte) error {
+	mode := fs.FileMode(0o644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hostsfile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+const systemHosts = `127.0.0.1	localhost
+::1	localhost ip6-localhost
+`
+
+func newTestProvider(t *testing.T, content string, dryRun bool) (*HostsFileProvider, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hosts")
+	if content != "" {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	p, err := NewHostsFileProvider(HostsFileConfig{
+		Path:         path,
+		DomainFilter: endpoint.NewDomainFilter([]string{"example.com"}),
+		DryRun:       dryRun,
+	})
+	require.NoError(t, err)
+	return p, path
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestNewHostsFileProvider(t *testing.T) {
+	_, err := NewHostsFileProvider(HostsFileConfig{})
+	assert.Error(t, err)
+}
+
+func TestHostsFileApplyChanges(t *testing.T) {
+	p, path := newTestProvider(t, systemHosts, false)
+	ctx := context.Background()
+
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	web := endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeA, "10.0.0.2", "10.0.0.1")
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{
+		web,
+		endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeAAAA, "2001:db8::1"),
+		endpoint.NewEndpoint("api.example.com", endpoint.RecordTypeA, "10.0.0.3"),
+		// Skipped: unsupported record type, invalid target.
+		endpoint.NewEndpoint("alias.example.com", endpoint.RecordTypeCNAME, "web.example.com"),
+		endpoint.NewEndpoint("bad.example.com", endpoint.RecordTypeA, "2001:db8::2"),
+	}}))
+	assert.Equal(t, systemHosts+`# BEGIN external-dns
+10.0.0.3	api.example.com
+10.0.0.1	web.example.com
+10.0.0.2	web.example.com
+2001:db8::1	web.example.com
+# END external-dns
+`, readFile(t, path))
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+
+	records, err = p.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "api.example.com", records[0].DNSName)
+	assert.True(t, records[1].Targets.Same(web.Targets))
+
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{web},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeA, "10.0.0.4")},
+		Delete:    []*endpoint.Endpoint{endpoint.NewEndpoint("api.example.com", endpoint.RecordTypeA, "10.0.0.3")},
+	}))
+	assert.Equal(t, systemHosts+`# BEGIN external-dns
+10.0.0.4	web.example.com
+2001:db8::1	web.example.com
+# END external-dns
+`, readFile(t, path))
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files are removed")
+}
+
+func TestHostsFileKeepsOtherLines(t *testing.T) {
+	content := systemHosts + `# BEGIN external-dns
+10.0.0.9	other.example.org
+10.0.0.1	web.example.com
+# END external-dns
+192.168.0.1	router
+`
+	p, path := newTestProvider(t, content, false)
+	ctx := context.Background()
+
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "web.example.com", records[0].DNSName)
+
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{}))
+	assert.Equal(t, content, readFile(t, path))
+}
+
+func TestHostsFileMissing(t *testing.T) {
+	p, path := newTestProvider(t, "", false)
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeA, "10.0.0.1"),
+	}}))
+	assert.Equal(t, "# BEGIN external-dns\n10.0.0.1\tweb.example.com\n# END external-dns\n", readFile(t, path))
+}
+
+func TestHostsFileInvalid(t *testing.T) {
+	for _, content := range []string{
+		"# BEGIN external-dns\n10.0.0.1\tweb.example.com\n",
+		"# BEGIN external-dns\nweb.example.com\n# END external-dns\n",
+	} {
+		p, _ := newTestProvider(t, content, false)
+		_, err := p.Records(context.Background())
+		assert.Error(t, err, content)
+	}
+}
+
+func TestHostsFileDryRun(t *testing.T) {
+	p, path := newTestProvider(t, systemHosts, true)
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeA, "10.0.0.1"),
+	}}))
+	assert.Equal(t, systemHosts, readFile(t, path))
+}
+
+func TestHostsFileAdjustEndpoints(t *testing.T) {
+	p, _ := newTestProvider(t, "", false)
+	eps, err := p.AdjustEndpoints([]*endpoint.Endpoint{endpoint.NewEndpointWithTTL("web.example.com", endpoint.RecordTypeA, 60, "10.0.0.1")})
+	require.NoError(t, err)
+	assert.False(t, eps[0].RecordTTL.IsConfigured())
+}
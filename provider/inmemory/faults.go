@@ -0,0 +1,297 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/gitops"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+var (
+	// ErrInjected is the error of the injected failures.
+	ErrInjected = errors.New("injected failure")
+	// ErrPartialBatch is returned when only a part of the changes was applied.
+	ErrPartialBatch = errors.New("injected partial batch failure")
+	// ErrBatchTooLarge is returned for batches with more changes than MaxBatchSize.
+	ErrBatchTooLarge = errors.New("batch too large")
+	// ErrQuotaExceeded is returned for the calls over the quota.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+const (
+	// MethodRecords and MethodApplyChanges are the methods of the recorded calls.
+	MethodRecords      = "Records"
+	MethodApplyChanges = "ApplyChanges"
+)
+
+// FaultConfig configures the faults injected by FaultyProvider. The zero value
+// injects no faults.
+type FaultConfig struct {
+	// Latency is added to each call, plus a random duration up to Jitter.
+	Latency time.Duration `json:"latency,omitempty"`
+	Jitter  time.Duration `json:"jitter,omitempty"`
+
+	// RecordsErrorRate is the probability, from 0 to 1, of a failed Records call.
+	RecordsErrorRate float64 `json:"recordsErrorRate,omitempty"`
+	// ApplyErrorRate is the probability of an ApplyChanges call failing without
+	// applying any change.
+	ApplyErrorRate float64 `json:"applyErrorRate,omitempty"`
+	// PartialBatchRate is the probability of an ApplyChanges call applying the
+	// first half of the changes of each kind, then failing with ErrPartialBatch.
+	PartialBatchRate float64 `json:"partialBatchRate,omitempty"`
+
+	// MaxBatchSize fails the ApplyChanges calls with more changes, like the
+	// providers limiting the size of a change batch.
+	MaxBatchSize int `json:"maxBatchSize,omitempty"`
+	// Quota is the number of calls allowed in each QuotaWindow (a minute by
+	// default); the other calls fail with ErrQuotaExceeded.
+	Quota       int           `json:"quota,omitempty"`
+	QuotaWindow time.Duration `json:"quotaWindow,omitempty"`
+
+	// Seed makes the random faults reproducible; 0 uses the current time.
+	Seed int64 `json:"seed,omitempty"`
+
+	// MaxCalls is the number of recorded calls kept, the last ones; 0 keeps
+	// all of them.
+	MaxCalls int `json:"maxCalls,omitempty"`
+}
+
+// Call is a call of FaultyProvider.
+type Call struct {
+	Method string
+	// Changes are the changes of ApplyChanges.
+	Changes *plan.Changes
+	// Applied are the changes actually applied, a part of Changes after a
+	// partial batch failure.
+	Applied *plan.Changes
+	Err     error
+	Time    time.Time
+}
+
+// FaultyProvider wraps an InMemoryProvider, injecting latency, errors, partial
+// batch failures and quota errors, and records the calls - to test the retries
+// and batching of the controller, or the webhook server, against a realistic
+// provider. It can also wrap a real provider, see NewFaultInjector.
+type FaultyProvider struct {
+	provider.Provider
+	// InMemoryProvider is the provider of NewFaultyProvider, nil with
+	// NewFaultInjector.
+	InMemoryProvider *InMemoryProvider
+	config           FaultConfig
+
+	mu          sync.Mutex
+	rand        *rand.Rand
+	calls       []Call
+	failNext    map[string][]error
+	windowStart time.Time
+	windowCalls int
+
+	// now is the clock of the quota, replaced in tests.
+	now func() time.Time
+}
+
+// NewFaultyProvider returns a provider injecting the faults of config into the
+// calls of im.
+func NewFaultyProvider(im *InMemoryProvider, config FaultConfig) *FaultyProvider {
+	f := NewFaultInjector(im, config)
+	f.InMemoryProvider = im
+	return f
+}
+
+// NewFaultInjector returns a provider injecting the faults of config into the
+// calls of p - like a real provider in a sandbox, to check that the
+// controller converges.
+func NewFaultInjector(p provider.Provider, config FaultConfig) *FaultyProvider {
+	if config.QuotaWindow <= 0 {
+		config.QuotaWindow = time.Minute
+	}
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &FaultyProvider{
+		Provider: p,
+		config:   config,
+		rand:     rand.New(rand.NewSource(seed)),
+		failNext: map[string][]error{},
+		now:      time.Now,
+	}
+}
+
+// FailNext makes the next calls of the method fail with the errors, in order,
+// before the random faults are considered.
+func (f *FaultyProvider) FailNext(method string, errs ...error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failNext[method] = append(f.failNext[method], errs...)
+}
+
+// Calls returns the recorded calls.
+func (f *FaultyProvider) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// Methods returns the methods of the recorded calls, to assert call sequences.
+func (f *FaultyProvider) Methods() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	methods := make([]string, 0, len(f.calls))
+	for _, c := range f.calls {
+		methods = append(methods, c.Method)
+	}
+	return methods
+}
+
+// Reset forgets the recorded calls, the scheduled failures and the quota usage.
+func (f *FaultyProvider) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+	f.failNext = map[string][]error{}
+	f.windowCalls = 0
+}
+
+// Records returns the records of the wrapped provider, or an injected error.
+func (f *FaultyProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	if err := f.delay(ctx); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	call := Call{Method: MethodRecords, Time: f.now()}
+	var records []*endpoint.Endpoint
+	call.Err = f.fault(MethodRecords, f.config.RecordsErrorRate)
+	if call.Err == nil {
+		records, call.Err = f.Provider.Records(ctx)
+	}
+	f.record(call)
+	return records, call.Err
+}
+
+// ApplyChanges applies the changes to the wrapped provider, all or a part of
+// them, or fails with an injected error.
+func (f *FaultyProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	if err := f.delay(ctx); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	call := Call{Method: MethodApplyChanges, Changes: changes, Time: f.now()}
+	defer func() { f.record(call) }()
+
+	if call.Err = f.fault(MethodApplyChanges, f.config.ApplyErrorRate); call.Err != nil {
+		return call.Err
+	}
+	if size := gitops.CountChanges(changes); f.config.MaxBatchSize > 0 && size > f.config.MaxBatchSize {
+		call.Err = fmt.Errorf("%w: %d changes, the maximum is %d", ErrBatchTooLarge, size, f.config.MaxBatchSize)
+		return call.Err
+	}
+	if f.chance(f.config.PartialBatchRate) {
+		call.Applied = firstHalf(changes)
+		if err := f.Provider.ApplyChanges(ctx, call.Applied); err != nil {
+			call.Err = err
+		} else {
+			call.Err = ErrPartialBatch
+		}
+		return call.Err
+	}
+	call.Applied = changes
+	call.Err = f.Provider.ApplyChanges(ctx, changes)
+	return call.Err
+}
+
+// record adds a call, keeping the last MaxCalls. Called with the lock held.
+func (f *FaultyProvider) record(call Call) {
+	f.calls = append(f.calls, call)
+	if f.config.MaxCalls > 0 && len(f.calls) > f.config.MaxCalls {
+		f.calls = f.calls[len(f.calls)-f.config.MaxCalls:]
+	}
+}
+
+// delay waits for the latency of a call.
+func (f *FaultyProvider) delay(ctx context.Context) error {
+	d := f.config.Latency
+	if f.config.Jitter > 0 {
+		f.mu.Lock()
+		d += time.Duration(f.rand.Int63n(int64(f.config.Jitter)))
+		f.mu.Unlock()
+	}
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// fault returns the error of a call: a scheduled failure, the quota, or a
+// random failure with the rate. Called with the lock held.
+func (f *FaultyProvider) fault(method string, rate float64) error {
+	if errs := f.failNext[method]; len(errs) > 0 {
+		f.failNext[method] = errs[1:]
+		return errs[0]
+	}
+	if f.config.Quota > 0 {
+		now := f.now()
+		if now.Sub(f.windowStart) >= f.config.QuotaWindow {
+			f.windowStart, f.windowCalls = now, 0
+		}
+		f.windowCalls++
+		if f.windowCalls > f.config.Quota {
+			return ErrQuotaExceeded
+		}
+	}
+	if f.chance(rate) {
+		return fmt.Errorf("%s: %w", method, ErrInjected)
+	}
+	return nil
+}
+
+func (f *FaultyProvider) chance(rate float64) bool {
+	return rate > 0 && f.rand.Float64() < rate
+}
+
+// firstHalf returns the first half of the changes of each kind, keeping the
+// updates paired.
+func firstHalf(changes *plan.Changes) *plan.Changes {
+	half := func(eps []*endpoint.Endpoint) []*endpoint.Endpoint {
+		return eps[:len(eps)/2]
+	}
+	updates := min(len(changes.UpdateOld), len(changes.UpdateNew)) / 2
+	return &plan.Changes{
+		Create:    half(changes.Create),
+		UpdateOld: changes.UpdateOld[:updates],
+		UpdateNew: changes.UpdateNew[:updates],
+		Delete:    half(changes.Delete),
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	webhookapi "sigs.k8s.io/external-dns/provider/webhook/api"
+)
+
+var _ provider.Provider = &FaultyProvider{}
+
+func newFaultyProvider(config FaultConfig) *FaultyProvider {
+	return NewFaultyProvider(NewInMemoryProvider(InMemoryInitZones([]string{"example.com"})), config)
+}
+
+func createChanges(names ...string) *plan.Changes {
+	changes := &plan.Changes{}
+	for _, name := range names {
+		changes.Create = append(changes.Create, endpoint.NewEndpoint(name+".example.com", endpoint.RecordTypeA, "1.2.3.4"))
+	}
+	return changes
+}
+
+func TestFaultyProviderFailNext(t *testing.T) {
+	ctx := context.Background()
+	p := newFaultyProvider(FaultConfig{})
+	boom := errors.New("boom")
+	p.FailNext(MethodApplyChanges, boom)
+
+	assert.ErrorIs(t, p.ApplyChanges(ctx, createChanges("a")), boom)
+	require.NoError(t, p.ApplyChanges(ctx, createChanges("a")))
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	assert.Equal(t, []string{MethodApplyChanges, MethodApplyChanges, MethodRecords}, p.Methods())
+	calls := p.Calls()
+	assert.Nil(t, calls[0].Applied)
+	assert.Len(t, calls[1].Applied.Create, 1)
+
+	p.Reset()
+	assert.Empty(t, p.Calls())
+}
+
+func TestFaultyProviderPartialBatch(t *testing.T) {
+	ctx := context.Background()
+	p := newFaultyProvider(FaultConfig{PartialBatchRate: 1})
+
+	err := p.ApplyChanges(ctx, createChanges("a", "b", "c", "d"))
+	assert.ErrorIs(t, err, ErrPartialBatch)
+	records, err := p.InMemoryProvider.Records(ctx)
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Len(t, p.Calls()[0].Applied.Create, 2)
+}
+
+func TestFaultyProviderLimits(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	p := newFaultyProvider(FaultConfig{MaxBatchSize: 2, Quota: 2})
+	p.now = func() time.Time { return now }
+
+	assert.ErrorIs(t, p.ApplyChanges(ctx, createChanges("a", "b", "c")), ErrBatchTooLarge)
+	require.NoError(t, p.ApplyChanges(ctx, createChanges("a", "b")))
+	_, err := p.Records(ctx)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	now = now.Add(time.Minute)
+	_, err = p.Records(ctx)
+	assert.NoError(t, err)
+}
+
+func TestFaultyProviderRates(t *testing.T) {
+	ctx := context.Background()
+	p := newFaultyProvider(FaultConfig{RecordsErrorRate: 0.5, Seed: 1})
+	failed := 0
+	for i := 0; i < 100; i++ {
+		if _, err := p.Records(ctx); err != nil {
+			assert.ErrorIs(t, err, ErrInjected)
+			failed++
+		}
+	}
+	assert.InDelta(t, 50, failed, 20)
+
+	p = newFaultyProvider(FaultConfig{ApplyErrorRate: 1})
+	assert.ErrorIs(t, p.ApplyChanges(ctx, createChanges("a")), ErrInjected)
+	records, err := p.Records(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestFaultyProviderLatency(t *testing.T) {
+	p := newFaultyProvider(FaultConfig{Latency: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := p.Records(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Empty(t, p.Calls())
+}
+
+func TestFaultyProviderWebhookServer(t *testing.T) {
+	p := newFaultyProvider(FaultConfig{})
+	server := webhookapi.WebhookServer{Provider: p}
+
+	p.FailNext(MethodRecords, ErrQuotaExceeded)
+	w := httptest.NewRecorder()
+	server.RecordsHandler(w, httptest.NewRequest(http.MethodGet, "/records", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = httptest.NewRecorder()
+	server.RecordsHandler(w, httptest.NewRequest(http.MethodGet, "/records", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{MethodRecords, MethodRecords}, p.Methods())
+}
+
+func TestFaultyProviderMaxCalls(t *testing.T) {
+	ctx := context.Background()
+	p := newFaultyProvider(FaultConfig{MaxCalls: 2})
+	require.NoError(t, p.ApplyChanges(ctx, createChanges("a")))
+	for i := 0; i < 3; i++ {
+		_, err := p.Records(ctx)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{MethodRecords, MethodRecords}, p.Methods())
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inmemory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// InMemoryWithFile persists the zones and the records to a JSON file: they are
+// loaded from the file if it exists, and the file is replaced after each
+// change - so a local development loop survives restarts. If the file can't be
+// loaded, it is not replaced.
+func InMemoryWithFile(path string) InMemoryOption {
+	return func(p *InMemoryProvider) {
+		if err := p.load(path); err != nil {
+			log.Errorf("Unable to load the inmemory provider records from %s, not saving them: %v", path, err)
+			return
+		}
+		p.file = path
+	}
+}
+
+// load adds the zones and the records of the file.
+func (im *InMemoryProvider) load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var cfg InMemoryConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return err
+	}
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	for name, z := range cfg.Zones {
+		if err := im.client.CreateZone(name); err != nil && !errors.Is(err, ErrZoneAlreadyExists) {
+			return err
+		}
+		for _, ep := range z.Records {
+			im.client.zones[name][ep.Key()] = ep
+		}
+	}
+	return nil
+}
+
+// config returns the zones and the records, sorted.
+func (im *InMemoryProvider) config() *InMemoryConfig {
+	cfg := &InMemoryConfig{Zones: map[string]*Zone{}}
+	for name, z := range im.client.zones {
+		records := make([]*endpoint.Endpoint, 0, len(z))
+		for _, ep := range z {
+			records = append(records, ep)
+		}
+		sortEndpoints(records)
+		cfg.Zones[name] = &Zone{Name: name, Domain: name, Records: records}
+	}
+	return cfg
+}
+
+// save replaces the file with the zones and the records.
+func (im *InMemoryProvider) save() error {
+	data, err := json.MarshalIndent(im.config(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(im.file, data); err != nil {
+		return fmt.Errorf("failed to save the records to %s: %w", im.file, err)
+	}
+	return nil
+}
+
+// writeFile replaces the file atomically, so a crash doesn't lose the records.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".inmemory-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// sortEndpoints sorts the endpoints by name, type and set identifier.
+func sortEndpoints(eps []*endpoint.Endpoint) {
+	sort.Slice(eps, func(i, j int) bool {
+		a, b := eps[i], eps[j]
+		if a.DNSName != b.DNSName {
+			return a.DNSName < b.DNSName
+		}
+		if a.RecordType != b.RecordType {
+			return a.RecordType < b.RecordType
+		}
+		return a.SetIdentifier < b.SetIdentifier
+	})
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inmemory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestInMemoryWithFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.json")
+	ctx := context.Background()
+
+	p := NewInMemoryProvider(InMemoryInitZones([]string{"example.com"}), InMemoryWithFile(path))
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "the file is created by the first change")
+
+	web := endpoint.NewEndpointWithTTL("web.example.com", endpoint.RecordTypeA, 60, "10.0.0.1")
+	web.Labels = endpoint.Labels{endpoint.OwnerLabelKey: "default"}
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{
+		web,
+		endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeCNAME, "web.example.com"),
+	}}))
+
+	// The records survive a restart, with the zone.
+	restarted := NewInMemoryProvider(InMemoryWithFile(path))
+	assert.Equal(t, map[string]string{"example.com": "example.com"}, restarted.Zones())
+	records, err := restarted.Records(ctx)
+	require.NoError(t, err)
+	sortEndpoints(records)
+	require.Len(t, records, 2)
+	assert.Equal(t, "web.example.com", records[0].DNSName)
+	assert.Equal(t, endpoint.TTL(60), records[0].RecordTTL)
+	assert.Equal(t, "default", records[0].Labels[endpoint.OwnerLabelKey])
+	assert.Equal(t, "www.example.com", records[1].DNSName)
+
+	require.NoError(t, restarted.ApplyChanges(ctx, &plan.Changes{Delete: []*endpoint.Endpoint{records[1]}}))
+	records, err = NewInMemoryProvider(InMemoryWithFile(path)).Records(ctx)
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files are removed")
+}
+
+func TestInMemoryWithFileInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.json")
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o600))
+
+	// The invalid file is not replaced.
+	p := NewInMemoryProvider(InMemoryInitZones([]string{"example.com"}), InMemoryWithFile(path))
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeA, "10.0.0.1"),
+	}}))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "not json", string(data))
+}
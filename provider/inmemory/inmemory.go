@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -51,19 +52,26 @@ type InMemoryProvider struct {
 	filter         *filter
 	OnApplyChanges func(ctx context.Context, changes *plan.Changes)
 	OnRecords      func()
+
+	// mu guards the client, read by the zone file endpoint while the
+	// controller applies changes.
+	mu sync.RWMutex
+	// file is the file of InMemoryWithFile, saved after each change.
+	file string
 }
 
 // InMemoryConfig is the configuration for the InMemoryProvider as a json or yaml struct.
 // Unlike options, it is better suited for serialization and deserialization and CRDs
 type InMemoryConfig struct {
-	Zones map[string] *Zone
+	Zones map[string]*Zone `json:"zones"`
 }
 
+// Zone is a zone of the InMemoryConfig, with its records.
 type Zone struct {
-	Name string
-	Domain string
+	Name   string `json:"name"`
+	Domain string `json:"domain,omitempty"`
 
-	Records []*endpoint.Endpoint
+	Records []*endpoint.Endpoint `json:"records"`
 }
 
 // InMemoryOption allows to extend in-memory provider
@@ -126,21 +134,31 @@ func NewInMemoryProvider(opts ...InMemoryOption) *InMemoryProvider {
 
 // CreateZone adds new zone if not present
 func (im *InMemoryProvider) CreateZone(newZone string) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
 	return im.client.CreateZone(newZone)
 }
 
 // Zones returns filtered zones as specified by domain
 func (im *InMemoryProvider) Zones() map[string]string {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+	return im.zones()
+}
+
+func (im *InMemoryProvider) zones() map[string]string {
 	return im.filter.Zones(im.client.Zones())
 }
 
 // Records returns the list of endpoints
 func (im *InMemoryProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
 	defer im.OnRecords()
+	im.mu.RLock()
+	defer im.mu.RUnlock()
 
 	endpoints := make([]*endpoint.Endpoint, 0)
 
-	for zoneID := range im.Zones() {
+	for zoneID := range im.zones() {
 		records, err := im.client.Records(zoneID)
 		if err != nil {
 			return nil, err
@@ -159,10 +177,12 @@ func (im *InMemoryProvider) Records(ctx context.Context) ([]*endpoint.Endpoint,
 // create/update/delete lists should not have overlapping records
 func (im *InMemoryProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
 	defer im.OnApplyChanges(ctx, changes)
+	im.mu.Lock()
+	defer im.mu.Unlock()
 
 	perZoneChanges := map[string]*plan.Changes{}
 
-	zones := im.Zones()
+	zones := im.zones()
 	for zoneID := range zones {
 		perZoneChanges[zoneID] = &plan.Changes{}
 	}
@@ -209,6 +229,9 @@ func (im *InMemoryProvider) ApplyChanges(ctx context.Context, changes *plan.Chan
 		}
 	}
 
+	if im.file != "" && changes.HasChanges() {
+		return im.save()
+	}
 	return nil
 }
 
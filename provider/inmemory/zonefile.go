@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inmemory
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// zoneFileTTL is the $TTL of the zone files, for the records without a TTL.
+const zoneFileTTL = 300
+
+// WriteZoneFile writes the zones and their records in the zone file format of
+// RFC 1035, sorted by zone and name:
+//
+//	$ORIGIN example.com.
+//	$TTL 300
+//	web.example.com.	60	IN	A	10.0.0.1
+//	www.example.com.		IN	CNAME	web.example.com.
+func (im *InMemoryProvider) WriteZoneFile(w io.Writer) error {
+	im.mu.RLock()
+	cfg := im.config()
+	zones := im.zones()
+	im.mu.RUnlock()
+
+	names := make([]string, 0, len(zones))
+	for name := range zones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b := bufio.NewWriter(w)
+	for i, name := range names {
+		if i > 0 {
+			fmt.Fprintln(b)
+		}
+		fmt.Fprintf(b, "$ORIGIN %s\n$TTL %d\n", fqdn(name), zoneFileTTL)
+		for _, ep := range cfg.Zones[name].Records {
+			writeRecords(b, ep)
+		}
+	}
+	return b.Flush()
+}
+
+// ServeHTTP serves the zone file, for the inspection of the records.
+func (im *InMemoryProvider) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := im.WriteZoneFile(w); err != nil {
+		log.Errorf("Failed to write the zone file: %v", err)
+	}
+}
+
+// writeRecords writes a line for each target of the endpoint.
+func writeRecords(w io.Writer, ep *endpoint.Endpoint) {
+	ttl := ""
+	if ep.RecordTTL.IsConfigured() {
+		ttl = fmt.Sprint(int64(ep.RecordTTL))
+	}
+	if ep.SetIdentifier != "" {
+		fmt.Fprintf(w, "; set identifier %s\n", ep.SetIdentifier)
+	}
+	for _, target := range ep.Targets {
+		fmt.Fprintf(w, "%s\t%s\tIN\t%s\t%s\n", fqdn(ep.DNSName), ttl, ep.RecordType, zoneFileTarget(ep.RecordType, target))
+	}
+}
+
+// zoneFileTarget returns the target in the zone file format: the TXT targets
+// are quoted, and the names ending the targets are absolute.
+func zoneFileTarget(recordType, target string) string {
+	switch recordType {
+	case endpoint.RecordTypeTXT:
+		if !strings.HasPrefix(target, `"`) {
+			return fmt.Sprintf("%q", target)
+		}
+	case endpoint.RecordTypeCNAME, endpoint.RecordTypeNS, endpoint.RecordTypeMX, endpoint.RecordTypeSRV, endpoint.RecordTypePTR:
+		return fqdn(target)
+	}
+	return target
+}
+
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
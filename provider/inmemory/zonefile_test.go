@@ -0,0 +1,59 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inmemory
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestWriteZoneFile(t *testing.T) {
+	p := NewInMemoryProvider(InMemoryInitZones([]string{"example.com", "example.org"}))
+	weighted := endpoint.NewEndpoint("api.example.org", endpoint.RecordTypeA, "10.0.1.1").WithSetIdentifier("blue")
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{Create: []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("web.example.com", endpoint.RecordTypeA, 60, "10.0.0.1", "10.0.0.2"),
+		endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeCNAME, "web.example.com"),
+		endpoint.NewEndpoint("web.example.com", endpoint.RecordTypeTXT, "heritage=external-dns"),
+		endpoint.NewEndpoint("example.com", endpoint.RecordTypeMX, "10 mail.example.com"),
+		weighted,
+	}}))
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/zones", nil))
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `$ORIGIN example.com.
+$TTL 300
+example.com.		IN	MX	10 mail.example.com.
+web.example.com.	60	IN	A	10.0.0.1
+web.example.com.	60	IN	A	10.0.0.2
+web.example.com.		IN	TXT	"heritage=external-dns"
+www.example.com.		IN	CNAME	web.example.com.
+
+$ORIGIN example.org.
+$TTL 300
+; set identifier blue
+api.example.org.		IN	A	10.0.1.1
+`, w.Body.String())
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrJournalExpired is returned by Journal.Changes when the cursor is too old
+// for the journal - the records have to be listed again.
+var ErrJournalExpired = errors.New("journal cursor expired")
+
+// JournalChanges are the changes of a Journal since a cursor.
+type JournalChanges struct {
+	// Names are the DNS names of the records created, updated or deleted
+	// since the cursor, by someone else than the provider itself.
+	Names []string `json:"names,omitempty"`
+	// Cursor is the position of the journal after the changes, for the next call.
+	Cursor string `json:"cursor"`
+}
+
+// Journal is implemented by the providers which can list the changes made to
+// their records - like the Google Cloud DNS changes - so the controller does
+// not list every record at each sync.
+type Journal interface {
+	// Changes returns the changes made since the cursor, other than the ones
+	// applied by the provider. An empty cursor returns the current position,
+	// without names.
+	Changes(ctx context.Context, cursor string) (*JournalChanges, error)
+}
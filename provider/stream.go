@@ -0,0 +1,49 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// RecordStreamer is implemented by the providers which can return their
+// records page by page, without holding all of them in memory.
+type RecordStreamer interface {
+	// StreamRecords calls fn with each record, in no particular order. An
+	// error returned by fn stops the listing, and is returned.
+	StreamRecords(ctx context.Context, fn func(*endpoint.Endpoint) error) error
+}
+
+// StreamRecords calls fn with each record of the provider - streamed if it
+// is a RecordStreamer, from its Records otherwise.
+func StreamRecords(ctx context.Context, p Provider, fn func(*endpoint.Endpoint) error) error {
+	if s, ok := p.(RecordStreamer); ok {
+		return s.StreamRecords(ctx, fn)
+	}
+	records, err := p.Records(ctx)
+	if err != nil {
+		return err
+	}
+	for _, ep := range records {
+		if err := fn(ep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+type recordsProvider struct {
+	BaseProvider
+	records []*endpoint.Endpoint
+}
+
+func (p *recordsProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	return p.records, nil
+}
+
+func (p *recordsProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	return nil
+}
+
+func TestStreamRecords(t *testing.T) {
+	p := &recordsProvider{records: []*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "1.2.3.5"),
+	}}
+
+	var names []string
+	err := StreamRecords(context.Background(), p, func(ep *endpoint.Endpoint) error {
+		names = append(names, ep.DNSName)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.example.org", "b.example.org"}, names)
+
+	stop := errors.New("stop")
+	names = nil
+	err = StreamRecords(context.Background(), p, func(ep *endpoint.Endpoint) error {
+		names = append(names, ep.DNSName)
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, []string{"a.example.org"}, names)
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/pkg/dnsserver"
+)
+
+// AccessPolicy restricts the clients of the providers served under a prefix,
+// so a shared server can give a team the right to apply the changes of its
+// zones and only read the others.
+type AccessPolicy struct {
+	// Prefix is the URL prefix of the policy, like /prod - / for all the
+	// prefixes without a policy of their own.
+	Prefix string `json:"prefix"`
+	// ReadWrite are the identities of the clients allowed to read and to
+	// change the records, ReadOnly the ones only allowed to read them. The
+	// identities are the URI SANs, like SPIFFE IDs, the DNS SANs and the
+	// common name of the client certificate - * allows any client, including
+	// the cleartext ones.
+	ReadWrite []string `json:"readWrite,omitempty"`
+	ReadOnly  []string `json:"readOnly,omitempty"`
+}
+
+// Validate returns an error if the policy is not valid.
+func (ap *AccessPolicy) Validate() error {
+	if ap.Prefix != "/" && (!strings.HasPrefix(ap.Prefix, "/") || strings.HasSuffix(ap.Prefix, "/")) {
+		return fmt.Errorf("access policy %q: the prefix must be / or start and not end with /", ap.Prefix)
+	}
+	if len(ap.ReadWrite) == 0 && len(ap.ReadOnly) == 0 {
+		return fmt.Errorf("access policy %s: no client is allowed, set readWrite or readOnly", ap.Prefix)
+	}
+	return nil
+}
+
+// matches returns true if the policy applies to the path.
+func (ap *AccessPolicy) matches(path string) bool {
+	return ap.Prefix == "/" || path == ap.Prefix || strings.HasPrefix(path, ap.Prefix+"/")
+}
+
+// Authorizer allows the requests of the clients of the access policies. The
+// requests under a prefix without a policy are allowed.
+type Authorizer struct {
+	// policies are sorted by prefix, longest first.
+	policies []AccessPolicy
+}
+
+// NewAuthorizer returns the authorizer of the policies, nil without policies.
+func NewAuthorizer(policies []AccessPolicy) (*Authorizer, error) {
+	if len(policies) == 0 {
+		return nil, nil
+	}
+	seen := map[string]bool{}
+	for i := range policies {
+		if err := policies[i].Validate(); err != nil {
+			return nil, err
+		}
+		if seen[policies[i].Prefix] {
+			return nil, fmt.Errorf("access policy %s: duplicate prefix", policies[i].Prefix)
+		}
+		seen[policies[i].Prefix] = true
+	}
+	a := &Authorizer{policies: append([]AccessPolicy(nil), policies...)}
+	sort.Slice(a.policies, func(i, j int) bool {
+		return len(a.policies[i].Prefix) > len(a.policies[j].Prefix)
+	})
+	return a, nil
+}
+
+// Authorize returns true if the client of the request is allowed by the
+// policy of its prefix, or writes a 403 and returns false. A nil authorizer
+// allows all the requests.
+func (a *Authorizer) Authorize(w http.ResponseWriter, req *http.Request) bool {
+	if a == nil {
+		return true
+	}
+	policy := a.policyOf(req.URL.Path)
+	if policy == nil {
+		return true
+	}
+	ids := clientIdentities(req)
+	allowed := policy.ReadWrite
+	if isRead(req) {
+		allowed = append(allowed[:len(allowed):len(allowed)], policy.ReadOnly...)
+	}
+	if matchIdentity(allowed, ids) {
+		return true
+	}
+	log.Warnf("Denied %s %s to the client %v by the access policy of %s", req.Method, req.URL.Path, ids, policy.Prefix)
+	http.Error(w, "forbidden", http.StatusForbidden)
+	return false
+}
+
+// Handler returns the handler serving the requests allowed by the authorizer.
+func (a *Authorizer) Handler(h http.Handler) http.Handler {
+	if a == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if a.Authorize(w, req) {
+			h.ServeHTTP(w, req)
+		}
+	})
+}
+
+// policyOf returns the policy of the longest prefix of the path, nil if none.
+func (a *Authorizer) policyOf(path string) *AccessPolicy {
+	for i := range a.policies {
+		if a.policies[i].matches(path) {
+			return &a.policies[i]
+		}
+	}
+	return nil
+}
+
+// isRead returns true if the request does not change the records - the GET
+// requests, the adjustments of the endpoints and the DNS queries.
+func isRead(req *http.Request) bool {
+	switch {
+	case req.Method == http.MethodGet || req.Method == http.MethodHead:
+		return true
+	case strings.HasSuffix(req.URL.Path, "/adjustendpoints"):
+		return true
+	case strings.HasSuffix(req.URL.Path, dnsserver.DoHPath):
+		return true
+	}
+	return false
+}
+
+// clientIdentities returns the identities of the verified client certificate
+// of the request: the URI SANs, the DNS SANs and the common name.
+func clientIdentities(req *http.Request) []string {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+	cert := req.TLS.VerifiedChains[0][0]
+	var ids []string
+	for _, u := range cert.URIs {
+		ids = append(ids, u.String())
+	}
+	ids = append(ids, cert.DNSNames...)
+	if cert.Subject.CommonName != "" {
+		ids = append(ids, cert.Subject.CommonName)
+	}
+	return ids
+}
+
+// matchIdentity returns true if one of the identities is allowed.
+func matchIdentity(allowed, ids []string) bool {
+	for _, a := range allowed {
+		if a == "*" {
+			return true
+		}
+		for _, id := range ids {
+			if a == id {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clientRequest returns a request of the client with the SPIFFE ID, cleartext
+// if empty.
+func clientRequest(method, path, id string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	if id != "" {
+		u, _ := url.Parse(id)
+		cert := &x509.Certificate{URIs: []*url.URL{u}, Subject: pkix.Name{CommonName: "client"}}
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	}
+	return req
+}
+
+func TestAuthorizer(t *testing.T) {
+	teamA := "spiffe://example.com/ns/team-a/sa/external-dns"
+	teamB := "spiffe://example.com/ns/team-b/sa/external-dns"
+	a, err := NewAuthorizer([]AccessPolicy{
+		{Prefix: "/", ReadOnly: []string{"*"}},
+		{Prefix: "/team-a", ReadWrite: []string{teamA}, ReadOnly: []string{teamB}},
+		{Prefix: "/team-b", ReadWrite: []string{teamB}},
+	})
+	require.NoError(t, err)
+	h := a.Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+
+	for _, tc := range []struct {
+		method, path, id string
+		status           int
+	}{
+		{http.MethodGet, "/team-a/records", teamA, http.StatusOK},
+		{http.MethodPost, "/team-a/records", teamA, http.StatusOK},
+		{http.MethodGet, "/team-a/records", teamB, http.StatusOK},
+		{http.MethodPost, "/team-a/adjustendpoints", teamB, http.StatusOK},
+		{http.MethodPost, "/team-a/records", teamB, http.StatusForbidden},
+		{http.MethodPost, "/team-a/cutover", teamB, http.StatusForbidden},
+		{http.MethodGet, "/team-a/records", "", http.StatusForbidden},
+		{http.MethodGet, "/team-b/records", teamA, http.StatusForbidden},
+		{http.MethodPost, "/team-b/records", teamB, http.StatusOK},
+		// The prefixes without a policy of their own use the policy of /.
+		{http.MethodGet, "/team-c/records", "", http.StatusOK},
+		{http.MethodPost, "/team-c/records", teamA, http.StatusForbidden},
+		{http.MethodGet, "/team-ab/records", teamB, http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, clientRequest(tc.method, tc.path, tc.id))
+		assert.Equal(t, tc.status, w.Code, "%s %s by %q", tc.method, tc.path, tc.id)
+	}
+}
+
+func TestAuthorizerCommonName(t *testing.T) {
+	a, err := NewAuthorizer([]AccessPolicy{{Prefix: "/prod", ReadWrite: []string{"client"}}})
+	require.NoError(t, err)
+	assert.True(t, a.Authorize(httptest.NewRecorder(), clientRequest(http.MethodPost, "/prod/records", "spiffe://example.com/x")))
+	// Without a policy of /, the other prefixes are not restricted.
+	assert.True(t, a.Authorize(httptest.NewRecorder(), clientRequest(http.MethodPost, "/dev/records", "")))
+	var none *Authorizer
+	assert.True(t, none.Authorize(httptest.NewRecorder(), clientRequest(http.MethodPost, "/prod/records", "")))
+}
+
+func TestNewAuthorizerInvalid(t *testing.T) {
+	for _, policies := range [][]AccessPolicy{
+		{{Prefix: "prod", ReadOnly: []string{"*"}}},
+		{{Prefix: "/prod/", ReadOnly: []string{"*"}}},
+		{{Prefix: "/prod"}},
+		{{Prefix: "/prod", ReadOnly: []string{"*"}}, {Prefix: "/prod", ReadWrite: []string{"*"}}},
+	} {
+		_, err := NewAuthorizer(policies)
+		assert.Error(t, err, "%v", policies)
+	}
+	a, err := NewAuthorizer(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, a)
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+const (
+	// CertManagerSolverName is the solverName to use in the cert-manager issuer.
+	CertManagerSolverName = "external-dns"
+
+	challengeVersion = "v1alpha1"
+	challengeTTL     = 60
+)
+
+// ChallengeRequest, ChallengeResponse and ChallengePayload match the
+// webhook.acme.cert-manager.io/v1alpha1 types, without depending on cert-manager.
+type ChallengeRequest struct {
+	UID               string          `json:"uid"`
+	Action            string          `json:"action"`
+	Type              string          `json:"type"`
+	DNSName           string          `json:"dnsName"`
+	Key               string          `json:"key"`
+	ResourceNamespace string          `json:"resourceNamespace"`
+	ResolvedFQDN      string          `json:"resolvedFQDN"`
+	ResolvedZone      string          `json:"resolvedZone"`
+	Config            json.RawMessage `json:"config,omitempty"`
+}
+
+type ChallengeStatus struct {
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Code    int32  `json:"code,omitempty"`
+}
+
+type ChallengeResponse struct {
+	UID     string           `json:"uid"`
+	Success bool             `json:"success"`
+	Result  *ChallengeStatus `json:"status,omitempty"`
+}
+
+type ChallengePayload struct {
+	APIVersion string             `json:"apiVersion,omitempty"`
+	Kind       string             `json:"kind,omitempty"`
+	Request    *ChallengeRequest  `json:"request,omitempty"`
+	Response   *ChallengeResponse `json:"response,omitempty"`
+}
+
+// CertManagerSolver implements the cert-manager DNS01 webhook solver API,
+// creating the challenge TXT records with the provider. It is served as an
+// aggregated API - cert-manager posts the challenges to
+// /apis/<groupName>/v1alpha1/external-dns, with groupName from the issuer and
+// the APIService.
+type CertManagerSolver struct {
+	Provider provider.Provider
+
+	prefix string
+	// mu serializes the updates, since several challenges may share the same
+	// name - for example for a domain and its wildcard.
+	mu sync.Mutex
+}
+
+// ServeHTTP handles the API discovery and the challenge requests.
+func (s *CertManagerSolver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, s.prefix+"/apis/"), "/"), "/")
+	if len(parts) < 2 || parts[1] != challengeVersion {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	group := parts[0]
+
+	switch {
+	case len(parts) == 2 && req.Method == http.MethodGet:
+		s.discovery(w, group)
+	case len(parts) == 3 && parts[2] == CertManagerSolverName && req.Method == http.MethodPost:
+		s.challenge(w, req, group)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// discovery returns the APIResourceList, required by the API aggregation.
+func (s *CertManagerSolver) discovery(w http.ResponseWriter, group string) {
+	w.Header().Set(ContentTypeHeader, "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"kind":         "APIResourceList",
+		"apiVersion":   "v1",
+		"groupVersion": group + "/" + challengeVersion,
+		"resources": []map[string]interface{}{{
+			"name":       CertManagerSolverName,
+			"namespaced": false,
+			"kind":       "ChallengePayload",
+			"verbs":      []string{"create"},
+		}},
+	})
+}
+
+func (s *CertManagerSolver) challenge(w http.ResponseWriter, req *http.Request, group string) {
+	var payload ChallengePayload
+	if err := json.NewDecoder(req.Body).Decode(&payload); err != nil || payload.Request == nil {
+		log.Errorf("Failed to decode challenge: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	cr := payload.Request
+
+	var err error
+	switch cr.Action {
+	case "Present":
+		err = s.Present(req.Context(), cr.ResolvedFQDN, cr.Key)
+	case "CleanUp":
+		err = s.CleanUp(req.Context(), cr.ResolvedFQDN, cr.Key)
+	default:
+		err = fmt.Errorf("unsupported action %q", cr.Action)
+	}
+
+	res := &ChallengeResponse{UID: cr.UID, Success: err == nil}
+	if err != nil {
+		log.Errorf("Failed to %s challenge for %s: %v", cr.Action, cr.DNSName, err)
+		res.Result = &ChallengeStatus{Status: "Failure", Message: err.Error(), Reason: "InternalError", Code: http.StatusInternalServerError}
+	} else {
+		log.Infof("%s challenge for %s", cr.Action, cr.DNSName)
+	}
+	w.Header().Set(ContentTypeHeader, "application/json")
+	json.NewEncoder(w).Encode(ChallengePayload{
+		APIVersion: group + "/" + challengeVersion,
+		Kind:       "ChallengePayload",
+		Response:   res,
+	})
+}
+
+// Present adds the key to the TXT record of fqdn, keeping other keys.
+// It is idempotent, as cert-manager may retry.
+func (s *CertManagerSolver) Present(ctx context.Context, fqdn, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name, current, err := s.txtRecord(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		ep := endpoint.NewEndpointWithTTL(name, endpoint.RecordTypeTXT, challengeTTL, key)
+		return s.Provider.ApplyChanges(ctx, &plan.Changes{Create: []*endpoint.Endpoint{ep}})
+	}
+	if hasTarget(current, key) {
+		return nil
+	}
+	updated := current.DeepCopy()
+	updated.Targets = append(updated.Targets, key)
+	return s.Provider.ApplyChanges(ctx, &plan.Changes{UpdateOld: []*endpoint.Endpoint{current}, UpdateNew: []*endpoint.Endpoint{updated}})
+}
+
+// CleanUp removes the key from the TXT record of fqdn, deleting the record if
+// no other keys are left.
+func (s *CertManagerSolver) CleanUp(ctx context.Context, fqdn, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, current, err := s.txtRecord(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	if current == nil || !hasTarget(current, key) {
+		return nil
+	}
+	updated := current.DeepCopy()
+	updated.Targets = nil
+	for _, t := range current.Targets {
+		if strings.Trim(t, "\"") != key {
+			updated.Targets = append(updated.Targets, t)
+		}
+	}
+	if len(updated.Targets) == 0 {
+		return s.Provider.ApplyChanges(ctx, &plan.Changes{Delete: []*endpoint.Endpoint{current}})
+	}
+	return s.Provider.ApplyChanges(ctx, &plan.Changes{UpdateOld: []*endpoint.Endpoint{current}, UpdateNew: []*endpoint.Endpoint{updated}})
+}
+
+// txtRecord returns the name without the trailing dot, and the current TXT
+// record if it exists.
+func (s *CertManagerSolver) txtRecord(ctx context.Context, fqdn string) (string, *endpoint.Endpoint, error) {
+	name := strings.TrimSuffix(fqdn, ".")
+	if name == "" {
+		return "", nil, fmt.Errorf("missing resolvedFQDN")
+	}
+	if !s.Provider.GetDomainFilter().Match(name) {
+		return "", nil, fmt.Errorf("%s is not in the provider domains", name)
+	}
+	records, err := s.Provider.Records(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, r := range records {
+		if r.RecordType == endpoint.RecordTypeTXT && strings.EqualFold(r.DNSName, name) && r.SetIdentifier == "" {
+			return name, r, nil
+		}
+	}
+	return name, nil, nil
+}
+
+func hasTarget(ep *endpoint.Endpoint, key string) bool {
+	for _, t := range ep.Targets {
+		if strings.Trim(t, "\"") == key {
+			return true
+		}
+	}
+	return false
+}
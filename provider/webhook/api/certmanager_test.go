@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+const challengePath = "/apis/acme.example.com/v1alpha1/external-dns"
+
+func postChallenge(t *testing.T, m *http.ServeMux, action, key string) *ChallengeResponse {
+	body, err := json.Marshal(ChallengePayload{
+		APIVersion: "acme.example.com/v1alpha1",
+		Kind:       "ChallengePayload",
+		Request: &ChallengeRequest{
+			UID:          "1",
+			Action:       action,
+			Type:         "dns-01",
+			DNSName:      "example.com",
+			Key:          key,
+			ResolvedFQDN: "_acme-challenge.example.com.",
+			ResolvedZone: "example.com.",
+		},
+	})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodPost, challengePath, bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res ChallengePayload
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+	require.NotNil(t, res.Response)
+	assert.Equal(t, "1", res.Response.UID)
+	return res.Response
+}
+
+func challengeTargets(t *testing.T, p *inmemory.InMemoryProvider) endpoint.Targets {
+	records, err := p.Records(context.Background())
+	require.NoError(t, err)
+	for _, r := range records {
+		if r.DNSName == "_acme-challenge.example.com" && r.RecordType == endpoint.RecordTypeTXT {
+			return r.Targets
+		}
+	}
+	return nil
+}
+
+func TestCertManagerSolver(t *testing.T) {
+	p := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+	m := http.NewServeMux()
+	InitHandlers(p, m, "", Handlers{CertManager: true})
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/apis/acme.example.com/v1alpha1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"groupVersion":"acme.example.com/v1alpha1"`)
+
+	// A domain and its wildcard share the challenge name.
+	assert.True(t, postChallenge(t, m, "Present", "key1").Success)
+	assert.True(t, postChallenge(t, m, "Present", "key2").Success)
+	assert.True(t, postChallenge(t, m, "Present", "key2").Success)
+	assert.ElementsMatch(t, endpoint.Targets{"key1", "key2"}, challengeTargets(t, p))
+
+	assert.True(t, postChallenge(t, m, "CleanUp", "key1").Success)
+	assert.Equal(t, endpoint.Targets{"key2"}, challengeTargets(t, p))
+	assert.True(t, postChallenge(t, m, "CleanUp", "key2").Success)
+	assert.True(t, postChallenge(t, m, "CleanUp", "key2").Success)
+	assert.Nil(t, challengeTargets(t, p))
+
+	res := postChallenge(t, m, "Unknown", "key1")
+	assert.False(t, res.Success)
+	require.NotNil(t, res.Result)
+	assert.Equal(t, "Failure", res.Result.Status)
+}
+
+func TestCertManagerSolverDomainFilter(t *testing.T) {
+	m := http.NewServeMux()
+	InitHandlers(FakeWebhookProvider{domainFilter: endpoint.NewDomainFilter([]string{"other.com"})}, m, "", Handlers{CertManager: true})
+	assert.False(t, postChallenge(t, m, "Present", "key1").Success)
+}
@@ -0,0 +1,333 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+const (
+	// CutoverWeightProperty is the default provider specific property of the
+	// weights of the records.
+	CutoverWeightProperty = "aws/weight"
+
+	cutoverTTL = 60
+)
+
+// The states of a cutover.
+const (
+	CutoverPending    = "Pending"
+	CutoverInProgress = "InProgress"
+	CutoverCompleted  = "Completed"
+	CutoverRolledBack = "RolledBack"
+)
+
+var defaultCutoverSteps = []int{10, 50, 100}
+
+// CutoverSet is a weighted record of a name: the targets of a set identifier.
+type CutoverSet struct {
+	SetIdentifier string   `json:"setIdentifier"`
+	Targets       []string `json:"targets"`
+}
+
+// CutoverRequest creates a cutover of a name from a set of targets to another.
+type CutoverRequest struct {
+	DNSName    string `json:"dnsName"`
+	RecordType string `json:"recordType"`
+	// TTL of the records, 60 by default.
+	TTL  int64      `json:"ttl,omitempty"`
+	From CutoverSet `json:"from"`
+	To   CutoverSet `json:"to"`
+	// Steps are the increasing percentages of the weight of To, ending with
+	// 100: 10, 50, 100 by default.
+	Steps []int `json:"steps,omitempty"`
+	// WeightProperty is the provider specific property of the weights,
+	// CutoverWeightProperty by default.
+	WeightProperty string `json:"weightProperty,omitempty"`
+}
+
+// CutoverEvent is a weight change of a cutover.
+type CutoverEvent struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Weight int       `json:"weight"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// CutoverOperation is the state of a cutover.
+type CutoverOperation struct {
+	ID string `json:"id"`
+	CutoverRequest
+	State string `json:"state"`
+	// Step is the index of the applied step, -1 before the first one.
+	Step int `json:"step"`
+	// Weight is the applied percentage of To.
+	Weight  int            `json:"weight"`
+	History []CutoverEvent `json:"history"`
+}
+
+// Cutover shifts the traffic of a name between two sets of targets, by
+// adjusting the weights of their records in steps - for blue/green migrations
+// driven by CI:
+//
+//	POST /cutover                 creates a cutover, with a CutoverRequest
+//	GET  /cutover[/<id>]          returns the cutovers, or one
+//	POST /cutover/<id>/next       applies the next step
+//	POST /cutover/<id>/rollback   sets the whole weight back to From
+//
+// The cutovers are kept in memory, a restart loses them, not the records.
+type Cutover struct {
+	Provider provider.Provider
+
+	prefix string
+	mu     sync.Mutex
+	ops    map[string]*CutoverOperation
+	lastID int
+}
+
+// ServeHTTP handles the cutover requests.
+func (c *Cutover) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(req.URL.Path, c.prefix+"/cutover"), "/")
+	var parts []string
+	if path != "" {
+		parts = strings.Split(path, "/")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case len(parts) == 0 && req.Method == http.MethodGet:
+		ops := make([]*CutoverOperation, 0, len(c.ops))
+		for _, op := range c.ops {
+			ops = append(ops, op)
+		}
+		sort.Slice(ops, func(i, j int) bool {
+			a, _ := strconv.Atoi(ops[i].ID)
+			b, _ := strconv.Atoi(ops[j].ID)
+			return a < b
+		})
+		writeCutover(w, http.StatusOK, ops)
+	case len(parts) == 0 && req.Method == http.MethodPost:
+		var cr CutoverRequest
+		if err := json.NewDecoder(req.Body).Decode(&cr); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		op, status, err := c.create(cr)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		writeCutover(w, http.StatusCreated, op)
+	case len(parts) == 1 && req.Method == http.MethodGet:
+		op := c.ops[parts[0]]
+		if op == nil {
+			http.NotFound(w, req)
+			return
+		}
+		writeCutover(w, http.StatusOK, op)
+	case len(parts) == 2 && req.Method == http.MethodPost:
+		op := c.ops[parts[0]]
+		if op == nil {
+			http.NotFound(w, req)
+			return
+		}
+		var err error
+		switch parts[1] {
+		case "next":
+			err = c.next(req.Context(), op)
+		case "rollback":
+			err = c.rollback(req.Context(), op)
+		default:
+			http.NotFound(w, req)
+			return
+		}
+		if errors.Is(err, errCutoverState) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Errorf("Failed to apply the cutover %s of %s: %v", op.ID, op.DNSName, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeCutover(w, http.StatusOK, op)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func writeCutover(w http.ResponseWriter, status int, v any) {
+	w.Header().Set(ContentTypeHeader, "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+var errCutoverState = errors.New("invalid cutover state")
+
+// create validates the request, and returns the new pending cutover.
+func (c *Cutover) create(cr CutoverRequest) (*CutoverOperation, int, error) {
+	cr.DNSName = strings.TrimSuffix(cr.DNSName, ".")
+	if cr.DNSName == "" || cr.RecordType == "" {
+		return nil, http.StatusBadRequest, errors.New("dnsName and recordType are required")
+	}
+	if !c.Provider.GetDomainFilter().Match(cr.DNSName) {
+		return nil, http.StatusBadRequest, fmt.Errorf("%s is not in the provider domains", cr.DNSName)
+	}
+	if cr.From.SetIdentifier == "" || cr.To.SetIdentifier == "" || cr.From.SetIdentifier == cr.To.SetIdentifier {
+		return nil, http.StatusBadRequest, errors.New("from and to require distinct set identifiers")
+	}
+	if len(cr.From.Targets) == 0 || len(cr.To.Targets) == 0 {
+		return nil, http.StatusBadRequest, errors.New("from and to require targets")
+	}
+	if len(cr.Steps) == 0 {
+		cr.Steps = defaultCutoverSteps
+	}
+	for i, step := range cr.Steps {
+		if step < 1 || step > 100 || i > 0 && step <= cr.Steps[i-1] {
+			return nil, http.StatusBadRequest, errors.New("the steps must be increasing percentages")
+		}
+	}
+	if cr.Steps[len(cr.Steps)-1] != 100 {
+		return nil, http.StatusBadRequest, errors.New("the last step must be 100")
+	}
+	if cr.TTL == 0 {
+		cr.TTL = cutoverTTL
+	}
+	if cr.WeightProperty == "" {
+		cr.WeightProperty = CutoverWeightProperty
+	}
+	for _, op := range c.ops {
+		if op.State != CutoverRolledBack && op.State != CutoverCompleted && strings.EqualFold(op.DNSName, cr.DNSName) && op.RecordType == cr.RecordType {
+			return nil, http.StatusConflict, fmt.Errorf("the cutover %s of %s is in progress", op.ID, op.DNSName)
+		}
+	}
+
+	if c.ops == nil {
+		c.ops = map[string]*CutoverOperation{}
+	}
+	c.lastID++
+	op := &CutoverOperation{
+		ID:             strconv.Itoa(c.lastID),
+		CutoverRequest: cr,
+		State:          CutoverPending,
+		Step:           -1,
+		History:        []CutoverEvent{{Time: time.Now(), Action: "create"}},
+	}
+	c.ops[op.ID] = op
+	return op, 0, nil
+}
+
+// next applies the next step of the cutover.
+func (c *Cutover) next(ctx context.Context, op *CutoverOperation) error {
+	if op.State != CutoverPending && op.State != CutoverInProgress {
+		return fmt.Errorf("%w: the cutover is %s", errCutoverState, op.State)
+	}
+	weight := op.Steps[op.Step+1]
+	if err := c.apply(ctx, op, "next", weight); err != nil {
+		return err
+	}
+	op.Step++
+	op.State = CutoverInProgress
+	if weight == 100 {
+		op.State = CutoverCompleted
+	}
+	return nil
+}
+
+// rollback sets the whole weight back to From, also after the completion.
+func (c *Cutover) rollback(ctx context.Context, op *CutoverOperation) error {
+	if op.State == CutoverRolledBack {
+		return fmt.Errorf("%w: the cutover is %s", errCutoverState, op.State)
+	}
+	if err := c.apply(ctx, op, "rollback", 0); err != nil {
+		return err
+	}
+	op.Step = -1
+	op.State = CutoverRolledBack
+	return nil
+}
+
+// apply sets the weights of the records of From and To, with weight the
+// percentage of To, and records it in the history.
+func (c *Cutover) apply(ctx context.Context, op *CutoverOperation, action string, weight int) error {
+	err := c.applyWeights(ctx, op, weight)
+	event := CutoverEvent{Time: time.Now(), Action: action, Weight: weight}
+	if err != nil {
+		event.Error = err.Error()
+	} else {
+		op.Weight = weight
+		log.Infof("Cutover %s of %s: %d%% to %s", op.ID, op.DNSName, weight, op.To.SetIdentifier)
+	}
+	op.History = append(op.History, event)
+	return err
+}
+
+func (c *Cutover) applyWeights(ctx context.Context, op *CutoverOperation, weight int) error {
+	records, err := c.Provider.Records(ctx)
+	if err != nil {
+		return err
+	}
+	current := map[string]*endpoint.Endpoint{}
+	for _, r := range records {
+		if strings.EqualFold(r.DNSName, op.DNSName) && r.RecordType == op.RecordType {
+			current[r.SetIdentifier] = r
+		}
+	}
+
+	changes := &plan.Changes{}
+	for _, s := range []struct {
+		set    CutoverSet
+		weight int
+	}{{op.From, 100 - weight}, {op.To, weight}} {
+		desired := endpoint.NewEndpointWithTTL(op.DNSName, op.RecordType, endpoint.TTL(op.TTL), s.set.Targets...).
+			WithSetIdentifier(s.set.SetIdentifier).
+			WithProviderSpecific(op.WeightProperty, strconv.Itoa(s.weight))
+		old := current[s.set.SetIdentifier]
+		switch {
+		case old == nil:
+			changes.Create = append(changes.Create, desired)
+		case !old.Targets.Same(desired.Targets) || old.RecordTTL != desired.RecordTTL || !sameWeight(old, op.WeightProperty, s.weight):
+			changes.UpdateOld = append(changes.UpdateOld, old)
+			changes.UpdateNew = append(changes.UpdateNew, desired)
+		}
+	}
+	if !changes.HasChanges() {
+		return nil
+	}
+	return c.Provider.ApplyChanges(ctx, changes)
+}
+
+func sameWeight(ep *endpoint.Endpoint, property string, weight int) bool {
+	v, ok := ep.GetProviderSpecificProperty(property)
+	return ok && v == strconv.Itoa(weight)
+}
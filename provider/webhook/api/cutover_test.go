@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+func cutoverRequest(t *testing.T, m *http.ServeMux, path string, body any) (*httptest.ResponseRecorder, *CutoverOperation) {
+	t.Helper()
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		require.NoError(t, err)
+	}
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data)))
+	if w.Code >= 300 {
+		return w, nil
+	}
+	var op CutoverOperation
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&op))
+	return w, &op
+}
+
+// weights returns the weights of the records of the name, by set identifier.
+func weights(t *testing.T, p *inmemory.InMemoryProvider) map[string]string {
+	t.Helper()
+	records, err := p.Records(context.Background())
+	require.NoError(t, err)
+	weights := map[string]string{}
+	for _, r := range records {
+		if r.DNSName == "app.example.com" {
+			weights[r.SetIdentifier], _ = r.GetProviderSpecificProperty(CutoverWeightProperty)
+		}
+	}
+	return weights
+}
+
+func TestCutover(t *testing.T) {
+	p := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+	m := http.NewServeMux()
+	InitHandlers(p, m, "/google", Handlers{Cutover: true})
+
+	request := CutoverRequest{
+		DNSName:    "app.example.com",
+		RecordType: "CNAME",
+		From:       CutoverSet{SetIdentifier: "blue", Targets: []string{"blue.lb.example.net"}},
+		To:         CutoverSet{SetIdentifier: "green", Targets: []string{"green.lb.example.net"}},
+	}
+	w, op := cutoverRequest(t, m, "/google/cutover", request)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, CutoverPending, op.State)
+	assert.Equal(t, []int{10, 50, 100}, op.Steps)
+	assert.Empty(t, weights(t, p))
+
+	w, _ = cutoverRequest(t, m, "/google/cutover", request)
+	assert.Equal(t, http.StatusConflict, w.Code, "one cutover of a name at a time")
+
+	for _, step := range []struct {
+		weight      int
+		blue, green string
+		state       string
+	}{
+		{10, "90", "10", CutoverInProgress},
+		{50, "50", "50", CutoverInProgress},
+		{100, "0", "100", CutoverCompleted},
+	} {
+		w, op = cutoverRequest(t, m, "/google/cutover/"+op.ID+"/next", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, step.weight, op.Weight)
+		assert.Equal(t, step.state, op.State)
+		assert.Equal(t, map[string]string{"blue": step.blue, "green": step.green}, weights(t, p))
+	}
+	w, _ = cutoverRequest(t, m, "/google/cutover/"+op.ID+"/next", nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w, op = cutoverRequest(t, m, "/google/cutover/"+op.ID+"/rollback", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, CutoverRolledBack, op.State)
+	assert.Equal(t, map[string]string{"blue": "100", "green": "0"}, weights(t, p))
+	assert.Len(t, op.History, 5)
+
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/google/cutover/"+op.ID, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/google/cutover", nil))
+	var ops []CutoverOperation
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&ops))
+	assert.Len(t, ops, 1)
+}
+
+func TestCutoverInvalid(t *testing.T) {
+	p := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+	m := http.NewServeMux()
+	InitHandlers(p, m, "", Handlers{Cutover: true})
+
+	for name, request := range map[string]CutoverRequest{
+		"no targets": {DNSName: "app.example.com", RecordType: "A", From: CutoverSet{SetIdentifier: "blue"}, To: CutoverSet{SetIdentifier: "green"}},
+		"same sets":  {DNSName: "app.example.com", RecordType: "A", From: CutoverSet{SetIdentifier: "blue", Targets: []string{"10.0.0.1"}}, To: CutoverSet{SetIdentifier: "blue", Targets: []string{"10.0.0.2"}}},
+		"steps":      {DNSName: "app.example.com", RecordType: "A", From: CutoverSet{SetIdentifier: "blue", Targets: []string{"10.0.0.1"}}, To: CutoverSet{SetIdentifier: "green", Targets: []string{"10.0.0.2"}}, Steps: []int{50, 20, 100}},
+		"last step":  {DNSName: "app.example.com", RecordType: "A", From: CutoverSet{SetIdentifier: "blue", Targets: []string{"10.0.0.1"}}, To: CutoverSet{SetIdentifier: "green", Targets: []string{"10.0.0.2"}}, Steps: []int{50}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w, _ := cutoverRequest(t, m, "/cutover", request)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+
+	w, _ := cutoverRequest(t, m, "/cutover/42/next", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -17,13 +17,19 @@ limitations under the License.
 package api
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/dnsserver"
+	"sigs.k8s.io/external-dns/pkg/logging"
 	"sigs.k8s.io/external-dns/plan"
 	"sigs.k8s.io/external-dns/provider"
 
@@ -37,12 +43,16 @@ const (
 
 type WebhookServer struct {
 	Provider provider.Provider
+
+	// The changes applied by idempotency key, see applyOnce.
+	appliedMu sync.Mutex
+	applied   map[string]*appliedKey
 }
 
 func (p *WebhookServer) RecordsHandler(w http.ResponseWriter, req *http.Request) {
 	switch req.Method {
 	case http.MethodGet:
-		records, err := p.Provider.Records(context.Background())
+		records, err := p.Provider.Records(req.Context())
 		if err != nil {
 			log.Errorf("Failed to get Records: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -50,7 +60,7 @@ func (p *WebhookServer) RecordsHandler(w http.ResponseWriter, req *http.Request)
 		}
 		w.Header().Set(ContentTypeHeader, MediaTypeFormatAndVersion)
 		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode(records); err != nil {
+		if err := encodeRecords(w, records); err != nil {
 			log.Errorf("Failed to encode records: %v", err)
 		}
 		return
@@ -61,13 +71,16 @@ func (p *WebhookServer) RecordsHandler(w http.ResponseWriter, req *http.Request)
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
-		err := p.Provider.ApplyChanges(context.Background(), &changes)
-		if err != nil {
-			log.Errorf("Failed to apply changes: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		w.WriteHeader(http.StatusNoContent)
+		status := p.applyOnce(req.Context(), req.Header.Get(IdempotencyKeyHeader), func() int {
+			// A batch of changes is not interrupted if the client disconnects.
+			err := p.Provider.ApplyChanges(context.WithoutCancel(req.Context()), &changes)
+			if err != nil {
+				log.Errorf("Failed to apply changes: %v", err)
+				return http.StatusInternalServerError
+			}
+			return http.StatusNoContent
+		})
+		w.WriteHeader(status)
 		return
 	default:
 		log.Errorf("Unsupported method %s", req.Method)
@@ -75,6 +88,30 @@ func (p *WebhookServer) RecordsHandler(w http.ResponseWriter, req *http.Request)
 	}
 }
 
+// encodeRecords writes the records as a JSON array, encoding one record at a
+// time instead of the whole array in memory.
+func encodeRecords(w io.Writer, records []*endpoint.Endpoint) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if _, err := bw.WriteString("["); err != nil {
+		return err
+	}
+	for i, ep := range records {
+		if i > 0 {
+			if _, err := bw.WriteString(","); err != nil {
+				return err
+			}
+		}
+		if err := enc.Encode(ep); err != nil {
+			return err
+		}
+	}
+	if _, err := bw.WriteString("]\n"); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
 func (p *WebhookServer) AdjustEndpointsHandler(w http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodPost {
 		log.Errorf("Unsupported method %s", req.Method)
@@ -101,6 +138,37 @@ func (p *WebhookServer) AdjustEndpointsHandler(w http.ResponseWriter, req *http.
 	}
 }
 
+// ChangesHandler returns the changes of the provider journal since the cursor
+// query parameter, with 404 if the provider has no journal and 410 if the
+// cursor expired.
+func (p *WebhookServer) ChangesHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		log.Errorf("Unsupported method %s", req.Method)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	journal, ok := p.Provider.(provider.Journal)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	changes, err := journal.Changes(req.Context(), req.URL.Query().Get("cursor"))
+	if errors.Is(err, provider.ErrJournalExpired) {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+	if err != nil {
+		log.Errorf("Failed to get the journal changes: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(ContentTypeHeader, MediaTypeFormatAndVersion)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(changes); err != nil {
+		log.Errorf("Failed to encode the journal changes: %v", err)
+	}
+}
+
 // NegotiateHandler returns the domain filter for the supported provider.
 func (p *WebhookServer) NegotiateHandler(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set(ContentTypeHeader, MediaTypeFormatAndVersion)
@@ -115,37 +183,79 @@ func (p *WebhookServer) NegotiateHandler(w http.ResponseWriter, req *http.Reques
 // - /records (GET): returns the current records
 // - /records (POST): applies the changes
 // - /adjustendpoints (POST): executes the AdjustEndpoints method
+// - /changes (GET): returns the changes of the provider journal since ?cursor=
+// - /dns-query (GET, POST): DNS-over-HTTPS queries for the provider records, with Handlers.DoH
+// - /apis/<group>/v1alpha1/external-dns (POST): cert-manager DNS01 challenges, with Handlers.CertManager
 func StartHTTPApi(provider provider.Provider, startedChan chan struct{}, readTimeout, writeTimeout time.Duration, providerPort string) {
+	if err := ServeHTTPApi(context.Background(), provider, startedChan, readTimeout, writeTimeout, 0, providerPort, Handlers{}); err != nil {
+		log.Fatal(err)
+	}
+}
 
+// ServeHTTPApi serves the provider like StartHTTPApi until ctx is canceled,
+// then stops accepting connections and waits up to shutdownTimeout - forever
+// if zero - for the requests in progress, like an ApplyChanges batch.
+func ServeHTTPApi(ctx context.Context, provider provider.Provider, startedChan chan struct{}, readTimeout, writeTimeout, shutdownTimeout time.Duration, providerPort string, handlers Handlers) error {
 	m := http.NewServeMux()
-	InitHandlers(provider, m, "")
+	InitHandlers(provider, m, "", handlers)
+	return ServeHandler(ctx, m, startedChan, readTimeout, writeTimeout, shutdownTimeout, providerPort)
+}
 
+// ServeHandler serves the handler like ServeHTTPApi - for example a mux with
+// several providers, added by InitHandlers with different prefixes. The
+// request contexts have the trace of the client, for the logs.
+func ServeHandler(ctx context.Context, handler http.Handler, startedChan chan struct{}, readTimeout, writeTimeout, shutdownTimeout time.Duration, providerPort string) error {
 	s := &http.Server{
 		Addr:         providerPort,
-		Handler:      m,
+		Handler:      logging.TraceHandler(handler),
 		ReadTimeout:  readTimeout,
 		WriteTimeout: writeTimeout,
 	}
 
 	l, err := net.Listen("tcp", providerPort)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	if startedChan != nil {
 		startedChan <- struct{}{}
 	}
 
-	if err := s.Serve(l); err != nil {
-		log.Fatal(err)
+	shutdownDone := make(chan error, 1)
+	stop := context.AfterFunc(ctx, func() {
+		log.Info("Shutting down the webhook server")
+		shutdownCtx := context.Background()
+		if shutdownTimeout > 0 {
+			var cancel context.CancelFunc
+			shutdownCtx, cancel = context.WithTimeout(shutdownCtx, shutdownTimeout)
+			defer cancel()
+		}
+		shutdownDone <- s.Shutdown(shutdownCtx)
+	})
+	defer stop()
+
+	if err := s.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
 	}
+	return <-shutdownDone
+}
+
+// Handlers are the optional handlers of InitHandlers, only served when
+// enabled since they expose the provider beyond the webhook API.
+type Handlers struct {
+	// DoH serves DNS-over-HTTPS queries for the records of the provider.
+	DoH bool
+	// CertManager solves the cert-manager DNS01 challenges with the provider.
+	CertManager bool
+	// Cutover serves the weighted cutovers of the names, changing records.
+	Cutover bool
 }
 
 // InitHandlers will initialize the HTTP handlers for the given provider.
 // Caller can start a server and handle TLS, auth, etc.
 // The prefix allows multiple providers to be served on the same port and optional
 // parameters like zone.
-func InitHandlers(provider provider.Provider, m *http.ServeMux, prefix string) {
+func InitHandlers(provider provider.Provider, m *http.ServeMux, prefix string, handlers Handlers) {
 	p := WebhookServer{
 		Provider: provider,
 	}
@@ -160,4 +270,22 @@ func InitHandlers(provider provider.Provider, m *http.ServeMux, prefix string) {
 	//
 	m.HandleFunc(prefix +"/records", p.RecordsHandler)
 	m.HandleFunc(prefix +"/adjustendpoints", p.AdjustEndpointsHandler)
+	m.HandleFunc(prefix+"/changes", p.ChangesHandler)
+
+	// DNS-over-HTTPS for the records of the provider, using the same TLS and auth as the webhook.
+	if handlers.DoH {
+		m.Handle(prefix+dnsserver.DoHPath, dnsserver.New(provider))
+	}
+
+	// cert-manager DNS01 webhook solver, served as an aggregated API.
+	if handlers.CertManager {
+		m.Handle(prefix+"/apis/", &CertManagerSolver{Provider: provider, prefix: prefix})
+	}
+
+	// Weighted cutovers of the names between two sets of targets, for CI.
+	if handlers.Cutover {
+		cutover := &Cutover{Provider: provider, prefix: prefix}
+		m.Handle(prefix+"/cutover", cutover)
+		m.Handle(prefix+"/cutover/", cutover)
+	}
 }
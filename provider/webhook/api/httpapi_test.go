@@ -30,6 +30,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
 )
 
 var records []*endpoint.Endpoint
@@ -276,3 +277,134 @@ func TestStartHTTPApi(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, df.UnmarshalJSON(b))
 }
+
+// blockingProvider blocks ApplyChanges until release is closed.
+type blockingProvider struct {
+	FakeWebhookProvider
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p blockingProvider) ApplyChanges(context.Context, *plan.Changes) error {
+	p.started <- struct{}{}
+	<-p.release
+	return nil
+}
+
+func TestServeHTTPApiShutdown(t *testing.T) {
+	p := blockingProvider{started: make(chan struct{}), release: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	startedChan := make(chan struct{})
+	served := make(chan error)
+	go func() {
+		served <- ServeHTTPApi(ctx, p, startedChan, 5*time.Second, 10*time.Second, 10*time.Second, "127.0.0.1:8886", Handlers{})
+	}()
+	<-startedChan
+
+	applied := make(chan int)
+	go func() {
+		resp, err := http.Post("http://127.0.0.1:8886/records", MediaTypeFormatAndVersion, bytes.NewReader([]byte("{}")))
+		if err != nil {
+			applied <- 0
+			return
+		}
+		resp.Body.Close()
+		applied <- resp.StatusCode
+	}()
+
+	// The request in progress completes after the shutdown starts.
+	<-p.started
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	_, err := http.Get("http://127.0.0.1:8886")
+	require.Error(t, err, "new connections are refused")
+	close(p.release)
+	require.Equal(t, http.StatusNoContent, <-applied)
+	require.NoError(t, <-served)
+}
+
+func TestInitHandlersPrefixes(t *testing.T) {
+	m := http.NewServeMux()
+	InitHandlers(FakeWebhookProvider{domainFilter: endpoint.NewDomainFilter([]string{"a.com"})}, m, "/a", Handlers{})
+	InitHandlers(FakeWebhookProvider{domainFilter: endpoint.NewDomainFilter([]string{"b.com"})}, m, "/b", Handlers{})
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	for prefix, domain := range map[string]string{"/a": "a.com", "/b": "b.com"} {
+		// The client negotiates with the URL without the trailing slash.
+		resp, err := http.Get(server.URL + prefix)
+		require.NoError(t, err)
+		var df endpoint.DomainFilter
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&df))
+		resp.Body.Close()
+		require.Equal(t, []string{domain}, df.Filters)
+
+		resp, err = http.Get(server.URL + prefix + "/records")
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestInitHandlersOptional(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		handlers Handlers
+		path     string
+	}{
+		{name: "doh", handlers: Handlers{DoH: true}, path: "/dns-query"},
+		{name: "cert-manager", handlers: Handlers{CertManager: true}, path: "/apis/acme.example.com/v1alpha1"},
+		{name: "cutover", handlers: Handlers{Cutover: true}, path: "/cutover"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, enabled := range []bool{false, true} {
+				handlers := Handlers{}
+				if enabled {
+					handlers = tc.handlers
+				}
+				m := http.NewServeMux()
+				InitHandlers(FakeWebhookProvider{}, m, "", handlers)
+				w := httptest.NewRecorder()
+				m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+				// Without the handler, the request is a negotiation.
+				require.Equal(t, !enabled, w.Header().Get(ContentTypeHeader) == MediaTypeFormatAndVersion, "enabled: %v", enabled)
+			}
+		})
+	}
+}
+
+type FakeJournalProvider struct {
+	FakeWebhookProvider
+}
+
+func (p FakeJournalProvider) Changes(ctx context.Context, cursor string) (*provider.JournalChanges, error) {
+	if cursor == "expired" {
+		return nil, provider.ErrJournalExpired
+	}
+	return &provider.JournalChanges{Names: []string{"foo.bar.com"}, Cursor: cursor + "1"}, nil
+}
+
+func TestChangesHandler(t *testing.T) {
+	providerAPIServer := &WebhookServer{
+		Provider: &FakeJournalProvider{},
+	}
+	w := httptest.NewRecorder()
+	providerAPIServer.ChangesHandler(w, httptest.NewRequest(http.MethodGet, "/changes?cursor=1", nil))
+	res := w.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	changes := provider.JournalChanges{}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&changes))
+	require.Equal(t, provider.JournalChanges{Names: []string{"foo.bar.com"}, Cursor: "11"}, changes)
+
+	w = httptest.NewRecorder()
+	providerAPIServer.ChangesHandler(w, httptest.NewRequest(http.MethodGet, "/changes?cursor=expired", nil))
+	require.Equal(t, http.StatusGone, w.Result().StatusCode)
+
+	w = httptest.NewRecorder()
+	providerAPIServer = &WebhookServer{
+		Provider: &FakeWebhookProvider{},
+	}
+	providerAPIServer.ChangesHandler(w, httptest.NewRequest(http.MethodGet, "/changes", nil))
+	require.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
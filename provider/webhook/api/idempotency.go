@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// IdempotencyKeyHeader identifies a batch of changes: the requests
+	// retried with the same key are applied once.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// idempotencyTTL is how long the applied keys are remembered.
+	idempotencyTTL = 10 * time.Minute
+)
+
+// appliedKey is the result of the changes of an idempotency key.
+type appliedKey struct {
+	// done is closed once the changes are applied, or failed.
+	done    chan struct{}
+	status  int
+	expires time.Time
+}
+
+// applyOnce calls apply once per idempotency key, and returns its status.
+// A request with the key of changes being applied waits for them, and the
+// key of applied changes returns their status without applying them again.
+// Failed changes are forgotten, so that a retry applies them again. Without
+// a key, apply is always called.
+func (p *WebhookServer) applyOnce(ctx context.Context, key string, apply func() int) int {
+	if key == "" {
+		return apply()
+	}
+
+	p.appliedMu.Lock()
+	now := time.Now()
+	for k, a := range p.applied {
+		if now.After(a.expires) {
+			delete(p.applied, k)
+		}
+	}
+	if a, ok := p.applied[key]; ok {
+		p.appliedMu.Unlock()
+		select {
+		case <-a.done:
+			log.Infof("The changes of the idempotency key %s are already applied", key)
+			return a.status
+		case <-ctx.Done():
+			// The client retries with the same key.
+			return http.StatusServiceUnavailable
+		}
+	}
+	if p.applied == nil {
+		p.applied = map[string]*appliedKey{}
+	}
+	a := &appliedKey{done: make(chan struct{}), expires: now.Add(idempotencyTTL)}
+	p.applied[key] = a
+	p.appliedMu.Unlock()
+
+	a.status = apply()
+	if a.status >= http.StatusInternalServerError {
+		p.appliedMu.Lock()
+		delete(p.applied, key)
+		p.appliedMu.Unlock()
+	}
+	close(a.done)
+	return a.status
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// countingProvider counts the applied changes, failing while err is set.
+type countingProvider struct {
+	provider.BaseProvider
+	mu      sync.Mutex
+	applied int
+	err     error
+	// block, if set, is waited for by ApplyChanges.
+	block chan struct{}
+}
+
+func (p *countingProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	return nil, nil
+}
+
+func (p *countingProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	if p.block != nil {
+		<-p.block
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.applied++
+	return nil
+}
+
+func postChanges(server *WebhookServer, key string) int {
+	req := httptest.NewRequest(http.MethodPost, "/records", strings.NewReader(`{"Create":[{"dnsName":"foo.bar.com","recordType":"A","targets":["1.2.3.4"]}]}`))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	server.RecordsHandler(w, req)
+	return w.Result().StatusCode
+}
+
+func TestRecordsHandlerIdempotencyKey(t *testing.T) {
+	p := &countingProvider{}
+	server := &WebhookServer{Provider: p}
+
+	assert.Equal(t, http.StatusNoContent, postChanges(server, "key-1"))
+	assert.Equal(t, http.StatusNoContent, postChanges(server, "key-1"))
+	assert.Equal(t, 1, p.applied, "a retried request is applied once")
+
+	assert.Equal(t, http.StatusNoContent, postChanges(server, "key-2"))
+	assert.Equal(t, http.StatusNoContent, postChanges(server, ""))
+	assert.Equal(t, http.StatusNoContent, postChanges(server, ""))
+	assert.Equal(t, 4, p.applied)
+
+	// Failed changes are applied again by a retry.
+	p.err = errors.New("failed")
+	assert.Equal(t, http.StatusInternalServerError, postChanges(server, "key-3"))
+	p.err = nil
+	assert.Equal(t, http.StatusNoContent, postChanges(server, "key-3"))
+	assert.Equal(t, 5, p.applied)
+}
+
+func TestRecordsHandlerIdempotencyKeyInProgress(t *testing.T) {
+	p := &countingProvider{block: make(chan struct{})}
+	server := &WebhookServer{Provider: p}
+
+	first := make(chan int)
+	go func() { first <- postChanges(server, "key-1") }()
+	require.Eventually(t, func() bool {
+		server.appliedMu.Lock()
+		defer server.appliedMu.Unlock()
+		return server.applied["key-1"] != nil
+	}, time.Second, time.Millisecond)
+
+	// A retry waits for the changes in progress.
+	retry := make(chan int)
+	go func() { retry <- postChanges(server, "key-1") }()
+	close(p.block)
+	assert.Equal(t, http.StatusNoContent, <-first)
+	assert.Equal(t, http.StatusNoContent, <-retry)
+	assert.Equal(t, 1, p.applied)
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/pkg/logging"
+	"sigs.k8s.io/external-dns/pkg/tlsutils"
+)
+
+// Listener is an address of the webhook server, like a cleartext localhost
+// listener for the controller of the pod and an mTLS listener for the other
+// clusters, in the same process.
+type Listener struct {
+	// Address is the host:port to listen on.
+	Address string `json:"address"`
+	// CertFile and KeyFile are the server certificate, reloaded when the
+	// files change. The listener is cleartext without them.
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+	// ClientCAFile requires the clients to present a certificate signed by
+	// one of its CAs. It requires CertFile.
+	ClientCAFile string `json:"clientCAFile,omitempty"`
+	// Prefixes are the URL prefixes served, like /prod for the provider
+	// served at /prod/records - all if empty.
+	Prefixes []string `json:"prefixes,omitempty"`
+}
+
+// Validate returns an error if the listener is not valid.
+func (l *Listener) Validate() error {
+	if l.Address == "" {
+		return errors.New("the listener has no address")
+	}
+	if (l.CertFile == "") != (l.KeyFile == "") {
+		return fmt.Errorf("listener %s: either both certFile and keyFile or none must be provided", l.Address)
+	}
+	if l.ClientCAFile != "" && l.CertFile == "" {
+		return fmt.Errorf("listener %s: clientCAFile requires certFile", l.Address)
+	}
+	for _, p := range l.Prefixes {
+		if !strings.HasPrefix(p, "/") || strings.HasSuffix(p, "/") {
+			return fmt.Errorf("listener %s: the prefix %q must start and not end with /", l.Address, p)
+		}
+	}
+	return nil
+}
+
+// tlsConfig returns the TLS configuration of the listener, nil if cleartext.
+func (l *Listener) tlsConfig() (*tls.Config, error) {
+	if l.CertFile == "" {
+		return nil, nil
+	}
+	certs, err := tlsutils.NewCertReloader(l.CertFile, l.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg := certs.TLSConfig()
+	if l.ClientCAFile != "" {
+		// The roots of a client config are the CAs of the client certificates.
+		ca, err := tlsutils.NewTLSConfig("", "", l.ClientCAFile, "", false, 0)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = ca.RootCAs
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// handler returns the handler restricted to the prefixes of the listener.
+func (l *Listener) handler(h http.Handler) http.Handler {
+	if len(l.Prefixes) == 0 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for _, p := range l.Prefixes {
+			if req.URL.Path == p || strings.HasPrefix(req.URL.Path, p+"/") {
+				h.ServeHTTP(w, req)
+				return
+			}
+		}
+		http.NotFound(w, req)
+	})
+}
+
+// ServeListeners serves the handler like ServeHandler on each listener, with
+// its TLS and prefixes, until ctx is canceled or a listener fails - then all
+// the listeners are shut down. startedChan, if set, receives a value once all
+// the listeners accept connections.
+func ServeListeners(ctx context.Context, handler http.Handler, listeners []Listener, startedChan chan struct{}, readTimeout, writeTimeout, shutdownTimeout time.Duration) error {
+	if len(listeners) == 0 {
+		return errors.New("no listener")
+	}
+	servers := make([]*http.Server, 0, len(listeners))
+	netListeners := make([]net.Listener, 0, len(listeners))
+	closeAll := func() {
+		for _, l := range netListeners {
+			l.Close()
+		}
+	}
+	for i := range listeners {
+		l := &listeners[i]
+		if err := l.Validate(); err != nil {
+			closeAll()
+			return err
+		}
+		tlsConfig, err := l.tlsConfig()
+		if err != nil {
+			closeAll()
+			return fmt.Errorf("listener %s: %w", l.Address, err)
+		}
+		nl, err := net.Listen("tcp", l.Address)
+		if err != nil {
+			closeAll()
+			return err
+		}
+		if tlsConfig != nil {
+			nl = tls.NewListener(nl, tlsConfig)
+		}
+		netListeners = append(netListeners, nl)
+		servers = append(servers, &http.Server{
+			Handler:      logging.TraceHandler(l.handler(handler)),
+			TLSConfig:    tlsConfig,
+			ReadTimeout:  readTimeout,
+			WriteTimeout: writeTimeout,
+		})
+	}
+
+	if startedChan != nil {
+		startedChan <- struct{}{}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(servers))
+	for i, s := range servers {
+		log.Infof("Serving the webhook API on %s (TLS: %t)", listeners[i].Address, s.TLSConfig != nil)
+		go func() {
+			err := s.Serve(netListeners[i])
+			if errors.Is(err, http.ErrServerClosed) {
+				err = nil
+			}
+			if err != nil {
+				// Stop the other listeners.
+				cancel()
+			}
+			errs <- err
+		}()
+	}
+
+	<-ctx.Done()
+	log.Info("Shutting down the webhook server")
+	shutdownCtx := context.Background()
+	if shutdownTimeout > 0 {
+		var cancelShutdown context.CancelFunc
+		shutdownCtx, cancelShutdown = context.WithTimeout(shutdownCtx, shutdownTimeout)
+		defer cancelShutdown()
+	}
+	var errList []error
+	for _, s := range servers {
+		errList = append(errList, s.Shutdown(shutdownCtx))
+	}
+	for range servers {
+		errList = append(errList, <-errs)
+	}
+	return errors.Join(errList...)
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCert writes a self-signed cert with the given common name.
+func writeCert(t *testing.T, dir, cn string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{cn},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, cn+".crt")
+	keyFile := filepath.Join(dir, cn+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+// freeAddress returns a local address not in use.
+func freeAddress(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func get(t *testing.T, c *http.Client, url string) (int, string) {
+	res, err := c.Get(url)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res.StatusCode, string(body)
+}
+
+func TestServeListeners(t *testing.T) {
+	dir := t.TempDir()
+	serverCert, serverKey := writeCert(t, dir, "dns-google.example.com")
+	clientCert, clientKey := writeCert(t, dir, "remote.example.com")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/prod/records", func(w http.ResponseWriter, _ *http.Request) { io.WriteString(w, "prod") })
+	mux.HandleFunc("/dev/records", func(w http.ResponseWriter, _ *http.Request) { io.WriteString(w, "dev") })
+
+	local, remote := freeAddress(t), freeAddress(t)
+	listeners := []Listener{
+		{Address: local, Prefixes: []string{"/dev"}},
+		{Address: remote, CertFile: serverCert, KeyFile: serverKey, ClientCAFile: clientCert},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- ServeListeners(ctx, mux, listeners, started, time.Second, time.Second, time.Second)
+	}()
+	<-started
+
+	// The cleartext listener serves only its prefixes.
+	code, body := get(t, http.DefaultClient, "http://"+local+"/dev/records")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "dev", body)
+	code, _ = get(t, http.DefaultClient, "http://"+local+"/prod/records")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// The mTLS listener serves all the prefixes, to clients with a certificate.
+	cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	require.NoError(t, err)
+	mtls := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		InsecureSkipVerify: true,
+		Certificates:       []tls.Certificate{cert},
+	}}}
+	code, body = get(t, mtls, "https://"+remote+"/prod/records")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "prod", body)
+
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	code, _ = get(t, anonymous, "https://"+remote+"/prod/records")
+	assert.Zero(t, code, "the client certificate is required")
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestServeListenersErrors(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := writeCert(t, dir, "dns-google.example.com")
+
+	for name, l := range map[string]Listener{
+		"no address":       {},
+		"cert without key": {Address: "127.0.0.1:0", CertFile: cert},
+		"ca without cert":  {Address: "127.0.0.1:0", ClientCAFile: cert},
+		"invalid prefix":   {Address: "127.0.0.1:0", Prefixes: []string{"prod/"}},
+		"missing ca":       {Address: "127.0.0.1:0", CertFile: cert, KeyFile: key, ClientCAFile: filepath.Join(dir, "missing.crt")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := ServeListeners(context.Background(), http.NotFoundHandler(), []Listener{{Address: freeAddress(t)}, l}, nil, 0, 0, 0)
+			assert.Error(t, err)
+		})
+	}
+	assert.Error(t, ServeListeners(context.Background(), http.NotFoundHandler(), nil, nil, 0, 0, 0))
+}
@@ -0,0 +1,450 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conformance checks that a webhook provider implements the protocol
+// of provider/webhook/api, as ExternalDNS uses it: the negotiation, the
+// records round-trip, the AdjustEndpoints semantics, the errors and the large
+// batches. The providers of other repositories run it against a test
+// server, or with the webhook-conformance command against a deployment.
+//
+// The checks create, update and delete records under Config.Domain, named
+// after the check, and delete them when they fail.
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	webhookapi "sigs.k8s.io/external-dns/provider/webhook/api"
+)
+
+// DefaultRecords is the number of records of the large batch.
+const DefaultRecords = 1000
+
+// Config is the webhook provider to check.
+type Config struct {
+	// URL is the base URL of the webhook provider.
+	URL string
+	// Domain is the domain of the records of the checks. It defaults to the
+	// first domain of the domain filter of the provider.
+	Domain string
+	// Records is the number of records of the large batch, DefaultRecords if
+	// zero. The check is skipped if negative.
+	Records int
+	// Client sends the requests, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// Result is the result of a check.
+type Result struct {
+	Name    string
+	Err     error
+	Skipped bool
+}
+
+// Passed returns true if all the checks passed or were skipped.
+func Passed(results []Result) bool {
+	for _, r := range results {
+		if r.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// check is a check of the suite, in the order they run.
+type check struct {
+	name string
+	run  func(ctx context.Context, s *suite) error
+}
+
+var checks = []check{
+	{"negotiate", checkNegotiate},
+	{"records", checkRecords},
+	{"round-trip", checkRoundTrip},
+	{"adjustendpoints", checkAdjustEndpoints},
+	{"errors", checkErrors},
+	{"large-payload", checkLargePayload},
+}
+
+// errSkipped skips a check.
+var errSkipped = errors.New("skipped")
+
+type suite struct {
+	cfg    Config
+	base   *url.URL
+	client *http.Client
+	// run identifies the records of a run, so that runs don't conflict.
+	run string
+}
+
+// Run runs the checks against the webhook provider, in order. The checks
+// after a failed negotiation are not run: their results have its error.
+func Run(ctx context.Context, cfg Config) []Result {
+	s := &suite{cfg: cfg, client: cfg.Client}
+	if s.client == nil {
+		s.client = http.DefaultClient
+	}
+	if s.cfg.Records == 0 {
+		s.cfg.Records = DefaultRecords
+	}
+	results := make([]Result, 0, len(checks))
+	base, err := url.Parse(cfg.URL)
+	if err == nil {
+		s.base = base
+		s.run, err = newRunID()
+	}
+	for _, c := range checks {
+		if err != nil {
+			results = append(results, Result{Name: c.name, Err: err})
+			continue
+		}
+		cerr := c.run(ctx, s)
+		if errors.Is(cerr, errSkipped) {
+			results = append(results, Result{Name: c.name, Skipped: true})
+			continue
+		}
+		results = append(results, Result{Name: c.name, Err: cerr})
+		if c.name == "negotiate" && cerr != nil {
+			err = fmt.Errorf("negotiation failed: %w", cerr)
+		}
+	}
+	return results
+}
+
+func newRunID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// name returns the name of a record of a check.
+func (s *suite) name(check string, i int) string {
+	return fmt.Sprintf("%s-%s-%d.%s", check, s.run, i, s.cfg.Domain)
+}
+
+// do sends a request to the path of the provider and returns the status, the
+// content type and the body of the response.
+func (s *suite) do(ctx context.Context, method, path string, body []byte) (int, string, []byte, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.base.JoinPath(path).String(), r)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	req.Header.Set("Accept", webhookapi.MediaTypeFormatAndVersion)
+	if body != nil {
+		req.Header.Set(webhookapi.ContentTypeHeader, webhookapi.MediaTypeFormatAndVersion)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	return resp.StatusCode, resp.Header.Get(webhookapi.ContentTypeHeader), b, nil
+}
+
+// decode sends a request that must succeed with a JSON body of the protocol
+// media type, and decodes the body into v.
+func (s *suite) decode(ctx context.Context, method, path string, body []byte, v any) error {
+	status, contentType, b, err := s.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("%s %s returned %d, expected %d", method, path, status, http.StatusOK)
+	}
+	if contentType != webhookapi.MediaTypeFormatAndVersion {
+		return fmt.Errorf("%s %s returned the content type %q, expected %q", method, path, contentType, webhookapi.MediaTypeFormatAndVersion)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%s %s returned an invalid body: %w", method, path, err)
+	}
+	return nil
+}
+
+func (s *suite) records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	records := []*endpoint.Endpoint{}
+	return records, s.decode(ctx, http.MethodGet, "records", nil, &records)
+}
+
+func (s *suite) adjust(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	body, err := json.Marshal(endpoints)
+	if err != nil {
+		return nil, err
+	}
+	adjusted := []*endpoint.Endpoint{}
+	return adjusted, s.decode(ctx, http.MethodPost, "adjustendpoints", body, &adjusted)
+}
+
+// apply applies the changes, which must succeed with 204.
+func (s *suite) apply(ctx context.Context, changes *plan.Changes) error {
+	body, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	status, _, _, err := s.do(ctx, http.MethodPost, "records", body)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusNoContent {
+		return fmt.Errorf("POST records returned %d, expected %d", status, http.StatusNoContent)
+	}
+	return nil
+}
+
+// cleanup deletes the records of the run left by a failed check.
+func (s *suite) cleanup(ctx context.Context, check string) {
+	records, err := s.records(ctx)
+	if err != nil {
+		return
+	}
+	prefix := check + "-" + s.run + "-"
+	changes := &plan.Changes{}
+	for _, r := range records {
+		if strings.HasPrefix(r.DNSName, prefix) {
+			changes.Delete = append(changes.Delete, r)
+		}
+	}
+	if len(changes.Delete) > 0 {
+		_ = s.apply(ctx, changes)
+	}
+}
+
+// find returns the records of the name and the record type.
+func find(records []*endpoint.Endpoint, name, recordType string) *endpoint.Endpoint {
+	for _, r := range records {
+		if r.DNSName == name && r.RecordType == recordType {
+			return r
+		}
+	}
+	return nil
+}
+
+// checkNegotiate checks that the provider returns its domain filter with the
+// protocol media type.
+func checkNegotiate(ctx context.Context, s *suite) error {
+	df := endpoint.DomainFilter{}
+	if err := s.decode(ctx, http.MethodGet, "", nil, &df); err != nil {
+		return err
+	}
+	if s.cfg.Domain == "" {
+		if len(df.Filters) == 0 || df.Filters[0] == "" {
+			return fmt.Errorf("the domain filter of the provider has no domain, the domain of the checks must be configured")
+		}
+		s.cfg.Domain = strings.TrimPrefix(df.Filters[0], ".")
+	}
+	if df.IsConfigured() && !df.Match(s.name("negotiate", 0)) {
+		return fmt.Errorf("the domain %s is not matched by the domain filter of the provider", s.cfg.Domain)
+	}
+	return nil
+}
+
+// checkRecords checks that the provider lists its records as a JSON array.
+func checkRecords(ctx context.Context, s *suite) error {
+	_, err := s.records(ctx)
+	return err
+}
+
+// checkRoundTrip creates, updates and deletes records, which must be listed
+// with the same targets after each change: the TXT registry relies on it.
+func checkRoundTrip(ctx context.Context, s *suite) (err error) {
+	defer func() {
+		if err != nil {
+			s.cleanup(ctx, "round-trip")
+		}
+	}()
+	name := s.name("round-trip", 0)
+	desired, err := s.adjust(ctx, []*endpoint.Endpoint{
+		endpoint.NewEndpoint(name, endpoint.RecordTypeA, "192.0.2.1"),
+		endpoint.NewEndpoint(name, endpoint.RecordTypeTXT, "\"heritage=external-dns,external-dns/owner=conformance\""),
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.apply(ctx, &plan.Changes{Create: desired}); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	created, err := s.expect(ctx, desired)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	updated := make([]*endpoint.Endpoint, 0, len(created))
+	for _, r := range created {
+		u := r.DeepCopy()
+		if u.RecordType == endpoint.RecordTypeA {
+			u.Targets = endpoint.Targets{"192.0.2.2"}
+		}
+		updated = append(updated, u)
+	}
+	if err := s.apply(ctx, &plan.Changes{UpdateOld: created, UpdateNew: updated}); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	if updated, err = s.expect(ctx, updated); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	if err := s.apply(ctx, &plan.Changes{Delete: updated}); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	records, err := s.records(ctx)
+	if err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	for _, r := range updated {
+		if find(records, r.DNSName, r.RecordType) != nil {
+			return fmt.Errorf("delete: the %s record %s is still listed", r.RecordType, r.DNSName)
+		}
+	}
+	return nil
+}
+
+// expect returns the listed records of the expected ones, which must have
+// the same targets.
+func (s *suite) expect(ctx context.Context, expected []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	records, err := s.records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	listed := make([]*endpoint.Endpoint, 0, len(expected))
+	for _, e := range expected {
+		r := find(records, e.DNSName, e.RecordType)
+		if r == nil {
+			return nil, fmt.Errorf("the %s record %s is not listed", e.RecordType, e.DNSName)
+		}
+		if !r.Targets.Same(e.Targets) {
+			return nil, fmt.Errorf("the %s record %s is listed with the targets %s, expected %s", e.RecordType, e.DNSName, r.Targets, e.Targets)
+		}
+		listed = append(listed, r)
+	}
+	return listed, nil
+}
+
+// checkAdjustEndpoints checks that AdjustEndpoints returns the endpoints of
+// the given names only, and is idempotent: the controller adjusts the desired
+// endpoints at each sync, so adjusting them again must not change them.
+func checkAdjustEndpoints(ctx context.Context, s *suite) error {
+	adjusted, err := s.adjust(ctx, []*endpoint.Endpoint{})
+	if err != nil {
+		return err
+	}
+	if len(adjusted) != 0 {
+		return fmt.Errorf("adjusting no endpoints returned %d endpoints", len(adjusted))
+	}
+
+	names := map[string]bool{}
+	endpoints := []*endpoint.Endpoint{
+		endpoint.NewEndpoint(s.name("adjustendpoints", 0), endpoint.RecordTypeA, "192.0.2.1", "192.0.2.2"),
+		endpoint.NewEndpoint(s.name("adjustendpoints", 1), endpoint.RecordTypeCNAME, "target.example.org"),
+		endpoint.NewEndpointWithTTL(s.name("adjustendpoints", 2), endpoint.RecordTypeTXT, 300, "\"heritage=external-dns\""),
+	}
+	for _, e := range endpoints {
+		names[e.DNSName] = true
+	}
+	if adjusted, err = s.adjust(ctx, endpoints); err != nil {
+		return err
+	}
+	for _, e := range adjusted {
+		if !names[e.DNSName] {
+			return fmt.Errorf("AdjustEndpoints returned the endpoint %s, which was not given", e.DNSName)
+		}
+	}
+	again, err := s.adjust(ctx, adjusted)
+	if err != nil {
+		return err
+	}
+	a, _ := json.Marshal(adjusted)
+	b, _ := json.Marshal(again)
+	if !bytes.Equal(a, b) {
+		return fmt.Errorf("AdjustEndpoints is not idempotent: %s became %s", a, b)
+	}
+	return nil
+}
+
+// checkErrors checks that the invalid requests are rejected with a client
+// error: the server errors are retried by ExternalDNS.
+func checkErrors(ctx context.Context, s *suite) error {
+	for _, r := range []struct {
+		method, path string
+		body         []byte
+	}{
+		{http.MethodPost, "records", []byte("{invalid")},
+		{http.MethodPost, "adjustendpoints", []byte("{invalid")},
+		{http.MethodPut, "records", []byte("{}")},
+		{http.MethodGet, "adjustendpoints", nil},
+	} {
+		status, _, _, err := s.do(ctx, r.method, r.path, r.body)
+		if err != nil {
+			return err
+		}
+		if status < http.StatusBadRequest || status >= http.StatusInternalServerError {
+			return fmt.Errorf("%s %s with an invalid request returned %d, expected a client error", r.method, r.path, status)
+		}
+	}
+	return nil
+}
+
+// checkLargePayload creates, lists and deletes Config.Records records in one
+// batch each, like the first sync of a large cluster.
+func checkLargePayload(ctx context.Context, s *suite) (err error) {
+	if s.cfg.Records < 0 {
+		return errSkipped
+	}
+	defer func() {
+		if err != nil {
+			s.cleanup(ctx, "large-payload")
+		}
+	}()
+	endpoints := make([]*endpoint.Endpoint, 0, s.cfg.Records)
+	for i := 0; i < s.cfg.Records; i++ {
+		endpoints = append(endpoints, endpoint.NewEndpoint(s.name("large-payload", i), endpoint.RecordTypeA, fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff)))
+	}
+	desired, err := s.adjust(ctx, endpoints)
+	if err != nil {
+		return err
+	}
+	if err := s.apply(ctx, &plan.Changes{Create: desired}); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	created, err := s.expect(ctx, desired)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if err := s.apply(ctx, &plan.Changes{Delete: created}); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conformance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+	webhookapi "sigs.k8s.io/external-dns/provider/webhook/api"
+)
+
+// growingProvider adds a target at each AdjustEndpoints.
+type growingProvider struct {
+	*inmemory.InMemoryProvider
+}
+
+func (p growingProvider) AdjustEndpoints(endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	for _, e := range endpoints {
+		e.Targets = append(e.Targets, "192.0.2.255")
+	}
+	return endpoints, nil
+}
+
+func newServer(t *testing.T, p provider.Provider) *httptest.Server {
+	m := http.NewServeMux()
+	webhookapi.InitHandlers(p, m, "", webhookapi.Handlers{})
+	s := httptest.NewServer(m)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newInMemoryProvider returns a provider with the example.org zone. It has no
+// domain filter, so the domain of the checks is configured.
+func newInMemoryProvider() *inmemory.InMemoryProvider {
+	return inmemory.NewInMemoryProvider(
+		inmemory.InMemoryInitZones([]string{"example.org"}),
+		inmemory.InMemoryWithDomain(endpoint.NewDomainFilter([]string{"example.org"})),
+	)
+}
+
+func failures(results []Result) map[string]string {
+	errs := map[string]string{}
+	for _, r := range results {
+		if r.Err != nil {
+			errs[r.Name] = r.Err.Error()
+		}
+	}
+	return errs
+}
+
+func TestRun(t *testing.T) {
+	p := newInMemoryProvider()
+	s := newServer(t, p)
+
+	results := Run(context.Background(), Config{URL: s.URL, Domain: "example.org", Records: 100})
+
+	assert.Empty(t, failures(results))
+	assert.True(t, Passed(results))
+	require.Len(t, results, len(checks))
+	records, err := p.Records(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, records, "the records of the checks are deleted")
+}
+
+func TestRunSkipped(t *testing.T) {
+	s := newServer(t, newInMemoryProvider())
+
+	results := Run(context.Background(), Config{URL: s.URL, Domain: "example.org", Records: -1})
+
+	assert.True(t, Passed(results))
+	assert.Equal(t, Result{Name: "large-payload", Skipped: true}, results[len(results)-1])
+}
+
+func TestRunNegotiationFailed(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(webhookapi.ContentTypeHeader, "application/json")
+		w.Write([]byte("{}"))
+	}))
+	defer s.Close()
+
+	results := Run(context.Background(), Config{URL: s.URL, Domain: "example.org"})
+
+	assert.False(t, Passed(results))
+	errs := failures(results)
+	assert.Len(t, errs, len(checks))
+	assert.Contains(t, errs["negotiate"], "content type")
+	assert.Contains(t, errs["records"], "negotiation failed")
+}
+
+func TestRunNoDomain(t *testing.T) {
+	s := newServer(t, inmemory.NewInMemoryProvider())
+
+	results := Run(context.Background(), Config{URL: s.URL})
+
+	assert.Contains(t, failures(results)["negotiate"], "must be configured")
+}
+
+func TestRunAdjustEndpointsNotIdempotent(t *testing.T) {
+	s := newServer(t, growingProvider{newInMemoryProvider()})
+
+	results := Run(context.Background(), Config{URL: s.URL, Domain: "example.org", Records: -1})
+
+	errs := failures(results)
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs["adjustendpoints"], "not idempotent")
+}
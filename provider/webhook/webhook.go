@@ -19,12 +19,15 @@ package webhook
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 
 	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
 	"sigs.k8s.io/external-dns/plan"
 	"sigs.k8s.io/external-dns/provider"
 	webhookapi "sigs.k8s.io/external-dns/provider/webhook/api"
@@ -160,6 +163,61 @@ func NewWebhookProvider(u string) (*WebhookProvider, error) {
 
 // Records will make a GET call to remoteServerURL/records and return the results
 func (p WebhookProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	resp, err := p.getRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	endpoints := []*endpoint.Endpoint{}
+	if err := json.NewDecoder(resp.Body).Decode(&endpoints); err != nil {
+		recordsErrorsGauge.Inc()
+		log.Debugf("Failed to decode response body: %s", err.Error())
+		return nil, err
+	}
+	// The decoded strings are allocated for each record, the repeated ones
+	// are shared instead.
+	in := endpoint.NewInterner()
+	for _, ep := range endpoints {
+		in.Endpoint(ep)
+	}
+	return endpoints, nil
+}
+
+// StreamRecords will make a GET call to remoteServerURL/records and call fn
+// with each record as it is decoded, without holding the whole array.
+func (p WebhookProvider) StreamRecords(ctx context.Context, fn func(*endpoint.Endpoint) error) error {
+	resp, err := p.getRecords(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	if _, err := dec.Token(); err != nil {
+		recordsErrorsGauge.Inc()
+		log.Debugf("Failed to decode response body: %s", err.Error())
+		return err
+	}
+	in := endpoint.NewInterner()
+	for dec.More() {
+		ep := &endpoint.Endpoint{}
+		if err := dec.Decode(ep); err != nil {
+			recordsErrorsGauge.Inc()
+			log.Debugf("Failed to decode response body: %s", err.Error())
+			return err
+		}
+		in.Endpoint(ep)
+		if err := fn(ep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getRecords makes the GET call to remoteServerURL/records, returning the
+// response to decode if it is successful.
+func (p WebhookProvider) getRecords(ctx context.Context) (*http.Response, error) {
 	recordsRequestsGauge.Inc()
 	u := p.remoteServerURL.JoinPath("records").String()
 
@@ -170,15 +228,16 @@ func (p WebhookProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, err
 		return nil, err
 	}
 	req.Header.Set(acceptHeader, webhookapi.MediaTypeFormatAndVersion)
+	logging.SetTraceHeader(ctx, req.Header)
 	resp, err := p.client.Do(req)
 	if err != nil {
 		recordsErrorsGauge.Inc()
 		log.Debugf("Failed to perform request: %s", err.Error())
 		return nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
 		recordsErrorsGauge.Inc()
 		log.Debugf("Failed to get records with code %d", resp.StatusCode)
 		err := fmt.Errorf("failed to get records with code %d", resp.StatusCode)
@@ -187,17 +246,12 @@ func (p WebhookProvider) Records(ctx context.Context) ([]*endpoint.Endpoint, err
 		}
 		return nil, err
 	}
-
-	endpoints := []*endpoint.Endpoint{}
-	if err := json.NewDecoder(resp.Body).Decode(&endpoints); err != nil {
-		recordsErrorsGauge.Inc()
-		log.Debugf("Failed to decode response body: %s", err.Error())
-		return nil, err
-	}
-	return endpoints, nil
+	return resp, nil
 }
 
-// ApplyChanges will make a POST to remoteServerURL/records with the changes
+// ApplyChanges will make a POST to remoteServerURL/records with the changes.
+// The request is retried, on a connection error or a server error, with the
+// same idempotency key: the server applies the changes once.
 func (p WebhookProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
 	applyChangesRequestsGauge.Inc()
 	u := p.remoteServerURL.JoinPath("records").String()
@@ -208,29 +262,46 @@ func (p WebhookProvider) ApplyChanges(ctx context.Context, changes *plan.Changes
 		log.Debugf("Failed to encode changes: %s", err.Error())
 		return err
 	}
-
-	req, err := http.NewRequest("POST", u, b)
+	key, err := newIdempotencyKey()
 	if err != nil {
 		applyChangesErrorsGauge.Inc()
-		log.Debugf("Failed to create request: %s", err.Error())
 		return err
 	}
 
-	req.Header.Set(webhookapi.ContentTypeHeader, webhookapi.MediaTypeFormatAndVersion)
+	var statusCode int
+	err = backoff.Retry(func() error {
+		statusCode = 0
+		req, err := http.NewRequest("POST", u, bytes.NewReader(b.Bytes()))
+		if err != nil {
+			log.Debugf("Failed to create request: %s", err.Error())
+			return backoff.Permanent(err)
+		}
+		req.Header.Set(webhookapi.ContentTypeHeader, webhookapi.MediaTypeFormatAndVersion)
+		req.Header.Set(webhookapi.IdempotencyKeyHeader, key)
+		logging.SetTraceHeader(ctx, req.Header)
 
-	resp, err := p.client.Do(req)
-	if err != nil {
+		resp, err := p.client.Do(req)
+		if err != nil {
+			log.Debugf("Failed to perform request: %s", err.Error())
+			return err
+		}
+		resp.Body.Close()
+		statusCode = resp.StatusCode
+		if isRetryableError(statusCode) {
+			return fmt.Errorf("failed to apply changes with code %d", statusCode)
+		}
+		return nil
+	}, backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), maxRetries), ctx))
+	if err != nil && statusCode == 0 {
 		applyChangesErrorsGauge.Inc()
-		log.Debugf("Failed to perform request: %s", err.Error())
 		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusNoContent {
+	if statusCode != http.StatusNoContent {
 		applyChangesErrorsGauge.Inc()
-		log.Debugf("Failed to apply changes with code %d", resp.StatusCode)
-		err := fmt.Errorf("failed to apply changes with code %d", resp.StatusCode)
-		if isRetryableError(resp.StatusCode) {
+		log.Debugf("Failed to apply changes with code %d", statusCode)
+		err := fmt.Errorf("failed to apply changes with code %d", statusCode)
+		if isRetryableError(statusCode) {
 			return provider.NewSoftError(err)
 		}
 		return err
@@ -238,6 +309,15 @@ func (p WebhookProvider) ApplyChanges(ctx context.Context, changes *plan.Changes
 	return nil
 }
 
+// newIdempotencyKey returns a random key identifying a batch of changes.
+func newIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 // AdjustEndpoints will call the provider doing a POST on `/adjustendpoints` which will return a list of modified endpoints
 // based on a provider specific requirement.
 // This method returns an empty slice in case there is a technical error on the provider's side so that no endpoints will be considered.
@@ -295,6 +375,45 @@ func (p WebhookProvider) AdjustEndpoints(e []*endpoint.Endpoint) ([]*endpoint.En
 	return endpoints, nil
 }
 
+// Changes will make a GET call to remoteServerURL/changes with the cursor, and
+// implements provider.Journal. A webhook without a journal expires every
+// cursor, so the records are listed at each sync.
+func (p WebhookProvider) Changes(ctx context.Context, cursor string) (*provider.JournalChanges, error) {
+	u := p.remoteServerURL.JoinPath("changes")
+	u.RawQuery = url.Values{"cursor": {cursor}}.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(acceptHeader, webhookapi.MediaTypeFormatAndVersion)
+	logging.SetTraceHeader(ctx, req.Header)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		log.Debugf("Failed to perform request: %s", err.Error())
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return nil, provider.ErrJournalExpired
+	case resp.StatusCode != http.StatusOK:
+		err := fmt.Errorf("failed to get changes with code %d", resp.StatusCode)
+		if isRetryableError(resp.StatusCode) {
+			return nil, provider.NewSoftError(err)
+		}
+		return nil, err
+	}
+
+	changes := &provider.JournalChanges{}
+	if err := json.NewDecoder(resp.Body).Decode(changes); err != nil {
+		log.Debugf("Failed to decode response body: %s", err.Error())
+		return nil, err
+	}
+	return changes, nil
+}
+
 // GetDomainFilter make calls to get the serialized version of the domain filter
 func (p WebhookProvider) GetDomainFilter() endpoint.DomainFilter {
 	return p.DomainFilter
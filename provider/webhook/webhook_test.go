@@ -26,6 +26,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
 	"sigs.k8s.io/external-dns/plan"
 	"sigs.k8s.io/external-dns/provider"
 	webhookapi "sigs.k8s.io/external-dns/provider/webhook/api"
@@ -73,6 +74,7 @@ func TestRecords(t *testing.T) {
 			return
 		}
 		require.Equal(t, "/records", r.URL.Path)
+		require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", r.Header.Get("traceparent"))
 		w.Write([]byte(`[{
 			"dnsName" : "test.example.com"
 		}]`))
@@ -81,7 +83,8 @@ func TestRecords(t *testing.T) {
 
 	provider, err := NewWebhookProvider(svr.URL)
 	require.NoError(t, err)
-	endpoints, err := provider.Records(context.TODO())
+	ctx := logging.WithTrace(context.TODO(), "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7")
+	endpoints, err := provider.Records(ctx)
 	require.NoError(t, err)
 	require.NotNil(t, endpoints)
 	require.Equal(t, []*endpoint.Endpoint{{
@@ -89,6 +92,54 @@ func TestRecords(t *testing.T) {
 	}}, endpoints)
 }
 
+func TestRecordsWithSetIdentifier(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(webhookapi.ContentTypeHeader, webhookapi.MediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(`[
+			{"dnsName": "app.example.com", "recordType": "A", "targets": ["10.0.0.1"], "setIdentifier": "blue", "providerSpecific": [{"name": "google/weight", "value": "10"}]},
+			{"dnsName": "app.example.com", "recordType": "A", "targets": ["10.0.0.2"], "setIdentifier": "green", "providerSpecific": [{"name": "google/weight", "value": "90"}]}
+		]`))
+	}))
+	defer svr.Close()
+
+	provider, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+	endpoints, err := provider.Records(context.TODO())
+	require.NoError(t, err)
+	// The endpoints of the same name and type with different set identifiers are kept apart.
+	require.Len(t, endpoints, 2)
+	require.Equal(t, "blue", endpoints[0].SetIdentifier)
+	require.Equal(t, "green", endpoints[1].SetIdentifier)
+	require.NotEqual(t, endpoints[0].Key(), endpoints[1].Key())
+}
+
+func TestStreamRecords(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(webhookapi.ContentTypeHeader, webhookapi.MediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		require.Equal(t, "/records", r.URL.Path)
+		w.Write([]byte(`[{"dnsName" : "a.example.com"}, {"dnsName" : "b.example.com"}]`))
+	}))
+	defer svr.Close()
+
+	p, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+	var names []string
+	err = p.StreamRecords(context.Background(), func(ep *endpoint.Endpoint) error {
+		names = append(names, ep.DNSName)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.example.com", "b.example.com"}, names)
+}
+
 func TestRecordsWithErrors(t *testing.T) {
 	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/" {
@@ -108,6 +159,35 @@ func TestRecordsWithErrors(t *testing.T) {
 	require.ErrorIs(t, err, provider.SoftError)
 }
 
+func TestChanges(t *testing.T) {
+	journal := true
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(webhookapi.ContentTypeHeader, webhookapi.MediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		require.Equal(t, "/changes", r.URL.Path)
+		if !journal {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		require.Equal(t, "zone=1", r.URL.Query().Get("cursor"))
+		w.Write([]byte(`{"names": ["test.example.com"], "cursor": "zone=2"}`))
+	}))
+	defer svr.Close()
+
+	p, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+	changes, err := p.Changes(context.Background(), "zone=1")
+	require.NoError(t, err)
+	require.Equal(t, &provider.JournalChanges{Names: []string{"test.example.com"}, Cursor: "zone=2"}, changes)
+
+	journal = false
+	_, err = p.Changes(context.Background(), "zone=1")
+	require.ErrorIs(t, err, provider.ErrJournalExpired)
+}
+
 func TestApplyChanges(t *testing.T) {
 	successfulApplyChanges := true
 	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -137,6 +217,35 @@ func TestApplyChanges(t *testing.T) {
 	require.ErrorIs(t, err, provider.SoftError)
 }
 
+func TestApplyChangesRetriesWithIdempotencyKey(t *testing.T) {
+	var keys []string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set(webhookapi.ContentTypeHeader, webhookapi.MediaTypeFormatAndVersion)
+			w.Write([]byte(`{}`))
+			return
+		}
+		keys = append(keys, r.Header.Get(webhookapi.IdempotencyKeyHeader))
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer svr.Close()
+
+	p, err := NewWebhookProvider(svr.URL)
+	require.NoError(t, err)
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{}))
+	require.Len(t, keys, 2)
+	require.NotEmpty(t, keys[0])
+	require.Equal(t, keys[0], keys[1], "the retry has the key of the first request")
+
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{}))
+	require.Len(t, keys, 3)
+	require.NotEqual(t, keys[0], keys[2], "each batch of changes has its own key")
+}
+
 func TestAdjustEndpoints(t *testing.T) {
 	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/" {
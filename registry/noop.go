@@ -49,6 +49,21 @@ func (im *NoopRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, erro
 	return im.provider.Records(ctx)
 }
 
+// RecordsShard returns the records of the shard, streamed from the dns provider
+func (im *NoopRegistry) RecordsShard(ctx context.Context, shard, shards int) ([]*endpoint.Endpoint, error) {
+	records := []*endpoint.Endpoint{}
+	err := provider.StreamRecords(ctx, im.provider, func(ep *endpoint.Endpoint) error {
+		if plan.Shard(ep.DNSName, shards) == shard {
+			records = append(records, ep)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
 // ApplyChanges propagates changes to the dns provider
 func (im *NoopRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
 	return im.provider.ApplyChanges(ctx, changes)
@@ -34,3 +34,23 @@ type Registry interface {
 	GetDomainFilter() endpoint.DomainFilter
 	OwnerID() string
 }
+
+// ShardedRegistry is implemented by the registries which can read the records
+// of a shard of the names (see plan.Shard) streaming the provider records, so
+// the records of the other shards are not held in memory.
+type ShardedRegistry interface {
+	RecordsShard(ctx context.Context, shard, shards int) ([]*endpoint.Endpoint, error)
+}
+
+// RecordsShard returns the records of the shard - read by r if it is a
+// ShardedRegistry, filtered from all its records otherwise.
+func RecordsShard(ctx context.Context, r Registry, shard, shards int) ([]*endpoint.Endpoint, error) {
+	if sr, ok := r.(ShardedRegistry); ok {
+		return sr.RecordsShard(ctx, shard, shards)
+	}
+	records, err := r.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return plan.ShardEndpoints(records, shard, shards), nil
+}
@@ -40,6 +40,14 @@ type TXTRegistry struct {
 	ownerID  string // refers to the owner id of the current instance
 	mapper   nameMapper
 
+	// the formats of the TXT records of zones, the most specific zone first
+	formats       []zoneFormat
+	encodedMapper encodedNameMapper
+	// the encoded TXT records read from the provider, and the TXT records
+	// of the names of the encoded zones written in the other formats
+	encoded   map[encodedKey]*endpoint.Endpoint
+	legacyTXT map[encodedKey][]*endpoint.Endpoint
+
 	// cache the records in memory and update on an interval instead.
 	recordsCache            []*endpoint.Endpoint
 	recordsCacheRefreshTime time.Time
@@ -82,6 +90,9 @@ func NewTXTRegistry(provider provider.Provider, txtPrefix, txtSuffix, ownerID st
 		provider:            provider,
 		ownerID:             ownerID,
 		mapper:              mapper,
+		encodedMapper:       newEncodedNameMapper(txtPrefix, txtSuffix, txtWildcardReplacement),
+		encoded:             map[encodedKey]*endpoint.Endpoint{},
+		legacyTXT:           map[encodedKey][]*endpoint.Endpoint{},
 		cacheInterval:       cacheInterval,
 		wildcardReplacement: txtWildcardReplacement,
 		managedRecordTypes:  managedRecordTypes,
@@ -118,17 +129,94 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 	if err != nil {
 		return nil, err
 	}
+	im.encoded = map[encodedKey]*endpoint.Endpoint{}
+	im.legacyTXT = map[encodedKey][]*endpoint.Endpoint{}
+	endpoints, err := im.ownedRecords(records)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the cache.
+	if im.cacheInterval > 0 {
+		im.recordsCache = endpoints
+		im.recordsCacheRefreshTime = time.Now()
+	}
+
+	return endpoints, nil
+}
+
+// Flush flushes the cache of the records: the next Records lists the records
+// of the provider.
+func (im *TXTRegistry) Flush() {
+	im.recordsCache = nil
+}
 
+// RecordsShard returns the records of the shard, excluding TXT records, with
+// the labels of their TXT records. The provider records are streamed, keeping
+// the records and the TXT records of the names of the shard. The cache is not
+// used.
+func (im *TXTRegistry) RecordsShard(ctx context.Context, shard, shards int) ([]*endpoint.Endpoint, error) {
+	records := []*endpoint.Endpoint{}
+	err := provider.StreamRecords(ctx, im.provider, func(ep *endpoint.Endpoint) error {
+		if plan.Shard(ep.DNSName, shards) == shard || (ep.RecordType == endpoint.RecordTypeTXT && plan.Shard(im.ownedName(ep.DNSName), shards) == shard) {
+			records = append(records, ep)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := im.ownedRecords(records)
+	if err != nil {
+		return nil, err
+	}
+	// The TXT records of the shard which are not registry records.
+	return plan.ShardEndpoints(endpoints, shard, shards), nil
+}
+
+// ownedName returns the name of the record owned by a TXT record.
+func (im *TXTRegistry) ownedName(txtName string) string {
+	name, _ := im.toEndpointName(txtName)
+	if im.wildcardReplacement != "" {
+		if first, rest, ok := strings.Cut(name, "."); ok && strings.EqualFold(first, im.wildcardReplacement) {
+			return "*." + rest
+		}
+	}
+	return name
+}
+
+// ownedRecords returns the records excluding TXT records, with the labels of
+// their TXT records.
+func (im *TXTRegistry) ownedRecords(records []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
 	endpoints := []*endpoint.Endpoint{}
 
 	labelMap := map[endpoint.EndpointKey]endpoint.Labels{}
 	txtRecordsMap := map[string]struct{}{}
+	// the keys of the records with an encoded TXT record
+	encodedMap := map[endpoint.EndpointKey]struct{}{}
 
 	for _, record := range records {
 		if record.RecordType != endpoint.RecordTypeTXT {
 			endpoints = append(endpoints, record)
 			continue
 		}
+		if im.encodedFormat(record.DNSName) {
+			if payload, ok := decodeTXT(record.Targets[0]); ok {
+				endpointName, _ := im.toEndpointName(record.DNSName)
+				for recordType, labels := range payload {
+					key := endpoint.EndpointKey{
+						DNSName:       endpointName,
+						RecordType:    recordType,
+						SetIdentifier: record.SetIdentifier,
+					}
+					labelMap[key] = labels
+					encodedMap[key] = struct{}{}
+				}
+				im.encoded[encodedKey{txtName: strings.ToLower(record.DNSName), setIdentifier: record.SetIdentifier}] = record
+				txtRecordsMap[record.DNSName] = struct{}{}
+				continue
+			}
+		}
 		// We simply assume that TXT records for the registry will always have only one target.
 		labels, err := endpoint.NewLabelsFromString(record.Targets[0], im.txtEncryptAESKey)
 		if err == endpoint.ErrInvalidHeritage {
@@ -142,14 +230,21 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 			return nil, err
 		}
 
-		endpointName, recordType := im.mapper.toEndpointName(record.DNSName)
+		endpointName, recordType := im.toEndpointName(record.DNSName)
 		key := endpoint.EndpointKey{
 			DNSName:       endpointName,
 			RecordType:    recordType,
 			SetIdentifier: record.SetIdentifier,
 		}
-		labelMap[key] = labels
+		if _, encoded := encodedMap[key]; !encoded {
+			labelMap[key] = labels
+		}
 		txtRecordsMap[record.DNSName] = struct{}{}
+		// deleted once the encoded TXT record of the name is written
+		if im.encodedFormat(record.DNSName) && labels[endpoint.OwnerLabelKey] == im.ownerID {
+			legacyKey := encodedKey{txtName: im.encodedMapper.toTXTName(endpointName), setIdentifier: record.SetIdentifier}
+			im.legacyTXT[legacyKey] = append(im.legacyTXT[legacyKey], record)
+		}
 	}
 
 	for _, ep := range endpoints {
@@ -189,6 +284,13 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 		// The migration is done for the TXT records owned by this instance only.
 		if len(txtRecordsMap) > 0 && ep.Labels[endpoint.OwnerLabelKey] == im.ownerID {
 			if plan.IsManagedRecord(ep.RecordType, im.managedRecordTypes, im.excludeRecordTypes) {
+				if im.encodedFormat(ep.DNSName) {
+					// The records without an encoded TXT record are updated to write it.
+					key.RecordType = ownershipRecordType(ep)
+					if _, exists := encodedMap[key]; !exists {
+						ep.WithProviderSpecific(providerSpecificForceUpdate, "true")
+					}
+				}
 				// Get desired TXT records and detect the missing ones
 				desiredTXTs := im.generateTXTRecord(ep)
 				for _, desiredTXT := range desiredTXTs {
@@ -200,23 +302,23 @@ func (im *TXTRegistry) Records(ctx context.Context) ([]*endpoint.Endpoint, error
 		}
 	}
 
-	// Update the cache.
-	if im.cacheInterval > 0 {
-		im.recordsCache = endpoints
-		im.recordsCacheRefreshTime = time.Now()
-	}
-
 	return endpoints, nil
 }
 
 // generateTXTRecord generates both "old" and "new" TXT records.
 // Once we decide to drop old format we need to drop toTXTName() and rename toNewTXTName
+// The records of the encoded zones have a single TXT record per name, see
+// encodedChanges.
 func (im *TXTRegistry) generateTXTRecord(r *endpoint.Endpoint) []*endpoint.Endpoint {
 	endpoints := make([]*endpoint.Endpoint, 0)
+	if im.encodedFormat(r.DNSName) {
+		return endpoints
+	}
+	mapper := im.mapperOf(r.DNSName)
 
-	if !im.txtEncryptEnabled && !im.mapper.recordTypeInAffix() && r.RecordType != endpoint.RecordTypeAAAA {
+	if !im.txtEncryptEnabled && !mapper.recordTypeInAffix() && r.RecordType != endpoint.RecordTypeAAAA {
 		// old TXT record format
-		txt := endpoint.NewEndpoint(im.mapper.toTXTName(r.DNSName), endpoint.RecordTypeTXT, r.Labels.Serialize(true, im.txtEncryptEnabled, im.txtEncryptAESKey))
+		txt := endpoint.NewEndpoint(mapper.toTXTName(r.DNSName), endpoint.RecordTypeTXT, r.Labels.Serialize(true, im.txtEncryptEnabled, im.txtEncryptAESKey))
 		if txt != nil {
 			txt.WithSetIdentifier(r.SetIdentifier)
 			txt.Labels[endpoint.OwnedRecordLabelKey] = r.DNSName
@@ -225,12 +327,7 @@ func (im *TXTRegistry) generateTXTRecord(r *endpoint.Endpoint) []*endpoint.Endpo
 		}
 	}
 	// new TXT record format (containing record type)
-	recordType := r.RecordType
-	// AWS Alias records are encoded as type "cname"
-	if isAlias, found := r.GetProviderSpecificProperty("alias"); found && isAlias == "true" && recordType == endpoint.RecordTypeA {
-		recordType = endpoint.RecordTypeCNAME
-	}
-	txtNew := endpoint.NewEndpoint(im.mapper.toNewTXTName(r.DNSName, recordType), endpoint.RecordTypeTXT, r.Labels.Serialize(true, im.txtEncryptEnabled, im.txtEncryptAESKey))
+	txtNew := endpoint.NewEndpoint(mapper.toNewTXTName(r.DNSName, ownershipRecordType(r)), endpoint.RecordTypeTXT, r.Labels.Serialize(true, im.txtEncryptEnabled, im.txtEncryptAESKey))
 	if txtNew != nil {
 		txtNew.WithSetIdentifier(r.SetIdentifier)
 		txtNew.Labels[endpoint.OwnedRecordLabelKey] = r.DNSName
@@ -238,18 +335,27 @@ func (im *TXTRegistry) generateTXTRecord(r *endpoint.Endpoint) []*endpoint.Endpo
 		endpoints = append(endpoints, txtNew)
 	}
 
+	// the TXT records are in the zone of the record
+	if zone := r.Labels[endpoint.ZoneLabelKey]; zone != "" {
+		for _, txt := range endpoints {
+			txt.Labels[endpoint.ZoneLabelKey] = zone
+		}
+	}
+
 	return endpoints
 }
 
 // ApplyChanges updates dns provider with the changes
 // for each created/deleted record it will also take into account TXT records for creation/deletion
 func (im *TXTRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
+	updateOld, updateNew, adopted := im.ownedUpdates(changes)
 	filteredChanges := &plan.Changes{
 		Create:    changes.Create,
-		UpdateNew: endpoint.FilterEndpointsByOwnerID(im.ownerID, changes.UpdateNew),
-		UpdateOld: endpoint.FilterEndpointsByOwnerID(im.ownerID, changes.UpdateOld),
-		Delete:    endpoint.FilterEndpointsByOwnerID(im.ownerID, changes.Delete),
+		UpdateNew: updateNew,
+		UpdateOld: updateOld,
+		Delete:    ownedOrExpired(im.ownerID, changes.Delete, time.Now()),
 	}
+	encoded := im.encodedChanges(filteredChanges.Create, updateOld, updateNew, filteredChanges.Delete)
 	for _, r := range filteredChanges.Create {
 		if r.Labels == nil {
 			r.Labels = make(map[string]string)
@@ -275,10 +381,13 @@ func (im *TXTRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes)
 	}
 
 	// make sure TXT records are consistently updated as well
-	for _, r := range filteredChanges.UpdateOld {
+	for _, r := range updateOld {
 		// when we updateOld TXT records for which value has changed (due to new label) this would still work because
 		// !!! TXT record value is uniquely generated from the Labels of the endpoint. Hence old TXT record can be uniquely reconstructed
-		filteredChanges.UpdateOld = append(filteredChanges.UpdateOld, im.generateTXTRecord(r)...)
+		// An adopted record of no owner has no TXT records.
+		if r.Labels[endpoint.OwnerLabelKey] != "" {
+			filteredChanges.UpdateOld = append(filteredChanges.UpdateOld, im.generateTXTRecord(r)...)
+		}
 		// remove old version of record from cache
 		if im.cacheInterval > 0 {
 			im.removeFromCache(r)
@@ -286,19 +395,66 @@ func (im *TXTRegistry) ApplyChanges(ctx context.Context, changes *plan.Changes)
 	}
 
 	// make sure TXT records are consistently updated as well
-	for _, r := range filteredChanges.UpdateNew {
-		filteredChanges.UpdateNew = append(filteredChanges.UpdateNew, im.generateTXTRecord(r)...)
+	for _, r := range updateNew {
+		if adopted[r.Key()] {
+			filteredChanges.Create = append(filteredChanges.Create, im.generateTXTRecord(r)...)
+		} else {
+			filteredChanges.UpdateNew = append(filteredChanges.UpdateNew, im.generateTXTRecord(r)...)
+		}
 		// add new version of record to cache
 		if im.cacheInterval > 0 {
 			im.addToCache(r)
 		}
 	}
 
+	filteredChanges.Create = append(filteredChanges.Create, encoded.changes.Create...)
+	filteredChanges.UpdateOld = append(filteredChanges.UpdateOld, encoded.changes.UpdateOld...)
+	filteredChanges.UpdateNew = append(filteredChanges.UpdateNew, encoded.changes.UpdateNew...)
+	filteredChanges.Delete = append(filteredChanges.Delete, encoded.changes.Delete...)
+
 	// when caching is enabled, disable the provider from using the cache
 	if im.cacheInterval > 0 {
 		ctx = context.WithValue(ctx, provider.RecordsContextKey, nil)
 	}
-	return im.provider.ApplyChanges(ctx, filteredChanges)
+	if err := im.provider.ApplyChanges(ctx, filteredChanges); err != nil {
+		return err
+	}
+	im.applied(encoded)
+	return nil
+}
+
+// ownedOrExpired returns the records of the owner, and the records of other
+// owners whose lease expired, deleted by the instances with a lease.
+func ownedOrExpired(ownerID string, records []*endpoint.Endpoint, now time.Time) []*endpoint.Endpoint {
+	filtered := endpoint.FilterEndpointsByOwnerID(ownerID, records)
+	for _, r := range records {
+		if r.Labels[endpoint.OwnerLabelKey] != ownerID && r.Labels[endpoint.OwnerLabelKey] != "" && r.Expired(now) {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// ownedUpdates returns the updates of the records owned by the registry once
+// updated: its records, and the records of other owners, or of no owner,
+// adopted by the plan (see plan.Plan.AdoptForeign). adopted are the keys of
+// the records of no owner, without TXT records to update.
+func (im *TXTRegistry) ownedUpdates(changes *plan.Changes) (updateOld, updateNew []*endpoint.Endpoint, adopted map[endpoint.EndpointKey]bool) {
+	old := map[endpoint.EndpointKey]*endpoint.Endpoint{}
+	for _, r := range changes.UpdateOld {
+		old[r.Key()] = r
+	}
+	updateOld, updateNew, adopted = []*endpoint.Endpoint{}, []*endpoint.Endpoint{}, map[endpoint.EndpointKey]bool{}
+	for _, r := range endpoint.FilterEndpointsByOwnerID(im.ownerID, changes.UpdateNew) {
+		updateNew = append(updateNew, r)
+		if o := old[r.Key()]; o != nil {
+			updateOld = append(updateOld, o)
+			if o.Labels[endpoint.OwnerLabelKey] == "" {
+				adopted[r.Key()] = true
+			}
+		}
+	}
+	return updateOld, updateNew, adopted
 }
 
 // AdjustEndpoints modifies the endpoints as needed by the specific provider
@@ -0,0 +1,364 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+const (
+	// TXTFormatAffix is the default format of the TXT records: a TXT record
+	// per record, named with the prefix or suffix of the registry.
+	TXTFormatAffix = "affix"
+	// TXTFormatSuffix is a TXT record per record, named with a suffix of the
+	// first label of the record - TXTFormatSuffix:TEMPLATE, -%{record_type}
+	// by default.
+	TXTFormatSuffix = "suffix"
+	// TXTFormatEncoded is a single TXT record per name and set identifier,
+	// with the labels of all its records as a JSON payload, halving the
+	// records of the zones with a record per pod.
+	TXTFormatEncoded = "encoded"
+
+	defaultFormatSuffix = "-" + recordTemplate
+	// encodedRecordType replaces %{record_type} in the names of the encoded
+	// TXT records.
+	encodedRecordType = "rrset"
+	encodedHeritage   = "external-dns"
+)
+
+// zoneFormat is the format of the TXT records of a zone.
+type zoneFormat struct {
+	zone    string
+	encoded bool
+	// mapper names the TXT records of the records, if not encoded.
+	mapper nameMapper
+}
+
+// SetZoneFormats sets the format of the TXT records of the zones, as
+// ZONE=FORMAT with the domain of the zone: TXTFormatAffix, TXTFormatSuffix,
+// TXTFormatSuffix:TEMPLATE or TXTFormatEncoded. The records of the other
+// zones have the affix format.
+func (im *TXTRegistry) SetZoneFormats(values []string) error {
+	formats := []zoneFormat{}
+	for _, value := range values {
+		zone, format, ok := strings.Cut(value, "=")
+		zone = strings.ToLower(strings.Trim(zone, "."))
+		if !ok || zone == "" {
+			return fmt.Errorf("invalid TXT format %q, expected ZONE=FORMAT", value)
+		}
+		f := zoneFormat{zone: zone, mapper: im.mapper}
+		name, template, _ := strings.Cut(format, ":")
+		switch name {
+		case TXTFormatAffix:
+		case TXTFormatSuffix:
+			if template == "" {
+				template = defaultFormatSuffix
+			}
+			if !strings.Contains(template, recordTemplate) {
+				return fmt.Errorf("invalid TXT format %q, the suffix must contain %s", value, recordTemplate)
+			}
+			f.mapper = newaffixNameMapper("", template, im.wildcardReplacement)
+		case TXTFormatEncoded:
+			if im.txtEncryptEnabled {
+				return fmt.Errorf("invalid TXT format %q, the encoded TXT records can't be encrypted", value)
+			}
+			f.encoded = true
+		default:
+			return fmt.Errorf("invalid TXT format %q, expected %s, %s or %s", value, TXTFormatAffix, TXTFormatSuffix, TXTFormatEncoded)
+		}
+		formats = append(formats, f)
+	}
+	// The most specific zone first.
+	sort.SliceStable(formats, func(i, j int) bool {
+		return len(formats[i].zone) > len(formats[j].zone)
+	})
+	im.formats = formats
+	return nil
+}
+
+// formatOf returns the format of the zone of a name, nil for the default.
+func (im *TXTRegistry) formatOf(name string) *zoneFormat {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	for i := range im.formats {
+		if f := &im.formats[i]; name == f.zone || strings.HasSuffix(name, "."+f.zone) {
+			return f
+		}
+	}
+	return nil
+}
+
+// mapperOf returns the mapper of the TXT records of the zone of a name.
+func (im *TXTRegistry) mapperOf(name string) nameMapper {
+	if f := im.formatOf(name); f != nil {
+		return f.mapper
+	}
+	return im.mapper
+}
+
+// encodedFormat returns whether the TXT records of the zone of a name are
+// encoded.
+func (im *TXTRegistry) encodedFormat(name string) bool {
+	f := im.formatOf(name)
+	return f != nil && f.encoded
+}
+
+// toEndpointName returns the name and type of the record of a TXT record,
+// with the mapper of its zone, or the default mapper for the TXT records
+// written before the format of the zone was set.
+func (im *TXTRegistry) toEndpointName(txtName string) (string, string) {
+	if im.encodedFormat(txtName) {
+		if name := im.encodedMapper.toEndpointName(txtName); name != "" {
+			return name, ""
+		}
+	}
+	mapper := im.mapperOf(txtName)
+	name, recordType := mapper.toEndpointName(txtName)
+	if name == "" && mapper != im.mapper {
+		return im.mapper.toEndpointName(txtName)
+	}
+	return name, recordType
+}
+
+// ownershipRecordType returns the record type of the TXT records of a record:
+// AWS Alias records are encoded as type "cname".
+func ownershipRecordType(r *endpoint.Endpoint) string {
+	if isAlias, found := r.GetProviderSpecificProperty("alias"); found && isAlias == "true" && r.RecordType == endpoint.RecordTypeA {
+		return endpoint.RecordTypeCNAME
+	}
+	return r.RecordType
+}
+
+// encodedNameMapper names the encoded TXT records: the prefix or suffix of
+// the registry, with %{record_type} replaced by encodedRecordType.
+type encodedNameMapper struct {
+	prefix              string
+	suffix              string
+	wildcardReplacement string
+}
+
+func newEncodedNameMapper(prefix, suffix, wildcardReplacement string) encodedNameMapper {
+	prefix, suffix = strings.ToLower(prefix), strings.ToLower(suffix)
+	switch {
+	case strings.Contains(prefix, recordTemplate) || strings.Contains(suffix, recordTemplate):
+		prefix = strings.ReplaceAll(prefix, recordTemplate, encodedRecordType)
+		suffix = strings.ReplaceAll(suffix, recordTemplate, encodedRecordType)
+	case suffix == "":
+		prefix += encodedRecordType + "-"
+	default:
+		suffix = "-" + encodedRecordType + suffix
+	}
+	return encodedNameMapper{prefix: prefix, suffix: suffix, wildcardReplacement: strings.ToLower(wildcardReplacement)}
+}
+
+func (m encodedNameMapper) toTXTName(endpointDNSName string) string {
+	DNSName := strings.SplitN(strings.ToLower(endpointDNSName), ".", 2)
+	// If specified, replace a leading asterisk in the generated txt record name with some other string
+	if m.wildcardReplacement != "" && DNSName[0] == "*" {
+		DNSName[0] = m.wildcardReplacement
+	}
+	DNSName[0] = m.prefix + DNSName[0] + m.suffix
+	return strings.Join(DNSName, ".")
+}
+
+// toEndpointName returns the name of the records of an encoded TXT record,
+// empty if the name isn't the name of an encoded TXT record.
+func (m encodedNameMapper) toEndpointName(txtDNSName string) string {
+	lowerDNSName := strings.ToLower(txtDNSName)
+	if m.suffix == "" {
+		if !strings.HasPrefix(lowerDNSName, m.prefix) {
+			return ""
+		}
+		return strings.TrimPrefix(lowerDNSName, m.prefix)
+	}
+	dc := strings.Count(m.suffix, ".")
+	DNSName := strings.SplitN(lowerDNSName, ".", 2+dc)
+	if len(DNSName) < 1+dc {
+		return ""
+	}
+	domainWithSuffix := strings.Join(DNSName[:1+dc], ".")
+	if !strings.HasPrefix(domainWithSuffix, m.prefix) || !strings.HasSuffix(domainWithSuffix, m.suffix) {
+		return ""
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(domainWithSuffix, m.prefix), m.suffix)
+	if len(DNSName) < 2+dc {
+		return name
+	}
+	return name + "." + DNSName[1+dc]
+}
+
+// encodedPayload is the value of an encoded TXT record: the labels of the
+// records of its name by record type.
+type encodedPayload struct {
+	Heritage string                     `json:"heritage"`
+	Records  map[string]endpoint.Labels `json:"records"`
+}
+
+// encodeTXT returns the value of the encoded TXT record of the labels of
+// records by record type, quoted like the other TXT records.
+func encodeTXT(records map[string]endpoint.Labels) string {
+	// The keys of the maps are sorted, for consistency.
+	data, _ := json.Marshal(encodedPayload{Heritage: encodedHeritage, Records: records})
+	return strconv.Quote(string(data))
+}
+
+// decodeTXT returns the labels of the records by record type of the value of
+// an encoded TXT record, false if it isn't one.
+func decodeTXT(value string) (map[string]endpoint.Labels, bool) {
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+	if !strings.HasPrefix(value, "{") {
+		return nil, false
+	}
+	var payload encodedPayload
+	if err := json.Unmarshal([]byte(value), &payload); err != nil || payload.Heritage != encodedHeritage {
+		return nil, false
+	}
+	if payload.Records == nil {
+		payload.Records = map[string]endpoint.Labels{}
+	}
+	return payload.Records, true
+}
+
+// encodedKey is the key of an encoded TXT record.
+type encodedKey struct {
+	txtName       string
+	setIdentifier string
+}
+
+// encodedChanges are the changes of the encoded TXT records of changes.
+type encodedChanges struct {
+	changes *plan.Changes
+	// records are the encoded TXT records once applied, nil if deleted.
+	records map[encodedKey]*endpoint.Endpoint
+	// legacyTXT are the TXT records of the other formats kept.
+	legacyTXT map[encodedKey][]*endpoint.Endpoint
+}
+
+// encodedChanges returns the changes of the encoded TXT records of the
+// names of the owned records created, updated and deleted: the labels of
+// the deleted and old records are removed from the payload of their name,
+// then the labels of the created and new records are added. The TXT records
+// of the changed records written before the zone was encoded are deleted.
+func (im *TXTRegistry) encodedChanges(create, updateOld, updateNew, del []*endpoint.Endpoint) encodedChanges {
+	payloads := map[encodedKey]map[string]endpoint.Labels{}
+	owned := map[encodedKey]*endpoint.Endpoint{}
+	changed := map[encodedKey]map[string]bool{}
+	payloadOf := func(r *endpoint.Endpoint) map[string]endpoint.Labels {
+		if !im.encodedFormat(r.DNSName) {
+			return nil
+		}
+		key := encodedKey{txtName: im.encodedMapper.toTXTName(r.DNSName), setIdentifier: r.SetIdentifier}
+		records, ok := payloads[key]
+		if !ok {
+			records = map[string]endpoint.Labels{}
+			if txt := im.encoded[key]; txt != nil {
+				if decoded, ok := decodeTXT(txt.Targets[0]); ok {
+					records = decoded
+				}
+			}
+			payloads[key] = records
+		}
+		owned[key] = r
+		if changed[key] == nil {
+			changed[key] = map[string]bool{}
+		}
+		changed[key][ownershipRecordType(r)] = true
+		return records
+	}
+	for _, r := range slices.Concat(del, updateOld) {
+		if records := payloadOf(r); records != nil {
+			delete(records, ownershipRecordType(r))
+		}
+	}
+	for _, r := range slices.Concat(create, updateNew) {
+		if records := payloadOf(r); records != nil {
+			labels := maps.Clone(r.Labels)
+			if labels == nil {
+				labels = endpoint.NewLabels()
+			}
+			labels[endpoint.OwnerLabelKey] = im.ownerID
+			records[ownershipRecordType(r)] = labels
+		}
+	}
+
+	result := encodedChanges{
+		changes:   &plan.Changes{},
+		records:   map[encodedKey]*endpoint.Endpoint{},
+		legacyTXT: map[encodedKey][]*endpoint.Endpoint{},
+	}
+	for key, records := range payloads {
+		old := im.encoded[key]
+		for _, txt := range im.legacyTXT[key] {
+			// the TXT records of the old format are of all the types
+			if _, recordType := im.toEndpointName(txt.DNSName); recordType == "" || changed[key][recordType] {
+				result.changes.Delete = append(result.changes.Delete, txt)
+			} else {
+				result.legacyTXT[key] = append(result.legacyTXT[key], txt)
+			}
+		}
+		if len(records) == 0 {
+			if old != nil {
+				result.changes.Delete = append(result.changes.Delete, old)
+			}
+			result.records[key] = nil
+			continue
+		}
+		r := owned[key]
+		txt := endpoint.NewEndpoint(key.txtName, endpoint.RecordTypeTXT, encodeTXT(records)).WithSetIdentifier(key.setIdentifier)
+		txt.Labels[endpoint.OwnedRecordLabelKey] = r.DNSName
+		txt.ProviderSpecific = r.ProviderSpecific
+		// the TXT records are in the zone of the record
+		if zone := r.Labels[endpoint.ZoneLabelKey]; zone != "" {
+			txt.Labels[endpoint.ZoneLabelKey] = zone
+		}
+		switch {
+		case old == nil:
+			result.changes.Create = append(result.changes.Create, txt)
+		case old.Targets[0] != txt.Targets[0]:
+			result.changes.UpdateOld = append(result.changes.UpdateOld, old)
+			result.changes.UpdateNew = append(result.changes.UpdateNew, txt)
+		}
+		result.records[key] = txt
+	}
+	return result
+}
+
+// applied records the encoded TXT records of the applied changes.
+func (im *TXTRegistry) applied(changes encodedChanges) {
+	for key, txt := range changes.records {
+		if txt == nil {
+			delete(im.encoded, key)
+		} else {
+			im.encoded[key] = txt
+		}
+		if legacy := changes.legacyTXT[key]; len(legacy) > 0 {
+			im.legacyTXT[key] = legacy
+		} else {
+			delete(im.legacyTXT, key)
+		}
+	}
+}
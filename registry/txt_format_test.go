@@ -0,0 +1,172 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+func TestSetZoneFormats(t *testing.T) {
+	r, err := NewTXTRegistry(inmemory.NewInMemoryProvider(), "k8s-%{record_type}-", "", "owner", 0, "", nil, nil, false, nil)
+	require.NoError(t, err)
+	for _, values := range [][]string{
+		{"example.org"},
+		{"=encoded"},
+		{"example.org=compact"},
+		{"example.org=suffix:-owner"},
+	} {
+		assert.Error(t, r.SetZoneFormats(values), values)
+	}
+
+	require.NoError(t, r.SetZoneFormats([]string{"example.org=suffix", "pods.example.org.=encoded"}))
+	assert.Nil(t, r.formatOf("foo.example.com"))
+	assert.Nil(t, r.formatOf("fooexample.org"))
+	assert.False(t, r.formatOf("foo.example.org").encoded)
+	assert.True(t, r.formatOf("foo.pods.example.org").encoded)
+	assert.True(t, r.formatOf("pods.example.org").encoded)
+	assert.Equal(t, "foo-a.example.org", r.generateTXTRecord(newEndpointWithOwner("foo.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner"))[0].DNSName)
+	assert.Empty(t, r.generateTXTRecord(newEndpointWithOwner("foo.pods.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner")))
+	assert.Equal(t, "k8s-a-foo.example.com", r.generateTXTRecord(newEndpointWithOwner("foo.example.com", "1.2.3.4", endpoint.RecordTypeA, "owner"))[0].DNSName)
+
+	encrypted, err := NewTXTRegistry(inmemory.NewInMemoryProvider(), "", "", "owner", 0, "", nil, nil, true, []byte("12345678901234567890123456789012"))
+	require.NoError(t, err)
+	assert.Error(t, encrypted.SetZoneFormats([]string{"example.org=encoded"}))
+}
+
+func TestEncodedNameMapper(t *testing.T) {
+	for _, tc := range []struct {
+		prefix, suffix, txtName string
+	}{
+		{"", "", "rrset-foo.example.org"},
+		{"txt.", "", "txt.rrset-foo.example.org"},
+		{"k8s-%{record_type}-", "", "k8s-rrset-foo.example.org"},
+		{"", "-txt", "foo-rrset-txt.example.org"},
+		{"", "-%{record_type}.txt", "foo-rrset.txt.example.org"},
+	} {
+		m := newEncodedNameMapper(tc.prefix, tc.suffix, "all")
+		assert.Equal(t, tc.txtName, m.toTXTName("foo.example.org"), tc)
+		assert.Equal(t, "foo.example.org", m.toEndpointName(tc.txtName), tc)
+		assert.Empty(t, m.toEndpointName("foo.example.org"), tc)
+	}
+	m := newEncodedNameMapper("", "", "all")
+	assert.Equal(t, "rrset-all.example.org", m.toTXTName("*.example.org"))
+}
+
+func TestEncodeTXT(t *testing.T) {
+	records := map[string]endpoint.Labels{
+		endpoint.RecordTypeA:    {endpoint.OwnerLabelKey: "owner", endpoint.ResourceLabelKey: "pod/ns/foo"},
+		endpoint.RecordTypeAAAA: {endpoint.OwnerLabelKey: "owner"},
+	}
+	value := encodeTXT(records)
+	assert.Equal(t, `"{\"heritage\":\"external-dns\",\"records\":{\"A\":{\"owner\":\"owner\",\"resource\":\"pod/ns/foo\"},\"AAAA\":{\"owner\":\"owner\"}}}"`, value)
+	decoded, ok := decodeTXT(value)
+	require.True(t, ok)
+	assert.Equal(t, records, decoded)
+
+	for _, value := range []string{"\"heritage=external-dns,external-dns/owner=owner\"", "{}", "{\"heritage\":\"other\"}", "{"} {
+		_, ok := decodeTXT(value)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestTXTRegistryEncoded(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone("pods.example.org")
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			// Written before the zone was encoded.
+			newEndpointWithOwner("old.pods.example.org", "1.2.3.3", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("k8s-a-old.pods.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+		},
+	}))
+	r, err := NewTXTRegistry(p, "k8s-%{record_type}-", "", "owner", 0, "", []string{endpoint.RecordTypeA, endpoint.RecordTypeAAAA}, nil, false, nil)
+	require.NoError(t, err)
+	require.NoError(t, r.SetZoneFormats([]string{"pods.example.org=encoded"}))
+
+	txtRecords := func() map[string]string {
+		records, err := p.Records(ctx)
+		require.NoError(t, err)
+		txt := map[string]string{}
+		for _, record := range records {
+			if record.RecordType == endpoint.RecordTypeTXT {
+				txt[record.DNSName] = record.Targets[0]
+			}
+		}
+		return txt
+	}
+	owners := func() map[string]string {
+		records, err := r.Records(ctx)
+		require.NoError(t, err)
+		owners := map[string]string{}
+		for _, record := range records {
+			owners[record.DNSName+"/"+record.RecordType] = record.Labels[endpoint.OwnerLabelKey]
+		}
+		return owners
+	}
+
+	// The records without an encoded TXT record are updated.
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	value, _ := records[0].GetProviderSpecificProperty(providerSpecificForceUpdate)
+	assert.Equal(t, "true", value)
+
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.pods.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("foo.pods.example.org", "::1", endpoint.RecordTypeAAAA, ""),
+		},
+		UpdateOld: []*endpoint.Endpoint{newEndpointWithOwner("old.pods.example.org", "1.2.3.3", endpoint.RecordTypeA, "owner")},
+		UpdateNew: []*endpoint.Endpoint{newEndpointWithOwner("old.pods.example.org", "1.2.3.3", endpoint.RecordTypeA, "owner")},
+	}))
+	assert.Equal(t, map[string]string{
+		"k8s-rrset-foo.pods.example.org": encodeTXT(map[string]endpoint.Labels{
+			endpoint.RecordTypeA:    {endpoint.OwnerLabelKey: "owner"},
+			endpoint.RecordTypeAAAA: {endpoint.OwnerLabelKey: "owner"},
+		}),
+		"k8s-rrset-old.pods.example.org": encodeTXT(map[string]endpoint.Labels{
+			endpoint.RecordTypeA: {endpoint.OwnerLabelKey: "owner"},
+		}),
+	}, txtRecords())
+	assert.Equal(t, map[string]string{
+		"foo.pods.example.org/A":    "owner",
+		"foo.pods.example.org/AAAA": "owner",
+		"old.pods.example.org/A":    "owner",
+	}, owners())
+
+	// The TXT record is updated with the remaining records, then deleted.
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Delete: []*endpoint.Endpoint{newEndpointWithOwner("foo.pods.example.org", "1.2.3.4", endpoint.RecordTypeA, "owner")},
+	}))
+	assert.Equal(t, encodeTXT(map[string]endpoint.Labels{
+		endpoint.RecordTypeAAAA: {endpoint.OwnerLabelKey: "owner"},
+	}), txtRecords()["k8s-rrset-foo.pods.example.org"])
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		Delete: []*endpoint.Endpoint{newEndpointWithOwner("foo.pods.example.org", "::1", endpoint.RecordTypeAAAA, "owner")},
+	}))
+	assert.NotContains(t, txtRecords(), "k8s-rrset-foo.pods.example.org")
+	assert.Equal(t, map[string]string{"old.pods.example.org/A": "owner"}, owners())
+}
@@ -924,6 +924,69 @@ func testTXTRegistryApplyChangesNoPrefix(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestTXTRegistryApplyChangesAdopted(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+			newEndpointWithOwner("foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=other\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("cname-foo.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=other\"", endpoint.RecordTypeTXT, ""),
+			newEndpointWithOwner("bar.test-zone.example.org", "bar.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+		},
+	}))
+	r, err := NewTXTRegistry(p, "", "", "owner", 0, "", []string{}, []string{}, false, nil)
+	require.NoError(t, err)
+
+	// The updates adopted by the plan: owned by the registry once updated.
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{
+		UpdateOld: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "foo.loadbalancer.com", endpoint.RecordTypeCNAME, "other"),
+			newEndpointWithOwner("bar.test-zone.example.org", "bar.loadbalancer.com", endpoint.RecordTypeCNAME, ""),
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			newEndpointWithOwner("foo.test-zone.example.org", "new-foo.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
+			newEndpointWithOwner("bar.test-zone.example.org", "new-bar.loadbalancer.com", endpoint.RecordTypeCNAME, "owner"),
+		},
+	}))
+
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+	owners := map[string]string{}
+	for _, record := range records {
+		if record.RecordType == endpoint.RecordTypeCNAME {
+			owners[record.DNSName] = record.Labels[endpoint.OwnerLabelKey]
+		}
+	}
+	assert.Equal(t, map[string]string{"foo.test-zone.example.org": "owner", "bar.test-zone.example.org": "owner"}, owners)
+}
+
+func TestTXTRegistryApplyChangesExpired(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	other, err := NewTXTRegistry(p, "", "", "other", 0, "", []string{}, []string{}, false, nil)
+	require.NoError(t, err)
+	require.NoError(t, other.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			newEndpointWithOwnerAndLabels("expired.test-zone.example.org", "10.0.0.1", endpoint.RecordTypeA, "", endpoint.Labels{endpoint.ExpiresLabelKey: "1"}),
+			newEndpointWithOwnerAndLabels("leased.test-zone.example.org", "10.0.0.2", endpoint.RecordTypeA, "", endpoint.Labels{endpoint.ExpiresLabelKey: "4102444800"}),
+		},
+	}))
+	r, err := NewTXTRegistry(p, "", "", "owner", 0, "", []string{}, []string{}, false, nil)
+	require.NoError(t, err)
+	records, err := r.Records(ctx)
+	require.NoError(t, err)
+
+	// Only the records of other owners whose lease expired are deleted.
+	require.NoError(t, r.ApplyChanges(ctx, &plan.Changes{Delete: records}))
+	records, err = r.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "leased.test-zone.example.org", records[0].DNSName)
+}
+
 func testTXTRegistryMissingRecords(t *testing.T) {
 	t.Run("No prefix", testTXTRegistryMissingRecordsNoPrefix)
 	t.Run("With Prefix", testTXTRegistryMissingRecordsWithPrefix)
@@ -1211,6 +1274,16 @@ func TestCacheMethods(t *testing.T) {
 	}
 }
 
+func TestFlush(t *testing.T) {
+	registry := &TXTRegistry{
+		recordsCache:            []*endpoint.Endpoint{newEndpointWithOwner("thing.com", "1.2.3.4", "A", "owner")},
+		recordsCacheRefreshTime: time.Now(),
+		cacheInterval:           time.Hour,
+	}
+	registry.Flush()
+	assert.Nil(t, registry.recordsCache)
+}
+
 func TestDropPrefix(t *testing.T) {
 	mapper := newaffixNameMapper("foo-%{record_type}-", "", "")
 	expectedOutput := "test.example.com"
@@ -1507,6 +1580,19 @@ func TestGenerateTXT(t *testing.T) {
 	assert.Equal(t, expectedTXT, gotTXT)
 }
 
+func TestGenerateTXTZone(t *testing.T) {
+	record := newEndpointWithOwner("foo.test-zone.example.org", "10.0.0.1", endpoint.RecordTypeA, "owner")
+	record.Labels[endpoint.ZoneLabelKey] = "internal-zone"
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	r, _ := NewTXTRegistry(p, "", "", "owner", time.Hour, "", []string{}, []string{}, false, nil)
+	gotTXT := r.generateTXTRecord(record)
+	require.Len(t, gotTXT, 2)
+	for _, txt := range gotTXT {
+		assert.Equal(t, "internal-zone", txt.Labels[endpoint.ZoneLabelKey], "the TXT records are in the zone of the record")
+	}
+}
+
 func TestGenerateTXTForAAAA(t *testing.T) {
 	record := newEndpointWithOwner("foo.test-zone.example.org", "2001:DB8::1", endpoint.RecordTypeAAAA, "owner")
 	expectedTXT := []*endpoint.Endpoint{
@@ -1646,6 +1732,46 @@ func TestMultiClusterDifferentRecordTypeOwnership(t *testing.T) {
 	}
 }
 
+func TestTXTRegistryRecordsShard(t *testing.T) {
+	ctx := context.Background()
+	p := inmemory.NewInMemoryProvider()
+	p.CreateZone(testZone)
+	create := []*endpoint.Endpoint{
+		newEndpointWithOwner("*.test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+		newEndpointWithOwner("txt.a-wc.test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+		newEndpointWithOwner("manual.test-zone.example.org", "manual", endpoint.RecordTypeTXT, ""),
+	}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		create = append(create,
+			newEndpointWithOwner(name+".test-zone.example.org", "1.2.3.4", endpoint.RecordTypeA, ""),
+			newEndpointWithOwner("txt.a-"+name+".test-zone.example.org", "\"heritage=external-dns,external-dns/owner=owner\"", endpoint.RecordTypeTXT, ""),
+		)
+	}
+	require.NoError(t, p.ApplyChanges(ctx, &plan.Changes{Create: create}))
+
+	r, err := NewTXTRegistry(p, "txt.%{record_type}-", "", "owner", 0, "wc", []string{}, []string{}, false, nil)
+	require.NoError(t, err)
+	all, err := r.Records(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 8)
+
+	sharded := []*endpoint.Endpoint{}
+	for shard := 0; shard < 3; shard++ {
+		records, err := r.RecordsShard(ctx, shard, 3)
+		require.NoError(t, err)
+		for _, ep := range records {
+			assert.Equal(t, shard, plan.Shard(ep.DNSName, 3))
+		}
+		sharded = append(sharded, records...)
+	}
+	assert.True(t, testutils.SameEndpoints(all, sharded), "expected %v, got %v", all, sharded)
+	for _, ep := range sharded {
+		if ep.RecordType == endpoint.RecordTypeA {
+			assert.Equal(t, "owner", ep.Labels[endpoint.OwnerLabelKey], ep.DNSName)
+		}
+	}
+}
+
 /**
 
 helper methods
@@ -59,6 +59,8 @@ type ambassadorHostSource struct {
 	namespace              string
 	ambassadorHostInformer informers.GenericInformer
 	unstructuredConverter  *unstructuredConverter
+
+	*informerHealth
 }
 
 // NewAmbassadorHostSource creates a new ambassadorHostSource with the given config.
@@ -83,6 +85,10 @@ func NewAmbassadorHostSource(
 		},
 	)
 
+	health := trackInformers(map[string]cache.SharedInformer{
+		"hosts": ambassadorHostInformer.Informer(),
+	})
+
 	informerFactory.Start(ctx.Done())
 
 	if err := waitForDynamicCacheSync(context.Background(), informerFactory); err != nil {
@@ -100,6 +106,7 @@ func NewAmbassadorHostSource(
 		namespace:              namespace,
 		ambassadorHostInformer: ambassadorHostInformer,
 		unstructuredConverter:  uc,
+		informerHealth:         health,
 	}, nil
 }
 
@@ -0,0 +1,256 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// axfrSource is an implementation of Source that provides endpoints by transferring
+// zones from an existing authoritative server. It allows migrating legacy zones to a
+// provider using the normal plan and registry.
+//
+// If incremental is set, the zones are transferred once using AXFR and updated using
+// IXFR, falling back to AXFR if the server doesn't support it.
+type axfrSource struct {
+	server      string
+	zones       []string
+	tsigKey     string
+	tsigSecret  string
+	incremental bool
+
+	mu    sync.Mutex
+	cache map[string]*axfrZone
+}
+
+// axfrZone is the last transferred content of a zone.
+type axfrZone struct {
+	serial  uint32
+	records map[string]dns.RR
+}
+
+// NewAXFRSource creates a new axfrSource transferring the zones from server (host:port).
+// The TSIG key name and base64 secret are optional.
+func NewAXFRSource(server string, zones []string, tsigKey, tsigSecret string, incremental bool) (Source, error) {
+	if server == "" {
+		return nil, fmt.Errorf("axfr source requires a server")
+	}
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("axfr source requires at least one zone")
+	}
+	if !strings.Contains(server, ":") {
+		server = server + ":53"
+	}
+	return &axfrSource{
+		server:      server,
+		zones:       zones,
+		tsigKey:     tsigKey,
+		tsigSecret:  tsigSecret,
+		incremental: incremental,
+		cache:       map[string]*axfrZone{},
+	}, nil
+}
+
+// Endpoints returns endpoint objects for the records in all zones.
+func (as *axfrSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	endpoints := []*endpoint.Endpoint{}
+	for _, zone := range as.zones {
+		zone = dns.Fqdn(strings.ToLower(zone))
+		z, err := as.syncZone(zone)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, recordsToEndpoints(zone, z.records)...)
+	}
+	return endpoints, nil
+}
+
+func (as *axfrSource) AddEventHandler(ctx context.Context, handler func()) {
+}
+
+// syncZone updates the cached zone content, using IXFR if possible.
+func (as *axfrSource) syncZone(zone string) (*axfrZone, error) {
+	if z, ok := as.cache[zone]; ok && as.incremental {
+		m := new(dns.Msg)
+		m.SetIxfr(zone, z.serial, ".", ".")
+		rrs, err := as.transfer(m)
+		if err == nil && applyIXFR(z, rrs) {
+			return z, nil
+		}
+		log.Debugf("IXFR of %s from %s failed, using AXFR: %v", zone, as.server, err)
+	}
+
+	m := new(dns.Msg)
+	m.SetAxfr(zone)
+	rrs, err := as.transfer(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transfer zone %s from %s: %w", zone, as.server, err)
+	}
+	if len(rrs) == 0 {
+		return nil, fmt.Errorf("empty transfer of zone %s from %s", zone, as.server)
+	}
+	z := &axfrZone{records: map[string]dns.RR{}}
+	for _, rr := range rrs {
+		if soa, ok := rr.(*dns.SOA); ok {
+			z.serial = soa.Serial
+			continue
+		}
+		z.records[rrKey(rr)] = rr
+	}
+	as.cache[zone] = z
+	log.Debugf("Transferred zone %s from %s: %d records, serial %d", zone, as.server, len(z.records), z.serial)
+	return z, nil
+}
+
+func (as *axfrSource) transfer(m *dns.Msg) ([]dns.RR, error) {
+	tr := &dns.Transfer{DialTimeout: dialTimeout, ReadTimeout: dialTimeout}
+	if as.tsigKey != "" {
+		key := dns.Fqdn(as.tsigKey)
+		tr.TsigSecret = map[string]string{key: as.tsigSecret}
+		m.SetTsig(key, dns.HmacSHA256, 300, time.Now().Unix())
+	}
+	ch, err := tr.In(m, as.server)
+	if err != nil {
+		return nil, err
+	}
+	var rrs []dns.RR
+	for env := range ch {
+		if env.Error != nil {
+			return nil, env.Error
+		}
+		rrs = append(rrs, env.RR...)
+	}
+	return rrs, nil
+}
+
+// applyIXFR applies an IXFR response (RFC 1995) to the zone. Returns false if
+// the response can't be applied and a full transfer is needed.
+func applyIXFR(z *axfrZone, rrs []dns.RR) bool {
+	if len(rrs) == 0 {
+		return false
+	}
+	newSOA, ok := rrs[0].(*dns.SOA)
+	if !ok {
+		return false
+	}
+	if len(rrs) == 1 || newSOA.Serial == z.serial {
+		return true
+	}
+	if _, ok := rrs[1].(*dns.SOA); !ok {
+		// The server responded with a full zone.
+		z.records = map[string]dns.RR{}
+		for _, rr := range rrs[1 : len(rrs)-1] {
+			z.records[rrKey(rr)] = rr
+		}
+		z.serial = newSOA.Serial
+		return true
+	}
+
+	// Sequence of: old SOA, deleted records, new SOA, added records.
+	i := 1
+	for i < len(rrs)-1 {
+		i++
+		for ; i < len(rrs) && rrs[i].Header().Rrtype != dns.TypeSOA; i++ {
+			delete(z.records, rrKey(rrs[i]))
+		}
+		i++
+		for ; i < len(rrs) && rrs[i].Header().Rrtype != dns.TypeSOA; i++ {
+			z.records[rrKey(rrs[i])] = rrs[i]
+		}
+	}
+	z.serial = newSOA.Serial
+	return true
+}
+
+// rrKey identifies a record, ignoring the TTL.
+func rrKey(rr dns.RR) string {
+	c := dns.Copy(rr)
+	c.Header().Ttl = 0
+	c.Header().Name = strings.ToLower(c.Header().Name)
+	return c.String()
+}
+
+// recordsToEndpoints groups the records by name and type. The SOA and NS records
+// at the apex are owned by the provider and skipped.
+func recordsToEndpoints(zone string, records map[string]dns.RR) []*endpoint.Endpoint {
+	type key struct{ name, recordType string }
+	byKey := map[key]*endpoint.Endpoint{}
+	for _, rr := range records {
+		h := rr.Header()
+		name := strings.ToLower(h.Name)
+		if h.Rrtype == dns.TypeSOA || (h.Rrtype == dns.TypeNS && name == zone) {
+			continue
+		}
+		var target string
+		switch r := rr.(type) {
+		case *dns.A:
+			target = r.A.String()
+		case *dns.AAAA:
+			target = r.AAAA.String()
+		case *dns.CNAME:
+			target = strings.TrimSuffix(r.Target, ".")
+		case *dns.NS:
+			target = strings.TrimSuffix(r.Ns, ".")
+		case *dns.PTR:
+			target = strings.TrimSuffix(r.Ptr, ".")
+		case *dns.TXT:
+			target = strings.Join(r.Txt, "")
+		case *dns.MX, *dns.SRV, *dns.NAPTR:
+			target = strings.TrimSuffix(strings.TrimPrefix(rr.String(), h.String()), ".")
+		default:
+			log.Debugf("Skipping unsupported record %s", rr)
+			continue
+		}
+
+		k := key{strings.TrimSuffix(name, "."), dns.TypeToString[h.Rrtype]}
+		ep, ok := byKey[k]
+		if !ok {
+			ep = endpoint.NewEndpointWithTTL(k.name, k.recordType, endpoint.TTL(h.Ttl))
+			byKey[k] = ep
+		}
+		if endpoint.TTL(h.Ttl) < ep.RecordTTL {
+			ep.RecordTTL = endpoint.TTL(h.Ttl)
+		}
+		ep.Targets = append(ep.Targets, target)
+	}
+
+	endpoints := make([]*endpoint.Endpoint, 0, len(byKey))
+	for _, ep := range byKey {
+		sort.Strings(ep.Targets)
+		endpoints = append(endpoints, ep)
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].DNSName != endpoints[j].DNSName {
+			return endpoints[i].DNSName < endpoints[j].DNSName
+		}
+		return endpoints[i].RecordType < endpoints[j].RecordType
+	})
+	return endpoints
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/dnsserver"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider/inmemory"
+)
+
+const (
+	axfrTestKey    = "xfr.example.com."
+	axfrTestSecret = "c2VjcmV0LWtleS1mb3ItdHNpZw=="
+)
+
+// startAXFRServer starts the embedded DNS server as primary for example.com.
+func startAXFRServer(t *testing.T) (*inmemory.InMemoryProvider, string) {
+	p := inmemory.NewInMemoryProvider(inmemory.InMemoryInitZones([]string{"example.com"}))
+	journal := dnsserver.NewJournal()
+	p.OnApplyChanges = journal.OnApplyChanges
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpointWithTTL("a.example.com", endpoint.RecordTypeA, 60, "10.0.0.1", "10.0.0.2"),
+			endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeCNAME, "a.example.com"),
+			endpoint.NewEndpoint("txt.example.com", endpoint.RecordTypeTXT, "hello"),
+			endpoint.NewEndpoint("_sip._tcp.example.com", endpoint.RecordTypeSRV, "10 5 5060 sip.example.com."),
+		},
+	}))
+
+	s := dnsserver.New(p)
+	s.RefreshInterval = 0
+	s.Journal = journal
+	secrets := map[string]string{axfrTestKey: axfrTestSecret}
+	s.Transfer = &dnsserver.TransferConfig{TSIGSecrets: secrets}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &dns.Server{Listener: l, Handler: s, TsigSecret: secrets}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+	return p, l.Addr().String()
+}
+
+func TestAXFRSource(t *testing.T) {
+	p, addr := startAXFRServer(t)
+
+	_, err := NewAXFRSource("", []string{"example.com"}, "", "", false)
+	assert.Error(t, err)
+	_, err = NewAXFRSource(addr, nil, "", "", false)
+	assert.Error(t, err)
+
+	unsigned, err := NewAXFRSource(addr, []string{"example.com"}, "", "", false)
+	require.NoError(t, err)
+	_, err = unsigned.Endpoints(context.Background())
+	assert.Error(t, err)
+
+	src, err := NewAXFRSource(addr, []string{"example.com"}, axfrTestKey, axfrTestSecret, true)
+	require.NoError(t, err)
+	assert.Implements(t, (*Source)(nil), src)
+
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("_sip._tcp.example.com", endpoint.RecordTypeSRV, 300, "10 5 5060 sip.example.com."),
+		endpoint.NewEndpointWithTTL("a.example.com", endpoint.RecordTypeA, 60, "10.0.0.1", "10.0.0.2"),
+		endpoint.NewEndpointWithTTL("txt.example.com", endpoint.RecordTypeTXT, 300, "hello"),
+		endpoint.NewEndpointWithTTL("www.example.com", endpoint.RecordTypeCNAME, 300, "a.example.com"),
+	})
+
+	// Changes are picked up using IXFR.
+	require.NoError(t, p.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{endpoint.NewEndpoint("b.example.com", endpoint.RecordTypeA, "10.0.0.3")},
+		Delete: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("txt.example.com", endpoint.RecordTypeTXT, "hello"),
+			endpoint.NewEndpoint("_sip._tcp.example.com", endpoint.RecordTypeSRV, "10 5 5060 sip.example.com."),
+		},
+	}))
+	endpoints, err = src.Endpoints(context.Background())
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("a.example.com", endpoint.RecordTypeA, 60, "10.0.0.1", "10.0.0.2"),
+		endpoint.NewEndpointWithTTL("b.example.com", endpoint.RecordTypeA, 300, "10.0.0.3"),
+		endpoint.NewEndpointWithTTL("www.example.com", endpoint.RecordTypeCNAME, 300, "a.example.com"),
+	})
+}
+
+func TestApplyIXFR(t *testing.T) {
+	rr := func(s string) dns.RR {
+		r, err := dns.NewRR(s)
+		require.NoError(t, err)
+		return r
+	}
+	soa := func(serial string) dns.RR {
+		return rr("example.com. 300 IN SOA ns.example.com. hostmaster.example.com. " + serial + " 3600 600 86400 300")
+	}
+	z := &axfrZone{serial: 1, records: map[string]dns.RR{}}
+	for _, r := range []dns.RR{rr("a.example.com. 60 IN A 10.0.0.1"), rr("b.example.com. 60 IN A 10.0.0.2")} {
+		z.records[rrKey(r)] = r
+	}
+
+	// Up to date.
+	assert.True(t, applyIXFR(z, []dns.RR{soa("1")}))
+	assert.Len(t, z.records, 2)
+
+	// Two change sets, the deletion matches regardless of TTL.
+	assert.True(t, applyIXFR(z, []dns.RR{
+		soa("3"),
+		soa("1"), rr("A.example.com. 300 IN A 10.0.0.1"), soa("2"), rr("c.example.com. 60 IN A 10.0.0.3"),
+		soa("2"), soa("3"), rr("d.example.com. 60 IN A 10.0.0.4"),
+		soa("3"),
+	}))
+	assert.Equal(t, uint32(3), z.serial)
+	assert.Len(t, z.records, 3)
+	assert.NotContains(t, z.records, rrKey(rr("a.example.com. 60 IN A 10.0.0.1")))
+
+	// Full zone in the IXFR response.
+	assert.True(t, applyIXFR(z, []dns.RR{soa("4"), rr("e.example.com. 60 IN A 10.0.0.5"), soa("4")}))
+	assert.Len(t, z.records, 1)
+
+	assert.False(t, applyIXFR(z, nil))
+}
@@ -0,0 +1,304 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
+)
+
+const (
+	// consulHostnameMeta is the service meta key with the hostnames of the
+	// service, comma separated.
+	consulHostnameMeta = "external-dns-hostname"
+	// consulTTLMeta is the service meta key with the TTL, like "300" or "5m".
+	consulTTLMeta = "external-dns-ttl"
+	// consulProviderMeta is the service meta key of the services registered
+	// by the consul provider, skipped.
+	consulProviderMeta = "external-dns-name"
+
+	defaultConsulAddress = "http://127.0.0.1:8500"
+
+	// consulWaitTime is the maximum duration of a blocking query.
+	consulWaitTime = 5 * time.Minute
+	// consulRetryInterval is the interval between the blocking queries after
+	// an error.
+	consulRetryInterval = 10 * time.Second
+)
+
+// ConsulConfig is the configuration of the consul source.
+type ConsulConfig struct {
+	// Address of the Consul agent, like http://127.0.0.1:8500 - CONSUL_HTTP_ADDR
+	// if empty.
+	Address string
+	// Token is the ACL token - CONSUL_HTTP_TOKEN if empty.
+	Token string
+	// Datacenter to list, the datacenter of the agent if empty.
+	Datacenter string
+	// Tags the services must all have.
+	Tags []string
+	// Domain of the services without an external-dns-hostname meta, published
+	// as SERVICE.DOMAIN - they are skipped if empty.
+	Domain string
+}
+
+// consulSource is an implementation of Source for the services of a Consul
+// catalog, so VM services registered in Consul and Kubernetes services can
+// share a zone. Only the instances with passing health checks are published.
+type consulSource struct {
+	cfg     ConsulConfig
+	client  *http.Client
+	baseURL string
+	timeout time.Duration
+	retry   time.Duration
+	log     *slog.Logger
+}
+
+// consulServiceEntry is the part of an entry of the health API used by the
+// source.
+type consulServiceEntry struct {
+	Node struct {
+		Node    string `json:"Node"`
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Service struct {
+		ID      string            `json:"ID"`
+		Service string            `json:"Service"`
+		Tags    []string          `json:"Tags"`
+		Address string            `json:"Address"`
+		Meta    map[string]string `json:"Meta"`
+	} `json:"Service"`
+}
+
+// NewConsulSource returns a source for the services of the Consul catalog
+// with all the tags of the configuration. The services registered by the
+// consul provider are skipped, so both can share a catalog.
+func NewConsulSource(cfg ConsulConfig, timeout time.Duration) (Source, error) {
+	if cfg.Address == "" {
+		cfg.Address = os.Getenv("CONSUL_HTTP_ADDR")
+	}
+	if cfg.Address == "" {
+		cfg.Address = defaultConsulAddress
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("CONSUL_HTTP_TOKEN")
+	}
+	if !strings.Contains(cfg.Address, "://") {
+		cfg.Address = "http://" + cfg.Address
+	}
+	u, err := url.Parse(cfg.Address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid consul address %q", cfg.Address)
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &consulSource{
+		cfg:     cfg,
+		client:  &http.Client{},
+		baseURL: strings.TrimSuffix(u.String(), "/"),
+		timeout: timeout,
+		retry:   consulRetryInterval,
+		log:     logging.For("source/consul"),
+	}, nil
+}
+
+// Endpoints returns the endpoints of the healthy instances of the services
+// with the tags. An unreachable agent fails the sync, so the records are not
+// deleted.
+func (cs *consulSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, cs.timeout)
+	defer cancel()
+
+	services := map[string][]string{}
+	if _, err := cs.get(ctx, "/v1/catalog/services", nil, &services); err != nil {
+		return nil, fmt.Errorf("consul source: %w", err)
+	}
+	names := make([]string, 0, len(services))
+	for name, tags := range services {
+		if hasAllTags(tags, cs.cfg.Tags) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var endpoints []*endpoint.Endpoint
+	for _, name := range names {
+		query := url.Values{"passing": {"true"}}
+		var entries []consulServiceEntry
+		if _, err := cs.get(ctx, "/v1/health/service/"+url.PathEscape(name), query, &entries); err != nil {
+			return nil, fmt.Errorf("consul source: service %s: %w", name, err)
+		}
+		endpoints = append(endpoints, cs.serviceEndpoints(name, entries)...)
+	}
+	cs.log.Debug("Listed services", "services", len(names), "endpoints", len(endpoints))
+	return endpoints, nil
+}
+
+// serviceEndpoints returns the endpoints of the instances of a service, each
+// hostname pointing to the addresses of the instances with it.
+func (cs *consulSource) serviceEndpoints(name string, entries []consulServiceEntry) []*endpoint.Endpoint {
+	targets := map[string]endpoint.Targets{}
+	ttls := map[string]endpoint.TTL{}
+	var hostnames []string
+	for _, e := range entries {
+		// The catalog lists the services with a tag on any instance.
+		if !hasAllTags(e.Service.Tags, cs.cfg.Tags) {
+			continue
+		}
+		if e.Service.Meta[consulProviderMeta] != "" {
+			continue
+		}
+		address := e.Service.Address
+		if address == "" {
+			address = e.Node.Address
+		}
+		if address == "" {
+			continue
+		}
+		names := splitLabel(e.Service.Meta[consulHostnameMeta])
+		if len(names) == 0 && cs.cfg.Domain != "" {
+			names = []string{strings.ToLower(name) + "." + strings.Trim(cs.cfg.Domain, ".")}
+		}
+		for _, hostname := range names {
+			if _, ok := targets[hostname]; !ok {
+				hostnames = append(hostnames, hostname)
+			}
+			if !containsString(targets[hostname], address) {
+				targets[hostname] = append(targets[hostname], address)
+			}
+			if v, ok := e.Service.Meta[consulTTLMeta]; ok {
+				seconds, err := parseTTL(v)
+				if err != nil {
+					cs.log.Warn("Invalid TTL meta", "service", name, "instance", e.Service.ID, "ttl", v, "error", err)
+				} else {
+					ttls[hostname] = endpoint.TTL(seconds)
+				}
+			}
+		}
+	}
+
+	var endpoints []*endpoint.Endpoint
+	for _, hostname := range hostnames {
+		sort.Strings(targets[hostname])
+		endpoints = append(endpoints, endpointsForHostname(hostname, targets[hostname], ttls[hostname], nil, "", "consul/"+name)...)
+	}
+	return endpoints
+}
+
+func hasAllTags(tags, required []string) bool {
+	for _, r := range required {
+		if !containsString(tags, r) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// get decodes the response of the API into v, returning the X-Consul-Index
+// of the response.
+func (cs *consulSource) get(ctx context.Context, path string, query url.Values, v any) (string, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	if cs.cfg.Datacenter != "" {
+		query.Set("dc", cs.cfg.Datacenter)
+	}
+	u := cs.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	if cs.cfg.Token != "" {
+		req.Header.Set("X-Consul-Token", cs.cfg.Token)
+	}
+	resp, err := cs.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("GET %s: %s: %s", path, resp.Status, bytes.TrimSpace(data))
+	}
+	return resp.Header.Get("X-Consul-Index"), json.NewDecoder(resp.Body).Decode(v)
+}
+
+// AddEventHandler calls the handler when a service is registered or
+// deregistered, or its tags change, with blocking queries of the catalog.
+// The health changes are seen with the next sync.
+func (cs *consulSource) AddEventHandler(ctx context.Context, handler func()) {
+	go func() {
+		index := ""
+		for ctx.Err() == nil {
+			query := url.Values{"wait": {consulWaitTime.String()}}
+			if index != "" {
+				query.Set("index", index)
+			}
+			// The agent answers within the wait time, plus a random jitter of
+			// up to 1/16 of it.
+			reqCtx, cancel := context.WithTimeout(ctx, consulWaitTime+consulWaitTime/16+cs.timeout)
+			var services map[string][]string
+			next, err := cs.get(reqCtx, "/v1/catalog/services", query, &services)
+			cancel()
+			if err == nil && next == "" {
+				err = fmt.Errorf("no X-Consul-Index in the response")
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				cs.log.Warn("Failed to watch the catalog", "error", err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(cs.retry):
+				}
+				continue
+			}
+			if index != "" && next != index {
+				handler()
+			}
+			index = next
+		}
+	}()
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	testConsulServices = `{
+  "consul": [],
+  "web": ["public", "v2"],
+  "api": ["public"],
+  "db": ["internal"]
+}`
+	testConsulWeb = `[
+  {"Node": {"Node": "vm-1", "Address": "10.0.0.1"}, "Service": {"ID": "web-1", "Service": "web", "Tags": ["public", "v2"], "Address": ""}},
+  {"Node": {"Node": "vm-2", "Address": "10.0.0.2"}, "Service": {"ID": "web-2", "Service": "web", "Tags": ["public"], "Address": "192.168.0.2"}},
+  {"Node": {"Node": "vm-3", "Address": "10.0.0.3"}, "Service": {"ID": "web-3", "Service": "web", "Tags": ["public"], "Address": "10.0.0.3"}},
+  {"Node": {"Node": "external-dns", "Address": "127.0.0.1"}, "Service": {"ID": "web-4", "Service": "web", "Tags": ["public"], "Address": "10.0.0.4",
+   "Meta": {"external-dns-name": "web.example.com", "external-dns-type": "A"}}}
+]`
+	testConsulAPI = `[
+  {"Node": {"Node": "vm-1", "Address": "10.0.0.1"}, "Service": {"ID": "api-1", "Service": "api", "Tags": ["public"], "Address": "2001:db8::1",
+   "Meta": {"external-dns-hostname": "api.example.com,api.example.org", "external-dns-ttl": "60"}}}
+]`
+)
+
+// newConsulServer returns a Consul agent serving the catalog of the tests,
+// and the queries of the health API.
+func newConsulServer(t *testing.T, queries map[string]string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-Consul-Token"))
+		assert.Equal(t, "dc2", r.URL.Query().Get("dc"))
+		w.Header().Set("X-Consul-Index", "10")
+		switch r.URL.Path {
+		case "/v1/catalog/services":
+			w.Write([]byte(testConsulServices))
+		case "/v1/health/service/web":
+			queries["web"] = r.URL.RawQuery
+			w.Write([]byte(testConsulWeb))
+		case "/v1/health/service/api":
+			queries["api"] = r.URL.RawQuery
+			w.Write([]byte(testConsulAPI))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestConsulSourceEndpoints(t *testing.T) {
+	queries := map[string]string{}
+	srv := newConsulServer(t, queries)
+
+	src, err := NewConsulSource(ConsulConfig{
+		Address:    srv.URL,
+		Token:      "secret",
+		Datacenter: "dc2",
+		Tags:       []string{"public"},
+		Domain:     "service.example.com.",
+	}, time.Minute)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"web": "dc=dc2&passing=true", "api": "dc=dc2&passing=true"}, queries)
+	api := map[string]string{endpoint.ResourceLabelKey: "consul/api"}
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "api.example.com", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2001:db8::1"}, RecordTTL: 60, Labels: api},
+		{DNSName: "api.example.org", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2001:db8::1"}, RecordTTL: 60, Labels: api},
+		{DNSName: "web.service.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.0.1", "10.0.0.3", "192.168.0.2"},
+			Labels: map[string]string{endpoint.ResourceLabelKey: "consul/web"}},
+	})
+}
+
+func TestConsulSourceTags(t *testing.T) {
+	queries := map[string]string{}
+	srv := newConsulServer(t, queries)
+
+	src, err := NewConsulSource(ConsulConfig{Address: srv.URL, Token: "secret", Datacenter: "dc2", Tags: []string{"public", "v2"}}, time.Minute)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+
+	// Only web-1 has both tags, and without a domain web has no hostname.
+	assert.Empty(t, endpoints)
+	assert.Equal(t, map[string]string{"web": "dc=dc2&passing=true"}, queries)
+}
+
+func TestConsulSourceError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "ACL not found", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	src, err := NewConsulSource(ConsulConfig{Address: srv.URL}, time.Minute)
+	require.NoError(t, err)
+	_, err = src.Endpoints(context.Background())
+	assert.ErrorContains(t, err, "ACL not found")
+}
+
+func TestNewConsulSourceAddress(t *testing.T) {
+	t.Setenv("CONSUL_HTTP_ADDR", "consul.local:8500")
+	t.Setenv("CONSUL_HTTP_TOKEN", "from-env")
+	src, err := NewConsulSource(ConsulConfig{}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "http://consul.local:8500", src.(*consulSource).baseURL)
+	assert.Equal(t, "from-env", src.(*consulSource).cfg.Token)
+
+	t.Setenv("CONSUL_HTTP_ADDR", "")
+	src, err = NewConsulSource(ConsulConfig{Token: "flag"}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:8500", src.(*consulSource).baseURL)
+	assert.Equal(t, "flag", src.(*consulSource).cfg.Token)
+
+	_, err = NewConsulSource(ConsulConfig{Address: "ftp://consul.local"}, 0)
+	assert.Error(t, err)
+}
+
+func TestConsulSourceAddEventHandler(t *testing.T) {
+	var index atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/catalog/services", r.URL.Path)
+		assert.Equal(t, "5m0s", r.URL.Query().Get("wait"))
+		// The index changes on every other query.
+		w.Header().Set("X-Consul-Index", strconv.FormatInt(index.Add(1)/2, 10))
+		w.Write([]byte(testConsulServices))
+	}))
+	defer srv.Close()
+
+	src, err := NewConsulSource(ConsulConfig{Address: srv.URL}, time.Minute)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var events atomic.Int64
+	src.AddEventHandler(ctx, func() { events.Add(1) })
+
+	assert.Eventually(t, func() bool { return events.Load() >= 3 }, 5*time.Second, 10*time.Millisecond)
+}
@@ -48,6 +48,8 @@ type httpProxySource struct {
 	ignoreHostnameAnnotation bool
 	httpProxyInformer        informers.GenericInformer
 	unstructuredConverter    *UnstructuredConverter
+
+	*informerHealth
 }
 
 // NewContourHTTPProxySource creates a new contourHTTPProxySource with the given config.
@@ -78,6 +80,10 @@ func NewContourHTTPProxySource(
 		},
 	)
 
+	health := trackInformers(map[string]cache.SharedInformer{
+		"httpproxies": httpProxyInformer.Informer(),
+	})
+
 	informerFactory.Start(ctx.Done())
 
 	// wait for the local cache to be populated.
@@ -99,6 +105,7 @@ func NewContourHTTPProxySource(
 		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
 		httpProxyInformer:        httpProxyInformer,
 		unstructuredConverter:    uc,
+		informerHealth:           health,
 	}, nil
 }
 
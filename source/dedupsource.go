@@ -18,6 +18,7 @@ package source
 
 import (
 	"context"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -35,6 +36,8 @@ func NewDedupSource(source Source) Source {
 }
 
 // Endpoints collects endpoints from its wrapped source and returns them without duplicates.
+// The priorities of the SRV and MX endpoints are rendered into their targets first, so
+// that endpoints which only differ by them are duplicates.
 func (ms *dedupSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
 	result := []*endpoint.Endpoint{}
 	collected := map[string]bool{}
@@ -45,6 +48,10 @@ func (ms *dedupSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, err
 	}
 
 	for _, ep := range endpoints {
+		if err := ep.RenderPriority(); err != nil {
+			log.Warnf("Ignoring the priority of endpoint %s: %v", ep, err)
+		}
+
 		identifier := ep.DNSName + " / " + ep.SetIdentifier + " / " + ep.Targets.String()
 
 		if _, ok := collected[identifier]; ok {
@@ -62,3 +69,13 @@ func (ms *dedupSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, err
 func (ms *dedupSource) AddEventHandler(ctx context.Context, handler func()) {
 	ms.source.AddEventHandler(ctx, handler)
 }
+
+// Healthy returns the health of the wrapped source.
+func (ms *dedupSource) Healthy() error {
+	return Healthy(ms.source)
+}
+
+// LastEvent returns the time of the last event of the wrapped source.
+func (ms *dedupSource) LastEvent() time.Time {
+	return LastEvent(ms.source)
+}
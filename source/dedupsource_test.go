@@ -90,6 +90,16 @@ func testDedupEndpoints(t *testing.T) {
 				{DNSName: "foo.example.org", Targets: endpoint.Targets{"1.2.3.4"}},
 			},
 		},
+		{
+			"two srv endpoints with the same rendered priority return one endpoint",
+			[]*endpoint.Endpoint{
+				{DNSName: "_http._tcp.example.org", RecordType: endpoint.RecordTypeSRV, Targets: endpoint.Targets{"10 50 80 foo.example.org"}},
+				{DNSName: "_http._tcp.example.org", RecordType: endpoint.RecordTypeSRV, Targets: endpoint.Targets{"80 foo.example.org"}, ProviderSpecific: endpoint.ProviderSpecific{{Name: endpoint.ProviderSpecificPriority, Value: "10"}}},
+			},
+			[]*endpoint.Endpoint{
+				{DNSName: "_http._tcp.example.org", RecordType: endpoint.RecordTypeSRV, Targets: endpoint.Targets{"10 50 80 foo.example.org"}},
+			},
+		},
 	} {
 		t.Run(tc.title, func(t *testing.T) {
 			mockSource := new(testutils.MockSource)
@@ -0,0 +1,286 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
+)
+
+const (
+	// dockerNameLabel is the container label with the hostnames of the
+	// container, comma separated.
+	dockerNameLabel = "dns.name"
+	// dockerTargetLabel is the container label with the targets, comma
+	// separated - the --docker-target addresses or the container IPs if not set.
+	dockerTargetLabel = "dns.target"
+	// dockerTTLLabel is the container label with the TTL, like "300" or "5m".
+	dockerTTLLabel = "dns.ttl"
+
+	defaultDockerHost = "unix:///var/run/docker.sock"
+
+	// dockerRetryInterval is the interval between the reconnections to the
+	// events API.
+	dockerRetryInterval = 10 * time.Second
+)
+
+// dockerSource is an implementation of Source for the containers of a Docker
+// or Podman host with a dns.name label, for edge hosts without Kubernetes.
+// Podman serves the same API, on unix:///run/podman/podman.sock.
+type dockerSource struct {
+	client  *http.Client
+	baseURL string
+	targets endpoint.Targets
+	timeout time.Duration
+	retry   time.Duration
+	log     *slog.Logger
+}
+
+// dockerContainer is the part of a container of the list API used by the
+// source.
+type dockerContainer struct {
+	ID              string            `json:"Id"`
+	Names           []string          `json:"Names"`
+	Labels          map[string]string `json:"Labels"`
+	NetworkSettings struct {
+		Networks map[string]struct {
+			IPAddress         string `json:"IPAddress"`
+			GlobalIPv6Address string `json:"GlobalIPv6Address"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+// NewDockerSource returns a source for the containers of the API at host, a
+// unix:// socket or a tcp:// or http:// address - DOCKER_HOST or the Docker
+// socket if empty. The containers without a dns.target label point to
+// targets, or to their IPs if empty.
+func NewDockerSource(host string, targets []string, timeout time.Duration) (Source, error) {
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if host == "" {
+		host = defaultDockerHost
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	client, baseURL, err := dockerClient(host)
+	if err != nil {
+		return nil, err
+	}
+	return &dockerSource{
+		client:  client,
+		baseURL: baseURL,
+		targets: targets,
+		timeout: timeout,
+		retry:   dockerRetryInterval,
+		log:     logging.For("source/docker"),
+	}, nil
+}
+
+// dockerClient returns the HTTP client and the base URL of the API at host.
+func dockerClient(host string) (*http.Client, string, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid docker host %q: %w", host, err)
+	}
+	switch u.Scheme {
+	case "unix":
+		path := u.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}
+		// The host of the URL is ignored by the dialer.
+		return &http.Client{Transport: transport}, "http://docker", nil
+	case "tcp", "http":
+		return &http.Client{}, "http://" + u.Host, nil
+	}
+	return nil, "", fmt.Errorf("invalid docker host %q: use unix://, tcp:// or http://", host)
+}
+
+// Endpoints returns the endpoints of the running containers with a dns.name
+// label. An unreachable API fails the sync, so the records are not deleted.
+func (ds *dockerSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, ds.timeout)
+	defer cancel()
+
+	filters, _ := json.Marshal(map[string][]string{"label": {dockerNameLabel}})
+	var containers []dockerContainer
+	if err := ds.get(ctx, "/containers/json?filters="+url.QueryEscape(string(filters)), &containers); err != nil {
+		return nil, fmt.Errorf("docker source: %w", err)
+	}
+
+	var endpoints []*endpoint.Endpoint
+	for _, c := range containers {
+		endpoints = append(endpoints, ds.containerEndpoints(c)...)
+	}
+	ds.log.Debug("Listed containers", "containers", len(containers), "endpoints", len(endpoints))
+	return endpoints, nil
+}
+
+// containerEndpoints returns the endpoints of the labels of a container.
+func (ds *dockerSource) containerEndpoints(c dockerContainer) []*endpoint.Endpoint {
+	name := strings.TrimPrefix(firstOrEmpty(c.Names), "/")
+	if name == "" {
+		name = c.ID
+	}
+	resource := "docker/" + name
+
+	targets := splitLabel(c.Labels[dockerTargetLabel])
+	if len(targets) == 0 {
+		targets = ds.targets
+	}
+	if len(targets) == 0 {
+		targets = containerIPs(c)
+	}
+	if len(targets) == 0 {
+		ds.log.Debug("Skipping container without targets", "container", name)
+		return nil
+	}
+
+	var ttl endpoint.TTL
+	if v, ok := c.Labels[dockerTTLLabel]; ok {
+		seconds, err := parseTTL(v)
+		if err != nil {
+			ds.log.Warn("Invalid TTL label", "container", name, "ttl", v, "error", err)
+		} else {
+			ttl = endpoint.TTL(seconds)
+		}
+	}
+
+	var endpoints []*endpoint.Endpoint
+	for _, hostname := range splitLabel(c.Labels[dockerNameLabel]) {
+		endpoints = append(endpoints, endpointsForHostname(hostname, targets, ttl, nil, "", resource)...)
+	}
+	return endpoints
+}
+
+// containerIPs returns the IPs of the networks of the container, sorted.
+func containerIPs(c dockerContainer) endpoint.Targets {
+	var ips endpoint.Targets
+	for _, n := range c.NetworkSettings.Networks {
+		for _, ip := range []string{n.IPAddress, n.GlobalIPv6Address} {
+			if ip != "" {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+func splitLabel(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func firstOrEmpty(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (ds *dockerSource) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ds.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := ds.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("GET %s: %s: %s", path, resp.Status, bytes.TrimSpace(data))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// AddEventHandler calls the handler when a container is started or stopped,
+// watching the events API - reconnecting after errors.
+func (ds *dockerSource) AddEventHandler(ctx context.Context, handler func()) {
+	go func() {
+		for {
+			err := ds.watch(ctx, handler)
+			if ctx.Err() != nil {
+				return
+			}
+			ds.log.Warn("Failed to watch the container events", "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(ds.retry):
+			}
+		}
+	}()
+}
+
+// watch calls the handler for each container event, until the stream ends.
+func (ds *dockerSource) watch(ctx context.Context, handler func()) error {
+	filters, _ := json.Marshal(map[string][]string{
+		"type":  {"container"},
+		"event": {"start", "stop", "die", "destroy", "rename", "update"},
+		"label": {dockerNameLabel},
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ds.baseURL+"/events?filters="+url.QueryEscape(string(filters)), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := ds.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET /events: %s", resp.Status)
+	}
+	// The events are a stream of JSON objects, one per line.
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		ds.log.Debug("Container event", "event", scanner.Text())
+		handler()
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const testContainers = `[
+  {
+    "Id": "0a1b2c",
+    "Names": ["/web"],
+    "Labels": {"dns.name": "web.example.com, www.example.com", "dns.ttl": "5m"},
+    "NetworkSettings": {"Networks": {"bridge": {"IPAddress": "172.17.0.2", "GlobalIPv6Address": "fd00::2"}}}
+  },
+  {
+    "Id": "3d4e5f",
+    "Names": ["/api"],
+    "Labels": {"dns.name": "api.example.com", "dns.target": "edge.example.com"},
+    "NetworkSettings": {"Networks": {"bridge": {"IPAddress": "172.17.0.3"}}}
+  },
+  {
+    "Id": "6a7b8c",
+    "Names": ["/host-network"],
+    "Labels": {"dns.name": "host.example.com"},
+    "NetworkSettings": {"Networks": {"host": {"IPAddress": ""}}}
+  }
+]`
+
+// newDockerServer returns a Docker API serving the handler on a unix socket.
+func newDockerServer(t *testing.T, handler http.Handler) string {
+	path := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return "unix://" + path
+}
+
+func TestDockerSourceEndpoints(t *testing.T) {
+	var filters string
+	host := newDockerServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/containers/json", r.URL.Path)
+		filters = r.URL.Query().Get("filters")
+		w.Write([]byte(testContainers))
+	}))
+
+	src, err := NewDockerSource(host, nil, time.Minute)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"label":["dns.name"]}`, filters)
+
+	web := map[string]string{endpoint.ResourceLabelKey: "docker/web"}
+	api := map[string]string{endpoint.ResourceLabelKey: "docker/api"}
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "web.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"172.17.0.2"}, RecordTTL: 300, Labels: web},
+		{DNSName: "web.example.com", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"fd00::2"}, RecordTTL: 300, Labels: web},
+		{DNSName: "www.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"172.17.0.2"}, RecordTTL: 300, Labels: web},
+		{DNSName: "www.example.com", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"fd00::2"}, RecordTTL: 300, Labels: web},
+		{DNSName: "api.example.com", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"edge.example.com"}, Labels: api},
+	})
+}
+
+func TestDockerSourceTargets(t *testing.T) {
+	host := newDockerServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testContainers))
+	}))
+
+	src, err := NewDockerSource(host, []string{"203.0.113.10"}, time.Minute)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+
+	targets := map[string]endpoint.Targets{}
+	for _, ep := range endpoints {
+		targets[ep.DNSName] = append(targets[ep.DNSName], ep.Targets...)
+	}
+	assert.Equal(t, map[string]endpoint.Targets{
+		"web.example.com":  {"203.0.113.10"},
+		"www.example.com":  {"203.0.113.10"},
+		"api.example.com":  {"edge.example.com"},
+		"host.example.com": {"203.0.113.10"},
+	}, targets)
+}
+
+func TestDockerSourceError(t *testing.T) {
+	host := newDockerServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"permission denied"}`, http.StatusForbidden)
+	}))
+
+	src, err := NewDockerSource(host, nil, time.Minute)
+	require.NoError(t, err)
+	_, err = src.Endpoints(context.Background())
+	assert.ErrorContains(t, err, "permission denied")
+
+	src, err = NewDockerSource("unix://"+filepath.Join(t.TempDir(), "missing.sock"), nil, time.Minute)
+	require.NoError(t, err)
+	_, err = src.Endpoints(context.Background())
+	assert.Error(t, err)
+}
+
+func TestNewDockerSourceHost(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "tcp://127.0.0.1:2375")
+	src, err := NewDockerSource("", nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:2375", src.(*dockerSource).baseURL)
+
+	t.Setenv("DOCKER_HOST", "")
+	src, err = NewDockerSource("", nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "http://docker", src.(*dockerSource).baseURL)
+
+	_, err = NewDockerSource("ssh://user@host", nil, 0)
+	assert.Error(t, err)
+}
+
+func TestDockerSourceAddEventHandler(t *testing.T) {
+	host := newDockerServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/events", r.URL.Path)
+		w.Write([]byte(`{"Type":"container","Action":"start","id":"0a1b2c"}` + "\n"))
+		w.Write([]byte(`{"Type":"container","Action":"die","id":"0a1b2c"}` + "\n"))
+	}))
+
+	src, err := NewDockerSource(host, nil, time.Minute)
+	require.NoError(t, err)
+	src.(*dockerSource).retry = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan struct{}, 10)
+	src.AddEventHandler(ctx, func() {
+		select {
+		case events <- struct{}{}:
+		default:
+		}
+	})
+
+	// Two events, then two more after the reconnection.
+	for i := 0; i < 4; i++ {
+		select {
+		case <-events:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("event %d not received", i)
+		}
+	}
+}
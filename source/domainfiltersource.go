@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+var sourceRejectedEndpoints = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "external_dns",
+		Subsystem: "source",
+		Name:      "rejected_endpoints",
+		Help:      "Number of endpoints of a source outside of its domain filter, rejected by the last sync, by source.",
+	},
+	[]string{"source"},
+)
+
+func init() {
+	prometheus.MustRegister(sourceRejectedEndpoints)
+}
+
+// domainFilterSource is a Source that removes the endpoints outside of the
+// domain filter of its wrapped source.
+type domainFilterSource struct {
+	source       Source
+	name         string
+	domainFilter endpoint.DomainFilter
+}
+
+// NewDomainFilterSource creates a new domainFilterSource wrapping the source
+// of this name.
+func NewDomainFilterSource(source Source, name string, domainFilter endpoint.DomainFilter) Source {
+	return &domainFilterSource{source: source, name: name, domainFilter: domainFilter}
+}
+
+// Endpoints collects endpoints from its wrapped source and returns the ones
+// matching the domain filter.
+func (ds *domainFilterSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	endpoints, err := ds.source.Endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*endpoint.Endpoint, 0, len(endpoints))
+	rejected := 0
+	for _, ep := range endpoints {
+		if !ds.domainFilter.Match(ep.DNSName) {
+			log.WithField("endpoint", ep).Warnf("Rejecting endpoint of source %s outside of its domain filter", ds.name)
+			rejected++
+			continue
+		}
+		result = append(result, ep)
+	}
+	sourceRejectedEndpoints.WithLabelValues(ds.name).Set(float64(rejected))
+
+	return result, nil
+}
+
+func (ds *domainFilterSource) AddEventHandler(ctx context.Context, handler func()) {
+	ds.source.AddEventHandler(ctx, handler)
+}
+
+// Healthy returns the health of the wrapped source.
+func (ds *domainFilterSource) Healthy() error {
+	return Healthy(ds.source)
+}
+
+// LastEvent returns the time of the last event of the wrapped source.
+func (ds *domainFilterSource) LastEvent() time.Time {
+	return LastEvent(ds.source)
+}
+
+// ParseSourceDomainFilters returns the domain filters by source name of the
+// SOURCE=DOMAIN values; the domains of the same source are combined.
+func ParseSourceDomainFilters(values []string) (map[string]endpoint.DomainFilter, error) {
+	domains := map[string][]string{}
+	for _, v := range values {
+		name, domain, ok := strings.Cut(v, "=")
+		if !ok || name == "" || domain == "" {
+			return nil, fmt.Errorf("invalid source domain filter %q, use SOURCE=DOMAIN", v)
+		}
+		domains[name] = append(domains[name], domain)
+	}
+	filters := make(map[string]endpoint.DomainFilter, len(domains))
+	for name, d := range domains {
+		filters[name] = endpoint.NewDomainFilter(d)
+	}
+	return filters, nil
+}
+
+// WithDomainFilters wraps the sources of the names with a domain filter, the
+// names being those of the sources in the same order.
+func WithDomainFilters(sources []Source, names []string, filters map[string]endpoint.DomainFilter) []Source {
+	filtered := make([]Source, len(sources))
+	for i, s := range sources {
+		filtered[i] = s
+		if i < len(names) {
+			if df, ok := filters[names[i]]; ok {
+				filtered[i] = NewDomainFilterSource(s, names[i], df)
+			}
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/internal/testutils"
+)
+
+func TestDomainFilterSource(t *testing.T) {
+	mock := new(testutils.MockSource)
+	mock.On("Endpoints").Return([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("billing.mesh.example.com", endpoint.RecordTypeA, "10.0.0.1"),
+		endpoint.NewEndpoint("mesh.example.com", endpoint.RecordTypeA, "10.0.0.2"),
+		endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeA, "10.0.0.3"),
+		endpoint.NewEndpoint("evilmesh.example.com", endpoint.RecordTypeA, "10.0.0.4"),
+	}, nil)
+
+	src := NewDomainFilterSource(mock, "istio-se", endpoint.NewDomainFilter([]string{"mesh.example.com"}))
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		endpoint.NewEndpoint("billing.mesh.example.com", endpoint.RecordTypeA, "10.0.0.1"),
+		endpoint.NewEndpoint("mesh.example.com", endpoint.RecordTypeA, "10.0.0.2"),
+	})
+	assert.Equal(t, 2.0, testutil.ToFloat64(sourceRejectedEndpoints.WithLabelValues("istio-se")))
+}
+
+func TestParseSourceDomainFilters(t *testing.T) {
+	filters, err := ParseSourceDomainFilters([]string{"istio-se=mesh.example.com", "k8s=p.internal.example.com", "istio-se=svc.example.com"})
+	require.NoError(t, err)
+	require.Len(t, filters, 2)
+	assert.True(t, filters["istio-se"].Match("a.svc.example.com"))
+	assert.True(t, filters["istio-se"].Match("a.mesh.example.com"))
+	assert.False(t, filters["istio-se"].Match("a.p.internal.example.com"))
+	assert.True(t, filters["k8s"].Match("a.default.p.internal.example.com"))
+
+	for _, v := range []string{"istio-se", "=mesh.example.com", "istio-se="} {
+		_, err := ParseSourceDomainFilters([]string{v})
+		assert.Error(t, err, v)
+	}
+
+	sources := []Source{new(testutils.MockSource), new(testutils.MockSource)}
+	wrapped := WithDomainFilters(sources, []string{"service", "k8s"}, filters)
+	assert.Same(t, sources[0], wrapped[0])
+	assert.IsType(t, &domainFilterSource{}, wrapped[1])
+}
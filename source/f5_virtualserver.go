@@ -54,6 +54,8 @@ type f5VirtualServerSource struct {
 	annotationFilter      string
 	namespace             string
 	unstructuredConverter *unstructuredConverter
+
+	*informerHealth
 }
 
 func NewF5VirtualServerSource(
@@ -73,6 +75,10 @@ func NewF5VirtualServerSource(
 		},
 	)
 
+	health := trackInformers(map[string]cache.SharedInformer{
+		"virtualservers": virtualServerInformer.Informer(),
+	})
+
 	informerFactory.Start(ctx.Done())
 
 	// wait for the local cache to be populated.
@@ -92,6 +98,7 @@ func NewF5VirtualServerSource(
 		namespace:             namespace,
 		annotationFilter:      annotationFilter,
 		unstructuredConverter: uc,
+		informerHealth:        health,
 	}, nil
 }
 
@@ -0,0 +1,279 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/gitops"
+	"sigs.k8s.io/external-dns/pkg/logging"
+	"sigs.k8s.io/external-dns/pkg/tlsutils"
+)
+
+// fileWatchInterval is the interval between the checks of the modification
+// time of the files.
+var fileWatchInterval = 10 * time.Second
+
+// fileURLPollInterval is the interval between the requests for the URLs, with
+// their ETags, to detect the changes.
+var fileURLPollInterval = time.Minute
+
+// FileConfig is the configuration of the file source.
+type FileConfig struct {
+	// Locations of the files, each a path or an http:// or https:// URL.
+	Locations []string
+	// TokenFile has a bearer token for the URLs, read for each request so a
+	// rotated token is used - no Authorization header if empty. The URLs can
+	// also have a user and password, for basic authentication.
+	TokenFile string
+	// CAFile has the CA certificates of the https:// URLs, the system roots if
+	// empty.
+	CAFile string
+}
+
+// fileSource is an implementation of Source reading the endpoints from records
+// files or URLs, without Kubernetes - for VM fleets and home labs, or records
+// published by other clusters to a central instance. A file is a YAML or JSON
+// list of endpoints, as written by 'ednsctl records -o yaml', or a DNSEndpoint
+// spec with an endpoints list.
+type fileSource struct {
+	locations []string
+	tokenFile string
+	client    *http.Client
+	timeout   time.Duration
+	log       *slog.Logger
+
+	// mu guards cache, the last response of each URL.
+	mu    sync.Mutex
+	cache map[string]fileResponse
+}
+
+// fileResponse is a response for a URL, sent again if the ETag matches.
+type fileResponse struct {
+	etag string
+	data []byte
+}
+
+// NewFileSource returns a source for the files of the configuration.
+func NewFileSource(cfg FileConfig, timeout time.Duration) (Source, error) {
+	if len(cfg.Locations) == 0 {
+		return nil, fmt.Errorf("the file source requires --file-source")
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	client := &http.Client{}
+	if cfg.CAFile != "" {
+		tlsConfig, err := tlsutils.NewTLSConfig("", "", cfg.CAFile, "", false, 0)
+		if err != nil {
+			return nil, fmt.Errorf("file source: %w", err)
+		}
+		client.Transport = &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig}
+	}
+	return &fileSource{
+		locations: cfg.Locations,
+		tokenFile: cfg.TokenFile,
+		client:    client,
+		timeout:   timeout,
+		log:       logging.For("source/file"),
+		cache:     map[string]fileResponse{},
+	}, nil
+}
+
+// Endpoints returns the endpoints of all the files. A file that can't be read
+// fails the sync, so its records are not deleted.
+func (fs *fileSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, fs.timeout)
+	defer cancel()
+
+	var endpoints []*endpoint.Endpoint
+	for _, location := range fs.locations {
+		// The password of a URL is not logged.
+		name := redactURL(location)
+		data, _, err := fs.read(ctx, location)
+		if err != nil {
+			return nil, fmt.Errorf("file source %s: %w", name, err)
+		}
+		records, err := gitops.ParseRecords(data)
+		if err != nil {
+			return nil, fmt.Errorf("file source %s: %w", name, err)
+		}
+		for _, ep := range records {
+			if ep.Labels[endpoint.ResourceLabelKey] == "" {
+				ep.Labels[endpoint.ResourceLabelKey] = "file/" + name
+			}
+		}
+		fs.log.Debug("Read endpoints", "location", name, "count", len(records))
+		endpoints = append(endpoints, records...)
+	}
+	return endpoints, nil
+}
+
+// read returns the content of the location, and whether it changed since the
+// last read - always for the local files. The URLs are requested with the ETag
+// of the last response, and a 304 Not Modified reuses its content.
+func (fs *fileSource) read(ctx context.Context, location string) ([]byte, bool, error) {
+	if !isURL(location) {
+		data, err := os.ReadFile(location)
+		return data, true, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if fs.tokenFile != "" {
+		token, err := os.ReadFile(fs.tokenFile)
+		if err != nil {
+			return nil, false, err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	fs.mu.Lock()
+	cached, ok := fs.cache[location]
+	fs.mu.Unlock()
+	if ok && cached.etag != "" {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+	resp, err := fs.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	if resp.StatusCode == http.StatusNotModified && ok {
+		return cached.data, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("GET %s: %s: %s", redactURL(location), resp.Status, bytes.TrimSpace(data))
+	}
+	fs.mu.Lock()
+	fs.cache[location] = fileResponse{etag: resp.Header.Get("ETag"), data: data}
+	fs.mu.Unlock()
+	return data, !ok || !bytes.Equal(cached.data, data), nil
+}
+
+// AddEventHandler calls the handler when the modification time of a local
+// file changes, or the content of a URL - requested every minute with its
+// ETag.
+func (fs *fileSource) AddEventHandler(ctx context.Context, handler func()) {
+	var files, urls []string
+	for _, location := range fs.locations {
+		if isURL(location) {
+			urls = append(urls, location)
+		} else {
+			files = append(files, location)
+		}
+	}
+	if len(files) > 0 {
+		go fs.watchFiles(ctx, files, handler)
+	}
+	if len(urls) > 0 {
+		go fs.pollURLs(ctx, urls, handler)
+	}
+}
+
+func (fs *fileSource) watchFiles(ctx context.Context, files []string, handler func()) {
+	modTimes := fileModTimes(files)
+	ticker := time.NewTicker(fileWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current := fileModTimes(files)
+		for _, f := range files {
+			if !current[f].Equal(modTimes[f]) {
+				fs.log.Debug("File changed", "location", f)
+				handler()
+				break
+			}
+		}
+		modTimes = current
+	}
+}
+
+// pollURLs calls the handler when the content of a URL changed since the last
+// read, by a sync or a previous poll. The errors are left to the syncs.
+func (fs *fileSource) pollURLs(ctx context.Context, urls []string, handler func()) {
+	ticker := time.NewTicker(fileURLPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, u := range urls {
+			reqCtx, cancel := context.WithTimeout(ctx, fs.timeout)
+			_, changed, err := fs.read(reqCtx, u)
+			cancel()
+			if err != nil {
+				fs.log.Debug("Failed to poll the URL", "location", redactURL(u), "error", err)
+				continue
+			}
+			if changed {
+				fs.log.Debug("URL changed", "location", redactURL(u))
+				handler()
+				break
+			}
+		}
+	}
+}
+
+// fileModTimes returns the modification times of the files, zero for the
+// files that can't be read.
+func fileModTimes(files []string) map[string]time.Time {
+	modTimes := make(map[string]time.Time, len(files))
+	for _, f := range files {
+		if fi, err := os.Stat(f); err == nil {
+			modTimes[f] = fi.ModTime()
+		}
+	}
+	return modTimes
+}
+
+// redactURL returns the location with the password of a URL replaced.
+func redactURL(location string) string {
+	if !isURL(location) {
+		return location
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+	return u.Redacted()
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const fileRecords = `
+- dnsName: vm1.example.com
+  recordType: A
+  targets: [10.0.0.1]
+- dnsName: vm2.example.com
+  recordType: A
+  targets: [10.0.0.2]
+  labels:
+    resource: inventory/vm2
+`
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(fileRecords), 0o600))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"endpoints": [{"dnsName": "nas.home.example.com", "recordType": "A", "targets": ["192.168.1.2"]}]}`))
+	}))
+	defer server.Close()
+
+	src, err := NewFileSource(FileConfig{Locations: []string{path, server.URL}}, time.Minute)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+	require.Len(t, endpoints, 3)
+	assert.Equal(t, "vm1.example.com", endpoints[0].DNSName)
+	assert.Equal(t, "file/"+path, endpoints[0].Labels[endpoint.ResourceLabelKey])
+	assert.Equal(t, "inventory/vm2", endpoints[1].Labels[endpoint.ResourceLabelKey])
+	assert.Equal(t, endpoint.Targets{"192.168.1.2"}, endpoints[2].Targets)
+}
+
+func TestFileSourceErrors(t *testing.T) {
+	_, err := NewFileSource(FileConfig{}, 0)
+	assert.Error(t, err)
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	invalid := filepath.Join(t.TempDir(), "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("dnsName: [\n"), 0o600))
+	for _, location := range []string{server.URL, invalid, invalid + ".missing"} {
+		src, err := NewFileSource(FileConfig{Locations: []string{location}}, 0)
+		require.NoError(t, err)
+		_, err = src.Endpoints(context.Background())
+		assert.ErrorContains(t, err, location)
+	}
+}
+
+func TestFileSourceEvents(t *testing.T) {
+	defer func(interval time.Duration) { fileWatchInterval = interval }(fileWatchInterval)
+	fileWatchInterval = 10 * time.Millisecond
+
+	path := filepath.Join(t.TempDir(), "records.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(fileRecords), 0o600))
+	src, err := NewFileSource(FileConfig{Locations: []string{path}}, 0)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	src.AddEventHandler(ctx, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Hour)))
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the change of the file is not reported")
+	}
+}
+
+func TestFileSourceURL(t *testing.T) {
+	token := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(token, []byte("s3cr3t\n"), 0o600))
+	var requests, notModified int
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "Bearer s3cr3t", r.Header.Get("Authorization"))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(fileRecords))
+	}))
+	defer server.Close()
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+
+	src, err := NewFileSource(FileConfig{Locations: []string{server.URL}, TokenFile: token, CAFile: ca}, time.Minute)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		endpoints, err := src.Endpoints(context.Background())
+		require.NoError(t, err)
+		require.Len(t, endpoints, 2)
+		assert.Equal(t, "file/"+server.URL, endpoints[0].Labels[endpoint.ResourceLabelKey])
+	}
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 1, notModified)
+
+	_, err = NewFileSource(FileConfig{Locations: []string{server.URL}, CAFile: ca + ".missing"}, 0)
+	assert.Error(t, err)
+}
+
+func TestFileSourceURLPassword(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "edge" || password != "s3cr3t" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(fileRecords))
+	}))
+	defer server.Close()
+	location := strings.Replace(server.URL, "http://", "http://edge:s3cr3t@", 1)
+
+	src, err := NewFileSource(FileConfig{Locations: []string{location}}, time.Minute)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "file/"+strings.Replace(location, "s3cr3t", "xxxxx", 1), endpoints[0].Labels[endpoint.ResourceLabelKey])
+
+	src, err = NewFileSource(FileConfig{Locations: []string{server.URL}}, time.Minute)
+	require.NoError(t, err)
+	_, err = src.Endpoints(context.Background())
+	assert.ErrorContains(t, err, "401")
+}
+
+func TestFileSourceURLEvents(t *testing.T) {
+	defer func(interval time.Duration) { fileURLPollInterval = interval }(fileURLPollInterval)
+	fileURLPollInterval = 10 * time.Millisecond
+
+	var version atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := fmt.Sprintf(`"v%d"`, version.Load())
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		fmt.Fprintf(w, `[{"dnsName": "vm.example.com", "recordType": "TXT", "targets": ["v%d"]}]`, version.Load())
+	}))
+	defer server.Close()
+
+	src, err := NewFileSource(FileConfig{Locations: []string{server.URL}}, time.Minute)
+	require.NoError(t, err)
+	_, err = src.Endpoints(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	src.AddEventHandler(ctx, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+
+	select {
+	case <-changed:
+		t.Fatal("a change is reported without a new version")
+	case <-time.After(50 * time.Millisecond):
+	}
+	version.Store(1)
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the change of the URL is not reported")
+	}
+}
@@ -33,6 +33,7 @@ import (
 	kubeinformers "k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	cache "k8s.io/client-go/tools/cache"
+	gwv1 "sigs.k8s.io/gateway-api/apis/v1"
 	v1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 	gateway "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
 	informers "sigs.k8s.io/gateway-api/pkg/client/informers/externalversions"
@@ -99,6 +100,8 @@ type gatewayRouteSource struct {
 	fqdnTemplate             *template.Template
 	combineFQDNAnnotation    bool
 	ignoreHostnameAnnotation bool
+
+	*informerHealth
 }
 
 // NewGatewaySource creates a new Gateway source with the given config.
@@ -155,6 +158,15 @@ func newGatewayRouteSource(clients ClientGenerator, config *Config, kind string,
 	nsInformer := kubeInformerFactory.Core().V1().Namespaces() // TODO: Namespace informer should be shared across gateway sources.
 	nsInformer.Informer()                                      // Register with factory before starting.
 
+	tracked := map[string]cache.SharedInformer{
+		"gateways":   gwInformer.Informer(),
+		"namespaces": nsInformer.Informer(),
+	}
+	if rtInformer != nil {
+		tracked[strings.ToLower(kind)+"s"] = rtInformer.Informer()
+	}
+	health := trackInformers(tracked)
+
 	informerFactory.Start(wait.NeverStop)
 	kubeInformerFactory.Start(wait.NeverStop)
 
@@ -189,6 +201,8 @@ func newGatewayRouteSource(clients ClientGenerator, config *Config, kind string,
 		fqdnTemplate:             tmpl,
 		combineFQDNAnnotation:    config.CombineFQDNAndAnnotation,
 		ignoreHostnameAnnotation: config.IgnoreHostnameAnnotation,
+
+		informerHealth: health,
 	}
 	return src, nil
 }
@@ -352,11 +366,8 @@ func (c *gatewayRouteResolver) resolveGateway(gw *v1.Gateway) (map[string]endpoi
 
 	// Match the Route to all possible Listeners.
 	listeners := gw.Spec.Listeners
-	for _, lis := range listeners {
-		if lis.Hostname == nil {
-			continue
-		}
-		host := string(*lis.Hostname)
+	for i := range listeners {
+		host := gwListenerHost(&listeners[i])
 		if host == "" {
 			continue
 		}
@@ -434,12 +445,10 @@ func (c *gatewayRouteResolver) resolve(rt gatewayRoute) (map[string]endpoint.Tar
 				continue
 			}
 			// Find all overlapping hostnames between the Route and Listener.
-			// For {TCP,UDP}Routes, all annotation-generated hostnames should match since the Listener doesn't specify a hostname.
-			// For {HTTP,TLS}Routes, hostnames (including any annotation-generated) will be required to match any Listeners specified hostname.
-			gwHost := ""
-			if lis.Hostname != nil {
-				gwHost = string(*lis.Hostname)
-			}
+			// For {TCP,UDP}Routes on TCP or UDP Listeners, all annotation-generated hostnames match since the Listener hostname is ignored.
+			// For {HTTP,GRPC,TLS}Routes, and TCPRoutes on TLS Listeners, hostnames (including any annotation-generated) are
+			// required to match the Listener hostname, and a Route without hostnames gets the one of the Listener.
+			gwHost := gwListenerHost(lis)
 			for _, rtHost := range rtHosts {
 				if gwHost == "" && rtHost == "" {
 					// For {HTTP,TLS}Routes, this means the Route and the Listener both allow _any_ hostnames.
@@ -491,8 +500,8 @@ func (c *gatewayRouteResolver) hosts(rt gatewayRoute) ([]string, error) {
 		hostnames = append(hostnames, hosts...)
 	}
 	// This means that the route doesn't specify a hostname and should use any provided by
-	// attached Gateway Listeners. This is only useful for {HTTP,TLS}Routes, but it doesn't
-	// break {TCP,UDP}Routes.
+	// attached Gateway Listeners. This is only useful for {HTTP,GRPC,TLS}Routes and for
+	// TCPRoutes on TLS Listeners, but it doesn't break {TCP,UDP}Routes.
 	if len(rt.Hostnames()) == 0 {
 		hostnames = append(hostnames, "")
 	}
@@ -500,40 +509,40 @@ func (c *gatewayRouteResolver) hosts(rt gatewayRoute) ([]string, error) {
 }
 
 func (c *gatewayRouteResolver) routeIsAllowed(gw *v1.Gateway, lis *v1.Listener, rt gatewayRoute) bool {
-	//meta := rt.Metadata()
+	meta := rt.Metadata()
 	allow := lis.AllowedRoutes
 
-	//// Check the route's namespace.
-	//from := v1.NamespacesFromSame
-	//if allow != nil && allow.Namespaces != nil && allow.Namespaces.From != nil {
-	//	from = *allow.Namespaces.From
-	//}
-	//switch from {
-	//case v1.NamespacesFromAll:
-	//	// OK
-	//case v1.NamespacesFromSame:
-	//	if gw.Namespace != meta.Namespace {
-	//		return false
-	//	}
-	//case v1.NamespacesFromSelector:
-	//	selector, err := metav1.LabelSelectorAsSelector(allow.Namespaces.Selector)
-	//	if err != nil {
-	//		log.Debugf("Gateway %s/%s section %q has invalid namespace selector: %v", gw.Namespace, gw.Name, lis.Name, err)
-	//		return false
-	//	}
-	//	// Get namespace.
-	//	ns, ok := c.nss[meta.Namespace]
-	//	if !ok {
-	//		log.Errorf("Namespace not found for %s %s/%s", c.src.rtKind, meta.Namespace, meta.Name)
-	//		return false
-	//	}
-	//	if !selector.Matches(labels.Set(ns.Labels)) {
-	//		return false
-	//	}
-	//default:
-	//	log.Debugf("Gateway %s/%s section %q has unknown namespace from %q", gw.Namespace, gw.Name, lis.Name, from)
-	//	return false
-	//}
+	// Check the route's namespace.
+	from := gwv1.NamespacesFromSame
+	if allow != nil && allow.Namespaces != nil && allow.Namespaces.From != nil {
+		from = *allow.Namespaces.From
+	}
+	switch from {
+	case gwv1.NamespacesFromAll:
+		// OK
+	case gwv1.NamespacesFromSame:
+		if gw.Namespace != meta.Namespace {
+			return false
+		}
+	case gwv1.NamespacesFromSelector:
+		selector, err := metav1.LabelSelectorAsSelector(allow.Namespaces.Selector)
+		if err != nil {
+			log.Debugf("Gateway %s/%s section %q has invalid namespace selector: %v", gw.Namespace, gw.Name, lis.Name, err)
+			return false
+		}
+		// Get namespace.
+		ns, ok := c.nss[meta.Namespace]
+		if !ok {
+			log.Errorf("Namespace not found for %s %s/%s", c.src.rtKind, meta.Namespace, meta.Name)
+			return false
+		}
+		if !selector.Matches(labels.Set(ns.Labels)) {
+			return false
+		}
+	default:
+		log.Debugf("Gateway %s/%s section %q has unknown namespace from %q", gw.Namespace, gw.Name, lis.Name, from)
+		return false
+	}
 
 	// Check the route's kind, if any are specified by the listener.
 	// TODO: Do we need to consider SupportedKinds in the ListenerStatus instead of the Spec?
@@ -578,23 +587,32 @@ func uniqueTargets(targets endpoint.Targets) endpoint.Targets {
 	return targets[:n]
 }
 
-// gwProtocolMatches returns whether a and b are the same protocol,
-// where HTTP and HTTPS are considered the same.
-// and TLS and TCP are considered the same.
+// gwProtocolMatches returns whether a Route of protocol a can attach to a
+// Listener of protocol b: HTTP and HTTPS are considered the same, and a
+// TCPRoute can attach to a TLS Listener terminating TLS.
 func gwProtocolMatches(a, b v1.ProtocolType) bool {
-	//if a == v1.HTTPSProtocolType {
-	//	a = v1.HTTPProtocolType
-	//}
-	//if b == v1.HTTPSProtocolType {
-	//	b = v1.HTTPProtocolType
-	//}
-	//// if Listener is TLS and Route is TCP set Listener type to TCP as to pass true and return valid match
-	//if a == v1.TCPProtocolType && b == v1.TLSProtocolType {
-	//	b = v1.TCPProtocolType
-	//}
+	if a == gwv1.HTTPSProtocolType {
+		a = gwv1.HTTPProtocolType
+	}
+	if b == gwv1.HTTPSProtocolType {
+		b = gwv1.HTTPProtocolType
+	}
+	if a == gwv1.TCPProtocolType && b == gwv1.TLSProtocolType {
+		b = gwv1.TCPProtocolType
+	}
 	return a == b
 }
 
+// gwListenerHost returns the hostname of a Listener, empty if it has none or
+// if its protocol doesn't match on hostnames - the hostname of the TCP and UDP
+// Listeners is ignored.
+func gwListenerHost(lis *v1.Listener) string {
+	if lis.Hostname == nil || lis.Protocol == gwv1.TCPProtocolType || lis.Protocol == gwv1.UDPProtocolType {
+		return ""
+	}
+	return string(*lis.Hostname)
+}
+
 // gwMatchingHost returns the most-specific overlapping host and a bool indicating if one was found.
 // Hostnames that are prefixed with a wildcard label (`*.`) are interpreted as a suffix match.
 // That means that "*.example.com" would match both "test.example.com" and "foo.test.example.com",
@@ -27,6 +27,7 @@ import (
 	"sigs.k8s.io/external-dns/endpoint"
 	v1 "sigs.k8s.io/gateway-api/apis/v1"
 	"sigs.k8s.io/gateway-api/apis/v1alpha2"
+	"sigs.k8s.io/gateway-api/apis/v1beta1"
 	gatewayfake "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned/fake"
 )
 
@@ -61,7 +62,7 @@ func TestGatewayGRPCRouteSourceEndpoints(t *testing.T) {
 		},
 		Status: gatewayStatus(ips...),
 	}
-	_, err = gwClient.GatewayV1().Gateways(gw.Namespace).Create(ctx, gw, metav1.CreateOptions{})
+	_, err = gwClient.GatewayV1beta1().Gateways(gw.Namespace).Create(ctx, (*v1beta1.Gateway)(gw), metav1.CreateOptions{})
 	require.NoError(t, err, "failed to create Gateway")
 
 	rt := &v1alpha2.GRPCRoute{
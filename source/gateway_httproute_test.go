@@ -27,6 +27,7 @@ import (
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	"sigs.k8s.io/external-dns/endpoint"
 	v1 "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/gateway-api/apis/v1beta1"
 	gatewayfake "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned/fake"
 )
 
@@ -1127,12 +1128,12 @@ func TestGatewayHTTPRouteSourceEndpoints(t *testing.T) {
 			ctx := context.Background()
 			gwClient := gatewayfake.NewSimpleClientset()
 			for _, gw := range tt.gateways {
-				_, err := gwClient.GatewayV1().Gateways(gw.Namespace).Create(ctx, gw, metav1.CreateOptions{})
+				_, err := gwClient.GatewayV1beta1().Gateways(gw.Namespace).Create(ctx, (*v1beta1.Gateway)(gw), metav1.CreateOptions{})
 				require.NoError(t, err, "failed to create Gateway")
 
 			}
 			for _, rt := range tt.routes {
-				_, err := gwClient.GatewayV1().HTTPRoutes(rt.Namespace).Create(ctx, rt, metav1.CreateOptions{})
+				_, err := gwClient.GatewayV1beta1().HTTPRoutes(rt.Namespace).Create(ctx, (*v1beta1.HTTPRoute)(rt), metav1.CreateOptions{})
 				require.NoError(t, err, "failed to create HTTPRoute")
 			}
 			kubeClient := kubefake.NewSimpleClientset()
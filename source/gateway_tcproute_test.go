@@ -27,6 +27,7 @@ import (
 	"sigs.k8s.io/external-dns/endpoint"
 	v1 "sigs.k8s.io/gateway-api/apis/v1"
 	"sigs.k8s.io/gateway-api/apis/v1alpha2"
+	"sigs.k8s.io/gateway-api/apis/v1beta1"
 	gatewayfake "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned/fake"
 )
 
@@ -61,7 +62,7 @@ func TestGatewayTCPRouteSourceEndpoints(t *testing.T) {
 		},
 		Status: gatewayStatus(ips...),
 	}
-	_, err = gwClient.GatewayV1().Gateways(gw.Namespace).Create(ctx, gw, metav1.CreateOptions{})
+	_, err = gwClient.GatewayV1beta1().Gateways(gw.Namespace).Create(ctx, (*v1beta1.Gateway)(gw), metav1.CreateOptions{})
 	require.NoError(t, err, "failed to create Gateway")
 
 	rt := &v1alpha2.TCPRoute{
@@ -93,3 +94,158 @@ func TestGatewayTCPRouteSourceEndpoints(t *testing.T) {
 		newTestEndpoint("api-template.foobar.internal", "A", ips...),
 	})
 }
+
+func TestGatewayTCPRouteSourceListenerHostname(t *testing.T) {
+	t.Parallel()
+
+	gwClient := gatewayfake.NewSimpleClientset()
+	kubeClient := kubefake.NewSimpleClientset()
+	clients := new(MockClientGenerator)
+	clients.On("GatewayClient").Return(gwClient, nil)
+	clients.On("KubeClient").Return(kubeClient, nil)
+
+	ctx := context.Background()
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "default",
+		},
+	}
+	_, err := kubeClient.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
+	require.NoError(t, err, "failed to create Namespace")
+
+	ips := []string{"10.64.0.1"}
+	tlsHost := v1.Hostname("db.foobar.internal")
+	tcpHost := v1.Hostname("ignored.foobar.internal")
+	gw := &v1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "internal",
+			Namespace: "default",
+		},
+		Spec: v1.GatewaySpec{
+			Listeners: []v1.Listener{
+				// A TLS Listener terminating TLS for the TCPRoutes, matching on SNI.
+				{Name: "tls", Protocol: v1.TLSProtocolType, Hostname: &tlsHost},
+				// The hostname of a TCP Listener is ignored.
+				{Name: "tcp", Protocol: v1.TCPProtocolType, Hostname: &tcpHost},
+			},
+		},
+		Status: gatewayStatus(ips...),
+	}
+	_, err = gwClient.GatewayV1beta1().Gateways(gw.Namespace).Create(ctx, (*v1beta1.Gateway)(gw), metav1.CreateOptions{})
+	require.NoError(t, err, "failed to create Gateway")
+
+	for _, rt := range []*v1alpha2.TCPRoute{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Status: v1alpha2.TCPRouteStatus{
+				RouteStatus: gwRouteStatus(gwParentRef("default", "internal", withSectionName("tls"))),
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "raw",
+				Namespace:   "default",
+				Annotations: map[string]string{hostnameAnnotationKey: "raw.foobar.internal"},
+			},
+			Status: v1alpha2.TCPRouteStatus{
+				RouteStatus: gwRouteStatus(gwParentRef("default", "internal", withSectionName("tcp"))),
+			},
+		},
+	} {
+		_, err = gwClient.GatewayV1alpha2().TCPRoutes(rt.Namespace).Create(ctx, rt, metav1.CreateOptions{})
+		require.NoError(t, err, "failed to create TCPRoute")
+	}
+
+	src, err := NewGatewayTCPRouteSource(clients, &Config{})
+	require.NoError(t, err, "failed to create Gateway TCPRoute Source")
+
+	endpoints, err := src.Endpoints(ctx)
+	require.NoError(t, err, "failed to get Endpoints")
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		newTestEndpoint("db.foobar.internal", "A", ips...),
+		newTestEndpoint("raw.foobar.internal", "A", ips...),
+	})
+}
+
+func TestGatewayTCPRouteSourceAllowedNamespaces(t *testing.T) {
+	t.Parallel()
+
+	gwClient := gatewayfake.NewSimpleClientset()
+	kubeClient := kubefake.NewSimpleClientset()
+	clients := new(MockClientGenerator)
+	clients.On("GatewayClient").Return(gwClient, nil)
+	clients.On("KubeClient").Return(kubeClient, nil)
+
+	ctx := context.Background()
+	for _, name := range []string{"gateways", "apps"} {
+		_, err := kubeClient.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}, metav1.CreateOptions{})
+		require.NoError(t, err, "failed to create Namespace")
+	}
+
+	ips := []string{"10.64.0.1"}
+	fromAll := v1.NamespacesFromAll
+	gw := &v1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "internal",
+			Namespace: "gateways",
+		},
+		Spec: v1.GatewaySpec{
+			Listeners: []v1.Listener{
+				// Without allowedRoutes, only the routes of the namespace of the Gateway are allowed.
+				{Name: "same", Protocol: v1.TCPProtocolType},
+				{Name: "all", Protocol: v1.TCPProtocolType, AllowedRoutes: &v1.AllowedRoutes{
+					Namespaces: &v1.RouteNamespaces{From: &fromAll},
+				}},
+			},
+		},
+		Status: gatewayStatus(ips...),
+	}
+	_, err := gwClient.GatewayV1beta1().Gateways(gw.Namespace).Create(ctx, (*v1beta1.Gateway)(gw), metav1.CreateOptions{})
+	require.NoError(t, err, "failed to create Gateway")
+
+	for _, rt := range []*v1alpha2.TCPRoute{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "local",
+				Namespace:   "gateways",
+				Annotations: map[string]string{hostnameAnnotationKey: "local.foobar.internal"},
+			},
+			Status: v1alpha2.TCPRouteStatus{
+				RouteStatus: gwRouteStatus(gwParentRef("gateways", "internal", withSectionName("same"))),
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "denied",
+				Namespace:   "apps",
+				Annotations: map[string]string{hostnameAnnotationKey: "denied.foobar.internal"},
+			},
+			Status: v1alpha2.TCPRouteStatus{
+				RouteStatus: gwRouteStatus(gwParentRef("gateways", "internal", withSectionName("same"))),
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "allowed",
+				Namespace:   "apps",
+				Annotations: map[string]string{hostnameAnnotationKey: "allowed.foobar.internal"},
+			},
+			Status: v1alpha2.TCPRouteStatus{
+				RouteStatus: gwRouteStatus(gwParentRef("gateways", "internal", withSectionName("all"))),
+			},
+		},
+	} {
+		_, err = gwClient.GatewayV1alpha2().TCPRoutes(rt.Namespace).Create(ctx, rt, metav1.CreateOptions{})
+		require.NoError(t, err, "failed to create TCPRoute")
+	}
+
+	src, err := NewGatewayTCPRouteSource(clients, &Config{})
+	require.NoError(t, err, "failed to create Gateway TCPRoute Source")
+
+	endpoints, err := src.Endpoints(ctx)
+	require.NoError(t, err, "failed to get Endpoints")
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		newTestEndpoint("local.foobar.internal", "A", ips...),
+		newTestEndpoint("allowed.foobar.internal", "A", ips...),
+	})
+}
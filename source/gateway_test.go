@@ -143,6 +143,18 @@ func TestGatewayMatchingProtocol(t *testing.T) {
 			lis:   "TCP",
 			ok:    false,
 		},
+		{
+			desc:  "protocol-match-valid-lis-http-route-grpc",
+			route: "HTTPS",
+			lis:   "HTTP",
+			ok:    true,
+		},
+		{
+			desc:  "protocol-match-invalid-lis-tcp-route-udp",
+			route: "UDP",
+			lis:   "TCP",
+			ok:    false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -161,6 +173,45 @@ func TestGatewayMatchingProtocol(t *testing.T) {
 	}
 }
 
+func TestGatewayListenerHost(t *testing.T) {
+	host := v1.Hostname("db.example.net")
+	tests := []struct {
+		desc string
+		lis  v1.Listener
+		host string
+	}{
+		{
+			desc: "no-hostname",
+			lis:  v1.Listener{Protocol: v1.TLSProtocolType},
+		},
+		{
+			desc: "tls",
+			lis:  v1.Listener{Protocol: v1.TLSProtocolType, Hostname: &host},
+			host: "db.example.net",
+		},
+		{
+			desc: "https",
+			lis:  v1.Listener{Protocol: v1.HTTPSProtocolType, Hostname: &host},
+			host: "db.example.net",
+		},
+		{
+			desc: "tcp-ignored",
+			lis:  v1.Listener{Protocol: v1.TCPProtocolType, Hostname: &host},
+		},
+		{
+			desc: "udp-ignored",
+			lis:  v1.Listener{Protocol: v1.UDPProtocolType, Hostname: &host},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if host := gwListenerHost(&tt.lis); host != tt.host {
+				t.Errorf("gwListenerHost(%s); got: %q; want: %q", tt.desc, host, tt.host)
+			}
+		})
+	}
+}
+
 func TestIsDNS1123Domain(t *testing.T) {
 	tests := []struct {
 		desc string
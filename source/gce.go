@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
+)
+
+const (
+	// GCEInternalAddress publishes the internal IPs of the instances.
+	GCEInternalAddress = "internal"
+	// GCEExternalAddress publishes the external IPs of the instances.
+	GCEExternalAddress = "external"
+)
+
+// GCEConfig is the configuration of the gce source.
+type GCEConfig struct {
+	// Project of the instances, the project of the metadata server if empty.
+	Project string
+	// Suffix of the hostnames: an instance is published as NAME.ZONE.SUFFIX.
+	Suffix string
+	// Address is GCEInternalAddress or GCEExternalAddress.
+	Address string
+	// LabelFilter selects the instances by labels, all if empty.
+	LabelFilter string
+}
+
+// gceInstance is an instance of Compute Engine, as used by the source.
+type gceInstance struct {
+	Name        string
+	Zone        string
+	Labels      map[string]string
+	InternalIPs []string
+	ExternalIPs []string
+}
+
+// gceInstancesAPI lists the running instances of a project.
+type gceInstancesAPI interface {
+	runningInstances(ctx context.Context, project string) ([]gceInstance, error)
+}
+
+// gceSource is an implementation of Source for the Compute Engine instances
+// of a project, published as NAME.ZONE.SUFFIX - so the VMs and the GKE
+// workloads of a hybrid project share a private zone.
+type gceSource struct {
+	cfg      GCEConfig
+	selector labels.Selector
+	api      gceInstancesAPI
+	log      *slog.Logger
+}
+
+// NewGCESource returns a source for the running instances of the project,
+// with the default credentials.
+func NewGCESource(ctx context.Context, cfg GCEConfig) (Source, error) {
+	api, err := newGCEClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("gce source: %w", err)
+	}
+	if cfg.Project == "" {
+		if cfg.Project, err = gceProjectID(); err != nil {
+			return nil, fmt.Errorf("gce source: no project: %w", err)
+		}
+	}
+	return newGCESource(cfg, api)
+}
+
+func newGCESource(cfg GCEConfig, api gceInstancesAPI) (Source, error) {
+	if cfg.Suffix == "" {
+		return nil, fmt.Errorf("the gce source requires --gce-suffix")
+	}
+	if cfg.Address == "" {
+		cfg.Address = GCEInternalAddress
+	}
+	if cfg.Address != GCEInternalAddress && cfg.Address != GCEExternalAddress {
+		return nil, fmt.Errorf("invalid gce address %q: use %s or %s", cfg.Address, GCEInternalAddress, GCEExternalAddress)
+	}
+	selector, err := labels.Parse(cfg.LabelFilter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gce label filter %q: %w", cfg.LabelFilter, err)
+	}
+	cfg.Suffix = strings.Trim(cfg.Suffix, ".")
+	return &gceSource{
+		cfg:      cfg,
+		selector: selector,
+		api:      api,
+		log:      logging.For("source/gce"),
+	}, nil
+}
+
+// Endpoints returns the A and AAAA records of the running instances with the
+// labels. An API error fails the sync, so the records are not deleted.
+func (gs *gceSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	instances, err := gs.api.runningInstances(ctx, gs.cfg.Project)
+	if err != nil {
+		return nil, fmt.Errorf("gce source: %w", err)
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Zone != instances[j].Zone {
+			return instances[i].Zone < instances[j].Zone
+		}
+		return instances[i].Name < instances[j].Name
+	})
+
+	var endpoints []*endpoint.Endpoint
+	for _, instance := range instances {
+		if !gs.selector.Matches(labels.Set(instance.Labels)) {
+			continue
+		}
+		targets := instance.InternalIPs
+		if gs.cfg.Address == GCEExternalAddress {
+			targets = instance.ExternalIPs
+		}
+		if len(targets) == 0 {
+			gs.log.Debug("Skipping instance without addresses", "instance", instance.Name, "zone", instance.Zone, "address", gs.cfg.Address)
+			continue
+		}
+		hostname := instance.Name + "." + instance.Zone + "." + gs.cfg.Suffix
+		resource := "gce/" + gs.cfg.Project + "/" + instance.Zone + "/" + instance.Name
+		endpoints = append(endpoints, endpointsForHostname(hostname, targets, 0, nil, "", resource)...)
+	}
+	gs.log.Debug("Listed instances", "project", gs.cfg.Project, "instances", len(instances), "endpoints", len(endpoints))
+	return endpoints, nil
+}
+
+// AddEventHandler does nothing: the instances are listed at each sync.
+func (gs *gceSource) AddEventHandler(ctx context.Context, handler func()) {
+}
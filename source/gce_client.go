@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"path"
+
+	"cloud.google.com/go/compute/metadata"
+	"github.com/linki/instrumented_http"
+	"golang.org/x/oauth2/google"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+)
+
+// gceClient implements gceInstancesAPI and gceForwardingRulesAPI with the
+// Compute Engine API.
+type gceClient struct {
+	service *compute.Service
+}
+
+func newGCEClient(ctx context.Context) (*gceClient, error) {
+	client, err := google.DefaultClient(ctx, compute.ComputeReadonlyScope)
+	if err != nil {
+		return nil, err
+	}
+	client = instrumented_http.NewClient(client, &instrumented_http.Callbacks{})
+	service, err := compute.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, err
+	}
+	return &gceClient{service: service}, nil
+}
+
+// runningInstances returns the running instances of all the zones of the
+// project.
+func (c *gceClient) runningInstances(ctx context.Context, project string) ([]gceInstance, error) {
+	var instances []gceInstance
+	call := c.service.Instances.AggregatedList(project).Filter(`status = "RUNNING"`).Context(ctx)
+	err := call.Pages(ctx, func(page *compute.InstanceAggregatedList) error {
+		for _, scoped := range page.Items {
+			for _, i := range scoped.Instances {
+				instances = append(instances, gceInstanceOf(i))
+			}
+		}
+		return nil
+	})
+	return instances, err
+}
+
+// gceInstanceOf returns the instance with the IPs of its network interfaces.
+func gceInstanceOf(i *compute.Instance) gceInstance {
+	instance := gceInstance{
+		Name:   i.Name,
+		Zone:   path.Base(i.Zone),
+		Labels: i.Labels,
+	}
+	for _, ni := range i.NetworkInterfaces {
+		if ni.NetworkIP != "" {
+			instance.InternalIPs = append(instance.InternalIPs, ni.NetworkIP)
+		}
+		if ni.Ipv6Address != "" {
+			instance.InternalIPs = append(instance.InternalIPs, ni.Ipv6Address)
+		}
+		for _, ac := range ni.AccessConfigs {
+			if ac.NatIP != "" {
+				instance.ExternalIPs = append(instance.ExternalIPs, ac.NatIP)
+			}
+		}
+		for _, ac := range ni.Ipv6AccessConfigs {
+			if ac.ExternalIpv6 != "" {
+				instance.ExternalIPs = append(instance.ExternalIPs, ac.ExternalIpv6)
+			}
+		}
+	}
+	return instance
+}
+
+// forwardingRules returns the forwarding rules of all the regions of the
+// project, and the global ones in the "global" region.
+func (c *gceClient) forwardingRules(ctx context.Context, project string) ([]gceForwardingRule, error) {
+	var rules []gceForwardingRule
+	err := c.service.ForwardingRules.AggregatedList(project).Context(ctx).Pages(ctx, func(page *compute.ForwardingRuleAggregatedList) error {
+		for _, scoped := range page.Items {
+			for _, r := range scoped.ForwardingRules {
+				rules = append(rules, gceForwardingRuleOf(r, path.Base(r.Region)))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.service.GlobalForwardingRules.List(project).Context(ctx).Pages(ctx, func(page *compute.ForwardingRuleList) error {
+		for _, r := range page.Items {
+			rules = append(rules, gceForwardingRuleOf(r, "global"))
+		}
+		return nil
+	})
+	return rules, err
+}
+
+func gceForwardingRuleOf(r *compute.ForwardingRule, region string) gceForwardingRule {
+	return gceForwardingRule{
+		Name:        r.Name,
+		Region:      region,
+		Description: r.Description,
+		Labels:      r.Labels,
+		IPAddress:   r.IPAddress,
+	}
+}
+
+// gceProjectID returns the project of the metadata server.
+func gceProjectID() (string, error) {
+	return metadata.ProjectID()
+}
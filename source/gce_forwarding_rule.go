@@ -0,0 +1,179 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
+)
+
+// forwardingRuleNameTag is the prefix of the token of the description of a
+// forwarding rule with its hostnames, comma separated, like
+// dns.name=app.example.com.
+const forwardingRuleNameTag = "dns.name="
+
+// ForwardingRuleConfig is the configuration of the gce-forwarding-rule source.
+type ForwardingRuleConfig struct {
+	// Project of the forwarding rules, the project of the metadata server if
+	// empty.
+	Project string
+	// Suffix of the hostnames of the rules without a dns.name= description:
+	// a rule is published as NAME.SUFFIX - they are skipped if empty.
+	Suffix string
+	// LabelFilter selects the rules by labels, all if empty.
+	LabelFilter string
+	// DescriptionFilter is a regular expression selecting the rules by
+	// description, all if empty.
+	DescriptionFilter string
+}
+
+// gceForwardingRule is a regional or global forwarding rule, as used by the
+// source.
+type gceForwardingRule struct {
+	Name        string
+	Region      string
+	Description string
+	Labels      map[string]string
+	IPAddress   string
+}
+
+// gceForwardingRulesAPI lists the regional and global forwarding rules of a
+// project.
+type gceForwardingRulesAPI interface {
+	forwardingRules(ctx context.Context, project string) ([]gceForwardingRule, error)
+}
+
+// forwardingRuleSource is an implementation of Source for the forwarding
+// rules of a project - the load balancers created outside of Kubernetes, by
+// Terraform or gcloud.
+type forwardingRuleSource struct {
+	cfg         ForwardingRuleConfig
+	selector    labels.Selector
+	description *regexp.Regexp
+	api         gceForwardingRulesAPI
+	log         *slog.Logger
+}
+
+// NewForwardingRuleSource returns a source for the forwarding rules of the
+// project, with the default credentials.
+func NewForwardingRuleSource(ctx context.Context, cfg ForwardingRuleConfig) (Source, error) {
+	api, err := newGCEClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("gce-forwarding-rule source: %w", err)
+	}
+	if cfg.Project == "" {
+		if cfg.Project, err = gceProjectID(); err != nil {
+			return nil, fmt.Errorf("gce-forwarding-rule source: no project: %w", err)
+		}
+	}
+	return newForwardingRuleSource(cfg, api)
+}
+
+func newForwardingRuleSource(cfg ForwardingRuleConfig, api gceForwardingRulesAPI) (Source, error) {
+	selector, err := labels.Parse(cfg.LabelFilter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid forwarding rule label filter %q: %w", cfg.LabelFilter, err)
+	}
+	description, err := regexp.Compile(cfg.DescriptionFilter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid forwarding rule description filter %q: %w", cfg.DescriptionFilter, err)
+	}
+	cfg.Suffix = strings.Trim(cfg.Suffix, ".")
+	return &forwardingRuleSource{
+		cfg:         cfg,
+		selector:    selector,
+		description: description,
+		api:         api,
+		log:         logging.For("source/gce-forwarding-rule"),
+	}, nil
+}
+
+// Endpoints returns the records of the forwarding rules with the labels and
+// the description, each hostname pointing to the IPs of all its rules - like
+// the HTTP and HTTPS rules of a load balancer. An API error fails the sync,
+// so the records are not deleted.
+func (fs *forwardingRuleSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	rules, err := fs.api.forwardingRules(ctx, fs.cfg.Project)
+	if err != nil {
+		return nil, fmt.Errorf("gce-forwarding-rule source: %w", err)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Region != rules[j].Region {
+			return rules[i].Region < rules[j].Region
+		}
+		return rules[i].Name < rules[j].Name
+	})
+
+	targets := map[string]endpoint.Targets{}
+	resources := map[string]string{}
+	var hostnames []string
+	for _, rule := range rules {
+		if !fs.selector.Matches(labels.Set(rule.Labels)) || !fs.description.MatchString(rule.Description) {
+			continue
+		}
+		if rule.IPAddress == "" {
+			continue
+		}
+		names := forwardingRuleHostnames(rule.Description)
+		if len(names) == 0 && fs.cfg.Suffix != "" {
+			names = []string{rule.Name + "." + fs.cfg.Suffix}
+		}
+		for _, hostname := range names {
+			if _, ok := targets[hostname]; !ok {
+				hostnames = append(hostnames, hostname)
+				// The first rule of a hostname is its resource.
+				resources[hostname] = "forwardingrule/" + fs.cfg.Project + "/" + rule.Region + "/" + rule.Name
+			}
+			if !containsString(targets[hostname], rule.IPAddress) {
+				targets[hostname] = append(targets[hostname], rule.IPAddress)
+			}
+		}
+	}
+
+	var endpoints []*endpoint.Endpoint
+	for _, hostname := range hostnames {
+		sort.Strings(targets[hostname])
+		endpoints = append(endpoints, endpointsForHostname(hostname, targets[hostname], 0, nil, "", resources[hostname])...)
+	}
+	fs.log.Debug("Listed forwarding rules", "project", fs.cfg.Project, "rules", len(rules), "endpoints", len(endpoints))
+	return endpoints, nil
+}
+
+// forwardingRuleHostnames returns the hostnames of the dns.name= tokens of a
+// description.
+func forwardingRuleHostnames(description string) []string {
+	var hostnames []string
+	for _, token := range strings.Fields(description) {
+		if strings.HasPrefix(token, forwardingRuleNameTag) {
+			hostnames = append(hostnames, splitLabel(strings.TrimPrefix(token, forwardingRuleNameTag))...)
+		}
+	}
+	return hostnames
+}
+
+// AddEventHandler does nothing: the forwarding rules are listed at each sync.
+func (fs *forwardingRuleSource) AddEventHandler(ctx context.Context, handler func()) {
+}
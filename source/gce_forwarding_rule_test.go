@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+type fakeForwardingRules struct {
+	rules   []gceForwardingRule
+	err     error
+	project string
+}
+
+func (f *fakeForwardingRules) forwardingRules(_ context.Context, project string) ([]gceForwardingRule, error) {
+	f.project = project
+	return f.rules, f.err
+}
+
+var testForwardingRules = []gceForwardingRule{
+	{Name: "shop-https", Region: "global", Description: "Shop dns.name=shop.example.com,www.example.com",
+		Labels: map[string]string{"team": "shop"}, IPAddress: "34.120.1.2"},
+	{Name: "shop-http", Region: "global", Description: "Shop redirect dns.name=shop.example.com",
+		Labels: map[string]string{"team": "shop"}, IPAddress: "34.120.1.2"},
+	{Name: "shop-v6", Region: "global", Description: "dns.name=shop.example.com",
+		Labels: map[string]string{"team": "shop"}, IPAddress: "2600:1901::1"},
+	{Name: "billing-ilb", Region: "us-central1", Description: "terraform",
+		Labels: map[string]string{"team": "billing"}, IPAddress: "10.128.0.50"},
+	{Name: "legacy", Region: "europe-west1", Description: "dns.name=legacy.example.com",
+		Labels: map[string]string{"team": "legacy", "dns": "off"}, IPAddress: "35.1.2.3"},
+	{Name: "pending", Region: "us-central1", Description: "dns.name=pending.example.com"},
+}
+
+func TestForwardingRuleSourceEndpoints(t *testing.T) {
+	api := &fakeForwardingRules{rules: testForwardingRules}
+	src, err := newForwardingRuleSource(ForwardingRuleConfig{Project: "net", Suffix: "lb.example.com.", LabelFilter: "dns!=off"}, api)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "net", api.project)
+	// The resource of a hostname is its first rule, by region and name.
+	shop := map[string]string{endpoint.ResourceLabelKey: "forwardingrule/net/global/shop-http"}
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "billing-ilb.lb.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.128.0.50"},
+			Labels: map[string]string{endpoint.ResourceLabelKey: "forwardingrule/net/us-central1/billing-ilb"}},
+		{DNSName: "shop.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"34.120.1.2"}, Labels: shop},
+		{DNSName: "shop.example.com", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2600:1901::1"}, Labels: shop},
+		{DNSName: "www.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"34.120.1.2"},
+			Labels: map[string]string{endpoint.ResourceLabelKey: "forwardingrule/net/global/shop-https"}},
+	})
+}
+
+func TestForwardingRuleSourceFilters(t *testing.T) {
+	src, err := newForwardingRuleSource(ForwardingRuleConfig{Project: "net", DescriptionFilter: "^Shop", LabelFilter: "team=shop"},
+		&fakeForwardingRules{rules: testForwardingRules})
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+
+	// Without a suffix, only the rules with a dns.name= description are published.
+	require.Len(t, endpoints, 2)
+	assert.Equal(t, "shop.example.com", endpoints[0].DNSName)
+	assert.Equal(t, endpoint.Targets{"34.120.1.2"}, endpoints[0].Targets)
+	assert.Equal(t, "www.example.com", endpoints[1].DNSName)
+}
+
+func TestForwardingRuleHostnames(t *testing.T) {
+	assert.Equal(t, []string{"a.example.com", "b.example.com"}, forwardingRuleHostnames("web dns.name=a.example.com,b.example.com owner=x"))
+	assert.Equal(t, []string{"a.example.com", "c.example.com"}, forwardingRuleHostnames("dns.name=a.example.com dns.name=c.example.com"))
+	assert.Empty(t, forwardingRuleHostnames("dns.names=a.example.com"))
+}
+
+func TestForwardingRuleSourceErrors(t *testing.T) {
+	api := &fakeForwardingRules{err: errors.New("quota exceeded")}
+	src, err := newForwardingRuleSource(ForwardingRuleConfig{Project: "net"}, api)
+	require.NoError(t, err)
+	_, err = src.Endpoints(context.Background())
+	assert.ErrorContains(t, err, "quota exceeded")
+
+	for _, cfg := range []ForwardingRuleConfig{
+		{Project: "net", LabelFilter: "team in (shop"},
+		{Project: "net", DescriptionFilter: "(shop"},
+	} {
+		_, err := newForwardingRuleSource(cfg, api)
+		assert.Error(t, err, "%+v", cfg)
+	}
+}
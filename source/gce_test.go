@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+type fakeGCEInstances struct {
+	instances []gceInstance
+	err       error
+	project   string
+}
+
+func (f *fakeGCEInstances) runningInstances(_ context.Context, project string) ([]gceInstance, error) {
+	f.project = project
+	return f.instances, f.err
+}
+
+var testGCEInstances = []gceInstance{
+	{Name: "web-1", Zone: "us-central1-b", Labels: map[string]string{"env": "prod", "role": "web"},
+		InternalIPs: []string{"10.128.0.3", "fd20::3"}, ExternalIPs: []string{"34.1.2.3"}},
+	{Name: "db-1", Zone: "us-central1-a", Labels: map[string]string{"env": "prod", "role": "db"},
+		InternalIPs: []string{"10.128.0.2"}},
+	{Name: "dev-1", Zone: "europe-west1-b", Labels: map[string]string{"env": "dev"},
+		InternalIPs: []string{"10.132.0.2"}, ExternalIPs: []string{"35.1.2.3"}},
+}
+
+func TestGCESourceEndpoints(t *testing.T) {
+	api := &fakeGCEInstances{instances: testGCEInstances}
+	src, err := newGCESource(GCEConfig{Project: "hybrid", Suffix: "gce.example.com.", LabelFilter: "env=prod"}, api)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "hybrid", api.project)
+	web := map[string]string{endpoint.ResourceLabelKey: "gce/hybrid/us-central1-b/web-1"}
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "db-1.us-central1-a.gce.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.128.0.2"},
+			Labels: map[string]string{endpoint.ResourceLabelKey: "gce/hybrid/us-central1-a/db-1"}},
+		{DNSName: "web-1.us-central1-b.gce.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.128.0.3"}, Labels: web},
+		{DNSName: "web-1.us-central1-b.gce.example.com", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"fd20::3"}, Labels: web},
+	})
+}
+
+func TestGCESourceExternal(t *testing.T) {
+	src, err := newGCESource(GCEConfig{Project: "hybrid", Suffix: "example.com", Address: GCEExternalAddress}, &fakeGCEInstances{instances: testGCEInstances})
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+
+	// db-1 has no external IP.
+	require.Len(t, endpoints, 2)
+	assert.Equal(t, "dev-1.europe-west1-b.example.com", endpoints[0].DNSName)
+	assert.Equal(t, endpoint.Targets{"35.1.2.3"}, endpoints[0].Targets)
+	assert.Equal(t, "web-1.us-central1-b.example.com", endpoints[1].DNSName)
+	assert.Equal(t, endpoint.Targets{"34.1.2.3"}, endpoints[1].Targets)
+}
+
+func TestGCESourceErrors(t *testing.T) {
+	api := &fakeGCEInstances{err: errors.New("permission denied")}
+	src, err := newGCESource(GCEConfig{Project: "hybrid", Suffix: "example.com"}, api)
+	require.NoError(t, err)
+	_, err = src.Endpoints(context.Background())
+	assert.ErrorContains(t, err, "permission denied")
+
+	for _, cfg := range []GCEConfig{
+		{Project: "hybrid"},
+		{Project: "hybrid", Suffix: "example.com", Address: "public"},
+		{Project: "hybrid", Suffix: "example.com", LabelFilter: "env in (prod"},
+	} {
+		_, err := newGCESource(cfg, api)
+		assert.Error(t, err, "%+v", cfg)
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// HealthChecker is implemented by the sources which can tell a broken
+// collection from an empty one, like the informer sources - without it, a
+// source with a broken informer returns no endpoints and the plan deletes
+// their records.
+type HealthChecker interface {
+	// Healthy returns an error while the source is not synced or fails to
+	// list its resources.
+	Healthy() error
+	// LastEvent returns the time of the last change of the resources of the
+	// source, zero if none.
+	LastEvent() time.Time
+}
+
+// Healthy returns the error of the source if it implements HealthChecker.
+func Healthy(s Source) error {
+	if h, ok := s.(HealthChecker); ok {
+		return h.Healthy()
+	}
+	return nil
+}
+
+// LastEvent returns the time of the last event of the source if it implements
+// HealthChecker.
+func LastEvent(s Source) time.Time {
+	if h, ok := s.(HealthChecker); ok {
+		return h.LastEvent()
+	}
+	return time.Time{}
+}
+
+// Status returns the health of the source, for the status of its DNSSource.
+func Status(s Source) endpoint.DNSSourceStatus {
+	status := endpoint.DNSSourceStatus{Healthy: true}
+	if err := Healthy(s); err != nil {
+		status.Healthy = false
+		status.Message = err.Error()
+	}
+	if t := LastEvent(s); !t.IsZero() {
+		status.LastEvent = &metav1.Time{Time: t}
+	}
+	return status
+}
+
+// watchErrorWindow is the time a list or watch error makes the informers
+// unhealthy, unless an event is received since: the reflectors retry with a
+// backoff of at most 30s, so an informer still failing reports an error
+// again within it.
+const watchErrorWindow = time.Minute
+
+// informerHealth is the health of the informers of a source. Embedded in the
+// informer sources, it implements HealthChecker.
+type informerHealth struct {
+	informers map[string]cache.SharedInformer
+
+	mu            sync.Mutex
+	lastError     error
+	lastErrorTime time.Time
+	lastEvent     time.Time
+}
+
+// trackInformers returns the health of the informers, by resource like
+// "services". The informers must not be started yet.
+func trackInformers(informers map[string]cache.SharedInformer) *informerHealth {
+	h := &informerHealth{informers: informers}
+	for resource, informer := range informers {
+		err := informer.SetWatchErrorHandler(func(r *cache.Reflector, err error) {
+			cache.DefaultWatchErrorHandler(r, err)
+			h.setError(fmt.Errorf("failed to list %s: %w", resource, err))
+		})
+		if err != nil {
+			log.Debugf("Not tracking the list errors of %s: %v", resource, err)
+		}
+		informer.AddEventHandler(eventHandlerFunc(h.event))
+	}
+	return h
+}
+
+func (h *informerHealth) setError(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastError = err
+	h.lastErrorTime = time.Now()
+}
+
+func (h *informerHealth) event() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastEvent = time.Now()
+}
+
+// Healthy returns an error while an informer is not synced or stopped, or
+// failed to list or watch its resources since the last event.
+func (h *informerHealth) Healthy() error {
+	if h == nil {
+		return nil
+	}
+	resources := make([]string, 0, len(h.informers))
+	for resource := range h.informers {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	for _, resource := range resources {
+		informer := h.informers[resource]
+		if informer.IsStopped() {
+			return fmt.Errorf("the informer of %s is stopped", resource)
+		}
+		if !informer.HasSynced() {
+			return fmt.Errorf("the informer of %s is not synced", resource)
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.lastError != nil && time.Since(h.lastErrorTime) < watchErrorWindow && h.lastEvent.Before(h.lastErrorTime) {
+		return h.lastError
+	}
+	return nil
+}
+
+// LastEvent returns the time of the last event of the informers.
+func (h *informerHealth) LastEvent() time.Time {
+	if h == nil {
+		return time.Time{}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lastEvent
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestInformerHealth(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	factory := kubeinformers.NewSharedInformerFactory(client, 0)
+	health := trackInformers(map[string]cache.SharedInformer{
+		"pods": factory.Core().V1().Pods().Informer(),
+	})
+	assert.EqualError(t, health.Healthy(), "the informer of pods is not synced")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	factory.Start(ctx.Done())
+	require.NoError(t, waitForCacheSync(ctx, factory))
+	assert.NoError(t, health.Healthy())
+	assert.True(t, health.LastEvent().IsZero(), "no pod yet")
+
+	_, err := client.CoreV1().Pods("default").Create(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return !health.LastEvent().IsZero() }, 5*time.Second, 10*time.Millisecond)
+
+	// A list error after the last event makes the informer unhealthy, until
+	// the next event.
+	health.setError(errors.New("failed to list pods: forbidden"))
+	assert.EqualError(t, health.Healthy(), "failed to list pods: forbidden")
+	health.event()
+	assert.NoError(t, health.Healthy())
+
+	cancel()
+	require.Eventually(t, func() bool { return health.Healthy() != nil }, 5*time.Second, 10*time.Millisecond)
+	assert.EqualError(t, health.Healthy(), "the informer of pods is stopped")
+
+	var none *informerHealth
+	assert.NoError(t, none.Healthy())
+}
+
+// healthSource is a funcSource with the health of a function.
+type healthSource struct {
+	funcSource
+	healthy func() error
+}
+
+func (s healthSource) Healthy() error {
+	return s.healthy()
+}
+
+func (s healthSource) LastEvent() time.Time {
+	return time.Unix(100, 0)
+}
+
+func TestMultiSourceHealthy(t *testing.T) {
+	foo := &endpoint.Endpoint{DNSName: "foo", Targets: endpoint.Targets{"8.8.8.8"}}
+	var broken atomic.Bool
+	src := healthSource{
+		funcSource: func(ctx context.Context) ([]*endpoint.Endpoint, error) {
+			if broken.Load() {
+				return nil, nil
+			}
+			return []*endpoint.Endpoint{foo}, nil
+		},
+		healthy: func() error {
+			if broken.Load() {
+				return errors.New("the informer of services is not synced")
+			}
+			return nil
+		},
+	}
+
+	broken.Store(true)
+	ms := NewMultiSource([]Source{src}, nil, WithSourceNames([]string{"service"}))
+	_, err := ms.Endpoints(context.Background())
+	assert.EqualError(t, err, "source service: not healthy: the informer of services is not synced")
+	assert.EqualError(t, Healthy(ms), "source service: the informer of services is not synced")
+	assert.Equal(t, time.Unix(100, 0), LastEvent(ms))
+
+	// With WithSkipFailingSources, an unhealthy source reuses the endpoints
+	// of its last collection.
+	ms = NewMultiSource([]Source{NewDedupSource(src)}, nil, WithSkipFailingSources())
+	broken.Store(false)
+	_, err = ms.Endpoints(context.Background())
+	require.NoError(t, err)
+	broken.Store(true)
+	endpoints, err := ms.Endpoints(context.Background())
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{foo})
+	assert.NoError(t, Healthy(ms))
+}
+
+func TestStatus(t *testing.T) {
+	status := Status(healthSource{healthy: func() error { return errors.New("failed to list pods: forbidden") }})
+	assert.False(t, status.Healthy)
+	assert.Equal(t, "failed to list pods: forbidden", status.Message)
+	assert.Equal(t, time.Unix(100, 0), status.LastEvent.Time)
+
+	status = Status(funcSource(nil))
+	assert.True(t, status.Healthy)
+	assert.Nil(t, status.LastEvent)
+}
@@ -65,6 +65,8 @@ type ingressSource struct {
 	ignoreIngressTLSSpec     bool
 	ignoreIngressRulesSpec   bool
 	labelSelector            labels.Selector
+
+	*informerHealth
 }
 
 // NewIngressSource creates a new ingressSource with the given config.
@@ -102,6 +104,10 @@ func NewIngressSource(ctx context.Context, kubeClient kubernetes.Interface, name
 		},
 	)
 
+	health := trackInformers(map[string]cache.SharedInformer{
+		"ingresses": ingressInformer.Informer(),
+	})
+
 	informerFactory.Start(ctx.Done())
 
 	// wait for the local cache to be populated.
@@ -121,6 +127,7 @@ func NewIngressSource(ctx context.Context, kubeClient kubernetes.Interface, name
 		ignoreIngressTLSSpec:     ignoreIngressTLSSpec,
 		ignoreIngressRulesSpec:   ignoreIngressRulesSpec,
 		labelSelector:            labelSelector,
+		informerHealth:           health,
 	}
 	return sc, nil
 }
@@ -170,6 +177,8 @@ func (sc *ingressSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, e
 			continue
 		}
 
+		ingEndpoints = endpointsForViews(ingEndpoints, ing.Annotations)
+
 		log.Debugf("Endpoints generated from ingress: %s/%s: %v", ing.Namespace, ing.Name, ingEndpoints)
 		sc.setDualstackLabel(ing, ingEndpoints)
 		endpoints = append(endpoints, ingEndpoints...)
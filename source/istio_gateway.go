@@ -55,6 +55,8 @@ type gatewaySource struct {
 	ignoreHostnameAnnotation bool
 	serviceInformer          coreinformers.ServiceInformer
 	gatewayInformer          networkingv1alpha3informer.GatewayInformer
+
+	*informerHealth
 }
 
 // NewIstioGatewaySource creates a new gatewaySource with the given config.
@@ -97,6 +99,11 @@ func NewIstioGatewaySource(
 		},
 	)
 
+	health := trackInformers(map[string]cache.SharedInformer{
+		"services": serviceInformer.Informer(),
+		"gateways": gatewayInformer.Informer(),
+	})
+
 	informerFactory.Start(ctx.Done())
 	istioInformerFactory.Start(ctx.Done())
 
@@ -118,6 +125,7 @@ func NewIstioGatewaySource(
 		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
 		serviceInformer:          serviceInformer,
 		gatewayInformer:          gatewayInformer,
+		informerHealth:           health,
 	}, nil
 }
 
@@ -21,31 +21,65 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/netip"
 	"sort"
+	"strings"
+	"sync"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"istio.io/api/networking/v1alpha3"
 	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
 	istioclient "istio.io/client-go/pkg/clientset/versioned"
 	istioinformers "istio.io/client-go/pkg/informers/externalversions"
 	networkingv1alpha3informer "istio.io/client-go/pkg/informers/externalversions/networking/v1alpha3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 	// Integration with external-dns - implement the source interface.
 	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/ipam"
+	"sigs.k8s.io/external-dns/pkg/logging"
 )
 
 // TODO:
-// - reverse external dns - create SE from DNS as source of truth
 // - policy - may also live in the controller or DNS updater !
-// - split mode - generate records.yaml from each K8S cluster or from files,
-//   second tool will read the records.yaml plus existing entries and update the DNS
 // - it should also work offline, using files (CI/CD mode). Review and apply independently.
-// - patch the SE for auto-alloc - or for the DNS IP from DNS ( other cluster or tool auto-allocs)
-// - don't auto-alloc for http, https
 // - multi-cluster - setup a set of clusters ( kubeconfig or the Istio MC), do reverse update (possibly using a primary config cluster)
 
+var (
+	serviceEntriesGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "source",
+			Name:      "istio_service_entries",
+			Help:      "Number of ServiceEntries generating records, by location (internal, external).",
+		},
+		[]string{"location"},
+	)
+	serviceEntryErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "source",
+			Name:      "istio_service_entry_errors_total",
+			Help:      "Number of failures to reserve or allocate a ServiceEntry VIP, or to patch it in the ServiceEntry, by operation (reserve, allocate, patch).",
+		},
+		[]string{"operation"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(serviceEntriesGauge)
+	prometheus.MustRegister(serviceEntryErrorsTotal)
+}
+
+// seLogger returns the logger of the istio-se source.
+func seLogger() *slog.Logger {
+	return logging.For("source/istio-se")
+}
+
 // ServiceEntrySource is an implementation of Source for Istio ServiceEntry objects.
 //
 // It is strongly recommended to only use ServiceEntry as DNS config for mesh internal
@@ -60,6 +94,22 @@ type ServiceEntrySource struct {
 	seInformer  networkingv1alpha3informer.ServiceEntryInformer
 	ServiceEntrySourceConfig
 	syncHandler *OnAnyChange
+
+	// selectorsMu guards the fields of ServiceEntrySelectors, changed by
+	// SetSelectors.
+	selectorsMu sync.RWMutex
+
+	*informerHealth
+}
+
+// ServiceEntrySelectors are the fields of the ServiceEntrySourceConfig
+// selecting the ServiceEntries and the records of the reverse sync. The
+// informer watches all the namespaces, so they can change without listing the
+// ServiceEntries again.
+type ServiceEntrySelectors struct {
+	MeshExternalNamespace string
+	ReverseDomainFilter   endpoint.DomainFilter
+	ReverseLabelFilter    labels.Selector
 }
 
 type ServiceEntrySourceConfig struct {
@@ -84,7 +134,29 @@ type ServiceEntrySourceConfig struct {
 	// generate a listener for the VIP and route based on the Host header.
 	HttpVIP string
 
+	// Allocator assigns VIPs to MESH_INTERNAL ServiceEntry without addresses.
+	// Existing addresses in the pools are reserved.
+	Allocator *ipam.Allocator
+
+	// EgressAllocator assigns VIPs to MESH_EXTERNAL ServiceEntry without addresses.
+	// If not set, it is created from the EgressGatewayVIP entries that are CIDRs.
+	EgressAllocator *ipam.Allocator
+
+	// UpdateServiceEntry patches the allocated VIP into the ServiceEntry addresses.
 	UpdateServiceEntry bool
+
+	// ReverseNamespace enables the reverse sync - MESH_EXTERNAL ServiceEntries are
+	// generated in this namespace from the provider records.
+	ReverseNamespace string
+
+	// ReverseDomainFilter and ReverseLabelFilter select the records for the reverse sync.
+	ReverseDomainFilter endpoint.DomainFilter
+	ReverseLabelFilter  labels.Selector
+
+	// GeoRouting sets the region of the localities of the ServiceEntry
+	// endpoints, or GeoRegion, as the geo location of the records.
+	GeoRouting bool
+	GeoRegion  string
 }
 
 func NewIstioServiceEntrySourceConfig(
@@ -102,12 +174,31 @@ func NewIstioServiceEntrySourceConfig(
 
 	ses.syncHandler.source = ses
 
+	if ses.EgressAllocator == nil {
+		var cidrs []string
+		for _, vip := range ses.EgressGatewayVIP {
+			if strings.Contains(vip, "/") {
+				cidrs = append(cidrs, vip)
+			}
+		}
+		if len(cidrs) > 0 {
+			alloc, err := ipam.NewAllocator(cidrs, nil)
+			if err != nil {
+				return nil, err
+			}
+			ses.EgressAllocator = alloc
+		}
+	}
+
 	// Use shared informers to listen for add/update/delete of services/pods/nodes in the specified namespace.
 
 	istioInformerFactory := istioinformers.NewSharedInformerFactoryWithOptions(istioClient, 0, istioinformers.WithNamespace(""))
 	serviceEntryInformer := istioInformerFactory.Networking().V1alpha3().ServiceEntries()
 
 	ses.seInformer = serviceEntryInformer
+	ses.informerHealth = trackInformers(map[string]cache.SharedInformer{
+		"serviceentries": serviceEntryInformer.Informer(),
+	})
 
 	// Add default resource event handlers to properly initialize informer.
 	// This is required to avoid missing events during the initial synchronization,
@@ -124,26 +215,77 @@ func NewIstioServiceEntrySourceConfig(
 	return ses, nil
 }
 
-func (sc *ServiceEntrySource) SyncFromProvider(ctx context.Context, ep []*endpoint.Endpoint) error {
+// SetSelectors replaces the selectors, for the next Endpoints and reverse sync.
+func (sc *ServiceEntrySource) SetSelectors(s ServiceEntrySelectors) {
+	sc.selectorsMu.Lock()
+	defer sc.selectorsMu.Unlock()
+	sc.MeshExternalNamespace = s.MeshExternalNamespace
+	sc.ReverseDomainFilter = s.ReverseDomainFilter
+	sc.ReverseLabelFilter = s.ReverseLabelFilter
+}
 
+// selectors returns the current selectors.
+func (sc *ServiceEntrySource) selectors() ServiceEntrySelectors {
+	sc.selectorsMu.RLock()
+	defer sc.selectorsMu.RUnlock()
+	return ServiceEntrySelectors{
+		MeshExternalNamespace: sc.MeshExternalNamespace,
+		ReverseDomainFilter:   sc.ReverseDomainFilter,
+		ReverseLabelFilter:    sc.ReverseLabelFilter,
+	}
+}
 
-	return nil
+// HasSynced returns true once the ServiceEntry informer cache is populated.
+func (sc *ServiceEntrySource) HasSynced() bool {
+	return sc.seInformer.Informer().HasSynced()
 }
 
+// ServiceEntryDebugState is the size of the ServiceEntry informer cache and
+// of the VIP allocators, for /debug/state.
+type ServiceEntryDebugState struct {
+	Synced bool `json:"synced"`
+	// ServiceEntries are the cached ServiceEntries by namespace.
+	ServiceEntries map[string]int `json:"serviceEntries"`
+	Leases         int            `json:"leases,omitempty"`
+	EgressLeases   int            `json:"egressLeases,omitempty"`
+}
+
+// DebugState returns the size of the informer cache and of the allocators.
+func (sc *ServiceEntrySource) DebugState() ServiceEntryDebugState {
+	informer := sc.seInformer.Informer()
+	state := ServiceEntryDebugState{Synced: informer.HasSynced(), ServiceEntries: map[string]int{}}
+	for _, key := range informer.GetStore().ListKeys() {
+		ns, _, _ := strings.Cut(key, "/")
+		state.ServiceEntries[ns]++
+	}
+	if sc.Allocator != nil {
+		state.Leases = len(sc.Allocator.Leases())
+	}
+	if sc.EgressAllocator != nil {
+		state.EgressLeases = len(sc.EgressAllocator.Leases())
+	}
+	return state
+}
+
+// PatchSE sets the address of the ServiceEntry, marking it as patched by external-dns.
 func (sc *ServiceEntrySource) PatchSE(ctx context.Context, ns, name, address string) error {
-	se := networkingv1alpha3.ServiceEntry{
-		ObjectMeta: metav1.ObjectMeta{
-			Annotations: map[string]string{"external-dns/patched": "true"},
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{"external-dns/patched": "true"},
+		},
+		"spec": map[string]interface{}{
+			"addresses": []string{address},
 		},
 	}
 
-	seBytes, err := json.Marshal(se)
+	seBytes, err := json.Marshal(patch)
 	if err != nil {
 		return err
 	}
 
-	sc.istioClient.NetworkingV1alpha3().ServiceEntries(ns).Patch(ctx, name, types.StrategicMergePatchType, seBytes, metav1.PatchOptions{FieldManager: "ext-dns"})
-	return nil
+	// ServiceEntry is a CRD - strategic merge is not supported.
+	_, err = sc.istioClient.NetworkingV1alpha3().ServiceEntries(ns).Patch(ctx, name, types.MergePatchType, seBytes, metav1.PatchOptions{FieldManager: "ext-dns"})
+	return err
 }
 
 // Endpoints returns endpoint objects for each host-target combination that should be processed.
@@ -155,22 +297,28 @@ func (sc *ServiceEntrySource) Endpoints(ctx context.Context) ([]*endpoint.Endpoi
 	// External ServiceEntries
 
 	// If namespace empty - all namespaces are listed.
-	serviceEntries, err := sc.seInformer.Lister().ServiceEntries(sc.MeshExternalNamespace).List(labels.Everything())
+	serviceEntries, err := sc.seInformer.Lister().ServiceEntries(sc.selectors().MeshExternalNamespace).List(labels.Everything())
 	if err != nil {
 		return nil, err
 	}
 
+	external := 0
 	for _, se := range serviceEntries {
 		if se.Spec.Location !=  v1alpha3.ServiceEntry_MESH_EXTERNAL {
 			continue
 		}
+		if se.Labels[ReverseLabel] == "true" {
+			// Generated from the DNS records.
+			continue
+		}
+		external++
 
 		gwEndpoints, err := sc.dnsRecordsFromExtServiceEntry(ctx, se)
 		if err != nil {
 			return nil, err
 		}
 
-		slog.Debug("Endpoints generated from VirtualService", "namespace", se.Namespace, "name", se.Name,"records",  gwEndpoints)
+		seLogger().DebugContext(ctx, "Endpoints generated from VirtualService", "namespace", se.Namespace, "name", se.Name,"records",  gwEndpoints)
 		endpoints = append(endpoints, gwEndpoints...)
 	}
 
@@ -182,20 +330,25 @@ func (sc *ServiceEntrySource) Endpoints(ctx context.Context) ([]*endpoint.Endpoi
 		return nil, err
 	}
 
+	internal := 0
 	for _, se := range serviceEntriesInt {
 		if se.Spec.Location !=  v1alpha3.ServiceEntry_MESH_INTERNAL {
 			continue
 		}
+		internal++
 
 		gwEndpoints, err := sc.dnsRecordsFromServiceEntry(ctx, se)
 		if err != nil {
 			return nil, err
 		}
 
-		slog.Debug("Endpoints generated from VirtualService", "namespace", se.Namespace, "name", se.Name,"records",  gwEndpoints)
+		seLogger().DebugContext(ctx, "Endpoints generated from VirtualService", "namespace", se.Namespace, "name", se.Name,"records",  gwEndpoints)
 		endpoints = append(endpoints, gwEndpoints...)
 	}
 
+	serviceEntriesGauge.WithLabelValues("external").Set(float64(external))
+	serviceEntriesGauge.WithLabelValues("internal").Set(float64(internal))
+
 	for _, ep := range endpoints {
 		sort.Sort(ep.Targets)
 	}
@@ -240,73 +393,122 @@ func (fn OnAnyChange) OnDelete(obj interface{})                    {
 }
 
 func (sc *ServiceEntrySource) dnsRecordsFromServiceEntry(ctx context.Context, se *networkingv1alpha3.ServiceEntry) ([]*endpoint.Endpoint, error) {
-
-	var endpoints []*endpoint.Endpoint
-
 	resource := fmt.Sprintf("serviceentry/%s/%s", se.Namespace, se.Name)
+	targets := sc.serviceEntryTargets(ctx, se, resource, sc.Allocator)
+	return sc.endpointsForServiceEntry(se, resource, targets), nil
+}
 
-	ttl := getTTLFromAnnotations(se.Annotations, resource)
-
-	for _, host := range se.Spec.Hosts {
-		if host == "" || host == "*" {
-			continue
-		}
-
-		targets := endpoint.Targets{}
-		for _, sea := range se.Spec.Addresses {
-			targets = append(targets, sea)
-		}
-
-		// Auto-allocation should take into account the info in DNS - and set an annotation.
-
-		if len( targets) > 0 {
-			endpoints = append(endpoints, endpointsForHostname(host, targets, ttl, nil, "", resource)...)
+func (sc *ServiceEntrySource) dnsRecordsFromExtServiceEntry(ctx context.Context, se *networkingv1alpha3.ServiceEntry) ([]*endpoint.Endpoint, error) {
+	resource := fmt.Sprintf("serviceentry/%s/%s", se.Namespace, se.Name)
+	targets := sc.serviceEntryTargets(ctx, se, resource, sc.EgressAllocator)
+	if len(targets) == 0 && sc.EgressAllocator == nil {
+		// Shared egress gateway VIPs.
+		for _, vip := range sc.EgressGatewayVIP {
+			if !strings.Contains(vip, "/") {
+				targets = append(targets, vip)
+			}
 		}
 	}
-
-	return endpoints, nil
+	return sc.endpointsForServiceEntry(se, resource, targets), nil
 }
 
-
-func (sc *ServiceEntrySource) dnsRecordsFromExtServiceEntry(ctx context.Context, se *networkingv1alpha3.ServiceEntry) ([]*endpoint.Endpoint, error) {
-
+func (sc *ServiceEntrySource) endpointsForServiceEntry(se *networkingv1alpha3.ServiceEntry, resource string, targets endpoint.Targets) []*endpoint.Endpoint {
 	var endpoints []*endpoint.Endpoint
-
-	resource := fmt.Sprintf("serviceentry/%s/%s", se.Namespace, se.Name)
+	if len(targets) == 0 {
+		return endpoints
+	}
 
 	ttl := getTTLFromAnnotations(se.Annotations, resource)
-
+	providerSpecific, setIdentifier := getProviderSpecificAnnotations(se.Annotations)
 	for _, host := range se.Spec.Hosts {
 		if host == "" || host == "*" {
 			continue
 		}
-
-		targets := endpoint.Targets{}
-		for _, sea := range se.Spec.Addresses {
-			targets = append(targets, sea)
+		endpoints = append(endpoints, endpointsForHostname(host, targets, ttl, providerSpecific, setIdentifier, resource)...)
+	}
+	if sc.GeoRouting {
+		var localities []string
+		for _, we := range se.Spec.Endpoints {
+			localities = append(localities, we.Locality)
+		}
+		region := localitiesRegion(localities)
+		if region == "" {
+			region = sc.GeoRegion
 		}
+		setGeoLocation(endpoints, region)
+	}
+	setZoneFromAnnotations(endpoints, se.Annotations)
+	return endpoints
+}
 
-		if len(targets) == 0 && sc.HttpVIP != "" {
-			// Is it http only ?
-			isHttp := true
-			for _, port := range se.Spec.Ports {
-				if port.Protocol != "http" && port.Protocol != "https" {
-					isHttp = false
-					break
+// serviceEntryTargets returns the addresses of the ServiceEntry. Entries without
+// addresses get the HttpVIP if they only have HTTP ports, otherwise a VIP from the
+// allocator - which is patched into the ServiceEntry if UpdateServiceEntry is set.
+func (sc *ServiceEntrySource) serviceEntryTargets(ctx context.Context, se *networkingv1alpha3.ServiceEntry, resource string, alloc *ipam.Allocator) endpoint.Targets {
+	targets := endpoint.Targets{}
+	for _, sea := range se.Spec.Addresses {
+		targets = append(targets, sea)
+	}
+
+	if len(targets) > 0 {
+		if alloc != nil {
+			if ip, err := netip.ParseAddr(targets[0]); err == nil && alloc.Contains(ip) {
+				if err := alloc.Reserve(ctx, resource, ip); err != nil {
+					serviceEntryErrorsTotal.WithLabelValues("reserve").Inc()
+					seLogger().WarnContext(ctx, "Failed to reserve ServiceEntry address", "resource", resource, "error", err)
 				}
 			}
-			if isHttp {
-				targets = append(targets, sc.HttpVIP)
-			}
 		}
+		return targets
+	}
 
-		// Auto-allocation should take into account the info in DNS - and set an annotation.
+	if sc.HttpVIP != "" && isHTTPOnly(se) {
+		return append(targets, sc.HttpVIP)
+	}
+	if alloc == nil {
+		return targets
+	}
 
-		if len( targets) > 0 {
-			endpoints = append(endpoints, endpointsForHostname(host, targets, ttl, nil, "", resource)...)
+	ip, err := alloc.Allocate(ctx, resource)
+	if err != nil {
+		serviceEntryErrorsTotal.WithLabelValues("allocate").Inc()
+		seLogger().WarnContext(ctx, "Failed to allocate ServiceEntry VIP", "resource", resource, "error", err)
+		return targets
+	}
+	if sc.UpdateServiceEntry {
+		if err := sc.PatchSE(ctx, se.Namespace, se.Name, ip.String()); err != nil {
+			serviceEntryErrorsTotal.WithLabelValues("patch").Inc()
+			seLogger().WarnContext(ctx, "Failed to patch ServiceEntry address", "resource", resource, "error", err)
 		}
 	}
+	return append(targets, ip.String())
+}
 
-	return endpoints, nil
+// OwnerExists checks if the ServiceEntry owning a VIP lease still exists - for use
+// with ipam.Reclaimer. Other owners are reported as existing.
+func (sc *ServiceEntrySource) OwnerExists(ctx context.Context, owner string) (bool, error) {
+	parts := strings.Split(owner, "/")
+	if len(parts) != 3 || parts[0] != "serviceentry" {
+		return true, nil
+	}
+	_, err := sc.seInformer.Lister().ServiceEntries(parts[1]).Get(parts[2])
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
 }
 
+// isHTTPOnly returns true if all ports of the ServiceEntry are HTTP or HTTPS - these
+// are routed by Host header and don't need a dedicated VIP.
+func isHTTPOnly(se *networkingv1alpha3.ServiceEntry) bool {
+	if len(se.Spec.Ports) == 0 {
+		return false
+	}
+	for _, port := range se.Spec.Ports {
+		p := strings.ToLower(port.Protocol)
+		if p != "http" && p != "https" {
+			return false
+		}
+	}
+	return true
+}
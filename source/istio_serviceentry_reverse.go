@@ -0,0 +1,190 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"istio.io/api/networking/v1alpha3"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
+)
+
+// ReverseLabel marks the ServiceEntries generated from the provider records.
+// They are owned by the reverse sync, and ignored by Endpoints.
+const ReverseLabel = "external-dns.alpha.kubernetes.io/reverse"
+
+// reversePorts returns the ports of the generated ServiceEntries - DNS has no
+// port information, and most external services are HTTP or TLS.
+func reversePorts() []*v1alpha3.ServicePort {
+	return []*v1alpha3.ServicePort{
+		{Number: 80, Name: "http", Protocol: "HTTP"},
+		{Number: 443, Name: "tls", Protocol: "TLS"},
+	}
+}
+
+// ErrRecordsNotSynced is returned by the records of RunReverseSync until they
+// are first listed: the syncs are skipped, instead of deleting all the
+// generated ServiceEntries.
+var ErrRecordsNotSynced = errors.New("the records are not synced yet")
+
+// RunReverseSync calls SyncFromProvider with the records, every interval, until
+// the context is done. It is a no-op if ReverseNamespace is not set.
+func (sc *ServiceEntrySource) RunReverseSync(ctx context.Context, records func(context.Context) ([]*endpoint.Endpoint, error), interval time.Duration) {
+	if sc.ReverseNamespace == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// The messages of each sync share a trace.
+		syncCtx := logging.NewTrace(ctx)
+		eps, err := records(syncCtx)
+		if err == nil {
+			err = sc.SyncFromProvider(syncCtx, eps)
+		}
+		if errors.Is(err, ErrRecordsNotSynced) {
+			seLogger().DebugContext(syncCtx, "Skipping the sync of ServiceEntries from provider", "namespace", sc.ReverseNamespace, "error", err)
+		} else if err != nil {
+			seLogger().WarnContext(syncCtx, "Failed to sync ServiceEntries from provider", "namespace", sc.ReverseNamespace, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncFromProvider reconciles MESH_EXTERNAL ServiceEntries in ReverseNamespace
+// with the provider records - using DNS as the source of truth. Records matching
+// ReverseDomainFilter and ReverseLabelFilter get a ServiceEntry, with STATIC
+// resolution for A/AAAA and DNS resolution for CNAME. Records created from
+// ServiceEntries are skipped, and generated entries without a record are deleted.
+func (sc *ServiceEntrySource) SyncFromProvider(ctx context.Context, eps []*endpoint.Endpoint) error {
+	if sc.ReverseNamespace == "" {
+		return nil
+	}
+	desired := sc.reverseServiceEntries(eps)
+
+	existing, err := sc.seInformer.Lister().ServiceEntries(sc.ReverseNamespace).List(labels.SelectorFromSet(labels.Set{ReverseLabel: "true"}))
+	if err != nil {
+		return err
+	}
+
+	client := sc.istioClient.NetworkingV1alpha3().ServiceEntries(sc.ReverseNamespace)
+	var errs []error
+	for _, se := range existing {
+		want, ok := desired[se.Name]
+		if !ok {
+			seLogger().InfoContext(ctx, "Deleting ServiceEntry without DNS record", "namespace", se.Namespace, "name", se.Name)
+			errs = append(errs, client.Delete(ctx, se.Name, metav1.DeleteOptions{}))
+			continue
+		}
+		delete(desired, se.Name)
+		if proto.Equal(&se.Spec, &want.Spec) {
+			continue
+		}
+		want.ResourceVersion = se.ResourceVersion
+		seLogger().InfoContext(ctx, "Updating ServiceEntry from DNS record", "namespace", se.Namespace, "name", se.Name, "hosts", want.Spec.Hosts)
+		_, err := client.Update(ctx, want, metav1.UpdateOptions{FieldManager: "ext-dns"})
+		errs = append(errs, err)
+	}
+	for _, se := range desired {
+		seLogger().InfoContext(ctx, "Creating ServiceEntry from DNS record", "namespace", se.Namespace, "name", se.Name, "hosts", se.Spec.Hosts)
+		_, err := client.Create(ctx, se, metav1.CreateOptions{FieldManager: "ext-dns"})
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// reverseServiceEntries returns the desired ServiceEntries by name.
+func (sc *ServiceEntrySource) reverseServiceEntries(eps []*endpoint.Endpoint) map[string]*networkingv1alpha3.ServiceEntry {
+	selectors := sc.selectors()
+	byName := map[string][]*endpoint.Endpoint{}
+	for _, ep := range eps {
+		switch ep.RecordType {
+		case endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME:
+		default:
+			continue
+		}
+		if strings.HasPrefix(ep.Labels[endpoint.ResourceLabelKey], "serviceentry/") {
+			// Created from a ServiceEntry - reversing it would duplicate the entry.
+			continue
+		}
+		if !selectors.ReverseDomainFilter.Match(ep.DNSName) {
+			continue
+		}
+		if selectors.ReverseLabelFilter != nil && !selectors.ReverseLabelFilter.Matches(labels.Set(ep.Labels)) {
+			continue
+		}
+		host := strings.ToLower(strings.TrimSuffix(ep.DNSName, "."))
+		byName[host] = append(byName[host], ep)
+	}
+
+	desired := map[string]*networkingv1alpha3.ServiceEntry{}
+	for host, records := range byName {
+		se := &networkingv1alpha3.ServiceEntry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      reverseName(host),
+				Namespace: sc.ReverseNamespace,
+				Labels:    map[string]string{ReverseLabel: "true"},
+			},
+			Spec: v1alpha3.ServiceEntry{
+				Hosts:      []string{host},
+				Location:   v1alpha3.ServiceEntry_MESH_EXTERNAL,
+				Ports:      reversePorts(),
+				Resolution: v1alpha3.ServiceEntry_STATIC,
+			},
+		}
+		var addresses []string
+		for _, ep := range records {
+			if ep.RecordType == endpoint.RecordTypeCNAME {
+				// Resolved by the sidecar, following the CNAME.
+				se.Spec.Resolution = v1alpha3.ServiceEntry_DNS
+				addresses = nil
+				break
+			}
+			addresses = append(addresses, ep.Targets...)
+		}
+		sort.Strings(addresses)
+		for _, addr := range addresses {
+			se.Spec.Endpoints = append(se.Spec.Endpoints, &v1alpha3.WorkloadEntry{Address: addr})
+		}
+		desired[se.Name] = se
+	}
+	return desired
+}
+
+// reverseName returns a valid object name for the host - wildcards are replaced,
+// and long names are truncated.
+func reverseName(host string) string {
+	name := "dns-" + strings.NewReplacer("*", "wildcard", "_", "-").Replace(host)
+	if len(name) > 253 {
+		name = strings.TrimRight(name[:253], ".-")
+	}
+	return name
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"istio.io/api/networking/v1alpha3"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	istiofake "istio.io/client-go/pkg/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// newReverseSource returns a ServiceEntrySource with the reverse sync in the
+// external namespace, and the existing ServiceEntries.
+func newReverseSource(t *testing.T, existing ...runtime.Object) (*ServiceEntrySource, *istiofake.Clientset) {
+	t.Helper()
+	istioClient := istiofake.NewSimpleClientset(existing...)
+	src, err := NewIstioServiceEntrySourceConfig(context.Background(), fake.NewSimpleClientset(), istioClient, ServiceEntrySourceConfig{
+		ReverseNamespace:    "external",
+		ReverseDomainFilter: endpoint.NewDomainFilter([]string{"example.com"}),
+	})
+	require.NoError(t, err)
+	return src.(*ServiceEntrySource), istioClient
+}
+
+// reverseEntry returns a generated ServiceEntry for the host.
+func reverseEntry(host string, resolution v1alpha3.ServiceEntry_Resolution, addresses ...string) *networkingv1alpha3.ServiceEntry {
+	se := &networkingv1alpha3.ServiceEntry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      reverseName(host),
+			Namespace: "external",
+			Labels:    map[string]string{ReverseLabel: "true"},
+		},
+		Spec: v1alpha3.ServiceEntry{
+			Hosts:      []string{host},
+			Location:   v1alpha3.ServiceEntry_MESH_EXTERNAL,
+			Ports:      reversePorts(),
+			Resolution: resolution,
+		},
+	}
+	for _, addr := range addresses {
+		se.Spec.Endpoints = append(se.Spec.Endpoints, &v1alpha3.WorkloadEntry{Address: addr})
+	}
+	return se
+}
+
+func TestReverseName(t *testing.T) {
+	for _, tc := range []struct {
+		host string
+		name string
+	}{
+		{host: "app.example.com", name: "dns-app.example.com"},
+		{host: "*.example.com", name: "dns-wildcard.example.com"},
+		{host: "_http._tcp.example.com", name: "dns--http.-tcp.example.com"},
+		{host: strings.Repeat("a", 246) + ".example.com", name: "dns-" + strings.Repeat("a", 246) + ".ex"},
+		// The truncated name doesn't end with a separator.
+		{host: strings.Repeat("a", 248) + ".example.com", name: "dns-" + strings.Repeat("a", 248)},
+	} {
+		t.Run(tc.host[:min(len(tc.host), 20)], func(t *testing.T) {
+			name := reverseName(tc.host)
+			assert.Equal(t, tc.name, name)
+			assert.LessOrEqual(t, len(name), 253)
+		})
+	}
+}
+
+func TestReverseServiceEntries(t *testing.T) {
+	src, _ := newReverseSource(t)
+	owned := endpoint.NewEndpoint("owned.example.com", endpoint.RecordTypeA, "10.0.0.3")
+	owned.Labels[endpoint.OwnerLabelKey] = "my-cluster"
+	fromServiceEntry := endpoint.NewEndpoint("se.example.com", endpoint.RecordTypeA, "10.0.0.4")
+	fromServiceEntry.Labels[endpoint.ResourceLabelKey] = "serviceentry/default/se"
+
+	desired := src.reverseServiceEntries([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("App.example.com", endpoint.RecordTypeA, "10.0.0.2", "10.0.0.1"),
+		endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeAAAA, "2001:db8::1"),
+		endpoint.NewEndpoint("alias.example.com", endpoint.RecordTypeCNAME, "app.example.net"),
+		endpoint.NewEndpoint("alias.example.com", endpoint.RecordTypeA, "10.0.0.5"),
+		endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeTXT, "text"),
+		endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "10.0.0.6"),
+		owned,
+		fromServiceEntry,
+	})
+	assert.Equal(t, map[string]*networkingv1alpha3.ServiceEntry{
+		"dns-app.example.com":   reverseEntry("app.example.com", v1alpha3.ServiceEntry_STATIC, "10.0.0.1", "10.0.0.2", "2001:db8::1"),
+		"dns-alias.example.com": reverseEntry("alias.example.com", v1alpha3.ServiceEntry_DNS),
+		"dns-owned.example.com": reverseEntry("owned.example.com", v1alpha3.ServiceEntry_STATIC, "10.0.0.3"),
+	}, desired)
+
+	// The label filter matches the labels of the registry.
+	selector, err := labels.Parse("owner!=my-cluster")
+	require.NoError(t, err)
+	src.SetSelectors(ServiceEntrySelectors{ReverseDomainFilter: src.ReverseDomainFilter, ReverseLabelFilter: selector})
+	desired = src.reverseServiceEntries([]*endpoint.Endpoint{owned})
+	assert.Empty(t, desired)
+}
+
+func TestSyncFromProvider(t *testing.T) {
+	ctx := context.Background()
+	manual := &networkingv1alpha3.ServiceEntry{
+		ObjectMeta: metav1.ObjectMeta{Name: "dns-manual.example.com", Namespace: "external"},
+		Spec:       v1alpha3.ServiceEntry{Hosts: []string{"manual.example.com"}},
+	}
+	src, client := newReverseSource(t,
+		reverseEntry("app.example.com", v1alpha3.ServiceEntry_STATIC, "10.0.0.1"),
+		reverseEntry("same.example.com", v1alpha3.ServiceEntry_STATIC, "10.0.0.3"),
+		reverseEntry("old.example.com", v1alpha3.ServiceEntry_STATIC, "10.0.0.9"),
+		manual,
+	)
+
+	require.NoError(t, src.SyncFromProvider(ctx, []*endpoint.Endpoint{
+		endpoint.NewEndpoint("app.example.com", endpoint.RecordTypeA, "10.0.0.2"),
+		endpoint.NewEndpoint("same.example.com", endpoint.RecordTypeA, "10.0.0.3"),
+		endpoint.NewEndpoint("new.example.com", endpoint.RecordTypeCNAME, "new.example.net"),
+	}))
+
+	list, err := client.NetworkingV1alpha3().ServiceEntries("external").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	got := map[string]*networkingv1alpha3.ServiceEntry{}
+	for _, se := range list.Items {
+		got[se.Name] = se
+	}
+	require.ElementsMatch(t, []string{"dns-app.example.com", "dns-same.example.com", "dns-new.example.com", "dns-manual.example.com"}, serviceEntryNames(got),
+		"the generated entry without a record is deleted, the other entries are kept")
+	assert.Equal(t, []string{"10.0.0.2"}, serviceEntryAddresses(got["dns-app.example.com"]), "updated")
+	assert.Equal(t, v1alpha3.ServiceEntry_DNS, got["dns-new.example.com"].Spec.Resolution, "created")
+	assert.Equal(t, "true", got["dns-new.example.com"].Labels[ReverseLabel])
+	assert.Empty(t, got["dns-manual.example.com"].Spec.Endpoints)
+
+	var updates, creates, deletes int
+	for _, action := range client.Actions() {
+		switch action.GetVerb() {
+		case "update":
+			updates++
+		case "create":
+			creates++
+		case "delete":
+			deletes++
+		}
+	}
+	assert.Equal(t, []int{1, 1, 1}, []int{updates, creates, deletes}, "the unchanged entry is not updated")
+}
+
+func TestRunReverseSyncNotSynced(t *testing.T) {
+	src, client := newReverseSource(t, reverseEntry("app.example.com", v1alpha3.ServiceEntry_STATIC, "10.0.0.1"))
+	ctx, cancel := context.WithCancel(context.Background())
+	src.RunReverseSync(ctx, func(context.Context) ([]*endpoint.Endpoint, error) {
+		// The controller has not synced: there are no records yet.
+		cancel()
+		return nil, ErrRecordsNotSynced
+	}, time.Hour)
+
+	_, err := client.NetworkingV1alpha3().ServiceEntries("external").Get(context.Background(), "dns-app.example.com", metav1.GetOptions{})
+	assert.NoError(t, err, "the generated entries are kept")
+}
+
+func serviceEntryNames(m map[string]*networkingv1alpha3.ServiceEntry) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
+
+func serviceEntryAddresses(se *networkingv1alpha3.ServiceEntry) []string {
+	var addrs []string
+	for _, e := range se.Spec.Endpoints {
+		addrs = append(addrs, e.Address)
+	}
+	return addrs
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"istio.io/api/networking/v1alpha3"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestEndpointsForServiceEntrySetIdentifier(t *testing.T) {
+	se := &networkingv1alpha3.ServiceEntry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app",
+			Namespace: "default",
+			Annotations: map[string]string{
+				SetIdentifierKey: "blue",
+				"external-dns.alpha.kubernetes.io/google-weight": "10",
+			},
+		},
+		Spec: v1alpha3.ServiceEntry{Hosts: []string{"app.example.org", "app.example.com"}},
+	}
+	sc := &ServiceEntrySource{}
+	endpoints := sc.endpointsForServiceEntry(se, "serviceentry/default/app", endpoint.Targets{"10.0.0.1"})
+	assert.Len(t, endpoints, 2)
+	for _, ep := range endpoints {
+		assert.Equal(t, "blue", ep.SetIdentifier)
+		weight, _ := ep.GetProviderSpecificProperty("google/weight")
+		assert.Equal(t, "10", weight)
+	}
+}
@@ -55,6 +55,8 @@ type virtualServiceSource struct {
 	ignoreHostnameAnnotation bool
 	serviceInformer          coreinformers.ServiceInformer
 	virtualserviceInformer   networkingv1alpha3informer.VirtualServiceInformer
+
+	*informerHealth
 }
 
 // NewIstioVirtualServiceSource creates a new virtualServiceSource with the given config.
@@ -97,6 +99,11 @@ func NewIstioVirtualServiceSource(
 		},
 	)
 
+	health := trackInformers(map[string]cache.SharedInformer{
+		"services":        serviceInformer.Informer(),
+		"virtualservices": virtualServiceInformer.Informer(),
+	})
+
 	informerFactory.Start(ctx.Done())
 	istioInformerFactory.Start(ctx.Done())
 
@@ -118,6 +125,7 @@ func NewIstioVirtualServiceSource(
 		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
 		serviceInformer:          serviceInformer,
 		virtualserviceInformer:   virtualServiceInformer,
+		informerHealth:           health,
 	}, nil
 }
 
@@ -0,0 +1,210 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
+)
+
+const (
+	defaultIstiodAddress = "http://istiod.istio-system:15014"
+
+	// istiodKubernetesRegistry is the registry of the Kubernetes services.
+	istiodKubernetesRegistry = "Kubernetes"
+	// istiodUnspecifiedIP is the address of the services without a VIP.
+	istiodUnspecifiedIP = "0.0.0.0"
+)
+
+// IstiodConfig is the configuration of the istiod source.
+type IstiodConfig struct {
+	// Address of the debug API of istiod, like http://istiod.istio-system:15014.
+	Address string
+	// TokenFile has a bearer token for the API, read for each request - no
+	// Authorization header if empty.
+	TokenFile string
+	// Namespace of the services, all if empty.
+	Namespace string
+	// Domain of the Kubernetes services, published as NAME.NAMESPACE.DOMAIN -
+	// they are skipped if empty.
+	Domain string
+}
+
+// istiodSource is an implementation of Source for the service registry of
+// istiod, read from its debug API: the Kubernetes services of all the clusters
+// of the mesh and the ServiceEntries, as aggregated by istiod - without
+// listing them in each cluster.
+type istiodSource struct {
+	cfg     IstiodConfig
+	client  *http.Client
+	baseURL string
+	timeout time.Duration
+	log     *slog.Logger
+}
+
+// istiodAddresses are the addresses of a service by cluster.
+type istiodAddresses struct {
+	Addresses map[string][]string `json:"Addresses"`
+}
+
+// istiodService is the part of a service of /debug/registryz used by the
+// source.
+type istiodService struct {
+	Hostname   string `json:"hostname"`
+	Attributes struct {
+		ServiceRegistry          string          `json:"ServiceRegistry"`
+		Name                     string          `json:"Name"`
+		Namespace                string          `json:"Namespace"`
+		ClusterExternalAddresses istiodAddresses `json:"ClusterExternalAddresses"`
+	} `json:"Attributes"`
+	ClusterVIPs istiodAddresses `json:"clusterVIPs"`
+}
+
+// NewIstiodSource returns a source for the services of the registry of istiod.
+func NewIstiodSource(cfg IstiodConfig, timeout time.Duration) (Source, error) {
+	if cfg.Address == "" {
+		cfg.Address = defaultIstiodAddress
+	}
+	if !strings.Contains(cfg.Address, "://") {
+		cfg.Address = "http://" + cfg.Address
+	}
+	u, err := url.Parse(cfg.Address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid istiod address %q", cfg.Address)
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &istiodSource{
+		cfg:     cfg,
+		client:  &http.Client{},
+		baseURL: strings.TrimSuffix(u.String(), "/"),
+		timeout: timeout,
+		log:     logging.For("source/istiod"),
+	}, nil
+}
+
+// Endpoints returns the endpoints of the services of the registry. An
+// unreachable istiod fails the sync, so the records are not deleted.
+func (is *istiodSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, is.timeout)
+	defer cancel()
+
+	services, err := is.registry(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("istiod source: %w", err)
+	}
+	var endpoints []*endpoint.Endpoint
+	for _, svc := range services {
+		endpoints = append(endpoints, is.serviceEndpoints(svc)...)
+	}
+	is.log.Debug("Read the registry", "services", len(services), "endpoints", len(endpoints))
+	return endpoints, nil
+}
+
+// serviceEndpoints returns the endpoints of a service: a Kubernetes service as
+// NAME.NAMESPACE.DOMAIN, a ServiceEntry host as is. The targets are the
+// external addresses of the service in all the clusters, like the load
+// balancer IPs, or its VIPs.
+func (is *istiodSource) serviceEndpoints(svc istiodService) []*endpoint.Endpoint {
+	attrs := svc.Attributes
+	if is.cfg.Namespace != "" && attrs.Namespace != is.cfg.Namespace {
+		return nil
+	}
+	hostname := svc.Hostname
+	if attrs.ServiceRegistry == istiodKubernetesRegistry {
+		if is.cfg.Domain == "" {
+			return nil
+		}
+		hostname = attrs.Name + "." + attrs.Namespace + "." + strings.Trim(is.cfg.Domain, ".")
+	}
+	// A wildcard ServiceEntry host has no address to publish.
+	if hostname == "" || strings.HasPrefix(hostname, "*") {
+		return nil
+	}
+
+	targets := attrs.ClusterExternalAddresses.targets()
+	if len(targets) == 0 {
+		targets = svc.ClusterVIPs.targets()
+	}
+	if len(targets) == 0 {
+		is.log.Debug("Skipping service without addresses", "hostname", svc.Hostname)
+		return nil
+	}
+	return endpointsForHostname(hostname, targets, 0, nil, "", "istiod/"+svc.Hostname)
+}
+
+// targets returns the addresses of all the clusters, sorted and without
+// duplicates.
+func (a istiodAddresses) targets() endpoint.Targets {
+	var targets endpoint.Targets
+	for _, addresses := range a.Addresses {
+		for _, address := range addresses {
+			if address != "" && address != istiodUnspecifiedIP && !containsString(targets, address) {
+				targets = append(targets, address)
+			}
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// registry returns the services of /debug/registryz.
+func (is *istiodSource) registry(ctx context.Context) ([]istiodService, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, is.baseURL+"/debug/registryz", nil)
+	if err != nil {
+		return nil, err
+	}
+	if is.cfg.TokenFile != "" {
+		token, err := os.ReadFile(is.cfg.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	resp, err := is.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("GET /debug/registryz: %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	var services []istiodService
+	if err := json.NewDecoder(resp.Body).Decode(&services); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// AddEventHandler does nothing: the registry is read at each sync.
+func (is *istiodSource) AddEventHandler(ctx context.Context, handler func()) {
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// testRegistry is a /debug/registryz of a mesh of two clusters.
+const testRegistry = `[
+  {
+    "Attributes": {"ServiceRegistry": "Kubernetes", "Name": "reviews", "Namespace": "bookinfo"},
+    "hostname": "reviews.bookinfo.svc.cluster.local",
+    "clusterVIPs": {"Addresses": {"east": ["10.96.0.10"], "west": ["10.97.0.10", "fd00::10"]}}
+  },
+  {
+    "Attributes": {"ServiceRegistry": "Kubernetes", "Name": "ingress", "Namespace": "istio-system",
+      "ClusterExternalAddresses": {"Addresses": {"east": ["34.1.2.3"], "west": ["35.1.2.3"]}}},
+    "hostname": "ingress.istio-system.svc.cluster.local",
+    "clusterVIPs": {"Addresses": {"east": ["10.96.0.20"]}}
+  },
+  {
+    "Attributes": {"ServiceRegistry": "Kubernetes", "Name": "headless", "Namespace": "bookinfo"},
+    "hostname": "headless.bookinfo.svc.cluster.local",
+    "clusterVIPs": {"Addresses": {"east": ["0.0.0.0"]}}
+  },
+  {
+    "Attributes": {"ServiceRegistry": "External", "Name": "db.example.com", "Namespace": "bookinfo"},
+    "hostname": "db.example.com",
+    "clusterVIPs": {"Addresses": {"east": ["240.240.0.1"]}}
+  },
+  {
+    "Attributes": {"ServiceRegistry": "External", "Name": "*.googleapis.com", "Namespace": "bookinfo"},
+    "hostname": "*.googleapis.com",
+    "clusterVIPs": {"Addresses": {"east": ["240.240.0.2"]}}
+  }
+]`
+
+func newIstiodServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/debug/registryz" || r.Header.Get("Authorization") != "Bearer istiod-token" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(testRegistry))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestIstiodSourceEndpoints(t *testing.T) {
+	srv := newIstiodServer(t)
+	token := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(token, []byte("istiod-token\n"), 0o600))
+
+	src, err := NewIstiodSource(IstiodConfig{Address: srv.URL, TokenFile: token, Domain: "mesh.example.com."}, time.Minute)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+
+	reviews := map[string]string{endpoint.ResourceLabelKey: "istiod/reviews.bookinfo.svc.cluster.local"}
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "reviews.bookinfo.mesh.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.96.0.10", "10.97.0.10"}, Labels: reviews},
+		{DNSName: "reviews.bookinfo.mesh.example.com", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"fd00::10"}, Labels: reviews},
+		{DNSName: "ingress.istio-system.mesh.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"34.1.2.3", "35.1.2.3"},
+			Labels: map[string]string{endpoint.ResourceLabelKey: "istiod/ingress.istio-system.svc.cluster.local"}},
+		{DNSName: "db.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"240.240.0.1"},
+			Labels: map[string]string{endpoint.ResourceLabelKey: "istiod/db.example.com"}},
+	})
+}
+
+func TestIstiodSourceFilters(t *testing.T) {
+	srv := newIstiodServer(t)
+	token := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(token, []byte("istiod-token"), 0o600))
+
+	// Without a domain only the ServiceEntry hosts are published.
+	src, err := NewIstiodSource(IstiodConfig{Address: srv.URL, TokenFile: token, Namespace: "bookinfo"}, time.Minute)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, "db.example.com", endpoints[0].DNSName)
+
+	src, err = NewIstiodSource(IstiodConfig{Address: srv.URL, TokenFile: token, Namespace: "istio-system", Domain: "mesh.example.com"}, time.Minute)
+	require.NoError(t, err)
+	endpoints, err = src.Endpoints(context.Background())
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, "ingress.istio-system.mesh.example.com", endpoints[0].DNSName)
+}
+
+func TestIstiodSourceError(t *testing.T) {
+	srv := newIstiodServer(t)
+
+	src, err := NewIstiodSource(IstiodConfig{Address: srv.URL}, time.Minute)
+	require.NoError(t, err)
+	_, err = src.Endpoints(context.Background())
+	assert.ErrorContains(t, err, "403")
+
+	src, err = NewIstiodSource(IstiodConfig{Address: srv.URL, TokenFile: filepath.Join(t.TempDir(), "missing")}, time.Minute)
+	require.NoError(t, err)
+	_, err = src.Endpoints(context.Background())
+	assert.Error(t, err)
+
+	_, err = NewIstiodSource(IstiodConfig{Address: "grpc://istiod:15012"}, 0)
+	assert.Error(t, err)
+
+	src, err = NewIstiodSource(IstiodConfig{}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "http://istiod.istio-system:15014", src.(*istiodSource).baseURL)
+}
@@ -18,11 +18,15 @@ package source
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"sigs.k8s.io/external-dns/endpoint"
 
 	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
 	kubeinformers "k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
@@ -38,6 +42,17 @@ type K8SSource struct {
 
 	Internal string
 	K8SSourceConfig
+
+	// GeoRouting sets GeoRegion, or the region of the nodes, as the geo
+	// location of the endpoints.
+	GeoRouting bool
+	GeoRegion  string
+
+	// NodeHints adds a TXT record per pod, _node.NAME.NAMESPACE.p.SUFFIX,
+	// with the node and the creation time of the pod.
+	NodeHints bool
+
+	*informerHealth
 }
 
 // K8SSourceConfig is used to configure a new K8SSource, which creates DNS entries
@@ -57,6 +72,9 @@ func NewK8SSource(p ClientGenerator, config *Config) (*K8SSource, error) {
 	}
 	ps := &K8SSource{
 		client:        kubeClient,
+		GeoRouting:    config.GeoRouting,
+		GeoRegion:     config.GeoRegion,
+		NodeHints:     config.K8SNodeHints,
 	}
 	return ps, ps.Init(context.Background())
 }
@@ -94,6 +112,10 @@ func (ps *K8SSource) Init(ctx context.Context) error {
 	)
 	ps.podInformer = podInformer
 	ps.nodeInformer = nodeInformer
+	ps.informerHealth = trackInformers(map[string]cache.SharedInformer{
+		"pods":  podInformer.Informer(),
+		"nodes": nodeInformer.Informer(),
+	})
 
 	informerFactory.Start(ctx.Done())
 
@@ -126,12 +148,45 @@ func (ps *K8SSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error
 		if pod.Status.PodIP != "" {
 			// return internal endpoint IPs
 			addToEndpointMap(endpointMap, pod.Name+"."+pod.Namespace+".p."+ps.Internal, "A", pod.Status.PodIP)
+			if ps.NodeHints && pod.Spec.NodeName != "" {
+				addToEndpointMap(endpointMap, "_node."+pod.Name+"."+pod.Namespace+".p."+ps.Internal, endpoint.RecordTypeTXT, nodeHint(pod))
+			}
 		}
 	}
 	endpoints := []*endpoint.Endpoint{}
 	for key, targets := range endpointMap {
 		endpoints = append(endpoints, endpoint.NewEndpoint(key.DNSName, key.RecordType, targets...))
 	}
+	if ps.GeoRouting {
+		region := ps.GeoRegion
+		if region == "" {
+			nodes, err := ps.nodeInformer.Lister().List(labels.Everything())
+			if err != nil {
+				return nil, err
+			}
+			region = nodesRegion(nodes)
+		}
+		setGeoLocation(endpoints, region)
+	}
 	return endpoints, nil
 }
 
+// nodeHint returns the TXT target of the node hint of the pod, like
+// "node=worker-1 created=2024-01-02T15:04:05Z".
+func nodeHint(pod *corev1.Pod) string {
+	return fmt.Sprintf("node=%s created=%s", pod.Spec.NodeName, pod.CreationTimestamp.UTC().Format(time.RFC3339))
+}
+
+// OwnerExists checks if the pod owning a VIP lease ("pod/NAMESPACE/NAME") still
+// exists - for use with ipam.Reclaimer. Other owners are reported as existing.
+func (ps *K8SSource) OwnerExists(ctx context.Context, owner string) (bool, error) {
+	parts := strings.Split(owner, "/")
+	if len(parts) != 3 || parts[0] != "pod" {
+		return true, nil
+	}
+	_, err := ps.podInformer.Lister().Pods(parts[1]).Get(parts[2])
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
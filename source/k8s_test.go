@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestK8SSourceNodeHints(t *testing.T) {
+	created := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	client := fake.NewSimpleClientset(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", CreationTimestamp: metav1.NewTime(created)},
+			Spec:       corev1.PodSpec{NodeName: "worker-1"},
+			Status:     corev1.PodStatus{PodIP: "10.0.0.5"},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "no-node", Namespace: "default"},
+			Status:     corev1.PodStatus{PodIP: "10.0.0.6"},
+		},
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, hints := range []bool{false, true} {
+		ps := &K8SSource{client: client, Internal: "cluster.example.com", NodeHints: hints}
+		require.NoError(t, ps.Init(ctx))
+		endpoints, err := ps.Endpoints(ctx)
+		require.NoError(t, err)
+
+		expected := []*endpoint.Endpoint{
+			endpoint.NewEndpoint("web-1.default.p.cluster.example.com", endpoint.RecordTypeA, "10.0.0.5"),
+			endpoint.NewEndpoint("no-node.default.p.cluster.example.com", endpoint.RecordTypeA, "10.0.0.6"),
+		}
+		if hints {
+			// the pod without a node has no hint
+			expected = append(expected, endpoint.NewEndpoint("_node.web-1.default.p.cluster.example.com", endpoint.RecordTypeTXT, "node=worker-1 created=2024-01-02T15:04:05Z"))
+		}
+		validateEndpoints(t, endpoints, expected)
+	}
+}
@@ -0,0 +1,297 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+var (
+	knativeServiceGVR = schema.GroupVersionResource{
+		Group:    "serving.knative.dev",
+		Version:  "v1",
+		Resource: "services",
+	}
+	knativeDomainMappingGVR = schema.GroupVersionResource{
+		Group:    "serving.knative.dev",
+		Version:  "v1beta1",
+		Resource: "domainmappings",
+	}
+	knativeIngressGVR = schema.GroupVersionResource{
+		Group:    "networking.internal.knative.dev",
+		Version:  "v1alpha1",
+		Resource: "ingresses",
+	}
+)
+
+const (
+	// knativeVisibilityLabel is the label of the Knative Services only
+	// reachable in the cluster, skipped.
+	knativeVisibilityLabel = "networking.knative.dev/visibility"
+	knativeClusterLocal    = "cluster-local"
+)
+
+// knativeSource is an implementation of Source for the Knative Services and
+// DomainMappings. The hostname of a Service is the host of its URL, and of a
+// DomainMapping its name. The targets are the target annotation, or the
+// public load balancer of the Knative Ingress of the same name - the load
+// balancer of the gateway service when the Ingress only has its internal
+// domain, like kourier.kourier-system.svc.cluster.local.
+type knativeSource struct {
+	namespace                string
+	annotationFilter         string
+	ignoreHostnameAnnotation bool
+	serviceInformer          informers.GenericInformer
+	domainMappingInformer    informers.GenericInformer
+	ingressInformer          informers.GenericInformer
+	kubeServiceInformer      coreinformers.ServiceInformer
+
+	*informerHealth
+}
+
+// NewKnativeSource creates a new knativeSource with the given config.
+func NewKnativeSource(ctx context.Context, dynamicKubeClient dynamic.Interface, kubeClient kubernetes.Interface, namespace string, annotationFilter string, ignoreHostnameAnnotation bool) (Source, error) {
+	// Use shared informers to listen for add/update/delete of the resources in the specified namespace.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	informerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicKubeClient, 0, namespace, nil)
+	serviceInformer := informerFactory.ForResource(knativeServiceGVR)
+	domainMappingInformer := informerFactory.ForResource(knativeDomainMappingGVR)
+	ingressInformer := informerFactory.ForResource(knativeIngressGVR)
+
+	// Add default resource event handlers to properly initialize informers.
+	for _, informer := range []informers.GenericInformer{serviceInformer, domainMappingInformer, ingressInformer} {
+		informer.Informer().AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {},
+			},
+		)
+	}
+
+	// The gateway services are in the namespace of the networking layer.
+	kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+	kubeServiceInformer := kubeInformerFactory.Core().V1().Services()
+	kubeServiceInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {},
+		},
+	)
+
+	health := trackInformers(map[string]cache.SharedInformer{
+		"services.serving.knative.dev":              serviceInformer.Informer(),
+		"domainmappings":                            domainMappingInformer.Informer(),
+		"ingresses.networking.internal.knative.dev": ingressInformer.Informer(),
+		"services": kubeServiceInformer.Informer(),
+	})
+
+	informerFactory.Start(ctx.Done())
+	kubeInformerFactory.Start(ctx.Done())
+
+	// wait for the local cache to be populated.
+	if err := waitForDynamicCacheSync(context.Background(), informerFactory); err != nil {
+		return nil, err
+	}
+	if err := waitForCacheSync(context.Background(), kubeInformerFactory); err != nil {
+		return nil, err
+	}
+
+	return &knativeSource{
+		namespace:                namespace,
+		annotationFilter:         annotationFilter,
+		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
+		serviceInformer:          serviceInformer,
+		domainMappingInformer:    domainMappingInformer,
+		ingressInformer:          ingressInformer,
+		kubeServiceInformer:      kubeServiceInformer,
+		informerHealth:           health,
+	}, nil
+}
+
+// Endpoints returns the endpoints of the Knative Services and DomainMappings.
+func (ks *knativeSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	selector, err := getLabelSelector(ks.annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []*endpoint.Endpoint
+	for _, kind := range []struct {
+		name     string
+		informer informers.GenericInformer
+	}{
+		{"ksvc", ks.serviceInformer},
+		{"domainmapping", ks.domainMappingInformer},
+	} {
+		objs, err := kind.informer.Lister().ByNamespace(ks.namespace).List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				return nil, fmt.Errorf("could not convert %s object to unstructured", kind.name)
+			}
+			if !matchLabelSelector(selector, u.GetAnnotations()) {
+				continue
+			}
+			objEndpoints, err := ks.endpoints(kind.name, u)
+			if err != nil {
+				return nil, err
+			}
+			if len(objEndpoints) == 0 {
+				log.Debugf("No endpoints could be generated from %s %s/%s", kind.name, u.GetNamespace(), u.GetName())
+				continue
+			}
+			log.Debugf("Endpoints generated from %s %s/%s: %v", kind.name, u.GetNamespace(), u.GetName(), objEndpoints)
+			endpoints = append(endpoints, objEndpoints...)
+		}
+	}
+	return endpoints, nil
+}
+
+// endpoints returns the endpoints of a Knative Service or DomainMapping.
+func (ks *knativeSource) endpoints(kind string, u *unstructured.Unstructured) ([]*endpoint.Endpoint, error) {
+	resource := fmt.Sprintf("%s/%s/%s", kind, u.GetNamespace(), u.GetName())
+	annotations := u.GetAnnotations()
+
+	var hostnames []string
+	switch kind {
+	case "ksvc":
+		if u.GetLabels()[knativeVisibilityLabel] == knativeClusterLocal {
+			return nil, nil
+		}
+		status, _, _ := unstructured.NestedString(u.Object, "status", "url")
+		if status == "" {
+			return nil, nil
+		}
+		host, err := knativeURLHost(status)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", resource, err)
+		}
+		hostnames = append(hostnames, host)
+	case "domainmapping":
+		hostnames = append(hostnames, u.GetName())
+	}
+	if !ks.ignoreHostnameAnnotation {
+		hostnames = append(hostnames, getHostnamesFromAnnotations(annotations)...)
+	}
+
+	targets := getTargetsFromTargetAnnotation(annotations)
+	if len(targets) == 0 {
+		var err error
+		targets, err = ks.ingressTargets(u.GetNamespace(), u.GetName())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	ttl := getTTLFromAnnotations(annotations, resource)
+	providerSpecific, setIdentifier := getProviderSpecificAnnotations(annotations)
+	var endpoints []*endpoint.Endpoint
+	for _, hostname := range hostnames {
+		endpoints = append(endpoints, endpointsForHostname(hostname, targets, ttl, providerSpecific, setIdentifier, resource)...)
+	}
+	return endpoints, nil
+}
+
+// ingressTargets returns the public load balancer of the Knative Ingress, named
+// like the Service or the DomainMapping.
+func (ks *knativeSource) ingressTargets(namespace, name string) (endpoint.Targets, error) {
+	obj, err := ks.ingressInformer.Lister().ByNamespace(namespace).Get(name)
+	if err != nil {
+		log.Debugf("No Knative Ingress %s/%s: %v", namespace, name, err)
+		return nil, nil
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("could not convert Knative Ingress object to unstructured")
+	}
+	lbs, _, _ := unstructured.NestedSlice(u.Object, "status", "publicLoadBalancer", "ingress")
+
+	var targets endpoint.Targets
+	for _, item := range lbs {
+		lb, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ip, _, _ := unstructured.NestedString(lb, "ip")
+		domain, _, _ := unstructured.NestedString(lb, "domain")
+		internal, _, _ := unstructured.NestedString(lb, "domainInternal")
+		switch {
+		case ip != "":
+			targets = append(targets, ip)
+		case domain != "":
+			targets = append(targets, domain)
+		case internal != "":
+			targets = append(targets, ks.gatewayTargets(internal)...)
+		}
+	}
+	sort.Strings(targets)
+	return targets, nil
+}
+
+// gatewayTargets returns the load balancer of the gateway service with the
+// internal domain NAME.NAMESPACE.svc.CLUSTER-DOMAIN.
+func (ks *knativeSource) gatewayTargets(domain string) endpoint.Targets {
+	parts := strings.SplitN(domain, ".", 3)
+	if len(parts) < 3 || !strings.HasPrefix(parts[2], "svc.") {
+		return nil
+	}
+	svc, err := ks.kubeServiceInformer.Lister().Services(parts[1]).Get(parts[0])
+	if err != nil {
+		log.Debugf("No gateway service %s/%s: %v", parts[1], parts[0], err)
+		return nil
+	}
+	return extractLoadBalancerTargets(svc, false)
+}
+
+// knativeURLHost returns the host of the URL of a Knative Service.
+func knativeURLHost(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return u.Hostname(), nil
+}
+
+// AddEventHandler adds an event handler that should be triggered if the
+// watched Knative resources change.
+func (ks *knativeSource) AddEventHandler(ctx context.Context, handler func()) {
+	log.Debug("Adding event handler for Knative Services, DomainMappings and Ingresses")
+	ks.serviceInformer.Informer().AddEventHandler(eventHandlerFunc(handler))
+	ks.domainMappingInformer.Informer().AddEventHandler(eventHandlerFunc(handler))
+	ks.ingressInformer.Informer().AddEventHandler(eventHandlerFunc(handler))
+}
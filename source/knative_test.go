@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakeDynamic "k8s.io/client-go/dynamic/fake"
+	fakeKube "k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// This is a compile-time validation that knativeSource is a Source.
+var _ Source = &knativeSource{}
+
+func knativeObject(gvr schema.GroupVersionResource, kind, namespace, name string, annotations, labels map[string]string, status map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	if status != nil {
+		u.Object["status"] = status
+	}
+	u.SetAPIVersion(gvr.GroupVersion().String())
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	u.SetAnnotations(annotations)
+	u.SetLabels(labels)
+	return u
+}
+
+func TestKnativeSourceEndpoints(t *testing.T) {
+	kubeClient := fakeKube.NewSimpleClientset(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kourier-system", Name: "kourier"},
+		Status: v1.ServiceStatus{LoadBalancer: v1.LoadBalancerStatus{
+			Ingress: []v1.LoadBalancerIngress{{IP: "34.1.2.3"}},
+		}},
+	})
+	dynamicClient := fakeDynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		knativeServiceGVR:       "ServiceList",
+		knativeDomainMappingGVR: "DomainMappingList",
+		knativeIngressGVR:       "IngressList",
+	})
+	internal := map[string]interface{}{"publicLoadBalancer": map[string]interface{}{
+		"ingress": []interface{}{map[string]interface{}{"domainInternal": "kourier.kourier-system.svc.cluster.local"}},
+	}}
+	for _, obj := range []struct {
+		gvr schema.GroupVersionResource
+		u   *unstructured.Unstructured
+	}{
+		{knativeServiceGVR, knativeObject(knativeServiceGVR, "Service", "default", "hello", nil, nil,
+			map[string]interface{}{"url": "https://hello.default.example.com"})},
+		{knativeServiceGVR, knativeObject(knativeServiceGVR, "Service", "default", "private", nil,
+			map[string]string{knativeVisibilityLabel: knativeClusterLocal},
+			map[string]interface{}{"url": "http://private.default.svc.cluster.local"})},
+		{knativeServiceGVR, knativeObject(knativeServiceGVR, "Service", "default", "pending", nil, nil, nil)},
+		{knativeServiceGVR, knativeObject(knativeServiceGVR, "Service", "default", "pinned",
+			map[string]string{targetAnnotationKey: "edge.example.com", ttlAnnotationKey: "60"}, nil,
+			map[string]interface{}{"url": "https://pinned.default.example.com"})},
+		{knativeDomainMappingGVR, knativeObject(knativeDomainMappingGVR, "DomainMapping", "default", "www.example.org", nil, nil,
+			map[string]interface{}{"url": "https://www.example.org"})},
+		{knativeIngressGVR, knativeObject(knativeIngressGVR, "Ingress", "default", "hello", nil, nil, internal)},
+		{knativeIngressGVR, knativeObject(knativeIngressGVR, "Ingress", "default", "www.example.org", nil, nil,
+			map[string]interface{}{"publicLoadBalancer": map[string]interface{}{
+				"ingress": []interface{}{map[string]interface{}{"ip": "35.1.2.3"}},
+			}})},
+	} {
+		_, err := dynamicClient.Resource(obj.gvr).Namespace("default").Create(context.Background(), obj.u, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	src, err := NewKnativeSource(context.Background(), dynamicClient, kubeClient, "", "", false)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "hello.default.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"34.1.2.3"},
+			Labels: map[string]string{endpoint.ResourceLabelKey: "ksvc/default/hello"}},
+		{DNSName: "pinned.default.example.com", RecordType: endpoint.RecordTypeCNAME, Targets: endpoint.Targets{"edge.example.com"}, RecordTTL: 60,
+			Labels: map[string]string{endpoint.ResourceLabelKey: "ksvc/default/pinned"}},
+		{DNSName: "www.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"35.1.2.3"},
+			Labels: map[string]string{endpoint.ResourceLabelKey: "domainmapping/default/www.example.org"}},
+	})
+}
+
+func TestKnativeSourceAnnotationFilter(t *testing.T) {
+	dynamicClient := fakeDynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		knativeServiceGVR:       "ServiceList",
+		knativeDomainMappingGVR: "DomainMappingList",
+		knativeIngressGVR:       "IngressList",
+	})
+	for name, class := range map[string]string{"public": "external", "internal": "internal"} {
+		u := knativeObject(knativeServiceGVR, "Service", "default", name,
+			map[string]string{"dns.example.com/class": class, targetAnnotationKey: "1.2.3.4"}, nil,
+			map[string]interface{}{"url": "https://" + name + ".example.com"})
+		_, err := dynamicClient.Resource(knativeServiceGVR).Namespace("default").Create(context.Background(), u, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	src, err := NewKnativeSource(context.Background(), dynamicClient, fakeKube.NewSimpleClientset(), "default", "dns.example.com/class=external", true)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, "public.example.com", endpoints[0].DNSName)
+}
@@ -54,6 +54,8 @@ type kongTCPIngressSource struct {
 	kubeClient               kubernetes.Interface
 	namespace                string
 	unstructuredConverter    *unstructuredConverter
+
+	*informerHealth
 }
 
 // NewKongTCPIngressSource creates a new kongTCPIngressSource with the given config.
@@ -73,6 +75,10 @@ func NewKongTCPIngressSource(ctx context.Context, dynamicKubeClient dynamic.Inte
 		},
 	)
 
+	health := trackInformers(map[string]cache.SharedInformer{
+		"tcpingresses": kongTCPIngressInformer.Informer(),
+	})
+
 	informerFactory.Start(ctx.Done())
 
 	// wait for the local cache to be populated.
@@ -93,6 +99,7 @@ func NewKongTCPIngressSource(ctx context.Context, dynamicKubeClient dynamic.Inte
 		kubeClient:               kubeClient,
 		namespace:                namespace,
 		unstructuredConverter:    uc,
+		informerHealth:           health,
 	}, nil
 }
 
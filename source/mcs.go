@@ -0,0 +1,265 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	discoveryinformers "k8s.io/client-go/informers/discovery/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// MCSAPIGroup is the API group of the upstream Multi-Cluster Services
+	// API, implemented by Submariner and Cilium.
+	MCSAPIGroup = "multicluster.x-k8s.io"
+	// MCSGKEAPIGroup is the API group of the MCS API of GKE fleets.
+	MCSGKEAPIGroup = "net.gke.io"
+
+	// mcsServiceNameLabel is the label of the EndpointSlices of a
+	// ServiceImport, with the endpoints of all the clusters of the set.
+	mcsServiceNameLabel = "multicluster.kubernetes.io/service-name"
+	mcsHeadless         = "Headless"
+)
+
+// mcsVersions are the versions of the ServiceExports and ServiceImports of
+// the supported API groups.
+var mcsVersions = map[string]string{
+	MCSAPIGroup:    "v1alpha1",
+	MCSGKEAPIGroup: "v1",
+}
+
+// mcsSource is an implementation of Source for the ServiceImports of the
+// Multi-Cluster Services API, with the view of the clusters exporting a
+// service. A ServiceImport is published with the hostname annotations of
+// itself and of the ServiceExport of the same name, and as NAME.NAMESPACE.DOMAIN
+// with a domain. The targets are the target annotation, the clusterset VIPs
+// of the import, or the ready addresses of all the clusters for the headless
+// services.
+type mcsSource struct {
+	namespace                string
+	annotationFilter         string
+	domain                   string
+	ignoreHostnameAnnotation bool
+	exportInformer           informers.GenericInformer
+	importInformer           informers.GenericInformer
+	endpointSliceInformer    discoveryinformers.EndpointSliceInformer
+
+	*informerHealth
+}
+
+// NewMCSSource creates a new mcsSource for the ServiceImports of the API group.
+func NewMCSSource(ctx context.Context, dynamicKubeClient dynamic.Interface, kubeClient kubernetes.Interface, apiGroup string, namespace string, annotationFilter string, domain string, ignoreHostnameAnnotation bool) (Source, error) {
+	if apiGroup == "" {
+		apiGroup = MCSAPIGroup
+	}
+	version, ok := mcsVersions[apiGroup]
+	if !ok {
+		return nil, fmt.Errorf("unsupported MCS API group %q", apiGroup)
+	}
+
+	// Use shared informers to listen for add/update/delete of the resources in the specified namespace.
+	// Set resync period to 0, to prevent processing when nothing has changed.
+	informerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicKubeClient, 0, namespace, nil)
+	exportInformer := informerFactory.ForResource(schema.GroupVersionResource{Group: apiGroup, Version: version, Resource: "serviceexports"})
+	importInformer := informerFactory.ForResource(schema.GroupVersionResource{Group: apiGroup, Version: version, Resource: "serviceimports"})
+
+	// Add default resource event handlers to properly initialize informers.
+	for _, informer := range []informers.GenericInformer{exportInformer, importInformer} {
+		informer.Informer().AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {},
+			},
+		)
+	}
+
+	kubeInformerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 0, informers.WithNamespace(namespace))
+	endpointSliceInformer := kubeInformerFactory.Discovery().V1().EndpointSlices()
+	endpointSliceInformer.Informer().AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {},
+		},
+	)
+
+	health := trackInformers(map[string]cache.SharedInformer{
+		"serviceexports": exportInformer.Informer(),
+		"serviceimports": importInformer.Informer(),
+		"endpointslices": endpointSliceInformer.Informer(),
+	})
+
+	informerFactory.Start(ctx.Done())
+	kubeInformerFactory.Start(ctx.Done())
+
+	// wait for the local cache to be populated.
+	if err := waitForDynamicCacheSync(context.Background(), informerFactory); err != nil {
+		return nil, err
+	}
+	if err := waitForCacheSync(context.Background(), kubeInformerFactory); err != nil {
+		return nil, err
+	}
+
+	return &mcsSource{
+		namespace:                namespace,
+		annotationFilter:         annotationFilter,
+		domain:                   domain,
+		ignoreHostnameAnnotation: ignoreHostnameAnnotation,
+		exportInformer:           exportInformer,
+		importInformer:           importInformer,
+		endpointSliceInformer:    endpointSliceInformer,
+		informerHealth:           health,
+	}, nil
+}
+
+// Endpoints returns the endpoints of the ServiceImports.
+func (ms *mcsSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	selector, err := getLabelSelector(ms.annotationFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	objs, err := ms.importInformer.Lister().ByNamespace(ms.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var endpoints []*endpoint.Endpoint
+	for _, obj := range objs {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return nil, fmt.Errorf("could not convert ServiceImport object to unstructured")
+		}
+		annotations := ms.annotations(u)
+		if !matchLabelSelector(selector, annotations) {
+			continue
+		}
+		importEndpoints := ms.endpoints(u, annotations)
+		if len(importEndpoints) == 0 {
+			log.Debugf("No endpoints could be generated from ServiceImport %s/%s", u.GetNamespace(), u.GetName())
+			continue
+		}
+		log.Debugf("Endpoints generated from ServiceImport %s/%s: %v", u.GetNamespace(), u.GetName(), importEndpoints)
+		endpoints = append(endpoints, importEndpoints...)
+	}
+	return endpoints, nil
+}
+
+// annotations returns the annotations of the ServiceImport, over the ones of
+// the ServiceExport of the same name: the imports are created by the MCS
+// controller, the users annotate their exports.
+func (ms *mcsSource) annotations(u *unstructured.Unstructured) map[string]string {
+	annotations := map[string]string{}
+	if obj, err := ms.exportInformer.Lister().ByNamespace(u.GetNamespace()).Get(u.GetName()); err == nil {
+		if export, ok := obj.(*unstructured.Unstructured); ok {
+			for k, v := range export.GetAnnotations() {
+				annotations[k] = v
+			}
+		}
+	}
+	for k, v := range u.GetAnnotations() {
+		annotations[k] = v
+	}
+	return annotations
+}
+
+// endpoints returns the endpoints of a ServiceImport.
+func (ms *mcsSource) endpoints(u *unstructured.Unstructured, annotations map[string]string) []*endpoint.Endpoint {
+	resource := fmt.Sprintf("serviceimport/%s/%s", u.GetNamespace(), u.GetName())
+
+	var hostnames []string
+	if ms.domain != "" {
+		hostnames = append(hostnames, u.GetName()+"."+u.GetNamespace()+"."+ms.domain)
+	}
+	if !ms.ignoreHostnameAnnotation {
+		hostnames = append(hostnames, getHostnamesFromAnnotations(annotations)...)
+	}
+	if len(hostnames) == 0 {
+		return nil
+	}
+
+	targets := getTargetsFromTargetAnnotation(annotations)
+	if len(targets) == 0 {
+		importType, _, _ := unstructured.NestedString(u.Object, "spec", "type")
+		ips, _, _ := unstructured.NestedStringSlice(u.Object, "spec", "ips")
+		if importType == mcsHeadless || len(ips) == 0 {
+			targets = ms.clusterSetAddresses(u.GetNamespace(), u.GetName())
+		} else {
+			targets = ips
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	sort.Strings(targets)
+
+	ttl := getTTLFromAnnotations(annotations, resource)
+	providerSpecific, setIdentifier := getProviderSpecificAnnotations(annotations)
+	var endpoints []*endpoint.Endpoint
+	for _, hostname := range hostnames {
+		endpoints = append(endpoints, endpointsForHostname(hostname, targets, ttl, providerSpecific, setIdentifier, resource)...)
+	}
+	return endpoints
+}
+
+// clusterSetAddresses returns the ready addresses of the EndpointSlices of a
+// ServiceImport, from all the clusters exporting the service.
+func (ms *mcsSource) clusterSetAddresses(namespace, name string) endpoint.Targets {
+	slices, err := ms.endpointSliceInformer.Lister().EndpointSlices(namespace).List(labels.SelectorFromSet(labels.Set{mcsServiceNameLabel: name}))
+	if err != nil {
+		log.Debugf("No EndpointSlices for ServiceImport %s/%s: %v", namespace, name, err)
+		return nil
+	}
+	var targets endpoint.Targets
+	seen := map[string]bool{}
+	for _, slice := range slices {
+		if slice.AddressType == discoveryv1.AddressTypeFQDN {
+			continue
+		}
+		for _, ep := range slice.Endpoints {
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+				continue
+			}
+			for _, address := range ep.Addresses {
+				if !seen[address] {
+					seen[address] = true
+					targets = append(targets, address)
+				}
+			}
+		}
+	}
+	return targets
+}
+
+// AddEventHandler adds an event handler that should be triggered if the
+// ServiceExports, the ServiceImports or their EndpointSlices change.
+func (ms *mcsSource) AddEventHandler(ctx context.Context, handler func()) {
+	log.Debug("Adding event handler for ServiceExports, ServiceImports and EndpointSlices")
+	ms.exportInformer.Informer().AddEventHandler(eventHandlerFunc(handler))
+	ms.importInformer.Informer().AddEventHandler(eventHandlerFunc(handler))
+	ms.endpointSliceInformer.Informer().AddEventHandler(eventHandlerFunc(handler))
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakeDynamic "k8s.io/client-go/dynamic/fake"
+	fakeKube "k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// This is a compile-time validation that mcsSource is a Source.
+var _ Source = &mcsSource{}
+
+func mcsClients(t *testing.T, group string, objs ...*unstructured.Unstructured) *fakeDynamic.FakeDynamicClient {
+	version := mcsVersions[group]
+	exports := schema.GroupVersionResource{Group: group, Version: version, Resource: "serviceexports"}
+	imports := schema.GroupVersionResource{Group: group, Version: version, Resource: "serviceimports"}
+	dynamicClient := fakeDynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		exports: "ServiceExportList",
+		imports: "ServiceImportList",
+	})
+	for _, u := range objs {
+		gvr := imports
+		if u.GetKind() == "ServiceExport" {
+			gvr = exports
+		}
+		u.SetAPIVersion(gvr.GroupVersion().String())
+		_, err := dynamicClient.Resource(gvr).Namespace(u.GetNamespace()).Create(context.Background(), u, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+	return dynamicClient
+}
+
+func mcsObject(kind, namespace, name string, annotations map[string]string, spec map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	if spec != nil {
+		u.Object["spec"] = spec
+	}
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	u.SetAnnotations(annotations)
+	return u
+}
+
+func TestMCSSourceEndpoints(t *testing.T) {
+	notReady := false
+	kubeClient := fakeKube.NewSimpleClientset(
+		&discoveryv1.EndpointSlice{
+			ObjectMeta:  metav1.ObjectMeta{Namespace: "db", Name: "cassandra-east", Labels: map[string]string{mcsServiceNameLabel: "cassandra"}},
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Endpoints: []discoveryv1.Endpoint{
+				{Addresses: []string{"10.1.0.5"}},
+				{Addresses: []string{"10.1.0.6"}, Conditions: discoveryv1.EndpointConditions{Ready: &notReady}},
+			},
+		},
+		&discoveryv1.EndpointSlice{
+			ObjectMeta:  metav1.ObjectMeta{Namespace: "db", Name: "cassandra-west", Labels: map[string]string{mcsServiceNameLabel: "cassandra"}},
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"10.2.0.7"}}},
+		},
+	)
+	dynamicClient := mcsClients(t, MCSGKEAPIGroup,
+		mcsObject("ServiceExport", "shop", "checkout", map[string]string{hostnameAnnotationKey: "checkout.example.com", ttlAnnotationKey: "30"}, nil),
+		mcsObject("ServiceImport", "shop", "checkout", nil, map[string]interface{}{"type": "ClusterSetIP", "ips": []interface{}{"10.100.0.9"}}),
+		mcsObject("ServiceImport", "db", "cassandra", nil, map[string]interface{}{"type": mcsHeadless}),
+		// An export not imported yet is not published.
+		mcsObject("ServiceExport", "shop", "cart", map[string]string{hostnameAnnotationKey: "cart.example.com"}, nil),
+	)
+
+	src, err := NewMCSSource(context.Background(), dynamicClient, kubeClient, MCSGKEAPIGroup, "", "", "fleet.example.com", false)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+
+	cassandra := map[string]string{endpoint.ResourceLabelKey: "serviceimport/db/cassandra"}
+	checkout := map[string]string{endpoint.ResourceLabelKey: "serviceimport/shop/checkout"}
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "cassandra.db.fleet.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.1.0.5", "10.2.0.7"}, Labels: cassandra},
+		{DNSName: "checkout.shop.fleet.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.100.0.9"}, RecordTTL: 30, Labels: checkout},
+		{DNSName: "checkout.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.100.0.9"}, RecordTTL: 30, Labels: checkout},
+	})
+}
+
+func TestMCSSourceAnnotations(t *testing.T) {
+	dynamicClient := mcsClients(t, MCSAPIGroup,
+		mcsObject("ServiceExport", "default", "public", map[string]string{"dns.example.com/class": "external", hostnameAnnotationKey: "public.example.com"}, nil),
+		mcsObject("ServiceImport", "default", "public", map[string]string{targetAnnotationKey: "lb.example.com"}, map[string]interface{}{"ips": []interface{}{"10.100.0.1"}}),
+		mcsObject("ServiceExport", "default", "internal", map[string]string{"dns.example.com/class": "internal", hostnameAnnotationKey: "internal.example.com"}, nil),
+		mcsObject("ServiceImport", "default", "internal", nil, map[string]interface{}{"ips": []interface{}{"10.100.0.2"}}),
+	)
+
+	src, err := NewMCSSource(context.Background(), dynamicClient, fakeKube.NewSimpleClientset(), "", "default", "dns.example.com/class=external", "", false)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, "public.example.com", endpoints[0].DNSName)
+	assert.Equal(t, endpoint.Targets{"lb.example.com"}, endpoints[0].Targets)
+	assert.Equal(t, endpoint.RecordTypeCNAME, endpoints[0].RecordType)
+
+	_, err = NewMCSSource(context.Background(), dynamicClient, fakeKube.NewSimpleClientset(), "mcs.example.com", "", "", "", false)
+	assert.Error(t, err)
+}
@@ -18,41 +18,305 @@ package source
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
 
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
+var (
+	sourceFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "external_dns",
+			Subsystem: "source",
+			Name:      "failures_total",
+			Help:      "Number of failures to collect the endpoints of a source, by source.",
+		},
+		[]string{"source"},
+	)
+	sourceMergedEndpoints = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "external_dns",
+			Subsystem: "source",
+			Name:      "merged_endpoints",
+			Help:      "Number of names, by record type and set identifier, with different targets in several sources, merged by the merge policy.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(sourceFailuresTotal)
+	prometheus.MustRegister(sourceMergedEndpoints)
+}
+
+// The merge policies of the endpoints of the same name, record type and set
+// identifier, with different targets, of several sources.
+const (
+	// MergeNone keeps all the endpoints, the plan picks one of them.
+	MergeNone = "none"
+	// MergeUnion merges the targets of the endpoints, except for the CNAME
+	// endpoints, which keep the ones of the first source.
+	MergeUnion = "union"
+	// MergePriority keeps the endpoint of the first source, in the order of
+	// the sources.
+	MergePriority = "priority"
+	// MergeError fails the collection.
+	MergeError = "error"
+)
+
 // multiSource is a Source that merges the endpoints of its nested Sources.
 type multiSource struct {
 	children       []Source
 	defaultTargets []string
+	names          []string
+	timeout        time.Duration
+	skipFailing    bool
+	mergePolicy    string
+
+	// last holds the endpoints of the last successful collection of each
+	// child, reused for the failing ones with skipFailing.
+	mu   sync.Mutex
+	last [][]*endpoint.Endpoint
+}
+
+// MultiSourceOption configures the collection of the nested Sources.
+type MultiSourceOption func(*multiSource)
+
+// WithSourceNames names the nested Sources, in order, in the logs and metrics.
+func WithSourceNames(names []string) MultiSourceOption {
+	return func(ms *multiSource) {
+		ms.names = names
+	}
+}
+
+// WithSourceTimeout bounds the time to collect the endpoints of each nested
+// Source; 0 means no timeout.
+func WithSourceTimeout(timeout time.Duration) MultiSourceOption {
+	return func(ms *multiSource) {
+		ms.timeout = timeout
+	}
+}
+
+// WithSkipFailingSources makes a failing nested Source keep the endpoints of
+// its last successful collection instead of failing the whole collection. A
+// Source failing before any success still fails it: without its endpoints,
+// the plan would delete its records.
+func WithSkipFailingSources() MultiSourceOption {
+	return func(ms *multiSource) {
+		ms.skipFailing = true
+	}
+}
+
+// WithMergePolicy merges the endpoints of several Sources with the same name,
+// record type and set identifier, and different targets, with one of the
+// Merge policies - for example the endpoints of the same name in two clusters.
+func WithMergePolicy(policy string) MultiSourceOption {
+	return func(ms *multiSource) {
+		ms.mergePolicy = policy
+	}
 }
 
-// Endpoints collects endpoints of all nested Sources and returns them in a single slice.
+// Endpoints collects endpoints of all nested Sources, concurrently, and returns them in a single slice.
 func (ms *multiSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
-	result := []*endpoint.Endpoint{}
+	collected := make([][]*endpoint.Endpoint, len(ms.children))
+	eg, egCtx := errgroup.WithContext(ctx)
+	for i, s := range ms.children {
+		i, s := i, s
+		eg.Go(func() error {
+			endpoints, err := ms.collect(egCtx, s)
+			if err == nil {
+				collected[i] = endpoints
+				ms.setLast(i, endpoints)
+				return nil
+			}
+			sourceFailuresTotal.WithLabelValues(ms.name(i)).Inc()
+			if last, ok := ms.getLast(i); ms.skipFailing && ok {
+				log.Warnf("Source %s failed, reusing the endpoints of its last collection: %v", ms.name(i), err)
+				collected[i] = last
+				return nil
+			}
+			if i >= len(ms.names) {
+				return err
+			}
+			return fmt.Errorf("source %s: %w", ms.names[i], err)
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
 
-	for _, s := range ms.children {
-		endpoints, err := s.Endpoints(ctx)
-		if err != nil {
-			return nil, err
-		}
-		if len(ms.defaultTargets) > 0 {
+	if len(ms.defaultTargets) > 0 {
+		for c, endpoints := range collected {
+			var withDefaults []*endpoint.Endpoint
 			for i := range endpoints {
 				eps := endpointsForHostname(endpoints[i].DNSName, ms.defaultTargets, endpoints[i].RecordTTL, endpoints[i].ProviderSpecific, endpoints[i].SetIdentifier, "")
 				for _, ep := range eps {
 					ep.Labels = endpoints[i].Labels
 				}
-				result = append(result, eps...)
+				withDefaults = append(withDefaults, eps...)
 			}
-		} else {
+			collected[c] = withDefaults
+		}
+	}
+
+	return ms.merge(collected)
+}
+
+// mergeKey identifies the endpoints merged by the merge policy.
+type mergeKey struct {
+	dnsName, recordType, setIdentifier string
+}
+
+// merge returns the endpoints of the Sources, in order, with the endpoints of
+// the same mergeKey of several Sources merged by the merge policy. The
+// endpoints of a Source are not merged together.
+func (ms *multiSource) merge(collected [][]*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	result := []*endpoint.Endpoint{}
+	if ms.mergePolicy == "" || ms.mergePolicy == MergeNone {
+		for _, endpoints := range collected {
 			result = append(result, endpoints...)
 		}
+		return result, nil
 	}
 
+	// first is the index in result of the first endpoint of each key, and
+	// source the Source of it.
+	first := map[mergeKey]int{}
+	source := map[mergeKey]int{}
+	merged := map[mergeKey]bool{}
+	for i, endpoints := range collected {
+		for _, ep := range endpoints {
+			key := mergeKey{strings.ToLower(strings.TrimSuffix(ep.DNSName, ".")), ep.RecordType, ep.SetIdentifier}
+			j, ok := first[key]
+			if !ok {
+				first[key] = len(result)
+				source[key] = i
+			}
+			if !ok || source[key] == i {
+				result = append(result, ep)
+				continue
+			}
+			existing := result[j]
+			if existing.Targets.Same(ep.Targets) {
+				continue
+			}
+			merged[key] = true
+			switch {
+			case ms.mergePolicy == MergeError:
+				return nil, fmt.Errorf("sources %s and %s have different targets for %s %s: %s and %s", ms.name(source[key]), ms.name(i), ep.RecordType, ep.DNSName, existing.Targets, ep.Targets)
+			case ms.mergePolicy == MergeUnion && ep.RecordType != endpoint.RecordTypeCNAME:
+				union := existing.DeepCopy()
+				for _, t := range ep.Targets {
+					if !hasTarget(union.Targets, t) {
+						union.Targets = append(union.Targets, t)
+					}
+				}
+				result[j] = union
+			default:
+				log.Debugf("Keeping the targets %s of source %s for %s %s, not %s of source %s", existing.Targets, ms.name(source[key]), ep.RecordType, ep.DNSName, ep.Targets, ms.name(i))
+			}
+		}
+	}
+	sourceMergedEndpoints.Set(float64(len(merged)))
 	return result, nil
 }
 
+// collect returns the endpoints of a nested Source, within the timeout. An
+// unhealthy Source fails: its endpoints may be missing.
+func (ms *multiSource) collect(ctx context.Context, s Source) ([]*endpoint.Endpoint, error) {
+	if ms.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ms.timeout)
+		defer cancel()
+	}
+	endpoints, err := s.Endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := Healthy(s); err != nil {
+		return nil, fmt.Errorf("not healthy: %w", err)
+	}
+	return endpoints, nil
+}
+
+// Healthy returns the errors of the unhealthy nested Sources, except the
+// ones with a last collection to reuse with WithSkipFailingSources.
+func (ms *multiSource) Healthy() error {
+	var errs []error
+	for i, s := range ms.children {
+		err := Healthy(s)
+		if err == nil {
+			continue
+		}
+		if _, ok := ms.getLast(i); ms.skipFailing && ok {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("source %s: %w", ms.name(i), err))
+	}
+	return errors.Join(errs...)
+}
+
+// LastEvent returns the time of the last event of the nested Sources.
+func (ms *multiSource) LastEvent() time.Time {
+	var last time.Time
+	for _, s := range ms.children {
+		if t := LastEvent(s); t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
+
+func (ms *multiSource) setLast(i int, endpoints []*endpoint.Endpoint) {
+	if !ms.skipFailing {
+		return
+	}
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.last == nil {
+		ms.last = make([][]*endpoint.Endpoint, len(ms.children))
+	}
+	// A nil slice would read as no collection yet.
+	if endpoints == nil {
+		endpoints = []*endpoint.Endpoint{}
+	}
+	ms.last[i] = endpoints
+}
+
+func (ms *multiSource) getLast(i int) ([]*endpoint.Endpoint, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.last == nil || ms.last[i] == nil {
+		return nil, false
+	}
+	return ms.last[i], true
+}
+
+func hasTarget(targets endpoint.Targets, target string) bool {
+	for _, t := range targets {
+		if strings.EqualFold(t, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// name returns the name of the nested Source i, or its index.
+func (ms *multiSource) name(i int) string {
+	if i < len(ms.names) {
+		return ms.names[i]
+	}
+	return strconv.Itoa(i)
+}
+
 func (ms *multiSource) AddEventHandler(ctx context.Context, handler func()) {
 	for _, s := range ms.children {
 		s.AddEventHandler(ctx, handler)
@@ -60,6 +324,10 @@ func (ms *multiSource) AddEventHandler(ctx context.Context, handler func()) {
 }
 
 // NewMultiSource creates a new multiSource.
-func NewMultiSource(children []Source, defaultTargets []string) Source {
-	return &multiSource{children: children, defaultTargets: defaultTargets}
+func NewMultiSource(children []Source, defaultTargets []string, opts ...MultiSourceOption) Source {
+	ms := &multiSource{children: children, defaultTargets: defaultTargets}
+	for _, opt := range opts {
+		opt(ms)
+	}
+	return ms
 }
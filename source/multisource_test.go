@@ -19,7 +19,10 @@ package source
 import (
 	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -35,6 +38,10 @@ func TestMultiSource(t *testing.T) {
 	t.Run("Endpoints", testMultiSourceEndpoints)
 	t.Run("EndpointsWithError", testMultiSourceEndpointsWithError)
 	t.Run("EndpointsDefaultTargets", testMultiSourceEndpointsDefaultTargets)
+	t.Run("EndpointsConcurrent", testMultiSourceEndpointsConcurrent)
+	t.Run("EndpointsTimeout", testMultiSourceEndpointsTimeout)
+	t.Run("EndpointsSkipFailing", testMultiSourceEndpointsSkipFailing)
+	t.Run("EndpointsMergePolicy", testMultiSourceEndpointsMergePolicy)
 }
 
 // testMultiSourceImplementsSource tests that multiSource is a valid Source.
@@ -168,3 +175,140 @@ func testMultiSourceEndpointsDefaultTargets(t *testing.T) {
 	// Validate that the nested sources were called.
 	src.AssertExpectations(t)
 }
+
+// funcSource is a Source returning the endpoints of a function.
+type funcSource func(ctx context.Context) ([]*endpoint.Endpoint, error)
+
+func (f funcSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	return f(ctx)
+}
+
+func (f funcSource) AddEventHandler(context.Context, func()) {}
+
+// testMultiSourceEndpointsConcurrent tests that the children are collected
+// concurrently, and their endpoints merged in order.
+func testMultiSourceEndpointsConcurrent(t *testing.T) {
+	foo := &endpoint.Endpoint{DNSName: "foo", Targets: endpoint.Targets{"8.8.8.8"}}
+	bar := &endpoint.Endpoint{DNSName: "bar", Targets: endpoint.Targets{"8.8.4.4"}}
+
+	// Each source waits for the other one to be called.
+	var started sync.WaitGroup
+	started.Add(2)
+	wait := func(ep *endpoint.Endpoint) Source {
+		return funcSource(func(ctx context.Context) ([]*endpoint.Endpoint, error) {
+			started.Done()
+			started.Wait()
+			return []*endpoint.Endpoint{ep}, nil
+		})
+	}
+
+	endpoints, err := NewMultiSource([]Source{wait(foo), wait(bar)}, nil).Endpoints(context.Background())
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{foo, bar})
+}
+
+// testMultiSourceEndpointsTimeout tests that a slow child fails the
+// collection after the timeout.
+func testMultiSourceEndpointsTimeout(t *testing.T) {
+	slow := funcSource(func(ctx context.Context) ([]*endpoint.Endpoint, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	fast := funcSource(func(ctx context.Context) ([]*endpoint.Endpoint, error) {
+		return []*endpoint.Endpoint{}, nil
+	})
+
+	source := NewMultiSource([]Source{fast, slow}, nil, WithSourceNames([]string{"fast", "slow"}), WithSourceTimeout(10*time.Millisecond))
+	_, err := source.Endpoints(context.Background())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "source slow")
+}
+
+// testMultiSourceEndpointsSkipFailing tests that a failing child reuses the
+// endpoints of its last collection, and fails the collection without one.
+func testMultiSourceEndpointsSkipFailing(t *testing.T) {
+	foo := &endpoint.Endpoint{DNSName: "foo", Targets: endpoint.Targets{"8.8.8.8"}}
+	bar := &endpoint.Endpoint{DNSName: "bar", Targets: endpoint.Targets{"8.8.4.4"}}
+
+	var fail atomic.Bool
+	fail.Store(true)
+	flaky := funcSource(func(ctx context.Context) ([]*endpoint.Endpoint, error) {
+		if fail.Load() {
+			return nil, errors.New("some error")
+		}
+		return []*endpoint.Endpoint{bar}, nil
+	})
+	stable := funcSource(func(ctx context.Context) ([]*endpoint.Endpoint, error) {
+		return []*endpoint.Endpoint{foo}, nil
+	})
+	source := NewMultiSource([]Source{stable, flaky}, nil, WithSkipFailingSources())
+
+	_, err := source.Endpoints(context.Background())
+	assert.EqualError(t, err, "some error")
+
+	fail.Store(false)
+	endpoints, err := source.Endpoints(context.Background())
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{foo, bar})
+
+	fail.Store(true)
+	endpoints, err = source.Endpoints(context.Background())
+	require.NoError(t, err)
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{foo, bar})
+}
+
+// testMultiSourceEndpointsMergePolicy tests that the endpoints of the same name
+// of several children are merged by the merge policy.
+func testMultiSourceEndpointsMergePolicy(t *testing.T) {
+	static := func(endpoints ...*endpoint.Endpoint) Source {
+		return funcSource(func(ctx context.Context) ([]*endpoint.Endpoint, error) {
+			return endpoints, nil
+		})
+	}
+	east := static(
+		endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "10.0.0.1"),
+		endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeCNAME, "east.lb.example.net"),
+		endpoint.NewEndpoint("east.example.org", endpoint.RecordTypeA, "10.0.0.1"),
+	)
+	west := static(
+		endpoint.NewEndpoint("App.example.org.", endpoint.RecordTypeA, "10.0.1.1", "10.0.0.1"),
+		endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeCNAME, "west.lb.example.net"),
+		endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "10.0.2.1").WithSetIdentifier("west"),
+	)
+	names := WithSourceNames([]string{"east", "west"})
+
+	for _, tc := range []struct {
+		policy   string
+		expected []*endpoint.Endpoint
+	}{
+		{MergeNone, []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "10.0.0.1"),
+			endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeCNAME, "east.lb.example.net"),
+			endpoint.NewEndpoint("east.example.org", endpoint.RecordTypeA, "10.0.0.1"),
+			endpoint.NewEndpoint("App.example.org.", endpoint.RecordTypeA, "10.0.1.1", "10.0.0.1"),
+			endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeCNAME, "west.lb.example.net"),
+			endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "10.0.2.1").WithSetIdentifier("west"),
+		}},
+		{MergeUnion, []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "10.0.0.1", "10.0.1.1"),
+			endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeCNAME, "east.lb.example.net"),
+			endpoint.NewEndpoint("east.example.org", endpoint.RecordTypeA, "10.0.0.1"),
+			endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "10.0.2.1").WithSetIdentifier("west"),
+		}},
+		{MergePriority, []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "10.0.0.1"),
+			endpoint.NewEndpoint("www.example.org", endpoint.RecordTypeCNAME, "east.lb.example.net"),
+			endpoint.NewEndpoint("east.example.org", endpoint.RecordTypeA, "10.0.0.1"),
+			endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "10.0.2.1").WithSetIdentifier("west"),
+		}},
+	} {
+		t.Run(tc.policy, func(t *testing.T) {
+			endpoints, err := NewMultiSource([]Source{east, west}, nil, names, WithMergePolicy(tc.policy)).Endpoints(context.Background())
+			require.NoError(t, err)
+			validateEndpoints(t, endpoints, tc.expected)
+		})
+	}
+
+	_, err := NewMultiSource([]Source{east, west}, nil, names, WithMergePolicy(MergeError)).Endpoints(context.Background())
+	assert.ErrorContains(t, err, "sources east and west have different targets for A App.example.org:")
+}
@@ -39,6 +39,8 @@ type nodeSource struct {
 	fqdnTemplate     *template.Template
 	nodeInformer     coreinformers.NodeInformer
 	labelSelector    labels.Selector
+
+	*informerHealth
 }
 
 // NewNodeSource creates a new nodeSource with the given config.
@@ -62,6 +64,10 @@ func NewNodeSource(ctx context.Context, kubeClient kubernetes.Interface, annotat
 		},
 	)
 
+	health := trackInformers(map[string]cache.SharedInformer{
+		"nodes": nodeInformer.Informer(),
+	})
+
 	informerFactory.Start(ctx.Done())
 
 	// wait for the local cache to be populated.
@@ -75,6 +81,7 @@ func NewNodeSource(ctx context.Context, kubeClient kubernetes.Interface, annotat
 		fqdnTemplate:     tmpl,
 		nodeInformer:     nodeInformer,
 		labelSelector:    labelSelector,
+		informerHealth:   health,
 	}, nil
 }
 
@@ -0,0 +1,300 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
+)
+
+const (
+	// nomadNameTag is the prefix of the service tag with the hostnames of the
+	// service, comma separated, like dns.name=web.example.com.
+	nomadNameTag = "dns.name="
+	// nomadTTLTag is the prefix of the service tag with the TTL, like
+	// dns.ttl=5m.
+	nomadTTLTag = "dns.ttl="
+
+	defaultNomadAddress = "http://127.0.0.1:4646"
+
+	// nomadWaitTime is the maximum duration of a blocking query.
+	nomadWaitTime = 5 * time.Minute
+	// nomadRetryInterval is the interval between the blocking queries after
+	// an error.
+	nomadRetryInterval = 10 * time.Second
+)
+
+// NomadConfig is the configuration of the nomad source.
+type NomadConfig struct {
+	// Address of the Nomad API, like http://127.0.0.1:4646 - NOMAD_ADDR if
+	// empty.
+	Address string
+	// Token is the ACL token - NOMAD_TOKEN if empty.
+	Token string
+	// Region to list, the region of the agent if empty.
+	Region string
+	// Namespaces to list, "*" for all - NOMAD_NAMESPACE or default if empty.
+	Namespaces []string
+	// Tags the services must all have.
+	Tags []string
+	// Domain of the services without a dns.name tag, published as
+	// SERVICE.DOMAIN - they are skipped if empty.
+	Domain string
+}
+
+// nomadSource is an implementation of Source for the native service
+// registrations of Nomad, the Nomad counterpart of the consul source.
+type nomadSource struct {
+	cfg     NomadConfig
+	client  *http.Client
+	baseURL string
+	timeout time.Duration
+	retry   time.Duration
+	log     *slog.Logger
+}
+
+// nomadNamespaceServices is an entry of the services list API, with the
+// services of a namespace.
+type nomadNamespaceServices struct {
+	Namespace string `json:"Namespace"`
+	Services  []struct {
+		ServiceName string   `json:"ServiceName"`
+		Tags        []string `json:"Tags"`
+	} `json:"Services"`
+}
+
+// nomadServiceRegistration is the part of a service registration used by the
+// source.
+type nomadServiceRegistration struct {
+	ID          string   `json:"ID"`
+	ServiceName string   `json:"ServiceName"`
+	Namespace   string   `json:"Namespace"`
+	Tags        []string `json:"Tags"`
+	Address     string   `json:"Address"`
+}
+
+// NewNomadSource returns a source for the service registrations of the
+// namespaces of the configuration, with all its tags.
+func NewNomadSource(cfg NomadConfig, timeout time.Duration) (Source, error) {
+	if cfg.Address == "" {
+		cfg.Address = os.Getenv("NOMAD_ADDR")
+	}
+	if cfg.Address == "" {
+		cfg.Address = defaultNomadAddress
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("NOMAD_TOKEN")
+	}
+	if len(cfg.Namespaces) == 0 {
+		if ns := os.Getenv("NOMAD_NAMESPACE"); ns != "" {
+			cfg.Namespaces = []string{ns}
+		} else {
+			cfg.Namespaces = []string{"default"}
+		}
+	}
+	if !strings.Contains(cfg.Address, "://") {
+		cfg.Address = "http://" + cfg.Address
+	}
+	u, err := url.Parse(cfg.Address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid nomad address %q", cfg.Address)
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &nomadSource{
+		cfg:     cfg,
+		client:  &http.Client{},
+		baseURL: strings.TrimSuffix(u.String(), "/"),
+		timeout: timeout,
+		retry:   nomadRetryInterval,
+		log:     logging.For("source/nomad"),
+	}, nil
+}
+
+// Endpoints returns the endpoints of the registrations of the services with
+// the tags. An unreachable API fails the sync, so the records are not deleted.
+func (ns *nomadSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, ns.timeout)
+	defer cancel()
+
+	var list []nomadNamespaceServices
+	if _, err := ns.get(ctx, "/v1/services", url.Values{"namespace": {"*"}}, &list); err != nil {
+		return nil, fmt.Errorf("nomad source: %w", err)
+	}
+	type service struct{ namespace, name string }
+	var services []service
+	for _, l := range list {
+		if !ns.matchNamespace(l.Namespace) {
+			continue
+		}
+		for _, s := range l.Services {
+			if hasAllTags(s.Tags, ns.cfg.Tags) {
+				services = append(services, service{l.Namespace, s.ServiceName})
+			}
+		}
+	}
+	sort.Slice(services, func(i, j int) bool {
+		if services[i].namespace != services[j].namespace {
+			return services[i].namespace < services[j].namespace
+		}
+		return services[i].name < services[j].name
+	})
+
+	var endpoints []*endpoint.Endpoint
+	for _, s := range services {
+		var registrations []nomadServiceRegistration
+		if _, err := ns.get(ctx, "/v1/service/"+url.PathEscape(s.name), url.Values{"namespace": {s.namespace}}, &registrations); err != nil {
+			return nil, fmt.Errorf("nomad source: service %s/%s: %w", s.namespace, s.name, err)
+		}
+		endpoints = append(endpoints, ns.serviceEndpoints(s.namespace, s.name, registrations)...)
+	}
+	ns.log.Debug("Listed services", "services", len(services), "endpoints", len(endpoints))
+	return endpoints, nil
+}
+
+func (ns *nomadSource) matchNamespace(namespace string) bool {
+	return containsString(ns.cfg.Namespaces, "*") || containsString(ns.cfg.Namespaces, namespace)
+}
+
+// serviceEndpoints returns the endpoints of the registrations of a service,
+// each hostname pointing to the addresses of the registrations with it.
+func (ns *nomadSource) serviceEndpoints(namespace, name string, registrations []nomadServiceRegistration) []*endpoint.Endpoint {
+	targets := map[string]endpoint.Targets{}
+	ttls := map[string]endpoint.TTL{}
+	var hostnames []string
+	for _, r := range registrations {
+		// The list has the services with a tag on any registration.
+		if !hasAllTags(r.Tags, ns.cfg.Tags) || r.Address == "" {
+			continue
+		}
+		var names []string
+		var ttl endpoint.TTL
+		for _, tag := range r.Tags {
+			switch {
+			case strings.HasPrefix(tag, nomadNameTag):
+				names = append(names, splitLabel(strings.TrimPrefix(tag, nomadNameTag))...)
+			case strings.HasPrefix(tag, nomadTTLTag):
+				v := strings.TrimPrefix(tag, nomadTTLTag)
+				seconds, err := parseTTL(v)
+				if err != nil {
+					ns.log.Warn("Invalid TTL tag", "service", name, "registration", r.ID, "ttl", v, "error", err)
+				} else {
+					ttl = endpoint.TTL(seconds)
+				}
+			}
+		}
+		if len(names) == 0 && ns.cfg.Domain != "" {
+			names = []string{strings.ToLower(name) + "." + strings.Trim(ns.cfg.Domain, ".")}
+		}
+		for _, hostname := range names {
+			if _, ok := targets[hostname]; !ok {
+				hostnames = append(hostnames, hostname)
+			}
+			if !containsString(targets[hostname], r.Address) {
+				targets[hostname] = append(targets[hostname], r.Address)
+			}
+			if ttl != 0 {
+				ttls[hostname] = ttl
+			}
+		}
+	}
+
+	var endpoints []*endpoint.Endpoint
+	for _, hostname := range hostnames {
+		sort.Strings(targets[hostname])
+		endpoints = append(endpoints, endpointsForHostname(hostname, targets[hostname], ttls[hostname], nil, "", "nomad/"+namespace+"/"+name)...)
+	}
+	return endpoints
+}
+
+// get decodes the response of the API into v, returning the X-Nomad-Index of
+// the response.
+func (ns *nomadSource) get(ctx context.Context, path string, query url.Values, v any) (string, error) {
+	if ns.cfg.Region != "" {
+		query.Set("region", ns.cfg.Region)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ns.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if ns.cfg.Token != "" {
+		req.Header.Set("X-Nomad-Token", ns.cfg.Token)
+	}
+	resp, err := ns.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("GET %s: %s: %s", path, resp.Status, bytes.TrimSpace(data))
+	}
+	return resp.Header.Get("X-Nomad-Index"), json.NewDecoder(resp.Body).Decode(v)
+}
+
+// AddEventHandler calls the handler when a service is registered or
+// deregistered, with blocking queries of the services list.
+func (ns *nomadSource) AddEventHandler(ctx context.Context, handler func()) {
+	go func() {
+		index := ""
+		for ctx.Err() == nil {
+			query := url.Values{"namespace": {"*"}, "wait": {nomadWaitTime.String()}}
+			if index != "" {
+				query.Set("index", index)
+			}
+			// The agent answers within the wait time, plus a random jitter of
+			// up to 1/16 of it.
+			reqCtx, cancel := context.WithTimeout(ctx, nomadWaitTime+nomadWaitTime/16+ns.timeout)
+			var list []nomadNamespaceServices
+			next, err := ns.get(reqCtx, "/v1/services", query, &list)
+			cancel()
+			if err == nil && next == "" {
+				err = fmt.Errorf("no X-Nomad-Index in the response")
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				ns.log.Warn("Failed to watch the services", "error", err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(ns.retry):
+				}
+				continue
+			}
+			if index != "" && next != index {
+				handler()
+			}
+			index = next
+		}
+	}()
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	testNomadServices = `[
+  {"Namespace": "default", "Services": [{"ServiceName": "web", "Tags": ["public", "dns.ttl=5m"]}, {"ServiceName": "db", "Tags": []}]},
+  {"Namespace": "billing", "Services": [{"ServiceName": "api", "Tags": ["public", "dns.name=api.example.com,api.example.org"]}]}
+]`
+	testNomadWeb = `[
+  {"ID": "_nomad-task-1", "ServiceName": "web", "Namespace": "default", "Tags": ["public", "dns.ttl=5m"], "Address": "10.0.0.1"},
+  {"ID": "_nomad-task-2", "ServiceName": "web", "Namespace": "default", "Tags": ["public", "dns.ttl=5m"], "Address": "10.0.0.2"},
+  {"ID": "_nomad-task-3", "ServiceName": "web", "Namespace": "default", "Tags": ["canary"], "Address": "10.0.0.3"}
+]`
+	testNomadAPI = `[
+  {"ID": "_nomad-task-4", "ServiceName": "api", "Namespace": "billing", "Tags": ["public", "dns.name=api.example.com,api.example.org"], "Address": "2001:db8::4"}
+]`
+)
+
+// newNomadServer returns a Nomad API serving the services of the tests, and
+// the queries of the service API.
+func newNomadServer(t *testing.T, queries map[string]string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-Nomad-Token"))
+		assert.Equal(t, "eu", r.URL.Query().Get("region"))
+		w.Header().Set("X-Nomad-Index", "10")
+		switch r.URL.Path {
+		case "/v1/services":
+			assert.Equal(t, "*", r.URL.Query().Get("namespace"))
+			w.Write([]byte(testNomadServices))
+		case "/v1/service/web":
+			queries["web"] = r.URL.RawQuery
+			w.Write([]byte(testNomadWeb))
+		case "/v1/service/api":
+			queries["api"] = r.URL.RawQuery
+			w.Write([]byte(testNomadAPI))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNomadSourceEndpoints(t *testing.T) {
+	queries := map[string]string{}
+	srv := newNomadServer(t, queries)
+
+	src, err := NewNomadSource(NomadConfig{
+		Address:    srv.URL,
+		Token:      "secret",
+		Region:     "eu",
+		Namespaces: []string{"*"},
+		Tags:       []string{"public"},
+		Domain:     "nomad.example.com",
+	}, time.Minute)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"web": "namespace=default&region=eu", "api": "namespace=billing&region=eu"}, queries)
+	api := map[string]string{endpoint.ResourceLabelKey: "nomad/billing/api"}
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "api.example.com", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2001:db8::4"}, Labels: api},
+		{DNSName: "api.example.org", RecordType: endpoint.RecordTypeAAAA, Targets: endpoint.Targets{"2001:db8::4"}, Labels: api},
+		{DNSName: "web.nomad.example.com", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.0.1", "10.0.0.2"}, RecordTTL: 300,
+			Labels: map[string]string{endpoint.ResourceLabelKey: "nomad/default/web"}},
+	})
+}
+
+func TestNomadSourceNamespaces(t *testing.T) {
+	queries := map[string]string{}
+	srv := newNomadServer(t, queries)
+
+	src, err := NewNomadSource(NomadConfig{Address: srv.URL, Token: "secret", Region: "eu", Namespaces: []string{"billing"}}, time.Minute)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+
+	assert.Len(t, endpoints, 2)
+	assert.Equal(t, map[string]string{"api": "namespace=billing&region=eu"}, queries)
+}
+
+func TestNomadSourceError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	src, err := NewNomadSource(NomadConfig{Address: srv.URL}, time.Minute)
+	require.NoError(t, err)
+	_, err = src.Endpoints(context.Background())
+	assert.ErrorContains(t, err, "Permission denied")
+}
+
+func TestNewNomadSourceEnv(t *testing.T) {
+	t.Setenv("NOMAD_ADDR", "https://nomad.local:4646")
+	t.Setenv("NOMAD_TOKEN", "from-env")
+	t.Setenv("NOMAD_NAMESPACE", "apps")
+	src, err := NewNomadSource(NomadConfig{}, 0)
+	require.NoError(t, err)
+	cfg := src.(*nomadSource).cfg
+	assert.Equal(t, "https://nomad.local:4646", src.(*nomadSource).baseURL)
+	assert.Equal(t, "from-env", cfg.Token)
+	assert.Equal(t, []string{"apps"}, cfg.Namespaces)
+
+	t.Setenv("NOMAD_ADDR", "")
+	t.Setenv("NOMAD_NAMESPACE", "")
+	src, err = NewNomadSource(NomadConfig{}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:4646", src.(*nomadSource).baseURL)
+	assert.Equal(t, []string{"default"}, src.(*nomadSource).cfg.Namespaces)
+
+	_, err = NewNomadSource(NomadConfig{Address: "unix:///var/run/nomad.sock"}, 0)
+	assert.Error(t, err)
+}
+
+func TestNomadSourceAddEventHandler(t *testing.T) {
+	var index atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/services", r.URL.Path)
+		assert.Equal(t, "5m0s", r.URL.Query().Get("wait"))
+		// The index changes on every other query.
+		w.Header().Set("X-Nomad-Index", strconv.FormatInt(index.Add(1)/2, 10))
+		w.Write([]byte(testNomadServices))
+	}))
+	defer srv.Close()
+
+	src, err := NewNomadSource(NomadConfig{Address: srv.URL}, time.Minute)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var events atomic.Int64
+	src.AddEventHandler(ctx, func() { events.Add(1) })
+
+	assert.Eventually(t, func() bool { return events.Load() >= 3 }, 5*time.Second, 10*time.Millisecond)
+}
@@ -51,6 +51,8 @@ type ocpRouteSource struct {
 	routeInformer            routeInformer.RouteInformer
 	labelSelector            labels.Selector
 	ocpRouterName            string
+
+	*informerHealth
 }
 
 // NewOcpRouteSource creates a new ocpRouteSource with the given config.
@@ -83,6 +85,10 @@ func NewOcpRouteSource(
 		},
 	)
 
+	health := trackInformers(map[string]cache.SharedInformer{
+		"routes": informer.Informer(),
+	})
+
 	informerFactory.Start(ctx.Done())
 
 	// wait for the local cache to be populated.
@@ -100,6 +106,7 @@ func NewOcpRouteSource(
 		routeInformer:            informer,
 		labelSelector:            labelSelector,
 		ocpRouterName:            ocpRouterName,
+		informerHealth:           health,
 	}, nil
 }
 
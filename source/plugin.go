@@ -0,0 +1,252 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/pkg/logging"
+)
+
+const (
+	pluginWatchMinBackoff = time.Second
+	pluginWatchMaxBackoff = 5 * time.Minute
+)
+
+// pluginSource is an implementation of Source that gets the endpoints from an
+// external plugin, so bespoke inventory systems can be added without changing
+// the binary. A plugin is either an executable or a localhost HTTP server:
+//
+//   - 'COMMAND endpoints' or 'GET URL/endpoints' returns the endpoints, as a JSON
+//     list or an object with an "endpoints" list - the DNSEndpoint format.
+//   - 'COMMAND watch' or 'GET URL/watch' is optional, and writes a line each time
+//     the endpoints change, until the plugin exits or the connection is closed.
+type pluginSource struct {
+	name    string
+	command []string
+	url     string
+	client  *http.Client
+	timeout time.Duration
+	log     *slog.Logger
+}
+
+// NewPluginSource returns a source for the plugin. spec is an http:// or https://
+// URL, or a command with arguments separated by spaces.
+func NewPluginSource(name, spec string, timeout time.Duration) (Source, error) {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ps := &pluginSource{
+		name:    name,
+		timeout: timeout,
+		client:  &http.Client{},
+		log:     logging.For("source/plugin").With("plugin", name),
+	}
+	if strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://") {
+		ps.url = strings.TrimSuffix(spec, "/")
+	} else {
+		ps.command = strings.Fields(spec)
+	}
+	if ps.url == "" && len(ps.command) == 0 {
+		return nil, fmt.Errorf("plugin %s: a command or URL is required", name)
+	}
+	return ps, nil
+}
+
+// NewPluginSources returns a source combining the plugins, configured as
+// NAME=COMMAND or NAME=URL.
+func NewPluginSources(specs []string, timeout time.Duration) (Source, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("the plugin source requires --plugin")
+	}
+	var sources []Source
+	for _, spec := range specs {
+		name, s, ok := strings.Cut(spec, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid plugin %q, expected NAME=COMMAND or NAME=URL", spec)
+		}
+		ps, err := NewPluginSource(name, s, timeout)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, ps)
+	}
+	return NewMultiSource(sources, nil), nil
+}
+
+// Endpoints returns the endpoints of the plugin.
+func (ps *pluginSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.timeout)
+	defer cancel()
+
+	var data []byte
+	var err error
+	if ps.url != "" {
+		data, err = ps.get(ctx)
+	} else {
+		data, err = ps.run(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", ps.name, err)
+	}
+	endpoints, err := parsePluginEndpoints(data)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", ps.name, err)
+	}
+	for _, ep := range endpoints {
+		if ep.Labels == nil {
+			ep.Labels = endpoint.NewLabels()
+		}
+		if ep.Labels[endpoint.ResourceLabelKey] == "" {
+			ep.Labels[endpoint.ResourceLabelKey] = "plugin/" + ps.name
+		}
+	}
+	ps.log.Debug("Received endpoints", "count", len(endpoints))
+	return endpoints, nil
+}
+
+func (ps *pluginSource) get(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ps.url+"/endpoints", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := ps.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s/endpoints: %s: %s", ps.url, resp.Status, bytes.TrimSpace(data))
+	}
+	return data, nil
+}
+
+func (ps *pluginSource) run(ctx context.Context) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ps.command[0], append(ps.command[1:], "endpoints")...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s endpoints: %w: %s", ps.command[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return out, nil
+}
+
+func parsePluginEndpoints(data []byte) ([]*endpoint.Endpoint, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var endpoints []*endpoint.Endpoint
+		if err := json.Unmarshal(data, &endpoints); err != nil {
+			return nil, fmt.Errorf("parsing endpoints: %w", err)
+		}
+		return endpoints, nil
+	}
+	var spec endpoint.DNSEndpointSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parsing endpoints: %w", err)
+	}
+	return spec.Endpoints, nil
+}
+
+// AddEventHandler calls the handler for each line written by the watch of the
+// plugin. The watch is restarted with a backoff when it ends.
+func (ps *pluginSource) AddEventHandler(ctx context.Context, handler func()) {
+	go func() {
+		backoff := pluginWatchMinBackoff
+		for {
+			start := time.Now()
+			err := ps.watch(ctx, handler)
+			if ctx.Err() != nil {
+				return
+			}
+			if time.Since(start) > pluginWatchMaxBackoff {
+				backoff = pluginWatchMinBackoff
+			}
+			ps.log.Warn("Plugin watch ended", "error", err, "retry", backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, pluginWatchMaxBackoff)
+		}
+	}()
+}
+
+// watch runs a watch until it ends, calling the handler for each line.
+func (ps *pluginSource) watch(ctx context.Context, handler func()) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var r io.Reader
+	var wait func() error
+	if ps.url != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ps.url+"/watch", nil)
+		if err != nil {
+			return err
+		}
+		resp, err := ps.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("GET %s/watch: %s", ps.url, resp.Status)
+		}
+		r = resp.Body
+		wait = func() error { return nil }
+	} else {
+		cmd := exec.CommandContext(ctx, ps.command[0], append(ps.command[1:], "watch")...)
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		r = out
+		wait = cmd.Wait
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		handler()
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if err := wait(); err != nil {
+		return err
+	}
+	return io.EOF
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const pluginScript = `#!/bin/sh
+case "$2" in
+endpoints) echo '[{"dnsName": "'$1'.example.com", "recordType": "A", "targets": ["1.2.3.4"]}]' ;;
+watch) echo changed; echo changed ;;
+*) echo "unknown command $2" >&2; exit 1 ;;
+esac
+`
+
+func writePlugin(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "plugin.sh")
+	require.NoError(t, os.WriteFile(path, []byte(pluginScript), 0o755))
+	return path
+}
+
+func TestPluginSourceExec(t *testing.T) {
+	path := writePlugin(t)
+	src, err := NewPluginSource("inventory", path+" a", time.Minute)
+	require.NoError(t, err)
+
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, "a.example.com", endpoints[0].DNSName)
+	assert.Equal(t, endpoint.Targets{"1.2.3.4"}, endpoints[0].Targets)
+	assert.Equal(t, "plugin/inventory", endpoints[0].Labels[endpoint.ResourceLabelKey])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var events atomic.Int32
+	src.AddEventHandler(ctx, func() { events.Add(1) })
+	assert.Eventually(t, func() bool { return events.Load() >= 2 }, 5*time.Second, 10*time.Millisecond)
+
+	src, err = NewPluginSource("broken", "/bin/false", time.Minute)
+	require.NoError(t, err)
+	_, err = src.Endpoints(context.Background())
+	assert.ErrorContains(t, err, "plugin broken")
+}
+
+func TestPluginSourceHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/endpoints":
+			fmt.Fprint(w, `{"endpoints": [{"dnsName": "b.example.com", "recordType": "CNAME", "targets": ["a.example.com"],
+				"labels": {"resource": "cmdb/host-1"}}]}`)
+		case "/watch":
+			fmt.Fprintln(w, "changed")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	src, err := NewPluginSource("cmdb", srv.URL+"/", time.Minute)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, "b.example.com", endpoints[0].DNSName)
+	assert.Equal(t, "cmdb/host-1", endpoints[0].Labels[endpoint.ResourceLabelKey])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var events atomic.Int32
+	src.AddEventHandler(ctx, func() { events.Add(1) })
+	assert.Eventually(t, func() bool { return events.Load() >= 1 }, 5*time.Second, 10*time.Millisecond)
+
+	src, err = NewPluginSource("missing", srv.URL+"/missing", time.Minute)
+	require.NoError(t, err)
+	_, err = src.Endpoints(context.Background())
+	assert.ErrorContains(t, err, "404")
+}
+
+func TestNewPluginSources(t *testing.T) {
+	path := writePlugin(t)
+	src, err := NewPluginSources([]string{"a=" + path + " a", "b=" + path + " b"}, time.Minute)
+	require.NoError(t, err)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, endpoints, 2)
+
+	_, err = NewPluginSources(nil, time.Minute)
+	assert.Error(t, err)
+	_, err = NewPluginSources([]string{path}, time.Minute)
+	assert.Error(t, err)
+	_, err = NewPluginSources([]string{"empty="}, time.Minute)
+	assert.Error(t, err)
+}
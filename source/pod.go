@@ -18,11 +18,14 @@ package source
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"sigs.k8s.io/external-dns/endpoint"
 
 	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	kubeinformers "k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
@@ -36,6 +39,8 @@ type podSource struct {
 	podInformer   coreinformers.PodInformer
 	nodeInformer  coreinformers.NodeInformer
 	compatibility string
+
+	*informerHealth
 }
 
 // NewPodSource creates a new podSource with the given config.
@@ -57,6 +62,11 @@ func NewPodSource(ctx context.Context, kubeClient kubernetes.Interface, namespac
 		},
 	)
 
+	health := trackInformers(map[string]cache.SharedInformer{
+		"pods":  podInformer.Informer(),
+		"nodes": nodeInformer.Informer(),
+	})
+
 	informerFactory.Start(ctx.Done())
 
 	// wait for the local cache to be populated.
@@ -65,11 +75,12 @@ func NewPodSource(ctx context.Context, kubeClient kubernetes.Interface, namespac
 	}
 
 	return &podSource{
-		client:        kubeClient,
-		podInformer:   podInformer,
-		nodeInformer:  nodeInformer,
-		namespace:     namespace,
-		compatibility: compatibility,
+		client:         kubeClient,
+		podInformer:    podInformer,
+		nodeInformer:   nodeInformer,
+		namespace:      namespace,
+		compatibility:  compatibility,
+		informerHealth: health,
 	}, nil
 }
 
@@ -83,22 +94,36 @@ func (ps *podSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error
 	}
 
 	endpointMap := make(map[endpoint.EndpointKey][]string)
+	// The resource of a name is the controller of its pods, like their
+	// ReplicaSet or DaemonSet, if they all have the same one.
+	resources := make(map[endpoint.EndpointKey]string)
 	for _, pod := range pods {
 		if !pod.Spec.HostNetwork {
 			log.Debugf("skipping pod %s. hostNetwork=false", pod.Name)
 			continue
 		}
 
+		owner := podOwner(pod)
+		add := func(domain string, recordType string, address string) {
+			key := endpoint.EndpointKey{DNSName: domain, RecordType: recordType}
+			if resource, ok := resources[key]; ok && resource != owner {
+				resources[key] = ""
+			} else if !ok {
+				resources[key] = owner
+			}
+			addToEndpointMap(endpointMap, domain, recordType, address)
+		}
+
 		targets := getTargetsFromTargetAnnotation(pod.Annotations)
 
 		if domainAnnotation, ok := pod.Annotations[internalHostnameAnnotationKey]; ok {
 			domainList := splitHostnameAnnotation(domainAnnotation)
 			for _, domain := range domainList {
 				if len(targets) == 0 {
-					addToEndpointMap(endpointMap, domain, suitableType(pod.Status.PodIP), pod.Status.PodIP)
+					add(domain, suitableType(pod.Status.PodIP), pod.Status.PodIP)
 				} else {
 					for _, target := range targets {
-						addToEndpointMap(endpointMap, domain, suitableType(target), target)
+						add(domain, suitableType(target), target)
 					}
 				}
 			}
@@ -113,12 +138,12 @@ func (ps *podSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error
 						recordType := suitableType(address.Address)
 						// IPv6 addresses are labeled as NodeInternalIP despite being usable externally as well.
 						if address.Type == corev1.NodeExternalIP || (address.Type == corev1.NodeInternalIP && recordType == endpoint.RecordTypeAAAA) {
-							addToEndpointMap(endpointMap, domain, recordType, address.Address)
+							add(domain, recordType, address.Address)
 						}
 					}
 				} else {
 					for _, target := range targets {
-						addToEndpointMap(endpointMap, domain, suitableType(target), target)
+						add(domain, suitableType(target), target)
 					}
 				}
 			}
@@ -128,7 +153,7 @@ func (ps *podSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error
 			if domainAnnotation, ok := pod.Annotations[kopsDNSControllerInternalHostnameAnnotationKey]; ok {
 				domainList := splitHostnameAnnotation(domainAnnotation)
 				for _, domain := range domainList {
-					addToEndpointMap(endpointMap, domain, suitableType(pod.Status.PodIP), pod.Status.PodIP)
+					add(domain, suitableType(pod.Status.PodIP), pod.Status.PodIP)
 				}
 			}
 
@@ -140,7 +165,7 @@ func (ps *podSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error
 						recordType := suitableType(address.Address)
 						// IPv6 addresses are labeled as NodeInternalIP despite being usable externally as well.
 						if address.Type == corev1.NodeExternalIP || (address.Type == corev1.NodeInternalIP && recordType == endpoint.RecordTypeAAAA) {
-							addToEndpointMap(endpointMap, domain, recordType, address.Address)
+							add(domain, recordType, address.Address)
 						}
 					}
 				}
@@ -149,11 +174,25 @@ func (ps *podSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error
 	}
 	endpoints := []*endpoint.Endpoint{}
 	for key, targets := range endpointMap {
-		endpoints = append(endpoints, endpoint.NewEndpoint(key.DNSName, key.RecordType, targets...))
+		ep := endpoint.NewEndpoint(key.DNSName, key.RecordType, targets...)
+		if resource := resources[key]; resource != "" {
+			ep.Labels[endpoint.ResourceLabelKey] = resource
+		}
+		endpoints = append(endpoints, ep)
 	}
 	return endpoints, nil
 }
 
+// podOwner returns the resource label of the controller of the pod,
+// kind/namespace/name, or "" without a controller.
+func podOwner(pod *corev1.Pod) string {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/%s", strings.ToLower(ref.Kind), pod.Namespace, ref.Name)
+}
+
 func addToEndpointMap(endpointMap map[endpoint.EndpointKey][]string, domain string, recordType string, address string) {
 	key := endpoint.EndpointKey{
 		DNSName:    domain,
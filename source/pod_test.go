@@ -678,3 +678,51 @@ func TestPodSource(t *testing.T) {
 
 	}
 }
+
+func TestPodSourceResource(t *testing.T) {
+	kubernetes := fake.NewSimpleClientset()
+	ctx := context.Background()
+	controller := true
+	pod := func(name, owner, ip string) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "kube-system",
+				Annotations: map[string]string{
+					internalHostnameAnnotationKey: owner + ".example.org",
+				},
+			},
+			Spec:   corev1.PodSpec{HostNetwork: true},
+			Status: corev1.PodStatus{PodIP: ip},
+		}
+		if owner != "" {
+			p.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: owner, Controller: &controller}}
+		}
+		return p
+	}
+	for _, p := range []*corev1.Pod{
+		pod("a-1", "a", "10.0.1.1"),
+		pod("a-2", "a", "10.0.1.2"),
+		pod("b-1", "b", "10.0.1.3"),
+		pod("c-1", "c", "10.0.1.4"),
+	} {
+		_, err := kubernetes.CoreV1().Pods(p.Namespace).Create(ctx, p, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+	// Another DaemonSet with the same name.
+	other := pod("c-2", "d", "10.0.1.5")
+	other.Annotations[internalHostnameAnnotationKey] = "c.example.org"
+	_, err := kubernetes.CoreV1().Pods(other.Namespace).Create(ctx, other, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	client, err := NewPodSource(ctx, kubernetes, "", "")
+	require.NoError(t, err)
+	endpoints, err := client.Endpoints(ctx)
+	require.NoError(t, err)
+
+	validateEndpoints(t, endpoints, []*endpoint.Endpoint{
+		{DNSName: "a.example.org", Targets: endpoint.Targets{"10.0.1.1", "10.0.1.2"}, RecordType: endpoint.RecordTypeA, Labels: endpoint.Labels{endpoint.ResourceLabelKey: "daemonset/kube-system/a"}},
+		{DNSName: "b.example.org", Targets: endpoint.Targets{"10.0.1.3"}, RecordType: endpoint.RecordTypeA, Labels: endpoint.Labels{endpoint.ResourceLabelKey: "daemonset/kube-system/b"}},
+		{DNSName: "c.example.org", Targets: endpoint.Targets{"10.0.1.4", "10.0.1.5"}, RecordType: endpoint.RecordTypeA},
+	})
+}
@@ -61,6 +61,8 @@ type serviceSource struct {
 	nodeInformer                   coreinformers.NodeInformer
 	serviceTypeFilter              map[string]struct{}
 	labelSelector                  labels.Selector
+
+	*informerHealth
 }
 
 // NewServiceSource creates a new serviceSource with the given config.
@@ -104,6 +106,13 @@ func NewServiceSource(ctx context.Context, kubeClient kubernetes.Interface, name
 		},
 	)
 
+	health := trackInformers(map[string]cache.SharedInformer{
+		"services":  serviceInformer.Informer(),
+		"endpoints": endpointsInformer.Informer(),
+		"pods":      podInformer.Informer(),
+		"nodes":     nodeInformer.Informer(),
+	})
+
 	informerFactory.Start(ctx.Done())
 
 	// wait for the local cache to be populated.
@@ -136,6 +145,7 @@ func NewServiceSource(ctx context.Context, kubeClient kubernetes.Interface, name
 		serviceTypeFilter:              serviceTypes,
 		labelSelector:                  labelSelector,
 		resolveLoadBalancerHostname:    resolveLoadBalancerHostname,
+		informerHealth:                 health,
 	}, nil
 }
 
@@ -195,6 +205,8 @@ func (sc *serviceSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, e
 			continue
 		}
 
+		svcEndpoints = endpointsForViews(svcEndpoints, svc.Annotations)
+
 		log.Debugf("Endpoints generated from service: %s/%s: %v", svc.Namespace, svc.Name, svcEndpoints)
 		sc.setResourceLabel(svc, svcEndpoints)
 		endpoints = append(endpoints, svcEndpoints...)
@@ -495,9 +507,13 @@ func (sc *serviceSource) generateEndpoints(svc *v1.Service, hostname string, pro
 			targets = extractServiceExternalName(svc)
 		}
 
-		for _, endpoint := range endpoints {
-			endpoint.ProviderSpecific = providerSpecific
-			endpoint.SetIdentifier = setIdentifier
+		srvProviderSpecific := getSRVProviderSpecific(svc.Annotations, providerSpecific)
+		for _, ep := range endpoints {
+			ep.ProviderSpecific = providerSpecific
+			if ep.RecordType == endpoint.RecordTypeSRV {
+				ep.ProviderSpecific = srvProviderSpecific
+			}
+			ep.SetIdentifier = setIdentifier
 		}
 	}
 
@@ -711,6 +727,25 @@ func (sc *serviceSource) extractNodePortEndpoints(svc *v1.Service, hostname stri
 	return endpoints
 }
 
+// getSRVProviderSpecific returns the provider specific properties of the SRV
+// endpoints of a service: the ones of the service, followed by the priority and
+// the weight annotations replacing those of the target when rendered.
+func getSRVProviderSpecific(annotations map[string]string, providerSpecific endpoint.ProviderSpecific) endpoint.ProviderSpecific {
+	priority, hasPriority := annotations[srvPriorityAnnotationKey]
+	weight, hasWeight := annotations[srvWeightAnnotationKey]
+	if !hasPriority && !hasWeight {
+		return providerSpecific
+	}
+	srvProviderSpecific := append(endpoint.ProviderSpecific{}, providerSpecific...)
+	if hasPriority {
+		srvProviderSpecific = append(srvProviderSpecific, endpoint.ProviderSpecificProperty{Name: endpoint.ProviderSpecificPriority, Value: priority})
+	}
+	if hasWeight {
+		srvProviderSpecific = append(srvProviderSpecific, endpoint.ProviderSpecificProperty{Name: endpoint.ProviderSpecificWeight, Value: weight})
+	}
+	return srvProviderSpecific
+}
+
 func (sc *serviceSource) AddEventHandler(ctx context.Context, handler func()) {
 	log.Debug("Adding event handler for service")
 
@@ -1706,6 +1706,36 @@ func TestServiceSourceNodePortServices(t *testing.T) {
 				},
 			}},
 		},
+		{
+			title:            "NodePort services with an SRV priority and weight annotation return an SRV endpoint with them",
+			svcNamespace:     "testing",
+			svcName:          "foo",
+			svcType:          v1.ServiceTypeNodePort,
+			svcTrafficPolicy: v1.ServiceExternalTrafficPolicyTypeCluster,
+			annotations: map[string]string{
+				hostnameAnnotationKey:    "foo.example.org.",
+				srvPriorityAnnotationKey: "10",
+				srvWeightAnnotationKey:   "20",
+			},
+			expected: []*endpoint.Endpoint{
+				{DNSName: "_foo._tcp.foo.example.org", Targets: endpoint.Targets{"0 50 30192 foo.example.org"}, RecordType: endpoint.RecordTypeSRV, ProviderSpecific: endpoint.ProviderSpecific{
+					{Name: endpoint.ProviderSpecificPriority, Value: "10"},
+					{Name: endpoint.ProviderSpecificWeight, Value: "20"},
+				}},
+				{DNSName: "foo.example.org", Targets: endpoint.Targets{"54.10.11.1"}, RecordType: endpoint.RecordTypeA},
+			},
+			nodes: []*v1.Node{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "node1",
+				},
+				Status: v1.NodeStatus{
+					Addresses: []v1.NodeAddress{
+						{Type: v1.NodeExternalIP, Address: "54.10.11.1"},
+						{Type: v1.NodeInternalIP, Address: "10.0.1.1"},
+					},
+				},
+			}},
+		},
 		{
 			title:                    "hostname annotated NodePort services are ignored",
 			svcNamespace:             "testing",
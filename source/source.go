@@ -60,6 +60,11 @@ const (
 	controllerAnnotationValue = "dns-controller"
 	// The annotation used for defining the desired hostname
 	internalHostnameAnnotationKey = "external-dns.alpha.kubernetes.io/internal-hostname"
+	// The annotations used for defining the priority and the weight of the SRV records of a service
+	srvPriorityAnnotationKey = "external-dns.alpha.kubernetes.io/srv-priority"
+	srvWeightAnnotationKey   = "external-dns.alpha.kubernetes.io/srv-weight"
+	// The annotation used for selecting the zone of the records: the view, public or private, or the name of a zone
+	zoneAnnotationKey = "external-dns.alpha.kubernetes.io/zone"
 )
 
 const (
@@ -212,6 +217,12 @@ func getProviderSpecificAnnotations(annotations map[string]string) (endpoint.Pro
 				Name:  fmt.Sprintf("aws/%s", attr),
 				Value: v,
 			})
+		} else if strings.HasPrefix(k, "external-dns.alpha.kubernetes.io/google-") {
+			attr := strings.TrimPrefix(k, "external-dns.alpha.kubernetes.io/google-")
+			providerSpecificAnnotations = append(providerSpecificAnnotations, endpoint.ProviderSpecificProperty{
+				Name:  fmt.Sprintf("google/%s", attr),
+				Value: v,
+			})
 		} else if strings.HasPrefix(k, "external-dns.alpha.kubernetes.io/scw-") {
 			attr := strings.TrimPrefix(k, "external-dns.alpha.kubernetes.io/scw-")
 			providerSpecificAnnotations = append(providerSpecificAnnotations, endpoint.ProviderSpecificProperty{
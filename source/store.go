@@ -35,6 +35,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/external-dns/endpoint"
 	gateway "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
 )
 
@@ -59,6 +60,43 @@ type Config struct {
 	PublishHostIP                  bool
 	AlwaysPublishNotReadyAddresses bool
 	ConnectorServer                string
+	AXFRServer                     string
+	AXFRZones                      []string
+	AXFRTSIGKey                    string
+	AXFRTSIGSecret                 string `secure:"yes"`
+	AXFRIncremental                bool
+	PluginSources                  []string
+	FileSources                    []string
+	FileSourceTokenFile            string
+	FileSourceCAFile               string
+	DockerHost                     string
+	DockerTargets                  []string
+	ConsulAddress                  string
+	ConsulToken                    string `secure:"yes"`
+	ConsulDatacenter               string
+	ConsulSourceTags               []string
+	ConsulSourceDomain             string
+	NomadAddress                   string
+	NomadToken                     string `secure:"yes"`
+	NomadRegion                    string
+	NomadNamespaces                []string
+	NomadTags                      []string
+	NomadDomain                    string
+	IstiodAddress                  string
+	IstiodTokenFile                string
+	IstiodDomain                   string
+	GCEProject                     string
+	GCESuffix                      string
+	GCEAddress                     string
+	GCELabelFilter                 string
+	ForwardingRuleSuffix           string
+	ForwardingRuleLabelFilter      string
+	ForwardingRuleDescription      string
+	MCSAPIGroup                    string
+	MCSDomain                      string
+	IstioSEReverseNamespace        string
+	IstioSEReverseDomainFilter     []string
+	IstioSEReverseLabelFilter      string
 	CRDSourceAPIVersion            string
 	CRDSourceKind                  string
 	KubeConfig                     string
@@ -66,7 +104,7 @@ type Config struct {
 	ServiceTypeFilter              []string
 	CFAPIEndpoint                  string
 	CFUsername                     string
-	CFPassword                     string
+	CFPassword                     string `secure:"yes"`
 	GlooNamespaces                 []string
 	SkipperRouteGroupVersion       string
 	RequestTimeout                 time.Duration
@@ -76,6 +114,13 @@ type Config struct {
 	ResolveLoadBalancerHostname    bool
 	TraefikDisableLegacy           bool
 	TraefikDisableNew              bool
+	// GeoRouting sets the region of the endpoints of the k8s and istio-se
+	// sources as their geo location, GeoRegion or the region of the nodes.
+	GeoRouting bool
+	GeoRegion  string
+	// K8SNodeHints adds the TXT records of the nodes of the pods to the k8s
+	// source.
+	K8SNodeHints bool
 }
 
 // ClientGenerator provides clients
@@ -258,6 +303,16 @@ func BuildWithConfig(ctx context.Context, source string, p ClientGenerator, cfg
 		if err != nil {
 			return nil, err
 		}
+		reverseLabelFilter, err := labels.Parse(cfg.IstioSEReverseLabelFilter)
+		if err != nil {
+			return nil, err
+		}
+		geoRegion := cfg.GeoRegion
+		if cfg.GeoRouting && geoRegion == "" {
+			if geoRegion, err = ClusterRegion(ctx, kubernetesClient); err != nil {
+				return nil, err
+			}
+		}
 		return NewIstioServiceEntrySourceConfig(ctx, kubernetesClient, istioClient,
 			ServiceEntrySourceConfig{
 				MeshExternalNamespace: "",
@@ -265,6 +320,11 @@ func BuildWithConfig(ctx context.Context, source string, p ClientGenerator, cfg
 				EgressGatewayVIP:      nil,
 				HttpVIP:               "",
 				UpdateServiceEntry:    false,
+				ReverseNamespace:      cfg.IstioSEReverseNamespace,
+				ReverseDomainFilter:   endpoint.NewDomainFilter(cfg.IstioSEReverseDomainFilter),
+				ReverseLabelFilter:    reverseLabelFilter,
+				GeoRouting:            cfg.GeoRouting,
+				GeoRegion:             geoRegion,
 			})
 	case "istio-gateway":
 		kubernetesClient, err := p.KubeClient()
@@ -318,6 +378,26 @@ func BuildWithConfig(ctx context.Context, source string, p ClientGenerator, cfg
 			return nil, err
 		}
 		return NewGlooSource(dynamicClient, kubernetesClient, cfg.GlooNamespaces)
+	case "knative":
+		kubernetesClient, err := p.KubeClient()
+		if err != nil {
+			return nil, err
+		}
+		dynamicClient, err := p.DynamicKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewKnativeSource(ctx, dynamicClient, kubernetesClient, cfg.Namespace, cfg.AnnotationFilter, cfg.IgnoreHostnameAnnotation)
+	case "mcs":
+		kubernetesClient, err := p.KubeClient()
+		if err != nil {
+			return nil, err
+		}
+		dynamicClient, err := p.DynamicKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+		return NewMCSSource(ctx, dynamicClient, kubernetesClient, cfg.MCSAPIGroup, cfg.Namespace, cfg.AnnotationFilter, cfg.MCSDomain, cfg.IgnoreHostnameAnnotation)
 	case "traefik-proxy":
 		kubernetesClient, err := p.KubeClient()
 		if err != nil {
@@ -338,6 +418,56 @@ func BuildWithConfig(ctx context.Context, source string, p ClientGenerator, cfg
 		return NewFakeSource(cfg.FQDNTemplate)
 	case "connector":
 		return NewConnectorSource(cfg.ConnectorServer)
+	case "axfr":
+		return NewAXFRSource(cfg.AXFRServer, cfg.AXFRZones, cfg.AXFRTSIGKey, cfg.AXFRTSIGSecret, cfg.AXFRIncremental)
+	case "plugin":
+		return NewPluginSources(cfg.PluginSources, cfg.RequestTimeout)
+	case "file":
+		return NewFileSource(FileConfig{
+			Locations: cfg.FileSources,
+			TokenFile: cfg.FileSourceTokenFile,
+			CAFile:    cfg.FileSourceCAFile,
+		}, cfg.RequestTimeout)
+	case "docker":
+		return NewDockerSource(cfg.DockerHost, cfg.DockerTargets, cfg.RequestTimeout)
+	case "consul":
+		return NewConsulSource(ConsulConfig{
+			Address:    cfg.ConsulAddress,
+			Token:      cfg.ConsulToken,
+			Datacenter: cfg.ConsulDatacenter,
+			Tags:       cfg.ConsulSourceTags,
+			Domain:     cfg.ConsulSourceDomain,
+		}, cfg.RequestTimeout)
+	case "nomad":
+		return NewNomadSource(NomadConfig{
+			Address:    cfg.NomadAddress,
+			Token:      cfg.NomadToken,
+			Region:     cfg.NomadRegion,
+			Namespaces: cfg.NomadNamespaces,
+			Tags:       cfg.NomadTags,
+			Domain:     cfg.NomadDomain,
+		}, cfg.RequestTimeout)
+	case "istiod":
+		return NewIstiodSource(IstiodConfig{
+			Address:   cfg.IstiodAddress,
+			TokenFile: cfg.IstiodTokenFile,
+			Namespace: cfg.Namespace,
+			Domain:    cfg.IstiodDomain,
+		}, cfg.RequestTimeout)
+	case "gce":
+		return NewGCESource(ctx, GCEConfig{
+			Project:     cfg.GCEProject,
+			Suffix:      cfg.GCESuffix,
+			Address:     cfg.GCEAddress,
+			LabelFilter: cfg.GCELabelFilter,
+		})
+	case "gce-forwarding-rule":
+		return NewForwardingRuleSource(ctx, ForwardingRuleConfig{
+			Project:           cfg.GCEProject,
+			Suffix:            cfg.ForwardingRuleSuffix,
+			LabelFilter:       cfg.ForwardingRuleLabelFilter,
+			DescriptionFilter: cfg.ForwardingRuleDescription,
+		})
 	case "crd":
 		client, err := p.KubeClient()
 		if err != nil {
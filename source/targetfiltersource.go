@@ -18,6 +18,7 @@ package source
 
 import (
 	"context"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -71,3 +72,13 @@ func (ms *targetFilterSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoi
 func (ms *targetFilterSource) AddEventHandler(ctx context.Context, handler func()) {
 	ms.source.AddEventHandler(ctx, handler)
 }
+
+// Healthy returns the health of the wrapped source.
+func (ms *targetFilterSource) Healthy() error {
+	return Healthy(ms.source)
+}
+
+// LastEvent returns the time of the last event of the wrapped source.
+func (ms *targetFilterSource) LastEvent() time.Time {
+	return LastEvent(ms.source)
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// GeoLocationProperty is the provider-specific property with the region of
+// an endpoint in the geo routing policy of the Google provider.
+const GeoLocationProperty = "google/geo-location"
+
+// nodesRegion returns the most common region of the nodes, from their
+// topology.kubernetes.io/region label - a cluster normally has one.
+func nodesRegion(nodes []*corev1.Node) string {
+	count := map[string]int{}
+	region := ""
+	for _, node := range nodes {
+		r := node.Labels[corev1.LabelTopologyRegion]
+		if r == "" {
+			continue
+		}
+		count[r]++
+		if region == "" || count[r] > count[region] || count[r] == count[region] && r < region {
+			region = r
+		}
+	}
+	return region
+}
+
+// ClusterRegion returns the region of the nodes of the cluster, empty if they
+// have no region label.
+func ClusterRegion(ctx context.Context, client kubernetes.Interface) (string, error) {
+	list, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+	nodes := make([]*corev1.Node, 0, len(list.Items))
+	for i := range list.Items {
+		nodes = append(nodes, &list.Items[i])
+	}
+	return nodesRegion(nodes), nil
+}
+
+// localitiesRegion returns the region of the Istio localities, like
+// us-central1/us-central1-a, if they all have the same one.
+func localitiesRegion(localities []string) string {
+	region := ""
+	for _, locality := range localities {
+		r, _, _ := strings.Cut(locality, "/")
+		if r == "" || region != "" && r != region {
+			return ""
+		}
+		region = r
+	}
+	return region
+}
+
+// setGeoLocation sets the region of the endpoints without a set identifier
+// nor a geo location, as their set identifier and geo location.
+func setGeoLocation(endpoints []*endpoint.Endpoint, region string) {
+	if region == "" {
+		return
+	}
+	for _, ep := range endpoints {
+		if _, ok := ep.GetProviderSpecificProperty(GeoLocationProperty); ok || ep.SetIdentifier != "" {
+			continue
+		}
+		ep.SetIdentifier = region
+		ep.SetProviderSpecificProperty(GeoLocationProperty, region)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func regionNode(name, region string) *corev1.Node {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+	if region != "" {
+		node.Labels[corev1.LabelTopologyRegion] = region
+	}
+	return node
+}
+
+func TestClusterRegion(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		regionNode("a", "us-central1"),
+		regionNode("b", "europe-west1"),
+		regionNode("c", "us-central1"),
+		regionNode("d", ""),
+	)
+	region, err := ClusterRegion(context.Background(), client)
+	require.NoError(t, err)
+	assert.Equal(t, "us-central1", region)
+
+	assert.Equal(t, "", nodesRegion([]*corev1.Node{regionNode("a", "")}))
+	assert.Equal(t, "europe-west1", nodesRegion([]*corev1.Node{regionNode("a", "us-central1"), regionNode("b", "europe-west1")}))
+}
+
+func TestLocalitiesRegion(t *testing.T) {
+	assert.Equal(t, "us-central1", localitiesRegion([]string{"us-central1/us-central1-a", "us-central1/us-central1-b", "us-central1"}))
+	assert.Equal(t, "", localitiesRegion([]string{"us-central1/us-central1-a", "europe-west1/europe-west1-b"}))
+	assert.Equal(t, "", localitiesRegion([]string{"us-central1/us-central1-a", ""}))
+	assert.Equal(t, "", localitiesRegion(nil))
+}
+
+func TestSetGeoLocation(t *testing.T) {
+	endpoints := []*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "10.0.0.1"),
+		endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "10.0.0.2").WithSetIdentifier("blue"),
+		endpoint.NewEndpoint("c.example.org", endpoint.RecordTypeA, "10.0.0.3").WithProviderSpecific(GeoLocationProperty, "asia-east1"),
+	}
+	setGeoLocation(endpoints, "us-central1")
+	assert.Equal(t, []*endpoint.Endpoint{
+		endpoint.NewEndpoint("a.example.org", endpoint.RecordTypeA, "10.0.0.1").WithSetIdentifier("us-central1").WithProviderSpecific(GeoLocationProperty, "us-central1"),
+		endpoint.NewEndpoint("b.example.org", endpoint.RecordTypeA, "10.0.0.2").WithSetIdentifier("blue"),
+		endpoint.NewEndpoint("c.example.org", endpoint.RecordTypeA, "10.0.0.3").WithProviderSpecific(GeoLocationProperty, "asia-east1"),
+	}, endpoints)
+
+	props, _ := getProviderSpecificAnnotations(map[string]string{"external-dns.alpha.kubernetes.io/google-geo-location": "europe-west1"})
+	assert.Equal(t, endpoint.ProviderSpecific{{Name: GeoLocationProperty, Value: "europe-west1"}}, props)
+}
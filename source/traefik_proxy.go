@@ -91,6 +91,8 @@ type traefikSource struct {
 	kubeClient                 kubernetes.Interface
 	namespace                  string
 	unstructuredConverter      *unstructuredConverter
+
+	*informerHealth
 }
 
 func NewTraefikSource(ctx context.Context, dynamicKubeClient dynamic.Interface, kubeClient kubernetes.Interface, namespace string, annotationFilter string, ignoreHostnameAnnotation bool, disableLegacy bool, disableNew bool) (Source, error) {
@@ -142,6 +144,19 @@ func NewTraefikSource(ctx context.Context, dynamicKubeClient dynamic.Interface,
 		)
 	}
 
+	tracked := map[string]cache.SharedInformer{}
+	if !disableNew {
+		tracked["ingressroutes"] = ingressRouteInformer.Informer()
+		tracked["ingressroutetcps"] = ingressRouteTcpInformer.Informer()
+		tracked["ingressrouteudps"] = ingressRouteUdpInformer.Informer()
+	}
+	if !disableLegacy {
+		tracked["ingressroutes.traefik.containo.us"] = oldIngressRouteInformer.Informer()
+		tracked["ingressroutetcps.traefik.containo.us"] = oldIngressRouteTcpInformer.Informer()
+		tracked["ingressrouteudps.traefik.containo.us"] = oldIngressRouteUdpInformer.Informer()
+	}
+	health := trackInformers(tracked)
+
 	informerFactory.Start((ctx.Done()))
 
 	// wait for the local cache to be populated.
@@ -167,6 +182,7 @@ func NewTraefikSource(ctx context.Context, dynamicKubeClient dynamic.Interface,
 		kubeClient:                 kubeClient,
 		namespace:                  namespace,
 		unstructuredConverter:      uc,
+		informerHealth:             health,
 	}, nil
 }
 
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// Transformer rewrites the endpoints of a source before they are planned: it
+// can change, add or drop endpoints.
+type Transformer interface {
+	Transform(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error)
+}
+
+// transformSource is a Source that applies transformers to the endpoints of its
+// wrapped source.
+type transformSource struct {
+	source       Source
+	transformers []Transformer
+}
+
+// NewTransformSource creates a new transformSource wrapping the provided Source.
+// The transformers are applied in order.
+func NewTransformSource(source Source, transformers ...Transformer) Source {
+	return &transformSource{source: source, transformers: transformers}
+}
+
+// Endpoints collects endpoints from its wrapped source and returns them
+// transformed. An error of a transformer fails the sync, so a broken
+// transformation never deletes records.
+func (ts *transformSource) Endpoints(ctx context.Context) ([]*endpoint.Endpoint, error) {
+	endpoints, err := ts.source.Endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range ts.transformers {
+		endpoints, err = t.Transform(ctx, endpoints)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return endpoints, nil
+}
+
+func (ts *transformSource) AddEventHandler(ctx context.Context, handler func()) {
+	ts.source.AddEventHandler(ctx, handler)
+}
+
+// Healthy returns the health of the wrapped source.
+func (ts *transformSource) Healthy() error {
+	return Healthy(ts.source)
+}
+
+// LastEvent returns the time of the last event of the wrapped source.
+func (ts *transformSource) LastEvent() time.Time {
+	return LastEvent(ts.source)
+}
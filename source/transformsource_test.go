@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+type transformerFunc func(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error)
+
+func (f transformerFunc) Transform(ctx context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	return f(ctx, endpoints)
+}
+
+func TestTransformSource(t *testing.T) {
+	suffix := transformerFunc(func(_ context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+		for _, ep := range endpoints {
+			ep.DNSName += ".example.com"
+		}
+		return endpoints, nil
+	})
+	dropTXT := transformerFunc(func(_ context.Context, endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+		var out []*endpoint.Endpoint
+		for _, ep := range endpoints {
+			if ep.RecordType != endpoint.RecordTypeTXT {
+				out = append(out, ep)
+			}
+		}
+		return out, nil
+	})
+
+	src := NewTransformSource(NewEchoSource([]*endpoint.Endpoint{
+		endpoint.NewEndpoint("a", endpoint.RecordTypeA, "1.2.3.4"),
+		endpoint.NewEndpoint("b", endpoint.RecordTypeTXT, "text"),
+	}), suffix, dropTXT)
+	endpoints, err := src.Endpoints(context.Background())
+	require.NoError(t, err)
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, "a.example.com", endpoints[0].DNSName)
+
+	failing := transformerFunc(func(context.Context, []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+		return nil, errors.New("broken")
+	})
+	_, err = NewTransformSource(NewEchoSource(nil), failing).Endpoints(context.Background())
+	assert.EqualError(t, err, "broken")
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"strings"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// privateTargetAnnotationKey is the annotation with the targets of the private
+// view, like the internal IP of a service: the other targets are then those
+// of the public view.
+const privateTargetAnnotationKey = "external-dns.alpha.kubernetes.io/private-target"
+
+// getPrivateTargetsFromAnnotations returns the targets of the private view.
+func getPrivateTargetsFromAnnotations(annotations map[string]string) endpoint.Targets {
+	var targets endpoint.Targets
+	for _, target := range strings.Split(strings.ReplaceAll(annotations[privateTargetAnnotationKey], " ", ""), ",") {
+		if target != "" {
+			targets = append(targets, strings.TrimSuffix(target, "."))
+		}
+	}
+	return targets
+}
+
+// setZoneFromAnnotations sets the zone of the annotations on the endpoints:
+// the public or private view, or the name of a zone as the ZoneLabelKey.
+func setZoneFromAnnotations(endpoints []*endpoint.Endpoint, annotations map[string]string) {
+	zone := annotations[zoneAnnotationKey]
+	for _, ep := range endpoints {
+		switch zone {
+		case "":
+			return
+		case endpoint.ViewPublic, endpoint.ViewPrivate:
+			ep.WithView(zone)
+		default:
+			if ep.Labels == nil {
+				ep.Labels = endpoint.NewLabels()
+			}
+			ep.Labels[endpoint.ZoneLabelKey] = zone
+		}
+	}
+}
+
+// endpointsForViews returns the endpoints in the public view, with endpoints
+// of the private targets of the annotations in the private view for the same
+// names. The endpoints are unchanged without private targets.
+func endpointsForViews(endpoints []*endpoint.Endpoint, annotations map[string]string) []*endpoint.Endpoint {
+	targets := getPrivateTargetsFromAnnotations(annotations)
+	if len(targets) == 0 {
+		return endpoints
+	}
+	names := map[string]bool{}
+	var private []*endpoint.Endpoint
+	for _, ep := range endpoints {
+		setIdentifier := ep.SetIdentifier
+		ep.WithView(endpoint.ViewPublic)
+		if names[ep.DNSName] {
+			continue
+		}
+		names[ep.DNSName] = true
+		for _, p := range endpointsForHostname(ep.DNSName, targets, ep.RecordTTL, ep.ProviderSpecific, setIdentifier, ep.Labels[endpoint.ResourceLabelKey]) {
+			private = append(private, p.WithView(endpoint.ViewPrivate))
+		}
+	}
+	return append(endpoints, private...)
+}
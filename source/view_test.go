@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestEndpointsForViews(t *testing.T) {
+	endpoints := func() []*endpoint.Endpoint {
+		return []*endpoint.Endpoint{
+			endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "34.1.2.3"),
+			endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeAAAA, "2001:db8::1"),
+			endpoint.NewEndpoint("api.example.org", endpoint.RecordTypeCNAME, "lb.example.org").WithSetIdentifier("blue"),
+		}
+	}
+
+	assert.Equal(t, endpoints(), endpointsForViews(endpoints(), map[string]string{}))
+
+	annotations := map[string]string{privateTargetAnnotationKey: "10.0.0.1, 10.0.0.2"}
+	assert.Equal(t, []*endpoint.Endpoint{
+		endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "34.1.2.3").WithView(endpoint.ViewPublic),
+		endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeAAAA, "2001:db8::1").WithView(endpoint.ViewPublic),
+		endpoint.NewEndpoint("api.example.org", endpoint.RecordTypeCNAME, "lb.example.org").WithSetIdentifier("blue").WithView(endpoint.ViewPublic),
+		endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "10.0.0.1", "10.0.0.2").WithView(endpoint.ViewPrivate),
+		endpoint.NewEndpoint("api.example.org", endpoint.RecordTypeA, "10.0.0.1", "10.0.0.2").WithSetIdentifier("blue").WithView(endpoint.ViewPrivate),
+	}, endpointsForViews(endpoints(), annotations))
+}
+
+func TestSetZoneFromAnnotations(t *testing.T) {
+	endpoints := []*endpoint.Endpoint{endpoint.NewEndpoint("app.example.org", endpoint.RecordTypeA, "10.0.0.1")}
+	setZoneFromAnnotations(endpoints, map[string]string{})
+	assert.Empty(t, endpoints[0].View())
+	assert.Empty(t, endpoints[0].Labels[endpoint.ZoneLabelKey])
+
+	setZoneFromAnnotations(endpoints, map[string]string{zoneAnnotationKey: endpoint.ViewPrivate})
+	assert.Equal(t, endpoint.ViewPrivate, endpoints[0].View())
+	assert.Empty(t, endpoints[0].Labels[endpoint.ZoneLabelKey])
+
+	endpoints = []*endpoint.Endpoint{{DNSName: "app.example.org", RecordType: endpoint.RecordTypeA, Targets: endpoint.Targets{"10.0.0.1"}}}
+	setZoneFromAnnotations(endpoints, map[string]string{zoneAnnotationKey: "pods-zone"})
+	assert.Empty(t, endpoints[0].View())
+	assert.Equal(t, "pods-zone", endpoints[0].Labels[endpoint.ZoneLabelKey])
+}